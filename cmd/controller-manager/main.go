@@ -3,6 +3,12 @@ package main
 import (
 	"flag"
 	"github.com/kubespace/kubespace/pkg/controller"
+	"github.com/kubespace/kubespace/pkg/controller/app_drift"
+	"github.com/kubespace/kubespace/pkg/controller/app_git"
+	"github.com/kubespace/kubespace/pkg/controller/app_stack"
+	"github.com/kubespace/kubespace/pkg/controller/app_store"
+	"github.com/kubespace/kubespace/pkg/controller/cluster_event"
+	"github.com/kubespace/kubespace/pkg/controller/cluster_health"
 	"github.com/kubespace/kubespace/pkg/controller/pipeline_run"
 	"github.com/kubespace/kubespace/pkg/controller/pipeline_trigger"
 	"github.com/kubespace/kubespace/pkg/controller/spacelet"
@@ -20,6 +26,7 @@ var (
 	mysqlPassword = flag.String("mysql-password", utils.LookupEnvOrString("MYSQL_PASSWORD", ""), "mysql password used.")
 	mysqlDbName   = flag.String("mysql-dbname", utils.LookupEnvOrString("MYSQL_DBNAME", "kubespace"), "mysql db used.")
 	resyncSec     = flag.Int("resync-seconds", utils.LookupEnvOrInt("RESYNC_SECONDS", 5), "controller list resync seconds.")
+	encryptionKey = flag.String("encryption-key", utils.LookupEnvOrString("ENCRYPTION_KEY", ""), "key to encrypt sensitive data in database, must be same with server.")
 )
 
 func main() {
@@ -43,7 +50,7 @@ func main() {
 			DB:       *redisDB,
 		},
 	}
-	controllerConfig, err := controller.NewConfig(dbConfig, *resyncSec)
+	controllerConfig, err := controller.NewConfig(dbConfig, *resyncSec, *encryptionKey)
 	if err != nil {
 		panic(err)
 	}
@@ -59,5 +66,29 @@ func main() {
 	spaceletController := spacelet.NewSpaceletController(controllerConfig)
 	spaceletController.Run(stopCh)
 
+	// 应用商店同步controller
+	appStoreController := app_store.NewAppStoreController(controllerConfig)
+	appStoreController.Run(stopCh)
+
+	// 应用资源漂移检测controller
+	appDriftController := app_drift.NewAppDriftController(controllerConfig)
+	appDriftController.Run(stopCh)
+
+	// 应用git来源同步controller
+	appGitController := app_git.NewAppGitController(controllerConfig)
+	appGitController.Run(stopCh)
+
+	// 应用栈按依赖顺序部署controller
+	appStackController := app_stack.NewAppStackController(controllerConfig)
+	appStackController.Run(stopCh)
+
+	// 集群健康检查controller
+	clusterHealthController := cluster_health.NewClusterHealthController(controllerConfig)
+	clusterHealthController.Run(stopCh)
+
+	// 集群事件归档controller
+	clusterEventController := cluster_event.NewClusterEventController(controllerConfig)
+	clusterEventController.Run(stopCh)
+
 	<-stopCh
 }
@@ -3,14 +3,25 @@ package main
 import (
 	"flag"
 	"github.com/kubespace/kubespace/pkg/kubeagent"
+	"github.com/kubespace/kubespace/pkg/kubernetes/cache"
 	"github.com/kubespace/kubespace/pkg/utils"
 	"k8s.io/klog/v2"
+	"strings"
 )
 
 var (
 	kubeConfigFile = flag.String("kubeconfig", "", "Path to kubeconfig file with authorization and master location information.")
 	agentToken     = flag.String("token", utils.LookupEnvOrString("TOKEN", "local"), "Agent token to connect to server.")
 	serverHost     = flag.String("server-host", utils.LookupEnvOrString("SERVER_HOST", "kubespace"), "Server host:port agent to connect.")
+	profile        = flag.String("profile", utils.LookupEnvOrString("PROFILE", "admin"), "Agent permission profile: admin, namespace or readonly.")
+	namespaces     = flag.String("namespaces", utils.LookupEnvOrString("NAMESPACES", ""), "Comma separated namespaces agent can access with namespace profile.")
+	serverTLS      = flag.Bool("server-tls", false, "Connect to server with https and wss.")
+	caFile         = flag.String("ca-file", "", "CA file to verify server certificate, use system roots if empty.")
+	caHash         = flag.String("ca-hash", "", "Pin server certificate chain by public key sha256, format is sha256:<hex>.")
+	insecure       = flag.Bool("insecure-skip-tls-verify", false, "Skip verifying server certificate.")
+	disableCache   = flag.Bool("disable-cache", false, "Disable resource informer cache, list/get/watch requests go to apiserver directly.")
+	cacheResources = flag.String("cache-resources", utils.LookupEnvOrString("CACHE_RESOURCES", ""), "Comma separated resources to cache, such as pods,deployments, use default resources if empty.")
+	cacheMaxObjs   = flag.Int64("cache-max-objects", cache.DefaultMaxObjects, "Max objects of all cached resources.")
 )
 
 func buildAgent() (*kubeagent.Agent, error) {
@@ -18,6 +29,21 @@ func buildAgent() (*kubeagent.Agent, error) {
 		KubeConfigFile: *kubeConfigFile,
 		AgentToken:     *agentToken,
 		ServerHost:     *serverHost,
+		Profile:        *profile,
+
+		ServerTLS:             *serverTLS,
+		CAFile:                *caFile,
+		CAHash:                *caHash,
+		InsecureSkipTLSVerify: *insecure,
+
+		DisableCache:    *disableCache,
+		CacheMaxObjects: *cacheMaxObjs,
+	}
+	if *cacheResources != "" {
+		options.CacheResources = strings.Split(*cacheResources, ",")
+	}
+	if *namespaces != "" {
+		options.Namespaces = strings.Split(*namespaces, ",")
 	}
 	agentConfig, err := kubeagent.NewAgentConfig(options)
 	if err != nil {
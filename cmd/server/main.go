@@ -23,6 +23,8 @@ var (
 	agentVersion    = flag.String("agent-version", utils.LookupEnvOrString("AGENT_VERSION", "latest"), "kubespace agent version.")
 	agentRepository = flag.String("agent-repository", utils.LookupEnvOrString("AGENT_REPOSITORY", "kubespace/kubespace-agent"), "kubespace agent image repository.")
 	releaseVersion  = flag.String("release-version", utils.LookupEnvOrString("RELEASE_VERSION", ""), "kubespace release version.")
+	spaceletPort    = flag.Int("spacelet-port", utils.LookupEnvOrInt("SPACELET_PORT", 7443), "Server mutual tls port for spacelet to listen.")
+	encryptionKey   = flag.String("encryption-key", utils.LookupEnvOrString("ENCRYPTION_KEY", ""), "key to encrypt sensitive data in database, must be same with controller-manager.")
 )
 
 func createServerOptions() *config.ServerOptions {
@@ -41,6 +43,8 @@ func createServerOptions() *config.ServerOptions {
 		AgentVersion:    *agentVersion,
 		AgentRepository: *agentRepository,
 		ReleaseVersion:  *releaseVersion,
+		SpaceletPort:    *spaceletPort,
+		EncryptionKey:   *encryptionKey,
 	}
 }
 
@@ -3,8 +3,12 @@ package main
 import (
 	"flag"
 	"github.com/kubespace/kubespace/pkg/spacelet"
+	"github.com/kubespace/kubespace/pkg/spacelet/pipeline_job"
+	"github.com/kubespace/kubespace/pkg/third/httpclient"
 	"github.com/kubespace/kubespace/pkg/utils"
 	"k8s.io/klog/v2"
+	"os"
+	"strings"
 )
 
 var (
@@ -12,6 +16,14 @@ var (
 	hostIp    = flag.String("host-ip", utils.LookupEnvOrString("HOST_IP", ""), "spacelet host ip.")
 	dataDir   = flag.String("data-dir", utils.LookupEnvOrString("DATA_DIR", "/data"), "data directory.")
 	serverUrl = flag.String("server-url", utils.LookupEnvOrString("SERVER_URL", "http://kubespace"), "kubespace server url.")
+	serverTLS = flag.String("server-tls-url", utils.LookupEnvOrString("SERVER_TLS_URL", ""), "kubespace mutual tls url, default is the host of server url with the tls port returned by kubespace.")
+	caHash    = flag.String("ca-hash", utils.LookupEnvOrString("CA_HASH", ""), "sha256 of kubespace ca public key to verify when register, format is sha256:<hex>.")
+	bootstrap = flag.String("bootstrap-token", utils.LookupEnvOrString("BOOTSTRAP_TOKEN", ""), "bootstrap token generated by kubespace admin, required when register for the first time.")
+	maxJobs   = flag.Int("max-jobs", utils.LookupEnvOrInt("MAX_JOBS", 0), "max concurrent pipeline jobs, 0 means unlimited.")
+	cpu       = flag.String("cpu", utils.LookupEnvOrString("CPU", ""), "cpu capacity for pipeline jobs, such as 4 or 3500m, default is the number of cpu cores.")
+	memory    = flag.String("memory", utils.LookupEnvOrString("MEMORY", ""), "memory capacity for pipeline jobs, such as 16Gi, default is the host memory.")
+	execAllow = flag.String("exec-allowlist", utils.LookupEnvOrString("EXEC_ALLOWLIST", ""), "comma-separated commands allowed to run by exec api, exec api is disabled if empty.")
+	jobParams = flag.String("job-params", utils.LookupEnvOrString("JOB_PARAMS", ""), "pipeline job params file, run the job in pod and exit.")
 )
 
 func buildServer() (*spacelet.Server, error) {
@@ -20,6 +32,14 @@ func buildServer() (*spacelet.Server, error) {
 		Port:      *port,
 		DataDir:   *dataDir,
 		ServerUrl: *serverUrl,
+		MaxJobs:   *maxJobs,
+		Cpu:       *cpu,
+		Memory:    *memory,
+
+		ServerTLSUrl:   *serverTLS,
+		CAHash:         *caHash,
+		BootstrapToken: *bootstrap,
+		ExecAllowlist:  execAllowlist(),
 	})
 	if err != nil {
 		klog.Error("New server config error:", err)
@@ -28,12 +48,38 @@ func buildServer() (*spacelet.Server, error) {
 	return spacelet.NewServer(config)
 }
 
+func execAllowlist() []string {
+	var commands []string
+	for _, command := range strings.Split(*execAllow, ",") {
+		if command = strings.TrimSpace(command); command != "" {
+			commands = append(commands, command)
+		}
+	}
+	return commands
+}
+
+// runPodJob 在kubernetes pod中执行单个流水线任务，执行完成后退出
+func runPodJob() {
+	client, err := httpclient.NewHttpClient(*serverUrl)
+	if err != nil {
+		panic(err)
+	}
+	if err = pipeline_job.RunPodJob(*jobParams, *dataDir, client); err != nil {
+		klog.Errorf("run pipeline job error: %s", err.Error())
+		os.Exit(1)
+	}
+	os.Exit(0)
+}
+
 func main() {
 	klog.InitFlags(nil)
 	flag.Parse()
 	flag.VisitAll(func(flag *flag.Flag) {
 		klog.Infof("FLAG: --%s=%q", flag.Name, flag.Value)
 	})
+	if *jobParams != "" {
+		runPodJob()
+	}
 	var err error
 	svr, err := buildServer()
 	if err != nil {
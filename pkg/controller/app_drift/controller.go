@@ -0,0 +1,122 @@
+package app_drift
+
+import (
+	"fmt"
+	"github.com/kubespace/kubespace/pkg/controller"
+	"github.com/kubespace/kubespace/pkg/core/lock"
+	"github.com/kubespace/kubespace/pkg/informer"
+	applistwatcher "github.com/kubespace/kubespace/pkg/informer/listwatcher/app"
+	"github.com/kubespace/kubespace/pkg/model"
+	"github.com/kubespace/kubespace/pkg/model/types"
+	"github.com/kubespace/kubespace/pkg/service/notification"
+	projectservice "github.com/kubespace/kubespace/pkg/service/project"
+	"k8s.io/klog/v2"
+)
+
+// AppDriftController 定时比较已安装应用的release清单与集群中的实际资源，检测被手动修改或者删除的资源
+type AppDriftController struct {
+	models      *model.Models
+	appInformer informer.Informer
+	appService  *projectservice.AppService
+	// 检测应用时对其进行加锁，保证只有一个进行处理
+	lock lock.Lock
+
+	notificationService *notification.NotificationService
+}
+
+func NewAppDriftController(config *controller.Config) *AppDriftController {
+	appInformer := config.InformerFactory.AppInformer(&applistwatcher.AppWatchCondition{Installed: true})
+
+	c := &AppDriftController{
+		models:      config.Models,
+		appInformer: appInformer,
+		appService:  config.ServiceFactory.Project.AppService,
+		lock:        lock.NewMemLock(),
+
+		notificationService: config.ServiceFactory.Notification.NotificationService,
+	}
+
+	appInformer.AddHandler(&informer.ResourceHandler{
+		CheckFunc:  c.detectCheck,
+		HandleFunc: c.detect,
+	})
+	return c
+}
+
+func (a *AppDriftController) Run(stopCh <-chan struct{}) {
+	go a.appInformer.Run(stopCh)
+}
+
+func (a *AppDriftController) detectLockKey(id uint) string {
+	return fmt.Sprintf("app_drift_controller:app:%d", id)
+}
+
+func (a *AppDriftController) detectCheck(obj interface{}) bool {
+	app, ok := obj.(types.App)
+	if !ok {
+		return false
+	}
+	if locked, _ := a.lock.Locked(a.detectLockKey(app.ID)); locked {
+		return false
+	}
+	return app.DriftCheckDue()
+}
+
+// detect 检测应用资源漂移以及运行状态，并保存检测结果
+func (a *AppDriftController) detect(obj interface{}) error {
+	app := obj.(types.App)
+	if ok, _ := a.lock.Acquire(a.detectLockKey(app.ID)); !ok {
+		return nil
+	}
+	defer a.lock.Release(a.detectLockKey(app.ID))
+
+	// 缓存的数据可能已过期，重新获取判断是否需要检测
+	current, err := a.models.AppManager.GetById(app.ID)
+	if err != nil {
+		klog.Errorf("get app id=%d error: %s", app.ID, err.Error())
+		return err
+	}
+	if !current.DriftCheckDue() {
+		return nil
+	}
+	if _, _, err = a.appService.DetectAppDrift(current.ID); err != nil {
+		klog.Warningf("detect app id=%d name=%s drift error: %s", current.ID, current.Name, err.Error())
+		return err
+	}
+	previous, err := a.appService.CheckRuntimeStatus(current)
+	if err != nil {
+		klog.Warningf("check app id=%d name=%s runtime status error: %s", current.ID, current.Name, err.Error())
+		return err
+	}
+	if current.RuntimeStatus == types.AppStatusRunningFault && previous != types.AppStatusRunningFault {
+		a.notifyRunningFault(current)
+	}
+	return nil
+}
+
+// notifyRunningFault 应用运行状态变为RunningFault时发送通知，工作空间应用属于工作空间范围，集群组件属于集群范围
+func (a *AppDriftController) notifyRunningFault(app *types.App) {
+	event := &notification.Event{
+		Type:    types.NotificationEventAppRunningFault,
+		Scope:   app.Scope,
+		ScopeId: app.ScopeId,
+		Subject: app.Name,
+		Message: fmt.Sprintf("应用%s运行状态异常", app.Name),
+		Data: map[string]interface{}{
+			"app_id":       app.ID,
+			"app_name":     app.Name,
+			"namespace":    app.Namespace,
+			"sync_status":  app.SyncStatus,
+			"sync_message": app.SyncMessage,
+		},
+	}
+	if app.Scope == types.ScopeProject {
+		if projectObj, err := a.models.ProjectManager.Get(app.ScopeId); err == nil {
+			event.ScopeName = projectObj.Name
+			event.Data["namespace"] = projectObj.Namespace
+		}
+	} else if clusterObj, err := a.models.ClusterManager.GetById(app.ScopeId); err == nil {
+		event.ScopeName = clusterObj.Name1
+	}
+	a.notificationService.Notify(event)
+}
@@ -0,0 +1,83 @@
+package app_git
+
+import (
+	"fmt"
+	"github.com/kubespace/kubespace/pkg/controller"
+	"github.com/kubespace/kubespace/pkg/core/lock"
+	"github.com/kubespace/kubespace/pkg/informer"
+	applistwatcher "github.com/kubespace/kubespace/pkg/informer/listwatcher/app"
+	"github.com/kubespace/kubespace/pkg/model"
+	"github.com/kubespace/kubespace/pkg/model/types"
+	projectservice "github.com/kubespace/kubespace/pkg/service/project"
+	"k8s.io/klog/v2"
+)
+
+// AppGitController 定时拉取应用git来源的最新提交，有新提交时生成应用版本，开启自动同步时部署到集群
+type AppGitController struct {
+	models         *model.Models
+	sourceInformer informer.Informer
+	appService     *projectservice.AppService
+	// 同步git来源时对其进行加锁，保证只有一个进行处理
+	lock lock.Lock
+}
+
+func NewAppGitController(config *controller.Config) *AppGitController {
+	enabled := true
+	sourceInformer := config.InformerFactory.AppGitSourceInformer(&applistwatcher.AppGitSourceWatchCondition{Enabled: &enabled})
+
+	c := &AppGitController{
+		models:         config.Models,
+		sourceInformer: sourceInformer,
+		appService:     config.ServiceFactory.Project.AppService,
+		lock:           lock.NewMemLock(),
+	}
+
+	sourceInformer.AddHandler(&informer.ResourceHandler{
+		CheckFunc:  c.reconcileCheck,
+		HandleFunc: c.reconcile,
+	})
+	return c
+}
+
+func (a *AppGitController) Run(stopCh <-chan struct{}) {
+	go a.sourceInformer.Run(stopCh)
+}
+
+func (a *AppGitController) reconcileLockKey(id uint) string {
+	return fmt.Sprintf("app_git_controller:source:%d", id)
+}
+
+func (a *AppGitController) reconcileCheck(obj interface{}) bool {
+	source, ok := obj.(types.AppGitSource)
+	if !ok {
+		return false
+	}
+	if locked, _ := a.lock.Locked(a.reconcileLockKey(source.ID)); locked {
+		return false
+	}
+	return source.PollDue()
+}
+
+// reconcile 获取git来源的最新提交并生成应用版本
+func (a *AppGitController) reconcile(obj interface{}) error {
+	source := obj.(types.AppGitSource)
+	if ok, _ := a.lock.Acquire(a.reconcileLockKey(source.ID)); !ok {
+		return nil
+	}
+	defer a.lock.Release(a.reconcileLockKey(source.ID))
+
+	// 缓存的数据可能已过期，重新获取判断是否需要同步
+	current, err := a.models.AppGitSourceManager.Get(source.ID)
+	if err != nil {
+		klog.Errorf("get app git source id=%d error: %s", source.ID, err.Error())
+		return err
+	}
+	if !current.PollDue() {
+		return nil
+	}
+	if err = a.appService.ReconcileGitSource(current.ID); err != nil {
+		klog.Warningf("reconcile app id=%d git source id=%d error: %s", current.AppId, current.ID, err.Error())
+		return err
+	}
+	return nil
+}
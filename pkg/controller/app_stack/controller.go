@@ -0,0 +1,82 @@
+package app_stack
+
+import (
+	"fmt"
+	"github.com/kubespace/kubespace/pkg/controller"
+	"github.com/kubespace/kubespace/pkg/core/lock"
+	"github.com/kubespace/kubespace/pkg/informer"
+	applistwatcher "github.com/kubespace/kubespace/pkg/informer/listwatcher/app"
+	"github.com/kubespace/kubespace/pkg/model"
+	"github.com/kubespace/kubespace/pkg/model/types"
+	projectservice "github.com/kubespace/kubespace/pkg/service/project"
+	"k8s.io/klog/v2"
+)
+
+// AppStackController 按依赖顺序执行应用栈的安装、升级以及销毁
+type AppStackController struct {
+	models        *model.Models
+	stackInformer informer.Informer
+	appService    *projectservice.AppService
+	// 执行应用栈时对其进行加锁，保证只有一个进行处理
+	lock lock.Lock
+}
+
+func NewAppStackController(config *controller.Config) *AppStackController {
+	stackInformer := config.InformerFactory.AppStackInformer(&applistwatcher.AppStackWatchCondition{Operating: true})
+
+	c := &AppStackController{
+		models:        config.Models,
+		stackInformer: stackInformer,
+		appService:    config.ServiceFactory.Project.AppService,
+		lock:          lock.NewMemLock(),
+	}
+
+	stackInformer.AddHandler(&informer.ResourceHandler{
+		CheckFunc:  c.executeCheck,
+		HandleFunc: c.execute,
+	})
+	return c
+}
+
+func (a *AppStackController) Run(stopCh <-chan struct{}) {
+	go a.stackInformer.Run(stopCh)
+}
+
+func (a *AppStackController) executeLockKey(id uint) string {
+	return fmt.Sprintf("app_stack_controller:stack:%d", id)
+}
+
+func (a *AppStackController) executeCheck(obj interface{}) bool {
+	stack, ok := obj.(types.AppStack)
+	if !ok {
+		return false
+	}
+	if locked, _ := a.lock.Locked(a.executeLockKey(stack.ID)); locked {
+		return false
+	}
+	return stack.Operating()
+}
+
+// execute 执行应用栈的安装、升级或者销毁
+func (a *AppStackController) execute(obj interface{}) error {
+	stack := obj.(types.AppStack)
+	if ok, _ := a.lock.Acquire(a.executeLockKey(stack.ID)); !ok {
+		return nil
+	}
+	defer a.lock.Release(a.executeLockKey(stack.ID))
+
+	// 缓存的数据可能已过期，重新获取判断是否需要执行
+	current, err := a.models.AppStackManager.Get(stack.ID)
+	if err != nil {
+		klog.Errorf("get app stack id=%d error: %s", stack.ID, err.Error())
+		return err
+	}
+	if !current.Operating() {
+		return nil
+	}
+	if err = a.appService.ExecuteStack(current.ID); err != nil {
+		klog.Warningf("execute app stack id=%d name=%s error: %s", current.ID, current.Name, err.Error())
+		return err
+	}
+	return nil
+}
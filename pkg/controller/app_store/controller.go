@@ -0,0 +1,82 @@
+package app_store
+
+import (
+	"fmt"
+	"github.com/kubespace/kubespace/pkg/controller"
+	"github.com/kubespace/kubespace/pkg/core/lock"
+	"github.com/kubespace/kubespace/pkg/informer"
+	appstorelistwatcher "github.com/kubespace/kubespace/pkg/informer/listwatcher/appstore"
+	"github.com/kubespace/kubespace/pkg/model"
+	"github.com/kubespace/kubespace/pkg/model/types"
+	projectservice "github.com/kubespace/kubespace/pkg/service/project"
+	"k8s.io/klog/v2"
+)
+
+// AppStoreController 定时从远程chart仓库同步应用到应用商店
+type AppStoreController struct {
+	models          *model.Models
+	sourceInformer  informer.Informer
+	appStoreService *projectservice.AppStoreService
+	// 同步应用商店源时对其进行加锁，保证只有一个进行处理
+	lock lock.Lock
+}
+
+func NewAppStoreController(config *controller.Config) *AppStoreController {
+	enabled := true
+	// 定时监听所有启用的应用商店源，手动刷新时会立即通知
+	sourceInformer := config.InformerFactory.AppStoreSourceInformer(&appstorelistwatcher.AppStoreSourceWatchCondition{
+		Enabled: &enabled,
+	})
+
+	c := &AppStoreController{
+		models:          config.Models,
+		sourceInformer:  sourceInformer,
+		appStoreService: config.ServiceFactory.Project.AppStoreService,
+		lock:            lock.NewMemLock(),
+	}
+
+	sourceInformer.AddHandler(&informer.ResourceHandler{
+		CheckFunc:  c.syncCheck,
+		HandleFunc: c.sync,
+	})
+	return c
+}
+
+func (a *AppStoreController) Run(stopCh <-chan struct{}) {
+	go a.sourceInformer.Run(stopCh)
+}
+
+func (a *AppStoreController) syncLockKey(id uint) string {
+	return fmt.Sprintf("app_store_controller:source:%d", id)
+}
+
+func (a *AppStoreController) syncCheck(obj interface{}) bool {
+	source, ok := obj.(types.AppStoreSource)
+	if !ok {
+		return false
+	}
+	if locked, _ := a.lock.Locked(a.syncLockKey(source.ID)); locked {
+		return false
+	}
+	return source.SyncDue()
+}
+
+// sync 同步应用商店源中的chart新版本
+func (a *AppStoreController) sync(obj interface{}) error {
+	source := obj.(types.AppStoreSource)
+	if ok, _ := a.lock.Acquire(a.syncLockKey(source.ID)); !ok {
+		return nil
+	}
+	defer a.lock.Release(a.syncLockKey(source.ID))
+
+	// 通知或者缓存的数据可能已过期，重新获取判断是否需要同步
+	current, err := a.models.AppStoreSourceManager.Get(source.ID)
+	if err != nil {
+		klog.Errorf("get app store source id=%d error: %s", source.ID, err.Error())
+		return err
+	}
+	if !current.SyncDue() {
+		return nil
+	}
+	return a.appStoreService.SyncSource(current.ID)
+}
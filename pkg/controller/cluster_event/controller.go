@@ -0,0 +1,257 @@
+package cluster_event
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/kubespace/kubespace/pkg/controller"
+	"github.com/kubespace/kubespace/pkg/core/lock"
+	"github.com/kubespace/kubespace/pkg/informer"
+	"github.com/kubespace/kubespace/pkg/kubernetes/resource"
+	kubetypes "github.com/kubespace/kubespace/pkg/kubernetes/types"
+	"github.com/kubespace/kubespace/pkg/model"
+	"github.com/kubespace/kubespace/pkg/model/types"
+	clusterservice "github.com/kubespace/kubespace/pkg/service/cluster"
+	"github.com/kubespace/kubespace/pkg/utils"
+	"k8s.io/apimachinery/pkg/watch"
+	"k8s.io/klog/v2"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// retryBaseInterval 监听失败后的重试间隔，连续失败时指数增加
+	retryBaseInterval = 30 * time.Second
+	// retryMaxInterval 最大重试间隔，监听持续超过该时间后断开不计入连续失败
+	retryMaxInterval = 30 * time.Minute
+	// clusterCheckInterval 监听过程中检查集群是否已删除的间隔
+	clusterCheckInterval = time.Minute
+	// cleanupInterval 清理过期事件的间隔
+	cleanupInterval = time.Hour
+)
+
+// ClusterEventController 监听所有集群的kubernetes事件，去重后归档到数据库，并定期清理过期事件
+type ClusterEventController struct {
+	models          *model.Models
+	clusterInformer informer.Informer
+	kubeClient      *clusterservice.KubeClient
+	// 每个集群只有一个事件监听
+	lock   lock.Lock
+	stopCh <-chan struct{}
+
+	mu sync.Mutex
+	// retries 集群监听连续失败的次数以及下次重试的时间
+	retries map[uint]*retry
+}
+
+type retry struct {
+	failures int
+	next     time.Time
+}
+
+func NewClusterEventController(config *controller.Config) *ClusterEventController {
+	clusterInformer := config.InformerFactory.ClusterInformer(nil)
+
+	c := &ClusterEventController{
+		models:          config.Models,
+		clusterInformer: clusterInformer,
+		kubeClient:      config.ServiceFactory.Cluster.KubeClient,
+		lock:            lock.NewMemLock(),
+		retries:         make(map[uint]*retry),
+	}
+
+	clusterInformer.AddHandler(&informer.ResourceHandler{
+		CheckFunc:  c.watchCheck,
+		HandleFunc: c.watch,
+	})
+	return c
+}
+
+func (c *ClusterEventController) Run(stopCh <-chan struct{}) {
+	c.stopCh = stopCh
+	go c.clusterInformer.Run(stopCh)
+	go c.cleanup(stopCh)
+}
+
+func (c *ClusterEventController) watchLockKey(id uint) string {
+	return fmt.Sprintf("cluster_event_controller:cluster:%d", id)
+}
+
+func (c *ClusterEventController) watchCheck(obj interface{}) bool {
+	cluster, ok := obj.(types.Cluster)
+	if !ok {
+		return false
+	}
+	if locked, _ := c.lock.Locked(c.watchLockKey(cluster.ID)); locked {
+		return false
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if r, ok := c.retries[cluster.ID]; ok && time.Now().Before(r.next) {
+		return false
+	}
+	return true
+}
+
+// watch 监听集群事件直到监听断开或者集群被删除，断开后由informer重新同步时再次监听
+func (c *ClusterEventController) watch(obj interface{}) error {
+	cluster := obj.(types.Cluster)
+	if ok, _ := c.lock.Acquire(c.watchLockKey(cluster.ID)); !ok {
+		return nil
+	}
+	defer c.lock.Release(c.watchLockKey(cluster.ID))
+
+	start := time.Now()
+	err := c.archive(&cluster)
+	c.backoff(cluster.ID, err, time.Since(start))
+	return err
+}
+
+// backoff 记录监听结果，连续失败时延长重试时间
+func (c *ClusterEventController) backoff(clusterId uint, err error, duration time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if err == nil {
+		delete(c.retries, clusterId)
+		return
+	}
+	r, ok := c.retries[clusterId]
+	if !ok || duration > retryMaxInterval {
+		r = &retry{}
+		c.retries[clusterId] = r
+	}
+	r.failures += 1
+	interval := retryMaxInterval
+	if r.failures < 10 {
+		interval = retryBaseInterval * time.Duration(1<<(r.failures-1))
+		if interval > retryMaxInterval {
+			interval = retryMaxInterval
+		}
+	}
+	r.next = time.Now().Add(interval)
+}
+
+// archive 先监听再全量获取当前事件，保证获取与监听之间的事件不会丢失，重复的事件保存时去重
+func (c *ClusterEventController) archive(cluster *types.Cluster) error {
+	process := true
+	outer, err := c.kubeClient.Watch(cluster.Name, kubetypes.EventType, &resource.QueryParams{Process: &process})
+	if err != nil {
+		return fmt.Errorf("watch cluster %s events error: %s", cluster.Name1, err.Error())
+	}
+	defer outer.Close()
+	klog.Infof("start archiving events of cluster id=%d name=%s", cluster.ID, cluster.Name1)
+
+	resp := c.kubeClient.List(cluster.Name, kubetypes.EventType, &resource.QueryParams{})
+	if !resp.IsSuccess() {
+		return fmt.Errorf("list cluster %s events error: %s", cluster.Name1, resp.Msg)
+	}
+	var events []*resource.BuildEvent
+	if err = utils.ConvertTypeByJson(resp.Data, &events); err != nil {
+		return fmt.Errorf("convert cluster %s events error: %s", cluster.Name1, err.Error())
+	}
+	for _, event := range events {
+		c.save(cluster.ID, event)
+	}
+
+	ticker := time.NewTicker(clusterCheckInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case out, ok := <-outer.OutCh():
+			if !ok {
+				return fmt.Errorf("cluster %s event watch closed", cluster.Name1)
+			}
+			event, err := watchEvent(out)
+			if err != nil {
+				klog.Warningf("parse cluster %s watch event error: %s", cluster.Name1, err.Error())
+				continue
+			}
+			if event != nil {
+				c.save(cluster.ID, event)
+			}
+		case <-outer.StopCh():
+			return fmt.Errorf("cluster %s event watch closed", cluster.Name1)
+		case <-ticker.C:
+			if _, err = c.models.ClusterManager.GetById(cluster.ID); err != nil {
+				klog.Infof("cluster id=%d not found, stop archiving events: %s", cluster.ID, err.Error())
+				return nil
+			}
+		case <-c.stopCh:
+			return nil
+		}
+	}
+}
+
+// watchEvent 解析监听到的事件，agent返回的是序列化后的字符串，事件删除时返回nil
+func watchEvent(out interface{}) (*resource.BuildEvent, error) {
+	var res struct {
+		Type   watch.EventType
+		Object *resource.BuildEvent
+	}
+	var err error
+	if str, ok := out.(string); ok {
+		err = json.Unmarshal([]byte(str), &res)
+	} else {
+		err = utils.ConvertTypeByJson(out, &res)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if (res.Type != watch.Added && res.Type != watch.Modified) || res.Object == nil {
+		return nil, nil
+	}
+	return res.Object, nil
+}
+
+func (c *ClusterEventController) save(clusterId uint, event *resource.BuildEvent) {
+	if event.UID == "" {
+		return
+	}
+	clusterEvent := &types.ClusterEvent{
+		ClusterId: clusterId,
+		Uid:       event.UID,
+		Name:      event.Name,
+		Namespace: event.Namespace,
+		Reason:    event.Reason,
+		Type:      event.Type,
+		Message:   event.Message,
+		Count:     event.Count,
+		FirstTime: event.FirstTime.Time,
+		LastTime:  event.EventTime.Time,
+	}
+	if clusterEvent.Count == 0 {
+		clusterEvent.Count = 1
+	}
+	if clusterEvent.FirstTime.IsZero() {
+		clusterEvent.FirstTime = clusterEvent.LastTime
+	}
+	if event.Object != nil {
+		clusterEvent.ObjectKind = event.Object.Kind
+		clusterEvent.ObjectName = event.Object.Name
+		clusterEvent.ObjectUid = string(event.Object.UID)
+	}
+	if event.Source != nil {
+		clusterEvent.Source = strings.Trim(event.Source.Component+"/"+event.Source.Host, "/")
+	}
+	if err := c.models.ClusterManager.SaveEvent(clusterEvent); err != nil {
+		klog.Errorf("save cluster id=%d event uid=%s error: %s", clusterId, event.UID, err.Error())
+	}
+}
+
+// cleanup 定期清理超过保留时间的事件
+func (c *ClusterEventController) cleanup(stopCh <-chan struct{}) {
+	ticker := time.NewTicker(cleanupInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			if cnt, err := c.models.ClusterManager.DeleteExpiredEvents(); err != nil {
+				klog.Errorf("delete expired cluster events error: %s", err.Error())
+			} else if cnt > 0 {
+				klog.Infof("deleted %d expired cluster events", cnt)
+			}
+		case <-stopCh:
+			return
+		}
+	}
+}
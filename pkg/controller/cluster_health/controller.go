@@ -0,0 +1,84 @@
+package cluster_health
+
+import (
+	"fmt"
+	"github.com/kubespace/kubespace/pkg/controller"
+	"github.com/kubespace/kubespace/pkg/core/lock"
+	"github.com/kubespace/kubespace/pkg/informer"
+	clusterlistwatcher "github.com/kubespace/kubespace/pkg/informer/listwatcher/cluster"
+	"github.com/kubespace/kubespace/pkg/model"
+	"github.com/kubespace/kubespace/pkg/model/types"
+	clusterservice "github.com/kubespace/kubespace/pkg/service/cluster"
+	"k8s.io/klog/v2"
+)
+
+// ClusterHealthController 定期对集群进行健康检查，并保存检查记录
+type ClusterHealthController struct {
+	models          *model.Models
+	clusterInformer informer.Informer
+	healthService   *clusterservice.HealthService
+	// 检查集群时对其进行加锁，保证只有一个进行处理
+	lock lock.Lock
+}
+
+func NewClusterHealthController(config *controller.Config) *ClusterHealthController {
+	clusterInformer := config.InformerFactory.ClusterInformer(&clusterlistwatcher.ClusterWatchCondition{HealthCheckDue: true})
+
+	c := &ClusterHealthController{
+		models:          config.Models,
+		clusterInformer: clusterInformer,
+		healthService:   config.ServiceFactory.Cluster.HealthService,
+		lock:            lock.NewMemLock(),
+	}
+
+	clusterInformer.AddHandler(&informer.ResourceHandler{
+		CheckFunc:  c.probeCheck,
+		HandleFunc: c.probe,
+	})
+	return c
+}
+
+func (c *ClusterHealthController) Run(stopCh <-chan struct{}) {
+	go c.clusterInformer.Run(stopCh)
+}
+
+func (c *ClusterHealthController) probeLockKey(id uint) string {
+	return fmt.Sprintf("cluster_health_controller:cluster:%d", id)
+}
+
+func (c *ClusterHealthController) probeCheck(obj interface{}) bool {
+	cluster, ok := obj.(types.Cluster)
+	if !ok {
+		return false
+	}
+	if locked, _ := c.lock.Locked(c.probeLockKey(cluster.ID)); locked {
+		return false
+	}
+	return cluster.HealthCheckDue()
+}
+
+// probe 对集群进行健康检查
+func (c *ClusterHealthController) probe(obj interface{}) error {
+	cluster := obj.(types.Cluster)
+	if ok, _ := c.lock.Acquire(c.probeLockKey(cluster.ID)); !ok {
+		return nil
+	}
+	defer c.lock.Release(c.probeLockKey(cluster.ID))
+
+	// 缓存的数据可能已过期，重新获取判断是否需要检查
+	current, err := c.models.ClusterManager.GetById(cluster.ID)
+	if err != nil {
+		klog.Errorf("get cluster id=%d error: %s", cluster.ID, err.Error())
+		return err
+	}
+	if !current.HealthCheckDue() {
+		return nil
+	}
+	health, err := c.healthService.Probe(current)
+	if err != nil {
+		klog.Warningf("probe cluster id=%d name=%s health error: %s", current.ID, current.Name1, err.Error())
+		return err
+	}
+	klog.V(1).Infof("cluster id=%d name=%s health status: %s", current.ID, current.Name1, health.Status)
+	return nil
+}
@@ -14,7 +14,7 @@ type Config struct {
 	ServiceFactory  *service.Factory
 }
 
-func NewConfig(dbConfig *db.Config, resyncSec int) (*Config, error) {
+func NewConfig(dbConfig *db.Config, resyncSec int, encryptionKey string) (*Config, error) {
 	dB, err := db.NewDB(dbConfig)
 	if err != nil {
 		return nil, err
@@ -23,6 +23,7 @@ func NewConfig(dbConfig *db.Config, resyncSec int) (*Config, error) {
 	models, err := model.NewModels(&model.Config{
 		DB:                dB,
 		ListWatcherConfig: listWatcherConfig,
+		EncryptionKey:     encryptionKey,
 	})
 	if err != nil {
 		return nil, err
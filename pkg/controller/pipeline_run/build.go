@@ -63,12 +63,20 @@ func (p *PipelineRunController) build(object interface{}) (err error) {
 		if err != nil {
 			klog.Errorf("get pipeline run id=%d next stage error, current stage id %d", pipelineRun.ID, prevStageId)
 			pipelineRun.Status = types.PipelineStatusError
-			return p.models.PipelineRunManager.UpdatePipelineRun(&pipelineRun)
+			if err = p.models.PipelineRunManager.UpdatePipelineRun(&pipelineRun); err != nil {
+				return err
+			}
+			p.notify(&pipelineRun, types.NotificationEventPipelineRunFailed, nil)
+			return nil
 		}
 		if nextStage == nil {
 			// 下一个阶段为空，表示流水线构建已执行完成，状态置为ok
 			pipelineRun.Status = types.PipelineStatusOK
-			return p.models.PipelineRunManager.UpdatePipelineRun(&pipelineRun)
+			if err = p.models.PipelineRunManager.UpdatePipelineRun(&pipelineRun); err != nil {
+				return err
+			}
+			p.notify(&pipelineRun, types.NotificationEventPipelineRunSucceeded, nil)
+			return nil
 		}
 		if nextStage.Status == types.PipelineStatusOK {
 			// 阶段状态ok，执行下一个阶段
@@ -82,6 +90,9 @@ func (p *PipelineRunController) build(object interface{}) (err error) {
 		nextStage, _ = p.models.PipelineRunManager.GetStageRun(nextStage.ID)
 		if nextStage.Status != types.PipelineStatusOK {
 			// 当前阶段执行不成功，退出
+			if nextStage.Status == types.PipelineStatusError {
+				p.notify(&pipelineRun, types.NotificationEventPipelineRunFailed, nextStage)
+			}
 			return nil
 		}
 		prevStageId = nextStage.ID
@@ -98,8 +109,12 @@ func (p *PipelineRunController) executeStage(stageRun *types.PipelineRunStage) (
 			StageRunStatus: types.PipelineStatusPause,
 		}); err != nil {
 			klog.Errorf("update stage id=%d status to pause error: %v", stageRun.ID, err)
+			return err
 		}
-		return err
+		if pipelineRun, err := p.models.PipelineRunManager.Get(stageRun.PipelineRunId); err == nil {
+			p.notify(pipelineRun, types.NotificationEventPipelineStageManual, stageRun)
+		}
+		return nil
 	}
 	// 获取当前阶段之前的所有参数，并赋值给当前阶段
 	envs, _ := p.models.PipelineRunManager.GetEnvBeforeStageRun(stageRun)
@@ -8,6 +8,7 @@ import (
 	pipelinelistwatcher "github.com/kubespace/kubespace/pkg/informer/listwatcher/pipeline"
 	"github.com/kubespace/kubespace/pkg/model"
 	"github.com/kubespace/kubespace/pkg/model/types"
+	"github.com/kubespace/kubespace/pkg/service/notification"
 )
 
 // PipelineRunController 流水线构建controller
@@ -18,11 +19,14 @@ type PipelineRunController struct {
 	lock lock.Lock
 	// 任务执行处理
 	jobRun *job_run.JobRun
+	// 构建成功、失败以及等待手动执行时发送通知
+	notificationService *notification.NotificationService
 }
 
 func NewPipelineRunController(config *controller.Config) *PipelineRunController {
 
-	jobRun := job_run.NewJobRun(config.Models, config.ServiceFactory.Cluster.KubeClient, config.InformerFactory)
+	jobRun := job_run.NewJobRun(config.Models, config.ServiceFactory.Cluster.KubeClient,
+		config.ServiceFactory.Project.AppService, config.ServiceFactory.Pipeline.SpaceletService, config.InformerFactory)
 
 	// 监听未构建完成以及要取消的pipelineRun
 	pipelineRunInformer := config.InformerFactory.PipelineRunInformer(&pipelinelistwatcher.PipelineRunWatchCondition{
@@ -35,6 +39,8 @@ func NewPipelineRunController(config *controller.Config) *PipelineRunController
 		pipelineRunInformer: pipelineRunInformer,
 		lock:                lock.NewMemLock(),
 		jobRun:              jobRun,
+
+		notificationService: config.ServiceFactory.Notification.NotificationService,
 	}
 	// 流水线构建handler
 	pipelineRunInformer.AddHandler(&informer.ResourceHandler{
@@ -10,6 +10,8 @@ import (
 	"github.com/kubespace/kubespace/pkg/service/cluster"
 	"github.com/kubespace/kubespace/pkg/service/pipeline/job_runner"
 	"github.com/kubespace/kubespace/pkg/service/pipeline/job_runner/plugins"
+	"github.com/kubespace/kubespace/pkg/service/project"
+	spaceletservice "github.com/kubespace/kubespace/pkg/service/spacelet"
 	"github.com/kubespace/kubespace/pkg/utils"
 	"k8s.io/klog/v2"
 	"time"
@@ -22,24 +24,68 @@ type JobRun struct {
 	models    *model.Models
 }
 
-func NewJobRun(models *model.Models, kubeClient *cluster.KubeClient, informerFactory informer.Factory) *JobRun {
-	spacelet := SpaceletJob{models: models, informerFactory: informerFactory}
+func NewJobRun(models *model.Models, kubeClient *cluster.KubeClient, appService *project.AppService,
+	spaceletService *spaceletservice.SpaceletService, informerFactory informer.Factory) *JobRun {
+	spacelet := scheduleJobFactory{
+		models:     models,
+		spacelet:   NewSpaceletJob(models, spaceletService, informerFactory),
+		kubernetes: KubernetesJob{models: models, kubeClient: kubeClient},
+	}
 	return &JobRun{
 		jobRunner: job_runner.NewJobRunner(),
 		models:    models,
 		plugins: map[string]plugins.ExecutorFactory{
-			// 比较消耗资源的任务，通过spacelet代理执行
-			types.BuiltinPluginBuildCodeToImage: spacelet,
-			types.BuiltinPluginExecuteShell:     spacelet,
-			types.BuiltinPluginRelease:          spacelet,
+			// 比较消耗资源的任务，根据调度策略通过spacelet代理或者在kubernetes集群中执行
+			types.BuiltinPluginBuildCodeToImage: codeBuilderFactory{
+				spacelet: spacelet,
+				kaniko:   plugins.KanikoCodeBuilderPlugin{Models: models, KubeClient: kubeClient},
+			},
+			types.BuiltinPluginExecuteShell: spacelet,
+			types.BuiltinPluginRelease:      spacelet,
 
 			// 轻任务直接在controller内部执行
 			types.BuiltinPluginDeployK8s:  plugins.DeployK8sPlugin{Models: models, KubeClient: kubeClient},
-			types.BuiltinPluginUpgradeApp: plugins.UpgradeAppPlugin{Models: models, KubeClient: kubeClient},
+			types.BuiltinPluginUpgradeApp: plugins.UpgradeAppPlugin{Models: models, AppService: appService},
 		},
 	}
 }
 
+// scheduleJobFactory 根据任务的调度策略选择在spacelet节点或者kubernetes集群中执行任务
+type scheduleJobFactory struct {
+	models     *model.Models
+	spacelet   plugins.ExecutorFactory
+	kubernetes plugins.ExecutorFactory
+}
+
+func (f scheduleJobFactory) Executor(params *plugins.ExecutorParams) (plugins.Executor, error) {
+	jobRun, err := f.models.PipelineRunManager.GetJobRun(params.JobId)
+	if err != nil {
+		return nil, err
+	}
+	if jobRun.SchedulePolicy.IsKubernetes() {
+		return f.kubernetes.Executor(params)
+	}
+	return f.spacelet.Executor(params)
+}
+
+// codeBuilderFactory 代码构建镜像任务根据镜像构建方式选择执行位置
+// kaniko方式在指定集群中以Job运行，docker以及buildkit方式通过spacelet执行
+type codeBuilderFactory struct {
+	spacelet plugins.ExecutorFactory
+	kaniko   plugins.ExecutorFactory
+}
+
+func (f codeBuilderFactory) Executor(params *plugins.ExecutorParams) (plugins.Executor, error) {
+	var builderConfig *plugins.ImageBuilderConfig
+	if err := utils.ConvertTypeByJson(params.Params["image_builder"], &builderConfig); err != nil {
+		return nil, fmt.Errorf("镜像构建方式参数错误：%s", err.Error())
+	}
+	if builderConfig.GetType() == plugins.ImageBuilderKaniko {
+		return f.kaniko.Executor(params)
+	}
+	return f.spacelet.Executor(params)
+}
+
 func (b *JobRun) Execute(jobId uint, pluginKey string, params map[string]interface{}) (resp *utils.Response) {
 	if pluginKey == "" {
 		return &utils.Response{Code: code.PluginError, Msg: "not found plugin key parameter"}
@@ -0,0 +1,332 @@
+package job_run
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/kubespace/kubespace/pkg/core/code"
+	"github.com/kubespace/kubespace/pkg/core/errors"
+	kubetypes "github.com/kubespace/kubespace/pkg/kubernetes/types"
+	"github.com/kubespace/kubespace/pkg/model"
+	"github.com/kubespace/kubespace/pkg/model/types"
+	"github.com/kubespace/kubespace/pkg/service/cluster"
+	"github.com/kubespace/kubespace/pkg/service/pipeline/job_runner/plugins"
+	"github.com/kubespace/kubespace/pkg/spacelet/pipeline_job"
+	"github.com/kubespace/kubespace/pkg/utils"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/klog/v2"
+	"path/filepath"
+	"sigs.k8s.io/yaml"
+	"strings"
+	"time"
+)
+
+const (
+	// DefaultPipelineJobPodImage 在kubernetes集群中执行任务的默认镜像
+	DefaultPipelineJobPodImage = "registry.cn-hangzhou.aliyuncs.com/kubespace/spacelet:latest"
+	// DefaultPipelineJobServerUrl 任务pod中调用kubespace接口的默认地址
+	DefaultPipelineJobServerUrl = "http://kubespace"
+
+	podJobParamsDir    = "/kubespace/params"
+	podJobWorkspaceDir = "/kubespace/workspace"
+
+	// podGetMaxFailures 连续获取任务pod失败的最大次数，超过后任务执行失败
+	podGetMaxFailures = 12
+)
+
+// KubernetesJob 在kubernetes集群中以pod方式执行pipeline job
+// pod中通过spacelet --job-params执行任务插件，日志输出到标准输出，任务结果写入容器终止信息，
+// 同一次流水线构建的任务共享pvc中的构建目录
+type KubernetesJob struct {
+	models     *model.Models
+	kubeClient *cluster.KubeClient
+}
+
+// Executor 根据任务的kubernetes调度策略创建执行器
+func (k KubernetesJob) Executor(params *plugins.ExecutorParams) (plugins.Executor, error) {
+	jobRun, err := k.models.PipelineRunManager.GetJobRun(params.JobId)
+	if err != nil {
+		return nil, err
+	}
+	if !jobRun.SchedulePolicy.IsKubernetes() || jobRun.SchedulePolicy.Kubernetes == nil {
+		return nil, errors.New(code.ParamsError, "not found kubernetes schedule policy")
+	}
+	policy := *jobRun.SchedulePolicy.Kubernetes
+	if policy.Cluster == "" || policy.Namespace == "" {
+		return nil, errors.New(code.ParamsError, "kubernetes schedule policy cluster or namespace is empty")
+	}
+	if policy.WorkspaceClaim == "" {
+		return nil, errors.New(code.ParamsError, "kubernetes schedule policy workspace claim is empty")
+	}
+	if policy.Image == "" {
+		policy.Image = DefaultPipelineJobPodImage
+	}
+	if policy.ServerUrl == "" {
+		policy.ServerUrl = DefaultPipelineJobServerUrl
+	}
+	if len(policy.Requests) == 0 {
+		// 未单独配置pod资源请求时，使用任务调度策略的资源请求
+		policy.Requests = jobRun.SchedulePolicy.Requests
+	}
+	ctx, cancelFunc := context.WithCancel(context.Background())
+	return &kubernetesJob{
+		Logger:     params.Logger,
+		params:     params,
+		policy:     &policy,
+		runId:      jobRun.PipelineRunId,
+		kubeClient: k.kubeClient,
+		name:       fmt.Sprintf("kubespace-pipeline-job-%d", params.JobId),
+		ctx:        ctx,
+		cancelFunc: cancelFunc,
+	}, nil
+}
+
+type kubernetesJob struct {
+	plugins.Logger
+	params     *plugins.ExecutorParams
+	policy     *types.PipelineJobKubernetesPolicy
+	runId      uint
+	kubeClient *cluster.KubeClient
+	// 任务pod以及参数secret名称
+	name       string
+	canceled   bool
+	ctx        context.Context
+	cancelFunc context.CancelFunc
+}
+
+func (k *kubernetesJob) Execute() (interface{}, error) {
+	// 清理之前执行残留的pod，如controller重启后任务重新执行
+	k.cleanup()
+	defer k.cleanup()
+
+	if err := k.createParamsSecret(); err != nil {
+		k.Log("创建任务参数失败：%v", err)
+		return nil, err
+	}
+	pod, err := k.podSpec()
+	if err != nil {
+		k.Log("任务pod配置错误：%v", err)
+		return nil, err
+	}
+	k.Log("在集群%s中创建任务pod %s/%s", k.policy.Cluster, k.policy.Namespace, k.name)
+	if err = k.apply(pod); err != nil {
+		k.Log("创建任务pod失败：%v", err)
+		return nil, err
+	}
+	return k.wait()
+}
+
+// Cancel 删除任务pod取消执行
+func (k *kubernetesJob) Cancel() error {
+	klog.Infof("cancel kubernetes job id=%d", k.params.JobId)
+	k.canceled = true
+	k.cancelFunc()
+	k.cleanup()
+	return nil
+}
+
+// wait 等待任务pod执行完成，pod启动之后将日志写入任务日志。
+// pod被驱逐、删除或者回收后不存在，以及连续多次获取pod失败时，任务执行失败
+func (k *kubernetesJob) wait() (interface{}, error) {
+	logged := false
+	failures := 0
+	tick := time.NewTicker(SpaceletJobStatusInterval)
+	defer tick.Stop()
+	for {
+		select {
+		case <-k.ctx.Done():
+			return nil, nil
+		case <-tick.C:
+		}
+		resp := k.kubeClient.Get(k.policy.Cluster, kubetypes.PodType, map[string]interface{}{
+			"name":      k.name,
+			"namespace": k.policy.Namespace,
+		})
+		if !resp.IsSuccess() {
+			if k.canceled {
+				return nil, nil
+			}
+			klog.Errorf("get job=%d pod error: %s", k.params.JobId, resp.Msg)
+			if podNotFound(k.name, resp.Msg) {
+				k.Log("任务pod %s/%s不存在，可能已被驱逐或者删除", k.policy.Namespace, k.name)
+				return nil, errors.New(code.PluginError, "job pod not found: "+resp.Msg)
+			}
+			if failures++; failures >= podGetMaxFailures {
+				k.Log("连续%d次获取任务pod失败：%s", failures, resp.Msg)
+				return nil, errors.New(code.PluginError, "get job pod error: "+resp.Msg)
+			}
+			continue
+		}
+		failures = 0
+		var pod corev1.Pod
+		if err := utils.ConvertTypeByJson(resp.Data, &pod); err != nil {
+			klog.Errorf("convert job=%d pod error: %s", k.params.JobId, err.Error())
+			continue
+		}
+		if pod.Status.Phase == corev1.PodPending {
+			continue
+		}
+		if !logged {
+			if err := plugins.StreamPodLog(k.ctx, k.kubeClient, k.policy.Cluster, &pod, k.Logger); err != nil {
+				klog.Errorf("stream job=%d pod log error: %s", k.params.JobId, err.Error())
+			} else {
+				logged = true
+			}
+		}
+		if pod.Status.Phase == corev1.PodSucceeded || pod.Status.Phase == corev1.PodFailed {
+			return k.result(&pod)
+		}
+	}
+}
+
+// podNotFound 获取pod返回的错误是否为pod不存在
+func podNotFound(name, msg string) bool {
+	return strings.Contains(msg, fmt.Sprintf("pods %q not found", name))
+}
+
+// result 从容器终止信息中获取任务执行结果
+func (k *kubernetesJob) result(pod *corev1.Pod) (interface{}, error) {
+	// 等待日志输出完成
+	time.Sleep(time.Second)
+	for _, status := range pod.Status.ContainerStatuses {
+		if status.State.Terminated == nil || status.State.Terminated.Message == "" {
+			continue
+		}
+		message := status.State.Terminated.Message
+		var statusResult pipeline_job.StatusResult
+		if err := json.Unmarshal([]byte(message), &statusResult); err != nil {
+			if len(message) >= pipeline_job.PodTerminationMessageMaxSize {
+				k.Log("任务执行结果超过容器终止信息%d字节的限制被截断", pipeline_job.PodTerminationMessageMaxSize)
+				return nil, errors.New(code.PluginError, "job result is truncated by termination message size limit")
+			}
+			k.Log("解析任务执行结果失败：%v", err)
+			return nil, err
+		}
+		if statusResult.Status == types.PipelineStatusOK && statusResult.Result != nil {
+			return statusResult.Result.Data, nil
+		}
+		if statusResult.Result != nil {
+			return nil, errors.New(statusResult.Result.Code, statusResult.Result.Msg)
+		}
+	}
+	if pod.Status.Phase == corev1.PodSucceeded {
+		return nil, nil
+	}
+	k.Log("任务pod执行失败：%s", pod.Status.Reason)
+	return nil, errors.New(code.PluginError, "job pod failed: "+pod.Status.Reason)
+}
+
+func (k *kubernetesJob) labels() map[string]string {
+	return map[string]string{plugins.PipelineJobIdLabel: fmt.Sprintf("%d", k.params.JobId)}
+}
+
+// createParamsSecret 任务参数可能包含认证信息，通过secret挂载到pod中
+func (k *kubernetesJob) createParamsSecret() error {
+	jobParams, err := json.Marshal(&pipeline_job.JobRunParams{
+		JobId:  k.params.JobId,
+		Plugin: k.params.PluginKey,
+		Params: k.params.Params,
+	})
+	if err != nil {
+		return err
+	}
+	return k.apply(&corev1.Secret{
+		TypeMeta: metav1.TypeMeta{APIVersion: "v1", Kind: "Secret"},
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      k.name,
+			Namespace: k.policy.Namespace,
+			Labels:    k.labels(),
+		},
+		Type:       corev1.SecretTypeOpaque,
+		StringData: map[string]string{"params.json": string(jobParams)},
+	})
+}
+
+func (k *kubernetesJob) podSpec() (*corev1.Pod, error) {
+	requests, err := resourceList(k.policy.Requests)
+	if err != nil {
+		return nil, err
+	}
+	limits, err := resourceList(k.policy.Limits)
+	if err != nil {
+		return nil, err
+	}
+	return &corev1.Pod{
+		TypeMeta: metav1.TypeMeta{APIVersion: "v1", Kind: "Pod"},
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      k.name,
+			Namespace: k.policy.Namespace,
+			Labels:    k.labels(),
+		},
+		Spec: corev1.PodSpec{
+			RestartPolicy: corev1.RestartPolicyNever,
+			NodeSelector:  k.policy.NodeSelector,
+			Containers: []corev1.Container{{
+				Name:    "job",
+				Image:   k.policy.Image,
+				Command: []string{"/spacelet"},
+				Args: []string{
+					"--job-params=" + podJobParamsDir + "/params.json",
+					"--data-dir=" + filepath.Join(podJobWorkspaceDir, pipeline_job.PodWorkspace(k.runId)),
+					"--server-url=" + k.policy.ServerUrl,
+				},
+				Resources:                corev1.ResourceRequirements{Requests: requests, Limits: limits},
+				TerminationMessagePath:   pipeline_job.PodTerminationMessagePath,
+				TerminationMessagePolicy: corev1.TerminationMessageReadFile,
+				VolumeMounts: []corev1.VolumeMount{
+					{Name: "params", MountPath: podJobParamsDir, ReadOnly: true},
+					{Name: "workspace", MountPath: podJobWorkspaceDir},
+				},
+			}},
+			Volumes: []corev1.Volume{
+				{Name: "params", VolumeSource: corev1.VolumeSource{Secret: &corev1.SecretVolumeSource{SecretName: k.name}}},
+				{Name: "workspace", VolumeSource: corev1.VolumeSource{PersistentVolumeClaim: &corev1.PersistentVolumeClaimVolumeSource{
+					ClaimName: k.policy.WorkspaceClaim,
+				}}},
+			},
+		},
+	}, nil
+}
+
+func (k *kubernetesJob) apply(obj interface{}) error {
+	yamlBytes, err := yaml.Marshal(obj)
+	if err != nil {
+		return err
+	}
+	resp := k.kubeClient.Apply(k.policy.Cluster, map[string]interface{}{"yaml": string(yamlBytes)})
+	if !resp.IsSuccess() {
+		return fmt.Errorf("%s", resp.Msg)
+	}
+	return nil
+}
+
+// cleanup 删除任务pod以及参数secret
+func (k *kubernetesJob) cleanup() {
+	labelSelector := &metav1.LabelSelector{MatchLabels: k.labels()}
+	for _, resType := range []string{kubetypes.PodType, kubetypes.SecretType} {
+		resp := k.kubeClient.Delete(k.policy.Cluster, resType, map[string]interface{}{
+			"namespace":      k.policy.Namespace,
+			"label_selector": labelSelector,
+		})
+		if !resp.IsSuccess() {
+			klog.Errorf("delete job=%d %s error: %s", k.params.JobId, resType, resp.Msg)
+		}
+	}
+}
+
+func resourceList(resources map[string]string) (corev1.ResourceList, error) {
+	if len(resources) == 0 {
+		return nil, nil
+	}
+	list := corev1.ResourceList{}
+	for name, value := range resources {
+		quantity, err := resource.ParseQuantity(value)
+		if err != nil {
+			return nil, fmt.Errorf("resource %s=%s error: %s", name, value, err.Error())
+		}
+		list[corev1.ResourceName(name)] = quantity
+	}
+	return list, nil
+}
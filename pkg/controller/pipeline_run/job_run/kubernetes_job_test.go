@@ -0,0 +1,28 @@
+package job_run
+
+import (
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"testing"
+)
+
+func TestPodNotFound(t *testing.T) {
+	name := "kubespace-pipeline-job-1"
+	tests := []struct {
+		name string
+		msg  string
+		want bool
+	}{
+		{"not found", errors.NewNotFound(schema.GroupResource{Resource: "pods"}, name).Error(), true},
+		{"other pod not found", errors.NewNotFound(schema.GroupResource{Resource: "pods"}, name+"0").Error(), false},
+		{"other resource not found", errors.NewNotFound(schema.GroupResource{Resource: "secrets"}, name).Error(), false},
+		{"agent disconnected", "cluster agent is not connected", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := podNotFound(name, tt.msg); got != tt.want {
+				t.Errorf("podNotFound(%q) = %v, want %v", tt.msg, got, tt.want)
+			}
+		})
+	}
+}
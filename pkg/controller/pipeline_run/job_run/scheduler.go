@@ -0,0 +1,453 @@
+package job_run
+
+import (
+	"context"
+	"fmt"
+	"github.com/kubespace/kubespace/pkg/core/code"
+	"github.com/kubespace/kubespace/pkg/core/errors"
+	"github.com/kubespace/kubespace/pkg/model"
+	"github.com/kubespace/kubespace/pkg/model/manager/pipeline"
+	spaceletmanager "github.com/kubespace/kubespace/pkg/model/manager/spacelet"
+	"github.com/kubespace/kubespace/pkg/model/types"
+	"k8s.io/klog/v2"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// SpaceletScheduleInterval 排队任务定时重新调度的间隔
+const SpaceletScheduleInterval = time.Second * 5
+
+// queueRecoverPeriod controller重启后，之前排队的任务在该时间内重新进入队列，期间为其保留调度顺序，
+// 避免新提交的任务抢占重启前已排队任务的容量
+const queueRecoverPeriod = time.Minute
+
+// jobRequests 任务占用spacelet节点的资源
+type jobRequests struct {
+	// cpu毫核
+	cpu int64
+	// 内存字节
+	memory int64
+}
+
+func parseJobRequests(policy *types.PipelineJobSchedulePolicy) (*jobRequests, error) {
+	if policy == nil {
+		return &jobRequests{}, nil
+	}
+	list, err := resourceList(policy.Requests)
+	if err != nil {
+		return nil, err
+	}
+	return &jobRequests{cpu: list.Cpu().MilliValue(), memory: list.Memory().Value()}, nil
+}
+
+// spaceletUsage spacelet节点当前已分配的任务以及资源
+type spaceletUsage struct {
+	jobs   int
+	cpu    int64
+	memory int64
+	// 节点上正在执行任务的流水线构建，用于反亲和调度
+	pipelineRuns map[uint]int
+}
+
+func (u *spaceletUsage) fit(sp *types.Spacelet, req *jobRequests) bool {
+	if sp.MaxJobs > 0 && u.jobs+1 > sp.MaxJobs {
+		return false
+	}
+	if sp.Cpu > 0 && u.cpu+req.cpu > sp.Cpu {
+		return false
+	}
+	if sp.Memory > 0 && u.memory+req.memory > sp.Memory {
+		return false
+	}
+	return true
+}
+
+func (u *spaceletUsage) add(pipelineRunId uint, req *jobRequests) {
+	u.jobs += 1
+	u.cpu += req.cpu
+	u.memory += req.memory
+	u.pipelineRuns[pipelineRunId] += 1
+}
+
+// queuedJob 等待调度的任务
+type queuedJob struct {
+	jobRun      *types.PipelineRunJob
+	workspaceId uint
+	requests    *jobRequests
+	// enqueueTime 开始排队的时间，controller重启后从任务的queueTime恢复
+	enqueueTime time.Time
+	// 当前排队位置
+	position int
+	// 调度成功后发送分配的spacelet节点，为空时为重启前排队、还未重新进入队列的任务，只保留容量
+	scheduledCh chan *types.Spacelet
+}
+
+// spaceletScheduler spacelet任务调度器
+// 任务超出spacelet节点容量（最大任务数、cpu、内存）时进入queued排队状态，
+// 有空闲容量时按照流水线空间公平调度，正在执行任务数少的空间优先，相同时先排队的任务优先
+type spaceletScheduler struct {
+	models    *model.Models
+	mu        sync.Mutex
+	queue     []*queuedJob
+	triggerCh chan struct{}
+	startOnce sync.Once
+	startTime time.Time
+}
+
+func newSpaceletScheduler(models *model.Models) *spaceletScheduler {
+	return &spaceletScheduler{
+		models:    models,
+		triggerCh: make(chan struct{}, 1),
+		startTime: time.Now(),
+	}
+}
+
+// Trigger 触发一次排队任务调度，任务执行完成释放容量后调用
+func (s *spaceletScheduler) Trigger() {
+	select {
+	case s.triggerCh <- struct{}{}:
+	default:
+	}
+}
+
+func (s *spaceletScheduler) run() {
+	tick := time.NewTicker(SpaceletScheduleInterval)
+	for {
+		select {
+		case <-tick.C:
+		case <-s.triggerCh:
+		}
+		s.mu.Lock()
+		if len(s.queue) > 0 {
+			if err := s.scheduleQueue(); err != nil {
+				klog.Errorf("schedule queued pipeline jobs error: %s", err.Error())
+			}
+		}
+		s.mu.Unlock()
+	}
+}
+
+// Schedule 为任务分配spacelet节点，节点容量不足时排队等待，直到调度成功或者ctx取消
+func (s *spaceletScheduler) Schedule(ctx context.Context, jobRun *types.PipelineRunJob) (*types.Spacelet, error) {
+	s.startOnce.Do(func() { go s.run() })
+
+	requests, err := parseJobRequests(jobRun.SchedulePolicy)
+	if err != nil {
+		return nil, errors.New(code.ParamsError, err)
+	}
+	// 先检查是否有满足调度策略的节点，没有则直接返回失败
+	candidates, err := s.candidates(jobRun)
+	if err != nil {
+		return nil, err
+	}
+	fitAny := false
+	for _, sp := range candidates {
+		if (&spaceletUsage{pipelineRuns: map[uint]int{}}).fit(sp, requests) {
+			fitAny = true
+			break
+		}
+	}
+	if !fitAny {
+		return nil, errors.New(code.StatusError, "no spacelet node has enough capacity for job requests")
+	}
+	job := &queuedJob{
+		jobRun:      jobRun,
+		workspaceId: s.workspaceId(jobRun.PipelineRunId),
+		requests:    requests,
+		enqueueTime: time.Now(),
+		scheduledCh: make(chan *types.Spacelet, 1),
+	}
+	if jobRun.QueueTime != nil {
+		// 重启前已经在排队的任务，按照原先的排队时间调度
+		job.enqueueTime = *jobRun.QueueTime
+	}
+	s.mu.Lock()
+	s.queue = append(s.queue, job)
+	err = s.scheduleQueue()
+	s.mu.Unlock()
+	if err != nil {
+		s.dequeue(job)
+		return nil, err
+	}
+	select {
+	case sp := <-job.scheduledCh:
+		return sp, nil
+	case <-ctx.Done():
+		s.dequeue(job)
+		// 调度与取消同时发生时，释放已分配的节点
+		select {
+		case <-job.scheduledCh:
+			s.Trigger()
+		default:
+		}
+		return nil, ctx.Err()
+	}
+}
+
+func (s *spaceletScheduler) dequeue(job *queuedJob) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for i, j := range s.queue {
+		if j == job {
+			s.queue = append(s.queue[:i], s.queue[i+1:]...)
+			return
+		}
+	}
+}
+
+// candidates 根据任务调度策略中的主机名以及标签选择在线且未排空的spacelet节点
+func (s *spaceletScheduler) candidates(jobRun *types.PipelineRunJob) ([]*types.Spacelet, error) {
+	policy := jobRun.SchedulePolicy
+	spacelets, err := s.models.SpaceletManager.List(&spaceletmanager.SpaceletListCondition{
+		Status: types.SpaceletStatusOnline,
+	})
+	if err != nil {
+		return nil, err
+	}
+	// 排空的节点不再调度新任务
+	var schedulable []*types.Spacelet
+	for i, sp := range spacelets {
+		if sp.Schedulable() {
+			schedulable = append(schedulable, spacelets[i])
+		}
+	}
+	spacelets = schedulable
+	if policy != nil && policy.Hostname != "" {
+		for _, sp := range spacelets {
+			if sp.Hostname == policy.Hostname {
+				return []*types.Spacelet{sp}, nil
+			}
+		}
+		return nil, errors.New(code.DataNotExists, "not found schedulable spacelet node hostname="+policy.Hostname)
+	}
+	if policy != nil && len(policy.SpaceletSelector) > 0 {
+		var matchSpacelets []*types.Spacelet
+		for i, sp := range spacelets {
+			if matchSelector(sp.Labels, policy.SpaceletSelector) {
+				matchSpacelets = append(matchSpacelets, spacelets[i])
+			}
+		}
+		if len(matchSpacelets) == 0 {
+			var selectorStr []string
+			for sk, sv := range policy.SpaceletSelector {
+				selectorStr = append(selectorStr, fmt.Sprintf("%s=%v", sk, sv))
+			}
+			return nil, errors.New(code.DataNotExists, "no spacelet node with selector: "+strings.Join(selectorStr, ","))
+		}
+		return matchSpacelets, nil
+	}
+	if len(spacelets) == 0 {
+		return nil, errors.New(code.DataNotExists, "no schedulable spacelet node")
+	}
+	return spacelets, nil
+}
+
+// workspaceId 获取任务所属的流水线空间
+func (s *spaceletScheduler) workspaceId(pipelineRunId uint) uint {
+	pipelineRun, err := s.models.PipelineRunManager.Get(pipelineRunId)
+	if err != nil {
+		klog.Errorf("get pipeline run id=%d error: %s", pipelineRunId, err.Error())
+		return 0
+	}
+	id, _ := strconv.ParseUint(fmt.Sprintf("%v", pipelineRun.Env[types.PipelineEnvWorkspaceId]), 10, 64)
+	return uint(id)
+}
+
+// usages 调用时需加锁，统计所有spacelet节点正在执行的任务占用的容量，以及每个流水线空间正在执行的任务数
+func (s *spaceletScheduler) usages() (map[uint]*spaceletUsage, map[uint]int, error) {
+	withSpacelet := true
+	runningJobs, err := s.models.PipelineRunManager.ListJobRun(&pipeline.JobRunListCondition{
+		WithSpacelet: &withSpacelet,
+		StatusIn:     []string{types.PipelineStatusWait, types.PipelineStatusDoing},
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	queuedIds := make(map[uint]bool)
+	for _, job := range s.queue {
+		queuedIds[job.jobRun.ID] = true
+	}
+	usages := make(map[uint]*spaceletUsage)
+	workspaceJobs := make(map[uint]int)
+	runWorkspaces := make(map[uint]uint)
+	for _, job := range runningJobs {
+		if queuedIds[job.ID] {
+			// 排队中的任务不占用节点容量
+			continue
+		}
+		usage, ok := usages[job.SpaceletId]
+		if !ok {
+			usage = &spaceletUsage{pipelineRuns: make(map[uint]int)}
+			usages[job.SpaceletId] = usage
+		}
+		requests, err := parseJobRequests(job.SchedulePolicy)
+		if err != nil {
+			requests = &jobRequests{}
+		}
+		usage.add(job.PipelineRunId, requests)
+		workspaceId, ok := runWorkspaces[job.PipelineRunId]
+		if !ok {
+			workspaceId = s.workspaceId(job.PipelineRunId)
+			runWorkspaces[job.PipelineRunId] = workspaceId
+		}
+		workspaceJobs[workspaceId] += 1
+	}
+	return usages, workspaceJobs, nil
+}
+
+// recoveringJobs 调用时需加锁，controller重启后一段时间内，获取重启前已排队但还未重新进入队列的任务
+func (s *spaceletScheduler) recoveringJobs() []*queuedJob {
+	if time.Since(s.startTime) > queueRecoverPeriod {
+		return nil
+	}
+	withSpacelet := false
+	jobs, err := s.models.PipelineRunManager.ListJobRun(&pipeline.JobRunListCondition{
+		WithSpacelet: &withSpacelet,
+		StatusIn:     []string{types.PipelineStatusQueued, types.PipelineStatusDoing},
+	})
+	if err != nil {
+		klog.Errorf("list queued pipeline jobs error: %s", err.Error())
+		return nil
+	}
+	queuedIds := make(map[uint]bool)
+	for _, job := range s.queue {
+		queuedIds[job.jobRun.ID] = true
+	}
+	var recovering []*queuedJob
+	for _, job := range jobs {
+		if job.QueueTime == nil || queuedIds[job.ID] {
+			continue
+		}
+		requests, err := parseJobRequests(job.SchedulePolicy)
+		if err != nil {
+			continue
+		}
+		recovering = append(recovering, &queuedJob{
+			jobRun:      job,
+			workspaceId: s.workspaceId(job.PipelineRunId),
+			requests:    requests,
+			enqueueTime: *job.QueueTime,
+		})
+	}
+	return recovering
+}
+
+// sortQueue 公平调度：正在执行任务数最少的流水线空间优先，相同时先排队的优先
+func sortQueue(pending []*queuedJob, workspaceJobs map[uint]int) {
+	sort.SliceStable(pending, func(i, j int) bool {
+		wi, wj := workspaceJobs[pending[i].workspaceId], workspaceJobs[pending[j].workspaceId]
+		if wi != wj {
+			return wi < wj
+		}
+		return pending[i].enqueueTime.Before(pending[j].enqueueTime)
+	})
+}
+
+// scheduleQueue 调度排队的任务，调用时需加锁
+func (s *spaceletScheduler) scheduleQueue() error {
+	usages, workspaceJobs, err := s.usages()
+	if err != nil {
+		return err
+	}
+	pending := append([]*queuedJob{}, s.queue...)
+	pending = append(pending, s.recoveringJobs()...)
+	var waiting []*queuedJob
+	for len(pending) > 0 {
+		sortQueue(pending, workspaceJobs)
+		job := pending[0]
+		pending = pending[1:]
+		sp, err := s.selectSpacelet(job, usages)
+		if err != nil {
+			klog.Errorf("select spacelet for job id=%d error: %s", job.jobRun.ID, err.Error())
+		}
+		if sp == nil {
+			if job.scheduledCh != nil {
+				waiting = append(waiting, job)
+			}
+			continue
+		}
+		usage, ok := usages[sp.ID]
+		if !ok {
+			usage = &spaceletUsage{pipelineRuns: make(map[uint]int)}
+			usages[sp.ID] = usage
+		}
+		usage.add(job.jobRun.PipelineRunId, job.requests)
+		workspaceJobs[job.workspaceId] += 1
+		if job.scheduledCh == nil {
+			// 重启前排队的任务还未重新进入队列，只为其保留容量
+			continue
+		}
+		if _, err = s.models.PipelineRunManager.UpdateJobRunWithStatus(job.jobRun.ID,
+			[]string{types.PipelineStatusDoing, types.PipelineStatusQueued},
+			map[string]interface{}{"status": types.PipelineStatusDoing, "queue_position": 0, "queue_time": nil, "spacelet_id": sp.ID}); err != nil {
+			klog.Errorf("update job id=%d schedule status error: %s", job.jobRun.ID, err.Error())
+		}
+		job.scheduledCh <- sp
+	}
+	s.queue = waiting
+	// 更新排队任务的排队位置，并记录开始排队的时间，controller重启后按照该时间恢复排队顺序
+	for i, job := range waiting {
+		if job.position == i+1 {
+			continue
+		}
+		job.position = i + 1
+		klog.Infof("pipeline job id=%d queued, position=%d", job.jobRun.ID, job.position)
+		if _, err = s.models.PipelineRunManager.UpdateJobRunWithStatus(job.jobRun.ID,
+			[]string{types.PipelineStatusDoing, types.PipelineStatusQueued},
+			map[string]interface{}{"status": types.PipelineStatusQueued, "queue_position": job.position, "queue_time": job.enqueueTime, "spacelet_id": 0}); err != nil {
+			klog.Errorf("update job id=%d queue position error: %s", job.jobRun.ID, err.Error())
+		}
+	}
+	return nil
+}
+
+func (s *spaceletScheduler) selectSpacelet(job *queuedJob, usages map[uint]*spaceletUsage) (*types.Spacelet, error) {
+	candidates, err := s.candidates(job.jobRun)
+	if err != nil {
+		return nil, err
+	}
+	return pickSpacelet(job, candidates, usages), nil
+}
+
+// pickSpacelet 在满足调度策略以及容量的节点中选择执行任务数最少的节点
+// 配置反亲和时，不选择正在执行同一次构建任务的节点，否则优先选择同一次构建任务较少的节点
+func pickSpacelet(job *queuedJob, candidates []*types.Spacelet, usages map[uint]*spaceletUsage) *types.Spacelet {
+	antiAffinity := job.jobRun.SchedulePolicy != nil && job.jobRun.SchedulePolicy.AntiAffinity
+	var selected *types.Spacelet
+	var selectedUsage *spaceletUsage
+	for i, sp := range candidates {
+		usage, ok := usages[sp.ID]
+		if !ok {
+			usage = &spaceletUsage{pipelineRuns: make(map[uint]int)}
+		}
+		if !usage.fit(sp, job.requests) {
+			continue
+		}
+		sameRun := usage.pipelineRuns[job.jobRun.PipelineRunId]
+		if antiAffinity && sameRun > 0 {
+			continue
+		}
+		if selected == nil ||
+			sameRun < selectedUsage.pipelineRuns[job.jobRun.PipelineRunId] ||
+			(sameRun == selectedUsage.pipelineRuns[job.jobRun.PipelineRunId] && usage.jobs < selectedUsage.jobs) {
+			selected = candidates[i]
+			selectedUsage = usage
+		}
+	}
+	return selected
+}
+
+func matchSelector(labels, selector map[string]string) bool {
+	for sk, sv := range selector {
+		if _, ok := labels[sk]; !ok {
+			return false
+		}
+		if labels[sk] != sv {
+			return false
+		}
+	}
+	return true
+}
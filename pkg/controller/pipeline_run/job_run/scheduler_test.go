@@ -0,0 +1,195 @@
+package job_run
+
+import (
+	"github.com/kubespace/kubespace/pkg/model/types"
+	"testing"
+	"time"
+)
+
+func newUsage(jobs int, cpu, memory int64, pipelineRuns map[uint]int) *spaceletUsage {
+	if pipelineRuns == nil {
+		pipelineRuns = map[uint]int{}
+	}
+	return &spaceletUsage{jobs: jobs, cpu: cpu, memory: memory, pipelineRuns: pipelineRuns}
+}
+
+func TestSpaceletUsageFit(t *testing.T) {
+	tests := []struct {
+		name     string
+		spacelet *types.Spacelet
+		usage    *spaceletUsage
+		requests *jobRequests
+		want     bool
+	}{
+		{
+			name:     "unlimited capacity",
+			spacelet: &types.Spacelet{},
+			usage:    newUsage(100, 100000, 1<<40, nil),
+			requests: &jobRequests{cpu: 1000, memory: 1 << 30},
+			want:     true,
+		},
+		{
+			name:     "max jobs reached",
+			spacelet: &types.Spacelet{MaxJobs: 2},
+			usage:    newUsage(2, 0, 0, nil),
+			requests: &jobRequests{},
+			want:     false,
+		},
+		{
+			name:     "cpu exceeded",
+			spacelet: &types.Spacelet{Cpu: 4000},
+			usage:    newUsage(1, 3500, 0, nil),
+			requests: &jobRequests{cpu: 1000},
+			want:     false,
+		},
+		{
+			name:     "memory exceeded",
+			spacelet: &types.Spacelet{Memory: 8 << 30},
+			usage:    newUsage(1, 0, 7<<30, nil),
+			requests: &jobRequests{memory: 2 << 30},
+			want:     false,
+		},
+		{
+			name:     "exactly fit",
+			spacelet: &types.Spacelet{MaxJobs: 2, Cpu: 4000, Memory: 8 << 30},
+			usage:    newUsage(1, 3000, 6<<30, nil),
+			requests: &jobRequests{cpu: 1000, memory: 2 << 30},
+			want:     true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.usage.fit(tt.spacelet, tt.requests); got != tt.want {
+				t.Errorf("fit() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPickSpacelet(t *testing.T) {
+	spacelets := []*types.Spacelet{
+		{ID: 1, MaxJobs: 2},
+		{ID: 2, MaxJobs: 2},
+		{ID: 3, MaxJobs: 2},
+	}
+	tests := []struct {
+		name         string
+		antiAffinity bool
+		usages       map[uint]*spaceletUsage
+		want         uint
+	}{
+		{
+			name: "least jobs",
+			usages: map[uint]*spaceletUsage{
+				1: newUsage(1, 0, 0, map[uint]int{9: 1}),
+				2: newUsage(1, 0, 0, map[uint]int{9: 1}),
+			},
+			want: 3,
+		},
+		{
+			name: "prefer node with fewer jobs of the same pipeline run",
+			usages: map[uint]*spaceletUsage{
+				1: newUsage(1, 0, 0, map[uint]int{1: 1}),
+				2: newUsage(0, 0, 0, nil),
+				3: newUsage(0, 0, 0, nil),
+			},
+			want: 2,
+		},
+		{
+			name: "skip full nodes",
+			usages: map[uint]*spaceletUsage{
+				1: newUsage(2, 0, 0, nil),
+				2: newUsage(2, 0, 0, nil),
+				3: newUsage(1, 0, 0, map[uint]int{1: 1}),
+			},
+			want: 3,
+		},
+		{
+			name:         "anti affinity skips nodes running the same pipeline run",
+			antiAffinity: true,
+			usages: map[uint]*spaceletUsage{
+				1: newUsage(0, 0, 0, nil),
+				2: newUsage(1, 0, 0, map[uint]int{1: 1}),
+				3: newUsage(1, 0, 0, map[uint]int{1: 1}),
+			},
+			want: 1,
+		},
+		{
+			name:         "anti affinity without available node",
+			antiAffinity: true,
+			usages: map[uint]*spaceletUsage{
+				1: newUsage(2, 0, 0, nil),
+				2: newUsage(1, 0, 0, map[uint]int{1: 1}),
+				3: newUsage(1, 0, 0, map[uint]int{1: 1}),
+			},
+			want: 0,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			job := &queuedJob{
+				jobRun: &types.PipelineRunJob{
+					PipelineRunId:  1,
+					SchedulePolicy: &types.PipelineJobSchedulePolicy{AntiAffinity: tt.antiAffinity},
+				},
+				requests: &jobRequests{},
+			}
+			var got uint
+			if sp := pickSpacelet(job, spacelets, tt.usages); sp != nil {
+				got = sp.ID
+			}
+			if got != tt.want {
+				t.Errorf("pickSpacelet() = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSortQueue(t *testing.T) {
+	now := time.Now()
+	job := func(id, workspaceId uint, enqueue time.Duration) *queuedJob {
+		return &queuedJob{
+			jobRun:      &types.PipelineRunJob{ID: id},
+			workspaceId: workspaceId,
+			enqueueTime: now.Add(enqueue),
+		}
+	}
+	tests := []struct {
+		name          string
+		pending       []*queuedJob
+		workspaceJobs map[uint]int
+		want          []uint
+	}{
+		{
+			name:    "first in first out",
+			pending: []*queuedJob{job(1, 1, 2*time.Second), job(2, 1, time.Second), job(3, 1, 3*time.Second)},
+			want:    []uint{2, 1, 3},
+		},
+		{
+			name:          "workspace with fewer running jobs first",
+			pending:       []*queuedJob{job(1, 1, 0), job(2, 2, time.Second), job(3, 1, 2*time.Second)},
+			workspaceJobs: map[uint]int{1: 3, 2: 1},
+			want:          []uint{2, 1, 3},
+		},
+		{
+			// controller重启后按照持久化的排队时间恢复顺序，先排队的任务优先
+			name:    "recovered job keeps original enqueue time",
+			pending: []*queuedJob{job(1, 1, 0), job(2, 1, -time.Hour)},
+			want:    []uint{2, 1},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sortQueue(tt.pending, tt.workspaceJobs)
+			var got []uint
+			for _, j := range tt.pending {
+				got = append(got, j.jobRun.ID)
+			}
+			for i := range tt.want {
+				if got[i] != tt.want[i] {
+					t.Fatalf("sortQueue() = %v, want %v", got, tt.want)
+				}
+			}
+		})
+	}
+}
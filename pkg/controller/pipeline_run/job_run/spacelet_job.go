@@ -1,191 +1,93 @@
 package job_run
 
 import (
-	"fmt"
+	"context"
 	"github.com/kubespace/kubespace/pkg/core/code"
 	"github.com/kubespace/kubespace/pkg/core/errors"
 	"github.com/kubespace/kubespace/pkg/informer"
 	pipelinelistwatcher "github.com/kubespace/kubespace/pkg/informer/listwatcher/pipeline"
 	"github.com/kubespace/kubespace/pkg/model"
-	"github.com/kubespace/kubespace/pkg/model/manager"
-	"github.com/kubespace/kubespace/pkg/model/manager/pipeline"
-	spaceletmanager "github.com/kubespace/kubespace/pkg/model/manager/spacelet"
 	"github.com/kubespace/kubespace/pkg/model/types"
 	"github.com/kubespace/kubespace/pkg/service/pipeline/job_runner/plugins"
 	"github.com/kubespace/kubespace/pkg/service/spacelet"
 	"github.com/kubespace/kubespace/pkg/spacelet/pipeline_job"
 	"k8s.io/klog/v2"
-	"strings"
+	"sync"
 	"time"
 )
 
 const SpaceletJobStatusInterval = time.Second * 5
 
+// errJobRescheduled 任务所在spacelet节点失联，任务已被重新调度到其它节点
+var errJobRescheduled = errors.New(code.JobLost, "job rescheduled")
+
 // SpaceletJob spacelet节点执行pipeline job
 type SpaceletJob struct {
 	models          *model.Models
+	spaceletService *spacelet.SpaceletService
 	informerFactory informer.Factory
+	scheduler       *spaceletScheduler
 }
 
-// Executor 创建一个spaceletJob执行器，通过调用spacelet执行pipeline接口执行任务
-func (s SpaceletJob) Executor(params *plugins.ExecutorParams) (plugins.Executor, error) {
-	client, err := s.getSpaceletClient(params.JobId)
-	if err != nil {
-		return nil, err
+func NewSpaceletJob(models *model.Models, spaceletService *spacelet.SpaceletService, informerFactory informer.Factory) SpaceletJob {
+	return SpaceletJob{
+		models:          models,
+		spaceletService: spaceletService,
+		informerFactory: informerFactory,
+		scheduler:       newSpaceletScheduler(models),
 	}
-	return newSpaceletJob(client, params, s.informerFactory)
 }
 
-func (s SpaceletJob) getSpaceletClient(jobId uint) (spacelet.Client, error) {
+// Executor 创建一个spaceletJob执行器，调度到spacelet节点后，通过调用spacelet执行pipeline接口执行任务
+func (s SpaceletJob) Executor(params *plugins.ExecutorParams) (plugins.Executor, error) {
+	return newSpaceletJob(s, params), nil
+}
+
+// getSpacelet 获取任务执行的spacelet节点，如果节点容量不足，任务排队等待
+func (s SpaceletJob) getSpacelet(ctx context.Context, jobId uint) (*types.Spacelet, error) {
 	job, err := s.models.PipelineRunManager.GetJobRun(jobId)
 	if err != nil {
 		return nil, err
 	}
-	var sp *types.Spacelet
 	if job.SpaceletId != 0 {
 		oldSp, err := s.models.SpaceletManager.Get(job.SpaceletId)
 		if err != nil {
 			return nil, err
 		}
-		// 如果原先分配的spacelet节点已下线，则重新分配一个
-		if oldSp.Status == types.SpaceletStatusOnline {
-			sp = oldSp
-		}
-	}
-	if sp == nil {
-		// 分配一个执行任务数最少的spacelet节点
-		if sp, err = s.scheduleSpacelet(job); err != nil {
-			return nil, err
-		}
-		// 更新jobRun spaceletId
-		if err = s.models.PipelineRunManager.UpdateJobRun(jobId, &types.PipelineRunJob{SpaceletId: sp.ID}); err != nil {
-			return nil, err
-		}
-	}
-	return spacelet.NewClient(sp)
-}
-
-// scheduleSpacelet 根据job配置的spacelet调度策略选择节点
-func (s SpaceletJob) scheduleSpacelet(jobRun *types.PipelineRunJob) (*types.Spacelet, error) {
-	if jobRun.SchedulePolicy != nil && jobRun.SchedulePolicy.Hostname != "" {
-		spaceletObj, err := s.models.SpaceletManager.GetByHostname(jobRun.SchedulePolicy.Hostname, manager.NotFoundReturnNil)
-		if err != nil {
-			return nil, err
-		}
-		if spaceletObj == nil {
-			return nil, errors.New(code.DataNotExists, "not found spacelet node hostname="+jobRun.SchedulePolicy.Hostname)
-		}
-		if spaceletObj.Status == types.SpaceletStatusOffline {
-			return nil, errors.New(code.StatusError, fmt.Sprintf("spacelet node %s is offline", spaceletObj.Hostname))
-		}
-		return spaceletObj, nil
-	}
-	if jobRun.SchedulePolicy != nil && len(jobRun.SchedulePolicy.SpaceletSelector) > 0 {
-		return s.scheduleWithSelector(jobRun.SchedulePolicy.SpaceletSelector)
-	}
-	return s.scheduleAverageLeast(nil)
-}
-
-func (s SpaceletJob) scheduleWithSelector(selector map[string]string) (*types.Spacelet, error) {
-	// 查询所有在线的spacelet节点
-	spacelets, err := s.models.SpaceletManager.List(&spaceletmanager.SpaceletListCondition{
-		Status: types.SpaceletStatusOnline,
-	})
-	if err != nil {
-		return nil, err
-	}
-	var matchSpacelets []*types.Spacelet
-	for i, obj := range spacelets {
-		if s.matchSelector(obj.Labels, selector) {
-			matchSpacelets = append(matchSpacelets, spacelets[i])
-		}
-	}
-	if len(matchSpacelets) == 0 {
-		var selectorStr []string
-		for sk, sv := range selector {
-			selectorStr = append(selectorStr, fmt.Sprintf("%s=%v", sk, sv))
-		}
-		return nil, errors.New(code.DataNotExists, "no spacelet node with selector: "+strings.Join(selectorStr, ","))
-	}
-	return s.scheduleAverageLeast(matchSpacelets)
-}
-
-func (s SpaceletJob) matchSelector(labels, selector map[string]string) bool {
-	for sk, sv := range selector {
-		if _, ok := labels[sk]; !ok {
-			return false
-		}
-		if labels[sk] != sv {
-			return false
-		}
-	}
-	return true
-}
-
-func (s SpaceletJob) scheduleAverageLeast(spacelets []*types.Spacelet) (*types.Spacelet, error) {
-	var err error
-	if len(spacelets) == 0 {
-		// 查询所有在线的spacelet节点
-		spacelets, err = s.models.SpaceletManager.List(&spaceletmanager.SpaceletListCondition{
-			Status: types.SpaceletStatusOnline,
-		})
-		if err != nil {
-			return nil, err
-		}
-	}
-	if len(spacelets) == 0 {
-		return nil, errors.New(code.DataNotExists, "no online spacelet node")
-	}
-	withSpacelet := true
-	// 查询所有spacelet节点正在执行的pipeline job
-	spaceletJobs, err := s.models.PipelineRunManager.ListJobRun(&pipeline.JobRunListCondition{
-		WithSpacelet: &withSpacelet,
-		StatusIn:     []string{types.PipelineStatusWait, types.PipelineStatusDoing},
-	})
-	if err != nil {
-		return nil, err
-	}
-	// 每个spacelet节点执行的任务数，分配一个任务数最少的节点
-	spaceletJobMaps := make(map[uint]int)
-	for _, job := range spaceletJobs {
-		if _, ok := spaceletJobMaps[job.SpaceletId]; ok {
-			spaceletJobMaps[job.SpaceletId] += 1
-		} else {
-			spaceletJobMaps[job.SpaceletId] = 1
-		}
-	}
-	var leastSpacelet *types.Spacelet
-	var leastSpaceletNum = -1
-	// 节点任务数最少的spacelet
-	for i, sp := range spacelets {
-		num, _ := spaceletJobMaps[sp.ID]
-		if leastSpaceletNum == -1 || num < leastSpaceletNum {
-			leastSpaceletNum = num
-			leastSpacelet = spacelets[i]
+		// 如果原先分配的spacelet节点已下线或者排空，则重新分配一个
+		if oldSp.Schedulable() {
+			return oldSp, nil
 		}
 	}
-	if leastSpacelet == nil {
-		return nil, fmt.Errorf("no spacelet node")
-	}
-	return leastSpacelet, nil
+	// 根据调度策略以及节点容量分配spacelet节点，分配成功后会更新jobRun spaceletId
+	return s.scheduler.Schedule(ctx, job)
 }
 
 type spaceletJob struct {
 	plugins.Logger
+	factory         SpaceletJob
 	params          *plugins.ExecutorParams
+	spaceletId      uint
 	spaceletClient  spacelet.Client
 	watchCh         chan struct{}
 	informerFactory informer.Factory
+	// 任务排队调度时取消
+	ctx        context.Context
+	cancelFunc context.CancelFunc
+	mu         sync.Mutex
 }
 
-func newSpaceletJob(client spacelet.Client, params *plugins.ExecutorParams, informerFactory informer.Factory) (*spaceletJob, error) {
+func newSpaceletJob(factory SpaceletJob, params *plugins.ExecutorParams) *spaceletJob {
+	ctx, cancelFunc := context.WithCancel(context.Background())
 	return &spaceletJob{
+		factory:         factory,
 		params:          params,
-		spaceletClient:  client,
 		Logger:          params.Logger,
 		watchCh:         make(chan struct{}),
-		informerFactory: informerFactory,
-	}, nil
+		informerFactory: factory.informerFactory,
+		ctx:             ctx,
+		cancelFunc:      cancelFunc,
+	}
 }
 
 func (s *spaceletJob) Execute() (interface{}, error) {
@@ -202,11 +104,48 @@ func (s *spaceletJob) Execute() (interface{}, error) {
 	defer close(stopCh)
 	// 开始监听PipelineRunJob对象
 	go pipelineRunJobInformer.Run(stopCh)
+
+	for {
+		result, err := s.scheduleAndExecute()
+		if err != errJobRescheduled {
+			return result, err
+		}
+		// 任务所在节点失联，任务已被重新调度，重新分配节点执行
+		s.Log("任务执行所在spacelet节点失联，重新调度执行")
+	}
+}
+
+func (s *spaceletJob) scheduleAndExecute() (interface{}, error) {
+	sp, err := s.factory.getSpacelet(s.ctx, s.params.JobId)
+	if err != nil {
+		if s.ctx.Err() != nil {
+			// 排队时任务被取消
+			return nil, nil
+		}
+		return nil, err
+	}
+	client, err := s.factory.spaceletService.Client(sp)
+	if err != nil {
+		return nil, err
+	}
+	s.mu.Lock()
+	s.spaceletId = sp.ID
+	s.spaceletClient = client
+	s.mu.Unlock()
+	// 任务执行完成释放节点容量，触发排队任务调度
+	defer s.factory.scheduler.Trigger()
 	return s.execute()
 }
 
 func (s *spaceletJob) Cancel() error {
 	klog.Infof("cancel job id=%d", s.params.JobId)
+	s.cancelFunc()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.spaceletClient == nil {
+		// 任务还在排队，未调度到spacelet节点
+		return nil
+	}
 	if err := s.spaceletClient.PipelineJobCancel(&pipeline_job.JobCancelParams{JobId: s.params.JobId}); err != nil {
 		klog.Errorf("cancel pipeline job error: %s", err.Error())
 		return err
@@ -240,13 +179,22 @@ func (s *spaceletJob) execute() (interface{}, error) {
 			// 收到spacelet回调
 			klog.Infof("watch pipeline job changed and get pipeline job=%d status", s.params.JobId)
 		}
+		// 检查任务是否被spacelet controller判定为丢失
+		if err := s.checkLost(); err != nil {
+			return nil, err
+		}
 		// 查询spacelet节点任务状态接口
 		statusLog, err := s.spaceletClient.PipelineJobStatus(&pipeline_job.JobStatusParams{
 			JobId:   s.params.JobId,
 			WithLog: true,
 		})
 		if err != nil {
-			return nil, err
+			if s.spaceletAlive() {
+				return nil, err
+			}
+			// 节点失联，等待spacelet controller检测任务丢失后重新调度或者标记失败
+			klog.Errorf("get pipeline job=%d status from lost spacelet error: %s", s.params.JobId, err.Error())
+			continue
 		}
 		// 重置日志内容
 		s.Logger.Reset(statusLog.Log)
@@ -259,6 +207,35 @@ func (s *spaceletJob) execute() (interface{}, error) {
 	}
 }
 
+// checkLost 任务丢失后被重新调度返回errJobRescheduled，被标记为失败时返回失败原因
+func (s *spaceletJob) checkLost() error {
+	jobRun, err := s.factory.models.PipelineRunManager.GetJobRun(s.params.JobId)
+	if err != nil {
+		klog.Errorf("get job run id=%d error: %s", s.params.JobId, err.Error())
+		return nil
+	}
+	if jobRun.Status == types.PipelineStatusError && jobRun.Result != nil && jobRun.Result.Code == code.JobLost {
+		s.Log(jobRun.Result.Msg)
+		return errors.New(code.JobLost, jobRun.Result.Msg)
+	}
+	if jobRun.Status == types.PipelineStatusDoing && jobRun.SpaceletId != s.spaceletId {
+		return errJobRescheduled
+	}
+	return nil
+}
+
+// spaceletAlive 任务所在spacelet节点是否仍在线
+func (s *spaceletJob) spaceletAlive() bool {
+	sp, err := s.factory.models.SpaceletManager.Get(s.spaceletId)
+	if err != nil {
+		return false
+	}
+	if sp.HeartbeatTime != nil {
+		return sp.HeartbeatAlive()
+	}
+	return sp.Status == types.SpaceletStatusOnline
+}
+
 func (s *spaceletJob) Check(obj interface{}) bool {
 	return true
 }
@@ -0,0 +1,41 @@
+package pipeline_run
+
+import (
+	"fmt"
+	"github.com/kubespace/kubespace/pkg/model/types"
+	"github.com/kubespace/kubespace/pkg/service/notification"
+	"k8s.io/klog/v2"
+)
+
+// notify 发送流水线构建事件通知，流水线所在的流水线空间为通知范围
+func (p *PipelineRunController) notify(pipelineRun *types.PipelineRun, eventType string, stageRun *types.PipelineRunStage) {
+	pipelineObj, err := p.models.PipelineManager.GetById(pipelineRun.PipelineId)
+	if err != nil {
+		klog.Warningf("get pipeline id=%d for notification error: %s", pipelineRun.PipelineId, err.Error())
+		return
+	}
+	workspace, err := p.models.PipelineWorkspaceManager.Get(pipelineObj.WorkspaceId)
+	if err != nil {
+		klog.Warningf("get pipeline workspace id=%d for notification error: %s", pipelineObj.WorkspaceId, err.Error())
+		return
+	}
+	event := &notification.Event{
+		Type:      eventType,
+		Scope:     types.ScopePipeline,
+		ScopeId:   workspace.ID,
+		ScopeName: workspace.Name,
+		Subject:   pipelineObj.Name,
+		Message:   fmt.Sprintf("构建#%d，执行人：%s", pipelineRun.BuildNumber, pipelineRun.Operator),
+		Data: map[string]interface{}{
+			"pipeline_id":     pipelineObj.ID,
+			"pipeline_run_id": pipelineRun.ID,
+			"build_number":    pipelineRun.BuildNumber,
+			"operator":        pipelineRun.Operator,
+		},
+	}
+	if stageRun != nil {
+		event.Message = fmt.Sprintf("构建#%d，阶段：%s，执行人：%s", pipelineRun.BuildNumber, stageRun.Name, pipelineRun.Operator)
+		event.Data["stage"] = stageRun.Name
+	}
+	p.notificationService.Notify(event)
+}
@@ -6,13 +6,22 @@ import (
 	"github.com/kubespace/kubespace/pkg/informer"
 	spaceletlistwatcher "github.com/kubespace/kubespace/pkg/informer/listwatcher/spacelet"
 	"github.com/kubespace/kubespace/pkg/model"
+	"github.com/kubespace/kubespace/pkg/service/notification"
+	spaceletservice "github.com/kubespace/kubespace/pkg/service/spacelet"
+	"sync"
 )
 
 type SpaceletController struct {
 	models           *model.Models
 	spaceletInformer informer.Informer
+	spaceletService  *spaceletservice.SpaceletService
+	// spacelet节点不在线时发送通知
+	notificationService *notification.NotificationService
 	// 流水线构建时对其进行加锁，保证只有一个进行处理
 	lock lock.Lock
+	// 执行中的任务未出现在spacelet心跳中的计数，用于判断任务是否丢失
+	missMu    sync.Mutex
+	jobMisses map[uint]*jobMiss
 }
 
 func NewSpaceletController(config *controller.Config) *SpaceletController {
@@ -22,7 +31,11 @@ func NewSpaceletController(config *controller.Config) *SpaceletController {
 	c := &SpaceletController{
 		models:           config.Models,
 		spaceletInformer: spaceletInformer,
+		spaceletService:  config.ServiceFactory.Pipeline.SpaceletService,
 		lock:             lock.NewMemLock(),
+		jobMisses:        make(map[uint]*jobMiss),
+
+		notificationService: config.ServiceFactory.Notification.NotificationService,
 	}
 
 	// 定时探测spacelet节点存活
@@ -0,0 +1,123 @@
+package spacelet
+
+import (
+	"fmt"
+	"github.com/kubespace/kubespace/pkg/core/code"
+	"github.com/kubespace/kubespace/pkg/model/manager/pipeline"
+	"github.com/kubespace/kubespace/pkg/model/types"
+	"github.com/kubespace/kubespace/pkg/utils"
+	"k8s.io/klog/v2"
+	"time"
+)
+
+// lostJobHeartbeats 任务连续未出现在多少次心跳中才判定为丢失，避免任务刚执行完成、结果还未获取时被误判
+const lostJobHeartbeats = 2
+
+// jobMiss 任务未出现在心跳中的次数以及最近一次未出现的心跳时间
+type jobMiss struct {
+	spaceletId uint
+	heartbeat  time.Time
+	count      int
+}
+
+// detectLostJobs 检测分配到spacelet节点上执行中但已丢失的任务
+// spacelet节点不在线时，节点上所有执行中的任务丢失；
+// spacelet节点在线时，分配之后超过心跳超时时间，且连续多次心跳上报的正在执行以及最近完成的任务中都不存在的任务丢失，如spacelet重启
+// 可以重复执行的任务清空spacelet节点，由pipeline controller重新调度到其它健康节点执行，其它任务标记为执行失败
+func (s *SpaceletController) detectLostJobs(spaceletObj *types.Spacelet) error {
+	if spaceletObj.Status == types.SpaceletStatusOnline && spaceletObj.Load == nil {
+		// 未上报心跳的在线节点无法判断任务是否丢失
+		return nil
+	}
+	jobs, err := s.models.PipelineRunManager.ListJobRun(&pipeline.JobRunListCondition{
+		StatusIn:    []string{types.PipelineStatusDoing},
+		SpaceletIds: []uint{spaceletObj.ID},
+	})
+	if err != nil {
+		return err
+	}
+	s.pruneJobMisses(spaceletObj.ID, jobs)
+	for _, job := range jobs {
+		if !s.jobLost(spaceletObj, job) {
+			continue
+		}
+		if err = s.handleLostJob(spaceletObj, job); err != nil {
+			klog.Errorf("handle lost job id=%d on spacelet id=%d error: %s", job.ID, spaceletObj.ID, err.Error())
+		}
+		s.missMu.Lock()
+		delete(s.jobMisses, job.ID)
+		s.missMu.Unlock()
+	}
+	return nil
+}
+
+func (s *SpaceletController) jobLost(spaceletObj *types.Spacelet, job *types.PipelineRunJob) bool {
+	if spaceletObj.Status != types.SpaceletStatusOnline {
+		return true
+	}
+	if spaceletObj.HeartbeatTime == nil || spaceletObj.HeartbeatTime.Sub(job.UpdateTime) < types.SpaceletHeartbeatTimeout {
+		// 任务刚分配到节点，心跳还未上报
+		return false
+	}
+	s.missMu.Lock()
+	defer s.missMu.Unlock()
+	if load := spaceletObj.Load; load != nil && (containsJob(load.JobIds, job.ID) || containsJob(load.FinishedJobIds, job.ID)) {
+		delete(s.jobMisses, job.ID)
+		return false
+	}
+	// 同一次心跳只计数一次
+	miss, ok := s.jobMisses[job.ID]
+	if !ok {
+		miss = &jobMiss{spaceletId: spaceletObj.ID}
+		s.jobMisses[job.ID] = miss
+	}
+	if !miss.heartbeat.Equal(*spaceletObj.HeartbeatTime) {
+		miss.heartbeat = *spaceletObj.HeartbeatTime
+		miss.count++
+	}
+	return miss.count >= lostJobHeartbeats
+}
+
+func containsJob(jobIds []uint, jobId uint) bool {
+	for _, id := range jobIds {
+		if id == jobId {
+			return true
+		}
+	}
+	return false
+}
+
+// pruneJobMisses 清理节点上已不在执行中的任务的计数
+func (s *SpaceletController) pruneJobMisses(spaceletId uint, jobs []*types.PipelineRunJob) {
+	doing := make(map[uint]bool)
+	for _, job := range jobs {
+		doing[job.ID] = true
+	}
+	s.missMu.Lock()
+	defer s.missMu.Unlock()
+	for jobId, miss := range s.jobMisses {
+		if miss.spaceletId == spaceletId && !doing[jobId] {
+			delete(s.jobMisses, jobId)
+		}
+	}
+}
+
+func (s *SpaceletController) handleLostJob(spaceletObj *types.Spacelet, job *types.PipelineRunJob) error {
+	if types.IsIdempotentJob(job.PluginKey, job.SchedulePolicy) && job.Reschedules < types.PipelineJobMaxReschedules {
+		klog.Infof("job id=%d lost on spacelet host=%s, reschedule times=%d", job.ID, spaceletObj.Hostname, job.Reschedules+1)
+		_, err := s.models.PipelineRunManager.UpdateLostJobRun(job.ID, spaceletObj.ID, map[string]interface{}{
+			"spacelet_id": 0,
+			"reschedules": job.Reschedules + 1,
+		})
+		return err
+	}
+	klog.Infof("job id=%d lost on spacelet host=%s, mark job error", job.ID, spaceletObj.Hostname)
+	_, err := s.models.PipelineRunManager.UpdateLostJobRun(job.ID, spaceletObj.ID, map[string]interface{}{
+		"status": types.PipelineStatusError,
+		"result": &utils.Response{
+			Code: code.JobLost,
+			Msg:  fmt.Sprintf("任务执行所在spacelet节点%s(%s)失联", spaceletObj.Hostname, spaceletObj.HostIp),
+		},
+	})
+	return err
+}
@@ -0,0 +1,60 @@
+package spacelet
+
+import (
+	"github.com/kubespace/kubespace/pkg/model/types"
+	"testing"
+	"time"
+)
+
+func TestJobLost(t *testing.T) {
+	s := &SpaceletController{jobMisses: make(map[uint]*jobMiss)}
+	job := &types.PipelineRunJob{ID: 1, UpdateTime: time.Now().Add(-time.Hour)}
+	heartbeat := func(load *types.SpaceletLoad) *types.Spacelet {
+		now := time.Now()
+		return &types.Spacelet{ID: 1, Status: types.SpaceletStatusOnline, HeartbeatTime: &now, Load: load}
+	}
+
+	// 第一次心跳中不存在，还不判定为丢失
+	first := heartbeat(&types.SpaceletLoad{})
+	if s.jobLost(first, job) {
+		t.Fatal("job lost after one missing heartbeat")
+	}
+	// 同一次心跳重复检测不计数
+	if s.jobLost(first, job) {
+		t.Fatal("same heartbeat counted twice")
+	}
+	// 任务刚执行完成，出现在完成列表中，计数清零
+	time.Sleep(time.Millisecond)
+	if s.jobLost(heartbeat(&types.SpaceletLoad{FinishedJobIds: []uint{1}}), job) {
+		t.Fatal("finished job marked lost")
+	}
+	time.Sleep(time.Millisecond)
+	if s.jobLost(heartbeat(&types.SpaceletLoad{}), job) {
+		t.Fatal("job lost after finished list reset")
+	}
+	// 连续两次心跳中都不存在，判定为丢失
+	time.Sleep(time.Millisecond)
+	if !s.jobLost(heartbeat(nil), job) {
+		t.Fatal("job not lost after two missing heartbeats")
+	}
+
+	// 节点不在线时直接判定为丢失
+	if !s.jobLost(&types.Spacelet{ID: 1, Status: types.SpaceletStatusOffline}, &types.PipelineRunJob{ID: 2}) {
+		t.Fatal("job on offline spacelet not lost")
+	}
+}
+
+func TestPruneJobMisses(t *testing.T) {
+	s := &SpaceletController{jobMisses: map[uint]*jobMiss{
+		1: {spaceletId: 1, count: 1},
+		2: {spaceletId: 1, count: 1},
+		3: {spaceletId: 2, count: 1},
+	}}
+	s.pruneJobMisses(1, []*types.PipelineRunJob{{ID: 1}})
+	if _, ok := s.jobMisses[2]; ok {
+		t.Error("job 2 not pruned")
+	}
+	if len(s.jobMisses) != 2 {
+		t.Errorf("unexpected misses %v", s.jobMisses)
+	}
+}
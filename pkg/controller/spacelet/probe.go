@@ -3,7 +3,7 @@ package spacelet
 import (
 	"fmt"
 	"github.com/kubespace/kubespace/pkg/model/types"
-	spaceletservice "github.com/kubespace/kubespace/pkg/service/spacelet"
+	"github.com/kubespace/kubespace/pkg/service/notification"
 	spacelet "github.com/kubespace/kubespace/pkg/spacelet"
 	"k8s.io/klog/v2"
 	"time"
@@ -24,22 +24,54 @@ func (s *SpaceletController) probeCheck(obj interface{}) bool {
 	return true
 }
 
-// 定时探测spacelet节点是否存活
+// 定时探测spacelet节点是否存活，并检测分配到该节点上的任务是否丢失
 func (s *SpaceletController) probe(obj interface{}) error {
 	spaceletObj := obj.(types.Spacelet)
 	status := s.status(&spaceletObj)
 	if spaceletObj.Status != status {
 		klog.Infof("spacelet host=%s ip=%s stauts=%s", spaceletObj.Hostname, spaceletObj.HostIp, status)
-		return s.models.SpaceletManager.Update(spaceletObj.ID, &types.Spacelet{
+		if err := s.models.SpaceletManager.Update(spaceletObj.ID, &types.Spacelet{
 			Status:     status,
 			UpdateTime: time.Now(),
-		})
+		}); err != nil {
+			return err
+		}
+		if status == types.SpaceletStatusOffline && spaceletObj.Status == types.SpaceletStatusOnline {
+			s.notifyOffline(&spaceletObj)
+		}
+		spaceletObj.Status = status
 	}
-	return nil
+	return s.detectLostJobs(&spaceletObj)
 }
 
+// notifyOffline spacelet节点为平台级资源，由平台范围的通知规则订阅
+func (s *SpaceletController) notifyOffline(spaceletObj *types.Spacelet) {
+	message := "未探测到spacelet存活"
+	if spaceletObj.HeartbeatTime != nil {
+		message = fmt.Sprintf("最近一次心跳时间：%s", spaceletObj.HeartbeatTime.Format("2006-01-02 15:04:05"))
+	}
+	s.notificationService.Notify(&notification.Event{
+		Type:    types.NotificationEventSpaceletOffline,
+		Scope:   types.ScopePlatform,
+		Subject: fmt.Sprintf("%s(%s)", spaceletObj.Hostname, spaceletObj.HostIp),
+		Message: message,
+		Data: map[string]interface{}{
+			"spacelet_id": spaceletObj.ID,
+			"hostname":    spaceletObj.Hostname,
+			"hostip":      spaceletObj.HostIp,
+		},
+	})
+}
+
+// status 上报心跳的spacelet根据心跳时间判断是否在线，未上报过心跳的旧版本spacelet通过调用exec接口探测
 func (s *SpaceletController) status(spaceletObj *types.Spacelet) string {
-	spaceletClient, err := spaceletservice.NewClient(spaceletObj)
+	if spaceletObj.HeartbeatTime != nil {
+		if spaceletObj.HeartbeatAlive() {
+			return types.SpaceletStatusOnline
+		}
+		return types.SpaceletStatusOffline
+	}
+	spaceletClient, err := s.spaceletService.Client(spaceletObj)
 	if err != nil {
 		return types.SpaceletStatusOffline
 	}
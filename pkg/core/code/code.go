@@ -23,6 +23,7 @@ const (
 	HelmError      = "HelmError"
 	PluginError    = "PluginError"
 	JobCanceled    = "JobCanceled"
+	JobLost        = "JobLost"
 	GitError       = "GitError"
 	StatusError    = "StatusError"
 	CookieError    = "CookieError"
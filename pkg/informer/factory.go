@@ -1,6 +1,8 @@
 package informer
 
 import (
+	"github.com/kubespace/kubespace/pkg/informer/listwatcher/app"
+	"github.com/kubespace/kubespace/pkg/informer/listwatcher/appstore"
 	"github.com/kubespace/kubespace/pkg/informer/listwatcher/cluster"
 	"github.com/kubespace/kubespace/pkg/informer/listwatcher/config"
 	"github.com/kubespace/kubespace/pkg/informer/listwatcher/pipeline"
@@ -8,7 +10,8 @@ import (
 )
 
 type Factory interface {
-	ClusterAgentInformer(token string) Informer
+	ClusterAgentInformer(clusterName string) Informer
+	ClusterInformer(cond *cluster.ClusterWatchCondition) Informer
 
 	PipelineRunInformer(cond *pipeline.PipelineRunWatchCondition) Informer
 	PipelineRunJobInformer(cond *pipeline.PipelineRunJobWatchCondition) Informer
@@ -17,6 +20,12 @@ type Factory interface {
 	PipelineCodeCacheInformer(cond *pipeline.PipelineCodeCacheWatchCondition) Informer
 
 	SpaceletInformer(cond *spacelet.SpaceletWatchCondition) Informer
+
+	AppStoreSourceInformer(cond *appstore.AppStoreSourceWatchCondition) Informer
+
+	AppInformer(cond *app.AppWatchCondition) Informer
+	AppGitSourceInformer(cond *app.AppGitSourceWatchCondition) Informer
+	AppStackInformer(cond *app.AppStackWatchCondition) Informer
 }
 
 type informerFactory struct {
@@ -29,11 +38,15 @@ func NewInformerFactory(config *config.ListWatcherConfig) Factory {
 	}
 }
 
-func (s *informerFactory) ClusterAgentInformer(token string) Informer {
-	agentListWatcher := cluster.NewAgentListWatcher(token, s.config)
+func (s *informerFactory) ClusterAgentInformer(clusterName string) Informer {
+	agentListWatcher := cluster.NewAgentListWatcher(clusterName, s.config)
 	return NewInformer(agentListWatcher)
 }
 
+func (s *informerFactory) ClusterInformer(cond *cluster.ClusterWatchCondition) Informer {
+	return NewInformer(cluster.NewClusterListWatcher(s.config, cond))
+}
+
 func (s *informerFactory) PipelineRunInformer(cond *pipeline.PipelineRunWatchCondition) Informer {
 	return NewInformer(pipeline.NewPipelineRunListWatcher(s.config, cond))
 }
@@ -57,3 +70,19 @@ func (s *informerFactory) PipelineCodeCacheInformer(cond *pipeline.PipelineCodeC
 func (s *informerFactory) SpaceletInformer(cond *spacelet.SpaceletWatchCondition) Informer {
 	return NewInformer(spacelet.NewSpaceletListWatcher(s.config, cond))
 }
+
+func (s *informerFactory) AppStoreSourceInformer(cond *appstore.AppStoreSourceWatchCondition) Informer {
+	return NewInformer(appstore.NewAppStoreSourceListWatcher(s.config, cond))
+}
+
+func (s *informerFactory) AppInformer(cond *app.AppWatchCondition) Informer {
+	return NewInformer(app.NewAppListWatcher(s.config, cond))
+}
+
+func (s *informerFactory) AppGitSourceInformer(cond *app.AppGitSourceWatchCondition) Informer {
+	return NewInformer(app.NewAppGitSourceListWatcher(s.config, cond))
+}
+
+func (s *informerFactory) AppStackInformer(cond *app.AppStackWatchCondition) Informer {
+	return NewInformer(app.NewAppStackListWatcher(s.config, cond))
+}
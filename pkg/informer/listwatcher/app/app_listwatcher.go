@@ -0,0 +1,65 @@
+package app
+
+import (
+	"github.com/kubespace/kubespace/pkg/informer/listwatcher"
+	"github.com/kubespace/kubespace/pkg/informer/listwatcher/config"
+	"github.com/kubespace/kubespace/pkg/informer/listwatcher/storage"
+	"github.com/kubespace/kubespace/pkg/model/types"
+	"gorm.io/gorm"
+)
+
+const AppWatchKey = "kubespace:app"
+
+// AppWatchCondition 应用监听条件
+type AppWatchCondition struct {
+	// Installed 只监听已安装的应用
+	Installed bool
+}
+
+type appListWatcher struct {
+	storage.Storage
+	config    *config.ListWatcherConfig
+	db        *gorm.DB
+	condition *AppWatchCondition
+}
+
+func NewAppListWatcher(config *config.ListWatcherConfig, cond *AppWatchCondition) listwatcher.Interface {
+	if cond == nil {
+		cond = &AppWatchCondition{}
+	}
+	a := &appListWatcher{
+		config:    config,
+		db:        config.DB,
+		condition: cond,
+	}
+	resync := 60
+	a.Storage = config.NewStorage(AppWatchKey, a.List, a.Filter, &resync, &types.App{})
+	return a
+}
+
+func (a *appListWatcher) Filter(obj interface{}) bool {
+	app, ok := obj.(types.App)
+	if !ok {
+		return false
+	}
+	if a.condition.Installed && app.Status == types.AppStatusUninstall {
+		return false
+	}
+	return true
+}
+
+func (a *appListWatcher) List() ([]interface{}, error) {
+	var apps []types.App
+	var tx = a.db
+	if a.condition.Installed {
+		tx = tx.Where("status != ?", types.AppStatusUninstall)
+	}
+	if err := tx.Find(&apps).Error; err != nil {
+		return nil, err
+	}
+	var objs []interface{}
+	for i := range apps {
+		objs = append(objs, apps[i])
+	}
+	return objs, nil
+}
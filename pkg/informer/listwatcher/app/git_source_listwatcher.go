@@ -0,0 +1,64 @@
+package app
+
+import (
+	"github.com/kubespace/kubespace/pkg/informer/listwatcher"
+	"github.com/kubespace/kubespace/pkg/informer/listwatcher/config"
+	"github.com/kubespace/kubespace/pkg/informer/listwatcher/storage"
+	"github.com/kubespace/kubespace/pkg/model/types"
+	"gorm.io/gorm"
+)
+
+const AppGitSourceWatchKey = "kubespace:app:git_source"
+
+// AppGitSourceWatchCondition 应用git来源监听条件
+type AppGitSourceWatchCondition struct {
+	Enabled *bool
+}
+
+type appGitSourceListWatcher struct {
+	storage.Storage
+	config    *config.ListWatcherConfig
+	db        *gorm.DB
+	condition *AppGitSourceWatchCondition
+}
+
+func NewAppGitSourceListWatcher(config *config.ListWatcherConfig, cond *AppGitSourceWatchCondition) listwatcher.Interface {
+	if cond == nil {
+		cond = &AppGitSourceWatchCondition{}
+	}
+	a := &appGitSourceListWatcher{
+		config:    config,
+		db:        config.DB,
+		condition: cond,
+	}
+	resync := 60
+	a.Storage = config.NewStorage(AppGitSourceWatchKey, a.List, a.Filter, &resync, &types.AppGitSource{})
+	return a
+}
+
+func (a *appGitSourceListWatcher) Filter(obj interface{}) bool {
+	source, ok := obj.(types.AppGitSource)
+	if !ok {
+		return false
+	}
+	if a.condition.Enabled != nil && source.Enabled != *a.condition.Enabled {
+		return false
+	}
+	return true
+}
+
+func (a *appGitSourceListWatcher) List() ([]interface{}, error) {
+	var sources []types.AppGitSource
+	var tx = a.db
+	if a.condition.Enabled != nil {
+		tx = tx.Where("enabled = ?", *a.condition.Enabled)
+	}
+	if err := tx.Find(&sources).Error; err != nil {
+		return nil, err
+	}
+	var objs []interface{}
+	for i := range sources {
+		objs = append(objs, sources[i])
+	}
+	return objs, nil
+}
@@ -0,0 +1,69 @@
+package app
+
+import (
+	"github.com/kubespace/kubespace/pkg/informer/listwatcher"
+	"github.com/kubespace/kubespace/pkg/informer/listwatcher/config"
+	"github.com/kubespace/kubespace/pkg/informer/listwatcher/storage"
+	"github.com/kubespace/kubespace/pkg/model/types"
+	"gorm.io/gorm"
+)
+
+const AppStackWatchKey = "kubespace:app:stack"
+
+// AppStackWatchCondition 应用栈监听条件
+type AppStackWatchCondition struct {
+	// Operating 只监听有未完成的安装、升级或者销毁的应用栈
+	Operating bool
+}
+
+type appStackListWatcher struct {
+	storage.Storage
+	config    *config.ListWatcherConfig
+	db        *gorm.DB
+	condition *AppStackWatchCondition
+}
+
+func NewAppStackListWatcher(config *config.ListWatcherConfig, cond *AppStackWatchCondition) listwatcher.Interface {
+	if cond == nil {
+		cond = &AppStackWatchCondition{}
+	}
+	a := &appStackListWatcher{
+		config:    config,
+		db:        config.DB,
+		condition: cond,
+	}
+	resync := 60
+	a.Storage = config.NewStorage(AppStackWatchKey, a.List, a.Filter, &resync, &types.AppStack{})
+	return a
+}
+
+func (a *appStackListWatcher) Filter(obj interface{}) bool {
+	stack, ok := obj.(types.AppStack)
+	if !ok {
+		return false
+	}
+	if a.condition.Operating && !stack.Operating() {
+		return false
+	}
+	return true
+}
+
+func (a *appStackListWatcher) List() ([]interface{}, error) {
+	var stacks []types.AppStack
+	var tx = a.db
+	if a.condition.Operating {
+		tx = tx.Where("status in ?", []string{
+			types.AppStackStatusInstalling,
+			types.AppStackStatusUpgrading,
+			types.AppStackStatusDestroying,
+		})
+	}
+	if err := tx.Find(&stacks).Error; err != nil {
+		return nil, err
+	}
+	var objs []interface{}
+	for i := range stacks {
+		objs = append(objs, stacks[i])
+	}
+	return objs, nil
+}
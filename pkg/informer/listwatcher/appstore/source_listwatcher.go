@@ -0,0 +1,64 @@
+package appstore
+
+import (
+	"github.com/kubespace/kubespace/pkg/informer/listwatcher"
+	"github.com/kubespace/kubespace/pkg/informer/listwatcher/config"
+	"github.com/kubespace/kubespace/pkg/informer/listwatcher/storage"
+	"github.com/kubespace/kubespace/pkg/model/types"
+	"gorm.io/gorm"
+)
+
+const AppStoreSourceWatchKey = "kubespace:appstore:source"
+
+// AppStoreSourceWatchCondition 应用商店同步源监听条件
+type AppStoreSourceWatchCondition struct {
+	Enabled *bool
+}
+
+type appStoreSourceListWatcher struct {
+	storage.Storage
+	config    *config.ListWatcherConfig
+	db        *gorm.DB
+	condition *AppStoreSourceWatchCondition
+}
+
+func NewAppStoreSourceListWatcher(config *config.ListWatcherConfig, cond *AppStoreSourceWatchCondition) listwatcher.Interface {
+	if cond == nil {
+		cond = &AppStoreSourceWatchCondition{}
+	}
+	a := &appStoreSourceListWatcher{
+		config:    config,
+		db:        config.DB,
+		condition: cond,
+	}
+	resync := 60
+	a.Storage = config.NewStorage(AppStoreSourceWatchKey, a.List, a.Filter, &resync, &types.AppStoreSource{})
+	return a
+}
+
+func (p *appStoreSourceListWatcher) Filter(obj interface{}) bool {
+	source, ok := obj.(types.AppStoreSource)
+	if !ok {
+		return false
+	}
+	if p.condition.Enabled != nil && source.Enabled != *p.condition.Enabled {
+		return false
+	}
+	return true
+}
+
+func (p *appStoreSourceListWatcher) List() ([]interface{}, error) {
+	var sources []types.AppStoreSource
+	var tx = p.db
+	if p.condition.Enabled != nil {
+		tx = tx.Where("enabled = ?", *p.condition.Enabled)
+	}
+	if err := tx.Find(&sources).Error; err != nil {
+		return nil, err
+	}
+	var objs []interface{}
+	for i := range sources {
+		objs = append(objs, sources[i])
+	}
+	return objs, nil
+}
@@ -19,19 +19,24 @@ type AgentListWatcher interface {
 	NotifyResult(req *kubetypes.Request, timeout int) *utils.Response
 	NotifyWatch(traceId string, stopCh <-chan struct{}) <-chan []byte
 	NotifyResponse(resp *kubetypes.Response) error
+	// NotifyResponseBytes 隧道v2协议中agent返回的数据已经是序列化后的内容，直接通知
+	NotifyResponseBytes(traceId string, data []byte) error
+	// NotifyPending 请求方还未取走的返回数据条数，用于控制隧道v2中stream窗口的返还
+	NotifyPending(traceId string) (int64, error)
 }
 
 type agentListWatcher struct {
 	storage.Storage
-	token  string
-	config *config.ListWatcherConfig
+	clusterName string
+	config      *config.ListWatcherConfig
 }
 
-func NewAgentListWatcher(token string, config *config.ListWatcherConfig) AgentListWatcher {
-	watchKey := fmt.Sprintf(ClusterAgentWatchKey, token)
+// NewAgentListWatcher 集群agent请求的监听，以集群名称（id）区分，集群导入token轮换后不影响已建立的隧道
+func NewAgentListWatcher(clusterName string, config *config.ListWatcherConfig) AgentListWatcher {
+	watchKey := fmt.Sprintf(ClusterAgentWatchKey, clusterName)
 	a := &agentListWatcher{
-		token:  token,
-		config: config,
+		clusterName: clusterName,
+		config:      config,
 	}
 	resyncSec := 0
 	a.Storage = config.NewStorage(watchKey, nil, nil, &resyncSec, &kubetypes.Request{})
@@ -63,3 +68,7 @@ func (a *agentListWatcher) NotifyResponse(resp *kubetypes.Response) error {
 	}
 	return a.Storage.NotifyResponse(resp.TraceId, respBytes)
 }
+
+func (a *agentListWatcher) NotifyResponseBytes(traceId string, data []byte) error {
+	return a.Storage.NotifyResponse(traceId, data)
+}
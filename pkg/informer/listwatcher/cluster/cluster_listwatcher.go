@@ -0,0 +1,65 @@
+package cluster
+
+import (
+	"fmt"
+	"github.com/kubespace/kubespace/pkg/informer/listwatcher"
+	"github.com/kubespace/kubespace/pkg/informer/listwatcher/config"
+	"github.com/kubespace/kubespace/pkg/informer/listwatcher/storage"
+	"github.com/kubespace/kubespace/pkg/model/types"
+	"gorm.io/gorm"
+)
+
+const ClusterWatchKey = "kubespace:cluster"
+
+// ClusterWatchCondition 集群监听条件
+type ClusterWatchCondition struct {
+	// HealthCheckDue 只监听到了健康检查时间的集群
+	HealthCheckDue bool
+}
+
+type clusterListWatcher struct {
+	storage.Storage
+	config    *config.ListWatcherConfig
+	db        *gorm.DB
+	condition *ClusterWatchCondition
+}
+
+func NewClusterListWatcher(config *config.ListWatcherConfig, cond *ClusterWatchCondition) listwatcher.Interface {
+	if cond == nil {
+		cond = &ClusterWatchCondition{}
+	}
+	c := &clusterListWatcher{
+		config:    config,
+		db:        config.DB,
+		condition: cond,
+	}
+	resync := 60
+	c.Storage = config.NewStorage(ClusterWatchKey, c.List, c.Filter, &resync, &types.Cluster{})
+	return c
+}
+
+func (c *clusterListWatcher) Filter(obj interface{}) bool {
+	cluster, ok := obj.(types.Cluster)
+	if !ok {
+		return false
+	}
+	if c.condition.HealthCheckDue && !cluster.HealthCheckDue() {
+		return false
+	}
+	return true
+}
+
+func (c *clusterListWatcher) List() ([]interface{}, error) {
+	var clusters []types.Cluster
+	if err := c.db.Find(&clusters).Error; err != nil {
+		return nil, err
+	}
+	var objs []interface{}
+	for i := range clusters {
+		clusters[i].Name = fmt.Sprintf("%d", clusters[i].ID)
+		if c.Filter(clusters[i]) {
+			objs = append(objs, clusters[i])
+		}
+	}
+	return objs, nil
+}
@@ -210,6 +210,10 @@ func (r *RedisStorage) NotifyResponse(traceId string, resp []byte) error {
 	return nil
 }
 
+func (r *RedisStorage) NotifyPending(traceId string) (int64, error) {
+	return r.client.LLen(context.Background(), traceId).Result()
+}
+
 func (r *RedisStorage) Stop() error {
 	if r.stopped {
 		return nil
@@ -11,6 +11,8 @@ type Storage interface {
 	NotifyResult(traceId string, timeout int, data interface{}) ([]byte, error)
 	NotifyWatch(traceId string, stopCh <-chan struct{}) <-chan []byte
 	NotifyResponse(traceId string, resp []byte) error
+	// NotifyPending 请求方还未取走的返回数据条数
+	NotifyPending(traceId string) (int64, error)
 }
 
 type ListFunc func() ([]interface{}, error)
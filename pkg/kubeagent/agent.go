@@ -6,6 +6,7 @@ import (
 	"fmt"
 	"github.com/kubespace/kubespace/pkg/core/code"
 	"github.com/kubespace/kubespace/pkg/kubernetes"
+	"github.com/kubespace/kubespace/pkg/kubernetes/cache"
 	kubeconfig "github.com/kubespace/kubespace/pkg/kubernetes/config"
 	"github.com/kubespace/kubespace/pkg/kubernetes/resource"
 	kubetypes "github.com/kubespace/kubespace/pkg/kubernetes/types"
@@ -23,21 +24,29 @@ type Agent struct {
 	kubeFactory    kubernetes.KubeFactory
 	sessionWriters sync.Map
 	serverCli      *httpclient.HttpClient
+	session        *agentSession
+	// impersonateFactories 模拟用户身份访问集群的KubeFactory，key为模拟身份的唯一标识
+	impersonateFactories sync.Map
 }
 
 func NewAgent(config *AgentConfig) *Agent {
 	a := &Agent{
 		config:      config,
 		kubeConfig:  config.KubeConfig,
-		kubeFactory: kubernetes.NewKubeFactory(config.KubeConfig),
+		kubeFactory: kubernetes.NewKubeFactoryWithProfile(config.KubeConfig, config.Profile),
 		serverCli:   config.ServerClient,
+		session:     newAgentSession(config.Token, config.ServerClient),
 	}
-	a.tunnel = NewTunnel(config.Token, config.ServerHost, a)
+	a.tunnel = NewTunnel(config, a.session, a)
 	return a
 }
 
 // Run 运行agent，tunnel通过websocket监听服务端消息，从tunnel接收到消息后，开启一个协程处理
 func (a *Agent) Run(stopCh <-chan struct{}) {
+	if a.config.CacheOptions != nil {
+		// 资源informer在第一次请求时启动，模拟用户身份的请求不使用缓存
+		a.kubeConfig.Cache = cache.New(a.kubeConfig.Client.Dynamic(), a.config.CacheOptions, stopCh)
+	}
 	go a.tunnel.Run(stopCh)
 	for {
 		select {
@@ -62,6 +71,10 @@ func (a *Agent) Handle(receiveBytes []byte) {
 	}
 	// 通过tunnel将结果发送回server
 	a.tunnel.Send(&kubetypes.Response{TraceId: req.TraceId, Data: resp})
+	// exec/log/watch会话的stream在会话关闭后再释放
+	if _, ok := a.sessionWriters.Load(req.TraceId); !ok {
+		a.tunnel.CloseStream(req.TraceId)
+	}
 }
 
 // 具体的请求处理逻辑，根据请求的类型，做不同的处理
@@ -76,9 +89,13 @@ func (a *Agent) handle(req *kubetypes.Request) (resp *utils.Response) {
 			resp = &utils.Response{Code: "UnknownError", Msg: msg}
 		}
 	}()
+	kubeFactory, err := a.getKubeFactory(req.Impersonate)
+	if err != nil {
+		return &utils.Response{Code: code.RequestError, Msg: err.Error()}
+	}
 	switch {
 	case req.Resource == kubetypes.PodType && (req.Action == kubetypes.ExecAction || req.Action == kubetypes.LogAction):
-		if podHandler, err := a.kubeFactory.GetPod(); err != nil {
+		if podHandler, err := kubeFactory.GetPod(); err != nil {
 			resp = &utils.Response{Code: code.GetError, Msg: err.Error()}
 		} else {
 			writer := newSessionWriter(req.TraceId, a.tunnel)
@@ -90,16 +107,10 @@ func (a *Agent) handle(req *kubetypes.Request) (resp *utils.Response) {
 			}
 		}
 	case req.Action == kubetypes.CloseSession:
-		obj, ok := a.sessionWriters.LoadAndDelete(req.TraceId)
-		if ok {
-			writer, ok := obj.(resource.OutWriter)
-			if ok {
-				writer.Close()
-			}
-		}
+		a.closeSession(req.TraceId)
 		resp = &utils.Response{Code: code.Success}
 	case req.Action == kubetypes.WatchAction:
-		if resHandler, err := a.kubeFactory.GetResource(req.Resource); err != nil {
+		if resHandler, err := kubeFactory.GetResource(req.Resource); err != nil {
 			resp = &utils.Response{Code: code.GetError, Msg: err.Error()}
 		} else {
 			writer := newSessionWriter(req.TraceId, a.tunnel)
@@ -107,7 +118,7 @@ func (a *Agent) handle(req *kubetypes.Request) (resp *utils.Response) {
 			resp = resHandler.Watch(req.Params, writer)
 		}
 	default:
-		if resHandler, err := a.kubeFactory.GetResource(req.Resource); err != nil {
+		if resHandler, err := kubeFactory.GetResource(req.Resource); err != nil {
 			resp = &utils.Response{Code: code.GetError, Msg: err.Error()}
 		} else {
 			resp = resHandler.Handle(req.Action, req.Params)
@@ -116,6 +127,41 @@ func (a *Agent) handle(req *kubetypes.Request) (resp *utils.Response) {
 	return
 }
 
+// closeSession 关闭exec/log/watch会话
+func (a *Agent) closeSession(traceId string) {
+	obj, ok := a.sessionWriters.LoadAndDelete(traceId)
+	if ok {
+		writer, ok := obj.(resource.OutWriter)
+		if ok {
+			writer.Close()
+		}
+	}
+}
+
+// OnCancel v2协议下server关闭会话时发送取消帧，关闭会话后返回成功，server端等待该结果
+func (a *Agent) OnCancel(traceId string) {
+	a.closeSession(traceId)
+	a.tunnel.Send(&kubetypes.Response{TraceId: traceId, Data: &utils.Response{Code: code.Success}})
+	a.tunnel.CloseStream(traceId)
+}
+
+// getKubeFactory 获取请求身份对应的KubeFactory，没有模拟用户时使用agent自身的身份
+func (a *Agent) getKubeFactory(impersonate *kubetypes.Impersonate) (kubernetes.KubeFactory, error) {
+	if impersonate == nil || impersonate.User == "" {
+		return a.kubeFactory, nil
+	}
+	key := impersonate.Key()
+	if factory, ok := a.impersonateFactories.Load(key); ok {
+		return factory.(kubernetes.KubeFactory), nil
+	}
+	impersonateConfig, err := a.kubeConfig.Impersonate(impersonate.User, impersonate.Groups)
+	if err != nil {
+		return nil, fmt.Errorf("impersonate user %s error: %s", impersonate.User, err.Error())
+	}
+	factory, _ := a.impersonateFactories.LoadOrStore(key, kubernetes.NewKubeFactoryWithProfile(impersonateConfig, a.config.Profile))
+	return factory.(kubernetes.KubeFactory), nil
+}
+
 // OnSuccess agent在每个集群中独立运行，当重新连接tunnel后，server有可能更新到最新版本，
 // agent从server下载当前版本匹配的yaml，并更新；
 func (a *Agent) OnSuccess() {
@@ -123,8 +169,18 @@ func (a *Agent) OnSuccess() {
 		// bearerToken为空表示agent未运行在集群pod中，不更新agent
 		return
 	}
+	if !a.config.Profile.IsAdmin() {
+		// 非admin权限的agent没有更新自身RBAC以及deployment的权限，需要重新执行导入命令更新
+		klog.Infof("agent profile is %s, skip updating agent yaml", a.config.Profile.Name)
+		return
+	}
 	bytesBuf := new(bytes.Buffer)
-	_, err := a.serverCli.Get("/import/agent/"+a.config.Token, nil, bytesBuf, httpclient.RequestOptions{})
+	// 使用会话凭证获取yaml，集群导入token重新生成后，yaml中为新的导入token
+	query := &importQuery{
+		CAHash:                a.config.Options.CAHash,
+		InsecureSkipTLSVerify: a.config.Options.InsecureSkipTLSVerify,
+	}
+	_, err := a.serverCli.Get("/import/agent/"+a.session.Credential(), query, bytesBuf, httpclient.RequestOptions{})
 	if err != nil {
 		klog.Errorf("get server agent yaml error: %s", err.Error())
 		return
@@ -145,6 +201,12 @@ func (a *Agent) OnSuccess() {
 	}
 }
 
+// importQuery 获取agent yaml时带上当前的tls配置，生成的yaml保持一致
+type importQuery struct {
+	CAHash                string `url:"ca_hash,omitempty"`
+	InsecureSkipTLSVerify bool   `url:"insecure_skip_tls_verify,omitempty"`
+}
+
 type sessionWriter struct {
 	traceId string
 	tunnel  Tunnel
@@ -161,6 +223,10 @@ func newSessionWriter(traceId string, tunnel Tunnel) resource.OutWriter {
 }
 
 func (s *sessionWriter) Write(out interface{}) error {
+	if s.stopped {
+		// 会话关闭后不再发送，避免与关闭会话的结果混在一起
+		return nil
+	}
 	s.tunnel.Send(&kubetypes.Response{TraceId: s.traceId, Data: out})
 	return nil
 }
@@ -175,4 +241,5 @@ func (s *sessionWriter) Close() {
 	}
 	s.stopped = true
 	close(s.stopCh)
+	s.tunnel.CloseStream(s.traceId)
 }
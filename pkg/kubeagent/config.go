@@ -1,15 +1,37 @@
 package kubeagent
 
 import (
+	"crypto/tls"
 	"fmt"
+	"github.com/kubespace/kubespace/pkg/kubernetes/cache"
 	"github.com/kubespace/kubespace/pkg/kubernetes/config"
+	kubetypes "github.com/kubespace/kubespace/pkg/kubernetes/types"
 	"github.com/kubespace/kubespace/pkg/third/httpclient"
+	"net"
+	"strings"
 )
 
 type AgentOptions struct {
 	KubeConfigFile string
 	AgentToken     string
 	ServerHost     string
+	// Profile agent安装时的权限配置，admin/namespace/readonly
+	Profile string
+	// Namespaces namespace权限时可以操作的命名空间
+	Namespaces []string
+	// ServerTLS 通过https/wss连接服务端，server-host以https://开头时默认开启
+	ServerTLS bool
+	// CAFile 校验服务端证书的CA文件，为空时使用系统根证书
+	CAFile string
+	// CAHash 固定服务端证书链中证书的公钥sha256，格式为sha256:<hex>
+	CAHash                string
+	InsecureSkipTLSVerify bool
+	// DisableCache 不使用资源缓存，list/get/watch直接访问apiserver
+	DisableCache bool
+	// CacheResources 缓存的资源名称，为空时使用默认的资源
+	CacheResources []string
+	// CacheMaxObjects 缓存的对象总数上限
+	CacheMaxObjects int64
 }
 
 type AgentConfig struct {
@@ -17,12 +39,52 @@ type AgentConfig struct {
 	KubeConfig   *config.KubeConfig
 	ServerHost   string
 	ServerClient *httpclient.HttpClient
+	Profile      *kubetypes.AgentProfile
+	// TLSConfig 为空时通过http/ws连接服务端
+	TLSConfig *tls.Config
+	Options   *AgentOptions
+	// CacheOptions 为空时不使用资源缓存
+	CacheOptions *cache.Options
+}
+
+// WebsocketScheme 连接服务端隧道的协议
+func (a *AgentConfig) WebsocketScheme() string {
+	if a.TLSConfig != nil {
+		return "wss"
+	}
+	return "ws"
 }
 
 func NewAgentConfig(options *AgentOptions) (a *AgentConfig, err error) {
+	serverHost := options.ServerHost
+	if strings.HasPrefix(serverHost, "https://") {
+		options.ServerTLS = true
+	}
+	serverHost = strings.TrimSuffix(strings.TrimPrefix(strings.TrimPrefix(serverHost, "https://"), "http://"), "/")
 	a = &AgentConfig{
 		Token:      options.AgentToken,
-		ServerHost: options.ServerHost,
+		ServerHost: serverHost,
+		Options:    options,
+		Profile: &kubetypes.AgentProfile{
+			Name:       options.Profile,
+			Namespaces: options.Namespaces,
+		},
+	}
+	switch options.Profile {
+	case "", kubetypes.AgentProfileAdmin, kubetypes.AgentProfileReadOnly:
+	case kubetypes.AgentProfileNamespace:
+		if len(options.Namespaces) == 0 {
+			return nil, fmt.Errorf("namespaces is empty with namespace profile")
+		}
+	default:
+		return nil, fmt.Errorf("unknown agent profile %s", options.Profile)
+	}
+	// namespace权限的agent没有集群范围的list/watch权限，无法使用缓存
+	if !options.DisableCache && options.Profile != kubetypes.AgentProfileNamespace {
+		a.CacheOptions = &cache.Options{Resources: options.CacheResources, MaxObjects: options.CacheMaxObjects}
+		if len(a.CacheOptions.Resources) == 0 {
+			a.CacheOptions.Resources = cache.DefaultResources
+		}
 	}
 	kubeOptions := &config.Options{}
 	if options.KubeConfigFile != "" {
@@ -33,7 +95,20 @@ func NewAgentConfig(options *AgentOptions) (a *AgentConfig, err error) {
 	if a.KubeConfig, err = config.NewKubeConfig(kubeOptions); err != nil {
 		return nil, err
 	}
-	if a.ServerClient, err = httpclient.NewHttpClient(fmt.Sprintf("http://%s", a.ServerHost)); err != nil {
+	if !options.ServerTLS {
+		if a.ServerClient, err = httpclient.NewHttpClient(fmt.Sprintf("http://%s", a.ServerHost)); err != nil {
+			return nil, err
+		}
+		return
+	}
+	serverName := a.ServerHost
+	if host, _, splitErr := net.SplitHostPort(serverName); splitErr == nil {
+		serverName = host
+	}
+	if a.TLSConfig, err = newTLSConfig(serverName, options.CAFile, options.CAHash, options.InsecureSkipTLSVerify); err != nil {
+		return nil, err
+	}
+	if a.ServerClient, err = httpclient.NewHttpClientWithTLS(fmt.Sprintf("https://%s", a.ServerHost), a.TLSConfig); err != nil {
 		return nil, err
 	}
 	return
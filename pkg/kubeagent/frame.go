@@ -0,0 +1,150 @@
+package kubeagent
+
+import (
+	"bytes"
+	"compress/flate"
+	"encoding/binary"
+	"fmt"
+	"io"
+)
+
+// 隧道v2协议，agent与server通过一个websocket连接多路复用所有请求以及会话数据，
+// 每个websocket二进制消息为一个帧，格式为：
+//
+//	| type(1) | flags(1) | stream id length(2) | stream id | payload |
+//
+// stream id为请求的traceId，同一个stream的数据按顺序发送，不同stream的帧交替发送，
+// agent发送给server的数据受stream窗口限制，server处理后通过window帧返还窗口
+const (
+	HeaderTunnelProtocol = "tunnel-protocol"
+	TunnelProtocolV2     = "v2"
+)
+
+const (
+	// FrameRequest server发送给agent的请求，payload为kubetypes.Request json
+	FrameRequest byte = 1
+	// FrameData agent返回的json数据，超过帧大小时拆分为多个帧，最后一个帧带有fin标记
+	FrameData byte = 2
+	// FrameBinary agent返回的exec/log原始数据，每个帧可以单独处理
+	FrameBinary byte = 3
+	// FrameWindow server处理完数据后返还的窗口大小，payload为4字节无符号整数
+	FrameWindow byte = 4
+	// FrameCancel server取消请求，agent停止会话并丢弃未发送的数据
+	FrameCancel byte = 5
+)
+
+const (
+	// FlagCompressed payload使用deflate压缩
+	FlagCompressed byte = 1 << 0
+	// FlagFin 消息的最后一个帧
+	FlagFin byte = 1 << 1
+)
+
+const (
+	// MaxFramePayload 单个帧压缩前的最大数据大小，大的数据拆分为多个帧，避免阻塞其他stream
+	MaxFramePayload = 32 * 1024
+	// InitialStreamWindow stream初始窗口大小
+	InitialStreamWindow = 256 * 1024
+	// MaxRequestPayload server发送给agent的请求不拆分帧，如apply的yaml以及helm values，解压后的最大大小
+	MaxRequestPayload = 16 * 1024 * 1024
+	// compressThreshold 超过该大小的payload进行压缩
+	compressThreshold = 1024
+)
+
+type Frame struct {
+	Type     byte
+	Flags    byte
+	StreamId string
+	Payload  []byte
+}
+
+func (f *Frame) Fin() bool {
+	return f.Flags&FlagFin != 0
+}
+
+// EncodeFrame 编码帧，payload超过压缩阈值并且压缩后更小时进行压缩
+func EncodeFrame(f *Frame) ([]byte, error) {
+	if len(f.StreamId) > 0xffff {
+		return nil, fmt.Errorf("stream id too long")
+	}
+	payload := f.Payload
+	flags := f.Flags &^ FlagCompressed
+	if len(payload) > compressThreshold {
+		var buf bytes.Buffer
+		w, err := flate.NewWriter(&buf, flate.BestSpeed)
+		if err != nil {
+			return nil, err
+		}
+		if _, err = w.Write(payload); err != nil {
+			return nil, err
+		}
+		if err = w.Close(); err != nil {
+			return nil, err
+		}
+		if buf.Len() < len(payload) {
+			payload = buf.Bytes()
+			flags |= FlagCompressed
+		}
+	}
+	data := make([]byte, 4+len(f.StreamId)+len(payload))
+	data[0] = f.Type
+	data[1] = flags
+	binary.BigEndian.PutUint16(data[2:4], uint16(len(f.StreamId)))
+	copy(data[4:], f.StreamId)
+	copy(data[4+len(f.StreamId):], payload)
+	return data, nil
+}
+
+// DecodeFrame 解码帧，压缩的payload解压后返回
+func DecodeFrame(data []byte) (*Frame, error) {
+	if len(data) < 4 {
+		return nil, fmt.Errorf("frame too short")
+	}
+	idLen := int(binary.BigEndian.Uint16(data[2:4]))
+	if len(data) < 4+idLen {
+		return nil, fmt.Errorf("frame stream id length %d exceeds frame size", idLen)
+	}
+	f := &Frame{
+		Type:     data[0],
+		Flags:    data[1],
+		StreamId: string(data[4 : 4+idLen]),
+		Payload:  data[4+idLen:],
+	}
+	if f.Flags&FlagCompressed != 0 {
+		// 限制解压后的大小，避免很小的压缩数据解压后耗尽内存
+		limit := maxPayload(f.Type)
+		payload, err := io.ReadAll(io.LimitReader(flate.NewReader(bytes.NewReader(f.Payload)), int64(limit)+1))
+		if err != nil {
+			return nil, fmt.Errorf("decompress frame error: %s", err.Error())
+		}
+		if len(payload) > limit {
+			return nil, fmt.Errorf("decompressed frame payload exceeds %d bytes", limit)
+		}
+		f.Payload = payload
+		f.Flags &^= FlagCompressed
+	}
+	return f, nil
+}
+
+// maxPayload 帧解压后允许的最大大小，agent返回的数据按MaxFramePayload拆分，请求不拆分
+func maxPayload(frameType byte) int {
+	if frameType == FrameRequest {
+		return MaxRequestPayload
+	}
+	return MaxFramePayload
+}
+
+// WindowFrame 返还stream窗口的帧
+func WindowFrame(streamId string, size int) *Frame {
+	payload := make([]byte, 4)
+	binary.BigEndian.PutUint32(payload, uint32(size))
+	return &Frame{Type: FrameWindow, StreamId: streamId, Payload: payload}
+}
+
+// WindowSize 解析window帧返还的窗口大小
+func (f *Frame) WindowSize() int {
+	if len(f.Payload) < 4 {
+		return 0
+	}
+	return int(binary.BigEndian.Uint32(f.Payload))
+}
@@ -0,0 +1,123 @@
+package kubeagent
+
+import (
+	"bytes"
+	"compress/flate"
+	"encoding/binary"
+	"testing"
+)
+
+func TestFrameRoundTrip(t *testing.T) {
+	tests := []struct {
+		name       string
+		frame      *Frame
+		compressed bool
+	}{
+		{
+			name:  "empty payload",
+			frame: &Frame{Type: FrameCancel, StreamId: "trace-1"},
+		},
+		{
+			name:  "small payload not compressed",
+			frame: &Frame{Type: FrameBinary, StreamId: "trace-2", Payload: []byte("hello")},
+		},
+		{
+			name:       "large payload compressed",
+			frame:      &Frame{Type: FrameData, Flags: FlagFin, StreamId: "trace-3", Payload: bytes.Repeat([]byte("a"), MaxFramePayload)},
+			compressed: true,
+		},
+		{
+			name:  "window frame",
+			frame: WindowFrame("trace-4", InitialStreamWindow),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data, err := EncodeFrame(tt.frame)
+			if err != nil {
+				t.Fatalf("encode frame error: %s", err)
+			}
+			if compressed := data[1]&FlagCompressed != 0; compressed != tt.compressed {
+				t.Errorf("compressed = %v, want %v", compressed, tt.compressed)
+			}
+			got, err := DecodeFrame(data)
+			if err != nil {
+				t.Fatalf("decode frame error: %s", err)
+			}
+			if got.Type != tt.frame.Type || got.StreamId != tt.frame.StreamId || got.Fin() != tt.frame.Fin() {
+				t.Errorf("decode frame = %+v, want %+v", got, tt.frame)
+			}
+			if got.Flags&FlagCompressed != 0 {
+				t.Errorf("decoded frame still has compressed flag")
+			}
+			if !bytes.Equal(got.Payload, tt.frame.Payload) {
+				t.Errorf("payload length = %d, want %d", len(got.Payload), len(tt.frame.Payload))
+			}
+		})
+	}
+}
+
+func TestWindowFrameSize(t *testing.T) {
+	data, err := EncodeFrame(WindowFrame("trace", 12345))
+	if err != nil {
+		t.Fatal(err)
+	}
+	frame, err := DecodeFrame(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if frame.Type != FrameWindow || frame.WindowSize() != 12345 {
+		t.Errorf("window frame type = %d size = %d", frame.Type, frame.WindowSize())
+	}
+}
+
+// compressedFrame 构造压缩后的帧，不经过EncodeFrame以构造超过限制的数据
+func compressedFrame(t *testing.T, frameType byte, size int) []byte {
+	var buf bytes.Buffer
+	w, _ := flate.NewWriter(&buf, flate.BestCompression)
+	if _, err := w.Write(make([]byte, size)); err != nil {
+		t.Fatal(err)
+	}
+	w.Close()
+	streamId := "trace"
+	data := make([]byte, 4+len(streamId)+buf.Len())
+	data[0] = frameType
+	data[1] = FlagCompressed
+	binary.BigEndian.PutUint16(data[2:4], uint16(len(streamId)))
+	copy(data[4:], streamId)
+	copy(data[4+len(streamId):], buf.Bytes())
+	return data
+}
+
+func TestDecodeFrameLimit(t *testing.T) {
+	tests := []struct {
+		name      string
+		frameType byte
+		size      int
+		wantErr   bool
+	}{
+		{name: "data frame within limit", frameType: FrameBinary, size: MaxFramePayload},
+		{name: "data frame exceeds limit", frameType: FrameBinary, size: MaxFramePayload + 1, wantErr: true},
+		{name: "request frame larger than data frame", frameType: FrameRequest, size: 1024 * 1024},
+		{name: "request frame exceeds limit", frameType: FrameRequest, size: MaxRequestPayload + 1, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			frame, err := DecodeFrame(compressedFrame(t, tt.frameType, tt.size))
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("decode frame error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err == nil && len(frame.Payload) != tt.size {
+				t.Errorf("payload length = %d, want %d", len(frame.Payload), tt.size)
+			}
+		})
+	}
+}
+
+func TestDecodeFrameInvalid(t *testing.T) {
+	for _, data := range [][]byte{nil, {FrameData, 0, 0}, {FrameData, 0, 0, 10, 'a'}} {
+		if _, err := DecodeFrame(data); err == nil {
+			t.Errorf("decode frame %v should return error", data)
+		}
+	}
+}
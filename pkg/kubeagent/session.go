@@ -0,0 +1,182 @@
+package kubeagent
+
+import (
+	"fmt"
+	"github.com/kubespace/kubespace/pkg/third/httpclient"
+	"github.com/kubespace/kubespace/pkg/utils"
+	"k8s.io/klog/v2"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Version agent版本，编译时通过-ldflags设置
+var Version = "latest"
+
+const (
+	TunnelSessionPath = "/api/v1/cluster/agent/session"
+
+	// HeaderToken 集群导入token，旧版本agent直接使用导入token建立隧道
+	HeaderToken = "token"
+	// HeaderSession 通过导入token换取的短期会话凭证
+	HeaderSession = "session"
+	// HeaderVersion agent版本
+	HeaderVersion = "version"
+)
+
+// SessionResponse 服务端颁发的会话凭证
+type SessionResponse struct {
+	SessionToken string `json:"session_token"`
+	// ExpireSeconds 会话凭证有效期，agent在有效期过半时轮换
+	ExpireSeconds int `json:"expire_seconds"`
+}
+
+// agentSession 使用导入token换取短期会话凭证，并在过期前自动轮换
+type agentSession struct {
+	token      string
+	client     *httpclient.HttpClient
+	mu         sync.Mutex
+	session    string
+	issueTime  time.Time
+	expireTime time.Time
+	// legacyUntil 服务端不支持会话凭证时在该时间之前直接使用导入token，之后重新尝试换取会话凭证，
+	// 避免服务端升级前的404导致agent一直使用导入token
+	legacyUntil   time.Time
+	legacyBackoff *backoff
+}
+
+func newAgentSession(token string, client *httpclient.HttpClient) *agentSession {
+	return &agentSession{token: token, client: client, legacyBackoff: newBackoff(time.Minute, time.Hour)}
+}
+
+// legacy 当前是否使用导入token
+func (s *agentSession) legacy() bool {
+	return time.Now().Before(s.legacyUntil)
+}
+
+// Get 获取有效的会话凭证，没有或者已过期时使用导入token重新换取
+func (s *agentSession) Get() (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.legacy() {
+		return "", nil
+	}
+	if s.session != "" && time.Now().Before(s.expireTime) {
+		return s.session, nil
+	}
+	return s.exchange(s.token)
+}
+
+// Invalidate 会话凭证被服务端拒绝时清除，下次使用时重新换取
+func (s *agentSession) Invalidate() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.session = ""
+}
+
+// Header 请求服务端时的认证header，不支持会话凭证时使用导入token
+func (s *agentSession) Header() http.Header {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	header := http.Header{}
+	if s.session != "" {
+		header.Set(HeaderSession, s.session)
+	} else {
+		header.Set(HeaderToken, s.token)
+	}
+	header.Set(HeaderVersion, Version)
+	return header
+}
+
+// Credential 当前使用的凭证，用于获取agent yaml
+func (s *agentSession) Credential() string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.session != "" {
+		return s.session
+	}
+	return s.token
+}
+
+func (s *agentSession) exchange(credential string) (string, error) {
+	var resp utils.Response
+	options := httpclient.RequestOptions{}
+	options.WithHeader(HeaderToken, credential)
+	options.WithHeader(HeaderVersion, Version)
+	if _, err := s.client.Post(TunnelSessionPath, nil, &resp, options); err != nil {
+		if strings.Contains(err.Error(), "status_code=404") {
+			retry := s.legacyBackoff.Next()
+			klog.Warningf("server does not support agent session, use cluster token instead and retry after %s", retry)
+			s.legacyUntil = time.Now().Add(retry)
+			return "", nil
+		}
+		return "", err
+	}
+	if !resp.IsSuccess() {
+		return "", fmt.Errorf("%s", resp.Msg)
+	}
+	var sessionResp SessionResponse
+	if err := utils.ConvertTypeByJson(resp.Data, &sessionResp); err != nil {
+		return "", err
+	}
+	now := time.Now()
+	s.legacyUntil = time.Time{}
+	s.legacyBackoff.Reset()
+	s.session = sessionResp.SessionToken
+	s.issueTime = now
+	s.expireTime = now.Add(time.Duration(sessionResp.ExpireSeconds) * time.Second)
+	return s.session, nil
+}
+
+// Run 在会话凭证有效期过半时使用当前凭证轮换，当前凭证不可用时使用导入token重新换取，
+// 服务端不支持会话凭证时在重试时间后重新换取
+func (s *agentSession) Run(stopCh <-chan struct{}) {
+	b := newBackoff(time.Second, time.Minute)
+	for {
+		s.mu.Lock()
+		wait := time.Minute
+		if s.legacy() {
+			wait = time.Until(s.legacyUntil)
+		} else if s.session != "" {
+			wait = time.Until(s.issueTime.Add(s.expireTime.Sub(s.issueTime) / 2))
+		}
+		s.mu.Unlock()
+		select {
+		case <-stopCh:
+			return
+		case <-time.After(wait):
+		}
+		if err := s.rotate(); err != nil {
+			klog.Errorf("rotate agent session error: %s", err.Error())
+			select {
+			case <-stopCh:
+				return
+			case <-time.After(b.Next()):
+			}
+			continue
+		}
+		b.Reset()
+	}
+}
+
+func (s *agentSession) rotate() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.legacy() {
+		return nil
+	}
+	if s.session == "" {
+		// 之前服务端不支持会话凭证，重试时间到达后使用导入token重新换取
+		_, err := s.exchange(s.token)
+		return err
+	}
+	if _, err := s.exchange(s.session); err == nil {
+		klog.Info("rotate agent session success")
+		return nil
+	} else {
+		klog.Warningf("rotate agent session with current session error: %s, retry with cluster token", err.Error())
+	}
+	_, err := s.exchange(s.token)
+	return err
+}
@@ -0,0 +1,55 @@
+package kubeagent
+
+import (
+	"encoding/json"
+	"github.com/kubespace/kubespace/pkg/core/code"
+	"github.com/kubespace/kubespace/pkg/third/httpclient"
+	"github.com/kubespace/kubespace/pkg/utils"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+// TestSessionRetryAfterLegacy 服务端不支持会话凭证时使用导入token，重试时间到达后重新换取会话凭证
+func TestSessionRetryAfterLegacy(t *testing.T) {
+	supported := false
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !supported {
+			http.NotFound(w, r)
+			return
+		}
+		json.NewEncoder(w).Encode(&utils.Response{
+			Code: code.Success,
+			Data: &SessionResponse{SessionToken: "session", ExpireSeconds: 3600},
+		})
+	}))
+	defer server.Close()
+	client, err := httpclient.NewHttpClient(server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := newAgentSession("token", client)
+
+	session, err := s.Get()
+	if err != nil || session != "" {
+		t.Fatalf("Get() on legacy server = (%q, %v), want empty session", session, err)
+	}
+	if got := s.Header().Get(HeaderToken); got != "token" {
+		t.Errorf("legacy header token = %q, want token", got)
+	}
+
+	// 服务端升级后，重试时间之前仍使用导入token
+	supported = true
+	if session, _ = s.Get(); session != "" {
+		t.Errorf("Get() before retry = %q, want empty session", session)
+	}
+
+	s.legacyUntil = time.Now().Add(-time.Second)
+	if session, err = s.Get(); err != nil || session != "session" {
+		t.Fatalf("Get() after retry = (%q, %v), want session", session, err)
+	}
+	if got := s.Header().Get(HeaderSession); got != "session" {
+		t.Errorf("header session = %q, want session", got)
+	}
+}
@@ -0,0 +1,85 @@
+package kubeagent
+
+import (
+	"fmt"
+	"sync"
+)
+
+var errStreamCanceled = fmt.Errorf("stream canceled")
+
+// stream 隧道v2协议中一个请求的数据流，发送数据前需要获取窗口，窗口不足时等待server返还
+type stream struct {
+	// sendMu 保证同一stream的消息按顺序完整发送，拆分的帧不会与其他消息交错
+	sendMu   sync.Mutex
+	window   int
+	canceled bool
+}
+
+// streams agent所有正在发送数据的stream，stream在请求返回或者会话关闭后删除
+type streams struct {
+	mu    sync.Mutex
+	cond  *sync.Cond
+	items map[string]*stream
+}
+
+func newStreams() *streams {
+	s := &streams{items: make(map[string]*stream)}
+	s.cond = sync.NewCond(&s.mu)
+	return s
+}
+
+func (s *streams) get(id string) *stream {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	st, ok := s.items[id]
+	if !ok {
+		st = &stream{window: InitialStreamWindow}
+		s.items[id] = st
+	}
+	return st
+}
+
+// acquire 获取发送数据的窗口，窗口不足时阻塞，stream被取消时返回错误
+func (s *streams) acquire(st *stream, size int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for st.window < size && !st.canceled {
+		s.cond.Wait()
+	}
+	if st.canceled {
+		return errStreamCanceled
+	}
+	st.window -= size
+	return nil
+}
+
+// credit server处理完数据后返还窗口
+func (s *streams) credit(id string, size int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if st, ok := s.items[id]; ok {
+		st.window += size
+		s.cond.Broadcast()
+	}
+}
+
+// remove 删除stream，等待窗口的发送直接返回
+func (s *streams) remove(id string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if st, ok := s.items[id]; ok {
+		st.canceled = true
+		delete(s.items, id)
+		s.cond.Broadcast()
+	}
+}
+
+// reset 重新连接后server不会返还之前连接的窗口，重置所有stream的窗口
+func (s *streams) reset() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, st := range s.items {
+		st.window = InitialStreamWindow
+	}
+	s.cond.Broadcast()
+}
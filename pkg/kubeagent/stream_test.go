@@ -0,0 +1,83 @@
+package kubeagent
+
+import (
+	"testing"
+	"time"
+)
+
+func TestStreamWindow(t *testing.T) {
+	s := newStreams()
+	st := s.get("trace")
+	if err := s.acquire(st, InitialStreamWindow); err != nil {
+		t.Fatalf("acquire initial window error: %s", err)
+	}
+
+	acquired := make(chan error, 1)
+	go func() {
+		acquired <- s.acquire(st, MaxFramePayload)
+	}()
+	select {
+	case <-acquired:
+		t.Fatal("acquire should block when window is exhausted")
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	// 返还的窗口不足时继续等待
+	s.credit("trace", MaxFramePayload-1)
+	select {
+	case <-acquired:
+		t.Fatal("acquire should block until enough window is credited")
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	s.credit("trace", 1)
+	select {
+	case err := <-acquired:
+		if err != nil {
+			t.Fatalf("acquire error: %s", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("acquire should return after window credited")
+	}
+	if st.window != 0 {
+		t.Errorf("window = %d, want 0", st.window)
+	}
+}
+
+func TestStreamWindowCanceled(t *testing.T) {
+	s := newStreams()
+	st := s.get("trace")
+	if err := s.acquire(st, InitialStreamWindow); err != nil {
+		t.Fatal(err)
+	}
+	acquired := make(chan error, 1)
+	go func() {
+		acquired <- s.acquire(st, 1)
+	}()
+	s.remove("trace")
+	select {
+	case err := <-acquired:
+		if err != errStreamCanceled {
+			t.Errorf("acquire error = %v, want %v", err, errStreamCanceled)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("acquire should return after stream removed")
+	}
+	// 删除后的stream返还窗口不生效
+	s.credit("trace", 1)
+	if st.window != 0 {
+		t.Errorf("window = %d, want 0", st.window)
+	}
+}
+
+func TestStreamWindowReset(t *testing.T) {
+	s := newStreams()
+	st := s.get("trace")
+	if err := s.acquire(st, InitialStreamWindow); err != nil {
+		t.Fatal(err)
+	}
+	s.reset()
+	if st.window != InitialStreamWindow {
+		t.Errorf("window = %d, want %d", st.window, InitialStreamWindow)
+	}
+}
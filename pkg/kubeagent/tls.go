@@ -0,0 +1,83 @@
+package kubeagent
+
+import (
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// newTLSConfig 连接服务端的tls配置，默认使用系统根证书校验服务端证书
+// 指定caFile时只信任该CA，指定caHash时服务端证书链中需要包含公钥sha256与之相同的证书
+func newTLSConfig(serverName, caFile, caHash string, insecureSkipVerify bool) (*tls.Config, error) {
+	if insecureSkipVerify {
+		return &tls.Config{InsecureSkipVerify: true}, nil
+	}
+	config := &tls.Config{ServerName: serverName}
+	if caFile != "" {
+		caBytes, err := os.ReadFile(caFile)
+		if err != nil {
+			return nil, fmt.Errorf("read ca file %s error: %s", caFile, err.Error())
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(caBytes) {
+			return nil, fmt.Errorf("not found certificate in ca file %s", caFile)
+		}
+		config.RootCAs = pool
+	}
+	if caHash != "" {
+		pin := strings.ToLower(strings.TrimPrefix(caHash, "sha256:"))
+		if _, err := hex.DecodeString(pin); err != nil || len(pin) != sha256.Size*2 {
+			return nil, fmt.Errorf("ca hash %s is invalid, format is sha256:<hex>", caHash)
+		}
+		roots := config.RootCAs
+		// 通过VerifyPeerCertificate校验固定的证书，跳过默认的证书校验
+		config.InsecureSkipVerify = true
+		config.VerifyPeerCertificate = func(rawCerts [][]byte, _ [][]*x509.Certificate) error {
+			return verifyPinnedChain(rawCerts, pin, serverName, roots)
+		}
+	}
+	return config, nil
+}
+
+// verifyPinnedChain 校验服务端证书链中存在固定的证书，且服务端证书由该证书签发
+func verifyPinnedChain(rawCerts [][]byte, pin, serverName string, roots *x509.CertPool) error {
+	if len(rawCerts) == 0 {
+		return fmt.Errorf("server did not present certificate")
+	}
+	var certs []*x509.Certificate
+	for _, raw := range rawCerts {
+		cert, err := x509.ParseCertificate(raw)
+		if err != nil {
+			return fmt.Errorf("parse server certificate error: %s", err.Error())
+		}
+		certs = append(certs, cert)
+	}
+	var pinned *x509.Certificate
+	intermediates := x509.NewCertPool()
+	for _, cert := range certs {
+		sum := sha256.Sum256(cert.RawSubjectPublicKeyInfo)
+		if hex.EncodeToString(sum[:]) == pin {
+			pinned = cert
+		} else {
+			intermediates.AddCert(cert)
+		}
+	}
+	if pinned == nil {
+		return fmt.Errorf("server certificate chain does not match pinned ca hash sha256:%s", pin)
+	}
+	if pinned == certs[0] {
+		return nil
+	}
+	if roots == nil {
+		roots = x509.NewCertPool()
+	} else {
+		roots = roots.Clone()
+	}
+	roots.AddCert(pinned)
+	_, err := certs[0].Verify(x509.VerifyOptions{Roots: roots, Intermediates: intermediates, DNSName: serverName})
+	return err
+}
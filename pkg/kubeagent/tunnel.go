@@ -1,12 +1,14 @@
 package kubeagent
 
 import (
-	"crypto/tls"
 	"encoding/json"
 	"github.com/gorilla/websocket"
+	kubetypes "github.com/kubespace/kubespace/pkg/kubernetes/types"
 	"k8s.io/klog/v2"
+	"math/rand"
 	"net/http"
 	"net/url"
+	"sync"
 	"time"
 )
 
@@ -17,6 +19,8 @@ const (
 
 type TunnelCallback interface {
 	OnSuccess()
+	// OnCancel server取消请求后调用，关闭请求对应的会话
+	OnCancel(traceId string)
 }
 
 type ConnectOnSuccess func()
@@ -25,25 +29,37 @@ type Tunnel interface {
 	Run(stopCh <-chan struct{})
 	Receive() <-chan []byte
 	Send(interface{})
+	// CloseStream 请求的数据发送完成后关闭stream，释放窗口
+	CloseStream(traceId string)
 }
 
 type tunnel struct {
-	token       string
+	session     *agentSession
+	dialer      *websocket.Dialer
 	connUrl     *url.URL
 	respUrl     *url.URL
 	wsConn      *websocket.Conn
 	receiveChan chan []byte
 	callback    TunnelCallback
 	stopped     bool
+
+	// writeMu 保护wsConn以及v2的写入，websocket同时写会报错
+	writeMu sync.Mutex
+	// v2 server是否支持v2协议，每次连接时协商
+	v2      bool
+	streams *streams
 }
 
-func NewTunnel(token string, serverHost string, callback TunnelCallback) Tunnel {
+func NewTunnel(config *AgentConfig, session *agentSession, callback TunnelCallback) Tunnel {
+	scheme := config.WebsocketScheme()
 	return &tunnel{
-		token:       token,
-		connUrl:     &url.URL{Scheme: "ws", Host: serverHost, Path: TunnelConnectPath},
-		respUrl:     &url.URL{Scheme: "ws", Host: serverHost, Path: TunnelResponsePath},
+		session:     session,
+		dialer:      &websocket.Dialer{TLSClientConfig: config.TLSConfig, HandshakeTimeout: 30 * time.Second},
+		connUrl:     &url.URL{Scheme: scheme, Host: config.ServerHost, Path: TunnelConnectPath},
+		respUrl:     &url.URL{Scheme: scheme, Host: config.ServerHost, Path: TunnelResponsePath},
 		callback:    callback,
-		receiveChan: make(chan []byte),
+		receiveChan: make(chan []byte, 100),
+		streams:     newStreams(),
 	}
 }
 
@@ -57,44 +73,77 @@ func (t *tunnel) Run(stopCh <-chan struct{}) {
 			}
 		}
 	}()
-	defer t.wsConn.Close()
+	go t.session.Run(stopCh)
 
-	t.connect()
+	if !t.connect(stopCh) {
+		return
+	}
 	for {
-		_, data, err := t.wsConn.ReadMessage()
+		msgType, data, err := t.wsConn.ReadMessage()
 		if t.stopped {
+			t.wsConn.Close()
 			return
 		}
 		if err != nil {
 			klog.Error("read err:", err)
 			t.wsConn.Close()
-			t.connect()
+			if !t.connect(stopCh) {
+				return
+			}
+			continue
+		}
+		if msgType == websocket.BinaryMessage {
+			t.handleFrame(data)
 			continue
 		}
 		t.receiveChan <- data
 	}
 }
 
+// handleFrame 处理v2协议server发送的帧
+func (t *tunnel) handleFrame(data []byte) {
+	frame, err := DecodeFrame(data)
+	if err != nil {
+		klog.Errorf("decode tunnel frame error: %s", err.Error())
+		return
+	}
+	switch frame.Type {
+	case FrameRequest:
+		t.receiveChan <- frame.Payload
+	case FrameWindow:
+		t.streams.credit(frame.StreamId, frame.WindowSize())
+	case FrameCancel:
+		t.streams.remove(frame.StreamId)
+		go t.callback.OnCancel(frame.StreamId)
+	default:
+		klog.Warningf("unknown tunnel frame type %d of stream %s", frame.Type, frame.StreamId)
+	}
+}
+
 func (t *tunnel) Receive() <-chan []byte {
 	return t.receiveChan
 }
 
 func (t *tunnel) Send(obj interface{}) {
+	t.writeMu.Lock()
+	v2 := t.v2
+	t.writeMu.Unlock()
+	if resp, ok := obj.(*kubetypes.Response); ok && v2 {
+		t.sendFrames(resp)
+		return
+	}
 	respMsg, err := json.Marshal(obj)
 	if err != nil {
 		klog.Errorf("response %v serializer error: %s", obj, err)
 		return
 	}
 
-	klog.V(1).Info("start connect to server response websocket", t.connUrl.String())
-	wsHeader := http.Header{}
-	wsHeader.Add("token", t.token)
-
-	d := &websocket.Dialer{TLSClientConfig: &tls.Config{RootCAs: nil, InsecureSkipVerify: true}}
-	conn, _, err := d.Dial(t.respUrl.String(), wsHeader)
-
+	klog.V(1).Info("start connect to server response websocket", t.respUrl.String())
+	conn, resp, err := t.dialer.Dial(t.respUrl.String(), t.session.Header())
 	if err != nil {
+		t.checkAuth(resp)
 		klog.Errorf("connect to server %s error: %v", t.respUrl.String(), err)
+		return
 	}
 	defer conn.Close()
 	if err = conn.WriteMessage(websocket.TextMessage, respMsg); err != nil {
@@ -105,37 +154,133 @@ func (t *tunnel) Send(obj interface{}) {
 
 }
 
-func (t *tunnel) connect() {
-	err := t.connectServer()
-	if err == nil {
-		return
+// sendFrames v2协议通过隧道连接发送返回数据，exec/log的原始数据使用binary帧，其他数据序列化为json后使用data帧，
+// 超过帧大小时拆分发送，每个帧发送前需要获取stream窗口
+func (t *tunnel) sendFrames(resp *kubetypes.Response) {
+	frameType := FrameData
+	var payload []byte
+	switch data := resp.Data.(type) {
+	case string:
+		frameType, payload = FrameBinary, []byte(data)
+	case []byte:
+		frameType, payload = FrameBinary, data
+	default:
+		var err error
+		if payload, err = json.Marshal(data); err != nil {
+			klog.Errorf("response %v serializer error: %s", resp, err)
+			return
+		}
+	}
+	st := t.streams.get(resp.TraceId)
+	st.sendMu.Lock()
+	defer st.sendMu.Unlock()
+	for offset := 0; ; offset += MaxFramePayload {
+		end := offset + MaxFramePayload
+		if end > len(payload) {
+			end = len(payload)
+		}
+		frame := &Frame{Type: frameType, StreamId: resp.TraceId, Payload: payload[offset:end]}
+		if end == len(payload) {
+			frame.Flags |= FlagFin
+		}
+		if err := t.streams.acquire(st, len(frame.Payload)); err != nil {
+			klog.V(1).Infof("stream %s canceled, drop response", resp.TraceId)
+			return
+		}
+		if err := t.writeFrame(frame); err != nil {
+			klog.Errorf("write stream %s frame error: %s", resp.TraceId, err.Error())
+			return
+		}
+		if frame.Fin() {
+			return
+		}
+	}
+}
+
+func (t *tunnel) writeFrame(frame *Frame) error {
+	data, err := EncodeFrame(frame)
+	if err != nil {
+		return err
 	}
-	ticker := time.NewTicker(time.Second * 5)
-	defer ticker.Stop()
+	t.writeMu.Lock()
+	defer t.writeMu.Unlock()
+	return t.wsConn.WriteMessage(websocket.BinaryMessage, data)
+}
 
+func (t *tunnel) CloseStream(traceId string) {
+	t.streams.remove(traceId)
+}
+
+// connect 连接服务端，失败时按指数退避加随机抖动重试，避免大量agent同时重连，agent停止时返回false
+func (t *tunnel) connect(stopCh <-chan struct{}) bool {
+	b := newBackoff(time.Second, time.Minute)
 	for {
+		err := t.connectServer()
+		if err == nil {
+			return true
+		}
+		wait := b.Next()
+		klog.Infof("connect to server %s error: %v, retry after %s", t.connUrl.String(), err, wait)
 		select {
-		case <-ticker.C:
-			err := t.connectServer()
-			if err == nil {
-				return
-			}
+		case <-stopCh:
+			return false
+		case <-time.After(wait):
 		}
 	}
 }
 
 func (t *tunnel) connectServer() error {
 	klog.Info("start connect to server ", t.connUrl.String())
-	wsHeader := http.Header{}
-	wsHeader.Add("token", t.token)
-	d := &websocket.Dialer{TLSClientConfig: &tls.Config{RootCAs: nil, InsecureSkipVerify: true}}
-	conn, _, err := d.Dial(t.connUrl.String(), wsHeader)
+	if _, err := t.session.Get(); err != nil {
+		return err
+	}
+	header := t.session.Header()
+	header.Set(HeaderTunnelProtocol, TunnelProtocolV2)
+	conn, resp, err := t.dialer.Dial(t.connUrl.String(), header)
 	if err != nil {
-		klog.Infof("connect to server %s error: %v, retry after 5 seconds\n", t.connUrl.String(), err)
+		t.checkAuth(resp)
 		return err
-	} else {
-		klog.Infof("connect to server %s success\n", t.connUrl.String())
-		t.wsConn = conn
-		return nil
 	}
+	// 旧版本server不返回协议头，使用之前的json消息以及单独的返回连接
+	v2 := resp.Header.Get(HeaderTunnelProtocol) == TunnelProtocolV2
+	klog.Infof("connect to server %s success, tunnel protocol v2: %v\n", t.connUrl.String(), v2)
+	t.writeMu.Lock()
+	t.wsConn = conn
+	t.v2 = v2
+	t.writeMu.Unlock()
+	t.streams.reset()
+	return nil
+}
+
+// checkAuth 服务端拒绝会话凭证时，清除凭证以便重新换取
+func (t *tunnel) checkAuth(resp *http.Response) {
+	if resp != nil && (resp.StatusCode == http.StatusForbidden || resp.StatusCode == http.StatusUnauthorized) {
+		t.session.Invalidate()
+	}
+}
+
+// backoff 指数退避，每次等待时间在基础时间的0.5到1.5倍之间随机
+type backoff struct {
+	base    time.Duration
+	max     time.Duration
+	attempt int
+}
+
+func newBackoff(base, max time.Duration) *backoff {
+	return &backoff{base: base, max: max}
+}
+
+func (b *backoff) Next() time.Duration {
+	d := b.max
+	if b.attempt < 30 {
+		if exp := b.base << b.attempt; exp < b.max {
+			d = exp
+			b.attempt++
+		}
+	}
+	return d/2 + time.Duration(rand.Int63n(int64(d)))
+}
+
+func (b *backoff) Reset() {
+	b.attempt = 0
 }
@@ -0,0 +1,415 @@
+package cache
+
+import (
+	"fmt"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/watch"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/dynamic/dynamicinformer"
+	toolscache "k8s.io/client-go/tools/cache"
+	"k8s.io/klog/v2"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// DefaultResources 默认缓存的资源，secret以及event数据量大或者敏感，默认不缓存
+var DefaultResources = []string{
+	"namespaces", "nodes", "pods", "deployments", "statefulsets", "daemonsets", "replicasets",
+	"jobs", "cronjobs", "services", "endpoints", "ingresses", "configmaps", "persistentvolumeclaims",
+	"persistentvolumes", "storageclasses", "serviceaccounts",
+}
+
+const (
+	// DefaultMaxObjects 默认缓存的对象总数上限
+	DefaultMaxObjects = 200000
+	// syncTimeout 等待informer同步完成的时间，超时后直接访问apiserver
+	syncTimeout = 30 * time.Second
+	// retryInterval 资源informer启动失败后（如没有list权限），间隔一段时间后再次尝试
+	retryInterval = 5 * time.Minute
+	// watchBufferSize 每个watch会话缓冲的事件数，消费过慢时关闭会话，由客户端重新watch
+	watchBufferSize = 1000
+	// writeBypassPeriod 通过agent写入资源后，该时间内的读取直接访问apiserver，避免读到写入之前的缓存
+	writeBypassPeriod = 5 * time.Second
+)
+
+type Options struct {
+	// Resources 缓存的资源名称，如pods、deployments
+	Resources []string
+	// MaxObjects 缓存的对象总数上限，超过后停止新增数据的资源缓存，修改配置重启agent前不再缓存该资源
+	MaxObjects int64
+}
+
+// Cache agent端共享的资源缓存，每种资源只启动一个informer，
+// list/get从缓存中获取，多个watch会话共用informer的事件，减少对apiserver的请求
+type Cache struct {
+	client     dynamic.Interface
+	resources  map[string]bool
+	maxObjects int64
+	objects    int64
+	stopCh     <-chan struct{}
+	mu         sync.Mutex
+	types      map[schema.GroupVersionResource]*typeCache
+}
+
+func New(client dynamic.Interface, options *Options, stopCh <-chan struct{}) *Cache {
+	c := &Cache{
+		client:     client,
+		resources:  make(map[string]bool),
+		maxObjects: options.MaxObjects,
+		stopCh:     stopCh,
+		types:      make(map[schema.GroupVersionResource]*typeCache),
+	}
+	if c.maxObjects <= 0 {
+		c.maxObjects = DefaultMaxObjects
+	}
+	for _, res := range options.Resources {
+		c.resources[res] = true
+	}
+	return c
+}
+
+// typeCache 一种资源的informer以及watch会话
+type typeCache struct {
+	gvr      schema.GroupVersionResource
+	informer toolscache.SharedIndexInformer
+	stopCh   chan struct{}
+	synced   chan struct{}
+	// failed 启动失败或者超过对象上限后不可用，启动失败的资源failedTime之后再次尝试
+	failed     bool
+	failedTime time.Time
+	// disabled 超过对象上限后停止缓存，不再重新尝试，避免周期性地重新list全部数据
+	disabled bool
+	count    int64
+	// written 最近一次通过agent写入资源的时间（UnixNano）
+	written int64
+
+	mu       sync.Mutex
+	stopped  bool
+	watchers map[*cacheWatcher]struct{}
+}
+
+// getType 获取资源缓存，第一次访问时启动informer并等待同步，不可用时返回nil
+func (c *Cache) getType(gvr schema.GroupVersionResource) *typeCache {
+	if c == nil || !c.resources[gvr.Resource] {
+		return nil
+	}
+	c.mu.Lock()
+	t, ok := c.types[gvr]
+	if ok && t.failed && !t.disabled && time.Since(t.failedTime) > retryInterval {
+		ok = false
+	}
+	if !ok {
+		t = c.startType(gvr)
+		c.types[gvr] = t
+	}
+	c.mu.Unlock()
+	select {
+	case <-t.synced:
+	case <-time.After(syncTimeout):
+		return nil
+	}
+	if c.isFailed(t) {
+		return nil
+	}
+	return t
+}
+
+func (c *Cache) isFailed(t *typeCache) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return t.failed
+}
+
+func (c *Cache) startType(gvr schema.GroupVersionResource) *typeCache {
+	t := &typeCache{
+		gvr:      gvr,
+		stopCh:   make(chan struct{}),
+		synced:   make(chan struct{}),
+		watchers: make(map[*cacheWatcher]struct{}),
+	}
+	t.informer = dynamicinformer.NewFilteredDynamicInformer(
+		c.client, gvr, "", 0, toolscache.Indexers{toolscache.NamespaceIndex: toolscache.MetaNamespaceIndexFunc}, nil).Informer()
+	// managedFields占用内存较多，并且页面不需要展示
+	t.informer.SetTransform(func(obj interface{}) (interface{}, error) {
+		if u, ok := obj.(*unstructured.Unstructured); ok {
+			u.SetManagedFields(nil)
+		}
+		return obj, nil
+	})
+	t.informer.SetWatchErrorHandler(func(r *toolscache.Reflector, err error) {
+		klog.Warningf("resource %s cache watch error: %s", gvr.String(), err.Error())
+	})
+	t.informer.AddEventHandler(toolscache.ResourceEventHandlerDetailedFuncs{
+		AddFunc: func(obj interface{}, isInInitialList bool) {
+			atomic.AddInt64(&t.count, 1)
+			if atomic.AddInt64(&c.objects, 1) > c.maxObjects {
+				go c.evict(t, fmt.Sprintf("cache objects exceed %d", c.maxObjects), true)
+			}
+			if !isInInitialList {
+				t.dispatch(watch.Added, obj)
+			}
+		},
+		UpdateFunc: func(oldObj, newObj interface{}) {
+			t.dispatch(watch.Modified, newObj)
+		},
+		DeleteFunc: func(obj interface{}) {
+			atomic.AddInt64(&t.count, -1)
+			atomic.AddInt64(&c.objects, -1)
+			if tombstone, ok := obj.(toolscache.DeletedFinalStateUnknown); ok {
+				obj = tombstone.Obj
+			}
+			t.dispatch(watch.Deleted, obj)
+		},
+	})
+	go func() {
+		select {
+		case <-c.stopCh:
+			t.stop()
+		case <-t.stopCh:
+		}
+	}()
+	go t.informer.Run(t.stopCh)
+	go func() {
+		defer close(t.synced)
+		ctx := make(chan struct{})
+		time.AfterFunc(syncTimeout, func() { close(ctx) })
+		if !toolscache.WaitForCacheSync(ctx, t.informer.HasSynced) {
+			c.evict(t, "wait for cache sync timeout", false)
+			return
+		}
+		klog.Infof("resource %s cache synced, objects: %d", gvr.String(), atomic.LoadInt64(&t.count))
+	}()
+	return t
+}
+
+// evict 停止资源缓存，之后的请求直接访问apiserver，disable为true时不再重新启动该资源的缓存
+func (c *Cache) evict(t *typeCache, reason string, disable bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if t.failed {
+		return
+	}
+	klog.Warningf("stop resource %s cache: %s", t.gvr.String(), reason)
+	t.failed = true
+	t.failedTime = time.Now()
+	t.disabled = disable
+	t.stop()
+	atomic.AddInt64(&c.objects, -atomic.SwapInt64(&t.count, 0))
+}
+
+func (t *typeCache) stop() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.stopped {
+		return
+	}
+	t.stopped = true
+	close(t.stopCh)
+	for w := range t.watchers {
+		w.close()
+	}
+	t.watchers = nil
+}
+
+// dispatch 将informer事件分发给所有的watch会话，会话缓冲已满时关闭该会话
+func (t *typeCache) dispatch(eventType watch.EventType, obj interface{}) {
+	u, ok := obj.(*unstructured.Unstructured)
+	if !ok {
+		return
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	for w := range t.watchers {
+		if !w.match(u) {
+			continue
+		}
+		select {
+		case w.result <- watch.Event{Type: eventType, Object: u.DeepCopy()}:
+		default:
+			klog.Warningf("resource %s cache watcher is too slow, close it", t.gvr.String())
+			delete(t.watchers, w)
+			w.close()
+		}
+	}
+}
+
+// resourceVersion 缓存当前同步到的资源版本
+func (t *typeCache) resourceVersion() uint64 {
+	rv, _ := strconv.ParseUint(t.informer.LastSyncResourceVersion(), 10, 64)
+	return rv
+}
+
+// fresh 判断缓存是否满足请求的resourceVersion：为空或者为0时可以使用任意版本的缓存，
+// 指定版本时缓存版本不能早于该版本，版本无法比较时直接访问apiserver。
+// 刚写入资源时informer可能还未收到对应的事件，不使用缓存
+func (t *typeCache) fresh(resourceVersion string) bool {
+	if time.Since(time.Unix(0, atomic.LoadInt64(&t.written))) < writeBypassPeriod {
+		return false
+	}
+	if resourceVersion == "" || resourceVersion == "0" {
+		return true
+	}
+	rv, err := strconv.ParseUint(resourceVersion, 10, 64)
+	if err != nil {
+		return false
+	}
+	return t.resourceVersion() >= rv
+}
+
+// List 从缓存中获取资源列表，缓存不可用时ok为false，返回的对象为拷贝，可以修改
+func (c *Cache) List(gvr schema.GroupVersionResource, namespace, name string, selector labels.Selector, resourceVersion string) (items []*unstructured.Unstructured, rv string, ok bool) {
+	t := c.getType(gvr)
+	if t == nil || !t.fresh(resourceVersion) {
+		return nil, "", false
+	}
+	var objs []interface{}
+	if namespace != "" {
+		var err error
+		if objs, err = t.informer.GetIndexer().ByIndex(toolscache.NamespaceIndex, namespace); err != nil {
+			return nil, "", false
+		}
+	} else {
+		objs = t.informer.GetStore().List()
+	}
+	for _, obj := range objs {
+		u, isUnstructured := obj.(*unstructured.Unstructured)
+		if !isUnstructured {
+			continue
+		}
+		if name != "" && u.GetName() != name {
+			continue
+		}
+		if selector != nil && !selector.Matches(labels.Set(u.GetLabels())) {
+			continue
+		}
+		items = append(items, u.DeepCopy())
+	}
+	return items, t.informer.LastSyncResourceVersion(), true
+}
+
+// Get 从缓存中获取资源，缓存不可用或者不满足请求的resourceVersion时ok为false，资源不存在时exists为false
+func (c *Cache) Get(gvr schema.GroupVersionResource, namespace, name, resourceVersion string) (obj *unstructured.Unstructured, exists bool, ok bool) {
+	t := c.getType(gvr)
+	if t == nil || !t.fresh(resourceVersion) {
+		return nil, false, false
+	}
+	key := name
+	if namespace != "" {
+		key = namespace + "/" + name
+	}
+	item, exists, err := t.informer.GetStore().GetByKey(key)
+	if err != nil {
+		return nil, false, false
+	}
+	if !exists {
+		return nil, false, true
+	}
+	u, isUnstructured := item.(*unstructured.Unstructured)
+	if !isUnstructured {
+		return nil, false, false
+	}
+	return u.DeepCopy(), true, true
+}
+
+// Written 记录通过agent写入了资源，gvr为空时写入的资源类型不确定，如helm安装以及apply多个资源，
+// 所有资源的缓存都在writeBypassPeriod内不使用
+func (c *Cache) Written(gvr *schema.GroupVersionResource) {
+	if c == nil {
+		return
+	}
+	now := time.Now().UnixNano()
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for key, t := range c.types {
+		if gvr == nil || key == *gvr {
+			atomic.StoreInt64(&t.written, now)
+		}
+	}
+}
+
+// Watch 在共享的informer上注册watch会话，只接收之后的事件，
+// 指定的resourceVersion早于缓存版本时无法补全中间的事件，ok为false
+func (c *Cache) Watch(gvr schema.GroupVersionResource, namespace, name string, selector labels.Selector, resourceVersion string) (w watch.Interface, ok bool) {
+	t := c.getType(gvr)
+	if t == nil {
+		return nil, false
+	}
+	var rv uint64
+	if resourceVersion != "" && resourceVersion != "0" {
+		var err error
+		if rv, err = strconv.ParseUint(resourceVersion, 10, 64); err != nil || rv < t.resourceVersion() {
+			return nil, false
+		}
+	}
+	cw := &cacheWatcher{
+		typeCache:       t,
+		namespace:       namespace,
+		name:            name,
+		selector:        selector,
+		resourceVersion: rv,
+		result:          make(chan watch.Event, watchBufferSize),
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.stopped {
+		return nil, false
+	}
+	t.watchers[cw] = struct{}{}
+	return cw, true
+}
+
+type cacheWatcher struct {
+	typeCache       *typeCache
+	namespace       string
+	name            string
+	selector        labels.Selector
+	resourceVersion uint64
+	result          chan watch.Event
+	closed          bool
+}
+
+var _ watch.Interface = &cacheWatcher{}
+
+func (w *cacheWatcher) match(obj *unstructured.Unstructured) bool {
+	if w.namespace != "" && obj.GetNamespace() != w.namespace {
+		return false
+	}
+	if w.name != "" && obj.GetName() != w.name {
+		return false
+	}
+	if w.selector != nil && !w.selector.Matches(labels.Set(obj.GetLabels())) {
+		return false
+	}
+	if w.resourceVersion > 0 {
+		// 请求版本之前的事件已经包含在客户端的数据中
+		if rv, err := strconv.ParseUint(obj.GetResourceVersion(), 10, 64); err == nil && rv <= w.resourceVersion {
+			return false
+		}
+	}
+	return true
+}
+
+// close 调用方需持有typeCache的锁
+func (w *cacheWatcher) close() {
+	if w.closed {
+		return
+	}
+	w.closed = true
+	close(w.result)
+}
+
+func (w *cacheWatcher) Stop() {
+	t := w.typeCache
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	delete(t.watchers, w)
+	w.close()
+}
+
+func (w *cacheWatcher) ResultChan() <-chan watch.Event {
+	return w.result
+}
@@ -0,0 +1,121 @@
+package cache
+
+import (
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/dynamic/fake"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+var podGVR = schema.GroupVersionResource{Version: "v1", Resource: "pods"}
+
+func newPod(name, resourceVersion string) *unstructured.Unstructured {
+	pod := &unstructured.Unstructured{}
+	pod.SetAPIVersion("v1")
+	pod.SetKind("Pod")
+	pod.SetNamespace("default")
+	pod.SetName(name)
+	pod.SetResourceVersion(resourceVersion)
+	return pod
+}
+
+// newTestCache 使用fake client创建只缓存pod的资源缓存
+func newTestCache(t *testing.T, maxObjects int64, objects ...runtime.Object) *Cache {
+	scheme := runtime.NewScheme()
+	client := fake.NewSimpleDynamicClientWithCustomListKinds(scheme,
+		map[schema.GroupVersionResource]string{podGVR: "PodList"}, objects...)
+	stopCh := make(chan struct{})
+	t.Cleanup(func() { close(stopCh) })
+	return New(client, &Options{Resources: []string{"pods"}, MaxObjects: maxObjects}, stopCh)
+}
+
+func TestCacheGet(t *testing.T) {
+	c := newTestCache(t, 0, newPod("a", "10"))
+	tests := []struct {
+		name            string
+		pod             string
+		resourceVersion string
+		exists          bool
+		ok              bool
+	}{
+		{name: "any version", pod: "a", exists: true, ok: true},
+		{name: "not exists", pod: "b", exists: false, ok: true},
+		{name: "resource version newer than cache", pod: "a", resourceVersion: "999999", ok: false},
+		{name: "invalid resource version", pod: "a", resourceVersion: "abc", ok: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			obj, exists, ok := c.Get(podGVR, "default", tt.pod, tt.resourceVersion)
+			if ok != tt.ok || exists != tt.exists {
+				t.Fatalf("Get() exists = %v ok = %v, want exists = %v ok = %v", exists, ok, tt.exists, tt.ok)
+			}
+			if exists && obj.GetName() != tt.pod {
+				t.Errorf("Get() name = %s, want %s", obj.GetName(), tt.pod)
+			}
+		})
+	}
+}
+
+func TestCacheWritten(t *testing.T) {
+	c := newTestCache(t, 0, newPod("a", "10"))
+	if _, _, ok := c.Get(podGVR, "default", "a", ""); !ok {
+		t.Fatal("cache should be available before write")
+	}
+	c.Written(&podGVR)
+	if _, _, ok := c.Get(podGVR, "default", "a", ""); ok {
+		t.Error("cache should not be used after write")
+	}
+	if _, _, ok := c.List(podGVR, "default", "", nil, ""); ok {
+		t.Error("cache list should not be used after write")
+	}
+
+	// 超过writeBypassPeriod后重新使用缓存
+	t.Run("after bypass period", func(t *testing.T) {
+		c.types[podGVR].written = time.Now().Add(-writeBypassPeriod).UnixNano()
+		if _, _, ok := c.Get(podGVR, "default", "a", ""); !ok {
+			t.Error("cache should be available after bypass period")
+		}
+	})
+
+	// 资源类型不确定时所有资源都不使用缓存
+	t.Run("all resources", func(t *testing.T) {
+		c.Written(nil)
+		if _, _, ok := c.Get(podGVR, "default", "a", ""); ok {
+			t.Error("cache should not be used after write without gvr")
+		}
+	})
+}
+
+func TestCacheExceedMaxObjects(t *testing.T) {
+	c := newTestCache(t, 1, newPod("a", "10"), newPod("b", "11"))
+	c.getType(podGVR)
+	// 超过上限后异步停止缓存
+	deadline := time.Now().Add(time.Second)
+	for !c.isFailed(c.types[podGVR]) && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	c.mu.Lock()
+	typ := c.types[podGVR]
+	failed, disabled := typ.failed, typ.disabled
+	// 模拟已经超过重试间隔
+	typ.failedTime = time.Now().Add(-2 * retryInterval)
+	c.mu.Unlock()
+	if !failed || !disabled {
+		t.Fatalf("type failed = %v disabled = %v, want both true", failed, disabled)
+	}
+	if got := c.getType(podGVR); got != nil {
+		t.Error("disabled type should not be used")
+	}
+	c.mu.Lock()
+	restarted := c.types[podGVR] != typ
+	c.mu.Unlock()
+	if restarted {
+		t.Error("disabled type should not be restarted")
+	}
+	if objects := atomic.LoadInt64(&c.objects); objects != 0 {
+		t.Errorf("objects = %d, want 0", objects)
+	}
+}
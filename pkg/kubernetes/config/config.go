@@ -2,6 +2,7 @@ package config
 
 import (
 	"fmt"
+	"github.com/kubespace/kubespace/pkg/kubernetes/cache"
 	"github.com/kubespace/kubespace/pkg/kubernetes/kubeclient"
 	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 	"k8s.io/apimachinery/pkg/runtime"
@@ -20,6 +21,8 @@ type KubeConfig struct {
 	Client          kubeclient.Client
 	DecUnstructured runtime.Serializer
 	RestMapper      *restmapper.DeferredDiscoveryRESTMapper
+	// Cache agent端共享的资源缓存，为空时直接访问apiserver
+	Cache *cache.Cache
 }
 
 func NewKubeConfig(options *Options) (c *KubeConfig, err error) {
@@ -42,3 +45,17 @@ func NewKubeConfig(options *Options) (c *KubeConfig, err error) {
 		RestMapper:      restmapper.NewDeferredDiscoveryRESTMapper(memory.NewMemCacheClient(client.Discovery())),
 	}, nil
 }
+
+// Impersonate 以指定的用户以及用户组身份访问集群，资源映射与用户无关，直接复用；
+// 缓存以agent身份获取，不经过用户的权限校验，模拟用户时不使用缓存
+func (c *KubeConfig) Impersonate(userName string, groups []string) (*KubeConfig, error) {
+	client, err := kubeclient.NewImpersonatedClient(c.Client, userName, groups)
+	if err != nil {
+		return nil, err
+	}
+	return &KubeConfig{
+		Client:          client,
+		DecUnstructured: c.DecUnstructured,
+		RestMapper:      c.RestMapper,
+	}, nil
+}
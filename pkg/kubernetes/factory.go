@@ -31,13 +31,21 @@ type kubeFactory struct {
 	config      *config.KubeConfig
 	resourceMap map[string]ResourceHandler
 	mu          sync.Mutex
+	// profile agent的权限配置，为空时不做限制
+	profile *types.AgentProfile
 }
 
 func NewKubeFactory(config *config.KubeConfig) KubeFactory {
+	return NewKubeFactoryWithProfile(config, nil)
+}
+
+// NewKubeFactoryWithProfile 根据agent权限配置限制可以执行的操作，不可执行时返回具体原因
+func NewKubeFactoryWithProfile(config *config.KubeConfig, profile *types.AgentProfile) KubeFactory {
 	return &kubeFactory{
 		config:      config,
 		resourceMap: make(map[string]ResourceHandler),
 		mu:          sync.Mutex{},
+		profile:     profile,
 	}
 }
 
@@ -48,6 +56,7 @@ func (k *kubeFactory) GetResource(resType string) (ResourceHandler, error) {
 	k.mu.Lock()
 	ins, err := k.getResource(resType)
 	if err != nil {
+		k.mu.Unlock()
 		return nil, err
 	}
 	k.resourceMap[resType] = ins
@@ -56,6 +65,14 @@ func (k *kubeFactory) GetResource(resType string) (ResourceHandler, error) {
 }
 
 func (k *kubeFactory) getResource(resType string) (ResourceHandler, error) {
+	handler, err := k.newResource(resType)
+	if err != nil {
+		return nil, err
+	}
+	return withProfile(handler, k.profile, resType), nil
+}
+
+func (k *kubeFactory) newResource(resType string) (ResourceHandler, error) {
 	switch resType {
 	case types.ClusterType:
 		return resource.NewCluster(k.config), nil
@@ -92,6 +92,35 @@ func NewClientWithRestConfig(restConfig *rest.Config) (Client, error) {
 	}, nil
 }
 
+// NewImpersonatedClient 使用与base相同的集群连接配置，通过Impersonate-User/Impersonate-Group请求头模拟用户访问集群
+func NewImpersonatedClient(base Client, userName string, groups []string) (Client, error) {
+	restConfig := rest.CopyConfig(base.RestConfig())
+	restConfig.Impersonate = rest.ImpersonationConfig{
+		UserName: userName,
+		Groups:   groups,
+	}
+	clientSet, err := kubernetes.NewForConfig(restConfig)
+	if err != nil {
+		return nil, err
+	}
+	dynamicClient, err := dynamic.NewForConfig(restConfig)
+	if err != nil {
+		return nil, err
+	}
+	discoveryClient, err := discovery.NewDiscoveryClientForConfig(restConfig)
+	if err != nil {
+		return nil, err
+	}
+	return &client{
+		Interface:  clientSet,
+		dynamic:    dynamicClient,
+		restConfig: restConfig,
+		discovery:  discoveryClient,
+		// 集群版本与模拟的用户无关，不再重新获取
+		serverVersion: base.ServerVersion(),
+	}, nil
+}
+
 func (c *client) RestConfig() *rest.Config {
 	return c.restConfig
 }
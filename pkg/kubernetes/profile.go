@@ -0,0 +1,170 @@
+package kubernetes
+
+import (
+	"fmt"
+	"github.com/kubespace/kubespace/pkg/core/code"
+	"github.com/kubespace/kubespace/pkg/kubernetes/resource"
+	"github.com/kubespace/kubespace/pkg/kubernetes/types"
+	"github.com/kubespace/kubespace/pkg/utils"
+	"strings"
+)
+
+// readOnlyActions 只读权限可以执行的操作
+var readOnlyActions = map[string]bool{
+	types.ListAction:   true,
+	types.GetAction:    true,
+	types.WatchAction:  true,
+	types.LogAction:    true,
+	types.DriftAction:  true,
+	types.HealthAction: true,
+}
+
+// writeActions 修改集群资源的操作，只读权限下不可用
+var writeActions = []string{
+	types.CreateAction,
+	types.UpdateAction,
+	types.PatchAction,
+	types.ApplyAction,
+	types.DeleteAction,
+	types.ExecAction,
+}
+
+// clusterScopedTypes 集群级别的资源，namespace权限下不可用
+var clusterScopedTypes = map[string]bool{
+	types.NamespaceType:                true,
+	types.NodeType:                     true,
+	types.PersistentVolumeType:         true,
+	types.StorageClassType:             true,
+	types.ClusterRoleType:              true,
+	types.ClusterRoleBindingType:       true,
+	types.CustomResourceDefinitionType: true,
+}
+
+// profileParams 请求参数中与权限校验相关的字段
+type profileParams struct {
+	Name        string                          `json:"name"`
+	Namespace   string                          `json:"namespace"`
+	OnlyVersion bool                            `json:"only_version"`
+	Resources   []*resource.DeleteParamResource `json:"resources"`
+}
+
+// checkProfile 校验agent权限配置下是否可以执行该操作，不可以时返回具体的原因，避免直接返回k8s的403错误
+func checkProfile(profile *types.AgentProfile, resType, action string, params interface{}) error {
+	// stdin为exec会话的输入，exec时已经校验过
+	if profile.IsAdmin() || action == types.StdinAction {
+		return nil
+	}
+	switch profile.Name {
+	case types.AgentProfileReadOnly:
+		if readOnlyActions[action] {
+			return nil
+		}
+		return fmt.Errorf("集群agent为只读（readonly）权限，不支持%s资源的%s操作，不可用的操作：%s",
+			resType, action, strings.Join(writeActions, ", "))
+	case types.AgentProfileNamespace:
+		return checkNamespaceProfile(profile, resType, action, params)
+	}
+	return fmt.Errorf("不支持的集群agent权限：%s", profile.Name)
+}
+
+func checkNamespaceProfile(profile *types.AgentProfile, resType, action string, params interface{}) error {
+	// apply的资源命名空间在yaml中定义，由集群RBAC校验
+	if action == types.ApplyAction {
+		return nil
+	}
+	p := &profileParams{}
+	if err := utils.ConvertTypeByJson(params, p); err != nil {
+		return err
+	}
+	allowed := strings.Join(profile.Namespaces, ", ")
+	if resType == types.NamespaceType {
+		if action == types.GetAction && utils.Contains(profile.Namespaces, p.Name) {
+			return nil
+		}
+		return fmt.Errorf("集群agent权限限制在命名空间[%s]，namespace资源只能查看这些命名空间，不支持%s操作", allowed, action)
+	}
+	if resType == types.ClusterType && p.OnlyVersion {
+		return nil
+	}
+	if clusterScopedTypes[resType] {
+		return fmt.Errorf("集群agent权限限制在命名空间[%s]，不支持集群级别资源%s的%s操作", allowed, resType, action)
+	}
+	var namespaces []string
+	if p.Namespace != "" {
+		namespaces = append(namespaces, p.Namespace)
+	}
+	for _, r := range p.Resources {
+		if r.Namespace != "" {
+			namespaces = append(namespaces, r.Namespace)
+		}
+	}
+	if len(namespaces) == 0 {
+		return fmt.Errorf("集群agent权限限制在命名空间[%s]，不支持跨命名空间或者集群级别的%s资源%s操作", allowed, resType, action)
+	}
+	for _, ns := range namespaces {
+		if !utils.Contains(profile.Namespaces, ns) {
+			return fmt.Errorf("集群agent权限限制在命名空间[%s]，不支持命名空间%s中%s资源的%s操作", allowed, ns, resType, action)
+		}
+	}
+	return nil
+}
+
+// profileHandler 执行操作前根据agent权限配置校验
+type profileHandler struct {
+	ResourceHandler
+	profile *types.AgentProfile
+	resType string
+}
+
+func (h *profileHandler) Handle(action string, params interface{}) *utils.Response {
+	if err := checkProfile(h.profile, h.resType, action, params); err != nil {
+		return &utils.Response{Code: code.AuthError, Msg: err.Error()}
+	}
+	return h.forbiddenHint(h.ResourceHandler.Handle(action, params))
+}
+
+func (h *profileHandler) Watch(params interface{}, writer resource.OutWriter) *utils.Response {
+	if err := checkProfile(h.profile, h.resType, types.WatchAction, params); err != nil {
+		return &utils.Response{Code: code.AuthError, Msg: err.Error()}
+	}
+	return h.forbiddenHint(h.ResourceHandler.Watch(params, writer))
+}
+
+// forbiddenHint k8s返回403时，提示当前agent的权限配置
+func (h *profileHandler) forbiddenHint(resp *utils.Response) *utils.Response {
+	if resp != nil && !resp.IsSuccess() && strings.Contains(resp.Msg, "forbidden") {
+		resp.Msg = fmt.Sprintf("%s（集群agent权限为%s，命名空间：%s）", resp.Msg, h.profile.Name, strings.Join(h.profile.Namespaces, ", "))
+	}
+	return resp
+}
+
+type profilePodHandler struct {
+	*profileHandler
+	pod PodHandler
+}
+
+func (h *profilePodHandler) Exec(params interface{}, writer resource.OutWriter) *utils.Response {
+	if err := checkProfile(h.profile, h.resType, types.ExecAction, params); err != nil {
+		return &utils.Response{Code: code.AuthError, Msg: err.Error()}
+	}
+	return h.forbiddenHint(h.pod.Exec(params, writer))
+}
+
+func (h *profilePodHandler) Log(params interface{}, writer resource.OutWriter) *utils.Response {
+	if err := checkProfile(h.profile, h.resType, types.LogAction, params); err != nil {
+		return &utils.Response{Code: code.AuthError, Msg: err.Error()}
+	}
+	return h.forbiddenHint(h.pod.Log(params, writer))
+}
+
+// withProfile 非admin权限时，使用权限校验包装资源操作
+func withProfile(handler ResourceHandler, profile *types.AgentProfile, resType string) ResourceHandler {
+	if profile.IsAdmin() {
+		return handler
+	}
+	ph := &profileHandler{ResourceHandler: handler, profile: profile, resType: resType}
+	if pod, ok := handler.(PodHandler); ok {
+		return &profilePodHandler{profileHandler: ph, pod: pod}
+	}
+	return ph
+}
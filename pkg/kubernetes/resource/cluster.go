@@ -20,8 +20,9 @@ func NewCluster(config *config.KubeConfig) *Cluster {
 	p := &Cluster{}
 	p.Resource = NewResource(config, "", nil, nil)
 	p.actions = map[string]ActionHandle{
-		types.GetAction:   p.Get,
-		types.ApplyAction: p.Apply,
+		types.GetAction:    p.Get,
+		types.ApplyAction:  p.Apply,
+		types.HealthAction: p.Health,
 	}
 	return p
 }
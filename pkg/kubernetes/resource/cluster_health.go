@@ -0,0 +1,301 @@
+package resource
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"github.com/kubespace/kubespace/pkg/core/code"
+	"github.com/kubespace/kubespace/pkg/utils"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"net"
+	"net/url"
+	"time"
+)
+
+const (
+	// pendingPodTimeout pod处于Pending状态超过该时间认为异常
+	pendingPodTimeout = 5 * time.Minute
+	// defaultEventWindow 默认统计最近一小时的warning事件
+	defaultEventWindow = 60
+)
+
+// failingWaitingReasons 容器处于这些等待原因时认为pod异常
+var failingWaitingReasons = map[string]bool{
+	"CrashLoopBackOff":           true,
+	"ImagePullBackOff":           true,
+	"ErrImagePull":               true,
+	"CreateContainerConfigError": true,
+	"CreateContainerError":       true,
+	"InvalidImageName":           true,
+	"RunContainerError":          true,
+}
+
+// controlPlaneComponents kubeadm部署的控制面组件，托管集群中一般获取不到
+var controlPlaneComponents = []string{"kube-apiserver", "kube-controller-manager", "kube-scheduler", "etcd"}
+
+type ClusterHealthParams struct {
+	// EventWindow 统计warning事件的时间范围，单位分钟
+	EventWindow int `json:"event_window"`
+}
+
+// ClusterHealthData 集群健康检查的原始数据，由server端根据阈值判断健康状态
+type ClusterHealthData struct {
+	Version string `json:"version"`
+	// ApiserverLatency 请求apiserver readyz的耗时，单位毫秒
+	ApiserverLatency int64  `json:"apiserver_latency"`
+	ApiserverError   string `json:"apiserver_error"`
+
+	Nodes       []*NodeHealth      `json:"nodes"`
+	Components  []*ComponentHealth `json:"components"`
+	CoreDNS     *CoreDNSHealth     `json:"coredns"`
+	FailingPods []*FailingPod      `json:"failing_pods"`
+
+	EventWindow         int            `json:"event_window"`
+	WarningEvents       int            `json:"warning_events"`
+	WarningEventReasons map[string]int `json:"warning_event_reasons"`
+
+	Certificate *CertificateHealth `json:"certificate"`
+}
+
+type NodeHealth struct {
+	Name          string `json:"name"`
+	Ready         bool   `json:"ready"`
+	Unschedulable bool   `json:"unschedulable"`
+	// Pressures 状态为True的MemoryPressure/DiskPressure/PIDPressure/NetworkUnavailable
+	Pressures []string `json:"pressures"`
+}
+
+type ComponentHealth struct {
+	Name    string `json:"name"`
+	Healthy bool   `json:"healthy"`
+	Message string `json:"message"`
+}
+
+type CoreDNSHealth struct {
+	Found    bool  `json:"found"`
+	Desired  int32 `json:"desired"`
+	Ready    int32 `json:"ready"`
+	Restarts int32 `json:"restarts"`
+}
+
+type FailingPod struct {
+	Namespace string `json:"namespace"`
+	Name      string `json:"name"`
+	Reason    string `json:"reason"`
+}
+
+type CertificateHealth struct {
+	Subject  string    `json:"subject"`
+	NotAfter time.Time `json:"not_after"`
+	Error    string    `json:"error"`
+}
+
+// Health 获取集群健康检查数据，apiserver、控制面组件以及证书获取失败时记录错误信息，不影响其他检查项
+func (c *Cluster) Health(params interface{}) *utils.Response {
+	query := &ClusterHealthParams{}
+	if err := utils.ConvertTypeByJson(params, query); err != nil {
+		return &utils.Response{Code: code.ParamsError, Msg: err.Error()}
+	}
+	if query.EventWindow <= 0 {
+		query.EventWindow = defaultEventWindow
+	}
+	// agent请求的超时时间为30秒，需要在此之前结束
+	ctx, cancel := context.WithTimeout(context.Background(), 25*time.Second)
+	defer cancel()
+	data := &ClusterHealthData{EventWindow: query.EventWindow}
+
+	start := time.Now()
+	if _, err := c.client.Discovery().RESTClient().Get().AbsPath("/readyz").DoRaw(ctx); err != nil {
+		data.ApiserverError = err.Error()
+	}
+	data.ApiserverLatency = time.Since(start).Milliseconds()
+	if version, err := c.client.Discovery().ServerVersion(); err == nil {
+		data.Version = version.GitVersion
+	}
+
+	nodes, err := c.client.CoreV1().Nodes().List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return &utils.Response{Code: code.RequestError, Msg: err.Error()}
+	}
+	for _, node := range nodes.Items {
+		data.Nodes = append(data.Nodes, nodeHealth(&node))
+	}
+
+	pods, err := c.client.CoreV1().Pods("").List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return &utils.Response{Code: code.RequestError, Msg: err.Error()}
+	}
+	for i := range pods.Items {
+		if reason := failingReason(&pods.Items[i]); reason != "" {
+			data.FailingPods = append(data.FailingPods, &FailingPod{
+				Namespace: pods.Items[i].Namespace,
+				Name:      pods.Items[i].Name,
+				Reason:    reason,
+			})
+		}
+	}
+	data.Components = c.componentsHealth(ctx, pods.Items)
+	data.CoreDNS = coreDNSHealth(pods.Items)
+
+	events, err := c.client.CoreV1().Events("").List(ctx, metav1.ListOptions{FieldSelector: "type=" + corev1.EventTypeWarning})
+	if err != nil {
+		return &utils.Response{Code: code.RequestError, Msg: err.Error()}
+	}
+	data.WarningEventReasons = make(map[string]int)
+	since := time.Now().Add(-time.Duration(query.EventWindow) * time.Minute)
+	for _, event := range events.Items {
+		if eventLastTime(&event).Before(since) {
+			continue
+		}
+		data.WarningEvents += 1
+		data.WarningEventReasons[event.Reason] += 1
+	}
+
+	data.Certificate = c.apiserverCertificate()
+	return &utils.Response{Code: code.Success, Data: data}
+}
+
+func nodeHealth(node *corev1.Node) *NodeHealth {
+	h := &NodeHealth{Name: node.Name, Unschedulable: node.Spec.Unschedulable}
+	for _, cond := range node.Status.Conditions {
+		if cond.Type == corev1.NodeReady {
+			h.Ready = cond.Status == corev1.ConditionTrue
+		} else if cond.Status == corev1.ConditionTrue {
+			h.Pressures = append(h.Pressures, string(cond.Type))
+		}
+	}
+	return h
+}
+
+// failingReason 获取pod异常的原因，正常时返回空
+func failingReason(pod *corev1.Pod) string {
+	switch pod.Status.Phase {
+	case corev1.PodSucceeded:
+		return ""
+	case corev1.PodFailed:
+		if pod.Status.Reason != "" {
+			return pod.Status.Reason
+		}
+		return string(corev1.PodFailed)
+	}
+	for _, status := range pod.Status.ContainerStatuses {
+		if status.State.Waiting != nil && failingWaitingReasons[status.State.Waiting.Reason] {
+			return status.State.Waiting.Reason
+		}
+	}
+	if pod.Status.Phase == corev1.PodPending && time.Since(pod.CreationTimestamp.Time) > pendingPodTimeout {
+		return string(corev1.PodPending)
+	}
+	return ""
+}
+
+// componentsHealth 优先通过kube-system中的控制面静态pod判断组件状态，获取不到时使用componentstatuses
+func (c *Cluster) componentsHealth(ctx context.Context, pods []corev1.Pod) []*ComponentHealth {
+	var components []*ComponentHealth
+	for _, name := range controlPlaneComponents {
+		for i := range pods {
+			pod := &pods[i]
+			if pod.Namespace != metav1.NamespaceSystem || pod.Labels["component"] != name {
+				continue
+			}
+			h := &ComponentHealth{Name: name + "/" + pod.Spec.NodeName, Healthy: podReady(pod)}
+			if !h.Healthy {
+				h.Message = failingReason(pod)
+			}
+			components = append(components, h)
+		}
+	}
+	if len(components) > 0 {
+		return components
+	}
+	statuses, err := c.client.CoreV1().ComponentStatuses().List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil
+	}
+	for _, status := range statuses.Items {
+		h := &ComponentHealth{Name: status.Name}
+		for _, cond := range status.Conditions {
+			if cond.Type == corev1.ComponentHealthy {
+				h.Healthy = cond.Status == corev1.ConditionTrue
+				h.Message = cond.Message + cond.Error
+			}
+		}
+		components = append(components, h)
+	}
+	return components
+}
+
+func coreDNSHealth(pods []corev1.Pod) *CoreDNSHealth {
+	h := &CoreDNSHealth{}
+	for i := range pods {
+		pod := &pods[i]
+		if pod.Namespace != metav1.NamespaceSystem || pod.Labels["k8s-app"] != "kube-dns" {
+			continue
+		}
+		h.Found = true
+		h.Desired += 1
+		if podReady(pod) {
+			h.Ready += 1
+		}
+		for _, status := range pod.Status.ContainerStatuses {
+			h.Restarts += status.RestartCount
+		}
+	}
+	return h
+}
+
+func podReady(pod *corev1.Pod) bool {
+	for _, cond := range pod.Status.Conditions {
+		if cond.Type == corev1.PodReady {
+			return cond.Status == corev1.ConditionTrue
+		}
+	}
+	return false
+}
+
+func eventLastTime(event *corev1.Event) time.Time {
+	if !event.LastTimestamp.IsZero() {
+		return event.LastTimestamp.Time
+	}
+	if event.Series != nil && !event.Series.LastObservedTime.IsZero() {
+		return event.Series.LastObservedTime.Time
+	}
+	if !event.EventTime.IsZero() {
+		return event.EventTime.Time
+	}
+	return event.CreationTimestamp.Time
+}
+
+// apiserverCertificate 获取apiserver服务证书的过期时间，只读取证书，不校验证书链
+func (c *Cluster) apiserverCertificate() *CertificateHealth {
+	h := &CertificateHealth{}
+	u, err := url.Parse(c.client.RestConfig().Host)
+	if err != nil {
+		h.Error = err.Error()
+		return h
+	}
+	if u.Scheme != "https" {
+		h.Error = "apiserver未使用https"
+		return h
+	}
+	host := u.Host
+	if u.Port() == "" {
+		host = net.JoinHostPort(u.Hostname(), "443")
+	}
+	dialer := &net.Dialer{Timeout: 5 * time.Second}
+	conn, err := tls.DialWithDialer(dialer, "tcp", host, &tls.Config{InsecureSkipVerify: true})
+	if err != nil {
+		h.Error = err.Error()
+		return h
+	}
+	defer conn.Close()
+	certs := conn.ConnectionState().PeerCertificates
+	if len(certs) == 0 {
+		h.Error = fmt.Sprintf("apiserver %s未返回证书", host)
+		return h
+	}
+	h.Subject = certs[0].Subject.CommonName
+	h.NotAfter = certs[0].NotAfter
+	return h
+}
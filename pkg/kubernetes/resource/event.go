@@ -8,7 +8,9 @@ import (
 	"github.com/kubespace/kubespace/pkg/utils"
 	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 	"k8s.io/apimachinery/pkg/fields"
+	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/runtime/schema"
 	"strings"
 )
@@ -16,7 +18,7 @@ import (
 var EventGVR = &schema.GroupVersionResource{
 	Group:    "",
 	Version:  "v1",
-	Resource: "namespaces",
+	Resource: "events",
 }
 
 type Event struct {
@@ -24,9 +26,8 @@ type Event struct {
 }
 
 func NewEvent(config *config.KubeConfig) *Event {
-	p := &Event{
-		Resource: NewResource(config, types.PodType, EventGVR, nil),
-	}
+	p := &Event{}
+	p.Resource = NewResource(config, types.EventType, EventGVR, p.listObjectProcess)
 	p.actions = map[string]ActionHandle{
 		types.ListAction: p.List,
 		types.GetAction:  p.Get,
@@ -36,6 +37,7 @@ func NewEvent(config *config.KubeConfig) *Event {
 
 type BuildEvent struct {
 	UID             string                  `json:"uid"`
+	Name            string                  `json:"name"`
 	Namespace       string                  `json:"namespace"`
 	Reason          string                  `json:"reason"`
 	Message         string                  `json:"message"`
@@ -43,6 +45,7 @@ type BuildEvent struct {
 	Object          *corev1.ObjectReference `json:"object"`
 	Source          *corev1.EventSource     `json:"source"`
 	EventTime       metav1.Time             `json:"event_time"`
+	FirstTime       metav1.Time             `json:"first_time"`
 	Count           int32                   `json:"count"`
 	ResourceVersion string                  `json:"resource_version"`
 }
@@ -58,8 +61,13 @@ func (e *Event) ToBuildEvent(event *corev1.Event) *BuildEvent {
 	if eventTime.IsZero() {
 		eventTime = event.CreationTimestamp
 	}
+	firstTime := event.FirstTimestamp
+	if firstTime.IsZero() {
+		firstTime = eventTime
+	}
 	eventData := &BuildEvent{
 		UID:             string(event.UID),
+		Name:            event.Name,
 		Namespace:       event.Namespace,
 		Reason:          event.Reason,
 		Message:         event.Message,
@@ -67,6 +75,7 @@ func (e *Event) ToBuildEvent(event *corev1.Event) *BuildEvent {
 		Object:          &event.InvolvedObject,
 		Source:          &event.Source,
 		EventTime:       eventTime,
+		FirstTime:       firstTime,
 		Count:           event.Count,
 		ResourceVersion: event.ResourceVersion,
 	}
@@ -74,6 +83,15 @@ func (e *Event) ToBuildEvent(event *corev1.Event) *BuildEvent {
 	return eventData
 }
 
+// listObjectProcess watch事件时转换为BuildEvent返回
+func (e *Event) listObjectProcess(query *QueryParams, obj *unstructured.Unstructured) (interface{}, error) {
+	event := &corev1.Event{}
+	if err := runtime.DefaultUnstructuredConverter.FromUnstructured(obj.Object, event); err != nil {
+		return nil, err
+	}
+	return e.ToBuildEvent(event), nil
+}
+
 func (e *Event) List(params interface{}) *utils.Response {
 	query := &QueryParams{}
 	if err := utils.ConvertTypeByJson(params, query); err != nil {
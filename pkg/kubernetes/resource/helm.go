@@ -41,6 +41,7 @@ func NewHelm(conf *config.KubeConfig) *Helm {
 		types.CreateAction: p.Create,
 		types.DeleteAction: p.Delete,
 		types.UpdateAction: p.Update,
+		types.DriftAction:  p.Drift,
 	}
 	return p
 }
@@ -327,11 +328,58 @@ func (h *Helm) addWorkloadObject(
 	return resources, nil
 }
 
+const (
+	// HelmDefaultMaxHistory release默认保留的历史版本数
+	HelmDefaultMaxHistory = 3
+	// HelmDefaultTimeout 等待资源就绪以及hook执行的默认超时时间，单位秒
+	HelmDefaultTimeout = 300
+)
+
+// HelmInstallOptions helm安装/升级选项
+type HelmInstallOptions struct {
+	// Wait 等待所有资源就绪后才返回成功
+	Wait bool `json:"wait"`
+	// WaitForJobs 等待所有job执行完成，需要同时设置Wait
+	WaitForJobs bool `json:"wait_for_jobs"`
+	// Timeout 等待资源就绪以及hook执行的超时时间，单位秒
+	Timeout int `json:"timeout"`
+	// Atomic 安装失败时删除release，升级失败时回滚到上一版本，设置后会同时等待资源就绪
+	Atomic       bool `json:"atomic"`
+	SkipCRDs     bool `json:"skip_crds"`
+	DisableHooks bool `json:"disable_hooks"`
+	// Force 升级时通过删除重建的方式强制更新资源
+	Force bool `json:"force"`
+	// ResetValues 升级时重置为chart中的默认values
+	ResetValues bool `json:"reset_values"`
+	// ReuseValues 升级时复用上一版本的values，并与本次values合并
+	ReuseValues bool `json:"reuse_values"`
+	// MaxHistory release最多保留的历史版本数
+	MaxHistory int `json:"max_history"`
+}
+
+// WaitTimeout 等待资源就绪以及hook执行的超时时间，未设置时为默认超时时间
+func (o *HelmInstallOptions) WaitTimeout() time.Duration {
+	if o.Timeout <= 0 {
+		return HelmDefaultTimeout * time.Second
+	}
+	return time.Duration(o.Timeout) * time.Second
+}
+
+func (o *HelmInstallOptions) maxHistory() int {
+	if o.MaxHistory <= 0 {
+		return HelmDefaultMaxHistory
+	}
+	return o.MaxHistory
+}
+
 type HelmObjectParams struct {
 	Name       string `json:"name"`
 	Namespace  string `json:"namespace"`
 	Values     string `json:"values"`
 	ChartBytes []byte `json:"chart_bytes"`
+	// DryRun 只渲染资源清单并返回与当前release的差异预览，不实际安装/升级
+	DryRun bool `json:"dry_run"`
+	HelmInstallOptions
 }
 
 func (h *Helm) Create(requestParams interface{}) *utils.Response {
@@ -354,24 +402,39 @@ func (h *Helm) Create(requestParams interface{}) *utils.Response {
 		return &utils.Response{Code: code.RequestError, Msg: err.Error()}
 	}
 
-	actionConfig.Releases.MaxHistory = 3
+	actionConfig.Releases.MaxHistory = createParams.maxHistory()
 	clientInstall := action.NewInstall(actionConfig)
 	clientInstall.ReleaseName = createParams.Name
 	clientInstall.Namespace = createParams.Namespace
+	clientInstall.Wait = createParams.Wait
+	clientInstall.WaitForJobs = createParams.WaitForJobs
+	clientInstall.Timeout = createParams.WaitTimeout()
+	clientInstall.Atomic = createParams.Atomic
+	clientInstall.SkipCRDs = createParams.SkipCRDs
+	clientInstall.DisableHooks = createParams.DisableHooks
 
 	values := make(map[string]interface{})
 	err = yaml.Unmarshal([]byte(createParams.Values), &values)
 	if err != nil {
 		return &utils.Response{Code: code.ParamsError, Msg: "values参数解析错误：" + err.Error()}
 	}
+	if createParams.DryRun {
+		// 资源schema校验在预览中逐个资源进行，返回所有校验错误
+		clientInstall.DryRun = true
+		clientInstall.DisableOpenAPIValidation = true
+		rel, err := clientInstall.Run(chart, values)
+		if err != nil {
+			return &utils.Response{Code: code.RequestError, Msg: err.Error()}
+		}
+		return &utils.Response{Code: code.Success, Msg: "Success", Data: h.preview(actionConfig, rel, nil)}
+	}
 	_, err = clientInstall.Run(chart, values)
 
 	if err != nil {
 		klog.Errorf("install release error: %s", err)
-		clientUnInstall := action.NewUninstall(actionConfig)
-		_, err1 := clientUnInstall.Run(createParams.Name)
-		if err1 != nil {
-			klog.Errorf("uninstall release error: %s", err)
+		// atomic安装失败时helm已删除release，否则只清理本次安装失败的release，避免后续无法重新安装
+		if !createParams.Atomic {
+			h.uninstallFailedRelease(actionConfig, createParams.Name)
 		}
 		return &utils.Response{Code: code.RequestError, Msg: err.Error()}
 	}
@@ -398,15 +461,37 @@ func (h *Helm) Update(requestParams interface{}) *utils.Response {
 		return &utils.Response{Code: code.RequestError, Msg: err.Error()}
 	}
 
-	actionConfig.Releases.MaxHistory = 3
 	clientInstall := action.NewUpgrade(actionConfig)
 	clientInstall.Namespace = updateParams.Namespace
+	clientInstall.MaxHistory = updateParams.maxHistory()
+	clientInstall.Wait = updateParams.Wait
+	clientInstall.WaitForJobs = updateParams.WaitForJobs
+	clientInstall.Timeout = updateParams.WaitTimeout()
+	clientInstall.Atomic = updateParams.Atomic
+	clientInstall.SkipCRDs = updateParams.SkipCRDs
+	clientInstall.DisableHooks = updateParams.DisableHooks
+	clientInstall.Force = updateParams.Force
+	clientInstall.ResetValues = updateParams.ResetValues
+	clientInstall.ReuseValues = updateParams.ReuseValues
 
 	values := make(map[string]interface{})
 	err = yaml.Unmarshal([]byte(updateParams.Values), &values)
 	if err != nil {
 		return &utils.Response{Code: code.ParamsError, Msg: "values参数解析错误：" + err.Error()}
 	}
+	if updateParams.DryRun {
+		current, err := action.NewGet(actionConfig).Run(updateParams.Name)
+		if err != nil {
+			return &utils.Response{Code: code.GetError, Msg: err.Error()}
+		}
+		clientInstall.DryRun = true
+		clientInstall.DisableOpenAPIValidation = true
+		rel, err := clientInstall.Run(updateParams.Name, chart, values)
+		if err != nil {
+			return &utils.Response{Code: code.RequestError, Msg: err.Error()}
+		}
+		return &utils.Response{Code: code.Success, Msg: "Success", Data: h.preview(actionConfig, rel, current)}
+	}
 	_, err = clientInstall.Run(updateParams.Name, chart, values)
 
 	if err != nil {
@@ -416,6 +501,20 @@ func (h *Helm) Update(requestParams interface{}) *utils.Response {
 	return &utils.Response{Code: code.Success, Msg: "Success"}
 }
 
+// uninstallFailedRelease 删除安装失败的release，release不存在或者不是本次安装失败的状态时不删除
+func (h *Helm) uninstallFailedRelease(actionConfig *action.Configuration, name string) {
+	rel, err := action.NewStatus(actionConfig).Run(name)
+	if err != nil {
+		return
+	}
+	if rel.Version != 1 || (rel.Info.Status != release.StatusFailed && rel.Info.Status != release.StatusPendingInstall) {
+		return
+	}
+	if _, err = action.NewUninstall(actionConfig).Run(name); err != nil {
+		klog.Errorf("uninstall failed release %s/%s error: %s", rel.Namespace, name, err)
+	}
+}
+
 func (h *Helm) Delete(requestParams interface{}) *utils.Response {
 	delParams := &HelmObjectParams{}
 	if err := utils.ConvertTypeByJson(requestParams, &delParams); err != nil {
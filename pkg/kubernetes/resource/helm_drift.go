@@ -0,0 +1,240 @@
+package resource
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"github.com/kubespace/kubespace/pkg/core/code"
+	"github.com/kubespace/kubespace/pkg/utils"
+	"helm.sh/helm/v3/pkg/action"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"sort"
+	"strings"
+)
+
+type HelmDriftParams struct {
+	Name      string `json:"name"`
+	Namespace string `json:"namespace"`
+}
+
+// HelmDrift release清单与集群中实际资源的差异
+type HelmDrift struct {
+	Name      string `json:"name"`
+	Namespace string `json:"namespace"`
+	// Revision 比较的release版本
+	Revision int  `json:"revision"`
+	Drifted  bool `json:"drifted"`
+	// Resources 与release清单不一致的资源
+	Resources []*HelmResourceDrift `json:"resources"`
+}
+
+// HelmResourceDrift 单个资源的差异，只比较清单中定义的字段
+type HelmResourceDrift struct {
+	ApiVersion string `json:"api_version"`
+	Kind       string `json:"kind"`
+	Namespace  string `json:"namespace"`
+	Name       string `json:"name"`
+	// Missing 资源在集群中已被删除
+	Missing bool `json:"missing"`
+	// Fields 与清单不一致的字段路径，如spec.replicas
+	Fields []string `json:"fields"`
+	// Diff release清单到集群中实际资源的差异
+	Diff string `json:"diff"`
+}
+
+// Key 资源唯一标识
+func (d *HelmResourceDrift) Key() string {
+	return fmt.Sprintf("%s/%s/%s", d.Kind, d.Namespace, d.Name)
+}
+
+// Drift 通过dynamic client获取release清单中的资源，比较集群中资源与清单的差异
+func (h *Helm) Drift(params interface{}) *utils.Response {
+	driftParams := &HelmDriftParams{}
+	if err := utils.ConvertTypeByJson(params, driftParams); err != nil {
+		return &utils.Response{Code: code.ParamsError, Msg: err.Error()}
+	}
+	if driftParams.Name == "" || driftParams.Namespace == "" {
+		return &utils.Response{Code: code.ParamsError, Msg: "Name or namespace is blank"}
+	}
+	actionConfig, err := h.actionConfig(driftParams.Namespace)
+	if err != nil {
+		return &utils.Response{Code: code.RequestError, Msg: err.Error()}
+	}
+	rel, err := action.NewGet(actionConfig).Run(driftParams.Name)
+	if err != nil {
+		return &utils.Response{Code: code.GetError, Msg: err.Error()}
+	}
+	drift := &HelmDrift{
+		Name:      rel.Name,
+		Namespace: rel.Namespace,
+		Revision:  rel.Version,
+	}
+	for key, obj := range h.keyedObjects(h.GetReleaseObjects(rel), rel.Namespace) {
+		resourceDrift, err := h.resourceDrift(key, obj)
+		if err != nil {
+			return &utils.Response{Code: code.GetError, Msg: err.Error()}
+		}
+		if resourceDrift != nil {
+			drift.Resources = append(drift.Resources, resourceDrift)
+		}
+	}
+	sort.Slice(drift.Resources, func(i, j int) bool {
+		return drift.Resources[i].Key() < drift.Resources[j].Key()
+	})
+	drift.Drifted = len(drift.Resources) > 0
+	return &utils.Response{Code: code.Success, Msg: "Success", Data: drift}
+}
+
+// resourceDrift 获取集群中的资源并与清单比较，没有差异时返回nil
+func (h *Helm) resourceDrift(key string, obj *unstructured.Unstructured) (*HelmResourceDrift, error) {
+	gvk := obj.GroupVersionKind()
+	mapping, err := h.config.RestMapper.RESTMapping(gvk.GroupKind(), gvk.Version)
+	if err != nil {
+		return nil, fmt.Errorf("mapping %s error: %s", key, err.Error())
+	}
+	drift := &HelmResourceDrift{
+		ApiVersion: obj.GetAPIVersion(),
+		Kind:       obj.GetKind(),
+		Namespace:  keyNamespace(key),
+		Name:       obj.GetName(),
+	}
+	var live *unstructured.Unstructured
+	if mapping.Scope.Name() == meta.RESTScopeNameNamespace {
+		live, err = h.client.Dynamic().Resource(mapping.Resource).Namespace(drift.Namespace).Get(
+			context.Background(), obj.GetName(), metav1.GetOptions{})
+	} else {
+		drift.Namespace = ""
+		live, err = h.client.Dynamic().Resource(mapping.Resource).Get(context.Background(), obj.GetName(), metav1.GetOptions{})
+	}
+	manifest := objectYaml(obj)
+	if k8serrors.IsNotFound(err) {
+		drift.Missing = true
+		drift.Diff = utils.UnifiedDiff("current/"+key, "live/"+key, manifest, "")
+		return drift, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("get live object %s error: %s", key, err.Error())
+	}
+	drift.Fields = driftFields(live.Object, normalizeTemplate(obj.Object), "")
+	if len(drift.Fields) == 0 {
+		return nil, nil
+	}
+	pruned, _ := pruneToTemplate(live.Object, obj.Object).(map[string]interface{})
+	drift.Diff = utils.UnifiedDiff("current/"+key, "live/"+key, manifest, objectYaml(&unstructured.Unstructured{Object: pruned}))
+	return drift, nil
+}
+
+// normalizeTemplate 将清单转换为apiserver保存后的格式，返回新的对象，不修改原清单
+// Secret的stringData在保存时会base64编码后合并到data中，集群中的Secret不存在stringData
+func normalizeTemplate(template map[string]interface{}) map[string]interface{} {
+	stringData, ok := template["stringData"].(map[string]interface{})
+	if !ok || template["kind"] != "Secret" || template["apiVersion"] != "v1" {
+		return template
+	}
+	normalized := make(map[string]interface{}, len(template))
+	for k, v := range template {
+		normalized[k] = v
+	}
+	delete(normalized, "stringData")
+	data := make(map[string]interface{})
+	if templateData, ok := normalized["data"].(map[string]interface{}); ok {
+		for k, v := range templateData {
+			data[k] = v
+		}
+	}
+	for k, v := range stringData {
+		// stringData与data中存在相同的key时，以stringData为准
+		data[k] = base64.StdEncoding.EncodeToString([]byte(fmt.Sprint(v)))
+	}
+	normalized["data"] = data
+	return normalized
+}
+
+// equalValue 比较清单以及集群中的标量值，数字可能分别解析为int64以及float64，转换为字符串比较，
+// apiserver会将资源数量规范化，如cpu: 0.5保存为500m、1024Mi保存为1Gi，都能解析为数量时比较数量的大小
+func equalValue(live, template interface{}) bool {
+	liveStr, templateStr := fmt.Sprint(live), fmt.Sprint(template)
+	if liveStr == templateStr {
+		return true
+	}
+	liveQuantity, err := resource.ParseQuantity(liveStr)
+	if err != nil {
+		return false
+	}
+	templateQuantity, err := resource.ParseQuantity(templateStr)
+	if err != nil {
+		return false
+	}
+	return liveQuantity.Cmp(templateQuantity) == 0
+}
+
+// driftFields 返回集群中资源与清单不一致的字段路径，集群中多出的字段不算作差异
+func driftFields(live, template interface{}, path string) []string {
+	switch tmpl := template.(type) {
+	case map[string]interface{}:
+		liveMap, ok := live.(map[string]interface{})
+		if !ok {
+			if live == nil && len(tmpl) == 0 {
+				return nil
+			}
+			return []string{path}
+		}
+		var fields []string
+		for k, v := range tmpl {
+			subPath := k
+			if path != "" {
+				subPath = path + "." + k
+			}
+			lv, ok := liveMap[k]
+			if !ok {
+				// 清单中的空值在集群中会被省略
+				if isEmptyValue(v) {
+					continue
+				}
+				fields = append(fields, subPath)
+				continue
+			}
+			fields = append(fields, driftFields(lv, v, subPath)...)
+		}
+		sort.Strings(fields)
+		return fields
+	case []interface{}:
+		liveList, ok := live.([]interface{})
+		if !ok || len(liveList) != len(tmpl) {
+			if live == nil && len(tmpl) == 0 {
+				return nil
+			}
+			return []string{path}
+		}
+		var fields []string
+		for i := range tmpl {
+			fields = append(fields, driftFields(liveList[i], tmpl[i], fmt.Sprintf("%s[%d]", path, i))...)
+		}
+		return fields
+	case nil:
+		return nil
+	default:
+		if !equalValue(live, tmpl) {
+			return []string{path}
+		}
+		return nil
+	}
+}
+
+func isEmptyValue(v interface{}) bool {
+	switch val := v.(type) {
+	case nil:
+		return true
+	case map[string]interface{}:
+		return len(val) == 0
+	case []interface{}:
+		return len(val) == 0
+	case string:
+		return strings.TrimSpace(val) == ""
+	}
+	return false
+}
@@ -0,0 +1,137 @@
+package resource
+
+import (
+	"github.com/kubespace/kubespace/pkg/core/code"
+	"github.com/kubespace/kubespace/pkg/utils"
+	"reflect"
+	"sigs.k8s.io/yaml"
+	"testing"
+)
+
+func unmarshalObject(t *testing.T, data string) map[string]interface{} {
+	obj := map[string]interface{}{}
+	if err := yaml.Unmarshal([]byte(data), &obj); err != nil {
+		t.Fatal(err)
+	}
+	return obj
+}
+
+func TestDriftFields(t *testing.T) {
+	tests := []struct {
+		name     string
+		template string
+		live     string
+		want     []string
+	}{
+		{
+			name:     "identical",
+			template: "spec:\n  replicas: 2\n",
+			live:     "spec:\n  replicas: 2\n",
+		},
+		{
+			name:     "int and float",
+			template: "spec:\n  replicas: 2\n",
+			live:     "spec:\n  replicas: 2.0\n",
+		},
+		{
+			name:     "extra live fields",
+			template: "spec:\n  replicas: 2\n",
+			live:     "spec:\n  replicas: 2\n  revisionHistoryLimit: 10\nstatus:\n  readyReplicas: 2\n",
+		},
+		{
+			name:     "changed replicas",
+			template: "spec:\n  replicas: 2\n",
+			live:     "spec:\n  replicas: 3\n",
+			want:     []string{"spec.replicas"},
+		},
+		{
+			name:     "empty template values omitted",
+			template: "metadata:\n  annotations: {}\n  labels:\n    app: demo\nspec:\n  args: []\n  command: ''\n",
+			live:     "metadata:\n  labels:\n    app: demo\nspec: {}\n",
+		},
+		{
+			name:     "missing field",
+			template: "metadata:\n  labels:\n    app: demo\n    tier: web\n",
+			live:     "metadata:\n  labels:\n    app: demo\n",
+			want:     []string{"metadata.labels.tier"},
+		},
+		{
+			name:     "list length changed",
+			template: "spec:\n  ports:\n  - port: 80\n  - port: 443\n",
+			live:     "spec:\n  ports:\n  - port: 80\n",
+			want:     []string{"spec.ports"},
+		},
+		{
+			name:     "cpu quantity normalized",
+			template: "resources:\n  limits:\n    cpu: 0.5\n    memory: 1024Mi\n  requests:\n    cpu: '1'\n    memory: 512M\n",
+			live:     "resources:\n  limits:\n    cpu: 500m\n    memory: 1Gi\n  requests:\n    cpu: '1'\n    memory: 512M\n",
+		},
+		{
+			name:     "quantity changed",
+			template: "resources:\n  limits:\n    cpu: 0.5\n    memory: 1Gi\n",
+			live:     "resources:\n  limits:\n    cpu: 250m\n    memory: 1Gi\n",
+			want:     []string{"resources.limits.cpu"},
+		},
+		{
+			name:     "non quantity strings",
+			template: "spec:\n  image: nginx:1.0\n",
+			live:     "spec:\n  image: nginx:1\n",
+			want:     []string{"spec.image"},
+		},
+		{
+			name:     "secret string data",
+			template: "apiVersion: v1\nkind: Secret\nstringData:\n  password: admin\ndata:\n  user: cm9vdA==\n",
+			live:     "apiVersion: v1\nkind: Secret\ndata:\n  password: YWRtaW4=\n  user: cm9vdA==\ntype: Opaque\n",
+		},
+		{
+			name:     "secret string data changed",
+			template: "apiVersion: v1\nkind: Secret\nstringData:\n  password: admin\n",
+			live:     "apiVersion: v1\nkind: Secret\ndata:\n  password: cm9vdA==\n",
+			want:     []string{"data.password"},
+		},
+		{
+			name:     "string data of other kinds",
+			template: "apiVersion: v1\nkind: ConfigMap\nstringData:\n  key: value\n",
+			live:     "apiVersion: v1\nkind: ConfigMap\n",
+			want:     []string{"stringData"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			template := unmarshalObject(t, tt.template)
+			got := driftFields(unmarshalObject(t, tt.live), normalizeTemplate(template), "")
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("driftFields() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNormalizeTemplateNotModifyManifest(t *testing.T) {
+	template := unmarshalObject(t, "apiVersion: v1\nkind: Secret\nstringData:\n  password: admin\n")
+	normalizeTemplate(template)
+	if _, ok := template["stringData"]; !ok {
+		t.Error("normalizeTemplate should not modify the release manifest")
+	}
+	if _, ok := template["data"]; ok {
+		t.Error("normalizeTemplate should not modify the release manifest")
+	}
+}
+
+func TestIsActionNotFound(t *testing.T) {
+	tests := []struct {
+		resp *utils.Response
+		want bool
+	}{
+		{(&Resource{resType: "helm", actions: map[string]ActionHandle{}}).Handle("drift", nil), true},
+		{&utils.Response{Code: code.GetError, Msg: "not found helm drift action"}, true},
+		{&utils.Response{Code: code.GetError, Msg: "not found helm create action"}, false},
+		{&utils.Response{Code: code.GetError, Msg: "release: not found"}, false},
+		{&utils.Response{Code: code.Success}, false},
+	}
+	for _, tt := range tests {
+		if got := IsActionNotFound(tt.resp, "drift"); got != tt.want {
+			t.Errorf("IsActionNotFound(%v) = %v, want %v", tt.resp, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,315 @@
+package resource
+
+import (
+	"bytes"
+	"fmt"
+	"github.com/kubespace/kubespace/pkg/utils"
+	"helm.sh/helm/v3/pkg/action"
+	"helm.sh/helm/v3/pkg/release"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/klog/v2"
+	"reflect"
+	"sigs.k8s.io/yaml"
+	"sort"
+	"strings"
+)
+
+const (
+	HelmResourceActionCreate    = "create"
+	HelmResourceActionUpdate    = "update"
+	HelmResourceActionDelete    = "delete"
+	HelmResourceActionUnchanged = "unchanged"
+
+	// secretValueMask 预览中Secret资源data以及stringData的值使用掩码代替，只标识值是否变化
+	secretValueMask = "***"
+)
+
+// HelmPreview 安装/升级dry-run预览结果
+type HelmPreview struct {
+	Name      string `json:"name"`
+	Namespace string `json:"namespace"`
+	Upgrade   bool   `json:"upgrade"`
+	// Manifest 新渲染的release资源清单
+	Manifest string                       `json:"manifest"`
+	Objects  []*unstructured.Unstructured `json:"objects"`
+	Notes    string                       `json:"notes"`
+	// Resources 每个资源的变更以及差异
+	Resources []*HelmResourceDiff `json:"resources"`
+	// ValidationErrors 资源清单通过集群schema校验的错误
+	ValidationErrors []string `json:"validation_errors"`
+}
+
+// HelmResourceDiff release中单个资源的三方差异，包括当前release清单、集群中实际资源以及新渲染的清单
+type HelmResourceDiff struct {
+	ApiVersion string `json:"api_version"`
+	Kind       string `json:"kind"`
+	Namespace  string `json:"namespace"`
+	Name       string `json:"name"`
+	// Action 资源变更动作，create/update/delete/unchanged
+	Action string `json:"action"`
+	// Diff 当前release清单到新渲染清单的差异
+	Diff string `json:"diff"`
+	// LiveDiff 当前release清单到集群中实际资源的差异，只比较清单中定义的字段，不为空时表示资源在集群中被修改
+	LiveDiff string `json:"live_diff"`
+}
+
+// Key 资源唯一标识
+func (d *HelmResourceDiff) Key() string {
+	return fmt.Sprintf("%s/%s/%s", d.Kind, d.Namespace, d.Name)
+}
+
+// FormatHelmPreviewDiff 将预览结果格式化为文本，用于流水线日志等场景输出
+func FormatHelmPreviewDiff(preview *HelmPreview) string {
+	var buf strings.Builder
+	for _, e := range preview.ValidationErrors {
+		buf.WriteString("校验错误：" + e + "\n")
+	}
+	for _, r := range preview.Resources {
+		if r.Action == HelmResourceActionUnchanged && r.LiveDiff == "" {
+			continue
+		}
+		buf.WriteString(fmt.Sprintf("%s %s\n", r.Action, r.Key()))
+		buf.WriteString(r.Diff)
+		if r.LiveDiff != "" {
+			buf.WriteString(fmt.Sprintf("集群中资源%s与当前release不一致：\n", r.Key()))
+			buf.WriteString(r.LiveDiff)
+		}
+	}
+	return buf.String()
+}
+
+// preview 根据dry-run渲染的release生成预览，current为当前release，安装时为空。
+// 预览结果会返回给用户以及输出到流水线日志，其中Secret资源的值均使用掩码代替
+func (h *Helm) preview(actionConfig *action.Configuration, rel, current *release.Release) *HelmPreview {
+	objects := h.GetReleaseObjects(rel)
+	res := &HelmPreview{
+		Name:      rel.Name,
+		Namespace: rel.Namespace,
+		Upgrade:   current != nil,
+		Manifest:  maskManifestSecrets(rel.Manifest),
+		Notes:     rel.Info.Notes,
+	}
+	for _, obj := range objects {
+		_, masked := maskSecretPair(nil, obj)
+		res.Objects = append(res.Objects, masked)
+	}
+	newObjects := h.keyedObjects(objects, rel.Namespace)
+	oldObjects := map[string]*unstructured.Unstructured{}
+	if current != nil {
+		oldObjects = h.keyedObjects(h.GetReleaseObjects(current), current.Namespace)
+	}
+	for key, obj := range newObjects {
+		if _, err := actionConfig.KubeClient.Build(bytes.NewBufferString(objectYaml(obj)), true); err != nil {
+			res.ValidationErrors = append(res.ValidationErrors, fmt.Sprintf("%s: %s", key, err.Error()))
+		}
+		diff := &HelmResourceDiff{
+			ApiVersion: obj.GetAPIVersion(),
+			Kind:       obj.GetKind(),
+			Namespace:  keyNamespace(key),
+			Name:       obj.GetName(),
+			Action:     HelmResourceActionCreate,
+		}
+		oldYaml := ""
+		old, ok := oldObjects[key]
+		maskedOld, maskedNew := maskSecretPair(old, obj)
+		if ok {
+			oldYaml = objectYaml(maskedOld)
+			diff.LiveDiff = h.liveDiff(actionConfig, key, old)
+			diff.Action = HelmResourceActionUpdate
+		}
+		diff.Diff = utils.UnifiedDiff("current/"+key, "new/"+key, oldYaml, objectYaml(maskedNew))
+		if diff.Action == HelmResourceActionUpdate && diff.Diff == "" {
+			diff.Action = HelmResourceActionUnchanged
+		}
+		res.Resources = append(res.Resources, diff)
+	}
+	for key, old := range oldObjects {
+		if _, ok := newObjects[key]; ok {
+			continue
+		}
+		maskedOld, _ := maskSecretPair(old, nil)
+		res.Resources = append(res.Resources, &HelmResourceDiff{
+			ApiVersion: old.GetAPIVersion(),
+			Kind:       old.GetKind(),
+			Namespace:  keyNamespace(key),
+			Name:       old.GetName(),
+			Action:     HelmResourceActionDelete,
+			Diff:       utils.UnifiedDiff("current/"+key, "new/"+key, objectYaml(maskedOld), ""),
+			LiveDiff:   h.liveDiff(actionConfig, key, old),
+		})
+	}
+	sort.Slice(res.Resources, func(i, j int) bool {
+		return res.Resources[i].Key() < res.Resources[j].Key()
+	})
+	sort.Strings(res.ValidationErrors)
+	return res
+}
+
+// objectKey 资源kind/namespace/name标识，清单中没有namespace的资源使用release所在namespace
+func objectKey(obj *unstructured.Unstructured, namespace string) string {
+	if obj.GetNamespace() != "" {
+		namespace = obj.GetNamespace()
+	}
+	return fmt.Sprintf("%s/%s/%s", obj.GetKind(), namespace, obj.GetName())
+}
+
+// keyedObjects 以kind/namespace/name为key索引资源
+func (h *Helm) keyedObjects(objects []*unstructured.Unstructured, namespace string) map[string]*unstructured.Unstructured {
+	res := make(map[string]*unstructured.Unstructured)
+	for _, obj := range objects {
+		if obj.GetKind() == "" {
+			continue
+		}
+		res[objectKey(obj, namespace)] = obj
+	}
+	return res
+}
+
+// liveDiff 获取集群中的实际资源，并与release清单中的资源比较差异
+func (h *Helm) liveDiff(actionConfig *action.Configuration, key string, obj *unstructured.Unstructured) string {
+	manifest := objectYaml(obj)
+	resources, err := actionConfig.KubeClient.Build(bytes.NewBufferString(manifest), false)
+	if err != nil || len(resources) == 0 {
+		return ""
+	}
+	info := resources[0]
+	if err = info.Get(); err != nil {
+		klog.V(1).Infof("get live object %s error: %s", key, err.Error())
+		maskedObj, _ := maskSecretPair(obj, nil)
+		return utils.UnifiedDiff("current/"+key, "live/"+key, objectYaml(maskedObj), "")
+	}
+	live, ok := info.Object.(*unstructured.Unstructured)
+	if !ok {
+		return ""
+	}
+	pruned, _ := pruneToTemplate(live.Object, obj.Object).(map[string]interface{})
+	return liveObjectDiff(key, obj, &unstructured.Unstructured{Object: pruned})
+}
+
+// liveObjectDiff release清单中的资源与集群中实际资源的差异，Secret资源的值使用掩码代替
+func liveObjectDiff(key string, obj, live *unstructured.Unstructured) string {
+	maskedObj, maskedLive := maskSecretPair(obj, live)
+	return utils.UnifiedDiff("current/"+key, "live/"+key, objectYaml(maskedObj), objectYaml(maskedLive))
+}
+
+// maskSecretPair 将Secret资源data以及stringData的值替换为掩码，返回资源的副本，其它类型的资源原样返回。
+// 同时传入变更前后的资源时，值发生变化的key分别标识为before/after，使差异中仍能看出哪些值被修改
+func maskSecretPair(old, new *unstructured.Unstructured) (*unstructured.Unstructured, *unstructured.Unstructured) {
+	isSecret := func(obj *unstructured.Unstructured) bool {
+		return obj != nil && obj.GetKind() == "Secret" && obj.GetAPIVersion() == "v1"
+	}
+	if !isSecret(old) && !isSecret(new) {
+		return old, new
+	}
+	var maskedOld, maskedNew *unstructured.Unstructured
+	if isSecret(old) {
+		maskedOld = old.DeepCopy()
+	}
+	if isSecret(new) {
+		maskedNew = new.DeepCopy()
+	}
+	for _, field := range []string{"data", "stringData"} {
+		var oldValues, newValues map[string]interface{}
+		if maskedOld != nil {
+			oldValues, _ = maskedOld.Object[field].(map[string]interface{})
+		}
+		if maskedNew != nil {
+			newValues, _ = maskedNew.Object[field].(map[string]interface{})
+		}
+		changed := make(map[string]bool)
+		for k, v := range oldValues {
+			if nv, ok := newValues[k]; ok && !reflect.DeepEqual(v, nv) {
+				changed[k] = true
+			}
+		}
+		for k := range oldValues {
+			oldValues[k] = secretValueMask
+			if changed[k] {
+				oldValues[k] = secretValueMask + " (before)"
+			}
+		}
+		for k := range newValues {
+			newValues[k] = secretValueMask
+			if changed[k] {
+				newValues[k] = secretValueMask + " (after)"
+			}
+		}
+	}
+	if maskedOld == nil {
+		maskedOld = old
+	}
+	if maskedNew == nil {
+		maskedNew = new
+	}
+	return maskedOld, maskedNew
+}
+
+// maskManifestSecrets 将资源清单中Secret资源的值替换为掩码，保留资源前的注释，其它资源原样保留
+func maskManifestSecrets(manifest string) string {
+	var buf strings.Builder
+	for _, doc := range strings.SplitAfter(manifest, "\n---") {
+		body := strings.TrimSuffix(doc, "\n---")
+		obj := &unstructured.Unstructured{}
+		if err := yaml.Unmarshal([]byte(body), &obj.Object); err != nil || obj.GetKind() != "Secret" {
+			buf.WriteString(doc)
+			continue
+		}
+		for _, line := range strings.SplitAfter(body, "\n") {
+			trimmed := strings.TrimSpace(line)
+			if trimmed != "" && trimmed != "---" && !strings.HasPrefix(trimmed, "#") {
+				break
+			}
+			buf.WriteString(line)
+		}
+		_, masked := maskSecretPair(nil, obj)
+		buf.WriteString(strings.TrimSuffix(objectYaml(masked), "\n"))
+		buf.WriteString(doc[len(body):])
+	}
+	return buf.String()
+}
+
+// pruneToTemplate 只保留集群资源中在清单里定义的字段，忽略集群自动填充的默认值以及状态
+func pruneToTemplate(live, template interface{}) interface{} {
+	switch tmpl := template.(type) {
+	case map[string]interface{}:
+		liveMap, ok := live.(map[string]interface{})
+		if !ok {
+			return live
+		}
+		res := make(map[string]interface{})
+		for k, v := range tmpl {
+			if lv, ok := liveMap[k]; ok {
+				res[k] = pruneToTemplate(lv, v)
+			}
+		}
+		return res
+	case []interface{}:
+		liveList, ok := live.([]interface{})
+		if !ok {
+			return live
+		}
+		res := make([]interface{}, len(liveList))
+		for i, lv := range liveList {
+			if i < len(tmpl) {
+				res[i] = pruneToTemplate(lv, tmpl[i])
+			} else {
+				res[i] = lv
+			}
+		}
+		return res
+	default:
+		return live
+	}
+}
+
+func keyNamespace(key string) string {
+	return strings.SplitN(key, "/", 3)[1]
+}
+
+func objectYaml(obj *unstructured.Unstructured) string {
+	data, err := yaml.Marshal(obj.Object)
+	if err != nil {
+		return ""
+	}
+	return string(data)
+}
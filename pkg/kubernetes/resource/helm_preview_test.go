@@ -0,0 +1,118 @@
+package resource
+
+import (
+	"encoding/json"
+	"helm.sh/helm/v3/pkg/action"
+	kubefake "helm.sh/helm/v3/pkg/kube/fake"
+	"helm.sh/helm/v3/pkg/release"
+	"io"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"strings"
+	"testing"
+)
+
+const (
+	previewCurrentManifest = `---
+# Source: app/templates/secret.yaml
+apiVersion: v1
+kind: Secret
+metadata:
+  name: app
+data:
+  password: b2xkLXBhc3N3b3Jk
+  user: YWRtaW4=
+---
+# Source: app/templates/configmap.yaml
+apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: app
+data:
+  mode: old`
+	previewNewManifest = `---
+# Source: app/templates/secret.yaml
+apiVersion: v1
+kind: Secret
+metadata:
+  name: app
+data:
+  password: bmV3LXBhc3N3b3Jk
+  user: YWRtaW4=
+stringData:
+  token: plain-token
+---
+# Source: app/templates/configmap.yaml
+apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: app
+data:
+  mode: new`
+)
+
+// TestPreviewMaskSecrets 预览结果以及格式化后的差异中不包含Secret的值，但能看出哪些值被修改
+func TestPreviewMaskSecrets(t *testing.T) {
+	h := &Helm{}
+	actionConfig := &action.Configuration{KubeClient: &kubefake.PrintingKubeClient{Out: io.Discard}}
+	current := &release.Release{Name: "app", Namespace: "default", Manifest: previewCurrentManifest, Info: &release.Info{}}
+	rel := &release.Release{Name: "app", Namespace: "default", Manifest: previewNewManifest, Info: &release.Info{}}
+
+	preview := h.preview(actionConfig, rel, current)
+	data, err := json.Marshal(preview)
+	if err != nil {
+		t.Fatal(err)
+	}
+	output := string(data) + FormatHelmPreviewDiff(preview)
+	for _, secret := range []string{"b2xkLXBhc3N3b3Jk", "bmV3LXBhc3N3b3Jk", "YWRtaW4=", "plain-token"} {
+		if strings.Contains(output, secret) {
+			t.Errorf("preview contains secret value %s", secret)
+		}
+	}
+	// 非Secret资源原样保留
+	if !strings.Contains(preview.Manifest, "mode: new") || !strings.Contains(preview.Manifest, "# Source: app/templates/secret.yaml") {
+		t.Errorf("manifest = %s, want configmap and source comments kept", preview.Manifest)
+	}
+	var secretDiff *HelmResourceDiff
+	for _, r := range preview.Resources {
+		if r.Kind == "Secret" {
+			secretDiff = r
+		}
+	}
+	if secretDiff == nil {
+		t.Fatalf("secret diff not found")
+	}
+	for _, want := range []string{"-  password: '*** (before)'", "+  password: '*** (after)'", "+stringData:"} {
+		if !strings.Contains(secretDiff.Diff, want) {
+			t.Errorf("secret diff = %s, want contains %s", secretDiff.Diff, want)
+		}
+	}
+	if !strings.Contains(secretDiff.Diff, "\n   user: '***'\n") {
+		t.Errorf("secret diff = %s, want unchanged user masked", secretDiff.Diff)
+	}
+}
+
+func TestLiveObjectDiffMaskSecrets(t *testing.T) {
+	obj := &unstructured.Unstructured{Object: map[string]interface{}{
+		"apiVersion": "v1",
+		"kind":       "Secret",
+		"metadata":   map[string]interface{}{"name": "app"},
+		"data":       map[string]interface{}{"password": "bmV3LXBhc3N3b3Jk"},
+	}}
+	live := obj.DeepCopy()
+	live.Object["data"] = map[string]interface{}{"password": "bGl2ZS1wYXNzd29yZA=="}
+
+	diff := liveObjectDiff("Secret/default/app", obj, live)
+	if diff == "" {
+		t.Fatalf("live diff is empty, want changed password")
+	}
+	if strings.Contains(diff, "bmV3LXBhc3N3b3Jk") || strings.Contains(diff, "bGl2ZS1wYXNzd29yZA==") {
+		t.Errorf("live diff contains secret value: %s", diff)
+	}
+	// 不修改原始资源
+	if obj.Object["data"].(map[string]interface{})["password"] != "bmV3LXBhc3N3b3Jk" {
+		t.Errorf("original object modified")
+	}
+	if diff = liveObjectDiff("Secret/default/app", obj, obj.DeepCopy()); diff != "" {
+		t.Errorf("live diff = %s, want empty for identical secret", diff)
+	}
+}
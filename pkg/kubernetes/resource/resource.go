@@ -12,6 +12,7 @@ import (
 	kubetypes "github.com/kubespace/kubespace/pkg/kubernetes/types"
 	"github.com/kubespace/kubespace/pkg/utils"
 	"io"
+	"k8s.io/apimachinery/pkg/api/errors"
 	"k8s.io/apimachinery/pkg/api/meta"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
@@ -20,6 +21,7 @@ import (
 	"k8s.io/apimachinery/pkg/runtime/schema"
 	"k8s.io/apimachinery/pkg/types"
 	utilyaml "k8s.io/apimachinery/pkg/util/yaml"
+	"k8s.io/apimachinery/pkg/watch"
 	"k8s.io/client-go/dynamic"
 	"k8s.io/klog/v2"
 	"sigs.k8s.io/yaml"
@@ -64,12 +66,34 @@ func NewResource(
 
 func (r *Resource) Handle(action string, params interface{}) *utils.Response {
 	if actionHandle, ok := r.actions[action]; ok {
-		return actionHandle(params)
+		resp := actionHandle(params)
+		r.written(action)
+		return resp
 	} else {
 		return &utils.Response{Code: code.GetError, Msg: fmt.Sprintf("not found %s %s action", r.resType, action)}
 	}
 }
 
+// written 写入资源后，之后一段时间的读取不使用agent缓存，保证读取到写入后的数据。
+// apply的yaml可以包含任意资源，helm安装的资源类型不确定，不区分资源类型
+func (r *Resource) written(action string) {
+	if r.config == nil || r.config.Cache == nil {
+		return
+	}
+	switch action {
+	case kubetypes.CreateAction, kubetypes.UpdateAction, kubetypes.PatchAction, kubetypes.DeleteAction:
+		r.config.Cache.Written(r.gvr)
+	case kubetypes.ApplyAction:
+		r.config.Cache.Written(nil)
+	}
+}
+
+// IsActionNotFound 资源是否不支持该操作，旧版本agent不支持新增的操作时同样返回该错误
+func IsActionNotFound(resp *utils.Response, action string) bool {
+	return resp.Code == code.GetError && strings.HasPrefix(resp.Msg, "not found ") &&
+		strings.HasSuffix(resp.Msg, " "+action+" action")
+}
+
 type QueryParams struct {
 	Kind               string                `json:"kind" form:"kind"`
 	Name               string                `json:"name" form:"name"`
@@ -111,15 +135,19 @@ func (r *Resource) List(params interface{}) *utils.Response {
 	if err != nil {
 		return &utils.Response{Code: code.ParamsError, Msg: err.Error()}
 	}
-	objects, err := r.client.Dynamic().Resource(*r.gvr).Namespace(query.Namespace).List(context.Background(), *listOptions)
-	if err != nil {
-		return &utils.Response{Code: code.RequestError, Msg: err.Error()}
+	items, ok := r.listFromCache(query)
+	if !ok {
+		objects, err := r.client.Dynamic().Resource(*r.gvr).Namespace(query.Namespace).List(context.Background(), *listOptions)
+		if err != nil {
+			return &utils.Response{Code: code.RequestError, Msg: err.Error()}
+		}
+		items = objects.Items
 	}
 	var data []interface{}
-	for i := range objects.Items {
+	for i := range items {
 		if query.OwnerReferenceKind != "" && query.OwnerReferenceName != "" {
 			find := false
-			for _, ref := range objects.Items[i].GetOwnerReferences() {
+			for _, ref := range items[i].GetOwnerReferences() {
 				if ref.Kind == query.OwnerReferenceKind && ref.Name == query.OwnerReferenceName {
 					find = true
 					break
@@ -130,18 +158,45 @@ func (r *Resource) List(params interface{}) *utils.Response {
 			}
 		}
 		if query.Process == nil || *query.Process {
-			if obj, err := r.listObjectProcess(query, &objects.Items[i]); err != nil {
+			if obj, err := r.listObjectProcess(query, &items[i]); err != nil {
 				return &utils.Response{Code: code.RequestError, Msg: err.Error()}
 			} else if obj != nil {
 				data = append(data, obj)
 			}
 		} else {
-			data = append(data, objects.Items[i].Object)
+			data = append(data, items[i].Object)
 		}
 	}
 	return &utils.Response{Code: code.Success, Msg: "Success", Data: data}
 }
 
+// listFromCache 从agent缓存中获取资源列表，缓存不可用或者不满足请求的resourceVersion时返回false
+func (r *Resource) listFromCache(query *QueryParams) ([]unstructured.Unstructured, bool) {
+	if r.config.Cache == nil {
+		return nil, false
+	}
+	selector, err := r.labelSelector(query)
+	if err != nil {
+		return nil, false
+	}
+	objs, _, ok := r.config.Cache.List(*r.gvr, query.Namespace, query.Name, selector, query.ResourceVersion)
+	if !ok {
+		return nil, false
+	}
+	items := make([]unstructured.Unstructured, 0, len(objs))
+	for _, obj := range objs {
+		items = append(items, *obj)
+	}
+	return items, true
+}
+
+func (r *Resource) labelSelector(query *QueryParams) (labels.Selector, error) {
+	if query.LabelSelector == nil {
+		return labels.Everything(), nil
+	}
+	return metav1.LabelSelectorAsSelector(query.LabelSelector)
+}
+
 func (r *Resource) Get(params interface{}) *utils.Response {
 	var query QueryParams
 	if err := utils.ConvertTypeByJson(params, &query); err != nil {
@@ -149,6 +204,15 @@ func (r *Resource) Get(params interface{}) *utils.Response {
 	}
 	var obj *unstructured.Unstructured
 	var err error
+	// yaml用于编辑资源，需要获取最新的版本，不使用缓存
+	if r.config.Cache != nil && query.Output != "yaml" {
+		if cached, exists, ok := r.config.Cache.Get(*r.gvr, query.Namespace, query.Name, query.ResourceVersion); ok {
+			if !exists {
+				return &utils.Response{Code: code.RequestError, Msg: errors.NewNotFound(r.gvr.GroupResource(), query.Name).Error()}
+			}
+			return &utils.Response{Code: code.Success, Data: cached}
+		}
+	}
 	if query.Namespace != "" {
 		obj, err = r.client.Dynamic().Resource(*r.gvr).Namespace(query.Namespace).Get(
 			context.Background(), query.Name, metav1.GetOptions{})
@@ -177,16 +241,7 @@ func (r *Resource) Watch(params interface{}, writer OutWriter) *utils.Response {
 	if err != nil {
 		return &utils.Response{Code: code.ParamsError, Msg: err.Error()}
 	}
-	if listOptions.ResourceVersion == "" {
-		listObjs, err := r.client.Dynamic().Resource(*r.gvr).Namespace(query.Namespace).List(context.Background(), *listOptions)
-		if err != nil {
-			return &utils.Response{Code: code.RequestError, Msg: err.Error()}
-		}
-		listOptions.ResourceVersion = listObjs.GetResourceVersion()
-	}
-	var timeout int64 = 0
-	listOptions.TimeoutSeconds = &timeout
-	watcher, err := r.client.Dynamic().Resource(*r.gvr).Namespace(query.Namespace).Watch(context.Background(), *listOptions)
+	watcher, err := r.watch(query, listOptions)
 	if err != nil {
 		return &utils.Response{Code: code.RequestError, Msg: err.Error()}
 	}
@@ -226,6 +281,27 @@ func (r *Resource) Watch(params interface{}, writer OutWriter) *utils.Response {
 	return &utils.Response{Code: code.Success}
 }
 
+// watch 优先使用agent缓存共享的informer，多个会话只有一个到apiserver的watch
+func (r *Resource) watch(query *QueryParams, listOptions *metav1.ListOptions) (watch.Interface, error) {
+	if r.config.Cache != nil {
+		if selector, err := r.labelSelector(query); err == nil {
+			if watcher, ok := r.config.Cache.Watch(*r.gvr, query.Namespace, query.Name, selector, listOptions.ResourceVersion); ok {
+				return watcher, nil
+			}
+		}
+	}
+	if listOptions.ResourceVersion == "" {
+		listObjs, err := r.client.Dynamic().Resource(*r.gvr).Namespace(query.Namespace).List(context.Background(), *listOptions)
+		if err != nil {
+			return nil, err
+		}
+		listOptions.ResourceVersion = listObjs.GetResourceVersion()
+	}
+	var timeout int64 = 0
+	listOptions.TimeoutSeconds = &timeout
+	return r.client.Dynamic().Resource(*r.gvr).Namespace(query.Namespace).Watch(context.Background(), *listOptions)
+}
+
 type DeleteParamResource struct {
 	Name      string `json:"name"`
 	Namespace string `json:"namespace"`
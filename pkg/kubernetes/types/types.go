@@ -3,6 +3,8 @@ package types
 import (
 	"encoding/json"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sort"
+	"strings"
 )
 
 const ServerVersion16 = "v1.16.0"
@@ -20,6 +22,42 @@ type Request struct {
 	Resource string      `json:"resource"`
 	Action   string      `json:"action"`
 	Params   interface{} `json:"params"`
+	// Impersonate 不为空时，agent以该用户身份访问集群
+	Impersonate *Impersonate `json:"impersonate,omitempty"`
+}
+
+// Impersonate 通过Impersonate-User/Impersonate-Group请求头模拟的k8s用户以及用户组
+type Impersonate struct {
+	User   string   `json:"user"`
+	Groups []string `json:"groups"`
+}
+
+// Key 模拟身份的唯一标识，用于缓存该身份的客户端
+func (i *Impersonate) Key() string {
+	groups := append([]string{}, i.Groups...)
+	sort.Strings(groups)
+	return i.User + "|" + strings.Join(groups, ",")
+}
+
+const (
+	// AgentProfileAdmin agent绑定cluster-admin，可以操作集群所有资源
+	AgentProfileAdmin = "admin"
+	// AgentProfileNamespace agent只能操作导入时指定的命名空间中的资源
+	AgentProfileNamespace = "namespace"
+	// AgentProfileReadOnly agent只能查看集群资源
+	AgentProfileReadOnly = "readonly"
+)
+
+// AgentProfile agent安装时的权限配置，决定生成的ClusterRole/Role以及agent可以执行的操作
+type AgentProfile struct {
+	Name string `json:"name"`
+	// Namespaces namespace权限时，agent可以操作的命名空间
+	Namespaces []string `json:"namespaces"`
+}
+
+// IsAdmin 没有配置权限时默认为admin，兼容之前导入的集群
+func (p *AgentProfile) IsAdmin() bool {
+	return p == nil || p.Name == "" || p.Name == AgentProfileAdmin
 }
 
 func (r *Request) Unmarshal(data []byte) (interface{}, error) {
@@ -75,6 +113,10 @@ const (
 	UpdateAction = "update"
 	ApplyAction  = "apply"
 	PatchAction  = "patch"
+	// DriftAction 比较helm release清单与集群中资源的差异
+	DriftAction = "drift"
+	// HealthAction 获取集群健康检查数据
+	HealthAction = "health"
 
 	ExecAction   = "exec"
 	StdinAction  = "stdin"
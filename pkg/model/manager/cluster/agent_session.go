@@ -0,0 +1,106 @@
+package cluster
+
+import (
+	"fmt"
+	"github.com/kubespace/kubespace/pkg/model/types"
+	"github.com/kubespace/kubespace/pkg/utils"
+	"time"
+)
+
+// GetByAgentToken 通过导入token或者有效期内的会话凭证获取集群，isSession表示是否为会话凭证
+func (clu *ClusterManager) GetByAgentToken(token string) (cluster *types.Cluster, isSession bool, err error) {
+	if token == "" {
+		return nil, false, fmt.Errorf("agent token is empty")
+	}
+	var clusters []*types.Cluster
+	if err = clu.DB.Where("token = ? or agent_session_token = ? or agent_prev_session_token = ?", token, token, token).
+		Find(&clusters).Error; err != nil {
+		return nil, false, err
+	}
+	for _, c := range clusters {
+		c.Name = fmt.Sprintf("%d", c.ID)
+		if c.Token == token {
+			return c, false, nil
+		}
+		if c.ValidAgentSession(token) {
+			return c, true, nil
+		}
+	}
+	return nil, false, fmt.Errorf("agent token is invalid or expired")
+}
+
+// IssueAgentSession 生成新的agent会话凭证，当前凭证在新凭证有效期内仍可使用，以便agent平滑轮换
+func (clu *ClusterManager) IssueAgentSession(cluster *types.Cluster, version string) (string, error) {
+	now := time.Now()
+	session := utils.CreateUUID()
+	updates := map[string]interface{}{
+		"agent_session_token":      session,
+		"agent_prev_session_token": cluster.AgentSessionToken,
+		"agent_session_time":       &now,
+		"agent_last_seen":          &now,
+	}
+	if version != "" {
+		updates["agent_version"] = version
+	}
+	if err := clu.DB.Model(&types.Cluster{}).Where("id=?", cluster.ID).Updates(updates).Error; err != nil {
+		return "", err
+	}
+	cluster.AgentPrevSessionToken = cluster.AgentSessionToken
+	cluster.AgentSessionToken = session
+	cluster.AgentSessionTime = &now
+	return session, nil
+}
+
+// ReissueToken 重新生成集群导入token，之前的导入token失效，已连接agent的会话凭证仍然有效
+func (clu *ClusterManager) ReissueToken(id uint) (string, error) {
+	token := utils.ShortUUID()
+	if err := clu.DB.Model(&types.Cluster{}).Where("id=?", id).Update("token", token).Error; err != nil {
+		return "", err
+	}
+	return token, nil
+}
+
+// RevokeToken 吊销集群导入token以及所有agent会话凭证，agent需要使用新的导入token重新导入
+func (clu *ClusterManager) RevokeToken(id uint) (string, error) {
+	token := utils.ShortUUID()
+	if err := clu.DB.Model(&types.Cluster{}).Where("id=?", id).Updates(map[string]interface{}{
+		"token":                    token,
+		"agent_session_token":      "",
+		"agent_prev_session_token": "",
+		"agent_session_time":       nil,
+		"status":                   types.ClusterPending,
+	}).Error; err != nil {
+		return "", err
+	}
+	return token, nil
+}
+
+// UpdateAgentSeen 更新最近一次与agent通信的时间以及当前连接的agent版本，旧版本agent不上报版本，版本为空
+func (clu *ClusterManager) UpdateAgentSeen(id uint, version string) error {
+	return clu.DB.Model(&types.Cluster{}).Where("id=?", id).Updates(map[string]interface{}{
+		"agent_last_seen": time.Now(),
+		"agent_version":   version,
+	}).Error
+}
+
+func (clu *ClusterManager) CreateAgentConnection(conn *types.ClusterAgentConnection) error {
+	return clu.DB.Create(conn).Error
+}
+
+// FinishAgentConnection 记录agent断开连接的时间以及原因
+func (clu *ClusterManager) FinishAgentConnection(id uint, message string) error {
+	now := time.Now()
+	return clu.DB.Model(&types.ClusterAgentConnection{}).Where("id=?", id).Updates(map[string]interface{}{
+		"disconnect_time": &now,
+		"message":         message,
+	}).Error
+}
+
+// ListAgentConnections 获取集群最近的agent连接记录
+func (clu *ClusterManager) ListAgentConnections(clusterId uint, limit int) ([]*types.ClusterAgentConnection, error) {
+	var conns []*types.ClusterAgentConnection
+	if err := clu.DB.Where("cluster_id=?", clusterId).Order("id desc").Limit(limit).Find(&conns).Error; err != nil {
+		return nil, err
+	}
+	return conns, nil
+}
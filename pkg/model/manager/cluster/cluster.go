@@ -7,6 +7,7 @@ import (
 	"github.com/kubespace/kubespace/pkg/model/types"
 	"github.com/kubespace/kubespace/pkg/utils"
 	"gorm.io/gorm"
+	"k8s.io/apimachinery/pkg/labels"
 	"k8s.io/klog/v2"
 	"time"
 )
@@ -61,6 +62,16 @@ func (clu *ClusterManager) UpdateByObject(id uint, cluster *types.Cluster) error
 	return clu.DB.Where("id=?", id).Updates(cluster).Error
 }
 
+// UpdateImpersonation 更新集群的用户模拟配置
+func (clu *ClusterManager) UpdateImpersonation(id uint, impersonation *types.ClusterImpersonation) error {
+	return clu.DB.Model(&types.Cluster{}).Where("id=?", id).Update("impersonation", impersonation).Error
+}
+
+// UpdateAgentProfile 更新集群agent的权限配置
+func (clu *ClusterManager) UpdateAgentProfile(id uint, profile *types.ClusterAgentProfile) error {
+	return clu.DB.Model(&types.Cluster{}).Where("id=?", id).Update("agent_profile", profile).Error
+}
+
 func (clu *ClusterManager) GetById(id uint) (*types.Cluster, error) {
 	cluster := &types.Cluster{}
 	if err := clu.DB.First(cluster, "id = ?", id).Error; err != nil {
@@ -87,19 +98,34 @@ func (clu *ClusterManager) GetByToken(token string) (*types.Cluster, error) {
 	return cluster, nil
 }
 
-type ListClusterCondition struct{}
+type ListClusterCondition struct {
+	// LabelSelector 按集群标签过滤，kubernetes标签选择器格式
+	LabelSelector string
+}
 
 func (clu *ClusterManager) List(cond ListClusterCondition) ([]*types.Cluster, error) {
+	selector := labels.Everything()
+	if cond.LabelSelector != "" {
+		var err error
+		if selector, err = labels.Parse(cond.LabelSelector); err != nil {
+			return nil, fmt.Errorf("标签选择器格式错误：%s", err.Error())
+		}
+	}
 	tx := clu.DB.Model(&types.Cluster{})
 
 	var clusters []*types.Cluster
 	if err := tx.Find(&clusters).Error; err != nil {
 		return nil, err
 	}
+	var matched []*types.Cluster
 	for i, c := range clusters {
+		if !selector.Matches(labels.Set(c.Labels)) {
+			continue
+		}
 		clusters[i].Name = fmt.Sprintf("%d", c.ID)
+		matched = append(matched, clusters[i])
 	}
-	return clusters, nil
+	return matched, nil
 }
 
 func (clu *ClusterManager) Delete(id uint) error {
@@ -124,6 +150,15 @@ func (clu *ClusterManager) Delete(id uint) error {
 	if err := clu.DB.Delete(&types.UserRole{}, "scope = ? and scope_id = ?", types.ScopeCluster, id).Error; err != nil {
 		return err
 	}
+	if err := clu.DB.Delete(&types.ClusterAgentConnection{}, "cluster_id = ?", id).Error; err != nil {
+		return err
+	}
+	if err := clu.DB.Delete(&types.ClusterHealth{}, "cluster_id = ?", id).Error; err != nil {
+		return err
+	}
+	if err := clu.DB.Delete(&types.ClusterEvent{}, "cluster_id = ?", id).Error; err != nil {
+		return err
+	}
 	if err := clu.DB.Delete(types.Cluster{}, "id = ?", id).Error; err != nil {
 		return err
 	}
@@ -0,0 +1,85 @@
+package cluster
+
+import (
+	"github.com/kubespace/kubespace/pkg/model/manager"
+	"github.com/kubespace/kubespace/pkg/model/types"
+	"time"
+)
+
+// ClusterEventListCondition 归档事件的查询条件，时间范围为事件最后发生时间
+type ClusterEventListCondition struct {
+	manager.PaginationCondition
+	ClusterId  uint       `json:"cluster_id" form:"cluster_id"`
+	Namespace  string     `json:"namespace" form:"namespace"`
+	ObjectKind string     `json:"object_kind" form:"object_kind"`
+	ObjectName string     `json:"object_name" form:"object_name"`
+	Reason     string     `json:"reason" form:"reason"`
+	Type       string     `json:"type" form:"type"`
+	StartTime  *time.Time `json:"start_time" form:"start_time" time_format:"2006-01-02T15:04:05Z07:00"`
+	EndTime    *time.Time `json:"end_time" form:"end_time" time_format:"2006-01-02T15:04:05Z07:00"`
+}
+
+// SaveEvent 保存集群事件，已归档的事件更新次数、最后发生时间以及消息
+func (clu *ClusterManager) SaveEvent(event *types.ClusterEvent) error {
+	var events []*types.ClusterEvent
+	if err := clu.DB.Where("cluster_id=? and uid=?", event.ClusterId, event.Uid).Limit(1).Find(&events).Error; err != nil {
+		return err
+	}
+	if len(events) == 0 {
+		return clu.DB.Create(event).Error
+	}
+	existing := events[0]
+	// 事件重复推送或者乱序时不覆盖更新的记录
+	if event.Count < existing.Count || event.LastTime.Before(existing.LastTime) {
+		return nil
+	}
+	return clu.DB.Model(existing).Updates(map[string]interface{}{
+		"reason":    event.Reason,
+		"type":      event.Type,
+		"message":   event.Message,
+		"source":    event.Source,
+		"count":     event.Count,
+		"last_time": event.LastTime,
+	}).Error
+}
+
+// ListEvents 查询归档的集群事件，默认按最后发生时间倒序
+func (clu *ClusterManager) ListEvents(cond *ClusterEventListCondition) ([]*types.ClusterEvent, *manager.Pagination, error) {
+	var events []*types.ClusterEvent
+	tx := clu.DB.Where("cluster_id = ?", cond.ClusterId)
+	if cond.Namespace != "" {
+		tx = tx.Where("namespace = ?", cond.Namespace)
+	}
+	if cond.ObjectKind != "" {
+		tx = tx.Where("object_kind = ?", cond.ObjectKind)
+	}
+	if cond.ObjectName != "" {
+		tx = tx.Where("object_name like ?", "%"+cond.ObjectName+"%")
+	}
+	if cond.Reason != "" {
+		tx = tx.Where("reason = ?", cond.Reason)
+	}
+	if cond.Type != "" {
+		tx = tx.Where("type = ?", cond.Type)
+	}
+	if cond.StartTime != nil {
+		tx = tx.Where("last_time >= ?", cond.StartTime)
+	}
+	if cond.EndTime != nil {
+		tx = tx.Where("last_time <= ?", cond.EndTime)
+	}
+	if cond.OrderBy == "" {
+		cond.PaginationCondition.OrderBy = "-last_time"
+	}
+	page, err := manager.NewPaginationFromDb(tx, &types.ClusterEvent{}, &events, cond.PaginationCondition)
+	if err != nil {
+		return nil, nil, err
+	}
+	return events, page, nil
+}
+
+// DeleteExpiredEvents 清理最后发生时间超过保留时间的事件
+func (clu *ClusterManager) DeleteExpiredEvents() (int64, error) {
+	tx := clu.DB.Delete(&types.ClusterEvent{}, "last_time < ?", time.Now().Add(-types.ClusterEventRetention))
+	return tx.RowsAffected, tx.Error
+}
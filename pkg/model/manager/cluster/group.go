@@ -0,0 +1,93 @@
+package cluster
+
+import (
+	"fmt"
+	"github.com/kubespace/kubespace/pkg/model/types"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/util/validation"
+	"strings"
+)
+
+// ParseSelector 解析集群标签选择器，集群组的选择器不能为空，避免包含所有集群
+func ParseSelector(selector string) (labels.Selector, error) {
+	if strings.TrimSpace(selector) == "" {
+		return nil, fmt.Errorf("标签选择器不能为空")
+	}
+	s, err := labels.Parse(selector)
+	if err != nil {
+		return nil, fmt.Errorf("标签选择器格式错误：%s", err.Error())
+	}
+	return s, nil
+}
+
+// ValidateLabels 校验集群标签，格式与kubernetes标签一致
+func ValidateLabels(l types.ClusterLabels) error {
+	for k, v := range l {
+		if errs := validation.IsQualifiedName(k); len(errs) > 0 {
+			return fmt.Errorf("标签%s格式错误：%s", k, strings.Join(errs, "; "))
+		}
+		if errs := validation.IsValidLabelValue(v); len(errs) > 0 {
+			return fmt.Errorf("标签%s的值%s格式错误：%s", k, v, strings.Join(errs, "; "))
+		}
+	}
+	return nil
+}
+
+func (clu *ClusterManager) CreateGroup(group *types.ClusterGroup) error {
+	return clu.DB.Create(group).Error
+}
+
+func (clu *ClusterManager) SaveGroup(group *types.ClusterGroup) error {
+	return clu.DB.Save(group).Error
+}
+
+func (clu *ClusterManager) GetGroup(id uint) (*types.ClusterGroup, error) {
+	group := &types.ClusterGroup{}
+	if err := clu.DB.First(group, "id = ?", id).Error; err != nil {
+		return nil, err
+	}
+	return group, nil
+}
+
+func (clu *ClusterManager) ListGroups() ([]*types.ClusterGroup, error) {
+	var groups []*types.ClusterGroup
+	if err := clu.DB.Order("name").Find(&groups).Error; err != nil {
+		return nil, err
+	}
+	return groups, nil
+}
+
+// DeleteGroup 删除集群组以及集群组的用户角色
+func (clu *ClusterManager) DeleteGroup(id uint) error {
+	if err := clu.DB.Delete(&types.UserRole{}, "scope = ? and scope_id = ?", types.ScopeClusterGroup, id).Error; err != nil {
+		return err
+	}
+	return clu.DB.Delete(&types.ClusterGroup{}, "id = ?", id).Error
+}
+
+// GroupClusters 获取集群组包含的集群
+func (clu *ClusterManager) GroupClusters(group *types.ClusterGroup) ([]*types.Cluster, error) {
+	return clu.List(ListClusterCondition{LabelSelector: group.Selector})
+}
+
+// InGroup 集群当前的标签是否匹配集群组的选择器
+func (clu *ClusterManager) InGroup(clusterId, groupId uint) (bool, error) {
+	group, err := clu.GetGroup(groupId)
+	if err != nil {
+		return false, err
+	}
+	selector, err := ParseSelector(group.Selector)
+	if err != nil {
+		return false, err
+	}
+	cluster, err := clu.GetById(clusterId)
+	if err != nil {
+		return false, err
+	}
+	return selector.Matches(labels.Set(cluster.Labels)), nil
+}
+
+// UpdateLabels 更新集群标签
+func (clu *ClusterManager) UpdateLabels(id uint, l types.ClusterLabels) error {
+	return clu.DB.Model(&types.Cluster{}).Where("id=?", id).Update("labels", l).Error
+}
@@ -0,0 +1,48 @@
+package cluster
+
+import (
+	"github.com/kubespace/kubespace/pkg/model/types"
+	"time"
+)
+
+// CreateHealth 保存集群健康检查记录，并更新集群的健康状态，同时清理过期的检查记录
+func (clu *ClusterManager) CreateHealth(health *types.ClusterHealth) error {
+	if err := clu.DB.Create(health).Error; err != nil {
+		return err
+	}
+	if err := clu.DB.Model(&types.Cluster{}).Where("id=?", health.ClusterId).Updates(map[string]interface{}{
+		"health_status":     health.Status,
+		"health_check_time": &health.CheckTime,
+	}).Error; err != nil {
+		return err
+	}
+	return clu.DB.Delete(&types.ClusterHealth{}, "cluster_id=? and check_time<?",
+		health.ClusterId, time.Now().Add(-types.ClusterHealthRetention)).Error
+}
+
+// GetLatestHealth 获取集群最近一次健康检查记录，没有检查记录时返回nil
+func (clu *ClusterManager) GetLatestHealth(clusterId uint) (*types.ClusterHealth, error) {
+	var healths []*types.ClusterHealth
+	if err := clu.DB.Where("cluster_id=?", clusterId).Order("check_time desc").Limit(1).Find(&healths).Error; err != nil {
+		return nil, err
+	}
+	if len(healths) == 0 {
+		return nil, nil
+	}
+	return healths[0], nil
+}
+
+// ListHealth 获取集群在时间范围内的健康检查记录，按检查时间倒序
+func (clu *ClusterManager) ListHealth(clusterId uint, since time.Time, limit int) ([]*types.ClusterHealth, error) {
+	var healths []*types.ClusterHealth
+	if err := clu.DB.Where("cluster_id=? and check_time>=?", clusterId, since).
+		Order("check_time desc").Limit(limit).Find(&healths).Error; err != nil {
+		return nil, err
+	}
+	return healths, nil
+}
+
+// UpdateHealthThresholds 更新集群健康检查阈值
+func (clu *ClusterManager) UpdateHealthThresholds(id uint, thresholds *types.ClusterHealthThresholds) error {
+	return clu.DB.Model(&types.Cluster{}).Where("id=?", id).Update("health_thresholds", thresholds).Error
+}
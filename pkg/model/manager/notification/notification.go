@@ -0,0 +1,150 @@
+package notification
+
+import (
+	"fmt"
+	"github.com/kubespace/kubespace/pkg/model/manager"
+	"github.com/kubespace/kubespace/pkg/model/types"
+	"gorm.io/gorm"
+	"time"
+)
+
+// NotificationManager 通知渠道、通知规则以及发送记录
+type NotificationManager struct {
+	DB *gorm.DB
+}
+
+func NewNotificationManager(db *gorm.DB) *NotificationManager {
+	return &NotificationManager{DB: db}
+}
+
+func (n *NotificationManager) CreateChannel(channel *types.NotificationChannel) error {
+	return n.DB.Create(channel).Error
+}
+
+func (n *NotificationManager) SaveChannel(channel *types.NotificationChannel) error {
+	return n.DB.Save(channel).Error
+}
+
+func (n *NotificationManager) GetChannel(id uint) (*types.NotificationChannel, error) {
+	var channel types.NotificationChannel
+	if err := n.DB.First(&channel, "id=?", id).Error; err != nil {
+		return nil, err
+	}
+	return &channel, nil
+}
+
+func (n *NotificationManager) ListChannels() ([]*types.NotificationChannel, error) {
+	var channels []*types.NotificationChannel
+	if err := n.DB.Find(&channels).Error; err != nil {
+		return nil, err
+	}
+	return channels, nil
+}
+
+// DeleteChannel 删除通知渠道，渠道被规则使用时不能删除
+func (n *NotificationManager) DeleteChannel(id uint) error {
+	rules, err := n.ListRules("", 0)
+	if err != nil {
+		return err
+	}
+	for _, rule := range rules {
+		for _, channelId := range rule.ChannelIds {
+			if channelId == id {
+				return fmt.Errorf("通知渠道被规则%s使用", rule.Name)
+			}
+		}
+	}
+	return n.DB.Delete(&types.NotificationChannel{}, "id=?", id).Error
+}
+
+func (n *NotificationManager) CreateRule(rule *types.NotificationRule) error {
+	return n.DB.Create(rule).Error
+}
+
+func (n *NotificationManager) SaveRule(rule *types.NotificationRule) error {
+	return n.DB.Save(rule).Error
+}
+
+func (n *NotificationManager) GetRule(id uint) (*types.NotificationRule, error) {
+	var rule types.NotificationRule
+	if err := n.DB.First(&rule, "id=?", id).Error; err != nil {
+		return nil, err
+	}
+	return &rule, nil
+}
+
+func (n *NotificationManager) DeleteRule(id uint) error {
+	return n.DB.Delete(&types.NotificationRule{}, "id=?", id).Error
+}
+
+// ListRules 获取范围下的通知规则，scope为空时获取所有规则
+func (n *NotificationManager) ListRules(scope string, scopeId uint) ([]*types.NotificationRule, error) {
+	var rules []*types.NotificationRule
+	tx := n.DB
+	if scope != "" {
+		tx = tx.Where("scope=? and scope_id=?", scope, scopeId)
+	}
+	if err := tx.Find(&rules).Error; err != nil {
+		return nil, err
+	}
+	return rules, nil
+}
+
+// MatchRules 获取订阅了该事件的启用规则，包括事件所在范围以及平台范围的规则
+func (n *NotificationManager) MatchRules(event, scope string, scopeId uint) ([]*types.NotificationRule, error) {
+	var rules []*types.NotificationRule
+	if err := n.DB.Where("enabled=? and ((scope=? and scope_id=?) or scope=?)",
+		true, scope, scopeId, types.ScopePlatform).Find(&rules).Error; err != nil {
+		return nil, err
+	}
+	var matched []*types.NotificationRule
+	for _, rule := range rules {
+		if rule.Subscribed(event) {
+			matched = append(matched, rule)
+		}
+	}
+	return matched, nil
+}
+
+// CreateDelivery 保存通知发送记录，并清理过期的记录
+func (n *NotificationManager) CreateDelivery(delivery *types.NotificationDelivery) error {
+	if err := n.DB.Create(delivery).Error; err != nil {
+		return err
+	}
+	return n.DB.Delete(&types.NotificationDelivery{}, "create_time<?",
+		time.Now().Add(-types.NotificationDeliveryRetention)).Error
+}
+
+type DeliveryListCondition struct {
+	manager.PaginationCondition
+	Scope   string `json:"scope" form:"scope"`
+	ScopeId uint   `json:"scope_id" form:"scope_id"`
+	RuleId  uint   `json:"rule_id" form:"rule_id"`
+	Event   string `json:"event" form:"event"`
+	Status  string `json:"status" form:"status"`
+}
+
+func (n *NotificationManager) ListDeliveries(cond *DeliveryListCondition) ([]*types.NotificationDelivery, *manager.Pagination, error) {
+	var deliveries []*types.NotificationDelivery
+	tx := n.DB
+	if cond.Scope != "" {
+		tx = tx.Where("scope = ? and scope_id = ?", cond.Scope, cond.ScopeId)
+	}
+	if cond.RuleId != 0 {
+		tx = tx.Where("rule_id = ?", cond.RuleId)
+	}
+	if cond.Event != "" {
+		tx = tx.Where("event = ?", cond.Event)
+	}
+	if cond.Status != "" {
+		tx = tx.Where("status = ?", cond.Status)
+	}
+	if cond.OrderBy == "" {
+		cond.PaginationCondition.OrderBy = "-create_time"
+	}
+	page, err := manager.NewPaginationFromDb(tx, &types.NotificationDelivery{}, &deliveries, cond.PaginationCondition)
+	if err != nil {
+		return nil, nil, err
+	}
+	return deliveries, page, nil
+}
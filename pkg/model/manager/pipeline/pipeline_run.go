@@ -196,6 +196,26 @@ func (p *PipelineRunManager) UpdateJobRun(id uint, jobRun *types.PipelineRunJob)
 	return p.DB.Model(types.PipelineRunJob{}).Where("id=?", id).Updates(jobRun).Error
 }
 
+// UpdateJobRunWithStatus 任务状态在statusIn中时才更新，防止覆盖已取消等状态，返回是否更新成功
+func (p *PipelineRunManager) UpdateJobRunWithStatus(id uint, statusIn []string, values map[string]interface{}) (bool, error) {
+	tx := p.DB.Model(types.PipelineRunJob{}).Where("id=? and status in ?", id, statusIn).Updates(values)
+	if tx.Error != nil {
+		return false, tx.Error
+	}
+	return tx.RowsAffected > 0, nil
+}
+
+// UpdateLostJobRun spacelet节点失联时，更新仍分配在该节点上执行中的任务，返回是否更新成功，
+// 任务执行结果已经写入时不更新，避免已完成的任务被重新调度执行
+func (p *PipelineRunManager) UpdateLostJobRun(id, spaceletId uint, values map[string]interface{}) (bool, error) {
+	tx := p.DB.Model(types.PipelineRunJob{}).Where("id=? and spacelet_id=? and status=? and result is null",
+		id, spaceletId, types.PipelineStatusDoing).Updates(values)
+	if tx.Error != nil {
+		return false, tx.Error
+	}
+	return tx.RowsAffected > 0, nil
+}
+
 func (p *PipelineRunManager) GetStageRun(stageId uint) (*types.PipelineRunStage, error) {
 	var err error
 	var stageRun types.PipelineRunStage
@@ -259,7 +279,7 @@ func (p *PipelineRunManager) UpdateStageJobRunParams(stageRun *types.PipelineRun
 }
 
 // GetStageRunStatus 根据stage的所有任务的状态返回该stage的状态
-//  1. 如果有doing的job，stage状态为doing；
+//  1. 如果有doing或queued的job，stage状态为doing；
 //  2. 如果所有job的状态为error/ok/wait，则
 //     a. job中有error的，则stage为error；
 //     b. 所有job都为ok，则stage为ok；
@@ -271,7 +291,7 @@ func (p *PipelineRunManager) GetStageRunStatus(stageRun *types.PipelineRunStage)
 	}
 	status := ""
 	for _, jobRun := range stageRun.Jobs {
-		if jobRun.Status == types.PipelineStatusDoing {
+		if jobRun.Status == types.PipelineStatusDoing || jobRun.Status == types.PipelineStatusQueued {
 			return types.PipelineStatusDoing
 		}
 		if jobRun.Status == types.PipelineStatusError {
@@ -38,7 +38,7 @@ var BuiltinPlugins = []types.PipelinePlugin{
 	{
 		Name:    "构建代码镜像",
 		Key:     types.BuiltinPluginBuildCodeToImage,
-		Version: "1.1",
+		Version: "1.2",
 		Url:     types.PipelinePluginBuiltinUrl,
 		Params: types.PipelinePluginParams{
 			Params: []*types.PipelinePluginParamsSpec{
@@ -132,6 +132,12 @@ var BuiltinPlugins = []types.PipelinePlugin{
 					FromName:  "image_builds",
 					Default:   nil,
 				},
+				{
+					ParamName: "image_builder",
+					From:      types.PluginParamsFromJob,
+					FromName:  "image_builder",
+					Default:   nil,
+				},
 			},
 		},
 		ResultEnv: types.PipelinePluginResultEnv{
@@ -194,7 +200,7 @@ var BuiltinPlugins = []types.PipelinePlugin{
 	{
 		Name:    "升级空间应用",
 		Key:     types.BuiltinPluginUpgradeApp,
-		Version: "1.0",
+		Version: "1.3",
 		Url:     types.PipelinePluginBuiltinUrl,
 		Params: types.PipelinePluginParams{
 			Params: []*types.PipelinePluginParamsSpec{
@@ -222,6 +228,28 @@ var BuiltinPlugins = []types.PipelinePlugin{
 					FromName:  "with_install",
 					Default:   true,
 				},
+				{
+					ParamName: "show_diff",
+					From:      types.PluginParamsFromJob,
+					FromName:  "show_diff",
+					Default:   false,
+				},
+				{
+					ParamName: "helm_options",
+					From:      types.PluginParamsFromJob,
+					FromName:  "helm_options",
+					Default: map[string]interface{}{
+						"wait":    true,
+						"atomic":  true,
+						"timeout": 300,
+					},
+				},
+				{
+					ParamName: "overlays",
+					From:      types.PluginParamsFromJob,
+					FromName:  "overlays",
+					Default:   nil,
+				},
 			},
 		},
 	},
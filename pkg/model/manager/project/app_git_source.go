@@ -0,0 +1,92 @@
+package project
+
+import (
+	"errors"
+	"github.com/kubespace/kubespace/pkg/informer/listwatcher"
+	applistwatcher "github.com/kubespace/kubespace/pkg/informer/listwatcher/app"
+	listwatcherconfig "github.com/kubespace/kubespace/pkg/informer/listwatcher/config"
+	"github.com/kubespace/kubespace/pkg/model/types"
+	"gorm.io/gorm"
+	"k8s.io/klog/v2"
+	"time"
+)
+
+// AppGitSourceManager 应用git来源
+type AppGitSourceManager struct {
+	DB                      *gorm.DB
+	appGitSourceListWatcher listwatcher.Interface
+}
+
+func NewAppGitSourceManager(db *gorm.DB, listwatcherConfig *listwatcherconfig.ListWatcherConfig) *AppGitSourceManager {
+	return &AppGitSourceManager{
+		DB:                      db,
+		appGitSourceListWatcher: applistwatcher.NewAppGitSourceListWatcher(listwatcherConfig, nil),
+	}
+}
+
+func (a *AppGitSourceManager) Create(source *types.AppGitSource) (*types.AppGitSource, error) {
+	if err := a.DB.Create(source).Error; err != nil {
+		return nil, err
+	}
+	a.notify(source)
+	return source, nil
+}
+
+func (a *AppGitSourceManager) Update(id uint, columns map[string]interface{}) error {
+	return a.DB.Model(&types.AppGitSource{}).Where("id=?", id).Updates(columns).Error
+}
+
+func (a *AppGitSourceManager) Delete(id uint) error {
+	return a.DB.Delete(&types.AppGitSource{}, "id=?", id).Error
+}
+
+func (a *AppGitSourceManager) Get(id uint) (*types.AppGitSource, error) {
+	var source types.AppGitSource
+	if err := a.DB.First(&source, "id=?", id).Error; err != nil {
+		return nil, err
+	}
+	return &source, nil
+}
+
+// GetByAppId 获取应用的git来源，应用没有配置git来源时返回nil
+func (a *AppGitSourceManager) GetByAppId(appId uint) (*types.AppGitSource, error) {
+	var source types.AppGitSource
+	err := a.DB.First(&source, "app_id=?", appId).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+	return &source, nil
+}
+
+func (a *AppGitSourceManager) GetByWebhookToken(token string) (*types.AppGitSource, error) {
+	var source types.AppGitSource
+	if err := a.DB.First(&source, "webhook_token=?", token).Error; err != nil {
+		return nil, err
+	}
+	return &source, nil
+}
+
+// Refresh 将git来源置为等待同步状态，并通知controller立即同步
+func (a *AppGitSourceManager) Refresh(id uint, user string) (*types.AppGitSource, error) {
+	if err := a.Update(id, map[string]interface{}{
+		"status":      types.AppGitSourceStatusPending,
+		"update_user": user,
+		"update_time": time.Now(),
+	}); err != nil {
+		return nil, err
+	}
+	source, err := a.Get(id)
+	if err != nil {
+		return nil, err
+	}
+	a.notify(source)
+	return source, nil
+}
+
+func (a *AppGitSourceManager) notify(source *types.AppGitSource) {
+	if err := a.appGitSourceListWatcher.Notify(*source); err != nil {
+		klog.Warningf("notify app git source id=%d error: %s", source.ID, err.Error())
+	}
+}
@@ -0,0 +1,73 @@
+package project
+
+import (
+	"github.com/kubespace/kubespace/pkg/model/types"
+	"gorm.io/gorm"
+)
+
+// AppPromotionManager 应用晋级记录
+type AppPromotionManager struct {
+	DB *gorm.DB
+}
+
+func NewAppPromotionManager(db *gorm.DB) *AppPromotionManager {
+	return &AppPromotionManager{DB: db}
+}
+
+func (a *AppPromotionManager) Create(promotion *types.AppPromotion) (*types.AppPromotion, error) {
+	if err := a.DB.Create(promotion).Error; err != nil {
+		return nil, err
+	}
+	return promotion, nil
+}
+
+func (a *AppPromotionManager) Update(id uint, columns map[string]interface{}) error {
+	return a.DB.Model(&types.AppPromotion{}).Where("id=?", id).Updates(columns).Error
+}
+
+// UpdatePending 更新待审批的晋级记录，记录已被其他人审批时返回false
+func (a *AppPromotionManager) UpdatePending(id uint, columns map[string]interface{}) (bool, error) {
+	tx := a.DB.Model(&types.AppPromotion{}).Where("id=? and status=?", id, types.AppPromotionStatusPending).Updates(columns)
+	if tx.Error != nil {
+		return false, tx.Error
+	}
+	return tx.RowsAffected > 0, nil
+}
+
+func (a *AppPromotionManager) Get(id uint) (*types.AppPromotion, error) {
+	var promotion types.AppPromotion
+	if err := a.DB.First(&promotion, "id=?", id).Error; err != nil {
+		return nil, err
+	}
+	return &promotion, nil
+}
+
+type ListAppPromotionCondition struct {
+	// AppId 作为晋级来源或者晋级目标的应用
+	AppId           uint
+	SourceProjectId uint
+	TargetProjectId uint
+	Status          string
+}
+
+// List 获取应用晋级记录，按id倒序，最多返回最近的200条
+func (a *AppPromotionManager) List(cond *ListAppPromotionCondition) ([]*types.AppPromotion, error) {
+	var promotions []*types.AppPromotion
+	tx := a.DB.Model(&types.AppPromotion{})
+	if cond.AppId != 0 {
+		tx = tx.Where("source_app_id = ? or target_app_id = ?", cond.AppId, cond.AppId)
+	}
+	if cond.SourceProjectId != 0 {
+		tx = tx.Where("source_project_id = ?", cond.SourceProjectId)
+	}
+	if cond.TargetProjectId != 0 {
+		tx = tx.Where("target_project_id = ?", cond.TargetProjectId)
+	}
+	if cond.Status != "" {
+		tx = tx.Where("status = ?", cond.Status)
+	}
+	if err := tx.Order("id desc").Limit(200).Find(&promotions).Error; err != nil {
+		return nil, err
+	}
+	return promotions, nil
+}
@@ -0,0 +1,99 @@
+package project
+
+import (
+	"errors"
+	"github.com/kubespace/kubespace/pkg/informer/listwatcher"
+	applistwatcher "github.com/kubespace/kubespace/pkg/informer/listwatcher/app"
+	listwatcherconfig "github.com/kubespace/kubespace/pkg/informer/listwatcher/config"
+	"github.com/kubespace/kubespace/pkg/model/types"
+	"gorm.io/gorm"
+	"k8s.io/klog/v2"
+	"time"
+)
+
+// AppStackManager 应用栈
+type AppStackManager struct {
+	DB                  *gorm.DB
+	appStackListWatcher listwatcher.Interface
+}
+
+func NewAppStackManager(db *gorm.DB, listwatcherConfig *listwatcherconfig.ListWatcherConfig) *AppStackManager {
+	return &AppStackManager{
+		DB:                  db,
+		appStackListWatcher: applistwatcher.NewAppStackListWatcher(listwatcherConfig, nil),
+	}
+}
+
+func (a *AppStackManager) Create(stack *types.AppStack) (*types.AppStack, error) {
+	if err := a.DB.Create(stack).Error; err != nil {
+		return nil, err
+	}
+	return stack, nil
+}
+
+func (a *AppStackManager) Update(id uint, columns map[string]interface{}) error {
+	return a.DB.Model(&types.AppStack{}).Where("id=?", id).Updates(columns).Error
+}
+
+func (a *AppStackManager) Delete(id uint) error {
+	return a.DB.Delete(&types.AppStack{}, "id=?", id).Error
+}
+
+func (a *AppStackManager) Get(id uint) (*types.AppStack, error) {
+	var stack types.AppStack
+	if err := a.DB.First(&stack, "id=?", id).Error; err != nil {
+		return nil, err
+	}
+	return &stack, nil
+}
+
+// GetByName 获取工作空间中的应用栈，不存在时返回nil
+func (a *AppStackManager) GetByName(projectId uint, name string) (*types.AppStack, error) {
+	var stack types.AppStack
+	err := a.DB.First(&stack, "project_id=? and name=?", projectId, name).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+	return &stack, nil
+}
+
+func (a *AppStackManager) List(projectId uint) ([]*types.AppStack, error) {
+	var stacks []*types.AppStack
+	if err := a.DB.Where("project_id=?", projectId).Order("id").Find(&stacks).Error; err != nil {
+		return nil, err
+	}
+	return stacks, nil
+}
+
+// StartOperation 应用栈没有未完成的操作时，将状态置为安装、升级或者销毁中，并通知controller执行
+// 应用栈正在执行其他操作时返回false
+func (a *AppStackManager) StartOperation(id uint, status, user string) (bool, error) {
+	result := a.DB.Model(&types.AppStack{}).
+		Where("id=? and status not in ?", id, []string{
+			types.AppStackStatusInstalling,
+			types.AppStackStatusUpgrading,
+			types.AppStackStatusDestroying,
+		}).
+		Updates(map[string]interface{}{
+			"status":      status,
+			"message":     "",
+			"update_user": user,
+			"update_time": time.Now(),
+		})
+	if result.Error != nil {
+		return false, result.Error
+	}
+	if result.RowsAffected == 0 {
+		return false, nil
+	}
+	stack, err := a.Get(id)
+	if err != nil {
+		return false, err
+	}
+	if err = a.appStackListWatcher.Notify(*stack); err != nil {
+		klog.Warningf("notify app stack id=%d error: %s", stack.ID, err.Error())
+	}
+	return true, nil
+}
@@ -0,0 +1,83 @@
+package project
+
+import (
+	"github.com/kubespace/kubespace/pkg/informer/listwatcher"
+	appstorelistwatcher "github.com/kubespace/kubespace/pkg/informer/listwatcher/appstore"
+	listwatcherconfig "github.com/kubespace/kubespace/pkg/informer/listwatcher/config"
+	"github.com/kubespace/kubespace/pkg/model/types"
+	"gorm.io/gorm"
+	"k8s.io/klog/v2"
+	"time"
+)
+
+// AppStoreSourceManager 应用商店同步源
+type AppStoreSourceManager struct {
+	DB                        *gorm.DB
+	appStoreSourceListWatcher listwatcher.Interface
+}
+
+func NewAppStoreSourceManager(db *gorm.DB, listwatcherConfig *listwatcherconfig.ListWatcherConfig) *AppStoreSourceManager {
+	return &AppStoreSourceManager{
+		DB:                        db,
+		appStoreSourceListWatcher: appstorelistwatcher.NewAppStoreSourceListWatcher(listwatcherConfig, nil),
+	}
+}
+
+func (a *AppStoreSourceManager) Create(source *types.AppStoreSource) (*types.AppStoreSource, error) {
+	if err := a.DB.Create(source).Error; err != nil {
+		return nil, err
+	}
+	a.notify(source)
+	return source, nil
+}
+
+func (a *AppStoreSourceManager) Save(source *types.AppStoreSource) error {
+	return a.DB.Save(source).Error
+}
+
+func (a *AppStoreSourceManager) Update(id uint, columns map[string]interface{}) error {
+	return a.DB.Model(&types.AppStoreSource{}).Where("id=?", id).Updates(columns).Error
+}
+
+func (a *AppStoreSourceManager) Delete(id uint) error {
+	return a.DB.Delete(&types.AppStoreSource{}, "id=?", id).Error
+}
+
+func (a *AppStoreSourceManager) Get(id uint) (*types.AppStoreSource, error) {
+	var source types.AppStoreSource
+	if err := a.DB.First(&source, "id=?", id).Error; err != nil {
+		return nil, err
+	}
+	return &source, nil
+}
+
+func (a *AppStoreSourceManager) List() ([]*types.AppStoreSource, error) {
+	var sources []*types.AppStoreSource
+	if err := a.DB.Find(&sources).Error; err != nil {
+		return nil, err
+	}
+	return sources, nil
+}
+
+// Refresh 将同步源置为等待同步状态，并通知controller立即同步
+func (a *AppStoreSourceManager) Refresh(id uint, user string) (*types.AppStoreSource, error) {
+	if err := a.Update(id, map[string]interface{}{
+		"status":      types.AppStoreSourceStatusPending,
+		"update_user": user,
+		"update_time": time.Now(),
+	}); err != nil {
+		return nil, err
+	}
+	source, err := a.Get(id)
+	if err != nil {
+		return nil, err
+	}
+	a.notify(source)
+	return source, nil
+}
+
+func (a *AppStoreSourceManager) notify(source *types.AppStoreSource) {
+	if err := a.appStoreSourceListWatcher.Notify(*source); err != nil {
+		klog.Warningf("notify app store source id=%d error: %s", source.ID, err.Error())
+	}
+}
@@ -151,3 +151,22 @@ func (v *AppVersionManager) GetByPackageNameVersion(scope string, scopeId uint,
 	}
 	return &version, nil
 }
+
+// ListByProject 获取工作空间下所有应用的版本
+func (v *AppVersionManager) ListByProject(projectId uint) ([]*types.AppVersion, error) {
+	var appVersions []*types.AppVersion
+	appIds := v.DB.Model(&types.App{}).Select("id").Where("scope = ? and scope_id = ?", types.ScopeProject, projectId)
+	if err := v.DB.Where("scope = ? and scope_id in (?)", types.ScopeProject, appIds).Order("id desc").Find(&appVersions).Error; err != nil {
+		return nil, err
+	}
+	return appVersions, nil
+}
+
+// ListByScope 获取范围下的所有应用版本，如应用商店中所有应用的版本
+func (v *AppVersionManager) ListByScope(scope string) ([]*types.AppVersion, error) {
+	var appVersions []*types.AppVersion
+	if err := v.DB.Where("scope = ?", scope).Order("id desc").Find(&appVersions).Error; err != nil {
+		return nil, err
+	}
+	return appVersions, nil
+}
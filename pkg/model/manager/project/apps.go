@@ -2,6 +2,7 @@ package project
 
 import (
 	"errors"
+	"fmt"
 	"github.com/kubespace/kubespace/pkg/model/manager"
 	"github.com/kubespace/kubespace/pkg/model/types"
 	"gorm.io/gorm"
@@ -129,6 +130,15 @@ func (a *AppManager) ListApps(cond ListAppCondition) ([]*types.App, error) {
 	return apps, nil
 }
 
+// ListByName 获取所有范围内相同名称的应用，如各个工作空间中的同名应用
+func (a *AppManager) ListByName(scope string, name string) ([]*types.App, error) {
+	var apps []*types.App
+	if err := a.DB.Where("scope = ? and name = ?", scope, name).Order("scope_id").Find(&apps).Error; err != nil {
+		return nil, err
+	}
+	return apps, nil
+}
+
 func (a *AppManager) GetAppWithVersion(appId uint) (*types.App, error) {
 	var app types.App
 	var err error
@@ -174,6 +184,17 @@ func (a *AppManager) DeleteApp(appId uint) error {
 		if err != nil {
 			return err
 		}
+		if appObj.Scope == types.ScopeProject {
+			var stacks []types.AppStack
+			if err = tx.Find(&stacks, "project_id=?", appObj.ScopeId).Error; err != nil {
+				return err
+			}
+			for _, stack := range stacks {
+				if stack.Apps.Contains(appId) {
+					return fmt.Errorf("应用%s属于应用栈%s，请先从应用栈中移除", appObj.Name, stack.Name)
+				}
+			}
+		}
 		appVersions, err := a.List(appObj.Scope, appId)
 		if err != nil {
 			return err
@@ -186,6 +207,12 @@ func (a *AppManager) DeleteApp(appId uint) error {
 		if err = tx.Delete(&types.AppRevision{}, "app_id=?", appId).Error; err != nil {
 			return err
 		}
+		if err = tx.Delete(&types.AppValuesOverlay{}, "app_id=?", appId).Error; err != nil {
+			return err
+		}
+		if err = tx.Delete(&types.AppGitSource{}, "app_id=?", appId).Error; err != nil {
+			return err
+		}
 		if err = tx.Delete(&types.App{}, "id = ?", appId).Error; err != nil {
 			return err
 		}
@@ -260,3 +287,64 @@ func (a *AppManager) CreateRevision(appVersion *types.AppVersion, app *types.App
 	}
 	return &revision, nil
 }
+
+// ListRevisions 获取应用的安装升级历史记录，按版本号倒序
+func (a *AppManager) ListRevisions(appId uint) ([]*types.AppRevision, error) {
+	var revisions []*types.AppRevision
+	if err := a.DB.Where("app_id = ?", appId).Order("build_revision desc").Find(&revisions).Error; err != nil {
+		return nil, err
+	}
+	return revisions, nil
+}
+
+func (a *AppManager) GetRevision(id uint) (*types.AppRevision, error) {
+	var revision types.AppRevision
+	if err := a.DB.First(&revision, id).Error; err != nil {
+		return nil, err
+	}
+	return &revision, nil
+}
+
+// ListValuesOverlays 获取应用的所有values覆盖配置，按名称排序
+func (a *AppManager) ListValuesOverlays(appId uint) ([]*types.AppValuesOverlay, error) {
+	var overlays []*types.AppValuesOverlay
+	if err := a.DB.Where("app_id = ?", appId).Order("name").Find(&overlays).Error; err != nil {
+		return nil, err
+	}
+	return overlays, nil
+}
+
+func (a *AppManager) GetValuesOverlay(appId uint, name string) (*types.AppValuesOverlay, error) {
+	var overlay types.AppValuesOverlay
+	if err := a.DB.First(&overlay, "app_id = ? and name = ?", appId, name).Error; err != nil {
+		return nil, err
+	}
+	return &overlay, nil
+}
+
+// SaveValuesOverlay 保存应用values覆盖配置，同名配置已存在时更新
+func (a *AppManager) SaveValuesOverlay(overlay *types.AppValuesOverlay) (*types.AppValuesOverlay, error) {
+	overlay.UpdateTime = time.Now()
+	exists, err := a.GetValuesOverlay(overlay.AppId, overlay.Name)
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, err
+	}
+	if exists != nil {
+		overlay.ID = exists.ID
+		overlay.CreateUser = exists.CreateUser
+		overlay.CreateTime = exists.CreateTime
+		if err = a.DB.Model(overlay).Select("description", "values", "update_user", "update_time").Updates(*overlay).Error; err != nil {
+			return nil, err
+		}
+		return overlay, nil
+	}
+	overlay.CreateTime = time.Now()
+	if err = a.DB.Create(overlay).Error; err != nil {
+		return nil, err
+	}
+	return overlay, nil
+}
+
+func (a *AppManager) DeleteValuesOverlay(appId uint, name string) error {
+	return a.DB.Delete(&types.AppValuesOverlay{}, "app_id = ? and name = ?", appId, name).Error
+}
@@ -57,6 +57,9 @@ func (p *ManagerProject) List() ([]*types.Project, error) {
 func (p *ManagerProject) Delete(project *types.Project) error {
 	var apps []types.App
 	var err error
+	if err = p.DB.Delete(&types.AppStack{}, "project_id = ?", project.ID).Error; err != nil {
+		return err
+	}
 	if err = p.DB.Where("scope = ? and scope_id = ?", types.ScopeProject, project.ID).Find(&apps).Error; err != nil {
 		return err
 	}
@@ -85,7 +88,10 @@ func (p *ManagerProject) Clone(sourceProjectId uint, newProject *types.Project)
 		if err = tx.Where("scope = ? and scope_id = ?", types.ScopeProject, sourceProjectId).Find(&sourceApps).Error; err != nil {
 			return err
 		}
+		// 源应用id到克隆后应用id的映射，用于克隆应用栈
+		appIds := make(map[uint]uint)
 		for _, app := range sourceApps {
+			sourceAppId := app.ID
 			var appVersion types.AppVersion
 			if err = tx.First(&appVersion, "id = ?", app.AppVersionId).Error; err != nil {
 				return err
@@ -106,11 +112,43 @@ func (p *ManagerProject) Clone(sourceProjectId uint, newProject *types.Project)
 			if err = tx.Save(&app).Error; err != nil {
 				return err
 			}
+			appIds[sourceAppId] = app.ID
 		}
-		return nil
+		return p.cloneAppStacks(tx, sourceProjectId, newProject.ID, appIds)
 	})
 	if err != nil {
 		return nil, err
 	}
 	return newProject, nil
 }
+
+// cloneAppStacks 克隆工作空间的应用栈，应用栈中的应用以及依赖替换为克隆后的应用
+// 克隆后的应用只有当前版本，应用栈不再指定部署的应用版本
+func (p *ManagerProject) cloneAppStacks(tx *gorm.DB, sourceProjectId, newProjectId uint, appIds map[uint]uint) error {
+	var stacks []types.AppStack
+	if err := tx.Where("project_id = ?", sourceProjectId).Find(&stacks).Error; err != nil {
+		return err
+	}
+	for _, stack := range stacks {
+		var apps types.AppStackApps
+		for _, stackApp := range stack.Apps {
+			cloneApp := &types.AppStackApp{
+				AppId:        appIds[stackApp.AppId],
+				ReadyTimeout: stackApp.ReadyTimeout,
+			}
+			for _, dep := range stackApp.DependsOn {
+				cloneApp.DependsOn = append(cloneApp.DependsOn, appIds[dep])
+			}
+			apps = append(apps, cloneApp)
+		}
+		stack.ID = 0
+		stack.ProjectId = newProjectId
+		stack.Apps = apps
+		stack.Status = ""
+		stack.Message = ""
+		if err := tx.Create(&stack).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,87 @@
+package settings
+
+import (
+	"errors"
+	"github.com/kubespace/kubespace/pkg/model/types"
+	"github.com/kubespace/kubespace/pkg/utils"
+	"gorm.io/gorm"
+	"k8s.io/klog/v2"
+)
+
+type CertificateAuthorityManager struct {
+	DB *gorm.DB
+	// encryptionKey CA私钥的加密密钥，为空时私钥不加密保存
+	encryptionKey string
+}
+
+func NewCertificateAuthorityManager(db *gorm.DB, encryptionKey string) *CertificateAuthorityManager {
+	if encryptionKey == "" {
+		klog.Warningf("encryption key is not configured, certificate authority private key will be stored unencrypted")
+	}
+	return &CertificateAuthorityManager{DB: db, encryptionKey: encryptionKey}
+}
+
+// Get 获取CA，不存在时返回nil，返回的私钥为解密后的数据
+// 配置了加密密钥时，未加密保存的历史私钥会加密后重新保存
+func (c *CertificateAuthorityManager) Get(name string) (*types.CertificateAuthority, error) {
+	var ca types.CertificateAuthority
+	if err := c.DB.First(&ca, "name=?", name).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	if err := c.decryptKey(&ca); err != nil {
+		return nil, err
+	}
+	return &ca, nil
+}
+
+// GetOrCreate 获取CA，不存在时创建，多个实例同时创建时以先入库的为准
+func (c *CertificateAuthorityManager) GetOrCreate(ca *types.CertificateAuthority) (*types.CertificateAuthority, error) {
+	create := *ca
+	if c.encryptionKey != "" {
+		encrypted, err := utils.AESEncrypt(c.encryptionKey, ca.Key)
+		if err != nil {
+			return nil, err
+		}
+		create.Key = encrypted
+	}
+	var object types.CertificateAuthority
+	if err := c.DB.Where("name=?", ca.Name).Attrs(&create).FirstOrCreate(&object).Error; err != nil {
+		// 唯一索引冲突时，其它实例已创建
+		if existed, getErr := c.Get(ca.Name); getErr == nil && existed != nil {
+			return existed, nil
+		}
+		return nil, err
+	}
+	if err := c.decryptKey(&object); err != nil {
+		return nil, err
+	}
+	return &object, nil
+}
+
+func (c *CertificateAuthorityManager) decryptKey(ca *types.CertificateAuthority) error {
+	if utils.AESEncrypted(ca.Key) {
+		if c.encryptionKey == "" {
+			return errors.New("certificate authority private key is encrypted, but encryption key is not configured")
+		}
+		key, err := utils.AESDecrypt(c.encryptionKey, ca.Key)
+		if err != nil {
+			return err
+		}
+		ca.Key = key
+		return nil
+	}
+	if c.encryptionKey == "" {
+		return nil
+	}
+	encrypted, err := utils.AESEncrypt(c.encryptionKey, ca.Key)
+	if err != nil {
+		return err
+	}
+	if err = c.DB.Model(&types.CertificateAuthority{}).Where("id=?", ca.ID).Update("key", encrypted).Error; err != nil {
+		klog.Warningf("encrypt certificate authority %s private key error: %s", ca.Name, err.Error())
+	}
+	return nil
+}
@@ -48,6 +48,14 @@ func (s *SettingsSecretManager) Get(secretId uint) (*types.SettingsSecret, error
 	return &secret, nil
 }
 
+func (s *SettingsSecretManager) GetByName(name string) (*types.SettingsSecret, error) {
+	var secret types.SettingsSecret
+	if err := s.DB.First(&secret, "name = ?", name).Error; err != nil {
+		return nil, err
+	}
+	return &secret, nil
+}
+
 func (s *SettingsSecretManager) List() ([]types.SettingsSecret, error) {
 	var secrets []types.SettingsSecret
 	result := s.DB.Find(&secrets)
@@ -4,7 +4,9 @@ import (
 	"errors"
 	"github.com/kubespace/kubespace/pkg/model/manager"
 	"github.com/kubespace/kubespace/pkg/model/types"
+	"github.com/kubespace/kubespace/pkg/utils"
 	"gorm.io/gorm"
+	"time"
 )
 
 type SpaceletManager struct {
@@ -82,3 +84,36 @@ func (s *SpaceletManager) List(cond *SpaceletListCondition) ([]*types.Spacelet,
 	}
 	return objects, nil
 }
+
+// CreateBootstrapToken 生成spacelet引导token，同时清理已过期的引导token
+func (s *SpaceletManager) CreateBootstrapToken(user string) (*types.SpaceletBootstrapToken, error) {
+	now := time.Now()
+	if err := s.DB.Delete(types.SpaceletBootstrapToken{}, "expire_time < ?", now).Error; err != nil {
+		return nil, err
+	}
+	object := &types.SpaceletBootstrapToken{
+		Token:      utils.CreateUUID(),
+		ExpireTime: now.Add(types.SpaceletBootstrapTokenTTL),
+		CreateUser: user,
+		CreateTime: now,
+	}
+	if err := s.DB.Create(object).Error; err != nil {
+		return nil, err
+	}
+	return object, nil
+}
+
+// ValidBootstrapToken 引导token是否存在且在有效期内
+func (s *SpaceletManager) ValidBootstrapToken(token string) (bool, error) {
+	if token == "" {
+		return false, nil
+	}
+	var object types.SpaceletBootstrapToken
+	if err := s.DB.First(&object, "token=?", token).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return false, nil
+		}
+		return false, err
+	}
+	return object.Valid(), nil
+}
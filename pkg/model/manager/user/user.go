@@ -3,6 +3,7 @@ package user
 import (
 	"errors"
 	"github.com/kubespace/kubespace/pkg/model/manager"
+	"github.com/kubespace/kubespace/pkg/model/manager/cluster"
 	"github.com/kubespace/kubespace/pkg/model/types"
 	"github.com/kubespace/kubespace/pkg/utils"
 	"gorm.io/gorm"
@@ -89,12 +90,13 @@ func (u *UserManager) Permissions(user *types.User) ([]types.Permission, error)
 }
 
 type UserRoleManager struct {
-	DB          *gorm.DB
-	UserManager *UserManager
+	DB             *gorm.DB
+	UserManager    *UserManager
+	ClusterManager *cluster.ClusterManager
 }
 
-func NewUserRoleManager(db *gorm.DB, user *UserManager) *UserRoleManager {
-	return &UserRoleManager{DB: db, UserManager: user}
+func NewUserRoleManager(db *gorm.DB, user *UserManager, clusterMgr *cluster.ClusterManager) *UserRoleManager {
+	return &UserRoleManager{DB: db, UserManager: user, ClusterManager: clusterMgr}
 }
 
 func (r *UserRoleManager) GetById(id uint) (*types.UserRole, error) {
@@ -229,5 +231,18 @@ func (r *UserRoleManager) AuthRole(user *types.User, scope string, scopeId uint,
 			return true
 		}
 	}
+	if scope == types.ScopeCluster {
+		// 用户有集群所属集群组的角色权限
+		for _, userRole := range *user.Roles {
+			if userRole.Scope != types.ScopeClusterGroup || !utils.Contains(authRoles, userRole.Role) {
+				continue
+			}
+			if ok, err := r.ClusterManager.InGroup(scopeId, userRole.ScopeId); err != nil {
+				klog.Warningf("check cluster id=%d in group id=%d error: %s", scopeId, userRole.ScopeId, err.Error())
+			} else if ok {
+				return true
+			}
+		}
+	}
 	return false
 }
@@ -12,6 +12,25 @@ import (
 	_ "github.com/kubespace/kubespace/pkg/model/migrate/v1_2/v1_2_5_b_alter_app_name"
 	_ "github.com/kubespace/kubespace/pkg/model/migrate/v1_2/v1_2_6_a_update_app_scope"
 	_ "github.com/kubespace/kubespace/pkg/model/migrate/v1_2/v1_2_6_b_spacelet_add_labels"
+	_ "github.com/kubespace/kubespace/pkg/model/migrate/v1_2/v1_2_7_a_spacelet_capacity"
+	_ "github.com/kubespace/kubespace/pkg/model/migrate/v1_2/v1_2_7_b_spacelet_heartbeat"
+	_ "github.com/kubespace/kubespace/pkg/model/migrate/v1_2/v1_2_7_c_spacelet_mtls"
+	_ "github.com/kubespace/kubespace/pkg/model/migrate/v1_2/v1_2_7_d_app_store_source"
+	_ "github.com/kubespace/kubespace/pkg/model/migrate/v1_2/v1_2_7_e_app_values_overlay"
+	_ "github.com/kubespace/kubespace/pkg/model/migrate/v1_2/v1_2_7_f_app_promotion"
+	_ "github.com/kubespace/kubespace/pkg/model/migrate/v1_2/v1_2_7_g_app_sync_status"
+	_ "github.com/kubespace/kubespace/pkg/model/migrate/v1_2/v1_2_7_h_app_git_source"
+	_ "github.com/kubespace/kubespace/pkg/model/migrate/v1_2/v1_2_7_i_app_stack"
+	_ "github.com/kubespace/kubespace/pkg/model/migrate/v1_2/v1_2_7_j_cluster_impersonation"
+	_ "github.com/kubespace/kubespace/pkg/model/migrate/v1_2/v1_2_7_k_cluster_agent_profile"
+	_ "github.com/kubespace/kubespace/pkg/model/migrate/v1_2/v1_2_7_l_cluster_agent_session"
+	_ "github.com/kubespace/kubespace/pkg/model/migrate/v1_2/v1_2_7_m_cluster_health"
+	_ "github.com/kubespace/kubespace/pkg/model/migrate/v1_2/v1_2_7_n_cluster_event"
+	_ "github.com/kubespace/kubespace/pkg/model/migrate/v1_2/v1_2_7_o_notification"
+	_ "github.com/kubespace/kubespace/pkg/model/migrate/v1_2/v1_2_7_p_cluster_group"
+	_ "github.com/kubespace/kubespace/pkg/model/migrate/v1_2/v1_2_7_q_secret_scope"
+	_ "github.com/kubespace/kubespace/pkg/model/migrate/v1_2/v1_2_7_r_job_queue_time"
+	_ "github.com/kubespace/kubespace/pkg/model/migrate/v1_2/v1_2_7_s_spacelet_bootstrap_token"
 	"github.com/kubespace/kubespace/pkg/model/types"
 )
 
@@ -44,7 +63,21 @@ var initTypes = []interface{}{
 	&types.AppVersionChart{},
 	&types.AppStore{},
 	&types.AppRevision{},
+	&types.AppStoreSource{},
+	&types.AppValuesOverlay{},
+	&types.AppPromotion{},
+	&types.AppGitSource{},
+	&types.AppStack{},
+	&types.ClusterAgentConnection{},
+	&types.ClusterHealth{},
+	&types.ClusterEvent{},
+	&types.ClusterGroup{},
+	&types.NotificationChannel{},
+	&types.NotificationRule{},
+	&types.NotificationDelivery{},
 	&types.Spacelet{},
+	&types.SpaceletBootstrapToken{},
+	&types.CertificateAuthority{},
 	&types.Ldap{},
 
 	&types.AuditOperate{},
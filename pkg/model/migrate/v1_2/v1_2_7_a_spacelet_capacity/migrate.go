@@ -0,0 +1,36 @@
+package v1_2_7_a_spacelet_capacity
+
+import (
+	"github.com/kubespace/kubespace/pkg/model/migrate/migration"
+	v1_2_6_b "github.com/kubespace/kubespace/pkg/model/migrate/v1_2/v1_2_6_b_spacelet_add_labels"
+	"gorm.io/gorm"
+)
+
+var MigrateVersion = "v1.2.7_a"
+
+func init() {
+	migration.Register(&migration.Migration{
+		Version:       MigrateVersion,
+		ParentVersion: v1_2_6_b.MigrateVersion,
+		MigrateFunc:   Migrate,
+		Description:   "Spacelet增加任务数以及cpu/内存容量字段,pipelineRunJob增加排队位置字段",
+	})
+}
+
+type Spacelet struct {
+	MaxJobs int   `gorm:"not null;default:0;comment:最大并发任务数，0表示不限制" json:"max_jobs"`
+	Cpu     int64 `gorm:"not null;default:0;comment:cpu容量(毫核)，0表示不限制" json:"cpu"`
+	Memory  int64 `gorm:"not null;default:0;comment:内存容量(字节)，0表示不限制" json:"memory"`
+}
+
+func (s Spacelet) TableName() string {
+	return "spacelet"
+}
+
+type PipelineRunJob struct {
+	QueuePosition int `gorm:"not null;default:0;comment:任务排队位置，0表示未排队" json:"queue_position"`
+}
+
+func Migrate(db *gorm.DB) error {
+	return db.AutoMigrate(&Spacelet{}, &PipelineRunJob{})
+}
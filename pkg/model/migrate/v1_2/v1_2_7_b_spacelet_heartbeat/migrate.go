@@ -0,0 +1,38 @@
+package v1_2_7_b_spacelet_heartbeat
+
+import (
+	"github.com/kubespace/kubespace/pkg/model/migrate/migration"
+	v1_2_7_a "github.com/kubespace/kubespace/pkg/model/migrate/v1_2/v1_2_7_a_spacelet_capacity"
+	"gorm.io/gorm"
+	"time"
+)
+
+var MigrateVersion = "v1.2.7_b"
+
+func init() {
+	migration.Register(&migration.Migration{
+		Version:       MigrateVersion,
+		ParentVersion: v1_2_7_a.MigrateVersion,
+		MigrateFunc:   Migrate,
+		Description:   "Spacelet增加排空、版本、负载以及心跳时间字段,pipelineRunJob增加重新调度次数字段",
+	})
+}
+
+type Spacelet struct {
+	Drain         bool        `gorm:"not null;default:false;comment:排空后不再调度新任务到该节点" json:"drain"`
+	Version       string      `gorm:"size:255;not null;default:'';comment:spacelet版本" json:"version"`
+	Load          interface{} `gorm:"type:json;comment:心跳上报的节点负载" json:"load"`
+	HeartbeatTime *time.Time  `gorm:"column:heartbeat_time;comment:最近一次心跳时间" json:"heartbeat_time"`
+}
+
+func (s Spacelet) TableName() string {
+	return "spacelet"
+}
+
+type PipelineRunJob struct {
+	Reschedules int `gorm:"not null;default:0;comment:spacelet节点失联后重新调度的次数" json:"reschedules"`
+}
+
+func Migrate(db *gorm.DB) error {
+	return db.AutoMigrate(&Spacelet{}, &PipelineRunJob{})
+}
@@ -0,0 +1,46 @@
+package v1_2_7_c_spacelet_mtls
+
+import (
+	"github.com/kubespace/kubespace/pkg/model/migrate/migration"
+	v1_2_7_b "github.com/kubespace/kubespace/pkg/model/migrate/v1_2/v1_2_7_b_spacelet_heartbeat"
+	"gorm.io/gorm"
+	"time"
+)
+
+var MigrateVersion = "v1.2.7_c"
+
+func init() {
+	migration.Register(&migration.Migration{
+		Version:       MigrateVersion,
+		ParentVersion: v1_2_7_b.MigrateVersion,
+		MigrateFunc:   Migrate,
+		Description:   "增加CA证书表,Spacelet增加token轮换以及tls字段",
+	})
+}
+
+// CertificateAuthority kubespace作为CA签发证书
+type CertificateAuthority struct {
+	ID         uint      `gorm:"primaryKey" json:"id"`
+	Name       string    `gorm:"size:255;not null;uniqueIndex;comment:CA用途" json:"name"`
+	Cert       string    `gorm:"type:text;not null;comment:CA证书" json:"cert"`
+	Key        string    `gorm:"type:text;not null;comment:CA私钥" json:"-"`
+	CreateTime time.Time `gorm:"column:create_time;not null;autoCreateTime" json:"create_time"`
+}
+
+func (c CertificateAuthority) TableName() string {
+	return "certificate_authority"
+}
+
+type Spacelet struct {
+	PrevToken  string     `gorm:"size:255;not null;default:'';comment:轮换前的token，spacelet确认新token前仍有效" json:"-"`
+	TokenTime  *time.Time `gorm:"comment:token生成时间" json:"-"`
+	TlsEnabled bool       `gorm:"not null;default:false;comment:是否通过双向tls访问spacelet" json:"tls_enabled"`
+}
+
+func (s Spacelet) TableName() string {
+	return "spacelet"
+}
+
+func Migrate(db *gorm.DB) error {
+	return db.AutoMigrate(&CertificateAuthority{}, &Spacelet{})
+}
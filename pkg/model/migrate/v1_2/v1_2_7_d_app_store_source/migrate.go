@@ -0,0 +1,52 @@
+package v1_2_7_d_app_store_source
+
+import (
+	"github.com/kubespace/kubespace/pkg/model/migrate/migration"
+	v1_2_7_c "github.com/kubespace/kubespace/pkg/model/migrate/v1_2/v1_2_7_c_spacelet_mtls"
+	"gorm.io/gorm"
+	"time"
+)
+
+var MigrateVersion = "v1.2.7_d"
+
+func init() {
+	migration.Register(&migration.Migration{
+		Version:       MigrateVersion,
+		ParentVersion: v1_2_7_c.MigrateVersion,
+		MigrateFunc:   Migrate,
+		Description:   "增加应用商店同步源表,应用版本增加同步来源以及签名校验字段",
+	})
+}
+
+// AppStoreSource 应用商店同步源
+type AppStoreSource struct {
+	ID                uint        `gorm:"primaryKey" json:"id"`
+	Name              string      `gorm:"size:255;not null;uniqueIndex" json:"name"`
+	Type              string      `gorm:"size:50;not null;comment:仓库类型，helm/oci" json:"type"`
+	Url               string      `gorm:"size:1024;not null" json:"url"`
+	SecretId          uint        `gorm:"not null;default:0;comment:仓库认证密钥" json:"secret_id"`
+	Allowlist         interface{} `gorm:"type:json" json:"allowlist"`
+	AppType           string      `gorm:"size:255;not null;comment:同步到应用商店的应用类型" json:"app_type"`
+	MaxVersions       int         `gorm:"not null;default:5;comment:每个chart同步最新的版本数" json:"max_versions"`
+	Keyring           string      `gorm:"type:text" json:"keyring"`
+	RequireProvenance bool        `gorm:"not null;default:false" json:"require_provenance"`
+	SyncInterval      int         `gorm:"not null;default:60;comment:同步间隔，单位分钟" json:"sync_interval"`
+	Enabled           bool        `gorm:"not null;default:true" json:"enabled"`
+	Status            string      `gorm:"size:50;not null;default:'pending'" json:"status"`
+	Message           string      `gorm:"type:text" json:"message"`
+	LastSyncTime      *time.Time  `json:"last_sync_time"`
+	CreateUser        string      `gorm:"size:255;not null" json:"create_user"`
+	UpdateUser        string      `gorm:"size:255;not null" json:"update_user"`
+	CreateTime        time.Time   `gorm:"column:create_time;not null;autoCreateTime" json:"create_time"`
+	UpdateTime        time.Time   `gorm:"column:update_time;not null;autoUpdateTime" json:"update_time"`
+}
+
+type AppVersion struct {
+	SourceId uint   `gorm:"not null;default:0;comment:同步来源的应用商店源id" json:"source_id"`
+	Digest   string `gorm:"size:255;not null;default:'';comment:同步的chart包摘要" json:"digest"`
+	Verified bool   `gorm:"not null;default:false;comment:chart签名是否校验通过" json:"verified"`
+}
+
+func Migrate(db *gorm.DB) error {
+	return db.AutoMigrate(&AppStoreSource{}, &AppVersion{})
+}
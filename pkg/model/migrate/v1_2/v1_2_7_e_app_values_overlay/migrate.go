@@ -0,0 +1,36 @@
+package v1_2_7_e_app_values_overlay
+
+import (
+	"github.com/kubespace/kubespace/pkg/model/migrate/migration"
+	v1_2_7_d "github.com/kubespace/kubespace/pkg/model/migrate/v1_2/v1_2_7_d_app_store_source"
+	"gorm.io/gorm"
+	"time"
+)
+
+var MigrateVersion = "v1.2.7_e"
+
+func init() {
+	migration.Register(&migration.Migration{
+		Version:       MigrateVersion,
+		ParentVersion: v1_2_7_d.MigrateVersion,
+		MigrateFunc:   Migrate,
+		Description:   "增加应用values覆盖配置表",
+	})
+}
+
+// AppValuesOverlay 应用values覆盖配置
+type AppValuesOverlay struct {
+	ID          uint      `gorm:"primaryKey" json:"id"`
+	AppId       uint      `gorm:"not null;uniqueIndex:AppValuesOverlayNameUnique" json:"app_id"`
+	Name        string    `gorm:"size:255;not null;uniqueIndex:AppValuesOverlayNameUnique" json:"name"`
+	Description string    `gorm:"size:2000;" json:"description"`
+	Values      string    `gorm:"type:longtext;not null" json:"values"`
+	CreateUser  string    `gorm:"size:255;not null" json:"create_user"`
+	UpdateUser  string    `gorm:"size:255;not null" json:"update_user"`
+	CreateTime  time.Time `gorm:"column:create_time;not null;autoCreateTime" json:"create_time"`
+	UpdateTime  time.Time `gorm:"column:update_time;not null;autoUpdateTime" json:"update_time"`
+}
+
+func Migrate(db *gorm.DB) error {
+	return db.AutoMigrate(&AppValuesOverlay{})
+}
@@ -0,0 +1,47 @@
+package v1_2_7_f_app_promotion
+
+import (
+	"github.com/kubespace/kubespace/pkg/model/migrate/migration"
+	v1_2_7_e "github.com/kubespace/kubespace/pkg/model/migrate/v1_2/v1_2_7_e_app_values_overlay"
+	"gorm.io/gorm"
+	"time"
+)
+
+var MigrateVersion = "v1.2.7_f"
+
+func init() {
+	migration.Register(&migration.Migration{
+		Version:       MigrateVersion,
+		ParentVersion: v1_2_7_e.MigrateVersion,
+		MigrateFunc:   Migrate,
+		Description:   "增加应用晋级记录表",
+	})
+}
+
+// AppPromotion 应用版本晋级记录
+type AppPromotion struct {
+	ID                 uint        `gorm:"primaryKey" json:"id"`
+	SourceProjectId    uint        `gorm:"not null;index" json:"source_project_id"`
+	SourceAppId        uint        `gorm:"not null;index" json:"source_app_id"`
+	SourceAppVersionId uint        `gorm:"not null" json:"source_app_version_id"`
+	TargetProjectId    uint        `gorm:"not null;index" json:"target_project_id"`
+	TargetAppId        uint        `gorm:"not null;default:0" json:"target_app_id"`
+	TargetAppVersionId uint        `gorm:"not null;default:0" json:"target_app_version_id"`
+	AppName            string      `gorm:"size:255;not null" json:"app_name"`
+	PackageName        string      `gorm:"size:255;not null" json:"package_name"`
+	PackageVersion     string      `gorm:"size:255;not null" json:"package_version"`
+	Values             string      `gorm:"type:longtext;not null" json:"values"`
+	Overlays           interface{} `gorm:"type:json" json:"overlays"`
+	Install            bool        `gorm:"not null;default:false" json:"install"`
+	Status             string      `gorm:"size:50;not null" json:"status"`
+	Message            string      `gorm:"type:text" json:"message"`
+	CreateUser         string      `gorm:"size:255;not null" json:"create_user"`
+	ApproveUser        string      `gorm:"size:255;not null;default:''" json:"approve_user"`
+	ApproveTime        *time.Time  `json:"approve_time"`
+	CreateTime         time.Time   `gorm:"column:create_time;not null;autoCreateTime" json:"create_time"`
+	UpdateTime         time.Time   `gorm:"column:update_time;not null;autoUpdateTime" json:"update_time"`
+}
+
+func Migrate(db *gorm.DB) error {
+	return db.AutoMigrate(&AppPromotion{})
+}
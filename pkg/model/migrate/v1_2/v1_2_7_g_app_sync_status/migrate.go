@@ -0,0 +1,29 @@
+package v1_2_7_g_app_sync_status
+
+import (
+	"github.com/kubespace/kubespace/pkg/model/migrate/migration"
+	v1_2_7_f "github.com/kubespace/kubespace/pkg/model/migrate/v1_2/v1_2_7_f_app_promotion"
+	"gorm.io/gorm"
+	"time"
+)
+
+var MigrateVersion = "v1.2.7_g"
+
+func init() {
+	migration.Register(&migration.Migration{
+		Version:       MigrateVersion,
+		ParentVersion: v1_2_7_f.MigrateVersion,
+		MigrateFunc:   Migrate,
+		Description:   "应用增加漂移检测状态字段",
+	})
+}
+
+type App struct {
+	SyncStatus    string     `gorm:"size:50;not null;default:''" json:"sync_status"`
+	SyncMessage   string     `gorm:"type:text" json:"sync_message"`
+	SyncCheckTime *time.Time `json:"sync_check_time"`
+}
+
+func Migrate(db *gorm.DB) error {
+	return db.AutoMigrate(&App{})
+}
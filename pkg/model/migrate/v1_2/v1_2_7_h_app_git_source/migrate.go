@@ -0,0 +1,50 @@
+package v1_2_7_h_app_git_source
+
+import (
+	"github.com/kubespace/kubespace/pkg/model/migrate/migration"
+	v1_2_7_g "github.com/kubespace/kubespace/pkg/model/migrate/v1_2/v1_2_7_g_app_sync_status"
+	"gorm.io/gorm"
+	"time"
+)
+
+var MigrateVersion = "v1.2.7_h"
+
+func init() {
+	migration.Register(&migration.Migration{
+		Version:       MigrateVersion,
+		ParentVersion: v1_2_7_g.MigrateVersion,
+		MigrateFunc:   Migrate,
+		Description:   "增加应用git来源表",
+	})
+}
+
+// AppGitSource 应用的git来源
+type AppGitSource struct {
+	ID               uint        `gorm:"primaryKey" json:"id"`
+	AppId            uint        `gorm:"not null;uniqueIndex" json:"app_id"`
+	CodeType         string      `gorm:"size:50;not null" json:"code_type"`
+	ApiUrl           string      `gorm:"size:1024;not null;default:''" json:"api_url"`
+	CloneUrl         string      `gorm:"size:1024;not null" json:"clone_url"`
+	SecretId         uint        `gorm:"not null;default:0;comment:代码仓库认证密钥" json:"secret_id"`
+	Branch           string      `gorm:"size:255;not null" json:"branch"`
+	Path             string      `gorm:"size:1024;not null" json:"path"`
+	ValuesFiles      interface{} `gorm:"type:json" json:"values_files"`
+	AutoSync         bool        `gorm:"not null;default:false" json:"auto_sync"`
+	PollInterval     int         `gorm:"not null;default:3;comment:轮询间隔，单位分钟" json:"poll_interval"`
+	Enabled          bool        `gorm:"not null;default:true" json:"enabled"`
+	WebhookToken     string      `gorm:"size:255;not null;uniqueIndex" json:"webhook_token"`
+	LastCommitId     string      `gorm:"size:255;not null;default:''" json:"last_commit_id"`
+	LastCommitTime   *time.Time  `json:"last_commit_time"`
+	LastAppVersionId uint        `gorm:"not null;default:0;comment:最新提交生成的应用版本" json:"last_app_version_id"`
+	Status           string      `gorm:"size:50;not null;default:'pending'" json:"status"`
+	Message          string      `gorm:"type:text" json:"message"`
+	LastPollTime     *time.Time  `json:"last_poll_time"`
+	CreateUser       string      `gorm:"size:255;not null" json:"create_user"`
+	UpdateUser       string      `gorm:"size:255;not null" json:"update_user"`
+	CreateTime       time.Time   `gorm:"column:create_time;not null;autoCreateTime" json:"create_time"`
+	UpdateTime       time.Time   `gorm:"column:update_time;not null;autoUpdateTime" json:"update_time"`
+}
+
+func Migrate(db *gorm.DB) error {
+	return db.AutoMigrate(&AppGitSource{})
+}
@@ -0,0 +1,38 @@
+package v1_2_7_i_app_stack
+
+import (
+	"github.com/kubespace/kubespace/pkg/model/migrate/migration"
+	v1_2_7_h "github.com/kubespace/kubespace/pkg/model/migrate/v1_2/v1_2_7_h_app_git_source"
+	"gorm.io/gorm"
+	"time"
+)
+
+var MigrateVersion = "v1.2.7_i"
+
+func init() {
+	migration.Register(&migration.Migration{
+		Version:       MigrateVersion,
+		ParentVersion: v1_2_7_h.MigrateVersion,
+		MigrateFunc:   Migrate,
+		Description:   "增加应用栈表",
+	})
+}
+
+// AppStack 应用栈
+type AppStack struct {
+	ID          uint        `gorm:"primaryKey" json:"id"`
+	ProjectId   uint        `gorm:"not null;uniqueIndex:idx_project_name" json:"project_id"`
+	Name        string      `gorm:"size:255;not null;uniqueIndex:idx_project_name" json:"name"`
+	Description string      `gorm:"type:text" json:"description"`
+	Apps        interface{} `gorm:"type:json" json:"apps"`
+	Status      string      `gorm:"size:50;not null;default:''" json:"status"`
+	Message     string      `gorm:"type:text" json:"message"`
+	CreateUser  string      `gorm:"size:255;not null" json:"create_user"`
+	UpdateUser  string      `gorm:"size:255;not null" json:"update_user"`
+	CreateTime  time.Time   `gorm:"column:create_time;not null;autoCreateTime" json:"create_time"`
+	UpdateTime  time.Time   `gorm:"column:update_time;not null;autoUpdateTime" json:"update_time"`
+}
+
+func Migrate(db *gorm.DB) error {
+	return db.AutoMigrate(&AppStack{})
+}
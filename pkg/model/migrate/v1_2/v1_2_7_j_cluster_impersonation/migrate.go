@@ -0,0 +1,26 @@
+package v1_2_7_j_cluster_impersonation
+
+import (
+	"github.com/kubespace/kubespace/pkg/model/migrate/migration"
+	v1_2_7_i "github.com/kubespace/kubespace/pkg/model/migrate/v1_2/v1_2_7_i_app_stack"
+	"gorm.io/gorm"
+)
+
+var MigrateVersion = "v1.2.7_j"
+
+func init() {
+	migration.Register(&migration.Migration{
+		Version:       MigrateVersion,
+		ParentVersion: v1_2_7_i.MigrateVersion,
+		MigrateFunc:   Migrate,
+		Description:   "集群增加用户模拟配置",
+	})
+}
+
+type Cluster struct {
+	Impersonation interface{} `gorm:"type:json" json:"impersonation"`
+}
+
+func Migrate(db *gorm.DB) error {
+	return db.AutoMigrate(&Cluster{})
+}
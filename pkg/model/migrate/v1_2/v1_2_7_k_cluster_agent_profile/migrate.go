@@ -0,0 +1,26 @@
+package v1_2_7_k_cluster_agent_profile
+
+import (
+	"github.com/kubespace/kubespace/pkg/model/migrate/migration"
+	v1_2_7_j "github.com/kubespace/kubespace/pkg/model/migrate/v1_2/v1_2_7_j_cluster_impersonation"
+	"gorm.io/gorm"
+)
+
+var MigrateVersion = "v1.2.7_k"
+
+func init() {
+	migration.Register(&migration.Migration{
+		Version:       MigrateVersion,
+		ParentVersion: v1_2_7_j.MigrateVersion,
+		MigrateFunc:   Migrate,
+		Description:   "集群增加agent权限配置",
+	})
+}
+
+type Cluster struct {
+	AgentProfile interface{} `gorm:"type:json" json:"agent_profile"`
+}
+
+func Migrate(db *gorm.DB) error {
+	return db.AutoMigrate(&Cluster{})
+}
@@ -0,0 +1,41 @@
+package v1_2_7_l_cluster_agent_session
+
+import (
+	"github.com/kubespace/kubespace/pkg/model/migrate/migration"
+	v1_2_7_k "github.com/kubespace/kubespace/pkg/model/migrate/v1_2/v1_2_7_k_cluster_agent_profile"
+	"gorm.io/gorm"
+	"time"
+)
+
+var MigrateVersion = "v1.2.7_l"
+
+func init() {
+	migration.Register(&migration.Migration{
+		Version:       MigrateVersion,
+		ParentVersion: v1_2_7_k.MigrateVersion,
+		MigrateFunc:   Migrate,
+		Description:   "集群增加agent会话凭证、版本以及连接记录",
+	})
+}
+
+type Cluster struct {
+	AgentSessionToken     string     `gorm:"size:255;not null;default:''" json:"-"`
+	AgentPrevSessionToken string     `gorm:"size:255;not null;default:'';comment:轮换前的会话凭证，有效期内仍可使用" json:"-"`
+	AgentSessionTime      *time.Time `gorm:"comment:会话凭证生成时间" json:"-"`
+	AgentVersion          string     `gorm:"size:255;not null;default:'';comment:agent版本" json:"agent_version"`
+	AgentLastSeen         *time.Time `gorm:"comment:最近一次与agent通信的时间" json:"agent_last_seen"`
+}
+
+type ClusterAgentConnection struct {
+	ID             uint       `gorm:"primaryKey" json:"id"`
+	ClusterId      uint       `gorm:"not null;index" json:"cluster_id"`
+	AgentVersion   string     `gorm:"size:255;not null;default:''" json:"agent_version"`
+	RemoteAddr     string     `gorm:"size:255;not null;default:''" json:"remote_addr"`
+	ConnectTime    time.Time  `gorm:"not null" json:"connect_time"`
+	DisconnectTime *time.Time `json:"disconnect_time"`
+	Message        string     `gorm:"type:text" json:"message"`
+}
+
+func Migrate(db *gorm.DB) error {
+	return db.AutoMigrate(&Cluster{}, &ClusterAgentConnection{})
+}
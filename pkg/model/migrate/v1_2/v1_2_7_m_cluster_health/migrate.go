@@ -0,0 +1,38 @@
+package v1_2_7_m_cluster_health
+
+import (
+	"github.com/kubespace/kubespace/pkg/model/migrate/migration"
+	v1_2_7_l "github.com/kubespace/kubespace/pkg/model/migrate/v1_2/v1_2_7_l_cluster_agent_session"
+	"gorm.io/gorm"
+	"time"
+)
+
+var MigrateVersion = "v1.2.7_m"
+
+func init() {
+	migration.Register(&migration.Migration{
+		Version:       MigrateVersion,
+		ParentVersion: v1_2_7_l.MigrateVersion,
+		MigrateFunc:   Migrate,
+		Description:   "集群增加健康检查状态、阈值以及健康检查记录",
+	})
+}
+
+type Cluster struct {
+	HealthStatus     string      `gorm:"size:50;not null;default:''" json:"health_status"`
+	HealthCheckTime  *time.Time  `json:"health_check_time"`
+	HealthThresholds interface{} `gorm:"type:json" json:"health_thresholds"`
+}
+
+type ClusterHealth struct {
+	ID        uint        `gorm:"primaryKey" json:"id"`
+	ClusterId uint        `gorm:"not null;index:ClusterHealthTime" json:"cluster_id"`
+	Status    string      `gorm:"size:50;not null" json:"status"`
+	Version   string      `gorm:"size:255;not null;default:''" json:"version"`
+	Checks    interface{} `gorm:"type:json" json:"checks"`
+	CheckTime time.Time   `gorm:"not null;index:ClusterHealthTime" json:"check_time"`
+}
+
+func Migrate(db *gorm.DB) error {
+	return db.AutoMigrate(&Cluster{}, &ClusterHealth{})
+}
@@ -0,0 +1,43 @@
+package v1_2_7_n_cluster_event
+
+import (
+	"github.com/kubespace/kubespace/pkg/model/migrate/migration"
+	v1_2_7_m "github.com/kubespace/kubespace/pkg/model/migrate/v1_2/v1_2_7_m_cluster_health"
+	"gorm.io/gorm"
+	"time"
+)
+
+var MigrateVersion = "v1.2.7_n"
+
+func init() {
+	migration.Register(&migration.Migration{
+		Version:       MigrateVersion,
+		ParentVersion: v1_2_7_m.MigrateVersion,
+		MigrateFunc:   Migrate,
+		Description:   "增加集群事件归档表",
+	})
+}
+
+type ClusterEvent struct {
+	ID         uint      `gorm:"primaryKey" json:"id"`
+	ClusterId  uint      `gorm:"not null;uniqueIndex:ClusterEventUid;index:ClusterEventTime" json:"cluster_id"`
+	Uid        string    `gorm:"size:64;not null;uniqueIndex:ClusterEventUid" json:"uid"`
+	Name       string    `gorm:"size:255;not null" json:"name"`
+	Namespace  string    `gorm:"size:255;not null;default:'';index:ClusterEventTime" json:"namespace"`
+	ObjectKind string    `gorm:"size:255;not null;default:''" json:"object_kind"`
+	ObjectName string    `gorm:"size:255;not null;default:''" json:"object_name"`
+	ObjectUid  string    `gorm:"size:64;not null;default:''" json:"object_uid"`
+	Reason     string    `gorm:"size:255;not null;default:''" json:"reason"`
+	Type       string    `gorm:"size:50;not null;default:''" json:"type"`
+	Message    string    `gorm:"type:text" json:"message"`
+	Source     string    `gorm:"size:255;not null;default:'';comment:产生事件的组件以及节点" json:"source"`
+	Count      int32     `gorm:"not null;default:1" json:"count"`
+	FirstTime  time.Time `gorm:"not null" json:"first_time"`
+	LastTime   time.Time `gorm:"not null;index:ClusterEventTime" json:"last_time"`
+	CreateTime time.Time `gorm:"column:create_time;not null;autoCreateTime" json:"create_time"`
+	UpdateTime time.Time `gorm:"column:update_time;not null;autoUpdateTime" json:"update_time"`
+}
+
+func Migrate(db *gorm.DB) error {
+	return db.AutoMigrate(&ClusterEvent{})
+}
@@ -0,0 +1,70 @@
+package v1_2_7_o_notification
+
+import (
+	"github.com/kubespace/kubespace/pkg/model/migrate/migration"
+	v1_2_7_n "github.com/kubespace/kubespace/pkg/model/migrate/v1_2/v1_2_7_n_cluster_event"
+	"gorm.io/gorm"
+	"time"
+)
+
+var MigrateVersion = "v1.2.7_o"
+
+func init() {
+	migration.Register(&migration.Migration{
+		Version:       MigrateVersion,
+		ParentVersion: v1_2_7_n.MigrateVersion,
+		MigrateFunc:   Migrate,
+		Description:   "增加通知渠道、通知规则以及发送记录，应用增加运行状态字段",
+	})
+}
+
+type App struct {
+	RuntimeStatus string `gorm:"size:50;not null;default:''" json:"runtime_status"`
+}
+
+type NotificationChannel struct {
+	ID         uint        `gorm:"primaryKey" json:"id"`
+	Name       string      `gorm:"size:255;not null;uniqueIndex" json:"name"`
+	Type       string      `gorm:"size:50;not null" json:"type"`
+	Config     interface{} `gorm:"type:json" json:"config"`
+	Secret     string      `gorm:"size:2000;not null;default:''" json:"-"`
+	CreateUser string      `gorm:"size:255;not null" json:"create_user"`
+	UpdateUser string      `gorm:"size:255;not null" json:"update_user"`
+	CreateTime time.Time   `gorm:"column:create_time;not null;autoCreateTime" json:"create_time"`
+	UpdateTime time.Time   `gorm:"column:update_time;not null;autoUpdateTime" json:"update_time"`
+}
+
+type NotificationRule struct {
+	ID              uint        `gorm:"primaryKey" json:"id"`
+	Name            string      `gorm:"size:255;not null" json:"name"`
+	Scope           string      `gorm:"size:50;not null;index:NotificationRuleScope" json:"scope"`
+	ScopeId         uint        `gorm:"not null;index:NotificationRuleScope" json:"scope_id"`
+	Events          interface{} `gorm:"type:json" json:"events"`
+	ChannelIds      interface{} `gorm:"type:json" json:"channel_ids"`
+	TitleTemplate   string      `gorm:"size:1000;not null;default:''" json:"title_template"`
+	ContentTemplate string      `gorm:"type:text" json:"content_template"`
+	Enabled         bool        `gorm:"not null;default:true" json:"enabled"`
+	CreateUser      string      `gorm:"size:255;not null" json:"create_user"`
+	UpdateUser      string      `gorm:"size:255;not null" json:"update_user"`
+	CreateTime      time.Time   `gorm:"column:create_time;not null;autoCreateTime" json:"create_time"`
+	UpdateTime      time.Time   `gorm:"column:update_time;not null;autoUpdateTime" json:"update_time"`
+}
+
+type NotificationDelivery struct {
+	ID          uint      `gorm:"primaryKey" json:"id"`
+	RuleId      uint      `gorm:"not null;index" json:"rule_id"`
+	ChannelId   uint      `gorm:"not null" json:"channel_id"`
+	ChannelName string    `gorm:"size:255;not null;default:''" json:"channel_name"`
+	Event       string    `gorm:"size:100;not null" json:"event"`
+	Scope       string    `gorm:"size:50;not null;index:NotificationDeliveryScope" json:"scope"`
+	ScopeId     uint      `gorm:"not null;index:NotificationDeliveryScope" json:"scope_id"`
+	Title       string    `gorm:"size:1000;not null;default:''" json:"title"`
+	Content     string    `gorm:"type:text" json:"content"`
+	Status      string    `gorm:"size:50;not null" json:"status"`
+	Error       string    `gorm:"type:text" json:"error"`
+	CreateTime  time.Time `gorm:"column:create_time;not null;autoCreateTime;index" json:"create_time"`
+}
+
+func Migrate(db *gorm.DB) error {
+	return db.AutoMigrate(&App{}, &NotificationChannel{}, &NotificationRule{}, &NotificationDelivery{})
+}
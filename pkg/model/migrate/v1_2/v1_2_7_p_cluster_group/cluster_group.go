@@ -0,0 +1,38 @@
+package v1_2_7_p_cluster_group
+
+import (
+	"github.com/kubespace/kubespace/pkg/model/migrate/migration"
+	v1_2_7_o "github.com/kubespace/kubespace/pkg/model/migrate/v1_2/v1_2_7_o_notification"
+	"gorm.io/gorm"
+	"time"
+)
+
+var MigrateVersion = "v1.2.7_p"
+
+func init() {
+	migration.Register(&migration.Migration{
+		Version:       MigrateVersion,
+		ParentVersion: v1_2_7_o.MigrateVersion,
+		MigrateFunc:   Migrate,
+		Description:   "增加集群标签以及集群组表",
+	})
+}
+
+type Cluster struct {
+	Labels interface{} `gorm:"type:json" json:"labels"`
+}
+
+type ClusterGroup struct {
+	ID          uint      `gorm:"primaryKey" json:"id"`
+	Name        string    `gorm:"size:255;not null;uniqueIndex" json:"name"`
+	Description string    `gorm:"size:1000;not null;default:''" json:"description"`
+	Selector    string    `gorm:"size:1000;not null" json:"selector"`
+	CreateUser  string    `gorm:"size:255;not null" json:"create_user"`
+	UpdateUser  string    `gorm:"size:255;not null" json:"update_user"`
+	CreateTime  time.Time `gorm:"column:create_time;not null;autoCreateTime" json:"create_time"`
+	UpdateTime  time.Time `gorm:"column:update_time;not null;autoUpdateTime" json:"update_time"`
+}
+
+func Migrate(db *gorm.DB) error {
+	return db.AutoMigrate(&Cluster{}, &ClusterGroup{})
+}
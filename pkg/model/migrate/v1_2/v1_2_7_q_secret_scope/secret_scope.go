@@ -0,0 +1,26 @@
+package v1_2_7_q_secret_scope
+
+import (
+	"github.com/kubespace/kubespace/pkg/model/migrate/migration"
+	v1_2_7_p "github.com/kubespace/kubespace/pkg/model/migrate/v1_2/v1_2_7_p_cluster_group"
+	"gorm.io/gorm"
+)
+
+var MigrateVersion = "v1.2.7_q"
+
+func init() {
+	migration.Register(&migration.Migration{
+		Version:       MigrateVersion,
+		ParentVersion: v1_2_7_p.MigrateVersion,
+		MigrateFunc:   Migrate,
+		Description:   "平台密钥增加允许引用的工作空间以及集群",
+	})
+}
+
+type SettingsSecret struct {
+	Scopes interface{} `gorm:"type:json" json:"scopes"`
+}
+
+func Migrate(db *gorm.DB) error {
+	return db.AutoMigrate(&SettingsSecret{})
+}
@@ -0,0 +1,27 @@
+package v1_2_7_r_job_queue_time
+
+import (
+	"github.com/kubespace/kubespace/pkg/model/migrate/migration"
+	v1_2_7_q "github.com/kubespace/kubespace/pkg/model/migrate/v1_2/v1_2_7_q_secret_scope"
+	"gorm.io/gorm"
+	"time"
+)
+
+var MigrateVersion = "v1.2.7_r"
+
+func init() {
+	migration.Register(&migration.Migration{
+		Version:       MigrateVersion,
+		ParentVersion: v1_2_7_q.MigrateVersion,
+		MigrateFunc:   Migrate,
+		Description:   "pipelineRunJob增加开始排队时间字段",
+	})
+}
+
+type PipelineRunJob struct {
+	QueueTime *time.Time `gorm:"comment:任务开始排队的时间，调度后清空" json:"queue_time"`
+}
+
+func Migrate(db *gorm.DB) error {
+	return db.AutoMigrate(&PipelineRunJob{})
+}
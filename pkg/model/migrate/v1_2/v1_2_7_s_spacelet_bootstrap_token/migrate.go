@@ -0,0 +1,31 @@
+package v1_2_7_s_spacelet_bootstrap_token
+
+import (
+	"github.com/kubespace/kubespace/pkg/model/migrate/migration"
+	v1_2_7_r "github.com/kubespace/kubespace/pkg/model/migrate/v1_2/v1_2_7_r_job_queue_time"
+	"gorm.io/gorm"
+	"time"
+)
+
+var MigrateVersion = "v1.2.7_s"
+
+func init() {
+	migration.Register(&migration.Migration{
+		Version:       MigrateVersion,
+		ParentVersion: v1_2_7_r.MigrateVersion,
+		MigrateFunc:   Migrate,
+		Description:   "增加spacelet引导token",
+	})
+}
+
+type SpaceletBootstrapToken struct {
+	ID         uint      `gorm:"primaryKey" json:"id"`
+	Token      string    `gorm:"size:255;not null;uniqueIndex" json:"token"`
+	ExpireTime time.Time `gorm:"not null" json:"expire_time"`
+	CreateUser string    `gorm:"size:255;not null" json:"create_user"`
+	CreateTime time.Time `gorm:"column:create_time;not null;autoCreateTime" json:"create_time"`
+}
+
+func Migrate(db *gorm.DB) error {
+	return db.AutoMigrate(&SpaceletBootstrapToken{})
+}
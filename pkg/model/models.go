@@ -5,6 +5,7 @@ import (
 	"github.com/kubespace/kubespace/pkg/informer/listwatcher/config"
 	"github.com/kubespace/kubespace/pkg/model/manager/audit"
 	"github.com/kubespace/kubespace/pkg/model/manager/cluster"
+	"github.com/kubespace/kubespace/pkg/model/manager/notification"
 	"github.com/kubespace/kubespace/pkg/model/manager/pipeline"
 	"github.com/kubespace/kubespace/pkg/model/manager/project"
 	"github.com/kubespace/kubespace/pkg/model/manager/settings"
@@ -16,6 +17,8 @@ import (
 type Config struct {
 	DB                *db.DB
 	ListWatcherConfig *config.ListWatcherConfig
+	// EncryptionKey 数据库中敏感数据的加密密钥，如CA私钥，多个实例需要使用相同的密钥
+	EncryptionKey string
 }
 
 type Models struct {
@@ -45,13 +48,21 @@ type Models struct {
 	ProjectManager    *project.ManagerProject
 	AppStoreManager   *project.AppStoreManager
 
+	AppStoreSourceManager *project.AppStoreSourceManager
+	AppPromotionManager   *project.AppPromotionManager
+	AppGitSourceManager   *project.AppGitSourceManager
+	AppStackManager       *project.AppStackManager
+
 	SettingsSecretManager *settings.SettingsSecretManager
 	ImageRegistryManager  *settings.ImageRegistryManager
 
-	LdapManager     *settings.LdapManager
-	SpaceletManager *spacelet.SpaceletManager
+	LdapManager                 *settings.LdapManager
+	CertificateAuthorityManager *settings.CertificateAuthorityManager
+	SpaceletManager             *spacelet.SpaceletManager
 
 	AuditOperateManager *audit.AuditOperateManager
+
+	NotificationManager *notification.NotificationManager
 }
 
 func NewModels(c *Config) (*Models, error) {
@@ -59,7 +70,6 @@ func NewModels(c *Config) (*Models, error) {
 	sess := user.NewTokenManager(c.DB.RedisInstance)
 
 	userMgr := user.NewUserManager(c.DB.Instance)
-	userRole := user.NewUserRoleManager(c.DB.Instance, userMgr)
 
 	pipelinePluginMgr := pipeline.NewPipelinePluginManager(c.DB.Instance)
 	pipelineMgr := pipeline.NewPipelineManager(c.DB.Instance)
@@ -76,18 +86,26 @@ func NewModels(c *Config) (*Models, error) {
 	imageRegistry := settings.NewSettingsImageRegistryManager(c.DB.Instance)
 
 	ldap := settings.NewLdapManager(c.DB.Instance)
+	certificateAuthority := settings.NewCertificateAuthorityManager(c.DB.Instance, c.EncryptionKey)
 
 	appVersionMgr := project.NewAppVersionManager(c.DB.Instance)
 	AppMgr := project.NewAppManager(appVersionMgr, c.DB.Instance)
 	appStoreMgr := project.NewAppStoreManager(appVersionMgr, c.DB.Instance)
+	appStoreSourceMgr := project.NewAppStoreSourceManager(c.DB.Instance, c.ListWatcherConfig)
+	appPromotionMgr := project.NewAppPromotionManager(c.DB.Instance)
+	appGitSourceMgr := project.NewAppGitSourceManager(c.DB.Instance, c.ListWatcherConfig)
+	appStackMgr := project.NewAppStackManager(c.DB.Instance, c.ListWatcherConfig)
 	projectMgr := project.NewManagerProject(c.DB.Instance, AppMgr)
 
 	cm := cluster.NewClusterManager(c.DB.Instance, c.ListWatcherConfig, AppMgr)
+	userRole := user.NewUserRoleManager(c.DB.Instance, userMgr, cm)
 
 	sl := spacelet.NewSpaceletManager(c.DB.Instance)
 
 	auditOperateMgr := audit.NewAuditOperateManager(c.DB.Instance)
 
+	notificationMgr := notification.NewNotificationManager(c.DB.Instance)
+
 	return &Models{
 		db:                          c.DB.Instance,
 		ListWatcherConfig:           c.ListWatcherConfig,
@@ -108,12 +126,18 @@ func NewModels(c *Config) (*Models, error) {
 		PipelineCodeCacheManager:    pipelineCodeCacheMgr,
 		SettingsSecretManager:       secrets,
 		LdapManager:                 ldap,
+		CertificateAuthorityManager: certificateAuthority,
 		ProjectManager:              projectMgr,
 		AppManager:                  AppMgr,
 		AppVersionManager:           appVersionMgr,
 		ImageRegistryManager:        imageRegistry,
 		AppStoreManager:             appStoreMgr,
+		AppStoreSourceManager:       appStoreSourceMgr,
+		AppPromotionManager:         appPromotionMgr,
+		AppGitSourceManager:         appGitSourceMgr,
+		AppStackManager:             appStackMgr,
 		SpaceletManager:             sl,
 		AuditOperateManager:         auditOperateMgr,
+		NotificationManager:         notificationMgr,
 	}, nil
 }
@@ -1,12 +1,27 @@
 package types
 
-import "time"
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"github.com/kubespace/kubespace/pkg/core/db"
+	"path"
+	"time"
+)
 
 const (
 	AppStatusUninstall    = "UnInstall"
 	AppStatusNotReady     = "NotReady"
 	AppStatusRunningFault = "RunningFault"
 	AppStatusRunning      = "Running"
+	// AppStatusOutOfSync 应用运行正常，但集群中的资源与安装时的release清单不一致
+	AppStatusOutOfSync = "OutOfSync"
+
+	// AppSyncStatusSynced 集群中的资源与release清单一致
+	AppSyncStatusSynced = "Synced"
+	// AppSyncStatusOutOfSync 集群中的资源被手动修改或者删除
+	AppSyncStatusOutOfSync = "OutOfSync"
+	// AppSyncStatusUnknown 集群agent版本过旧，不支持漂移检测
+	AppSyncStatusUnknown = "Unknown"
 
 	// AppTypeOrdinaryApp 普通应用
 	AppTypeOrdinaryApp = "ordinary_app"
@@ -32,10 +47,38 @@ type App struct {
 	CreateTime   time.Time   `gorm:"column:create_time;not null;autoCreateTime" json:"create_time"`
 	UpdateTime   time.Time   `gorm:"column:update_time;not null;autoUpdateTime" json:"update_time"`
 
+	// SyncStatus 漂移检测结果，为空表示还未检测
+	SyncStatus string `gorm:"size:50;not null;default:''" json:"sync_status"`
+	// SyncMessage 漂移的资源以及字段
+	SyncMessage   string     `gorm:"type:text" json:"sync_message"`
+	SyncCheckTime *time.Time `json:"sync_check_time"`
+
+	// RuntimeStatus 定时检测到的运行状态，状态变化时发送通知
+	RuntimeStatus string `gorm:"size:50;not null;default:''" json:"runtime_status"`
+
 	PodsNum      int `gorm:"-" json:"pods_num"`
 	ReadyPodsNum int `gorm:"-" json:"ready_pods_num"`
 }
 
+// AppDriftCheckInterval 应用漂移检测间隔
+const AppDriftCheckInterval = 5 * time.Minute
+
+// DriftCheckDue 已安装的应用是否到了下次漂移检测时间
+func (a *App) DriftCheckDue() bool {
+	if a.Status == AppStatusUninstall {
+		return false
+	}
+	return a.SyncCheckTime == nil || time.Since(*a.SyncCheckTime) >= AppDriftCheckInterval
+}
+
+func (a *App) Unmarshal(bytes []byte) (interface{}, error) {
+	var app App
+	if err := json.Unmarshal(bytes, &app); err != nil {
+		return nil, err
+	}
+	return app, nil
+}
+
 // AppRevision 应用安装升级历史记录
 type AppRevision struct {
 	ID            uint      `gorm:"primaryKey" json:"id"`
@@ -46,6 +89,23 @@ type AppRevision struct {
 	CreateUser    string    `gorm:"size:50;not null" json:"create_user"`
 	CreateTime    time.Time `gorm:"column:create_time;not null;autoCreateTime" json:"create_time"`
 	UpdateTime    time.Time `gorm:"column:update_time;not null;autoUpdateTime" json:"update_time"`
+
+	// 历史记录对应的应用版本包信息，版本被删除时为空
+	PackageName    string `gorm:"-" json:"package_name"`
+	PackageVersion string `gorm:"-" json:"package_version"`
+}
+
+// AppValuesOverlay 应用values覆盖配置，如不同环境的prod.yaml，安装时按指定顺序合并到应用values
+type AppValuesOverlay struct {
+	ID          uint      `gorm:"primaryKey" json:"id"`
+	AppId       uint      `gorm:"not null;uniqueIndex:AppValuesOverlayNameUnique" json:"app_id"`
+	Name        string    `gorm:"size:255;not null;uniqueIndex:AppValuesOverlayNameUnique" json:"name"`
+	Description string    `gorm:"size:2000;" json:"description"`
+	Values      string    `gorm:"type:longtext;not null" json:"values"`
+	CreateUser  string    `gorm:"size:255;not null" json:"create_user"`
+	UpdateUser  string    `gorm:"size:255;not null" json:"update_user"`
+	CreateTime  time.Time `gorm:"column:create_time;not null;autoCreateTime" json:"create_time"`
+	UpdateTime  time.Time `gorm:"column:update_time;not null;autoUpdateTime" json:"update_time"`
 }
 
 // AppStore 应用商店应用
@@ -61,11 +121,107 @@ type AppStore struct {
 	UpdateTime  time.Time `gorm:"column:update_time;not null;autoUpdateTime" json:"update_time"`
 }
 
+const (
+	// AppStoreSourceTypeHelm 通过index.yaml获取chart的helm仓库
+	AppStoreSourceTypeHelm = "helm"
+	// AppStoreSourceTypeOci oci镜像仓库
+	AppStoreSourceTypeOci = "oci"
+
+	// AppStoreSourceStatusPending 等待同步，新建或者手动刷新后立即同步
+	AppStoreSourceStatusPending = "pending"
+	AppStoreSourceStatusSyncing = "syncing"
+	AppStoreSourceStatusOK      = "ok"
+	AppStoreSourceStatusError   = "error"
+
+	// AppStoreSourceDefaultSyncInterval 默认同步间隔，单位分钟
+	AppStoreSourceDefaultSyncInterval = 60
+	// AppStoreSourceDefaultMaxVersions 每个chart默认同步最新的版本数
+	AppStoreSourceDefaultMaxVersions = 5
+)
+
+// AppStoreSource 应用商店同步源，定时从远程helm仓库或者oci仓库同步chart新版本到应用商店
+type AppStoreSource struct {
+	ID   uint   `gorm:"primaryKey" json:"id"`
+	Name string `gorm:"size:255;not null;uniqueIndex" json:"name"`
+	Type string `gorm:"size:50;not null;comment:仓库类型，helm/oci" json:"type"`
+	// helm仓库为http(s)地址，oci仓库为oci://registry/namespace
+	Url      string `gorm:"size:1024;not null" json:"url"`
+	SecretId uint   `gorm:"not null;default:0;comment:仓库认证密钥" json:"secret_id"`
+	// chart名称白名单，支持通配符，为空时同步helm仓库所有chart，oci仓库必须配置chart名称
+	Allowlist   AppStoreSourceAllowlist `gorm:"type:json" json:"allowlist"`
+	AppType     string                  `gorm:"size:255;not null;comment:同步到应用商店的应用类型" json:"app_type"`
+	MaxVersions int                     `gorm:"not null;default:5;comment:每个chart同步最新的版本数" json:"max_versions"`
+	// 校验chart签名的gpg公钥，配置后有签名文件的chart都会进行校验
+	Keyring string `gorm:"type:text" json:"keyring"`
+	// 是否只同步签名校验通过的chart
+	RequireProvenance bool `gorm:"not null;default:false" json:"require_provenance"`
+	SyncInterval      int  `gorm:"not null;default:60;comment:同步间隔，单位分钟" json:"sync_interval"`
+	Enabled           bool `gorm:"not null;default:true" json:"enabled"`
+
+	Status       string     `gorm:"size:50;not null;default:'pending'" json:"status"`
+	Message      string     `gorm:"type:text" json:"message"`
+	LastSyncTime *time.Time `json:"last_sync_time"`
+	CreateUser   string     `gorm:"size:255;not null" json:"create_user"`
+	UpdateUser   string     `gorm:"size:255;not null" json:"update_user"`
+	CreateTime   time.Time  `gorm:"column:create_time;not null;autoCreateTime" json:"create_time"`
+	UpdateTime   time.Time  `gorm:"column:update_time;not null;autoUpdateTime" json:"update_time"`
+}
+
+// Allowed chart是否在白名单中
+func (s *AppStoreSource) Allowed(chartName string) bool {
+	if len(s.Allowlist) == 0 {
+		return s.Type == AppStoreSourceTypeHelm
+	}
+	for _, pattern := range s.Allowlist {
+		if matched, _ := path.Match(pattern, chartName); matched {
+			return true
+		}
+	}
+	return false
+}
+
+// SyncDue 是否到了下次同步时间
+func (s *AppStoreSource) SyncDue() bool {
+	if !s.Enabled {
+		return false
+	}
+	if s.Status == AppStoreSourceStatusPending || s.LastSyncTime == nil {
+		return true
+	}
+	interval := s.SyncInterval
+	if interval <= 0 {
+		interval = AppStoreSourceDefaultSyncInterval
+	}
+	return time.Since(*s.LastSyncTime) >= time.Duration(interval)*time.Minute
+}
+
+func (s *AppStoreSource) Unmarshal(bytes []byte) (interface{}, error) {
+	var source AppStoreSource
+	if err := json.Unmarshal(bytes, &source); err != nil {
+		return nil, err
+	}
+	return source, nil
+}
+
+type AppStoreSourceAllowlist []string
+
+func (l *AppStoreSourceAllowlist) Scan(value interface{}) error {
+	return db.Scan(value, l)
+}
+
+func (l AppStoreSourceAllowlist) Value() (driver.Value, error) {
+	return db.Value(l)
+}
+
 const (
 	// AppVersionFromImport 导入应用
 	AppVersionFromImport = "import"
 	// AppVersionFromSpace 创建应用
 	AppVersionFromSpace = "space"
+	// AppVersionFromSync 从远程chart仓库同步
+	AppVersionFromSync = "sync"
+	// AppVersionFromGit 从git仓库中的chart目录打包
+	AppVersionFromGit = "git"
 )
 
 // AppVersion 应用版本
@@ -80,6 +236,9 @@ type AppVersion struct {
 	Values         string    `gorm:"type:longtext;not null" json:"values"`
 	Description    string    `gorm:"type:text;" json:"description"`
 	ChartPath      string    `gorm:"size:255;not null;comment:该应用版本chart存储路径" json:"chart_path"`
+	SourceId       uint      `gorm:"not null;default:0;comment:同步来源的应用商店源id" json:"source_id"`
+	Digest         string    `gorm:"size:255;not null;default:'';comment:同步的chart包摘要" json:"digest"`
+	Verified       bool      `gorm:"not null;default:false;comment:chart签名是否校验通过" json:"verified"`
 	CreateUser     string    `gorm:"size:50;not null" json:"create_user"`
 	CreateTime     time.Time `gorm:"column:create_time;not null;autoCreateTime" json:"create_time"`
 	UpdateTime     time.Time `gorm:"column:update_time;not null;autoUpdateTime" json:"update_time"`
@@ -93,3 +252,206 @@ type AppVersionChart struct {
 	CreateTime time.Time `gorm:"column:create_time;not null;autoCreateTime" json:"create_time"`
 	UpdateTime time.Time `gorm:"column:update_time;not null;autoUpdateTime" json:"update_time"`
 }
+
+const (
+	// AppPromotionStatusPending 等待目标工作空间管理员审批
+	AppPromotionStatusPending = "pending"
+	// AppPromotionStatusPromoting 正在晋级
+	AppPromotionStatusPromoting = "promoting"
+	// AppPromotionStatusRejected 审批拒绝
+	AppPromotionStatusRejected = "rejected"
+	// AppPromotionStatusSucceeded 晋级成功
+	AppPromotionStatusSucceeded = "succeeded"
+	// AppPromotionStatusFailed 晋级失败
+	AppPromotionStatusFailed = "failed"
+)
+
+// AppPromotion 应用版本晋级记录，将工作空间应用的版本发布到其他工作空间，如dev到staging再到prod
+type AppPromotion struct {
+	ID                 uint `gorm:"primaryKey" json:"id"`
+	SourceProjectId    uint `gorm:"not null;index" json:"source_project_id"`
+	SourceAppId        uint `gorm:"not null;index" json:"source_app_id"`
+	SourceAppVersionId uint `gorm:"not null" json:"source_app_version_id"`
+	TargetProjectId    uint `gorm:"not null;index" json:"target_project_id"`
+	// 晋级成功后目标工作空间中的应用以及版本
+	TargetAppId        uint   `gorm:"not null;default:0" json:"target_app_id"`
+	TargetAppVersionId uint   `gorm:"not null;default:0" json:"target_app_version_id"`
+	AppName            string `gorm:"size:255;not null" json:"app_name"`
+	PackageName        string `gorm:"size:255;not null" json:"package_name"`
+	PackageVersion     string `gorm:"size:255;not null" json:"package_version"`
+	Values             string `gorm:"type:longtext;not null" json:"values"`
+	// 安装时按顺序合并的目标应用values覆盖配置
+	Overlays AppPromotionOverlays `gorm:"type:json" json:"overlays"`
+	// 晋级后是否在目标工作空间安装/升级应用
+	Install     bool       `gorm:"not null;default:false" json:"install"`
+	Status      string     `gorm:"size:50;not null" json:"status"`
+	Message     string     `gorm:"type:text" json:"message"`
+	CreateUser  string     `gorm:"size:255;not null" json:"create_user"`
+	ApproveUser string     `gorm:"size:255;not null;default:''" json:"approve_user"`
+	ApproveTime *time.Time `json:"approve_time"`
+	CreateTime  time.Time  `gorm:"column:create_time;not null;autoCreateTime" json:"create_time"`
+	UpdateTime  time.Time  `gorm:"column:update_time;not null;autoUpdateTime" json:"update_time"`
+}
+
+type AppPromotionOverlays []string
+
+func (o *AppPromotionOverlays) Scan(value interface{}) error {
+	return db.Scan(value, o)
+}
+
+func (o AppPromotionOverlays) Value() (driver.Value, error) {
+	return db.Value(o)
+}
+
+const (
+	// AppGitSourceStatusPending 等待同步，新建、手动同步或者收到webhook后立即同步
+	AppGitSourceStatusPending = "pending"
+	AppGitSourceStatusSyncing = "syncing"
+	AppGitSourceStatusOK      = "ok"
+	AppGitSourceStatusError   = "error"
+
+	// AppGitSourceDefaultPollInterval 默认轮询git仓库的间隔，单位分钟
+	AppGitSourceDefaultPollInterval = 3
+)
+
+// AppGitSource 应用的git来源，应用的chart以及values保存在git仓库中，
+// 分支有新的提交时打包chart生成新的应用版本，并根据配置自动或者手动部署
+type AppGitSource struct {
+	ID    uint `gorm:"primaryKey" json:"id"`
+	AppId uint `gorm:"not null;uniqueIndex" json:"app_id"`
+	// CodeType 代码仓库类型，git/https/github/gitlab/gitee
+	CodeType string `gorm:"size:50;not null" json:"code_type"`
+	ApiUrl   string `gorm:"size:1024;not null;default:''" json:"api_url"`
+	CloneUrl string `gorm:"size:1024;not null" json:"clone_url"`
+	SecretId uint   `gorm:"not null;default:0;comment:代码仓库认证密钥" json:"secret_id"`
+	Branch   string `gorm:"size:255;not null" json:"branch"`
+	// Path chart目录在仓库中的路径
+	Path string `gorm:"size:1024;not null" json:"path"`
+	// ValuesFiles 仓库中的values文件路径，按顺序合并，为空时使用chart中的values.yaml
+	ValuesFiles AppGitSourceValuesFiles `gorm:"type:json" json:"values_files"`
+	// AutoSync 有新版本时是否自动安装或升级应用
+	AutoSync     bool `gorm:"not null;default:false" json:"auto_sync"`
+	PollInterval int  `gorm:"not null;default:3;comment:轮询间隔，单位分钟" json:"poll_interval"`
+	Enabled      bool `gorm:"not null;default:true" json:"enabled"`
+	// WebhookToken 代码仓库webhook回调地址中的认证token
+	WebhookToken string `gorm:"size:255;not null;uniqueIndex" json:"webhook_token"`
+
+	LastCommitId     string     `gorm:"size:255;not null;default:''" json:"last_commit_id"`
+	LastCommitTime   *time.Time `json:"last_commit_time"`
+	LastAppVersionId uint       `gorm:"not null;default:0;comment:最新提交生成的应用版本" json:"last_app_version_id"`
+	Status           string     `gorm:"size:50;not null;default:'pending'" json:"status"`
+	Message          string     `gorm:"type:text" json:"message"`
+	LastPollTime     *time.Time `json:"last_poll_time"`
+	CreateUser       string     `gorm:"size:255;not null" json:"create_user"`
+	UpdateUser       string     `gorm:"size:255;not null" json:"update_user"`
+	CreateTime       time.Time  `gorm:"column:create_time;not null;autoCreateTime" json:"create_time"`
+	UpdateTime       time.Time  `gorm:"column:update_time;not null;autoUpdateTime" json:"update_time"`
+}
+
+// PollDue 是否到了下次轮询git仓库的时间
+func (s *AppGitSource) PollDue() bool {
+	if !s.Enabled {
+		return false
+	}
+	if s.Status == AppGitSourceStatusPending || s.LastPollTime == nil {
+		return true
+	}
+	interval := s.PollInterval
+	if interval <= 0 {
+		interval = AppGitSourceDefaultPollInterval
+	}
+	return time.Since(*s.LastPollTime) >= time.Duration(interval)*time.Minute
+}
+
+func (s *AppGitSource) Unmarshal(bytes []byte) (interface{}, error) {
+	var source AppGitSource
+	if err := json.Unmarshal(bytes, &source); err != nil {
+		return nil, err
+	}
+	return source, nil
+}
+
+type AppGitSourceValuesFiles []string
+
+func (f *AppGitSourceValuesFiles) Scan(value interface{}) error {
+	return db.Scan(value, f)
+}
+
+func (f AppGitSourceValuesFiles) Value() (driver.Value, error) {
+	return db.Value(f)
+}
+
+const (
+	// AppStackStatusInstalling 等待或者正在按依赖顺序安装应用栈中的应用
+	AppStackStatusInstalling = "Installing"
+	AppStackStatusUpgrading  = "Upgrading"
+	// AppStackStatusDestroying 等待或者正在按依赖的逆序销毁应用栈中的应用
+	AppStackStatusDestroying = "Destroying"
+	AppStackStatusReady      = "Ready"
+	AppStackStatusDestroyed  = "Destroyed"
+	AppStackStatusFailed     = "Failed"
+
+	// AppStackDefaultReadyTimeout 默认等待应用运行正常的超时时间，单位秒
+	AppStackDefaultReadyTimeout = 600
+)
+
+// AppStack 应用栈，将工作空间中有依赖关系的多个应用作为整体，按依赖顺序安装、升级以及销毁
+type AppStack struct {
+	ID          uint   `gorm:"primaryKey" json:"id"`
+	ProjectId   uint   `gorm:"not null;uniqueIndex:idx_project_name" json:"project_id"`
+	Name        string `gorm:"size:255;not null;uniqueIndex:idx_project_name" json:"name"`
+	Description string `gorm:"type:text" json:"description"`
+	// Apps 应用栈中的应用以及依赖关系
+	Apps AppStackApps `gorm:"type:json" json:"apps"`
+	// Status 为Installing/Upgrading/Destroying时由controller按顺序执行
+	Status     string    `gorm:"size:50;not null;default:''" json:"status"`
+	Message    string    `gorm:"type:text" json:"message"`
+	CreateUser string    `gorm:"size:255;not null" json:"create_user"`
+	UpdateUser string    `gorm:"size:255;not null" json:"update_user"`
+	CreateTime time.Time `gorm:"column:create_time;not null;autoCreateTime" json:"create_time"`
+	UpdateTime time.Time `gorm:"column:update_time;not null;autoUpdateTime" json:"update_time"`
+}
+
+// Operating 应用栈是否有未完成的安装、升级或者销毁
+func (s *AppStack) Operating() bool {
+	return s.Status == AppStackStatusInstalling || s.Status == AppStackStatusUpgrading || s.Status == AppStackStatusDestroying
+}
+
+func (s *AppStack) Unmarshal(bytes []byte) (interface{}, error) {
+	var stack AppStack
+	if err := json.Unmarshal(bytes, &stack); err != nil {
+		return nil, err
+	}
+	return stack, nil
+}
+
+// AppStackApp 应用栈中的应用
+type AppStackApp struct {
+	AppId uint `json:"app_id"`
+	// AppVersionId 应用栈部署的应用版本，为0时使用应用当前版本
+	AppVersionId uint `json:"app_version_id"`
+	// DependsOn 依赖的应用id，依赖的应用运行正常后才会部署该应用
+	DependsOn []uint `json:"depends_on"`
+	// ReadyTimeout 部署后等待应用运行正常的超时时间，单位秒，为0时使用默认超时时间
+	ReadyTimeout int `json:"ready_timeout"`
+}
+
+type AppStackApps []*AppStackApp
+
+func (a *AppStackApps) Scan(value interface{}) error {
+	return db.Scan(value, a)
+}
+
+func (a AppStackApps) Value() (driver.Value, error) {
+	return db.Value(a)
+}
+
+// Contains 应用是否属于应用栈
+func (a AppStackApps) Contains(appId uint) bool {
+	for _, app := range a {
+		if app.AppId == appId {
+			return true
+		}
+	}
+	return false
+}
@@ -18,16 +18,31 @@ const (
 	AuditOperationClone   = "克隆"
 	AuditOperationRelease = "发布"
 	AuditOperationImport  = "导入"
+	// AuditOperationRollback 应用回滚到历史版本
+	AuditOperationRollback = "回滚"
+	// AuditOperationSync 手动触发同步
+	AuditOperationSync = "同步"
+	// AuditOperationPush 推送应用版本到oci仓库
+	AuditOperationPush = "推送"
+	// AuditOperationPromote 应用版本晋级到其他工作空间
+	AuditOperationPromote = "晋级"
+	AuditOperationApprove = "审批通过"
+	AuditOperationReject  = "审批拒绝"
+	// AuditOperationResync 使用最近一次安装记录重新部署应用，修复资源漂移
+	AuditOperationResync = "重新同步"
 )
 const (
 	AuditResourceApp        = "应用"
 	AuditResourceAppVersion = "应用版本"
+	AuditResourceAppStack   = "应用栈"
 	AuditResourceProject    = "工作空间"
 
-	AuditResourceAppStore = "应用商店"
+	AuditResourceAppStore       = "应用商店"
+	AuditResourceAppStoreSource = "应用商店源"
 
 	AuditResourceCluster          = "集群"
 	AuditResourceClusterComponent = "集群组件"
+	AuditResourceClusterGroup     = "集群组"
 
 	AuditResourcePipeSpace        = "流水线空间"
 	AuditResourcePipeline         = "流水线"
@@ -40,6 +55,9 @@ const (
 	AuditResourcePlatformUser     = "用户"
 
 	AuditResourcePermission = "权限"
+
+	AuditResourceNotificationChannel = "通知渠道"
+	AuditResourceNotificationRule    = "通知规则"
 )
 
 // AuditOperate 操作审计
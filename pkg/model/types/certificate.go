@@ -0,0 +1,22 @@
+package types
+
+import "time"
+
+const (
+	// CertificateAuthoritySpacelet kubespace与spacelet之间双向tls认证的CA
+	CertificateAuthoritySpacelet = "spacelet"
+)
+
+// CertificateAuthority kubespace作为CA签发证书，CA证书以及私钥保存在数据库，多个实例共享
+// 配置了加密密钥时私钥加密保存
+type CertificateAuthority struct {
+	ID         uint      `gorm:"primaryKey" json:"id"`
+	Name       string    `gorm:"size:255;not null;uniqueIndex;comment:CA用途" json:"name"`
+	Cert       string    `gorm:"type:text;not null;comment:CA证书" json:"cert"`
+	Key        string    `gorm:"type:text;not null;comment:CA私钥" json:"-"`
+	CreateTime time.Time `gorm:"column:create_time;not null;autoCreateTime" json:"create_time"`
+}
+
+func (c CertificateAuthority) TableName() string {
+	return "certificate_authority"
+}
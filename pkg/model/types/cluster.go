@@ -1,6 +1,11 @@
 package types
 
-import "time"
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"github.com/kubespace/kubespace/pkg/core/db"
+	"time"
+)
 
 const (
 	ClusterFailed  = "Failed"
@@ -8,6 +13,32 @@ const (
 	ClusterConnect = "Connect"
 )
 
+const (
+	// ClusterAgentSessionTTL agent会话凭证有效期，agent在有效期过半时轮换
+	ClusterAgentSessionTTL = time.Hour * 24
+)
+
+// 集群健康检查状态
+const (
+	ClusterHealthHealthy  = "Healthy"
+	ClusterHealthWarning  = "Warning"
+	ClusterHealthCritical = "Critical"
+	// ClusterHealthUnknown 集群无法连接或者检查项无法获取
+	ClusterHealthUnknown = "Unknown"
+)
+
+const (
+	// ClusterHealthCheckInterval 集群健康检查间隔
+	ClusterHealthCheckInterval = time.Minute * 5
+	// ClusterHealthRetention 健康检查历史保留时间
+	ClusterHealthRetention = time.Hour * 24 * 7
+)
+
+const (
+	// ClusterEventRetention 归档的集群事件保留时间，按事件最后发生时间清理
+	ClusterEventRetention = time.Hour * 24 * 30
+)
+
 type ClusterStore struct {
 	Common
 
@@ -29,4 +60,273 @@ type Cluster struct {
 	Members    []string  `gorm:"-" json:"members"`
 	CreateTime time.Time `gorm:"column:create_time;not null;autoCreateTime" json:"create_time"`
 	UpdateTime time.Time `gorm:"column:update_time;not null;autoUpdateTime" json:"update_time"`
+
+	// Impersonation 用户访问集群时，以映射的k8s用户以及用户组身份访问，为空时使用导入集群的身份
+	Impersonation *ClusterImpersonation `gorm:"type:json" json:"impersonation"`
+	// AgentProfile agent安装的权限配置，为空时agent绑定cluster-admin
+	AgentProfile *ClusterAgentProfile `gorm:"type:json" json:"agent_profile"`
+
+	// AgentSessionToken agent通过导入token换取的短期会话凭证，用于建立隧道
+	AgentSessionToken     string     `gorm:"size:255;not null;default:''" json:"-"`
+	AgentPrevSessionToken string     `gorm:"size:255;not null;default:'';comment:轮换前的会话凭证，有效期内仍可使用" json:"-"`
+	AgentSessionTime      *time.Time `gorm:"comment:会话凭证生成时间" json:"-"`
+	AgentVersion          string     `gorm:"size:255;not null;default:'';comment:agent版本" json:"agent_version"`
+	AgentLastSeen         *time.Time `gorm:"comment:最近一次与agent通信的时间" json:"agent_last_seen"`
+
+	// HealthStatus 最近一次健康检查的结果
+	HealthStatus    string     `gorm:"size:50;not null;default:''" json:"health_status"`
+	HealthCheckTime *time.Time `json:"health_check_time"`
+	// HealthThresholds 健康检查阈值，为空时使用默认阈值
+	HealthThresholds *ClusterHealthThresholds `gorm:"type:json" json:"health_thresholds"`
+
+	// Labels 集群标签，如env、region、team，集群组通过标签选择集群
+	Labels ClusterLabels `gorm:"type:json" json:"labels"`
+}
+
+func (c *Cluster) Unmarshal(bytes []byte) (interface{}, error) {
+	var cluster Cluster
+	if err := json.Unmarshal(bytes, &cluster); err != nil {
+		return nil, err
+	}
+	return cluster, nil
+}
+
+// HealthCheckDue 是否到了下次健康检查时间
+func (c *Cluster) HealthCheckDue() bool {
+	return c.HealthCheckTime == nil || time.Since(*c.HealthCheckTime) >= ClusterHealthCheckInterval
+}
+
+// Thresholds 集群健康检查阈值，没有设置的阈值使用默认值
+func (c *Cluster) Thresholds() *ClusterHealthThresholds {
+	t := DefaultClusterHealthThresholds()
+	if c.HealthThresholds == nil {
+		return t
+	}
+	custom := c.HealthThresholds
+	if custom.ApiserverLatencyWarning > 0 {
+		t.ApiserverLatencyWarning = custom.ApiserverLatencyWarning
+	}
+	if custom.ApiserverLatencyCritical > 0 {
+		t.ApiserverLatencyCritical = custom.ApiserverLatencyCritical
+	}
+	if custom.NotReadyNodesWarning > 0 {
+		t.NotReadyNodesWarning = custom.NotReadyNodesWarning
+	}
+	if custom.NotReadyNodesCritical > 0 {
+		t.NotReadyNodesCritical = custom.NotReadyNodesCritical
+	}
+	if custom.FailingPodsWarning > 0 {
+		t.FailingPodsWarning = custom.FailingPodsWarning
+	}
+	if custom.FailingPodsCritical > 0 {
+		t.FailingPodsCritical = custom.FailingPodsCritical
+	}
+	if custom.WarningEventsWarning > 0 {
+		t.WarningEventsWarning = custom.WarningEventsWarning
+	}
+	if custom.WarningEventsCritical > 0 {
+		t.WarningEventsCritical = custom.WarningEventsCritical
+	}
+	if custom.CertExpiryWarningDays > 0 {
+		t.CertExpiryWarningDays = custom.CertExpiryWarningDays
+	}
+	if custom.CertExpiryCriticalDays > 0 {
+		t.CertExpiryCriticalDays = custom.CertExpiryCriticalDays
+	}
+	return t
+}
+
+// ValidAgentSession 会话凭证是否为有效期内的当前或者轮换前的凭证
+func (c *Cluster) ValidAgentSession(token string) bool {
+	if token == "" || c.AgentSessionTime == nil || time.Since(*c.AgentSessionTime) > ClusterAgentSessionTTL {
+		return false
+	}
+	return token == c.AgentSessionToken || token == c.AgentPrevSessionToken
+}
+
+// AgentSupportImpersonate 当前连接的agent是否支持用户模拟，agent从上报版本开始支持用户模拟，
+// 未上报版本的旧版本agent会忽略用户模拟参数，以agent自身的身份访问集群
+func (c *Cluster) AgentSupportImpersonate() bool {
+	return c.AgentVersion != ""
+}
+
+// ClusterAgentConnection agent隧道的连接记录
+type ClusterAgentConnection struct {
+	ID             uint       `gorm:"primaryKey" json:"id"`
+	ClusterId      uint       `gorm:"not null;index" json:"cluster_id"`
+	AgentVersion   string     `gorm:"size:255;not null;default:''" json:"agent_version"`
+	RemoteAddr     string     `gorm:"size:255;not null;default:''" json:"remote_addr"`
+	ConnectTime    time.Time  `gorm:"not null" json:"connect_time"`
+	DisconnectTime *time.Time `json:"disconnect_time"`
+	// Message 断开连接的原因
+	Message string `gorm:"type:text" json:"message"`
+}
+
+// ClusterImpersonation kubespace用户到k8s用户以及用户组的映射，开启后集群的RBAC、准入以及审计日志都作用于实际的用户
+type ClusterImpersonation struct {
+	Enabled bool `json:"enabled"`
+	// UserPrefix k8s用户名前缀，k8s用户名为前缀加上kubespace用户名，如kubespace:admin
+	UserPrefix string `json:"user_prefix"`
+	// Groups 所有用户都会加入的k8s用户组
+	Groups []string `json:"groups"`
+	// RoleGroups 用户在该集群的角色（admin/editor/viewer）对应的k8s用户组
+	RoleGroups map[string][]string `json:"role_groups"`
+	// UserGroups 指定kubespace用户对应的k8s用户组
+	UserGroups map[string][]string `json:"user_groups"`
+}
+
+func (i *ClusterImpersonation) Scan(value interface{}) error {
+	return db.Scan(value, i)
+}
+
+// Value return json value, implement driver.Valuer interface
+func (i ClusterImpersonation) Value() (driver.Value, error) {
+	return db.Value(i)
+}
+
+// ClusterAgentProfile agent安装时的权限配置，admin/namespace/readonly，导入集群时生成对应的ClusterRole/Role
+type ClusterAgentProfile struct {
+	Name string `json:"name"`
+	// Namespaces namespace权限时agent可以操作的命名空间，一般为工作空间绑定的命名空间
+	Namespaces []string `json:"namespaces"`
+}
+
+func (p *ClusterAgentProfile) Scan(value interface{}) error {
+	return db.Scan(value, p)
+}
+
+// Value return json value, implement driver.Valuer interface
+func (p ClusterAgentProfile) Value() (driver.Value, error) {
+	return db.Value(p)
+}
+
+// ClusterHealthThresholds 集群健康检查阈值，达到warning阈值时为Warning状态，达到critical阈值时为Critical状态
+type ClusterHealthThresholds struct {
+	// ApiserverLatencyWarning apiserver请求耗时，单位毫秒
+	ApiserverLatencyWarning  int64 `json:"apiserver_latency_warning"`
+	ApiserverLatencyCritical int64 `json:"apiserver_latency_critical"`
+	// NotReadyNodesWarning 未就绪的节点数
+	NotReadyNodesWarning  int `json:"not_ready_nodes_warning"`
+	NotReadyNodesCritical int `json:"not_ready_nodes_critical"`
+	// FailingPodsWarning 异常的pod数
+	FailingPodsWarning  int `json:"failing_pods_warning"`
+	FailingPodsCritical int `json:"failing_pods_critical"`
+	// WarningEventsWarning 每小时的warning事件数
+	WarningEventsWarning  int `json:"warning_events_warning"`
+	WarningEventsCritical int `json:"warning_events_critical"`
+	// CertExpiryWarningDays apiserver证书剩余有效天数
+	CertExpiryWarningDays  int `json:"cert_expiry_warning_days"`
+	CertExpiryCriticalDays int `json:"cert_expiry_critical_days"`
+}
+
+func DefaultClusterHealthThresholds() *ClusterHealthThresholds {
+	return &ClusterHealthThresholds{
+		ApiserverLatencyWarning:  500,
+		ApiserverLatencyCritical: 2000,
+		NotReadyNodesWarning:     1,
+		NotReadyNodesCritical:    3,
+		FailingPodsWarning:       1,
+		FailingPodsCritical:      10,
+		WarningEventsWarning:     50,
+		WarningEventsCritical:    200,
+		CertExpiryWarningDays:    30,
+		CertExpiryCriticalDays:   7,
+	}
+}
+
+func (t *ClusterHealthThresholds) Scan(value interface{}) error {
+	return db.Scan(value, t)
+}
+
+// Value return json value, implement driver.Valuer interface
+func (t ClusterHealthThresholds) Value() (driver.Value, error) {
+	return db.Value(t)
+}
+
+// ClusterHealth 集群健康检查记录
+type ClusterHealth struct {
+	ID        uint                `gorm:"primaryKey" json:"id"`
+	ClusterId uint                `gorm:"not null;index:ClusterHealthTime" json:"cluster_id"`
+	Status    string              `gorm:"size:50;not null" json:"status"`
+	Version   string              `gorm:"size:255;not null;default:''" json:"version"`
+	Checks    ClusterHealthChecks `gorm:"type:json" json:"checks"`
+	CheckTime time.Time           `gorm:"not null;index:ClusterHealthTime" json:"check_time"`
+}
+
+// ClusterHealthCheck 集群健康检查项的结果
+type ClusterHealthCheck struct {
+	// Name 检查项：apiserver/nodes/components/coredns/pods/events/certificate
+	Name    string `json:"name"`
+	Status  string `json:"status"`
+	Message string `json:"message"`
+	// Value 检查项的数值，与阈值比较，如apiserver耗时、异常pod数
+	Value    float64 `json:"value"`
+	Warning  float64 `json:"warning"`
+	Critical float64 `json:"critical"`
+	// Details 检查项的明细，如各命名空间异常的pod
+	Details interface{} `json:"details,omitempty"`
+}
+
+type ClusterHealthChecks []*ClusterHealthCheck
+
+func (c *ClusterHealthChecks) Scan(value interface{}) error {
+	return db.Scan(value, c)
+}
+
+func (c ClusterHealthChecks) Value() (driver.Value, error) {
+	return db.Value(c)
+}
+
+// ClusterEvent 归档的集群kubernetes事件，同一个事件多次发生时更新次数以及最后发生时间
+type ClusterEvent struct {
+	ID        uint   `gorm:"primaryKey" json:"id"`
+	ClusterId uint   `gorm:"not null;uniqueIndex:ClusterEventUid;index:ClusterEventTime" json:"cluster_id"`
+	Uid       string `gorm:"size:64;not null;uniqueIndex:ClusterEventUid" json:"uid"`
+	Name      string `gorm:"size:255;not null" json:"name"`
+	Namespace string `gorm:"size:255;not null;default:'';index:ClusterEventTime" json:"namespace"`
+	// ObjectKind/ObjectName 事件关联的资源
+	ObjectKind string `gorm:"size:255;not null;default:''" json:"object_kind"`
+	ObjectName string `gorm:"size:255;not null;default:''" json:"object_name"`
+	ObjectUid  string `gorm:"size:64;not null;default:''" json:"object_uid"`
+	Reason     string `gorm:"size:255;not null;default:''" json:"reason"`
+	// Type Normal/Warning
+	Type       string    `gorm:"size:50;not null;default:''" json:"type"`
+	Message    string    `gorm:"type:text" json:"message"`
+	Source     string    `gorm:"size:255;not null;default:'';comment:产生事件的组件以及节点" json:"source"`
+	Count      int32     `gorm:"not null;default:1" json:"count"`
+	FirstTime  time.Time `gorm:"not null" json:"first_time"`
+	LastTime   time.Time `gorm:"not null;index:ClusterEventTime" json:"last_time"`
+	CreateTime time.Time `gorm:"column:create_time;not null;autoCreateTime" json:"create_time"`
+	UpdateTime time.Time `gorm:"column:update_time;not null;autoUpdateTime" json:"update_time"`
+}
+
+func (e ClusterEvent) TableName() string {
+	return "cluster_events"
+}
+
+type ClusterLabels map[string]string
+
+func (l *ClusterLabels) Scan(value interface{}) error {
+	return db.Scan(value, l)
+}
+
+func (l ClusterLabels) Value() (driver.Value, error) {
+	return db.Value(l)
+}
+
+// ClusterGroup 集群组，通过标签选择器动态包含集群，集群组的用户角色对组内所有集群生效
+type ClusterGroup struct {
+	ID          uint   `gorm:"primaryKey" json:"id"`
+	Name        string `gorm:"size:255;not null;uniqueIndex" json:"name"`
+	Description string `gorm:"size:1000;not null;default:''" json:"description"`
+	// Selector kubernetes标签选择器格式，如env=prod,region in (bj,sh)
+	Selector   string    `gorm:"size:1000;not null" json:"selector"`
+	CreateUser string    `gorm:"size:255;not null" json:"create_user"`
+	UpdateUser string    `gorm:"size:255;not null" json:"update_user"`
+	CreateTime time.Time `gorm:"column:create_time;not null;autoCreateTime" json:"create_time"`
+	UpdateTime time.Time `gorm:"column:update_time;not null;autoUpdateTime" json:"update_time"`
+}
+
+func (g ClusterGroup) TableName() string {
+	return "cluster_groups"
 }
@@ -0,0 +1,162 @@
+package types
+
+import (
+	"database/sql/driver"
+	"github.com/kubespace/kubespace/pkg/core/db"
+	"time"
+)
+
+// 通知渠道类型
+const (
+	NotificationChannelWebhook  = "webhook"
+	NotificationChannelEmail    = "email"
+	NotificationChannelDingTalk = "dingtalk"
+	NotificationChannelWeCom    = "wecom"
+	NotificationChannelFeishu   = "feishu"
+	NotificationChannelSlack    = "slack"
+)
+
+// 可以订阅的平台事件
+const (
+	NotificationEventPipelineRunFailed    = "pipeline_run_failed"
+	NotificationEventPipelineRunSucceeded = "pipeline_run_succeeded"
+	// NotificationEventPipelineStageManual 流水线阶段等待手动触发
+	NotificationEventPipelineStageManual = "pipeline_stage_manual"
+	// NotificationEventAppRunningFault 应用运行状态变为RunningFault
+	NotificationEventAppRunningFault = "app_running_fault"
+	// NotificationEventSpaceletOffline spacelet节点不在线，只能由平台范围的规则订阅
+	NotificationEventSpaceletOffline = "spacelet_offline"
+	// NotificationEventClusterAgentDisconnected 集群agent断开连接且没有重新连接
+	NotificationEventClusterAgentDisconnected = "cluster_agent_disconnected"
+)
+
+// NotificationEventScopes 事件所属的范围，规则只能订阅其范围内的事件，平台范围的规则可以订阅所有事件
+var NotificationEventScopes = map[string][]string{
+	NotificationEventPipelineRunFailed:    {ScopePipeline},
+	NotificationEventPipelineRunSucceeded: {ScopePipeline},
+	NotificationEventPipelineStageManual:  {ScopePipeline},
+	// 工作空间应用以及集群组件
+	NotificationEventAppRunningFault:          {ScopeProject, ScopeCluster},
+	NotificationEventSpaceletOffline:          {ScopePlatform},
+	NotificationEventClusterAgentDisconnected: {ScopeCluster},
+}
+
+const (
+	NotificationDeliverySuccess = "success"
+	NotificationDeliveryFailed  = "failed"
+
+	// NotificationDeliveryRetention 通知发送记录保留时间
+	NotificationDeliveryRetention = time.Hour * 24 * 30
+)
+
+// NotificationChannel 通知渠道，如webhook、邮件以及各IM机器人
+type NotificationChannel struct {
+	ID     uint                       `gorm:"primaryKey" json:"id"`
+	Name   string                     `gorm:"size:255;not null;uniqueIndex" json:"name"`
+	Type   string                     `gorm:"size:50;not null" json:"type"`
+	Config *NotificationChannelConfig `gorm:"type:json" json:"config"`
+	// Secret 机器人加签密钥、邮箱密码或者webhook的Authorization头
+	Secret     string    `gorm:"size:2000;not null;default:''" json:"-"`
+	CreateUser string    `gorm:"size:255;not null" json:"create_user"`
+	UpdateUser string    `gorm:"size:255;not null" json:"update_user"`
+	CreateTime time.Time `gorm:"column:create_time;not null;autoCreateTime" json:"create_time"`
+	UpdateTime time.Time `gorm:"column:update_time;not null;autoUpdateTime" json:"update_time"`
+}
+
+func (c NotificationChannel) TableName() string {
+	return "notification_channels"
+}
+
+// NotificationChannelConfig 通知渠道配置，webhook以及机器人使用Url，邮件使用smtp配置
+type NotificationChannelConfig struct {
+	Url string `json:"url"`
+
+	SmtpHost string   `json:"smtp_host"`
+	SmtpPort int      `json:"smtp_port"`
+	Username string   `json:"username"`
+	From     string   `json:"from"`
+	To       []string `json:"to"`
+	// Tls 是否直接使用tls连接smtp服务器，否则在服务器支持时使用STARTTLS
+	Tls bool `json:"tls"`
+}
+
+func (c *NotificationChannelConfig) Scan(value interface{}) error {
+	return db.Scan(value, c)
+}
+
+func (c NotificationChannelConfig) Value() (driver.Value, error) {
+	return db.Value(c)
+}
+
+// NotificationRule 通知规则，订阅范围内的平台事件，通过渠道发送模板渲染后的消息
+type NotificationRule struct {
+	ID      uint   `gorm:"primaryKey" json:"id"`
+	Name    string `gorm:"size:255;not null" json:"name"`
+	Scope   string `gorm:"size:50;not null;index:NotificationRuleScope" json:"scope"`
+	ScopeId uint   `gorm:"not null;index:NotificationRuleScope" json:"scope_id"`
+	// Events 订阅的事件
+	Events     NotificationStrings `gorm:"type:json" json:"events"`
+	ChannelIds NotificationIds     `gorm:"type:json" json:"channel_ids"`
+	// TitleTemplate/ContentTemplate go template格式，为空时使用事件默认模板
+	TitleTemplate   string    `gorm:"size:1000;not null;default:''" json:"title_template"`
+	ContentTemplate string    `gorm:"type:text" json:"content_template"`
+	Enabled         bool      `gorm:"not null;default:true" json:"enabled"`
+	CreateUser      string    `gorm:"size:255;not null" json:"create_user"`
+	UpdateUser      string    `gorm:"size:255;not null" json:"update_user"`
+	CreateTime      time.Time `gorm:"column:create_time;not null;autoCreateTime" json:"create_time"`
+	UpdateTime      time.Time `gorm:"column:update_time;not null;autoUpdateTime" json:"update_time"`
+}
+
+func (r NotificationRule) TableName() string {
+	return "notification_rules"
+}
+
+// Subscribed 规则是否订阅了该事件
+func (r *NotificationRule) Subscribed(event string) bool {
+	for _, e := range r.Events {
+		if e == event {
+			return true
+		}
+	}
+	return false
+}
+
+type NotificationStrings []string
+
+func (s *NotificationStrings) Scan(value interface{}) error {
+	return db.Scan(value, s)
+}
+
+func (s NotificationStrings) Value() (driver.Value, error) {
+	return db.Value(s)
+}
+
+type NotificationIds []uint
+
+func (s *NotificationIds) Scan(value interface{}) error {
+	return db.Scan(value, s)
+}
+
+func (s NotificationIds) Value() (driver.Value, error) {
+	return db.Value(s)
+}
+
+// NotificationDelivery 通知发送记录
+type NotificationDelivery struct {
+	ID          uint      `gorm:"primaryKey" json:"id"`
+	RuleId      uint      `gorm:"not null;index" json:"rule_id"`
+	ChannelId   uint      `gorm:"not null" json:"channel_id"`
+	ChannelName string    `gorm:"size:255;not null;default:''" json:"channel_name"`
+	Event       string    `gorm:"size:100;not null" json:"event"`
+	Scope       string    `gorm:"size:50;not null;index:NotificationDeliveryScope" json:"scope"`
+	ScopeId     uint      `gorm:"not null;index:NotificationDeliveryScope" json:"scope_id"`
+	Title       string    `gorm:"size:1000;not null;default:''" json:"title"`
+	Content     string    `gorm:"type:text" json:"content"`
+	Status      string    `gorm:"size:50;not null" json:"status"`
+	Error       string    `gorm:"type:text" json:"error"`
+	CreateTime  time.Time `gorm:"column:create_time;not null;autoCreateTime;index" json:"create_time"`
+}
+
+func (d NotificationDelivery) TableName() string {
+	return "notification_deliveries"
+}
@@ -36,6 +36,8 @@ const (
 	PipelineStatusOK    = "ok"
 	PipelineStatusError = "error"
 	PipelineStatusPause = "pause"
+	// PipelineStatusQueued 任务超出spacelet节点容量，排队等待调度
+	PipelineStatusQueued = "queued"
 	// PipelineStatusCancel 取消中，取消完成后状态为canceled
 	PipelineStatusCancel = "cancel"
 	// PipelineStatusCanceled 取消执行完成后状态
@@ -335,11 +337,54 @@ type PipelineJob struct {
 	SchedulePolicy *PipelineJobSchedulePolicy `json:"schedule_policy,omitempty"`
 }
 
+const (
+	// PipelineJobExecutorSpacelet 任务调度到spacelet节点执行
+	PipelineJobExecutorSpacelet = "spacelet"
+	// PipelineJobExecutorKubernetes 任务在kubernetes集群中以pod方式执行
+	PipelineJobExecutorKubernetes = "kubernetes"
+)
+
 type PipelineJobSchedulePolicy struct {
+	// 任务执行方式：spacelet/kubernetes，默认spacelet
+	Executor string `json:"executor,omitempty"`
 	// 指定spacelet主机名
 	Hostname string `json:"hostname,omitempty"`
 	// 指定spacelet标签
 	SpaceletSelector map[string]string `json:"spacelet_selector,omitempty"`
+	// 在kubernetes集群中执行任务的配置
+	Kubernetes *PipelineJobKubernetesPolicy `json:"kubernetes,omitempty"`
+	// 任务资源请求，如{"cpu": "2", "memory": "4Gi"}，调度时占用spacelet节点容量
+	Requests map[string]string `json:"requests,omitempty"`
+	// 同一次构建的任务不调度到同一个spacelet节点
+	AntiAffinity bool `json:"anti_affinity,omitempty"`
+	// 任务可以重复执行，spacelet节点失联时重新调度到其它节点执行
+	Idempotent bool `json:"idempotent,omitempty"`
+}
+
+// IsKubernetes 任务是否在kubernetes集群中以pod方式执行
+func (pj *PipelineJobSchedulePolicy) IsKubernetes() bool {
+	return pj != nil && pj.Executor == PipelineJobExecutorKubernetes
+}
+
+// PipelineJobKubernetesPolicy 任务在kubernetes集群中以pod方式执行的配置
+type PipelineJobKubernetesPolicy struct {
+	// 执行任务的集群
+	Cluster string `json:"cluster"`
+	// 执行任务的命名空间
+	Namespace string `json:"namespace"`
+	// 执行任务的镜像，需包含spacelet可执行文件，默认为spacelet镜像
+	Image string `json:"image,omitempty"`
+	// 任务中调用kubespace接口的地址，默认http://kubespace
+	ServerUrl string `json:"server_url,omitempty"`
+	// pod调度的节点标签
+	NodeSelector map[string]string `json:"node_selector,omitempty"`
+	// 资源请求，如{"cpu": "500m", "memory": "1Gi"}
+	Requests map[string]string `json:"requests,omitempty"`
+	// 资源限制
+	Limits map[string]string `json:"limits,omitempty"`
+	// 任务工作目录使用的pvc，同一次流水线构建的任务共享pvc中的构建目录，
+	// 任务pod可能调度到不同节点，pvc需要支持多节点读写（ReadWriteMany）
+	WorkspaceClaim string `json:"workspace_claim"`
 }
 
 func (pj *PipelineJobSchedulePolicy) Scan(value interface{}) error {
@@ -351,6 +396,18 @@ func (pj PipelineJobSchedulePolicy) Value() (driver.Value, error) {
 	return db.Value(pj)
 }
 
+// PipelineJobMaxReschedules spacelet节点失联后任务最多重新调度的次数
+const PipelineJobMaxReschedules = 3
+
+// IsIdempotentJob 任务是否可以在spacelet节点失联后重新调度执行
+// 代码构建镜像任务重复执行结果相同，默认可以重新调度，其它任务需要在调度策略中配置
+func IsIdempotentJob(pluginKey string, policy *PipelineJobSchedulePolicy) bool {
+	if pluginKey == BuiltinPluginBuildCodeToImage {
+		return true
+	}
+	return policy != nil && policy.Idempotent
+}
+
 const (
 	BuiltinPluginBuildCodeToImage = "build_code_to_image"
 	BuiltinPluginExecuteShell     = "execute_shell"
@@ -496,6 +553,9 @@ type PipelineRunJob struct {
 	Result         *utils.Response            `gorm:"type:json;comment:任务执行结果" json:"result"`
 	SpaceletId     uint                       `gorm:"comment:任务执行时的spacelet代理节点" json:"spacelet_id"`
 	SchedulePolicy *PipelineJobSchedulePolicy `gorm:"type:json;comment:任务执行时调度到spacelet策略" json:"schedule_policy"`
+	QueuePosition  int                        `gorm:"not null;default:0;comment:任务排队位置，0表示未排队" json:"queue_position"`
+	QueueTime      *time.Time                 `gorm:"comment:任务开始排队的时间，调度后清空" json:"queue_time"`
+	Reschedules    int                        `gorm:"not null;default:0;comment:spacelet节点失联后重新调度的次数" json:"reschedules"`
 	CreateTime     time.Time                  `gorm:"not null;autoCreateTime" json:"create_time"`
 	UpdateTime     time.Time                  `gorm:"not null;autoUpdateTime" json:"update_time"`
 }
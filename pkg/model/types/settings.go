@@ -1,6 +1,8 @@
 package types
 
 import (
+	"database/sql/driver"
+	"github.com/kubespace/kubespace/pkg/core/db"
 	"time"
 )
 
@@ -32,6 +34,34 @@ type SettingsSecret struct {
 	UpdateUser  string    `gorm:"size:255;not null" json:"update_user"`
 	CreateTime  time.Time `gorm:"not null;autoCreateTime" json:"create_time"`
 	UpdateTime  time.Time `gorm:"not null;autoUpdateTime" json:"update_time"`
+
+	// Scopes 允许在应用values以及应用git源中引用该密钥的工作空间/集群，为空时不允许引用
+	Scopes SettingsSecretScopes `gorm:"type:json" json:"scopes"`
+}
+
+// AllowScope 密钥是否允许在指定工作空间/集群中引用，scope为platform时允许所有工作空间以及集群引用
+func (s *SettingsSecret) AllowScope(scope string, scopeId uint) bool {
+	for _, allow := range s.Scopes {
+		if allow.Scope == ScopePlatform || (allow.Scope == scope && allow.ScopeId == scopeId) {
+			return true
+		}
+	}
+	return false
+}
+
+type SettingsSecretScope struct {
+	Scope   string `json:"scope"`
+	ScopeId uint   `json:"scope_id"`
+}
+
+type SettingsSecretScopes []SettingsSecretScope
+
+func (s *SettingsSecretScopes) Scan(value interface{}) error {
+	return db.Scan(value, s)
+}
+
+func (s SettingsSecretScopes) Value() (driver.Value, error) {
+	return db.Value(s)
 }
 
 func (s *SettingsSecret) GetSecret() *Secret {
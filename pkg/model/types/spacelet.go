@@ -11,19 +11,55 @@ const (
 	SpaceletStatusOnline = "online"
 	// SpaceletStatusOffline spacelet状态不在线
 	SpaceletStatusOffline = "offline"
+
+	// SpaceletHeartbeatInterval spacelet上报心跳的间隔
+	SpaceletHeartbeatInterval = time.Second * 10
+	// SpaceletHeartbeatTimeout 超过该时间未收到心跳，spacelet节点为不在线
+	SpaceletHeartbeatTimeout = SpaceletHeartbeatInterval * 3
+	// SpaceletTokenRotateInterval spacelet认证token轮换间隔
+	SpaceletTokenRotateInterval = time.Hour * 24
+	// SpaceletCertValidity 签发给spacelet的证书有效期，spacelet每次启动注册时重新签发
+	SpaceletCertValidity = time.Hour * 24 * 365
+	// SpaceletBootstrapTokenTTL spacelet引导token有效期，有效期内可以用于注册多个spacelet
+	SpaceletBootstrapTokenTTL = time.Hour * 24
 )
 
 // Spacelet 流水线执行代理节点，spacelet启动时会进行注册
 type Spacelet struct {
-	ID         uint           `gorm:"primaryKey" json:"id"`
-	Hostname   string         `gorm:"size:255;not null;" json:"hostname"`
-	HostIp     string         `gorm:"size:255;not null;uniqueIndex:HostPortUnique" json:"hostip"`
-	Port       int            `gorm:"not null;uniqueIndex:HostPortUnique" json:"port"`
-	Labels     SpaceletLabels `gorm:"type:json;comment:spacelet标签" json:"labels"`
-	Token      string         `gorm:"size:255;not null;" json:"token,omitempty"`
-	Status     string         `gorm:"size:50;not null" json:"status"`
-	CreateTime time.Time      `gorm:"column:create_time;not null;autoCreateTime" json:"create_time"`
-	UpdateTime time.Time      `gorm:"column:update_time;not null;autoUpdateTime" json:"update_time"`
+	ID            uint           `gorm:"primaryKey" json:"id"`
+	Hostname      string         `gorm:"size:255;not null;" json:"hostname"`
+	HostIp        string         `gorm:"size:255;not null;uniqueIndex:HostPortUnique" json:"hostip"`
+	Port          int            `gorm:"not null;uniqueIndex:HostPortUnique" json:"port"`
+	Labels        SpaceletLabels `gorm:"type:json;comment:spacelet标签" json:"labels"`
+	MaxJobs       int            `gorm:"not null;default:0;comment:最大并发任务数，0表示不限制" json:"max_jobs"`
+	Cpu           int64          `gorm:"not null;default:0;comment:cpu容量(毫核)，0表示不限制" json:"cpu"`
+	Memory        int64          `gorm:"not null;default:0;comment:内存容量(字节)，0表示不限制" json:"memory"`
+	Token         string         `gorm:"size:255;not null;" json:"token,omitempty"`
+	PrevToken     string         `gorm:"size:255;not null;default:'';comment:轮换前的token，spacelet确认新token前仍有效" json:"-"`
+	TokenTime     *time.Time     `gorm:"comment:token生成时间" json:"-"`
+	TlsEnabled    bool           `gorm:"not null;default:false;comment:是否通过双向tls访问spacelet" json:"tls_enabled"`
+	Status        string         `gorm:"size:50;not null" json:"status"`
+	Drain         bool           `gorm:"not null;default:false;comment:排空后不再调度新任务到该节点" json:"drain"`
+	Version       string         `gorm:"size:255;not null;default:'';comment:spacelet版本" json:"version"`
+	Load          *SpaceletLoad  `gorm:"type:json;comment:心跳上报的节点负载" json:"load"`
+	HeartbeatTime *time.Time     `gorm:"column:heartbeat_time;comment:最近一次心跳时间" json:"heartbeat_time"`
+	CreateTime    time.Time      `gorm:"column:create_time;not null;autoCreateTime" json:"create_time"`
+	UpdateTime    time.Time      `gorm:"column:update_time;not null;autoUpdateTime" json:"update_time"`
+}
+
+// HeartbeatAlive 是否在超时时间内收到心跳，从未上报过心跳的spacelet返回false
+func (s *Spacelet) HeartbeatAlive() bool {
+	return s.HeartbeatTime != nil && time.Since(*s.HeartbeatTime) < SpaceletHeartbeatTimeout
+}
+
+// ValidToken token是否为当前或者轮换前的token
+func (s *Spacelet) ValidToken(token string) bool {
+	return token != "" && (token == s.Token || token == s.PrevToken)
+}
+
+// Schedulable spacelet节点是否可以调度新任务
+func (s *Spacelet) Schedulable() bool {
+	return s.Status == SpaceletStatusOnline && !s.Drain
 }
 
 type SpaceletLabels map[string]string
@@ -40,3 +76,38 @@ func (m SpaceletLabels) Value() (driver.Value, error) {
 func (s Spacelet) TableName() string {
 	return "spacelet"
 }
+
+// SpaceletBootstrapToken 管理员生成的spacelet引导token，安装spacelet时指定，未注册过的spacelet注册时需要提供
+type SpaceletBootstrapToken struct {
+	ID         uint      `gorm:"primaryKey" json:"id"`
+	Token      string    `gorm:"size:255;not null;uniqueIndex" json:"token"`
+	ExpireTime time.Time `gorm:"not null" json:"expire_time"`
+	CreateUser string    `gorm:"size:255;not null" json:"create_user"`
+	CreateTime time.Time `gorm:"column:create_time;not null;autoCreateTime" json:"create_time"`
+}
+
+// Valid 引导token是否在有效期内
+func (t *SpaceletBootstrapToken) Valid() bool {
+	return time.Now().Before(t.ExpireTime)
+}
+
+// SpaceletLoad spacelet心跳时上报的节点负载
+type SpaceletLoad struct {
+	// 最近1分钟系统平均负载
+	Load1 float64 `json:"load1"`
+	// 正在执行的任务数
+	RunningJobs int `json:"running_jobs"`
+	// 正在执行的任务id
+	JobIds []uint `json:"job_ids"`
+	// 最近执行完成且还未被清理的任务id
+	FinishedJobIds []uint `json:"finished_job_ids"`
+}
+
+func (l *SpaceletLoad) Scan(value interface{}) error {
+	return db.Scan(value, l)
+}
+
+// Value return json value, implement driver.Valuer interface
+func (l SpaceletLoad) Value() (driver.Value, error) {
+	return db.Value(l)
+}
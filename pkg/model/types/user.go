@@ -25,6 +25,8 @@ const (
 	ScopePipeline = "pipeline"
 	ScopeProject  = "project"
 	ScopeAppStore = "appstore"
+	// ScopeClusterGroup 集群组，该范围的角色对组内所有集群生效
+	ScopeClusterGroup = "cluster_group"
 
 	// RoleViewer 观察员，范围内只有查询资源权限
 	RoleViewer = "viewer"
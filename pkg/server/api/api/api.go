@@ -31,4 +31,9 @@ type Handler interface {
 	Handle(c *Context) *utils.Response
 }
 
+// BasicAuthHandler 没有登录会话时允许通过http basic认证访问的api，只用于helm命令行访问chart仓库等场景
+type BasicAuthHandler interface {
+	AllowBasicAuth() bool
+}
+
 //type Handler func(*Context) *utils.Response
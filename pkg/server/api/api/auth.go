@@ -1,12 +1,14 @@
 package api
 
 import (
+	"crypto/subtle"
 	"fmt"
 	"github.com/kubespace/kubespace/pkg/core/code"
 	"github.com/kubespace/kubespace/pkg/core/errors"
 	"github.com/kubespace/kubespace/pkg/model"
 	"github.com/kubespace/kubespace/pkg/model/types"
 	"github.com/kubespace/kubespace/pkg/server/config"
+	"github.com/kubespace/kubespace/pkg/utils"
 )
 
 const (
@@ -30,10 +32,14 @@ func NewAuth(conf *config.ServerConfig) *Auth {
 
 // Authenticate 认证，
 // a. 通过session获取用户信息
-// b. todo: 通过用户token认证
-func (a *Auth) Authenticate(c *Context) (*types.User, error) {
+// b. 没有session并且api允许时通过http basic认证，如helm命令行访问chart仓库
+// c. todo: 通过用户token认证
+func (a *Auth) Authenticate(c *Context, allowBasicAuth bool) (*types.User, error) {
 	sessionId, err := c.Cookie(SessionId)
 	if err != nil {
+		if username, password, ok := c.Request.BasicAuth(); ok && allowBasicAuth {
+			return a.basicAuthenticate(username, password)
+		}
 		return nil, errors.New(code.CookieError, fmt.Sprintf("get auth cookie session error: %v", err))
 	}
 	session, err := a.models.SessionManager.Get(sessionId)
@@ -48,6 +54,21 @@ func (a *Auth) Authenticate(c *Context) (*types.User, error) {
 	return user, nil
 }
 
+// basicAuthenticate 通过用户名密码认证
+func (a *Auth) basicAuthenticate(username, password string) (*types.User, error) {
+	if username == "" || password == "" {
+		return nil, errors.New(code.AuthError, "用户名或密码为空")
+	}
+	user, err := a.models.UserManager.GetByName(username)
+	if err != nil {
+		return nil, errors.New(code.AuthError, "用户名或密码错误")
+	}
+	if subtle.ConstantTimeCompare([]byte(utils.Encrypt(password)), []byte(user.Password)) != 1 {
+		return nil, errors.New(code.AuthError, "用户名或密码错误")
+	}
+	return user, nil
+}
+
 // Authorize 鉴权，用户是否有该perm权限
 func (a *Auth) Authorize(c *Context, perm *AuthPerm) (bool, error) {
 	ok := a.models.UserRoleManager.AuthRole(c.User, perm.Scope, perm.ScopeId, perm.Role)
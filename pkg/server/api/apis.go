@@ -4,8 +4,10 @@ import (
 	"github.com/kubespace/kubespace/pkg/server/api/api"
 	"github.com/kubespace/kubespace/pkg/server/api/apps"
 	"github.com/kubespace/kubespace/pkg/server/api/apps/appstore"
+	"github.com/kubespace/kubespace/pkg/server/api/apps/chartrepo"
 	"github.com/kubespace/kubespace/pkg/server/api/audit"
 	"github.com/kubespace/kubespace/pkg/server/api/cluster"
+	"github.com/kubespace/kubespace/pkg/server/api/notification"
 	"github.com/kubespace/kubespace/pkg/server/api/pipeline"
 	"github.com/kubespace/kubespace/pkg/server/api/project"
 	"github.com/kubespace/kubespace/pkg/server/api/settings"
@@ -16,15 +18,18 @@ import (
 
 func Apis(c *config.ServerConfig) map[string]api.ApiGroup {
 	return map[string]api.ApiGroup{
-		"user":     user.ApiGroup(c),
-		"audit":    audit.ApiGroup(c),
-		"cluster":  cluster.ApiGroup(c),
-		"pipeline": pipeline.ApiGroup(c),
-		"project":  project.ApiGroup(c),
-		"apps":     apps.ApiGroup(c),
-		"appstore": appstore.ApiGroup(c),
-		"settings": settings.ApiGroup(c),
-		"spacelet": spacelet.ApiGroup(c),
+		"user":      user.ApiGroup(c),
+		"audit":     audit.ApiGroup(c),
+		"cluster":   cluster.ApiGroup(c),
+		"pipeline":  pipeline.ApiGroup(c),
+		"project":   project.ApiGroup(c),
+		"apps":      apps.ApiGroup(c),
+		"appstore":  appstore.ApiGroup(c),
+		"chartrepo": chartrepo.ApiGroup(c),
+		"settings":  settings.ApiGroup(c),
+		"spacelet":  spacelet.ApiGroup(c),
+
+		"notification": notification.ApiGroup(c),
 	}
 }
 
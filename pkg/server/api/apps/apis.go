@@ -3,6 +3,11 @@ package apps
 import (
 	"github.com/kubespace/kubespace/pkg/server/api/api"
 	"github.com/kubespace/kubespace/pkg/server/api/apps/apps"
+	"github.com/kubespace/kubespace/pkg/server/api/apps/gitsource"
+	"github.com/kubespace/kubespace/pkg/server/api/apps/overlay"
+	"github.com/kubespace/kubespace/pkg/server/api/apps/promotion"
+	"github.com/kubespace/kubespace/pkg/server/api/apps/revision"
+	"github.com/kubespace/kubespace/pkg/server/api/apps/stack"
 	"github.com/kubespace/kubespace/pkg/server/api/apps/version"
 	"github.com/kubespace/kubespace/pkg/server/config"
 	"net/http"
@@ -29,12 +34,46 @@ func (a *apiGroup) Apis() []*api.Api {
 		api.NewApi(http.MethodPost, "/import_storeapp", apps.ImportStoreAppHandler(a.config)),
 		api.NewApi(http.MethodPost, "/import_custom_app", apps.ImportCustomAppHandler(a.config)),
 		api.NewApi(http.MethodPost, "/duplicate_app", apps.DuplicateHandler(a.config)),
+		api.NewApi(http.MethodGet, "/:id/drift", apps.DriftHandler(a.config)),
+		api.NewApi(http.MethodPost, "/resync", apps.ResyncHandler(a.config)),
 		api.NewApi(http.MethodDelete, "/:id", apps.DeleteHandler(a.config)),
 
 		api.NewApi(http.MethodGet, "/versions", version.ListHandler(a.config)),
 		api.NewApi(http.MethodGet, "/version/:id", version.GetHandler(a.config)),
 		api.NewApi(http.MethodGet, "/version/:id/chartfiles", version.ChartFilesHandler(a.config)),
+		api.NewApi(http.MethodPost, "/version/push_oci", version.PushOciHandler(a.config)),
 		api.NewApi(http.MethodDelete, "/version/:id", version.DeleteHandler(a.config)),
+
+		api.NewApi(http.MethodGet, "/:id/revisions", revision.ListHandler(a.config)),
+		api.NewApi(http.MethodGet, "/:id/revisions/diff", revision.DiffHandler(a.config)),
+		api.NewApi(http.MethodGet, "/:id/revisions/:revisionId", revision.GetHandler(a.config)),
+		api.NewApi(http.MethodPost, "/rollback", revision.RollbackHandler(a.config)),
+
+		api.NewApi(http.MethodGet, "/:id/overlays", overlay.ListHandler(a.config)),
+		api.NewApi(http.MethodPost, "/:id/overlays", overlay.SaveHandler(a.config)),
+		api.NewApi(http.MethodDelete, "/:id/overlays/:name", overlay.DeleteHandler(a.config)),
+
+		api.NewApi(http.MethodGet, "/promotions", promotion.ListHandler(a.config)),
+		api.NewApi(http.MethodPost, "/promotions", promotion.CreateHandler(a.config)),
+		api.NewApi(http.MethodPost, "/promotions/:id/approve", promotion.ApproveHandler(a.config)),
+		api.NewApi(http.MethodPost, "/promotions/:id/reject", promotion.RejectHandler(a.config)),
+		api.NewApi(http.MethodGet, "/:id/environments", promotion.EnvironmentsHandler(a.config)),
+
+		api.NewApi(http.MethodGet, "/:id/git_source", gitsource.GetHandler(a.config)),
+		api.NewApi(http.MethodPost, "/:id/git_source", gitsource.SaveHandler(a.config)),
+		api.NewApi(http.MethodDelete, "/:id/git_source", gitsource.DeleteHandler(a.config)),
+		api.NewApi(http.MethodPost, "/:id/git_source/refresh", gitsource.RefreshHandler(a.config)),
+		api.NewApi(http.MethodPost, "/:id/git_source/sync", gitsource.SyncHandler(a.config)),
+		api.NewApi(http.MethodPost, "/git_webhook/:token", gitsource.WebhookHandler(a.config)),
+
+		api.NewApi(http.MethodGet, "/stacks", stack.ListHandler(a.config)),
+		api.NewApi(http.MethodGet, "/stacks/:id", stack.GetHandler(a.config)),
+		api.NewApi(http.MethodPost, "/stacks", stack.CreateHandler(a.config)),
+		api.NewApi(http.MethodPut, "/stacks/:id", stack.UpdateHandler(a.config)),
+		api.NewApi(http.MethodDelete, "/stacks/:id", stack.DeleteHandler(a.config)),
+		api.NewApi(http.MethodPost, "/stacks/:id/install", stack.InstallHandler(a.config)),
+		api.NewApi(http.MethodPost, "/stacks/:id/upgrade", stack.UpgradeHandler(a.config)),
+		api.NewApi(http.MethodPost, "/stacks/:id/destroy", stack.DestroyHandler(a.config)),
 	}
 	return apis
 }
@@ -0,0 +1,55 @@
+package apps
+
+import (
+	"github.com/kubespace/kubespace/pkg/core/code"
+	"github.com/kubespace/kubespace/pkg/core/errors"
+	"github.com/kubespace/kubespace/pkg/model"
+	"github.com/kubespace/kubespace/pkg/model/types"
+	"github.com/kubespace/kubespace/pkg/server/api/api"
+	"github.com/kubespace/kubespace/pkg/server/config"
+	projectservice "github.com/kubespace/kubespace/pkg/service/project"
+	"github.com/kubespace/kubespace/pkg/utils"
+)
+
+type driftHandler struct {
+	models     *model.Models
+	appService *projectservice.AppService
+}
+
+// DriftHandler 立即检测应用的资源漂移，返回漂移的资源以及字段差异
+func DriftHandler(conf *config.ServerConfig) api.Handler {
+	return &driftHandler{
+		models:     conf.Models,
+		appService: conf.ServiceFactory.Project.AppService,
+	}
+}
+
+func (h *driftHandler) Auth(c *api.Context) (bool, *api.AuthPerm, error) {
+	appId, err := utils.ParseUint(c.Param("id"))
+	if err != nil {
+		return true, nil, errors.New(code.ParamsError, err)
+	}
+	app, err := h.models.AppManager.GetById(appId)
+	if err != nil {
+		return true, nil, errors.New(code.DataNotExists, err)
+	}
+	return true, &api.AuthPerm{
+		Scope:   app.Scope,
+		ScopeId: app.ScopeId,
+		Role:    types.RoleViewer,
+	}, nil
+}
+
+func (h *driftHandler) Handle(c *api.Context) *utils.Response {
+	appId, _ := utils.ParseUint(c.Param("id"))
+	app, drift, err := h.appService.DetectAppDrift(appId)
+	if err != nil {
+		return c.ResponseError(err)
+	}
+	return c.ResponseOK(map[string]interface{}{
+		"sync_status":     app.SyncStatus,
+		"sync_message":    app.SyncMessage,
+		"sync_check_time": app.SyncCheckTime,
+		"drift":           drift,
+	})
+}
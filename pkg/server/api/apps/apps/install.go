@@ -47,6 +47,10 @@ func (h *installHandler) Handle(c *api.Context) *utils.Response {
 		return c.ResponseError(errors.New(code.ParamsError, err.Error()))
 	}
 	form.User = c.User.Name
+	if form.DryRun {
+		preview, err := h.appService.PreviewInstallApp(&form)
+		return c.Response(err, preview)
+	}
 
 	app, versionApp, err := h.appService.InstallApp(&form)
 	resp := c.ResponseError(err)
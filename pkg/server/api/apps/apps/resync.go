@@ -0,0 +1,97 @@
+package apps
+
+import (
+	"fmt"
+	"github.com/gin-gonic/gin/binding"
+	"github.com/kubespace/kubespace/pkg/core/code"
+	"github.com/kubespace/kubespace/pkg/core/errors"
+	"github.com/kubespace/kubespace/pkg/model"
+	"github.com/kubespace/kubespace/pkg/model/types"
+	"github.com/kubespace/kubespace/pkg/server/api/api"
+	"github.com/kubespace/kubespace/pkg/server/config"
+	projectservice "github.com/kubespace/kubespace/pkg/service/project"
+	"github.com/kubespace/kubespace/pkg/utils"
+)
+
+type resyncHandler struct {
+	models     *model.Models
+	appService *projectservice.AppService
+}
+
+// ResyncHandler 使用最近一次安装记录重新部署应用
+func ResyncHandler(conf *config.ServerConfig) api.Handler {
+	return &resyncHandler{
+		models:     conf.Models,
+		appService: conf.ServiceFactory.Project.AppService,
+	}
+}
+
+func (h *resyncHandler) Auth(c *api.Context) (bool, *api.AuthPerm, error) {
+	var form projectservice.ResyncAppForm
+	if err := c.ShouldBindBodyWith(&form, binding.JSON); err != nil {
+		return true, nil, errors.New(code.ParamsError, err)
+	}
+	app, err := h.models.AppManager.GetById(form.AppId)
+	if err != nil {
+		return true, nil, errors.New(code.DataNotExists, err)
+	}
+	return true, &api.AuthPerm{
+		Scope:   app.Scope,
+		ScopeId: app.ScopeId,
+		Role:    types.RoleEditor,
+	}, nil
+}
+
+func (h *resyncHandler) Handle(c *api.Context) *utils.Response {
+	var form projectservice.ResyncAppForm
+	if err := c.ShouldBindBodyWith(&form, binding.JSON); err != nil {
+		return c.ResponseError(errors.New(code.ParamsError, err.Error()))
+	}
+	form.User = c.User.Name
+	app, err := h.models.AppManager.GetById(form.AppId)
+	if err != nil {
+		return c.ResponseError(errors.New(code.DataNotExists, err))
+	}
+	_, revision, err := h.appService.ResyncApp(&form)
+	resp := c.ResponseError(err)
+
+	var opDetail, opScopeName, opNamespace, opResType string
+	revisionStr := ""
+	if revision != nil {
+		revisionStr = fmt.Sprintf("#%d", revision.BuildRevision)
+	}
+	if app.Scope == types.ScopeProject {
+		projectObj, err := h.models.ProjectManager.Get(app.ScopeId)
+		if err != nil {
+			return c.ResponseError(errors.New(code.DataNotExists, fmt.Sprintf("获取应用所在工作空间失败：%s", err.Error())))
+		}
+		opNamespace = projectObj.Namespace
+		opScopeName = projectObj.Name
+		opResType = types.AuditResourceApp
+		opDetail = fmt.Sprintf("使用安装记录%s重新同步应用：%s", revisionStr, app.Name)
+	} else {
+		clusterObj, err := h.models.ClusterManager.GetById(app.ScopeId)
+		if err != nil {
+			return c.ResponseError(errors.New(code.DataNotExists, fmt.Sprintf("获取集群id=%d失败：%s", app.ScopeId, err.Error())))
+		}
+		opNamespace = app.Namespace
+		opScopeName = clusterObj.Name1
+		opResType = types.AuditResourceClusterComponent
+		opDetail = fmt.Sprintf("使用安装记录%s重新同步集群组件：%s", revisionStr, app.Name)
+	}
+	c.CreateAudit(&types.AuditOperate{
+		Operation:            types.AuditOperationResync,
+		OperateDetail:        opDetail,
+		Scope:                app.Scope,
+		ScopeId:              app.ScopeId,
+		ScopeName:            opScopeName,
+		Namespace:            opNamespace,
+		ResourceId:           app.ID,
+		ResourceType:         opResType,
+		ResourceName:         app.Name,
+		Code:                 resp.Code,
+		Message:              resp.Msg,
+		OperateDataInterface: form,
+	})
+	return resp
+}
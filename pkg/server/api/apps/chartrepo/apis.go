@@ -0,0 +1,64 @@
+package chartrepo
+
+import (
+	"github.com/kubespace/kubespace/pkg/core/code"
+	"github.com/kubespace/kubespace/pkg/core/errors"
+	"github.com/kubespace/kubespace/pkg/model/types"
+	"github.com/kubespace/kubespace/pkg/server/api/api"
+	"github.com/kubespace/kubespace/pkg/server/config"
+	"github.com/kubespace/kubespace/pkg/utils"
+	"net/http"
+)
+
+type apiGroup struct {
+	config *config.ServerConfig
+}
+
+// ApiGroup chart仓库接口，提供helm仓库标准的index.yaml以及chart包下载，支持helm命令行以及argocd通过basic认证访问
+func ApiGroup(conf *config.ServerConfig) api.ApiGroup {
+	return &apiGroup{conf}
+}
+
+func (a *apiGroup) Apis() []*api.Api {
+	apis := []*api.Api{
+		api.NewApi(http.MethodGet, "/project/:projectId/index.yaml", IndexHandler(a.config)),
+		api.NewApi(http.MethodGet, "/project/:projectId/charts/:versionId/:filename", DownloadHandler(a.config)),
+		api.NewApi(http.MethodGet, "/appstore/index.yaml", IndexHandler(a.config)),
+		api.NewApi(http.MethodGet, "/appstore/charts/:versionId/:filename", DownloadHandler(a.config)),
+	}
+	return apis
+}
+
+// repoScope 根据请求路径获取chart仓库所属范围，工作空间仓库路径中包括工作空间id
+func repoScope(c *api.Context) (string, uint, error) {
+	if c.Param("projectId") == "" {
+		return types.ScopeAppStore, 0, nil
+	}
+	projectId, err := utils.ParseUint(c.Param("projectId"))
+	if err != nil {
+		return "", 0, errors.New(code.ParamsError, err)
+	}
+	return types.ScopeProject, projectId, nil
+}
+
+// repoAuth 工作空间仓库需要工作空间的查看权限，应用商店仓库登录用户均可访问
+func repoAuth(c *api.Context) (bool, *api.AuthPerm, error) {
+	scope, scopeId, err := repoScope(c)
+	if err != nil {
+		return true, nil, err
+	}
+	if scope == types.ScopeAppStore {
+		return true, nil, nil
+	}
+	return true, &api.AuthPerm{
+		Scope:   scope,
+		ScopeId: scopeId,
+		Role:    types.RoleViewer,
+	}, nil
+}
+
+// responseError chart仓库客户端根据http状态码判断请求结果，出错时返回非200状态码
+func responseError(c *api.Context, status int, err error) *utils.Response {
+	c.JSON(status, c.ResponseError(err))
+	return nil
+}
@@ -0,0 +1,52 @@
+package chartrepo
+
+import (
+	"fmt"
+	"github.com/kubespace/kubespace/pkg/core/code"
+	"github.com/kubespace/kubespace/pkg/core/errors"
+	"github.com/kubespace/kubespace/pkg/server/api/api"
+	"github.com/kubespace/kubespace/pkg/server/config"
+	projectservice "github.com/kubespace/kubespace/pkg/service/project"
+	"github.com/kubespace/kubespace/pkg/utils"
+	"net/http"
+)
+
+type downloadHandler struct {
+	appService *projectservice.AppService
+}
+
+func DownloadHandler(conf *config.ServerConfig) api.Handler {
+	return &downloadHandler{
+		appService: conf.ServiceFactory.Project.AppService,
+	}
+}
+
+func (h *downloadHandler) Auth(c *api.Context) (bool, *api.AuthPerm, error) {
+	return repoAuth(c)
+}
+
+func (h *downloadHandler) AllowBasicAuth() bool {
+	return true
+}
+
+func (h *downloadHandler) Handle(c *api.Context) *utils.Response {
+	scope, scopeId, err := repoScope(c)
+	if err != nil {
+		return responseError(c, http.StatusBadRequest, err)
+	}
+	versionId, err := utils.ParseUint(c.Param("versionId"))
+	if err != nil {
+		return responseError(c, http.StatusBadRequest, errors.New(code.ParamsError, err))
+	}
+	appVersion, content, err := h.appService.GetChartRepoChart(scope, scopeId, versionId)
+	if err != nil {
+		return responseError(c, http.StatusNotFound, err)
+	}
+	filename := fmt.Sprintf("%s-%s.tgz", appVersion.PackageName, appVersion.PackageVersion)
+	if c.Param("filename") != filename {
+		return responseError(c, http.StatusNotFound, errors.New(code.DataNotExists, "chart仓库中不存在该chart包："+c.Param("filename")))
+	}
+	c.Header("Content-Disposition", "attachment;filename=\""+filename+"\"")
+	c.Data(http.StatusOK, "application/x-tar", content)
+	return nil
+}
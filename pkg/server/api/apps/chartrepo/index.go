@@ -0,0 +1,40 @@
+package chartrepo
+
+import (
+	"github.com/kubespace/kubespace/pkg/server/api/api"
+	"github.com/kubespace/kubespace/pkg/server/config"
+	projectservice "github.com/kubespace/kubespace/pkg/service/project"
+	"github.com/kubespace/kubespace/pkg/utils"
+	"net/http"
+)
+
+type indexHandler struct {
+	appService *projectservice.AppService
+}
+
+func IndexHandler(conf *config.ServerConfig) api.Handler {
+	return &indexHandler{
+		appService: conf.ServiceFactory.Project.AppService,
+	}
+}
+
+func (h *indexHandler) Auth(c *api.Context) (bool, *api.AuthPerm, error) {
+	return repoAuth(c)
+}
+
+func (h *indexHandler) AllowBasicAuth() bool {
+	return true
+}
+
+func (h *indexHandler) Handle(c *api.Context) *utils.Response {
+	scope, scopeId, err := repoScope(c)
+	if err != nil {
+		return responseError(c, http.StatusBadRequest, err)
+	}
+	index, err := h.appService.ChartRepoIndex(scope, scopeId)
+	if err != nil {
+		return responseError(c, http.StatusInternalServerError, err)
+	}
+	c.Data(http.StatusOK, "application/x-yaml", index)
+	return nil
+}
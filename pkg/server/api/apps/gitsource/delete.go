@@ -0,0 +1,59 @@
+package gitsource
+
+import (
+	"fmt"
+	"github.com/kubespace/kubespace/pkg/core/code"
+	"github.com/kubespace/kubespace/pkg/core/errors"
+	"github.com/kubespace/kubespace/pkg/model"
+	"github.com/kubespace/kubespace/pkg/model/types"
+	"github.com/kubespace/kubespace/pkg/server/api/api"
+	"github.com/kubespace/kubespace/pkg/server/config"
+	projectservice "github.com/kubespace/kubespace/pkg/service/project"
+	"github.com/kubespace/kubespace/pkg/utils"
+)
+
+type deleteHandler struct {
+	models     *model.Models
+	appService *projectservice.AppService
+}
+
+// DeleteHandler 删除应用的git来源配置，已生成的应用版本不会删除
+func DeleteHandler(conf *config.ServerConfig) api.Handler {
+	return &deleteHandler{
+		models:     conf.Models,
+		appService: conf.ServiceFactory.Project.AppService,
+	}
+}
+
+func (h *deleteHandler) Auth(c *api.Context) (bool, *api.AuthPerm, error) {
+	return appAuth(c, h.models, types.RoleEditor)
+}
+
+func (h *deleteHandler) Handle(c *api.Context) *utils.Response {
+	appId, _ := utils.ParseUint(c.Param("id"))
+	app, err := h.models.AppManager.GetById(appId)
+	if err != nil {
+		return c.ResponseError(errors.New(code.DataNotExists, err))
+	}
+	resp := c.ResponseError(h.appService.DeleteGitSource(appId))
+
+	opScope, opScopeId, opScopeName, opNamespace, err := auditScope(h.models, app)
+	if err != nil {
+		return c.ResponseError(err)
+	}
+	c.CreateAudit(&types.AuditOperate{
+		Operation:            types.AuditOperationDelete,
+		OperateDetail:        fmt.Sprintf("删除应用%s的git来源", app.Name),
+		Scope:                opScope,
+		ScopeId:              opScopeId,
+		ScopeName:            opScopeName,
+		Namespace:            opNamespace,
+		ResourceId:           app.ID,
+		ResourceType:         types.AuditResourceApp,
+		ResourceName:         app.Name,
+		Code:                 resp.Code,
+		Message:              resp.Msg,
+		OperateDataInterface: nil,
+	})
+	return resp
+}
@@ -0,0 +1,71 @@
+package gitsource
+
+import (
+	"github.com/kubespace/kubespace/pkg/core/code"
+	"github.com/kubespace/kubespace/pkg/core/errors"
+	"github.com/kubespace/kubespace/pkg/model"
+	"github.com/kubespace/kubespace/pkg/model/types"
+	"github.com/kubespace/kubespace/pkg/server/api/api"
+	"github.com/kubespace/kubespace/pkg/server/config"
+	projectservice "github.com/kubespace/kubespace/pkg/service/project"
+	"github.com/kubespace/kubespace/pkg/utils"
+)
+
+type getHandler struct {
+	models     *model.Models
+	appService *projectservice.AppService
+}
+
+// GetHandler 获取应用的git来源配置以及最近一次同步状态，没有配置时返回空
+func GetHandler(conf *config.ServerConfig) api.Handler {
+	return &getHandler{
+		models:     conf.Models,
+		appService: conf.ServiceFactory.Project.AppService,
+	}
+}
+
+func (h *getHandler) Auth(c *api.Context) (bool, *api.AuthPerm, error) {
+	return appAuth(c, h.models, types.RoleViewer)
+}
+
+func (h *getHandler) Handle(c *api.Context) *utils.Response {
+	appId, _ := utils.ParseUint(c.Param("id"))
+	source, err := h.appService.GetGitSource(appId)
+	if err != nil {
+		return c.ResponseError(err)
+	}
+	return c.ResponseOK(source)
+}
+
+// appAuth 根据路径中的应用id获取应用所属范围进行鉴权
+func appAuth(c *api.Context, models *model.Models, role string) (bool, *api.AuthPerm, error) {
+	appId, err := utils.ParseUint(c.Param("id"))
+	if err != nil {
+		return true, nil, errors.New(code.ParamsError, err)
+	}
+	app, err := models.AppManager.GetById(appId)
+	if err != nil {
+		return true, nil, errors.New(code.DataNotExists, err)
+	}
+	return true, &api.AuthPerm{
+		Scope:   app.Scope,
+		ScopeId: app.ScopeId,
+		Role:    role,
+	}, nil
+}
+
+// auditScope 获取应用审计记录的范围信息，工作空间应用为工作空间，集群组件为集群
+func auditScope(models *model.Models, app *types.App) (scope string, scopeId uint, scopeName, namespace string, err error) {
+	if app.Scope == types.ScopeProject {
+		projectObj, err := models.ProjectManager.Get(app.ScopeId)
+		if err != nil {
+			return "", 0, "", "", errors.New(code.DataNotExists, err)
+		}
+		return types.ScopeProject, projectObj.ID, projectObj.Name, projectObj.Namespace, nil
+	}
+	clusterObj, err := models.ClusterManager.GetById(app.ScopeId)
+	if err != nil {
+		return "", 0, "", "", errors.New(code.DataNotExists, err)
+	}
+	return types.ScopeCluster, clusterObj.ID, clusterObj.Name1, app.Namespace, nil
+}
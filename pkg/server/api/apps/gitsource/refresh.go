@@ -0,0 +1,33 @@
+package gitsource
+
+import (
+	"github.com/kubespace/kubespace/pkg/model"
+	"github.com/kubespace/kubespace/pkg/model/types"
+	"github.com/kubespace/kubespace/pkg/server/api/api"
+	"github.com/kubespace/kubespace/pkg/server/config"
+	projectservice "github.com/kubespace/kubespace/pkg/service/project"
+	"github.com/kubespace/kubespace/pkg/utils"
+)
+
+type refreshHandler struct {
+	models     *model.Models
+	appService *projectservice.AppService
+}
+
+// RefreshHandler 通知controller立即拉取git仓库的最新提交
+func RefreshHandler(conf *config.ServerConfig) api.Handler {
+	return &refreshHandler{
+		models:     conf.Models,
+		appService: conf.ServiceFactory.Project.AppService,
+	}
+}
+
+func (h *refreshHandler) Auth(c *api.Context) (bool, *api.AuthPerm, error) {
+	return appAuth(c, h.models, types.RoleEditor)
+}
+
+func (h *refreshHandler) Handle(c *api.Context) *utils.Response {
+	appId, _ := utils.ParseUint(c.Param("id"))
+	source, err := h.appService.RefreshGitSource(appId, c.User.Name)
+	return c.Response(err, source)
+}
@@ -0,0 +1,64 @@
+package gitsource
+
+import (
+	"fmt"
+	"github.com/kubespace/kubespace/pkg/core/code"
+	"github.com/kubespace/kubespace/pkg/core/errors"
+	"github.com/kubespace/kubespace/pkg/model"
+	"github.com/kubespace/kubespace/pkg/model/types"
+	"github.com/kubespace/kubespace/pkg/server/api/api"
+	"github.com/kubespace/kubespace/pkg/server/config"
+	projectservice "github.com/kubespace/kubespace/pkg/service/project"
+	"github.com/kubespace/kubespace/pkg/utils"
+)
+
+type syncHandler struct {
+	models     *model.Models
+	appService *projectservice.AppService
+}
+
+// SyncHandler 将应用安装或升级到git来源最新提交生成的应用版本
+func SyncHandler(conf *config.ServerConfig) api.Handler {
+	return &syncHandler{
+		models:     conf.Models,
+		appService: conf.ServiceFactory.Project.AppService,
+	}
+}
+
+func (h *syncHandler) Auth(c *api.Context) (bool, *api.AuthPerm, error) {
+	return appAuth(c, h.models, types.RoleEditor)
+}
+
+func (h *syncHandler) Handle(c *api.Context) *utils.Response {
+	appId, _ := utils.ParseUint(c.Param("id"))
+	app, err := h.models.AppManager.GetById(appId)
+	if err != nil {
+		return c.ResponseError(errors.New(code.DataNotExists, err))
+	}
+	_, appVersion, err := h.appService.SyncGitSource(appId, c.User.Name)
+	resp := c.ResponseError(err)
+
+	versionStr := ""
+	if appVersion != nil {
+		versionStr = appVersion.PackageVersion
+	}
+	opScope, opScopeId, opScopeName, opNamespace, err := auditScope(h.models, app)
+	if err != nil {
+		return c.ResponseError(err)
+	}
+	c.CreateAudit(&types.AuditOperate{
+		Operation:            types.AuditOperationSync,
+		OperateDetail:        fmt.Sprintf("同步应用%s到git来源版本：%s", app.Name, versionStr),
+		Scope:                opScope,
+		ScopeId:              opScopeId,
+		ScopeName:            opScopeName,
+		Namespace:            opNamespace,
+		ResourceId:           app.ID,
+		ResourceType:         types.AuditResourceApp,
+		ResourceName:         app.Name,
+		Code:                 resp.Code,
+		Message:              resp.Msg,
+		OperateDataInterface: nil,
+	})
+	return resp
+}
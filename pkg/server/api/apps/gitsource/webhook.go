@@ -0,0 +1,35 @@
+package gitsource
+
+import (
+	"github.com/gin-gonic/gin/binding"
+	"github.com/kubespace/kubespace/pkg/server/api/api"
+	"github.com/kubespace/kubespace/pkg/server/config"
+	projectservice "github.com/kubespace/kubespace/pkg/service/project"
+	"github.com/kubespace/kubespace/pkg/utils"
+)
+
+type webhookHandler struct {
+	appService *projectservice.AppService
+}
+
+// WebhookHandler 代码仓库推送事件的webhook，github/gitlab/gitee推送事件中都包含ref字段
+func WebhookHandler(conf *config.ServerConfig) api.Handler {
+	return &webhookHandler{appService: conf.ServiceFactory.Project.AppService}
+}
+
+// Auth 通过路径中git来源的webhook token认证
+func (h *webhookHandler) Auth(c *api.Context) (bool, *api.AuthPerm, error) {
+	return false, nil, nil
+}
+
+type webhookPayload struct {
+	Ref string `json:"ref"`
+}
+
+func (h *webhookHandler) Handle(c *api.Context) *utils.Response {
+	var payload webhookPayload
+	// 请求体不是json时不判断分支，直接同步
+	_ = c.ShouldBindBodyWith(&payload, binding.JSON)
+	_, err := h.appService.GitWebhook(c.Param("token"), payload.Ref)
+	return c.ResponseError(err)
+}
@@ -0,0 +1,66 @@
+package overlay
+
+import (
+	"fmt"
+	"github.com/gin-gonic/gin/binding"
+	"github.com/kubespace/kubespace/pkg/core/code"
+	"github.com/kubespace/kubespace/pkg/core/errors"
+	"github.com/kubespace/kubespace/pkg/model"
+	"github.com/kubespace/kubespace/pkg/model/types"
+	"github.com/kubespace/kubespace/pkg/server/api/api"
+	"github.com/kubespace/kubespace/pkg/server/config"
+	projectservice "github.com/kubespace/kubespace/pkg/service/project"
+	"github.com/kubespace/kubespace/pkg/utils"
+)
+
+type saveHandler struct {
+	models     *model.Models
+	appService *projectservice.AppService
+}
+
+func SaveHandler(conf *config.ServerConfig) api.Handler {
+	return &saveHandler{
+		models:     conf.Models,
+		appService: conf.ServiceFactory.Project.AppService,
+	}
+}
+
+func (h *saveHandler) Auth(c *api.Context) (bool, *api.AuthPerm, error) {
+	return appAuth(c, h.models, types.RoleEditor)
+}
+
+func (h *saveHandler) Handle(c *api.Context) *utils.Response {
+	var form projectservice.SaveValuesOverlayForm
+	if err := c.ShouldBindBodyWith(&form, binding.JSON); err != nil {
+		return c.ResponseError(errors.New(code.ParamsError, err.Error()))
+	}
+	appId, _ := utils.ParseUint(c.Param("id"))
+	app, err := h.models.AppManager.GetById(appId)
+	if err != nil {
+		return c.ResponseError(errors.New(code.DataNotExists, err))
+	}
+	form.AppId = appId
+	form.User = c.User.Name
+	overlay, err := h.appService.SaveValuesOverlay(&form)
+	resp := c.Response(err, overlay)
+
+	opScope, opScopeId, opScopeName, opNamespace, err := auditScope(h.models, app)
+	if err != nil {
+		return c.ResponseError(err)
+	}
+	c.CreateAudit(&types.AuditOperate{
+		Operation:            types.AuditOperationUpdate,
+		OperateDetail:        fmt.Sprintf("保存应用%s的values覆盖配置：%s", app.Name, form.Name),
+		Scope:                opScope,
+		ScopeId:              opScopeId,
+		ScopeName:            opScopeName,
+		Namespace:            opNamespace,
+		ResourceId:           app.ID,
+		ResourceType:         types.AuditResourceApp,
+		ResourceName:         app.Name,
+		Code:                 resp.Code,
+		Message:              resp.Msg,
+		OperateDataInterface: form,
+	})
+	return resp
+}
@@ -0,0 +1,86 @@
+package promotion
+
+import (
+	"fmt"
+	"github.com/gin-gonic/gin/binding"
+	"github.com/kubespace/kubespace/pkg/core/code"
+	"github.com/kubespace/kubespace/pkg/core/errors"
+	"github.com/kubespace/kubespace/pkg/model"
+	"github.com/kubespace/kubespace/pkg/model/types"
+	"github.com/kubespace/kubespace/pkg/server/api/api"
+	"github.com/kubespace/kubespace/pkg/server/config"
+	projectservice "github.com/kubespace/kubespace/pkg/service/project"
+	"github.com/kubespace/kubespace/pkg/utils"
+)
+
+type approveHandler struct {
+	models     *model.Models
+	appService *projectservice.AppService
+	approve    bool
+}
+
+// ApproveHandler 审批通过晋级，需要目标工作空间管理员权限
+func ApproveHandler(conf *config.ServerConfig) api.Handler {
+	return &approveHandler{
+		models:     conf.Models,
+		appService: conf.ServiceFactory.Project.AppService,
+		approve:    true,
+	}
+}
+
+// RejectHandler 审批拒绝晋级，需要目标工作空间管理员权限
+func RejectHandler(conf *config.ServerConfig) api.Handler {
+	return &approveHandler{
+		models:     conf.Models,
+		appService: conf.ServiceFactory.Project.AppService,
+		approve:    false,
+	}
+}
+
+func (h *approveHandler) Auth(c *api.Context) (bool, *api.AuthPerm, error) {
+	promotionId, err := utils.ParseUint(c.Param("id"))
+	if err != nil {
+		return true, nil, errors.New(code.ParamsError, err)
+	}
+	promotion, err := h.models.AppPromotionManager.Get(promotionId)
+	if err != nil {
+		return true, nil, errors.New(code.DataNotExists, err)
+	}
+	return true, &api.AuthPerm{
+		Scope:   types.ScopeProject,
+		ScopeId: promotion.TargetProjectId,
+		Role:    types.RoleAdmin,
+	}, nil
+}
+
+type rejectForm struct {
+	Message string `json:"message" form:"message"`
+}
+
+func (h *approveHandler) Handle(c *api.Context) *utils.Response {
+	promotionId, _ := utils.ParseUint(c.Param("id"))
+	var form rejectForm
+	if !h.approve {
+		if err := c.ShouldBindBodyWith(&form, binding.JSON); err != nil {
+			return c.ResponseError(errors.New(code.ParamsError, err.Error()))
+		}
+	}
+	var promotion *types.AppPromotion
+	var err error
+	operation := types.AuditOperationApprove
+	if h.approve {
+		promotion, err = h.appService.ApprovePromotion(promotionId, c.User.Name)
+	} else {
+		operation = types.AuditOperationReject
+		promotion, err = h.appService.RejectPromotion(promotionId, c.User.Name, form.Message)
+	}
+	resp := c.Response(err, promotion)
+	if promotion == nil {
+		return resp
+	}
+	detail := fmt.Sprintf("%s应用%s版本%s的晋级", operation, promotion.AppName, promotion.PackageVersion)
+	if err = createAudit(c, h.models, promotion, promotion.TargetProjectId, operation, detail, resp, form); err != nil {
+		return c.ResponseError(err)
+	}
+	return resp
+}
@@ -0,0 +1,96 @@
+package promotion
+
+import (
+	"fmt"
+	"github.com/gin-gonic/gin/binding"
+	"github.com/kubespace/kubespace/pkg/core/code"
+	"github.com/kubespace/kubespace/pkg/core/errors"
+	"github.com/kubespace/kubespace/pkg/model"
+	"github.com/kubespace/kubespace/pkg/model/types"
+	"github.com/kubespace/kubespace/pkg/server/api/api"
+	"github.com/kubespace/kubespace/pkg/server/config"
+	projectservice "github.com/kubespace/kubespace/pkg/service/project"
+	"github.com/kubespace/kubespace/pkg/utils"
+)
+
+type createHandler struct {
+	models     *model.Models
+	appService *projectservice.AppService
+}
+
+func CreateHandler(conf *config.ServerConfig) api.Handler {
+	return &createHandler{
+		models:     conf.Models,
+		appService: conf.ServiceFactory.Project.AppService,
+	}
+}
+
+func (h *createHandler) Auth(c *api.Context) (bool, *api.AuthPerm, error) {
+	var form projectservice.CreatePromotionForm
+	if err := c.ShouldBindBodyWith(&form, binding.JSON); err != nil {
+		return true, nil, errors.New(code.ParamsError, err)
+	}
+	app, err := h.models.AppManager.GetById(form.AppId)
+	if err != nil {
+		return true, nil, errors.New(code.DataNotExists, err)
+	}
+	return true, &api.AuthPerm{
+		Scope:   app.Scope,
+		ScopeId: app.ScopeId,
+		Role:    types.RoleEditor,
+	}, nil
+}
+
+func (h *createHandler) Handle(c *api.Context) *utils.Response {
+	var form projectservice.CreatePromotionForm
+	if err := c.ShouldBindBodyWith(&form, binding.JSON); err != nil {
+		return c.ResponseError(errors.New(code.ParamsError, err.Error()))
+	}
+	targetProject, err := h.models.ProjectManager.Get(form.TargetProjectId)
+	if err != nil {
+		return c.ResponseError(errors.New(code.DataNotExists, fmt.Sprintf("获取目标工作空间失败：%v", err)))
+	}
+	promotion, err := h.appService.CreatePromotion(&form, c.User)
+	resp := c.Response(err, promotion)
+	if promotion == nil {
+		return resp
+	}
+	detail := fmt.Sprintf("晋级应用%s版本%s到工作空间%s", promotion.AppName, promotion.PackageVersion, targetProject.Name)
+	if promotion.Status == types.AppPromotionStatusPending {
+		detail += "，等待审批"
+	}
+	if err = createAudit(c, h.models, promotion, promotion.SourceProjectId, types.AuditOperationPromote, detail, resp, form); err != nil {
+		return c.ResponseError(err)
+	}
+	return resp
+}
+
+// createAudit 创建晋级的审计记录，范围为指定的工作空间
+func createAudit(
+	c *api.Context,
+	models *model.Models,
+	promotion *types.AppPromotion,
+	projectId uint,
+	operation, detail string,
+	resp *utils.Response,
+	data interface{}) error {
+	projectObj, err := models.ProjectManager.Get(projectId)
+	if err != nil {
+		return errors.New(code.DataNotExists, fmt.Sprintf("获取工作空间失败：%v", err))
+	}
+	c.CreateAudit(&types.AuditOperate{
+		Operation:            operation,
+		OperateDetail:        detail,
+		Scope:                types.ScopeProject,
+		ScopeId:              projectObj.ID,
+		ScopeName:            projectObj.Name,
+		Namespace:            projectObj.Namespace,
+		ResourceId:           promotion.SourceAppId,
+		ResourceType:         types.AuditResourceApp,
+		ResourceName:         promotion.AppName,
+		Code:                 resp.Code,
+		Message:              resp.Msg,
+		OperateDataInterface: data,
+	})
+	return nil
+}
@@ -0,0 +1,49 @@
+package revision
+
+import (
+	"github.com/kubespace/kubespace/pkg/core/code"
+	"github.com/kubespace/kubespace/pkg/core/errors"
+	"github.com/kubespace/kubespace/pkg/model"
+	"github.com/kubespace/kubespace/pkg/model/types"
+	"github.com/kubespace/kubespace/pkg/server/api/api"
+	"github.com/kubespace/kubespace/pkg/server/config"
+	projectservice "github.com/kubespace/kubespace/pkg/service/project"
+	"github.com/kubespace/kubespace/pkg/utils"
+)
+
+type diffHandler struct {
+	models     *model.Models
+	appService *projectservice.AppService
+}
+
+func DiffHandler(conf *config.ServerConfig) api.Handler {
+	return &diffHandler{
+		models:     conf.Models,
+		appService: conf.ServiceFactory.Project.AppService,
+	}
+}
+
+type diffForm struct {
+	From uint `json:"from" form:"from"`
+	To   uint `json:"to" form:"to"`
+}
+
+func (h *diffHandler) Auth(c *api.Context) (bool, *api.AuthPerm, error) {
+	return appAuth(c, h.models, types.RoleViewer)
+}
+
+func (h *diffHandler) Handle(c *api.Context) *utils.Response {
+	appId, _ := utils.ParseUint(c.Param("id"))
+	var form diffForm
+	if err := c.ShouldBindQuery(&form); err != nil {
+		return c.ResponseError(errors.New(code.ParamsError, err))
+	}
+	if form.From == 0 || form.To == 0 {
+		return c.ResponseError(errors.New(code.ParamsError, "请选择要比较的历史记录"))
+	}
+	diff, err := h.appService.DiffAppRevisions(appId, form.From, form.To)
+	if err != nil {
+		return c.ResponseError(err)
+	}
+	return c.ResponseOK(diff)
+}
@@ -0,0 +1,41 @@
+package revision
+
+import (
+	"github.com/kubespace/kubespace/pkg/core/code"
+	"github.com/kubespace/kubespace/pkg/core/errors"
+	"github.com/kubespace/kubespace/pkg/model"
+	"github.com/kubespace/kubespace/pkg/model/types"
+	"github.com/kubespace/kubespace/pkg/server/api/api"
+	"github.com/kubespace/kubespace/pkg/server/config"
+	projectservice "github.com/kubespace/kubespace/pkg/service/project"
+	"github.com/kubespace/kubespace/pkg/utils"
+)
+
+type getHandler struct {
+	models     *model.Models
+	appService *projectservice.AppService
+}
+
+func GetHandler(conf *config.ServerConfig) api.Handler {
+	return &getHandler{
+		models:     conf.Models,
+		appService: conf.ServiceFactory.Project.AppService,
+	}
+}
+
+func (h *getHandler) Auth(c *api.Context) (bool, *api.AuthPerm, error) {
+	return appAuth(c, h.models, types.RoleViewer)
+}
+
+func (h *getHandler) Handle(c *api.Context) *utils.Response {
+	appId, _ := utils.ParseUint(c.Param("id"))
+	revisionId, err := utils.ParseUint(c.Param("revisionId"))
+	if err != nil {
+		return c.ResponseError(errors.New(code.ParamsError, err))
+	}
+	revision, err := h.appService.GetAppRevision(appId, revisionId)
+	if err != nil {
+		return c.ResponseError(err)
+	}
+	return c.ResponseOK(revision)
+}
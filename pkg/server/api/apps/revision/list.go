@@ -0,0 +1,54 @@
+package revision
+
+import (
+	"github.com/kubespace/kubespace/pkg/core/code"
+	"github.com/kubespace/kubespace/pkg/core/errors"
+	"github.com/kubespace/kubespace/pkg/model"
+	"github.com/kubespace/kubespace/pkg/model/types"
+	"github.com/kubespace/kubespace/pkg/server/api/api"
+	"github.com/kubespace/kubespace/pkg/server/config"
+	projectservice "github.com/kubespace/kubespace/pkg/service/project"
+	"github.com/kubespace/kubespace/pkg/utils"
+)
+
+type listHandler struct {
+	models     *model.Models
+	appService *projectservice.AppService
+}
+
+func ListHandler(conf *config.ServerConfig) api.Handler {
+	return &listHandler{
+		models:     conf.Models,
+		appService: conf.ServiceFactory.Project.AppService,
+	}
+}
+
+func (h *listHandler) Auth(c *api.Context) (bool, *api.AuthPerm, error) {
+	return appAuth(c, h.models, types.RoleViewer)
+}
+
+func (h *listHandler) Handle(c *api.Context) *utils.Response {
+	appId, _ := utils.ParseUint(c.Param("id"))
+	revisions, err := h.appService.ListAppRevisions(appId)
+	if err != nil {
+		return c.ResponseError(err)
+	}
+	return c.ResponseOK(revisions)
+}
+
+// appAuth 根据路径中的应用id获取应用所属范围进行鉴权
+func appAuth(c *api.Context, models *model.Models, role string) (bool, *api.AuthPerm, error) {
+	appId, err := utils.ParseUint(c.Param("id"))
+	if err != nil {
+		return true, nil, errors.New(code.ParamsError, err)
+	}
+	app, err := models.AppManager.GetById(appId)
+	if err != nil {
+		return true, nil, errors.New(code.DataNotExists, err)
+	}
+	return true, &api.AuthPerm{
+		Scope:   app.Scope,
+		ScopeId: app.ScopeId,
+		Role:    role,
+	}, nil
+}
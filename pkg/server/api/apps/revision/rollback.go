@@ -0,0 +1,106 @@
+package revision
+
+import (
+	"fmt"
+	"github.com/gin-gonic/gin/binding"
+	"github.com/kubespace/kubespace/pkg/core/code"
+	"github.com/kubespace/kubespace/pkg/core/errors"
+	"github.com/kubespace/kubespace/pkg/model"
+	"github.com/kubespace/kubespace/pkg/model/types"
+	"github.com/kubespace/kubespace/pkg/server/api/api"
+	"github.com/kubespace/kubespace/pkg/server/config"
+	projectservice "github.com/kubespace/kubespace/pkg/service/project"
+	"github.com/kubespace/kubespace/pkg/utils"
+)
+
+type rollbackHandler struct {
+	models     *model.Models
+	appService *projectservice.AppService
+}
+
+func RollbackHandler(conf *config.ServerConfig) api.Handler {
+	return &rollbackHandler{
+		models:     conf.Models,
+		appService: conf.ServiceFactory.Project.AppService,
+	}
+}
+
+func (h *rollbackHandler) Auth(c *api.Context) (bool, *api.AuthPerm, error) {
+	var form projectservice.RollbackAppForm
+	if err := c.ShouldBindBodyWith(&form, binding.JSON); err != nil {
+		return true, nil, errors.New(code.ParamsError, err)
+	}
+	app, err := h.models.AppManager.GetById(form.AppId)
+	if err != nil {
+		return true, nil, errors.New(code.DataNotExists, err)
+	}
+	return true, &api.AuthPerm{
+		Scope:   app.Scope,
+		ScopeId: app.ScopeId,
+		Role:    types.RoleEditor,
+	}, nil
+}
+
+func (h *rollbackHandler) Handle(c *api.Context) *utils.Response {
+	var form projectservice.RollbackAppForm
+	if err := c.ShouldBindBodyWith(&form, binding.JSON); err != nil {
+		return c.ResponseError(errors.New(code.ParamsError, err.Error()))
+	}
+	form.User = c.User.Name
+
+	app, revision, err := h.appService.RollbackApp(&form)
+	resp := c.ResponseError(err)
+
+	if app == nil {
+		return resp
+	}
+
+	var opDetail, opScope, opScopeName, opNamespace, opResType string
+	var opScopeId uint
+	revisionStr := fmt.Sprintf("id=%d", form.RevisionId)
+	if revision != nil {
+		revisionStr = fmt.Sprintf("#%d", revision.BuildRevision)
+		if revision.PackageName != "" {
+			revisionStr += fmt.Sprintf("（%s-%s）", revision.PackageName, revision.PackageVersion)
+		}
+	}
+	if app.Scope == types.ScopeProject {
+		projectObj, err := h.models.ProjectManager.Get(app.ScopeId)
+		if err != nil {
+			return &utils.Response{Code: code.GetError, Msg: fmt.Sprintf("获取应用所在工作空间失败：%s", err.Error())}
+		}
+		opScope = types.ScopeProject
+		opScopeId = projectObj.ID
+		opNamespace = projectObj.Namespace
+		opScopeName = projectObj.Name
+		opResType = types.AuditResourceApp
+		opDetail = fmt.Sprintf("回滚应用%s到历史记录%s", app.Name, revisionStr)
+	} else {
+		clusterObj, err := h.models.ClusterManager.GetById(app.ScopeId)
+		if err != nil {
+			return &utils.Response{Code: code.DBError, Msg: fmt.Sprintf("获取集群id=%d失败：%s", app.ScopeId, err.Error())}
+		}
+		opScope = types.ScopeCluster
+		opScopeId = clusterObj.ID
+		opNamespace = app.Namespace
+		opScopeName = clusterObj.Name1
+		opResType = types.AuditResourceClusterComponent
+		opDetail = fmt.Sprintf("回滚集群组件%s到历史记录%s", app.Name, revisionStr)
+	}
+
+	c.CreateAudit(&types.AuditOperate{
+		Operation:            types.AuditOperationRollback,
+		OperateDetail:        opDetail,
+		Scope:                opScope,
+		ScopeId:              opScopeId,
+		ScopeName:            opScopeName,
+		Namespace:            opNamespace,
+		ResourceId:           app.ID,
+		ResourceType:         opResType,
+		ResourceName:         app.Name,
+		Code:                 resp.Code,
+		Message:              resp.Msg,
+		OperateDataInterface: form,
+	})
+	return resp
+}
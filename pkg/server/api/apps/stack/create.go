@@ -0,0 +1,56 @@
+package stack
+
+import (
+	"fmt"
+	"github.com/gin-gonic/gin/binding"
+	"github.com/kubespace/kubespace/pkg/core/code"
+	"github.com/kubespace/kubespace/pkg/core/errors"
+	"github.com/kubespace/kubespace/pkg/model"
+	"github.com/kubespace/kubespace/pkg/model/types"
+	"github.com/kubespace/kubespace/pkg/server/api/api"
+	"github.com/kubespace/kubespace/pkg/server/config"
+	projectservice "github.com/kubespace/kubespace/pkg/service/project"
+	"github.com/kubespace/kubespace/pkg/utils"
+)
+
+type createHandler struct {
+	models     *model.Models
+	appService *projectservice.AppService
+}
+
+func CreateHandler(conf *config.ServerConfig) api.Handler {
+	return &createHandler{
+		models:     conf.Models,
+		appService: conf.ServiceFactory.Project.AppService,
+	}
+}
+
+func (h *createHandler) Auth(c *api.Context) (bool, *api.AuthPerm, error) {
+	var form projectservice.SaveStackForm
+	if err := c.ShouldBindBodyWith(&form, binding.JSON); err != nil {
+		return true, nil, errors.New(code.ParamsError, err)
+	}
+	return true, &api.AuthPerm{
+		Scope:   types.ScopeProject,
+		ScopeId: form.ProjectId,
+		Role:    types.RoleEditor,
+	}, nil
+}
+
+func (h *createHandler) Handle(c *api.Context) *utils.Response {
+	var form projectservice.SaveStackForm
+	if err := c.ShouldBindBodyWith(&form, binding.JSON); err != nil {
+		return c.ResponseError(errors.New(code.ParamsError, err.Error()))
+	}
+	form.User = c.User.Name
+	stack, err := h.appService.CreateStack(&form)
+	resp := c.Response(err, stack)
+	if stack == nil {
+		stack = &types.AppStack{ProjectId: form.ProjectId, Name: form.Name}
+	}
+	if err = createAudit(c, h.models, stack, types.AuditOperationCreate,
+		fmt.Sprintf("创建应用栈：%s", form.Name), resp, form); err != nil {
+		return c.ResponseError(err)
+	}
+	return resp
+}
@@ -0,0 +1,44 @@
+package stack
+
+import (
+	"fmt"
+	"github.com/kubespace/kubespace/pkg/core/code"
+	"github.com/kubespace/kubespace/pkg/core/errors"
+	"github.com/kubespace/kubespace/pkg/model"
+	"github.com/kubespace/kubespace/pkg/model/types"
+	"github.com/kubespace/kubespace/pkg/server/api/api"
+	"github.com/kubespace/kubespace/pkg/server/config"
+	projectservice "github.com/kubespace/kubespace/pkg/service/project"
+	"github.com/kubespace/kubespace/pkg/utils"
+)
+
+type deleteHandler struct {
+	models     *model.Models
+	appService *projectservice.AppService
+}
+
+// DeleteHandler 删除应用栈，应用栈中的应用不会被删除或者销毁
+func DeleteHandler(conf *config.ServerConfig) api.Handler {
+	return &deleteHandler{
+		models:     conf.Models,
+		appService: conf.ServiceFactory.Project.AppService,
+	}
+}
+
+func (h *deleteHandler) Auth(c *api.Context) (bool, *api.AuthPerm, error) {
+	return stackAuth(c, h.models, types.RoleEditor)
+}
+
+func (h *deleteHandler) Handle(c *api.Context) *utils.Response {
+	stackId, _ := utils.ParseUint(c.Param("id"))
+	stack, err := h.models.AppStackManager.Get(stackId)
+	if err != nil {
+		return c.ResponseError(errors.New(code.DataNotExists, err))
+	}
+	resp := c.ResponseError(h.appService.DeleteStack(stackId))
+	if err = createAudit(c, h.models, stack, types.AuditOperationDelete,
+		fmt.Sprintf("删除应用栈：%s", stack.Name), resp, nil); err != nil {
+		return c.ResponseError(err)
+	}
+	return resp
+}
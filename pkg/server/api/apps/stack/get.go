@@ -0,0 +1,33 @@
+package stack
+
+import (
+	"github.com/kubespace/kubespace/pkg/model"
+	"github.com/kubespace/kubespace/pkg/model/types"
+	"github.com/kubespace/kubespace/pkg/server/api/api"
+	"github.com/kubespace/kubespace/pkg/server/config"
+	projectservice "github.com/kubespace/kubespace/pkg/service/project"
+	"github.com/kubespace/kubespace/pkg/utils"
+)
+
+type getHandler struct {
+	models     *model.Models
+	appService *projectservice.AppService
+}
+
+// GetHandler 获取应用栈以及按部署顺序排列的应用状态
+func GetHandler(conf *config.ServerConfig) api.Handler {
+	return &getHandler{
+		models:     conf.Models,
+		appService: conf.ServiceFactory.Project.AppService,
+	}
+}
+
+func (h *getHandler) Auth(c *api.Context) (bool, *api.AuthPerm, error) {
+	return stackAuth(c, h.models, types.RoleViewer)
+}
+
+func (h *getHandler) Handle(c *api.Context) *utils.Response {
+	stackId, _ := utils.ParseUint(c.Param("id"))
+	detail, err := h.appService.GetStack(stackId)
+	return c.Response(err, detail)
+}
@@ -0,0 +1,95 @@
+package stack
+
+import (
+	"github.com/kubespace/kubespace/pkg/core/code"
+	"github.com/kubespace/kubespace/pkg/core/errors"
+	"github.com/kubespace/kubespace/pkg/model"
+	"github.com/kubespace/kubespace/pkg/model/types"
+	"github.com/kubespace/kubespace/pkg/server/api/api"
+	"github.com/kubespace/kubespace/pkg/server/config"
+	projectservice "github.com/kubespace/kubespace/pkg/service/project"
+	"github.com/kubespace/kubespace/pkg/utils"
+)
+
+type listHandler struct {
+	models     *model.Models
+	appService *projectservice.AppService
+}
+
+// ListHandler 获取工作空间中的应用栈
+func ListHandler(conf *config.ServerConfig) api.Handler {
+	return &listHandler{
+		models:     conf.Models,
+		appService: conf.ServiceFactory.Project.AppService,
+	}
+}
+
+type listStackForm struct {
+	ProjectId uint `json:"project_id" form:"project_id"`
+}
+
+func (h *listHandler) Auth(c *api.Context) (bool, *api.AuthPerm, error) {
+	var form listStackForm
+	if err := c.ShouldBindQuery(&form); err != nil {
+		return true, nil, errors.New(code.ParamsError, err)
+	}
+	return true, &api.AuthPerm{
+		Scope:   types.ScopeProject,
+		ScopeId: form.ProjectId,
+		Role:    types.RoleViewer,
+	}, nil
+}
+
+func (h *listHandler) Handle(c *api.Context) *utils.Response {
+	var form listStackForm
+	if err := c.ShouldBindQuery(&form); err != nil {
+		return c.ResponseError(errors.New(code.ParamsError, err))
+	}
+	stacks, err := h.appService.ListStacks(form.ProjectId)
+	return c.Response(err, stacks)
+}
+
+// stackAuth 根据路径中的应用栈id获取所属工作空间进行鉴权
+func stackAuth(c *api.Context, models *model.Models, role string) (bool, *api.AuthPerm, error) {
+	stackId, err := utils.ParseUint(c.Param("id"))
+	if err != nil {
+		return true, nil, errors.New(code.ParamsError, err)
+	}
+	stack, err := models.AppStackManager.Get(stackId)
+	if err != nil {
+		return true, nil, errors.New(code.DataNotExists, err)
+	}
+	return true, &api.AuthPerm{
+		Scope:   types.ScopeProject,
+		ScopeId: stack.ProjectId,
+		Role:    role,
+	}, nil
+}
+
+func createAudit(
+	c *api.Context,
+	models *model.Models,
+	stack *types.AppStack,
+	operation, detail string,
+	resp *utils.Response,
+	data interface{}) error {
+	projectObj, err := models.ProjectManager.Get(stack.ProjectId)
+	if err != nil {
+		return errors.New(code.DataNotExists, err)
+	}
+	c.CreateAudit(&types.AuditOperate{
+		Operation:            operation,
+		OperateDetail:        detail,
+		Scope:                types.ScopeProject,
+		ScopeId:              projectObj.ID,
+		ScopeName:            projectObj.Name,
+		Namespace:            projectObj.Namespace,
+		ResourceId:           stack.ID,
+		ResourceType:         types.AuditResourceAppStack,
+		ResourceName:         stack.Name,
+		Code:                 resp.Code,
+		Message:              resp.Msg,
+		OperateDataInterface: data,
+	})
+	return nil
+}
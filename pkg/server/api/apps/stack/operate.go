@@ -0,0 +1,64 @@
+package stack
+
+import (
+	"fmt"
+	"github.com/kubespace/kubespace/pkg/core/code"
+	"github.com/kubespace/kubespace/pkg/core/errors"
+	"github.com/kubespace/kubespace/pkg/model"
+	"github.com/kubespace/kubespace/pkg/model/types"
+	"github.com/kubespace/kubespace/pkg/server/api/api"
+	"github.com/kubespace/kubespace/pkg/server/config"
+	projectservice "github.com/kubespace/kubespace/pkg/service/project"
+	"github.com/kubespace/kubespace/pkg/utils"
+)
+
+type operateHandler struct {
+	models     *model.Models
+	appService *projectservice.AppService
+	// status 操作对应的应用栈状态
+	status    string
+	operation string
+}
+
+// InstallHandler 按依赖顺序安装应用栈中未安装的应用
+func InstallHandler(conf *config.ServerConfig) api.Handler {
+	return newOperateHandler(conf, types.AppStackStatusInstalling, types.AuditOperationInstall)
+}
+
+// UpgradeHandler 按依赖顺序将应用栈中的应用升级到应用栈指定的版本
+func UpgradeHandler(conf *config.ServerConfig) api.Handler {
+	return newOperateHandler(conf, types.AppStackStatusUpgrading, types.AuditOperationUpgrade)
+}
+
+// DestroyHandler 按依赖的逆序销毁应用栈中的应用
+func DestroyHandler(conf *config.ServerConfig) api.Handler {
+	return newOperateHandler(conf, types.AppStackStatusDestroying, types.AuditOperationDestroy)
+}
+
+func newOperateHandler(conf *config.ServerConfig, status, operation string) api.Handler {
+	return &operateHandler{
+		models:     conf.Models,
+		appService: conf.ServiceFactory.Project.AppService,
+		status:     status,
+		operation:  operation,
+	}
+}
+
+func (h *operateHandler) Auth(c *api.Context) (bool, *api.AuthPerm, error) {
+	return stackAuth(c, h.models, types.RoleEditor)
+}
+
+func (h *operateHandler) Handle(c *api.Context) *utils.Response {
+	stackId, _ := utils.ParseUint(c.Param("id"))
+	stack, err := h.models.AppStackManager.Get(stackId)
+	if err != nil {
+		return c.ResponseError(errors.New(code.DataNotExists, err))
+	}
+	started, err := h.appService.StartStackOperation(stackId, h.status, c.User.Name)
+	resp := c.Response(err, started)
+	if err = createAudit(c, h.models, stack, h.operation,
+		fmt.Sprintf("%s应用栈：%s", h.operation, stack.Name), resp, nil); err != nil {
+		return c.ResponseError(err)
+	}
+	return resp
+}
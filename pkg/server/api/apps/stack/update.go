@@ -0,0 +1,51 @@
+package stack
+
+import (
+	"fmt"
+	"github.com/gin-gonic/gin/binding"
+	"github.com/kubespace/kubespace/pkg/core/code"
+	"github.com/kubespace/kubespace/pkg/core/errors"
+	"github.com/kubespace/kubespace/pkg/model"
+	"github.com/kubespace/kubespace/pkg/model/types"
+	"github.com/kubespace/kubespace/pkg/server/api/api"
+	"github.com/kubespace/kubespace/pkg/server/config"
+	projectservice "github.com/kubespace/kubespace/pkg/service/project"
+	"github.com/kubespace/kubespace/pkg/utils"
+)
+
+type updateHandler struct {
+	models     *model.Models
+	appService *projectservice.AppService
+}
+
+// UpdateHandler 更新应用栈的描述、应用以及依赖关系
+func UpdateHandler(conf *config.ServerConfig) api.Handler {
+	return &updateHandler{
+		models:     conf.Models,
+		appService: conf.ServiceFactory.Project.AppService,
+	}
+}
+
+func (h *updateHandler) Auth(c *api.Context) (bool, *api.AuthPerm, error) {
+	return stackAuth(c, h.models, types.RoleEditor)
+}
+
+func (h *updateHandler) Handle(c *api.Context) *utils.Response {
+	var form projectservice.SaveStackForm
+	if err := c.ShouldBindBodyWith(&form, binding.JSON); err != nil {
+		return c.ResponseError(errors.New(code.ParamsError, err.Error()))
+	}
+	stackId, _ := utils.ParseUint(c.Param("id"))
+	stack, err := h.models.AppStackManager.Get(stackId)
+	if err != nil {
+		return c.ResponseError(errors.New(code.DataNotExists, err))
+	}
+	form.User = c.User.Name
+	updated, err := h.appService.UpdateStack(stackId, &form)
+	resp := c.Response(err, updated)
+	if err = createAudit(c, h.models, stack, types.AuditOperationUpdate,
+		fmt.Sprintf("更新应用栈：%s", stack.Name), resp, form); err != nil {
+		return c.ResponseError(err)
+	}
+	return resp
+}
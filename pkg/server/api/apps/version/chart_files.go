@@ -1,6 +1,7 @@
 package version
 
 import (
+	"encoding/base64"
 	"github.com/kubespace/kubespace/pkg/core/code"
 	"github.com/kubespace/kubespace/pkg/core/errors"
 	"github.com/kubespace/kubespace/pkg/model"
@@ -49,10 +50,18 @@ func (h *chartFilesHandler) Handle(c *api.Context) *utils.Response {
 	if err != nil {
 		return c.ResponseError(err)
 	}
+	// values.schema.json用于生成values表单
+	valuesSchema := ""
+	if schema, ok := chartFiles["values.schema.json"].(string); ok {
+		if schemaBytes, err := base64.StdEncoding.DecodeString(schema); err == nil {
+			valuesSchema = string(schemaBytes)
+		}
+	}
 	data := map[string]interface{}{
-		"chart_files": chartFiles,
-		"app":         app,
-		"app_version": appVersion,
+		"chart_files":   chartFiles,
+		"values_schema": valuesSchema,
+		"app":           app,
+		"app_version":   appVersion,
 	}
 	return c.ResponseOK(data)
 }
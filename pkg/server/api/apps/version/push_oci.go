@@ -0,0 +1,117 @@
+package version
+
+import (
+	"fmt"
+	"github.com/gin-gonic/gin/binding"
+	"github.com/kubespace/kubespace/pkg/core/code"
+	"github.com/kubespace/kubespace/pkg/core/errors"
+	"github.com/kubespace/kubespace/pkg/model"
+	"github.com/kubespace/kubespace/pkg/model/types"
+	"github.com/kubespace/kubespace/pkg/server/api/api"
+	"github.com/kubespace/kubespace/pkg/server/config"
+	projectservice "github.com/kubespace/kubespace/pkg/service/project"
+	"github.com/kubespace/kubespace/pkg/utils"
+)
+
+type pushOciHandler struct {
+	models     *model.Models
+	appService *projectservice.AppService
+}
+
+func PushOciHandler(conf *config.ServerConfig) api.Handler {
+	return &pushOciHandler{
+		models:     conf.Models,
+		appService: conf.ServiceFactory.Project.AppService,
+	}
+}
+
+func (h *pushOciHandler) Auth(c *api.Context) (bool, *api.AuthPerm, error) {
+	var form projectservice.PushAppVersionForm
+	if err := c.ShouldBindBodyWith(&form, binding.JSON); err != nil {
+		return true, nil, errors.New(code.ParamsError, err)
+	}
+	appVersion, err := h.models.AppVersionManager.GetById(form.AppVersionId)
+	if err != nil {
+		return true, nil, errors.New(code.DataNotExists, err)
+	}
+	if appVersion.Scope == types.ScopeAppStore {
+		return true, &api.AuthPerm{
+			Scope:   types.ScopePlatform,
+			ScopeId: 0,
+			Role:    types.RoleEditor,
+		}, nil
+	}
+	app, err := h.models.AppManager.GetById(appVersion.ScopeId)
+	if err != nil {
+		return true, nil, errors.New(code.DataNotExists, err)
+	}
+	return true, &api.AuthPerm{
+		Scope:   app.Scope,
+		ScopeId: app.ScopeId,
+		Role:    types.RoleEditor,
+	}, nil
+}
+
+func (h *pushOciHandler) Handle(c *api.Context) *utils.Response {
+	var form projectservice.PushAppVersionForm
+	if err := c.ShouldBindBodyWith(&form, binding.JSON); err != nil {
+		return c.ResponseError(errors.New(code.ParamsError, err.Error()))
+	}
+	appVersion, err := h.models.AppVersionManager.GetById(form.AppVersionId)
+	if err != nil {
+		return c.ResponseError(errors.New(code.DataNotExists, err))
+	}
+	ref, err := h.appService.PushAppVersionToOci(&form)
+	resp := c.Response(err, map[string]interface{}{"ref": ref})
+
+	var opScope, opScopeName, opNamespace string
+	var opScopeId uint
+	if appVersion.Scope == types.ScopeAppStore {
+		appStoreObj, err := h.models.AppStoreManager.GetById(appVersion.ScopeId)
+		if err != nil {
+			return c.ResponseError(errors.New(code.DataNotExists, fmt.Sprintf("获取应用商店应用失败：%v", err)))
+		}
+		opScope = types.ScopeAppStore
+		opScopeId = appStoreObj.ID
+		opScopeName = appStoreObj.Name
+	} else {
+		appObj, err := h.models.AppManager.GetById(appVersion.ScopeId)
+		if err != nil {
+			return c.ResponseError(errors.New(code.DataNotExists, fmt.Sprintf("获取应用失败：%v", err)))
+		}
+		opNamespace = appObj.Namespace
+		if appObj.Scope == types.ScopeProject {
+			projectObj, err := h.models.ProjectManager.Get(appObj.ScopeId)
+			if err != nil {
+				return c.ResponseError(errors.New(code.DataNotExists, fmt.Sprintf("获取应用所在工作空间失败：%v", err)))
+			}
+			opScope = types.ScopeProject
+			opScopeId = projectObj.ID
+			opScopeName = projectObj.Name
+			opNamespace = projectObj.Namespace
+		} else {
+			clusterObj, err := h.models.ClusterManager.GetById(appObj.ScopeId)
+			if err != nil {
+				return c.ResponseError(errors.New(code.DBError, fmt.Sprintf("获取集群id=%d失败：%v", appObj.ScopeId, err)))
+			}
+			opScope = types.ScopeCluster
+			opScopeId = clusterObj.ID
+			opScopeName = clusterObj.Name1
+		}
+	}
+	c.CreateAudit(&types.AuditOperate{
+		Operation:            types.AuditOperationPush,
+		OperateDetail:        fmt.Sprintf("推送应用版本%s-%s到%s", appVersion.PackageName, appVersion.PackageVersion, ref),
+		Scope:                opScope,
+		ScopeId:              opScopeId,
+		ScopeName:            opScopeName,
+		Namespace:            opNamespace,
+		ResourceId:           appVersion.ID,
+		ResourceType:         types.AuditResourceAppVersion,
+		ResourceName:         appVersion.PackageName + "-" + appVersion.PackageVersion,
+		Code:                 resp.Code,
+		Message:              resp.Msg,
+		OperateDataInterface: form,
+	})
+	return resp
+}
@@ -1,26 +1,36 @@
 package agent
 
 import (
+	"fmt"
 	"github.com/gorilla/websocket"
 	"github.com/kubespace/kubespace/pkg/informer"
+	"github.com/kubespace/kubespace/pkg/informer/listwatcher/cluster"
+	"github.com/kubespace/kubespace/pkg/kubeagent"
 	"github.com/kubespace/kubespace/pkg/model"
 	"github.com/kubespace/kubespace/pkg/model/types"
 	"github.com/kubespace/kubespace/pkg/server/api/api"
 	"github.com/kubespace/kubespace/pkg/server/config"
+	"github.com/kubespace/kubespace/pkg/service/notification"
 	"github.com/kubespace/kubespace/pkg/utils"
 	"k8s.io/klog/v2"
 	"net/http"
+	"time"
 )
 
+// agentReconnectGrace agent断开后在该时间内没有重新连接才发送通知，避免网络抖动时频繁通知
+const agentReconnectGrace = time.Minute
+
 type connectHandler struct {
-	models          *model.Models
-	informerFactory informer.Factory
+	models              *model.Models
+	informerFactory     informer.Factory
+	notificationService *notification.NotificationService
 }
 
 func ConnectHandler(conf *config.ServerConfig) api.Handler {
 	return &connectHandler{
-		models:          conf.Models,
-		informerFactory: conf.InformerFactory,
+		models:              conf.Models,
+		informerFactory:     conf.InformerFactory,
+		notificationService: conf.ServiceFactory.Notification.NotificationService,
 	}
 }
 
@@ -30,35 +40,105 @@ func (h *connectHandler) Auth(c *api.Context) (bool, *api.AuthPerm, error) {
 }
 
 func (h *connectHandler) Handle(c *api.Context) *utils.Response {
-	token := c.GetHeader("token")
-	if token == "" {
-		c.String(http.StatusBadRequest, "params error, not found cluster token")
-	}
-
-	clusterObj, err := h.models.ClusterManager.GetByToken(token)
+	clusterObj, credential, err := agentCluster(h.models, c)
 	if err != nil {
 		c.String(http.StatusForbidden, "get cluster error: %v", err)
 		return nil
 	}
 
+	// agent请求v2协议时在返回头中确认，旧版本agent继续使用之前的协议
+	v2 := c.GetHeader(kubeagent.HeaderTunnelProtocol) == kubeagent.TunnelProtocolV2
+	var respHeader http.Header
+	if v2 {
+		respHeader = http.Header{kubeagent.HeaderTunnelProtocol: []string{kubeagent.TunnelProtocolV2}}
+	}
 	upGrader := &websocket.Upgrader{}
-	ws, err := upGrader.Upgrade(c.Writer, c.Request, nil)
+	ws, err := upGrader.Upgrade(c.Writer, c.Request, respHeader)
 	if err != nil {
 		c.String(http.StatusBadRequest, "upgrade connection error: %v", err)
 		return nil
 	}
 
-	tunnel, err := newAgentTunnel(ws, h.informerFactory.ClusterAgentInformer(token))
+	version := c.GetHeader(kubeagent.HeaderVersion)
+	conn := &types.ClusterAgentConnection{
+		ClusterId:    clusterObj.ID,
+		AgentVersion: version,
+		RemoteAddr:   c.ClientIP(),
+		ConnectTime:  time.Now(),
+	}
+	if err = h.models.ClusterManager.CreateAgentConnection(conn); err != nil {
+		klog.Warningf("create cluster agent connection error: %s", err.Error())
+	}
+	tunnel, err := newAgentTunnel(ws, h.informerFactory.ClusterAgentInformer(clusterObj.Name))
 	if err != nil {
 		klog.Errorf("create agent tunnel error: %v", err)
 		c.String(http.StatusInternalServerError, "create agent tunnel error: %v", err)
 		return nil
 	}
+	tunnel.v2 = v2
+	agentListWatcher := cluster.NewAgentListWatcher(clusterObj.Name, h.models.ListWatcherConfig)
+	tunnel.notify = agentListWatcher.NotifyResponseBytes
+	tunnel.windows = newStreamWindows(agentListWatcher.NotifyPending)
+	tunnel.keepalive = func() error {
+		obj, err := h.models.ClusterManager.GetById(clusterObj.ID)
+		if err != nil {
+			// 集群获取失败时不断开隧道，下次检查时重试
+			klog.Warningf("get cluster id=%d error: %s", clusterObj.ID, err.Error())
+			return nil
+		}
+		// 每次检查时重新读取集群的会话凭证，跟随agent的凭证轮换
+		current, ok := currentCredential(obj, credential)
+		if !ok {
+			return fmt.Errorf("agent凭证已过期或者被吊销")
+		}
+		credential = current
+		if err = h.models.ClusterManager.UpdateAgentSeen(clusterObj.ID, version); err != nil {
+			klog.Warningf("update cluster agent last seen error: %s", err.Error())
+		}
+		return nil
+	}
+	tunnel.onClose = func(reason string) {
+		if conn.ID == 0 {
+			return
+		}
+		if err := h.models.ClusterManager.FinishAgentConnection(conn.ID, reason); err != nil {
+			klog.Warningf("finish cluster agent connection error: %s", err.Error())
+		}
+		go h.notifyDisconnected(clusterObj, conn.ID, reason)
+	}
 	tunnel.Consume()
 	clusterObj.Status = types.ClusterConnect
 	if err = h.models.ClusterManager.UpdateByObject(clusterObj.ID, &types.Cluster{Status: types.ClusterConnect}); err != nil {
 		klog.Warningf("update cluster status error: %s", err.Error())
 	}
+	if err = h.models.ClusterManager.UpdateAgentSeen(clusterObj.ID, version); err != nil {
+		klog.Warningf("update cluster agent last seen error: %s", err.Error())
+	}
 	klog.V(1).Infof("cluster id=%s name=%s kube connect finish", clusterObj.Name, clusterObj.Name1)
 	return nil
 }
+
+// notifyDisconnected agent断开连接后等待重连，超时未重新连接时发送通知
+func (h *connectHandler) notifyDisconnected(clusterObj *types.Cluster, connId uint, reason string) {
+	time.Sleep(agentReconnectGrace)
+	conns, err := h.models.ClusterManager.ListAgentConnections(clusterObj.ID, 1)
+	if err != nil {
+		klog.Warningf("list cluster agent connections error: %s", err.Error())
+		return
+	}
+	if len(conns) > 0 && conns[0].ID != connId && conns[0].DisconnectTime == nil {
+		// 已重新连接
+		return
+	}
+	h.notificationService.Notify(&notification.Event{
+		Type:      types.NotificationEventClusterAgentDisconnected,
+		Scope:     types.ScopeCluster,
+		ScopeId:   clusterObj.ID,
+		ScopeName: clusterObj.Name1,
+		Subject:   clusterObj.Name1,
+		Message:   reason,
+		Data: map[string]interface{}{
+			"cluster_id": clusterObj.ID,
+		},
+	})
+}
@@ -2,26 +2,40 @@ package agent
 
 import (
 	"bytes"
+	"crypto/sha256"
 	_ "embed"
+	"encoding/hex"
 	"github.com/kubespace/kubespace/pkg/core/code"
 	"github.com/kubespace/kubespace/pkg/core/errors"
+	kubetypes "github.com/kubespace/kubespace/pkg/kubernetes/types"
+	"github.com/kubespace/kubespace/pkg/model"
+	usermgr "github.com/kubespace/kubespace/pkg/model/manager/user"
+	"github.com/kubespace/kubespace/pkg/model/types"
 	"github.com/kubespace/kubespace/pkg/server/api/api"
 	"github.com/kubespace/kubespace/pkg/server/config"
 	"github.com/kubespace/kubespace/pkg/utils"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
 	"text/template"
 )
 
 //go:embed import.yaml.tpl
 var importAgentYaml string
 
-// 导入agent yaml
+var caHashRegexp = regexp.MustCompile(`^sha256:[0-9a-fA-F]{64}$`)
+
+// 导入agent yaml，根据集群的agent权限配置生成对应的ClusterRole/Role
 type importHandler struct {
+	models          *model.Models
 	agentRepository string
 	agentVersion    string
 }
 
 func ImportHandler(conf *config.ServerConfig) api.Handler {
 	return &importHandler{
+		models:          conf.Models,
 		agentRepository: conf.AgentRepository,
 		agentVersion:    conf.AgentVersion,
 	}
@@ -33,14 +47,48 @@ func (h *importHandler) Auth(c *api.Context) (bool, *api.AuthPerm, error) {
 }
 
 func (h *importHandler) Handle(c *api.Context) *utils.Response {
-	token := c.Param("token")
 	serverUrl := utils.RequestHost(c.Request)
+	// agent更新自身yaml时使用会话凭证获取
+	clusterObj, _, err := h.models.ClusterManager.GetByAgentToken(c.Param("token"))
+	if err != nil {
+		return c.ResponseError(errors.New(code.DataNotExists, "get cluster error: "+err.Error()))
+	}
+	caHash := c.Query("ca_hash")
+	if caHash != "" && !caHashRegexp.MatchString(caHash) {
+		return c.ResponseError(errors.New(code.ParamsError, "ca_hash格式错误，格式为sha256:<hex>"))
+	}
+	tokenHash := sha256.Sum256([]byte(clusterObj.Token))
+	profile := kubetypes.AgentProfileAdmin
+	var namespaces []string
+	if clusterObj.AgentProfile != nil && clusterObj.AgentProfile.Name != "" {
+		profile = clusterObj.AgentProfile.Name
+		if profile == kubetypes.AgentProfileNamespace {
+			namespaces = clusterObj.AgentProfile.Namespaces
+		}
+	}
+	var impersonateUsers, impersonateGroups []string
+	if profile != kubetypes.AgentProfileAdmin {
+		if impersonateUsers, impersonateGroups, err = h.impersonateNames(clusterObj); err != nil {
+			return c.ResponseError(errors.New(code.DBError, err))
+		}
+	}
 
 	placeholders := map[string]interface{}{
 		"AgentRepository": h.agentRepository,
 		"AgentVersion":    h.agentVersion,
-		"Token":           token,
+		"Token":           clusterObj.Token,
+		"TokenHash":       hex.EncodeToString(tokenHash[:8]),
 		"ServerUrl":       serverUrl,
+		"ServerTLS":       c.Request.TLS != nil || c.GetHeader("X-Forwarded-Proto") == "https",
+		"CAHash":          caHash,
+		"Insecure":        c.Query("insecure_skip_tls_verify") == "true",
+		"Profile":         profile,
+		"Namespaces":      namespaces,
+		"NamespacesList":  quoteJoin(namespaces),
+		"NamespacesArg":   strings.Join(namespaces, ","),
+		// 受限权限的agent只能模拟映射的用户以及用户组，避免模拟system:masters等高权限用户组
+		"ImpersonateUsers":  quoteJoin(impersonateUsers),
+		"ImpersonateGroups": quoteJoin(impersonateGroups),
 	}
 	var buffer bytes.Buffer
 	if err := template.Must(template.New("import_agent.yaml").Parse(importAgentYaml)).Execute(&buffer, placeholders); err != nil {
@@ -49,3 +97,40 @@ func (h *importHandler) Handle(c *api.Context) *utils.Response {
 	c.String(200, buffer.String())
 	return nil
 }
+
+// impersonateNames 集群开启用户模拟时，agent需要模拟的k8s用户以及用户组，用户为前缀加上所有kubespace用户名
+func (h *importHandler) impersonateNames(clusterObj *types.Cluster) (users []string, groups []string, err error) {
+	mapping := clusterObj.Impersonation
+	if mapping == nil || !mapping.Enabled {
+		return nil, nil, nil
+	}
+	allUsers, err := h.models.UserManager.List(usermgr.UserListCondition{})
+	if err != nil {
+		return nil, nil, err
+	}
+	for _, u := range allUsers {
+		users = append(users, mapping.UserPrefix+u.Name)
+	}
+	mappedGroups := append([]string{}, mapping.Groups...)
+	for _, roleGroups := range mapping.RoleGroups {
+		mappedGroups = append(mappedGroups, roleGroups...)
+	}
+	for _, userGroups := range mapping.UserGroups {
+		mappedGroups = append(mappedGroups, userGroups...)
+	}
+	for _, group := range mappedGroups {
+		if group != "" && !utils.Contains(groups, group) {
+			groups = append(groups, group)
+		}
+	}
+	sort.Strings(groups)
+	return users, groups, nil
+}
+
+func quoteJoin(values []string) string {
+	var quoted []string
+	for _, v := range values {
+		quoted = append(quoted, strconv.Quote(v))
+	}
+	return strings.Join(quoted, ", ")
+}
@@ -13,27 +13,158 @@ metadata:
 
 ---
 
-kind: ClusterRoleBinding
-apiVersion: rbac.authorization.k8s.io/v1beta1
+apiVersion: v1
+kind: Secret
 metadata:
   name: kubespace-agent
   namespace: kubespace
+type: Opaque
+stringData:
+  token: {{ .Token }}
+
+---
+{{- define "impersonate" }}
+{{- if .ImpersonateUsers }}
+- apiGroups: [""]
+  resources: ["users"]
+  resourceNames: [{{ .ImpersonateUsers }}]
+  verbs: ["impersonate"]
+{{- end }}
+{{- if .ImpersonateGroups }}
+- apiGroups: [""]
+  resources: ["groups"]
+  resourceNames: [{{ .ImpersonateGroups }}]
+  verbs: ["impersonate"]
+{{- end }}
+{{- end }}
+{{- if ne .Profile "admin" }}
+# 受限权限的agent只能模拟用户模拟配置中映射的k8s用户以及用户组，新增用户或者修改映射后需要重新应用该yaml。
+# 从admin切换为受限权限时，kubectl apply不会删除原有的cluster-admin绑定，需要手动删除：
+#   kubectl delete clusterrolebinding kubespace-agent --ignore-not-found
+# 在readonly与namespace之间切换时，同样需要删除原有权限的ClusterRoleBinding：
+#   kubectl delete clusterrolebinding {{ if eq .Profile "readonly" }}kubespace-agent-namespace{{ else }}kubespace-agent-readonly{{ end }} --ignore-not-found
+{{- end }}
+{{- if eq .Profile "readonly" }}
+
+kind: ClusterRole
+apiVersion: rbac.authorization.k8s.io/v1
+metadata:
+  name: kubespace-agent-readonly
+# 只读权限按资源逐一授权，不包含secrets，避免通过agent读取集群中的敏感数据
+rules:
+- apiGroups: [""]
+  resources: ["namespaces", "nodes", "pods", "pods/log", "services", "endpoints", "configmaps", "serviceaccounts", "events", "persistentvolumes", "persistentvolumeclaims", "replicationcontrollers", "resourcequotas", "limitranges"]
+  verbs: ["get", "list", "watch"]
+- apiGroups: ["apps"]
+  resources: ["deployments", "statefulsets", "daemonsets", "replicasets"]
+  verbs: ["get", "list", "watch"]
+- apiGroups: ["batch"]
+  resources: ["jobs", "cronjobs"]
+  verbs: ["get", "list", "watch"]
+- apiGroups: ["autoscaling"]
+  resources: ["horizontalpodautoscalers"]
+  verbs: ["get", "list", "watch"]
+- apiGroups: ["networking.k8s.io", "extensions"]
+  resources: ["ingresses", "networkpolicies"]
+  verbs: ["get", "list", "watch"]
+- apiGroups: ["storage.k8s.io"]
+  resources: ["storageclasses"]
+  verbs: ["get", "list", "watch"]
+- apiGroups: ["rbac.authorization.k8s.io"]
+  resources: ["roles", "rolebindings", "clusterroles", "clusterrolebindings"]
+  verbs: ["get", "list", "watch"]
+- apiGroups: ["apiextensions.k8s.io"]
+  resources: ["customresourcedefinitions"]
+  verbs: ["get", "list", "watch"]
+- apiGroups: ["events.k8s.io"]
+  resources: ["events"]
+  verbs: ["get", "list", "watch"]
+- apiGroups: ["metrics.k8s.io"]
+  resources: ["nodes", "pods"]
+  verbs: ["get", "list"]
+- nonResourceURLs: ["/healthz", "/healthz/*", "/livez", "/livez/*", "/readyz", "/readyz/*", "/version", "/version/"]
+  verbs: ["get"]
+{{- template "impersonate" . }}
+
+---
+
+kind: ClusterRoleBinding
+apiVersion: rbac.authorization.k8s.io/v1
+metadata:
+  name: kubespace-agent-readonly
 subjects:
 - kind: ServiceAccount
   name: kubespace-agent
   namespace: kubespace
 roleRef:
   kind: ClusterRole
-  name: cluster-admin
+  name: kubespace-agent-readonly
   apiGroup: rbac.authorization.k8s.io
+{{- else if eq .Profile "namespace" }}
+
+kind: ClusterRole
+apiVersion: rbac.authorization.k8s.io/v1
+metadata:
+  name: kubespace-agent-namespace
+rules:
+- apiGroups: [""]
+  resources: ["namespaces"]
+  resourceNames: [{{ .NamespacesList }}]
+  verbs: ["get"]
+- nonResourceURLs: ["/healthz", "/healthz/*", "/livez", "/livez/*", "/readyz", "/readyz/*", "/version", "/version/"]
+  verbs: ["get"]
+{{- template "impersonate" . }}
 
 ---
 
 kind: ClusterRoleBinding
 apiVersion: rbac.authorization.k8s.io/v1
+metadata:
+  name: kubespace-agent-namespace
+subjects:
+- kind: ServiceAccount
+  name: kubespace-agent
+  namespace: kubespace
+roleRef:
+  kind: ClusterRole
+  name: kubespace-agent-namespace
+  apiGroup: rbac.authorization.k8s.io
+{{- range .Namespaces }}
+
+---
+
+kind: Role
+apiVersion: rbac.authorization.k8s.io/v1
+metadata:
+  name: kubespace-agent
+  namespace: {{ . }}
+rules:
+- apiGroups: ["*"]
+  resources: ["*"]
+  verbs: ["*"]
+
+---
+
+kind: RoleBinding
+apiVersion: rbac.authorization.k8s.io/v1
 metadata:
+  name: kubespace-agent
+  namespace: {{ . }}
+subjects:
+- kind: ServiceAccount
   name: kubespace-agent
   namespace: kubespace
+roleRef:
+  kind: Role
+  name: kubespace-agent
+  apiGroup: rbac.authorization.k8s.io
+{{- end }}
+{{- else }}
+
+kind: ClusterRoleBinding
+apiVersion: rbac.authorization.k8s.io/v1
+metadata:
+  name: kubespace-agent
 subjects:
 - kind: ServiceAccount
   name: kubespace-agent
@@ -42,6 +173,7 @@ roleRef:
   kind: ClusterRole
   name: cluster-admin
   apiGroup: rbac.authorization.k8s.io
+{{- end }}
 
 ---
 
@@ -65,17 +197,35 @@ spec:
         app.kubernetes.io/instance: kubespace
         app.kubernetes.io/name: kubespace
         kubespace-app: kubespace-agent
+      annotations:
+        kubespace.cn/token-hash: "{{ .TokenHash }}"
     spec:
       containers:
       - name: kubespace-agent
-        image: {{ AgentRepository }}:{{ AgentVersion }}
+        image: {{ .AgentRepository }}:{{ .AgentVersion }}
         command:
         - "/kube-agent"
         args:
-        - --token={{ Token }}
-        - --server-host={{ ServerUrl }}
+        - --server-host={{ .ServerUrl }}
+        - --profile={{ .Profile }}
+{{- if .Namespaces }}
+        - --namespaces={{ .NamespacesArg }}
+{{- end }}
+{{- if .ServerTLS }}
+        - --server-tls
+{{- end }}
+{{- if .CAHash }}
+        - --ca-hash={{ .CAHash }}
+{{- end }}
+{{- if .Insecure }}
+        - --insecure-skip-tls-verify
+{{- end }}
         env:
+        - name: TOKEN
+          valueFrom:
+            secretKeyRef:
+              name: kubespace-agent
+              key: token
         - name: TZ
           value: Asia/Shanghai
       serviceAccountName: kubespace-agent
-`
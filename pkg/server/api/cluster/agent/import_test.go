@@ -0,0 +1,52 @@
+package agent
+
+import (
+	"bytes"
+	"github.com/kubespace/kubespace/pkg/kubernetes/types"
+	rbacv1 "k8s.io/api/rbac/v1"
+	"sigs.k8s.io/yaml"
+	"strings"
+	"testing"
+	"text/template"
+)
+
+func TestReadonlyClusterRole(t *testing.T) {
+	var buffer bytes.Buffer
+	placeholders := map[string]interface{}{
+		"Profile":          types.AgentProfileReadOnly,
+		"ImpersonateUsers": `"dev"`,
+	}
+	if err := template.Must(template.New("import_agent.yaml").Parse(importAgentYaml)).Execute(&buffer, placeholders); err != nil {
+		t.Fatal(err)
+	}
+
+	var role *rbacv1.ClusterRole
+	for _, doc := range strings.Split(buffer.String(), "\n---\n") {
+		var obj rbacv1.ClusterRole
+		if err := yaml.Unmarshal([]byte(doc), &obj); err != nil {
+			t.Fatalf("unmarshal %q error: %s", doc, err)
+		}
+		if obj.Kind == "ClusterRole" && obj.Name == "kubespace-agent-readonly" {
+			role = &obj
+		}
+	}
+	if role == nil {
+		t.Fatal("readonly cluster role not found")
+	}
+	// 只读权限不能读取secrets，也不能通过通配符访问任意资源以及非资源url
+	for _, rule := range role.Rules {
+		for _, values := range [][]string{rule.APIGroups, rule.Resources, rule.NonResourceURLs} {
+			for _, v := range values {
+				if v == "*" || v == "secrets" {
+					t.Errorf("rule %v grants %q", rule, v)
+				}
+			}
+		}
+		for _, url := range rule.NonResourceURLs {
+			if !strings.HasPrefix(url, "/healthz") && !strings.HasPrefix(url, "/livez") &&
+				!strings.HasPrefix(url, "/readyz") && !strings.HasPrefix(url, "/version") {
+				t.Errorf("unexpected non resource url %q", url)
+			}
+		}
+	}
+}
@@ -33,12 +33,7 @@ func (h *responseHandler) Auth(c *api.Context) (bool, *api.AuthPerm, error) {
 }
 
 func (h *responseHandler) Handle(c *api.Context) *utils.Response {
-	token := c.GetHeader("token")
-	if token == "" {
-		c.String(http.StatusBadRequest, "params error, not found cluster token")
-	}
-
-	clusterObj, err := h.models.ClusterManager.GetByToken(token)
+	clusterObj, _, err := agentCluster(h.models, c)
 	if err != nil {
 		c.String(http.StatusForbidden, "get cluster error: %v", err)
 		return nil
@@ -63,7 +58,7 @@ func (h *responseHandler) Handle(c *api.Context) *utils.Response {
 				klog.Errorf("json unmarshal response error: %s", err.Error())
 				return
 			}
-			agentListWatcher := cluster.NewAgentListWatcher(clusterObj.Token, h.models.ListWatcherConfig)
+			agentListWatcher := cluster.NewAgentListWatcher(clusterObj.Name, h.models.ListWatcherConfig)
 			if err = agentListWatcher.NotifyResponse(&resp); err != nil {
 				klog.Errorf("notify response error: %s", err.Error())
 			}
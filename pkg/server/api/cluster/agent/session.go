@@ -0,0 +1,82 @@
+package agent
+
+import (
+	"github.com/kubespace/kubespace/pkg/core/code"
+	"github.com/kubespace/kubespace/pkg/core/errors"
+	"github.com/kubespace/kubespace/pkg/kubeagent"
+	"github.com/kubespace/kubespace/pkg/model"
+	"github.com/kubespace/kubespace/pkg/model/types"
+	"github.com/kubespace/kubespace/pkg/server/api/api"
+	"github.com/kubespace/kubespace/pkg/server/config"
+	"github.com/kubespace/kubespace/pkg/utils"
+	"k8s.io/klog/v2"
+)
+
+// agent使用导入token或者当前会话凭证换取新的短期会话凭证
+type sessionHandler struct {
+	models *model.Models
+}
+
+func SessionHandler(conf *config.ServerConfig) api.Handler {
+	return &sessionHandler{models: conf.Models}
+}
+
+func (h *sessionHandler) Auth(c *api.Context) (bool, *api.AuthPerm, error) {
+	// 不需要用户认证，通过agent token认证
+	return false, nil, nil
+}
+
+func (h *sessionHandler) Handle(c *api.Context) *utils.Response {
+	clusterObj, _, err := h.models.ClusterManager.GetByAgentToken(c.GetHeader(kubeagent.HeaderToken))
+	if err != nil {
+		return c.ResponseError(errors.New(code.AuthError, err))
+	}
+	session, err := h.models.ClusterManager.IssueAgentSession(clusterObj, c.GetHeader(kubeagent.HeaderVersion))
+	if err != nil {
+		return c.ResponseError(errors.New(code.DBError, err))
+	}
+	klog.V(1).Infof("issue agent session for cluster id=%s name=%s", clusterObj.Name, clusterObj.Name1)
+	return c.ResponseOK(&kubeagent.SessionResponse{
+		SessionToken:  session,
+		ExpireSeconds: int(types.ClusterAgentSessionTTL.Seconds()),
+	})
+}
+
+// agentCluster 获取agent连接对应的集群以及使用的凭证，新版本agent使用会话凭证，兼容只使用导入token的旧版本agent
+func agentCluster(models *model.Models, c *api.Context) (*types.Cluster, string, error) {
+	if session := c.GetHeader(kubeagent.HeaderSession); session != "" {
+		clusterObj, isSession, err := models.ClusterManager.GetByAgentToken(session)
+		if err != nil {
+			return nil, "", err
+		}
+		if !isSession {
+			return nil, "", errors.New(code.AuthError, "agent session is invalid")
+		}
+		return clusterObj, session, nil
+	}
+	token := c.GetHeader(kubeagent.HeaderToken)
+	if token == "" {
+		return nil, "", errors.New(code.ParamsError, "not found cluster token")
+	}
+	clusterObj, err := models.ClusterManager.GetByToken(token)
+	if err != nil {
+		return nil, "", err
+	}
+	return clusterObj, token, nil
+}
+
+// currentCredential 返回隧道当前应持有的凭证，集群导入token重新生成或者吊销后，使用之前凭证的agent连接失效。
+// agent通过隧道建立时的会话凭证换取新凭证后，建立时的凭证成为轮换前的凭证，此时隧道跟随为当前会话凭证，
+// 避免长时间连接的隧道在多次轮换后断开
+func currentCredential(clusterObj *types.Cluster, credential string) (string, bool) {
+	if credential == clusterObj.Token {
+		return credential, true
+	}
+	if !clusterObj.ValidAgentSession(credential) {
+		return "", false
+	}
+	if credential == clusterObj.AgentPrevSessionToken && clusterObj.AgentSessionToken != "" {
+		return clusterObj.AgentSessionToken, true
+	}
+	return credential, true
+}
@@ -0,0 +1,88 @@
+package agent
+
+import (
+	"github.com/kubespace/kubespace/pkg/model/types"
+	"testing"
+	"time"
+)
+
+func TestCurrentCredential(t *testing.T) {
+	now := time.Now()
+	expired := now.Add(-types.ClusterAgentSessionTTL - time.Minute)
+	tests := []struct {
+		name       string
+		cluster    *types.Cluster
+		credential string
+		want       string
+		ok         bool
+	}{
+		{
+			name:       "import token",
+			cluster:    &types.Cluster{Token: "token"},
+			credential: "token",
+			want:       "token",
+			ok:         true,
+		},
+		{
+			name:       "current session",
+			cluster:    &types.Cluster{Token: "token", AgentSessionToken: "s1", AgentSessionTime: &now},
+			credential: "s1",
+			want:       "s1",
+			ok:         true,
+		},
+		{
+			name: "rotated session follows current session",
+			cluster: &types.Cluster{Token: "token", AgentSessionToken: "s2", AgentPrevSessionToken: "s1",
+				AgentSessionTime: &now},
+			credential: "s1",
+			want:       "s2",
+			ok:         true,
+		},
+		{
+			name: "session rotated twice before check",
+			cluster: &types.Cluster{Token: "token", AgentSessionToken: "s3", AgentPrevSessionToken: "s2",
+				AgentSessionTime: &now},
+			credential: "s1",
+			ok:         false,
+		},
+		{
+			name:       "revoked session",
+			cluster:    &types.Cluster{Token: "new"},
+			credential: "s1",
+			ok:         false,
+		},
+		{
+			name:       "expired session",
+			cluster:    &types.Cluster{Token: "token", AgentSessionToken: "s1", AgentSessionTime: &expired},
+			credential: "s1",
+			ok:         false,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := currentCredential(tt.cluster, tt.credential)
+			if ok != tt.ok || got != tt.want {
+				t.Errorf("currentCredential() = (%q, %v), want (%q, %v)", got, ok, tt.want, tt.ok)
+			}
+		})
+	}
+}
+
+// TestKeepaliveFollowsRotation 隧道多次检查之间agent多次轮换凭证时隧道保持有效
+func TestKeepaliveFollowsRotation(t *testing.T) {
+	now := time.Now()
+	cluster := &types.Cluster{Token: "token", AgentSessionToken: "s0", AgentSessionTime: &now}
+	credential := "s0"
+	for i := 1; i <= 5; i++ {
+		cluster.AgentPrevSessionToken = cluster.AgentSessionToken
+		cluster.AgentSessionToken = "s" + string(rune('0'+i))
+		current, ok := currentCredential(cluster, credential)
+		if !ok {
+			t.Fatalf("credential invalid after %d rotations", i)
+		}
+		credential = current
+	}
+	if credential != "s5" {
+		t.Errorf("credential = %q, want s5", credential)
+	}
+}
@@ -1,12 +1,19 @@
 package agent
 
 import (
+	"encoding/json"
 	"github.com/gorilla/websocket"
 	"github.com/kubespace/kubespace/pkg/informer"
+	"github.com/kubespace/kubespace/pkg/kubeagent"
+	kubetypes "github.com/kubespace/kubespace/pkg/kubernetes/types"
 	"k8s.io/klog/v2"
 	"sync"
+	"time"
 )
 
+// agentKeepaliveInterval 检查agent连接以及凭证的间隔
+const agentKeepaliveInterval = 30 * time.Second
+
 // agentTunnel kube-agent服务起来后发送连接请求，kubespace与agent建立websocket隧道
 // agent_informer监听到要发送的请求数据后，从隧道发出，随后kube-agent从隧道接收到请求并处理
 type agentTunnel struct {
@@ -14,6 +21,20 @@ type agentTunnel struct {
 	wsConn   *websocket.Conn
 	stopCh   chan struct{}
 	mu       *sync.Mutex
+	// keepalive 定期检查agent凭证是否有效并更新最近通信时间，返回错误时断开隧道
+	keepalive func() error
+	// onClose 隧道断开后调用，参数为断开原因
+	onClose     func(reason string)
+	closeReason string
+
+	// v2 agent支持v2协议时，请求以及返回都通过该隧道以帧的形式多路复用，否则agent通过单独的连接返回数据
+	v2 bool
+	// notify v2协议下收到agent返回的完整数据后通知请求方
+	notify func(traceId string, data []byte) error
+	// pending 拆分为多个帧的json数据，收到最后一个帧后通知
+	pending map[string][]byte
+	// windows 请求方取走数据后再返还的stream窗口
+	windows *streamWindows
 }
 
 func newAgentTunnel(wsConn *websocket.Conn, clusterAgentInformer informer.Informer) (*agentTunnel, error) {
@@ -22,6 +43,7 @@ func newAgentTunnel(wsConn *websocket.Conn, clusterAgentInformer informer.Inform
 		wsConn:   wsConn,
 		stopCh:   make(chan struct{}),
 		mu:       &sync.Mutex{},
+		pending:  make(map[string][]byte),
 	}
 	k.informer.AddHandler(k)
 	return k, nil
@@ -30,6 +52,10 @@ func newAgentTunnel(wsConn *websocket.Conn, clusterAgentInformer informer.Inform
 func (k *agentTunnel) Consume() {
 	go k.informer.Run(k.stopCh)
 	go k.consume()
+	go k.keepaliveLoop()
+	if k.v2 {
+		go k.windowLoop()
+	}
 }
 
 func (k *agentTunnel) Check(obj interface{}) bool {
@@ -39,20 +65,163 @@ func (k *agentTunnel) Check(obj interface{}) bool {
 // Handle 从informer收到消息，发送给agent
 func (k *agentTunnel) Handle(obj interface{}) error {
 	// websocket同时写会报错
+	if !k.v2 {
+		k.mu.Lock()
+		defer k.mu.Unlock()
+		return k.wsConn.WriteJSON(obj)
+	}
+	frame := &kubeagent.Frame{Type: kubeagent.FrameRequest}
+	if req, ok := obj.(kubetypes.Request); ok && req.Action == kubetypes.CloseSession {
+		// 关闭会话转为取消帧，agent停止会话并丢弃未发送的数据
+		frame = &kubeagent.Frame{Type: kubeagent.FrameCancel, StreamId: req.TraceId}
+		if k.windows != nil {
+			k.windows.remove(req.TraceId)
+		}
+	} else {
+		payload, err := json.Marshal(obj)
+		if err != nil {
+			return err
+		}
+		frame.Payload = payload
+		if req, ok := obj.(kubetypes.Request); ok {
+			frame.StreamId = req.TraceId
+		}
+	}
+	return k.writeFrame(frame)
+}
+
+func (k *agentTunnel) writeFrame(frame *kubeagent.Frame) error {
+	data, err := kubeagent.EncodeFrame(frame)
+	if err != nil {
+		return err
+	}
 	k.mu.Lock()
 	defer k.mu.Unlock()
-	return k.wsConn.WriteJSON(obj)
+	return k.wsConn.WriteMessage(websocket.BinaryMessage, data)
 }
 
 // 接收agent发送的信息
 func (k *agentTunnel) consume() {
 	for {
-		_, data, err := k.wsConn.ReadMessage()
+		msgType, data, err := k.wsConn.ReadMessage()
 		if err != nil {
 			klog.Error("read err:", err)
+			k.close(err.Error())
 			break
 		}
+		if k.v2 && msgType == websocket.BinaryMessage {
+			k.handleFrame(data)
+			continue
+		}
 		klog.V(1).Infof("read data: %s", string(data))
 	}
 	close(k.stopCh)
+	if k.onClose != nil {
+		k.mu.Lock()
+		reason := k.closeReason
+		k.mu.Unlock()
+		k.onClose(reason)
+	}
+}
+
+// handleFrame 处理agent返回的帧，数据通知请求方并且请求方取走数据后返还stream窗口
+func (k *agentTunnel) handleFrame(data []byte) {
+	frame, err := kubeagent.DecodeFrame(data)
+	if err != nil {
+		klog.Errorf("decode agent frame error: %s", err.Error())
+		return
+	}
+	switch frame.Type {
+	case kubeagent.FrameData:
+		buf := append(k.pending[frame.StreamId], frame.Payload...)
+		if !frame.Fin() {
+			k.pending[frame.StreamId] = buf
+			break
+		}
+		delete(k.pending, frame.StreamId)
+		k.notifyResponse(frame.StreamId, buf)
+	case kubeagent.FrameBinary:
+		k.notifyResponse(frame.StreamId, frame.Payload)
+	default:
+		klog.Warningf("unknown agent frame type %d of stream %s", frame.Type, frame.StreamId)
+		return
+	}
+	if len(frame.Payload) > 0 && k.windows != nil {
+		k.windows.add(frame.StreamId, len(frame.Payload))
+		k.returnWindow(frame.StreamId)
+	}
+}
+
+// returnWindow 请求方积压的数据低于阈值时返还stream窗口
+func (k *agentTunnel) returnWindow(streamId string) {
+	size := k.windows.release(streamId)
+	if size == 0 {
+		return
+	}
+	if err := k.writeFrame(kubeagent.WindowFrame(streamId, size)); err != nil {
+		klog.Warningf("write stream %s window frame error: %s", streamId, err.Error())
+	}
+}
+
+// windowLoop 定期检查暂缓返还窗口的stream，请求方取走数据后返还
+func (k *agentTunnel) windowLoop() {
+	ticker := time.NewTicker(windowRetryInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-k.stopCh:
+			return
+		case <-ticker.C:
+		}
+		for _, streamId := range k.windows.held() {
+			k.returnWindow(streamId)
+		}
+	}
+}
+
+func (k *agentTunnel) notifyResponse(traceId string, data []byte) {
+	if k.notify == nil {
+		return
+	}
+	if err := k.notify(traceId, data); err != nil {
+		klog.Errorf("notify response error: %s", err.Error())
+	}
+}
+
+// keepaliveLoop 定期向agent发送ping，并检查凭证是否被吊销或者过期
+func (k *agentTunnel) keepaliveLoop() {
+	ticker := time.NewTicker(agentKeepaliveInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-k.stopCh:
+			return
+		case <-ticker.C:
+		}
+		k.mu.Lock()
+		err := k.wsConn.WriteControl(websocket.PingMessage, nil, time.Now().Add(10*time.Second))
+		k.mu.Unlock()
+		if err != nil {
+			k.close("ping agent error: " + err.Error())
+			return
+		}
+		if k.keepalive == nil {
+			continue
+		}
+		if err = k.keepalive(); err != nil {
+			klog.Warningf("agent tunnel keepalive error: %s", err.Error())
+			k.close(err.Error())
+			return
+		}
+	}
+}
+
+// close 关闭隧道，只记录第一次关闭的原因
+func (k *agentTunnel) close(reason string) {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	if k.closeReason == "" {
+		k.closeReason = reason
+	}
+	k.wsConn.Close()
 }
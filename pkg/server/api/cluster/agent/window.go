@@ -0,0 +1,77 @@
+package agent
+
+import (
+	"k8s.io/klog/v2"
+	"sync"
+	"time"
+)
+
+const (
+	// maxStreamBacklog 请求方未取走的数据超过该条数时暂不返还stream窗口，agent发送的帧最大为32KB，
+	// 每个stream在redis中积压的数据不超过256KB
+	maxStreamBacklog = 8
+	// windowRetryInterval 暂缓返还的窗口重新检查请求方是否取走数据的间隔
+	windowRetryInterval = 100 * time.Millisecond
+)
+
+// streamWindows 隧道v2中待返还给agent的stream窗口，数据通知到redis后，请求方取走数据才返还窗口，
+// 避免请求方处理慢时agent持续发送的数据堆积在redis中
+type streamWindows struct {
+	mu      sync.Mutex
+	credits map[string]int
+	// pending 请求方还未取走的数据条数
+	pending func(streamId string) (int64, error)
+}
+
+func newStreamWindows(pending func(streamId string) (int64, error)) *streamWindows {
+	return &streamWindows{
+		credits: make(map[string]int),
+		pending: pending,
+	}
+}
+
+// add 记录stream已处理的数据大小
+func (w *streamWindows) add(streamId string, size int) {
+	if size <= 0 {
+		return
+	}
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.credits[streamId] += size
+}
+
+// release 请求方积压的数据低于阈值时返回可以返还的窗口大小，否则返回0继续暂缓
+func (w *streamWindows) release(streamId string) int {
+	if w.pending != nil {
+		n, err := w.pending(streamId)
+		if err != nil {
+			// 获取失败时直接返还，避免stream一直阻塞
+			klog.Warningf("get stream %s pending response error: %s", streamId, err.Error())
+		} else if n >= maxStreamBacklog {
+			return 0
+		}
+	}
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	size := w.credits[streamId]
+	delete(w.credits, streamId)
+	return size
+}
+
+// held 有待返还窗口的stream
+func (w *streamWindows) held() []string {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	var ids []string
+	for id := range w.credits {
+		ids = append(ids, id)
+	}
+	return ids
+}
+
+// remove stream取消后agent不再发送数据，丢弃待返还的窗口
+func (w *streamWindows) remove(streamId string) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	delete(w.credits, streamId)
+}
@@ -0,0 +1,65 @@
+package agent
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestStreamWindows(t *testing.T) {
+	backlog := map[string]int64{}
+	w := newStreamWindows(func(streamId string) (int64, error) {
+		return backlog[streamId], nil
+	})
+
+	w.add("a", 100)
+	w.add("a", 200)
+	w.add("b", 50)
+	w.add("c", 0)
+
+	// 请求方积压的数据超过阈值时暂缓返还，窗口继续累计
+	backlog["a"] = maxStreamBacklog
+	if size := w.release("a"); size != 0 {
+		t.Errorf("release a with backlog = %d, want 0", size)
+	}
+	w.add("a", 300)
+	if size := w.release("b"); size != 50 {
+		t.Errorf("release b = %d, want 50", size)
+	}
+	if held := w.held(); len(held) != 1 || held[0] != "a" {
+		t.Errorf("held = %v, want [a]", held)
+	}
+
+	// 请求方取走数据后返还累计的窗口
+	backlog["a"] = maxStreamBacklog - 1
+	if size := w.release("a"); size != 600 {
+		t.Errorf("release a = %d, want 600", size)
+	}
+	if size := w.release("a"); size != 0 {
+		t.Errorf("release a again = %d, want 0", size)
+	}
+	if held := w.held(); len(held) != 0 {
+		t.Errorf("held = %v, want empty", held)
+	}
+}
+
+func TestStreamWindowsRemove(t *testing.T) {
+	w := newStreamWindows(func(streamId string) (int64, error) {
+		return maxStreamBacklog, nil
+	})
+	w.add("a", 100)
+	w.remove("a")
+	if held := w.held(); len(held) != 0 {
+		t.Errorf("held = %v, want empty", held)
+	}
+}
+
+func TestStreamWindowsPendingError(t *testing.T) {
+	w := newStreamWindows(func(streamId string) (int64, error) {
+		return 0, errors.New("redis unavailable")
+	})
+	w.add("a", 100)
+	// 获取积压数据失败时直接返还，避免stream一直阻塞
+	if size := w.release("a"); size != 100 {
+		t.Errorf("release a = %d, want 100", size)
+	}
+}
@@ -21,12 +21,38 @@ func (a *apiGroup) Apis() []*api.Api {
 	apis := []*api.Api{
 		api.NewApi(http.MethodGet, "", cluster.ListHandler(a.config)),
 		api.NewApi(http.MethodPost, "", cluster.CreateHandler(a.config)),
+		api.NewApi(http.MethodPost, "/import", cluster.ImportHandler(a.config)),
+		api.NewApi(http.MethodPost, "/kubeconfig/contexts", cluster.KubeConfigContextsHandler(a.config)),
 		api.NewApi(http.MethodPut, "/:id", cluster.UpdateHandler(a.config)),
 		api.NewApi(http.MethodDelete, "/:id", cluster.DeleteHandler(a.config)),
+		api.NewApi(http.MethodPut, "/:id/impersonation", cluster.ImpersonationHandler(a.config)),
+		api.NewApi(http.MethodPut, "/:id/agent_profile", cluster.AgentProfileHandler(a.config)),
+		api.NewApi(http.MethodPost, "/:id/token/reissue", cluster.ReissueTokenHandler(a.config)),
+		api.NewApi(http.MethodPost, "/:id/token/revoke", cluster.RevokeTokenHandler(a.config)),
+		api.NewApi(http.MethodGet, "/:id/agent/connections", cluster.AgentConnectionsHandler(a.config)),
+		api.NewApi(http.MethodPut, "/:id/labels", cluster.LabelsHandler(a.config)),
+
+		// 集群组以及跨集群查询
+		api.NewApi(http.MethodGet, "/group", cluster.GroupListHandler(a.config)),
+		api.NewApi(http.MethodPost, "/group", cluster.GroupCreateHandler(a.config)),
+		api.NewApi(http.MethodPut, "/group/:id", cluster.GroupUpdateHandler(a.config)),
+		api.NewApi(http.MethodDelete, "/group/:id", cluster.GroupDeleteHandler(a.config)),
+		api.NewApi(http.MethodPost, "/fleet/list", cluster.FleetListHandler(a.config)),
+
+		// 集群健康检查
+		api.NewApi(http.MethodGet, "/health/overview", cluster.HealthOverviewHandler(a.config)),
+		api.NewApi(http.MethodGet, "/:id/health", cluster.HealthHandler(a.config)),
+		api.NewApi(http.MethodGet, "/:id/health/history", cluster.HealthHistoryHandler(a.config)),
+		api.NewApi(http.MethodPost, "/:id/health/probe", cluster.HealthProbeHandler(a.config)),
+		api.NewApi(http.MethodPut, "/:id/health/thresholds", cluster.HealthThresholdsHandler(a.config)),
+
+		// 集群归档事件
+		api.NewApi(http.MethodGet, "/:id/events", cluster.EventsHandler(a.config)),
 
 		// agent连接请求
 		api.NewApi(http.MethodGet, "/agent/connect", agent.ConnectHandler(a.config)),
 		api.NewApi(http.MethodGet, "/agent/response", agent.ResponseHandler(a.config)),
+		api.NewApi(http.MethodPost, "/agent/session", agent.SessionHandler(a.config)),
 
 		// 集群kubernetes资源操作
 		api.NewApi(http.MethodPost, "/:id/apply", resource.ApplyHandler(a.config)),
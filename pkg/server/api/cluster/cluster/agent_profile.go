@@ -0,0 +1,94 @@
+package cluster
+
+import (
+	"fmt"
+	"github.com/gin-gonic/gin/binding"
+	"github.com/kubespace/kubespace/pkg/core/code"
+	"github.com/kubespace/kubespace/pkg/core/errors"
+	kubetypes "github.com/kubespace/kubespace/pkg/kubernetes/types"
+	"github.com/kubespace/kubespace/pkg/model"
+	"github.com/kubespace/kubespace/pkg/model/types"
+	"github.com/kubespace/kubespace/pkg/server/api/api"
+	"github.com/kubespace/kubespace/pkg/server/config"
+	"github.com/kubespace/kubespace/pkg/utils"
+	"k8s.io/apimachinery/pkg/util/validation"
+	"strings"
+)
+
+type agentProfileHandler struct {
+	models *model.Models
+}
+
+// AgentProfileHandler 更新集群agent的权限配置，更新后需要重新执行导入命令，
+// 从admin切换到其他权限时，需要先删除之前绑定cluster-admin的ClusterRoleBinding kubespace-agent
+func AgentProfileHandler(conf *config.ServerConfig) api.Handler {
+	return &agentProfileHandler{models: conf.Models}
+}
+
+func (h *agentProfileHandler) Auth(c *api.Context) (bool, *api.AuthPerm, error) {
+	clusterId, err := utils.ParseUint(c.Param("id"))
+	if err != nil {
+		return true, nil, errors.New(code.ParamsError, err)
+	}
+	// agent权限决定了平台可以对集群执行的操作，需要有集群管理员权限
+	return true, &api.AuthPerm{
+		Scope:   types.ScopeCluster,
+		ScopeId: clusterId,
+		Role:    types.RoleAdmin,
+	}, nil
+}
+
+func (h *agentProfileHandler) Handle(c *api.Context) *utils.Response {
+	var ser types.ClusterAgentProfile
+	if err := c.ShouldBindBodyWith(&ser, binding.JSON); err != nil {
+		return c.ResponseError(errors.New(code.ParamsError, err))
+	}
+	if err := validateAgentProfile(&ser); err != nil {
+		return c.ResponseError(err)
+	}
+	clusterId, _ := utils.ParseUint(c.Param("id"))
+	clusterObj, err := h.models.ClusterManager.GetById(clusterId)
+	if err != nil {
+		return c.ResponseError(errors.New(code.DataNotExists, fmt.Sprintf("not found cluster id=%d", clusterId)))
+	}
+	err = h.models.ClusterManager.UpdateAgentProfile(clusterId, &ser)
+	if err != nil {
+		err = errors.New(code.DBError, err)
+	}
+	resp := c.Response(err, nil)
+
+	c.CreateAudit(&types.AuditOperate{
+		Operation:            types.AuditOperationUpdate,
+		OperateDetail:        fmt.Sprintf("更新集群%s的agent权限为%s", clusterObj.Name1, ser.Name),
+		Scope:                types.ScopeCluster,
+		ScopeId:              clusterObj.ID,
+		ScopeName:            clusterObj.Name1,
+		ResourceId:           clusterObj.ID,
+		ResourceType:         types.AuditResourceCluster,
+		ResourceName:         clusterObj.Name1,
+		Code:                 resp.Code,
+		Message:              resp.Msg,
+		OperateDataInterface: ser,
+	})
+	return resp
+}
+
+// validateAgentProfile 校验agent权限配置，namespace权限需要指定合法的命名空间
+func validateAgentProfile(profile *types.ClusterAgentProfile) error {
+	switch profile.Name {
+	case kubetypes.AgentProfileAdmin, kubetypes.AgentProfileReadOnly:
+		profile.Namespaces = nil
+		return nil
+	case kubetypes.AgentProfileNamespace:
+		if len(profile.Namespaces) == 0 {
+			return errors.New(code.ParamsError, "namespace权限需要指定agent可以操作的命名空间")
+		}
+		for _, ns := range profile.Namespaces {
+			if errs := validation.IsDNS1123Label(ns); len(errs) > 0 {
+				return errors.New(code.ParamsError, fmt.Sprintf("命名空间%s不合法：%s", ns, strings.Join(errs, ", ")))
+			}
+		}
+		return nil
+	}
+	return errors.New(code.ParamsError, fmt.Sprintf("不支持的agent权限：%s，只支持admin/namespace/readonly", profile.Name))
+}
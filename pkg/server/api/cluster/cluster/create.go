@@ -18,6 +18,10 @@ type createHandler struct {
 type createClusterBody struct {
 	Name    string   `json:"name"`
 	Members []string `json:"members"`
+	// Labels 集群标签，用于集群组以及跨集群查询
+	Labels types.ClusterLabels `json:"labels"`
+	// AgentProfile agent的权限配置，为空时agent绑定cluster-admin
+	AgentProfile *types.ClusterAgentProfile `json:"agent_profile"`
 }
 
 func CreateHandler(conf *config.ServerConfig) api.Handler {
@@ -41,14 +45,24 @@ func (h *createHandler) Handle(c *api.Context) *utils.Response {
 	if body.Name == "" {
 		return c.ResponseError(errors.New(code.ParamsError, "cluster name is blank"))
 	}
+	if err := validateCreateLabels(c, h.models, body.Labels); err != nil {
+		return c.ResponseError(err)
+	}
+	if body.AgentProfile != nil {
+		if err := validateAgentProfile(body.AgentProfile); err != nil {
+			return c.ResponseError(err)
+		}
+	}
 	clusterObj := &types.Cluster{
-		Name1:      body.Name,
-		Token:      utils.ShortUUID(),
-		Status:     types.ClusterPending,
-		CreatedBy:  c.User.Name,
-		Members:    body.Members,
-		CreateTime: time.Now(),
-		UpdateTime: time.Now(),
+		Name1:        body.Name,
+		Token:        utils.ShortUUID(),
+		Status:       types.ClusterPending,
+		CreatedBy:    c.User.Name,
+		Members:      body.Members,
+		Labels:       body.Labels,
+		AgentProfile: body.AgentProfile,
+		CreateTime:   time.Now(),
+		UpdateTime:   time.Now(),
 	}
 	err := h.models.ClusterManager.Create(clusterObj)
 	if err != nil {
@@ -0,0 +1,41 @@
+package cluster
+
+import (
+	"github.com/kubespace/kubespace/pkg/core/code"
+	"github.com/kubespace/kubespace/pkg/core/errors"
+	"github.com/kubespace/kubespace/pkg/model"
+	clustermgr "github.com/kubespace/kubespace/pkg/model/manager/cluster"
+	"github.com/kubespace/kubespace/pkg/model/types"
+	"github.com/kubespace/kubespace/pkg/server/api/api"
+	"github.com/kubespace/kubespace/pkg/server/config"
+	"github.com/kubespace/kubespace/pkg/utils"
+)
+
+type eventsHandler struct {
+	models *model.Models
+}
+
+// EventsHandler 查询集群归档的事件，可以按命名空间、关联资源、原因、类型以及时间范围过滤
+func EventsHandler(conf *config.ServerConfig) api.Handler {
+	return &eventsHandler{models: conf.Models}
+}
+
+func (h *eventsHandler) Auth(c *api.Context) (bool, *api.AuthPerm, error) {
+	return clusterRoleAuth(c, types.RoleViewer)
+}
+
+func (h *eventsHandler) Handle(c *api.Context) *utils.Response {
+	var cond clustermgr.ClusterEventListCondition
+	if err := c.ShouldBindQuery(&cond); err != nil {
+		return c.ResponseError(errors.New(code.ParamsError, err))
+	}
+	cond.ClusterId, _ = utils.ParseUint(c.Param("id"))
+	events, page, err := h.models.ClusterManager.ListEvents(&cond)
+	if err != nil {
+		return c.ResponseError(errors.New(code.DBError, err))
+	}
+	return c.ResponseOK(map[string]interface{}{
+		"data":       events,
+		"pagination": page,
+	})
+}
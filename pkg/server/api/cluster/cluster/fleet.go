@@ -0,0 +1,36 @@
+package cluster
+
+import (
+	"github.com/gin-gonic/gin/binding"
+	"github.com/kubespace/kubespace/pkg/core/code"
+	"github.com/kubespace/kubespace/pkg/core/errors"
+	"github.com/kubespace/kubespace/pkg/server/api/api"
+	"github.com/kubespace/kubespace/pkg/server/config"
+	"github.com/kubespace/kubespace/pkg/service/cluster"
+	"github.com/kubespace/kubespace/pkg/utils"
+)
+
+type fleetListHandler struct {
+	fleetService *cluster.FleetService
+}
+
+// FleetListHandler 跨集群查询资源，只查询用户有查看权限的集群，并以用户身份访问集群
+func FleetListHandler(conf *config.ServerConfig) api.Handler {
+	return &fleetListHandler{fleetService: conf.ServiceFactory.Cluster.FleetService}
+}
+
+func (h *fleetListHandler) Auth(c *api.Context) (bool, *api.AuthPerm, error) {
+	return true, nil, nil
+}
+
+func (h *fleetListHandler) Handle(c *api.Context) *utils.Response {
+	var query cluster.FleetQuery
+	if err := c.ShouldBindBodyWith(&query, binding.JSON); err != nil {
+		return c.ResponseError(errors.New(code.ParamsError, err))
+	}
+	res, err := h.fleetService.List(c.User, &query)
+	if err != nil {
+		return c.ResponseError(errors.New(code.ParamsError, err))
+	}
+	return c.ResponseOK(res)
+}
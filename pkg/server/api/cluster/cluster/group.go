@@ -0,0 +1,226 @@
+package cluster
+
+import (
+	"fmt"
+	"github.com/gin-gonic/gin/binding"
+	"github.com/kubespace/kubespace/pkg/core/code"
+	"github.com/kubespace/kubespace/pkg/core/errors"
+	"github.com/kubespace/kubespace/pkg/model"
+	clustermgr "github.com/kubespace/kubespace/pkg/model/manager/cluster"
+	"github.com/kubespace/kubespace/pkg/model/types"
+	"github.com/kubespace/kubespace/pkg/server/api/api"
+	"github.com/kubespace/kubespace/pkg/server/config"
+	"github.com/kubespace/kubespace/pkg/utils"
+)
+
+type groupBody struct {
+	Name        string `json:"name"`
+	Description string `json:"description"`
+	Selector    string `json:"selector"`
+}
+
+// platformAdminAuth 集群标签以及集群组选择器决定了集群组角色作用的集群，修改后相当于授予集群权限，
+// 需要有平台管理员权限，与直接授予集群角色需要集群管理员权限一致
+func platformAdminAuth() (bool, *api.AuthPerm, error) {
+	return true, &api.AuthPerm{
+		Scope:   types.ScopePlatform,
+		ScopeId: 0,
+		Role:    types.RoleAdmin,
+	}, nil
+}
+
+// groupClusterIds 集群组当前包含的集群id
+func groupClusterIds(models *model.Models, group *types.ClusterGroup) []uint {
+	clusters, err := models.ClusterManager.GroupClusters(group)
+	if err != nil {
+		return nil
+	}
+	ids := []uint{}
+	for _, c := range clusters {
+		ids = append(ids, c.ID)
+	}
+	return ids
+}
+
+type groupListHandler struct {
+	models *model.Models
+}
+
+// GroupListHandler 集群组列表以及每个组当前包含的集群
+func GroupListHandler(conf *config.ServerConfig) api.Handler {
+	return &groupListHandler{models: conf.Models}
+}
+
+func (h *groupListHandler) Auth(c *api.Context) (bool, *api.AuthPerm, error) {
+	return true, nil, nil
+}
+
+func (h *groupListHandler) Handle(c *api.Context) *utils.Response {
+	groups, err := h.models.ClusterManager.ListGroups()
+	if err != nil {
+		return c.ResponseError(errors.New(code.DBError, err))
+	}
+	var data []map[string]interface{}
+	for _, group := range groups {
+		data = append(data, map[string]interface{}{
+			"id":          group.ID,
+			"name":        group.Name,
+			"description": group.Description,
+			"selector":    group.Selector,
+			"cluster_ids": groupClusterIds(h.models, group),
+			"create_user": group.CreateUser,
+			"update_user": group.UpdateUser,
+			"create_time": group.CreateTime,
+			"update_time": group.UpdateTime,
+		})
+	}
+	return c.ResponseOK(data)
+}
+
+type groupCreateHandler struct {
+	models *model.Models
+}
+
+func GroupCreateHandler(conf *config.ServerConfig) api.Handler {
+	return &groupCreateHandler{models: conf.Models}
+}
+
+func (h *groupCreateHandler) Auth(c *api.Context) (bool, *api.AuthPerm, error) {
+	return platformAdminAuth()
+}
+
+func (h *groupCreateHandler) Handle(c *api.Context) *utils.Response {
+	var body groupBody
+	if err := c.ShouldBindBodyWith(&body, binding.JSON); err != nil {
+		return c.ResponseError(errors.New(code.ParamsError, err))
+	}
+	if body.Name == "" {
+		return c.ResponseError(errors.New(code.ParamsError, "集群组名称不能为空"))
+	}
+	if _, err := clustermgr.ParseSelector(body.Selector); err != nil {
+		return c.ResponseError(errors.New(code.ParamsError, err))
+	}
+	group := &types.ClusterGroup{
+		Name:        body.Name,
+		Description: body.Description,
+		Selector:    body.Selector,
+		CreateUser:  c.User.Name,
+		UpdateUser:  c.User.Name,
+	}
+	var err error
+	if err = h.models.ClusterManager.CreateGroup(group); err != nil {
+		err = errors.New(code.CreateError, "创建集群组失败："+err.Error())
+	}
+	resp := c.Response(err, group)
+	c.CreateAudit(&types.AuditOperate{
+		Operation:            types.AuditOperationCreate,
+		OperateDetail:        fmt.Sprintf("创建集群组：%s", group.Name),
+		Scope:                types.ScopeClusterGroup,
+		ScopeId:              group.ID,
+		ScopeName:            group.Name,
+		ResourceId:           group.ID,
+		ResourceType:         types.AuditResourceClusterGroup,
+		ResourceName:         group.Name,
+		Code:                 resp.Code,
+		Message:              resp.Msg,
+		OperateDataInterface: body,
+	})
+	return resp
+}
+
+type groupUpdateHandler struct {
+	models *model.Models
+}
+
+func GroupUpdateHandler(conf *config.ServerConfig) api.Handler {
+	return &groupUpdateHandler{models: conf.Models}
+}
+
+func (h *groupUpdateHandler) Auth(c *api.Context) (bool, *api.AuthPerm, error) {
+	return platformAdminAuth()
+}
+
+// Handle 更新集群组，修改选择器会改变组内的集群，集群组的用户角色随之生效
+func (h *groupUpdateHandler) Handle(c *api.Context) *utils.Response {
+	var body groupBody
+	if err := c.ShouldBindBodyWith(&body, binding.JSON); err != nil {
+		return c.ResponseError(errors.New(code.ParamsError, err))
+	}
+	id, err := utils.ParseUint(c.Param("id"))
+	if err != nil {
+		return c.ResponseError(errors.New(code.ParamsError, err))
+	}
+	group, err := h.models.ClusterManager.GetGroup(id)
+	if err != nil {
+		return c.ResponseError(errors.New(code.DataNotExists, "获取集群组失败："+err.Error()))
+	}
+	if body.Name == "" {
+		return c.ResponseError(errors.New(code.ParamsError, "集群组名称不能为空"))
+	}
+	if _, err = clustermgr.ParseSelector(body.Selector); err != nil {
+		return c.ResponseError(errors.New(code.ParamsError, err))
+	}
+	group.Name = body.Name
+	group.Description = body.Description
+	group.Selector = body.Selector
+	group.UpdateUser = c.User.Name
+	if err = h.models.ClusterManager.SaveGroup(group); err != nil {
+		err = errors.New(code.UpdateError, "更新集群组失败："+err.Error())
+	}
+	resp := c.Response(err, group)
+	c.CreateAudit(&types.AuditOperate{
+		Operation:            types.AuditOperationUpdate,
+		OperateDetail:        fmt.Sprintf("更新集群组：%s", group.Name),
+		Scope:                types.ScopeClusterGroup,
+		ScopeId:              group.ID,
+		ScopeName:            group.Name,
+		ResourceId:           group.ID,
+		ResourceType:         types.AuditResourceClusterGroup,
+		ResourceName:         group.Name,
+		Code:                 resp.Code,
+		Message:              resp.Msg,
+		OperateDataInterface: body,
+	})
+	return resp
+}
+
+type groupDeleteHandler struct {
+	models *model.Models
+}
+
+func GroupDeleteHandler(conf *config.ServerConfig) api.Handler {
+	return &groupDeleteHandler{models: conf.Models}
+}
+
+func (h *groupDeleteHandler) Auth(c *api.Context) (bool, *api.AuthPerm, error) {
+	return platformAdminAuth()
+}
+
+func (h *groupDeleteHandler) Handle(c *api.Context) *utils.Response {
+	id, err := utils.ParseUint(c.Param("id"))
+	if err != nil {
+		return c.ResponseError(errors.New(code.ParamsError, err))
+	}
+	group, err := h.models.ClusterManager.GetGroup(id)
+	if err != nil {
+		return c.ResponseError(errors.New(code.DataNotExists, "获取集群组失败："+err.Error()))
+	}
+	if err = h.models.ClusterManager.DeleteGroup(group.ID); err != nil {
+		err = errors.New(code.DeleteError, "删除集群组失败："+err.Error())
+	}
+	resp := c.ResponseError(err)
+	c.CreateAudit(&types.AuditOperate{
+		Operation:            types.AuditOperationDelete,
+		OperateDetail:        fmt.Sprintf("删除集群组：%s", group.Name),
+		Scope:                types.ScopeClusterGroup,
+		ScopeId:              group.ID,
+		ScopeName:            group.Name,
+		ResourceId:           group.ID,
+		ResourceType:         types.AuditResourceClusterGroup,
+		ResourceName:         group.Name,
+		Code:                 resp.Code,
+		Message:              resp.Msg,
+		OperateDataInterface: nil,
+	})
+	return resp
+}
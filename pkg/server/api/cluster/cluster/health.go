@@ -0,0 +1,237 @@
+package cluster
+
+import (
+	"fmt"
+	"github.com/gin-gonic/gin/binding"
+	"github.com/kubespace/kubespace/pkg/core/code"
+	"github.com/kubespace/kubespace/pkg/core/errors"
+	"github.com/kubespace/kubespace/pkg/model"
+	clustermgr "github.com/kubespace/kubespace/pkg/model/manager/cluster"
+	"github.com/kubespace/kubespace/pkg/model/types"
+	"github.com/kubespace/kubespace/pkg/server/api/api"
+	"github.com/kubespace/kubespace/pkg/server/config"
+	"github.com/kubespace/kubespace/pkg/service/cluster"
+	"github.com/kubespace/kubespace/pkg/utils"
+	"time"
+)
+
+// clusterHealth 集群健康状态以及当前生效的阈值
+type clusterHealth struct {
+	ClusterId       uint                           `json:"cluster_id"`
+	ClusterName     string                         `json:"cluster_name"`
+	HealthStatus    string                         `json:"health_status"`
+	HealthCheckTime *time.Time                     `json:"health_check_time"`
+	Thresholds      *types.ClusterHealthThresholds `json:"thresholds"`
+	Latest          *types.ClusterHealth           `json:"latest"`
+}
+
+func newClusterHealth(models *model.Models, clusterObj *types.Cluster) (*clusterHealth, error) {
+	latest, err := models.ClusterManager.GetLatestHealth(clusterObj.ID)
+	if err != nil {
+		return nil, err
+	}
+	return &clusterHealth{
+		ClusterId:       clusterObj.ID,
+		ClusterName:     clusterObj.Name1,
+		HealthStatus:    clusterObj.HealthStatus,
+		HealthCheckTime: clusterObj.HealthCheckTime,
+		Thresholds:      clusterObj.Thresholds(),
+		Latest:          latest,
+	}, nil
+}
+
+func clusterRoleAuth(c *api.Context, role string) (bool, *api.AuthPerm, error) {
+	clusterId, err := utils.ParseUint(c.Param("id"))
+	if err != nil {
+		return true, nil, errors.New(code.ParamsError, err)
+	}
+	return true, &api.AuthPerm{
+		Scope:   types.ScopeCluster,
+		ScopeId: clusterId,
+		Role:    role,
+	}, nil
+}
+
+type healthHandler struct {
+	models *model.Models
+}
+
+// HealthHandler 获取集群最近一次的健康检查结果
+func HealthHandler(conf *config.ServerConfig) api.Handler {
+	return &healthHandler{models: conf.Models}
+}
+
+func (h *healthHandler) Auth(c *api.Context) (bool, *api.AuthPerm, error) {
+	return clusterRoleAuth(c, types.RoleViewer)
+}
+
+func (h *healthHandler) Handle(c *api.Context) *utils.Response {
+	clusterId, _ := utils.ParseUint(c.Param("id"))
+	clusterObj, err := h.models.ClusterManager.GetById(clusterId)
+	if err != nil {
+		return c.ResponseError(errors.New(code.DataNotExists, fmt.Sprintf("not found cluster id=%d", clusterId)))
+	}
+	data, err := newClusterHealth(h.models, clusterObj)
+	if err != nil {
+		return c.ResponseError(errors.New(code.DBError, err))
+	}
+	return c.ResponseOK(data)
+}
+
+type healthHistoryHandler struct {
+	models *model.Models
+}
+
+// HealthHistoryHandler 获取集群的健康检查历史，hours为最近的小时数，默认24小时
+func HealthHistoryHandler(conf *config.ServerConfig) api.Handler {
+	return &healthHistoryHandler{models: conf.Models}
+}
+
+func (h *healthHistoryHandler) Auth(c *api.Context) (bool, *api.AuthPerm, error) {
+	return clusterRoleAuth(c, types.RoleViewer)
+}
+
+func (h *healthHistoryHandler) Handle(c *api.Context) *utils.Response {
+	clusterId, _ := utils.ParseUint(c.Param("id"))
+	hours, _ := utils.ParseUint(c.Query("hours"))
+	if hours == 0 {
+		hours = 24
+	}
+	since := time.Now().Add(-time.Duration(hours) * time.Hour)
+	healths, err := h.models.ClusterManager.ListHealth(clusterId, since, 2000)
+	if err != nil {
+		return c.ResponseError(errors.New(code.DBError, err))
+	}
+	return c.ResponseOK(healths)
+}
+
+type healthProbeHandler struct {
+	models        *model.Models
+	healthService *cluster.HealthService
+}
+
+// HealthProbeHandler 立即对集群进行健康检查
+func HealthProbeHandler(conf *config.ServerConfig) api.Handler {
+	return &healthProbeHandler{
+		models:        conf.Models,
+		healthService: conf.ServiceFactory.Cluster.HealthService,
+	}
+}
+
+func (h *healthProbeHandler) Auth(c *api.Context) (bool, *api.AuthPerm, error) {
+	return clusterRoleAuth(c, types.RoleEditor)
+}
+
+func (h *healthProbeHandler) Handle(c *api.Context) *utils.Response {
+	clusterId, _ := utils.ParseUint(c.Param("id"))
+	clusterObj, err := h.models.ClusterManager.GetById(clusterId)
+	if err != nil {
+		return c.ResponseError(errors.New(code.DataNotExists, fmt.Sprintf("not found cluster id=%d", clusterId)))
+	}
+	health, err := h.healthService.Probe(clusterObj)
+	if err != nil {
+		return c.ResponseError(errors.New(code.DBError, err))
+	}
+	return c.ResponseOK(health)
+}
+
+type healthThresholdsHandler struct {
+	models *model.Models
+}
+
+// HealthThresholdsHandler 更新集群健康检查阈值，阈值为0时使用默认值
+func HealthThresholdsHandler(conf *config.ServerConfig) api.Handler {
+	return &healthThresholdsHandler{models: conf.Models}
+}
+
+func (h *healthThresholdsHandler) Auth(c *api.Context) (bool, *api.AuthPerm, error) {
+	return clusterRoleAuth(c, types.RoleAdmin)
+}
+
+func (h *healthThresholdsHandler) Handle(c *api.Context) *utils.Response {
+	var ser types.ClusterHealthThresholds
+	if err := c.ShouldBindBodyWith(&ser, binding.JSON); err != nil {
+		return c.ResponseError(errors.New(code.ParamsError, err))
+	}
+	clusterId, _ := utils.ParseUint(c.Param("id"))
+	clusterObj, err := h.models.ClusterManager.GetById(clusterId)
+	if err != nil {
+		return c.ResponseError(errors.New(code.DataNotExists, fmt.Sprintf("not found cluster id=%d", clusterId)))
+	}
+	clusterObj.HealthThresholds = &ser
+	if err = validateHealthThresholds(clusterObj.Thresholds()); err != nil {
+		return c.ResponseError(err)
+	}
+	err = h.models.ClusterManager.UpdateHealthThresholds(clusterId, &ser)
+	if err != nil {
+		err = errors.New(code.DBError, err)
+	}
+	resp := c.Response(err, clusterObj.Thresholds())
+
+	c.CreateAudit(&types.AuditOperate{
+		Operation:            types.AuditOperationUpdate,
+		OperateDetail:        fmt.Sprintf("更新集群%s的健康检查阈值", clusterObj.Name1),
+		Scope:                types.ScopeCluster,
+		ScopeId:              clusterObj.ID,
+		ScopeName:            clusterObj.Name1,
+		ResourceId:           clusterObj.ID,
+		ResourceType:         types.AuditResourceCluster,
+		ResourceName:         clusterObj.Name1,
+		Code:                 resp.Code,
+		Message:              resp.Msg,
+		OperateDataInterface: ser,
+	})
+	return resp
+}
+
+// validateHealthThresholds critical阈值要比warning阈值更严重
+func validateHealthThresholds(t *types.ClusterHealthThresholds) error {
+	if t.ApiserverLatencyWarning > t.ApiserverLatencyCritical {
+		return errors.New(code.ParamsError, "apiserver耗时的warning阈值不能大于critical阈值")
+	}
+	if t.NotReadyNodesWarning > t.NotReadyNodesCritical {
+		return errors.New(code.ParamsError, "未就绪节点数的warning阈值不能大于critical阈值")
+	}
+	if t.FailingPodsWarning > t.FailingPodsCritical {
+		return errors.New(code.ParamsError, "异常pod数的warning阈值不能大于critical阈值")
+	}
+	if t.WarningEventsWarning > t.WarningEventsCritical {
+		return errors.New(code.ParamsError, "warning事件数的warning阈值不能大于critical阈值")
+	}
+	if t.CertExpiryWarningDays < t.CertExpiryCriticalDays {
+		return errors.New(code.ParamsError, "证书剩余天数的warning阈值不能小于critical阈值")
+	}
+	return nil
+}
+
+type healthOverviewHandler struct {
+	models *model.Models
+}
+
+// HealthOverviewHandler 获取用户有权限查看的所有集群的健康状态
+func HealthOverviewHandler(conf *config.ServerConfig) api.Handler {
+	return &healthOverviewHandler{models: conf.Models}
+}
+
+func (h *healthOverviewHandler) Auth(c *api.Context) (bool, *api.AuthPerm, error) {
+	return true, nil, nil
+}
+
+func (h *healthOverviewHandler) Handle(c *api.Context) *utils.Response {
+	clusters, err := h.models.ClusterManager.List(clustermgr.ListClusterCondition{})
+	if err != nil {
+		return c.ResponseError(errors.New(code.DBError, err))
+	}
+	var data []*clusterHealth
+	for _, clusterObj := range clusters {
+		if !h.models.UserRoleManager.AuthRole(c.User, types.ScopeCluster, clusterObj.ID, types.RoleViewer) {
+			continue
+		}
+		health, err := newClusterHealth(h.models, clusterObj)
+		if err != nil {
+			return c.ResponseError(errors.New(code.DBError, err))
+		}
+		data = append(data, health)
+	}
+	return c.ResponseOK(data)
+}
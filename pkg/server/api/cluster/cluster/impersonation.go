@@ -0,0 +1,101 @@
+package cluster
+
+import (
+	"fmt"
+	"github.com/gin-gonic/gin/binding"
+	"github.com/kubespace/kubespace/pkg/core/code"
+	"github.com/kubespace/kubespace/pkg/core/errors"
+	"github.com/kubespace/kubespace/pkg/model"
+	"github.com/kubespace/kubespace/pkg/model/types"
+	"github.com/kubespace/kubespace/pkg/server/api/api"
+	"github.com/kubespace/kubespace/pkg/server/config"
+	"github.com/kubespace/kubespace/pkg/utils"
+)
+
+type impersonationHandler struct {
+	models *model.Models
+}
+
+// ImpersonationHandler 更新集群的用户模拟配置，开启后用户访问集群时以映射的k8s用户以及用户组身份访问
+func ImpersonationHandler(conf *config.ServerConfig) api.Handler {
+	return &impersonationHandler{models: conf.Models}
+}
+
+func (h *impersonationHandler) Auth(c *api.Context) (bool, *api.AuthPerm, error) {
+	clusterId, err := utils.ParseUint(c.Param("id"))
+	if err != nil {
+		return true, nil, errors.New(code.ParamsError, err)
+	}
+	// 用户模拟配置决定了用户在集群中的权限，需要有集群管理员权限
+	return true, &api.AuthPerm{
+		Scope:   types.ScopeCluster,
+		ScopeId: clusterId,
+		Role:    types.RoleAdmin,
+	}, nil
+}
+
+func (h *impersonationHandler) Handle(c *api.Context) *utils.Response {
+	var ser types.ClusterImpersonation
+	if err := c.ShouldBindBodyWith(&ser, binding.JSON); err != nil {
+		return c.ResponseError(errors.New(code.ParamsError, err))
+	}
+	for role := range ser.RoleGroups {
+		if role != types.RoleAdmin && role != types.RoleEditor && role != types.RoleViewer {
+			return c.ResponseError(errors.New(code.ParamsError, fmt.Sprintf("不支持的角色：%s", role)))
+		}
+	}
+	if err := validateImpersonationGroups(&ser); err != nil {
+		return c.ResponseError(errors.New(code.ParamsError, err))
+	}
+	clusterId, _ := utils.ParseUint(c.Param("id"))
+	clusterObj, err := h.models.ClusterManager.GetById(clusterId)
+	if err != nil {
+		return c.ResponseError(errors.New(code.DataNotExists, fmt.Sprintf("not found cluster id=%d", clusterId)))
+	}
+	// 已连接的agent不支持用户模拟时，需要先升级agent
+	if ser.Enabled && clusterObj.KubeConfig == "" && clusterObj.AgentLastSeen != nil && !clusterObj.AgentSupportImpersonate() {
+		return c.ResponseError(errors.New(code.ParamsError, "集群agent版本过旧，不支持用户模拟，请先重新导入升级agent"))
+	}
+	err = h.models.ClusterManager.UpdateImpersonation(clusterId, &ser)
+	if err != nil {
+		err = errors.New(code.DBError, err)
+	}
+	resp := c.Response(err, nil)
+
+	detail := "关闭集群用户模拟：" + clusterObj.Name1
+	if ser.Enabled {
+		detail = "开启集群用户模拟：" + clusterObj.Name1
+	}
+	c.CreateAudit(&types.AuditOperate{
+		Operation:            types.AuditOperationUpdate,
+		OperateDetail:        detail,
+		Scope:                types.ScopeCluster,
+		ScopeId:              clusterObj.ID,
+		ScopeName:            clusterObj.Name1,
+		ResourceId:           clusterObj.ID,
+		ResourceType:         types.AuditResourceCluster,
+		ResourceName:         clusterObj.Name1,
+		Code:                 resp.Code,
+		Message:              resp.Msg,
+		OperateDataInterface: ser,
+	})
+	return resp
+}
+
+// validateImpersonationGroups 映射的用户组不能为system:masters，该用户组绕过集群所有鉴权，
+// 受限权限的agent会被授予模拟映射用户组的权限
+func validateImpersonationGroups(ser *types.ClusterImpersonation) error {
+	groups := append([]string{}, ser.Groups...)
+	for _, g := range ser.RoleGroups {
+		groups = append(groups, g...)
+	}
+	for _, g := range ser.UserGroups {
+		groups = append(groups, g...)
+	}
+	for _, g := range groups {
+		if g == "system:masters" {
+			return fmt.Errorf("不能映射到用户组system:masters")
+		}
+	}
+	return nil
+}
@@ -0,0 +1,147 @@
+package cluster
+
+import (
+	"github.com/gin-gonic/gin/binding"
+	"github.com/kubespace/kubespace/pkg/core/code"
+	"github.com/kubespace/kubespace/pkg/core/errors"
+	"github.com/kubespace/kubespace/pkg/model"
+	"github.com/kubespace/kubespace/pkg/model/types"
+	"github.com/kubespace/kubespace/pkg/server/api/api"
+	"github.com/kubespace/kubespace/pkg/server/config"
+	clusterservice "github.com/kubespace/kubespace/pkg/service/cluster"
+	"github.com/kubespace/kubespace/pkg/utils"
+	"strings"
+	"time"
+)
+
+type kubeConfigBody struct {
+	KubeConfig string `json:"kubeconfig"`
+	// Context 导入的context，为空时使用kubeconfig的current-context
+	Context string `json:"context"`
+}
+
+// checkKubeConfig 选择context并检查集群连通性以及权限，缺少必须的权限时返回错误
+func checkKubeConfig(body *kubeConfigBody) (string, *clusterservice.KubeConfigCheck, error) {
+	kubeConfig, err := clusterservice.SelectKubeConfigContext(body.KubeConfig, body.Context)
+	if err != nil {
+		return "", nil, errors.New(code.ParamsError, err)
+	}
+	check, err := clusterservice.CheckKubeConfig(kubeConfig)
+	if err != nil {
+		return "", nil, errors.New(code.RequestError, err)
+	}
+	if missing := check.MissingRequired(); len(missing) > 0 {
+		return "", check, errors.New(code.ParamsError, "kubeconfig缺少必须的权限："+strings.Join(missing, ", "))
+	}
+	return kubeConfig, check, nil
+}
+
+type kubeConfigContextsHandler struct{}
+
+// KubeConfigContextsHandler 解析kubeconfig，返回可以导入的context
+func KubeConfigContextsHandler(conf *config.ServerConfig) api.Handler {
+	return &kubeConfigContextsHandler{}
+}
+
+func (h *kubeConfigContextsHandler) Auth(c *api.Context) (bool, *api.AuthPerm, error) {
+	return true, &api.AuthPerm{
+		Scope:   types.ScopePlatform,
+		ScopeId: 0,
+		Role:    types.RoleEditor,
+	}, nil
+}
+
+func (h *kubeConfigContextsHandler) Handle(c *api.Context) *utils.Response {
+	var body kubeConfigBody
+	if err := c.ShouldBindBodyWith(&body, binding.JSON); err != nil {
+		return c.ResponseError(errors.New(code.ParamsError, err))
+	}
+	contexts, err := clusterservice.KubeConfigContexts(body.KubeConfig)
+	if err != nil {
+		return c.ResponseError(errors.New(code.ParamsError, err))
+	}
+	return c.ResponseOK(contexts)
+}
+
+type importHandler struct {
+	models *model.Models
+}
+
+type importClusterBody struct {
+	kubeConfigBody
+	Name    string   `json:"name"`
+	Members []string `json:"members"`
+	// Labels 集群标签，用于集群组以及跨集群查询
+	Labels types.ClusterLabels `json:"labels"`
+	// DryRun 只检查kubeconfig，不创建集群
+	DryRun bool `json:"dry_run"`
+}
+
+// ImportHandler 通过kubeconfig导入集群，导入前检查集群连通性以及权限
+func ImportHandler(conf *config.ServerConfig) api.Handler {
+	return &importHandler{models: conf.Models}
+}
+
+func (h *importHandler) Auth(c *api.Context) (bool, *api.AuthPerm, error) {
+	// 创建集群需要有平台编辑权限
+	return true, &api.AuthPerm{
+		Scope:   types.ScopePlatform,
+		ScopeId: 0,
+		Role:    types.RoleEditor,
+	}, nil
+}
+
+func (h *importHandler) Handle(c *api.Context) *utils.Response {
+	var body importClusterBody
+	if err := c.ShouldBindBodyWith(&body, binding.JSON); err != nil {
+		return c.ResponseError(errors.New(code.ParamsError, err))
+	}
+	if body.Name == "" && !body.DryRun {
+		return c.ResponseError(errors.New(code.ParamsError, "cluster name is blank"))
+	}
+	if err := validateCreateLabels(c, h.models, body.Labels); err != nil {
+		return c.ResponseError(err)
+	}
+	kubeConfig, check, err := checkKubeConfig(&body.kubeConfigBody)
+	if err != nil || body.DryRun {
+		return c.Response(err, check)
+	}
+	clusterObj := &types.Cluster{
+		Name1:      body.Name,
+		KubeConfig: kubeConfig,
+		Token:      utils.ShortUUID(),
+		Status:     types.ClusterConnect,
+		CreatedBy:  c.User.Name,
+		Members:    body.Members,
+		Labels:     body.Labels,
+		CreateTime: time.Now(),
+		UpdateTime: time.Now(),
+	}
+	if err = h.models.ClusterManager.Create(clusterObj); err != nil {
+		err = errors.New(code.CreateError, err)
+	}
+	resp := c.Response(err, map[string]interface{}{
+		"cluster": clusterObj,
+		"check":   check,
+	})
+	c.CreateAudit(&types.AuditOperate{
+		Operation:     types.AuditOperationImport,
+		OperateDetail: "通过kubeconfig导入集群：" + clusterObj.Name1,
+		Scope:         types.ScopeCluster,
+		ScopeId:       clusterObj.ID,
+		ScopeName:     clusterObj.Name1,
+		ResourceId:    clusterObj.ID,
+		ResourceType:  types.AuditResourceCluster,
+		ResourceName:  clusterObj.Name1,
+		Code:          resp.Code,
+		Message:       resp.Msg,
+		// 不记录kubeconfig中的凭证
+		OperateDataInterface: map[string]interface{}{
+			"name":    body.Name,
+			"members": body.Members,
+			"context": check.Context,
+			"server":  check.Server,
+		},
+	})
+	return resp
+}
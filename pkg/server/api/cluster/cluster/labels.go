@@ -0,0 +1,84 @@
+package cluster
+
+import (
+	"fmt"
+	"github.com/gin-gonic/gin/binding"
+	"github.com/kubespace/kubespace/pkg/core/code"
+	"github.com/kubespace/kubespace/pkg/core/errors"
+	"github.com/kubespace/kubespace/pkg/model"
+	clustermgr "github.com/kubespace/kubespace/pkg/model/manager/cluster"
+	"github.com/kubespace/kubespace/pkg/model/types"
+	"github.com/kubespace/kubespace/pkg/server/api/api"
+	"github.com/kubespace/kubespace/pkg/server/config"
+	"github.com/kubespace/kubespace/pkg/utils"
+)
+
+type labelsHandler struct {
+	models *model.Models
+}
+
+type labelsBody struct {
+	Labels types.ClusterLabels `json:"labels"`
+}
+
+// LabelsHandler 更新集群标签，标签决定集群所属的集群组以及组的用户角色，需要平台管理员权限
+func LabelsHandler(conf *config.ServerConfig) api.Handler {
+	return &labelsHandler{models: conf.Models}
+}
+
+func (h *labelsHandler) Auth(c *api.Context) (bool, *api.AuthPerm, error) {
+	return platformAdminAuth()
+}
+
+func (h *labelsHandler) Handle(c *api.Context) *utils.Response {
+	var body labelsBody
+	if err := c.ShouldBindBodyWith(&body, binding.JSON); err != nil {
+		return c.ResponseError(errors.New(code.ParamsError, err))
+	}
+	clusterId, err := utils.ParseUint(c.Param("id"))
+	if err != nil {
+		return c.ResponseError(errors.New(code.ParamsError, err))
+	}
+	clusterObj, err := h.models.ClusterManager.GetById(clusterId)
+	if err != nil {
+		return c.ResponseError(errors.New(code.DataNotExists, fmt.Sprintf("not found cluster id=%d", clusterId)))
+	}
+	if err = clustermgr.ValidateLabels(body.Labels); err != nil {
+		return c.ResponseError(errors.New(code.ParamsError, err))
+	}
+	if body.Labels == nil {
+		body.Labels = types.ClusterLabels{}
+	}
+	if err = h.models.ClusterManager.UpdateLabels(clusterObj.ID, body.Labels); err != nil {
+		err = errors.New(code.DBError, err)
+	}
+	resp := c.Response(err, body.Labels)
+	c.CreateAudit(&types.AuditOperate{
+		Operation:            types.AuditOperationUpdate,
+		OperateDetail:        "更新集群标签：" + clusterObj.Name1,
+		Scope:                types.ScopeCluster,
+		ScopeId:              clusterObj.ID,
+		ScopeName:            clusterObj.Name1,
+		ResourceId:           clusterObj.ID,
+		ResourceType:         types.AuditResourceCluster,
+		ResourceName:         clusterObj.Name1,
+		Code:                 resp.Code,
+		Message:              resp.Msg,
+		OperateDataInterface: body,
+	})
+	return resp
+}
+
+// validateCreateLabels 创建集群时指定标签会将集群加入对应的集群组，同样需要平台管理员权限
+func validateCreateLabels(c *api.Context, models *model.Models, labels types.ClusterLabels) error {
+	if len(labels) == 0 {
+		return nil
+	}
+	if !models.UserRoleManager.AuthRole(c.User, types.ScopePlatform, 0, types.RoleAdmin) {
+		return errors.New(code.ParamsError, "设置集群标签需要平台管理员权限")
+	}
+	if err := clustermgr.ValidateLabels(labels); err != nil {
+		return errors.New(code.ParamsError, err)
+	}
+	return nil
+}
@@ -11,6 +11,7 @@ import (
 	"github.com/kubespace/kubespace/pkg/server/config"
 	"github.com/kubespace/kubespace/pkg/service/cluster"
 	"github.com/kubespace/kubespace/pkg/utils"
+	"k8s.io/apimachinery/pkg/labels"
 	"sync"
 )
 
@@ -36,8 +37,33 @@ func (h *listHandler) Auth(c *api.Context) (bool, *api.AuthPerm, error) {
 	return true, nil, nil
 }
 
+type listClusterQuery struct {
+	// LabelSelector 按集群标签过滤，如env=prod,region in (bj,sh)
+	LabelSelector string `form:"label_selector"`
+	// GroupId 只返回集群组中的集群
+	GroupId uint `form:"group_id"`
+}
+
 func (h *listHandler) Handle(c *api.Context) *utils.Response {
-	clusters, err := h.models.ClusterManager.List(clustermgr.ListClusterCondition{})
+	var query listClusterQuery
+	if err := c.ShouldBindQuery(&query); err != nil {
+		return c.ResponseError(errors.New(code.ParamsError, err))
+	}
+	selector := query.LabelSelector
+	if query.GroupId != 0 {
+		group, err := h.models.ClusterManager.GetGroup(query.GroupId)
+		if err != nil {
+			return c.ResponseError(errors.New(code.DataNotExists, err))
+		}
+		if selector != "" {
+			selector += ","
+		}
+		selector += group.Selector
+	}
+	if _, err := labels.Parse(selector); err != nil {
+		return c.ResponseError(errors.New(code.ParamsError, err))
+	}
+	clusters, err := h.models.ClusterManager.List(clustermgr.ListClusterCondition{LabelSelector: selector})
 	if err != nil {
 		return c.ResponseError(errors.New(code.DBError, err))
 	}
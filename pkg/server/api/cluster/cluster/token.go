@@ -0,0 +1,103 @@
+package cluster
+
+import (
+	"fmt"
+	"github.com/kubespace/kubespace/pkg/core/code"
+	"github.com/kubespace/kubespace/pkg/core/errors"
+	"github.com/kubespace/kubespace/pkg/model"
+	"github.com/kubespace/kubespace/pkg/model/types"
+	"github.com/kubespace/kubespace/pkg/server/api/api"
+	"github.com/kubespace/kubespace/pkg/server/config"
+	"github.com/kubespace/kubespace/pkg/utils"
+)
+
+type tokenHandler struct {
+	models *model.Models
+	revoke bool
+}
+
+// ReissueTokenHandler 重新生成集群导入token，之前的导入token失效，已连接的agent通过会话凭证继续使用
+func ReissueTokenHandler(conf *config.ServerConfig) api.Handler {
+	return &tokenHandler{models: conf.Models}
+}
+
+// RevokeTokenHandler 吊销集群导入token以及agent会话凭证，已连接的agent会断开，需要使用新的导入token重新导入
+func RevokeTokenHandler(conf *config.ServerConfig) api.Handler {
+	return &tokenHandler{models: conf.Models, revoke: true}
+}
+
+func (h *tokenHandler) Auth(c *api.Context) (bool, *api.AuthPerm, error) {
+	clusterId, err := utils.ParseUint(c.Param("id"))
+	if err != nil {
+		return true, nil, errors.New(code.ParamsError, err)
+	}
+	return true, &api.AuthPerm{
+		Scope:   types.ScopeCluster,
+		ScopeId: clusterId,
+		Role:    types.RoleAdmin,
+	}, nil
+}
+
+func (h *tokenHandler) Handle(c *api.Context) *utils.Response {
+	clusterId, _ := utils.ParseUint(c.Param("id"))
+	clusterObj, err := h.models.ClusterManager.GetById(clusterId)
+	if err != nil {
+		return c.ResponseError(errors.New(code.DataNotExists, fmt.Sprintf("not found cluster id=%d", clusterId)))
+	}
+	var token string
+	detail := "重新生成集群导入token：" + clusterObj.Name1
+	if h.revoke {
+		token, err = h.models.ClusterManager.RevokeToken(clusterId)
+		detail = "吊销集群agent凭证：" + clusterObj.Name1
+	} else {
+		token, err = h.models.ClusterManager.ReissueToken(clusterId)
+	}
+	if err != nil {
+		err = errors.New(code.DBError, err)
+	}
+	resp := c.Response(err, map[string]interface{}{"token": token})
+
+	c.CreateAudit(&types.AuditOperate{
+		Operation:     types.AuditOperationUpdate,
+		OperateDetail: detail,
+		Scope:         types.ScopeCluster,
+		ScopeId:       clusterObj.ID,
+		ScopeName:     clusterObj.Name1,
+		ResourceId:    clusterObj.ID,
+		ResourceType:  types.AuditResourceCluster,
+		ResourceName:  clusterObj.Name1,
+		Code:          resp.Code,
+		Message:       resp.Msg,
+	})
+	return resp
+}
+
+type agentConnectionsHandler struct {
+	models *model.Models
+}
+
+// AgentConnectionsHandler 获取集群agent最近的连接记录
+func AgentConnectionsHandler(conf *config.ServerConfig) api.Handler {
+	return &agentConnectionsHandler{models: conf.Models}
+}
+
+func (h *agentConnectionsHandler) Auth(c *api.Context) (bool, *api.AuthPerm, error) {
+	clusterId, err := utils.ParseUint(c.Param("id"))
+	if err != nil {
+		return true, nil, errors.New(code.ParamsError, err)
+	}
+	return true, &api.AuthPerm{
+		Scope:   types.ScopeCluster,
+		ScopeId: clusterId,
+		Role:    types.RoleViewer,
+	}, nil
+}
+
+func (h *agentConnectionsHandler) Handle(c *api.Context) *utils.Response {
+	clusterId, _ := utils.ParseUint(c.Param("id"))
+	conns, err := h.models.ClusterManager.ListAgentConnections(clusterId, 100)
+	if err != nil {
+		return c.ResponseError(errors.New(code.DBError, err))
+	}
+	return c.ResponseOK(conns)
+}
@@ -2,12 +2,14 @@ package cluster
 
 import (
 	"fmt"
+	"github.com/gin-gonic/gin/binding"
 	"github.com/kubespace/kubespace/pkg/core/code"
 	"github.com/kubespace/kubespace/pkg/core/errors"
 	"github.com/kubespace/kubespace/pkg/model"
 	"github.com/kubespace/kubespace/pkg/model/types"
 	"github.com/kubespace/kubespace/pkg/server/api/api"
 	"github.com/kubespace/kubespace/pkg/server/config"
+	clusterservice "github.com/kubespace/kubespace/pkg/service/cluster"
 	"github.com/kubespace/kubespace/pkg/utils"
 )
 
@@ -15,10 +17,6 @@ type updateHandler struct {
 	models *model.Models
 }
 
-type updateClusterBody struct {
-	KubeConfig string `json:"kubeconfig"`
-}
-
 func UpdateHandler(conf *config.ServerConfig) api.Handler {
 	return &updateHandler{models: conf.Models}
 }
@@ -36,10 +34,11 @@ func (h *updateHandler) Auth(c *api.Context) (bool, *api.AuthPerm, error) {
 	}, nil
 }
 
+// Handle 配置或者更换集群的kubeconfig，更换时只能更换凭证，apiserver地址需要与原kubeconfig一致
 func (h *updateHandler) Handle(c *api.Context) *utils.Response {
-	var ser updateClusterBody
+	var ser kubeConfigBody
 
-	if err := c.ShouldBind(&ser); err != nil {
+	if err := c.ShouldBindBodyWith(&ser, binding.JSON); err != nil {
 		return c.ResponseError(errors.New(code.ParamsError, err))
 	}
 
@@ -49,24 +48,38 @@ func (h *updateHandler) Handle(c *api.Context) *utils.Response {
 		return c.ResponseError(errors.New(code.DataNotExists, fmt.Sprintf("not found cluster id=%d", clusterId)))
 	}
 
-	err = h.models.ClusterManager.UpdateByObject(clusterId, &types.Cluster{KubeConfig: ser.KubeConfig})
+	kubeConfig, check, err := checkKubeConfig(&ser)
+	if err != nil {
+		return c.Response(err, check)
+	}
+	if clusterObj.KubeConfig != "" {
+		if server := clusterservice.KubeConfigServer(clusterObj.KubeConfig); server != "" && server != check.Server {
+			return c.ResponseError(errors.New(code.ParamsError,
+				fmt.Sprintf("kubeconfig的集群地址%s与原地址%s不一致，只能更换访问凭证", check.Server, server)))
+		}
+	}
+	err = h.models.ClusterManager.UpdateByObject(clusterId, &types.Cluster{KubeConfig: kubeConfig, Status: types.ClusterConnect})
 	if err != nil {
 		err = errors.New(code.DBError, err)
 	}
-	resp := c.Response(err, nil)
+	resp := c.Response(err, check)
 
 	c.CreateAudit(&types.AuditOperate{
-		Operation:            types.AuditOperationUpdate,
-		OperateDetail:        "更新集群：" + clusterObj.Name1,
-		Scope:                types.ScopeCluster,
-		ScopeId:              clusterObj.ID,
-		ScopeName:            clusterObj.Name1,
-		ResourceId:           clusterObj.ID,
-		ResourceType:         types.AuditResourceCluster,
-		ResourceName:         clusterObj.Name1,
-		Code:                 resp.Code,
-		Message:              resp.Msg,
-		OperateDataInterface: ser,
+		Operation:     types.AuditOperationUpdate,
+		OperateDetail: "更新集群kubeconfig：" + clusterObj.Name1,
+		Scope:         types.ScopeCluster,
+		ScopeId:       clusterObj.ID,
+		ScopeName:     clusterObj.Name1,
+		ResourceId:    clusterObj.ID,
+		ResourceType:  types.AuditResourceCluster,
+		ResourceName:  clusterObj.Name1,
+		Code:          resp.Code,
+		Message:       resp.Msg,
+		// 不记录kubeconfig中的凭证
+		OperateDataInterface: map[string]interface{}{
+			"context": check.Context,
+			"server":  check.Server,
+		},
 	})
 	return resp
 }
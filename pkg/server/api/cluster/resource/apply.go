@@ -60,7 +60,7 @@ func (h *applyHandler) Handle(c *api.Context) *utils.Response {
 		return c.ResponseError(err)
 	}
 
-	resp := h.kubeClient.Apply(c.Param("id"), &ser)
+	resp := h.kubeClient.WithUser(c.User).Apply(c.Param("id"), &ser)
 
 	var applyResources []*resource.ApplyResource
 	if err := utils.ConvertTypeByJson(resp.Data, &applyResources); err != nil {
@@ -60,7 +60,7 @@ func (h *deleteHandler) Handle(c *api.Context) *utils.Response {
 		return c.ResponseError(err)
 	}
 
-	resp := h.kubeClient.Delete(c.Param("id"), c.Param("resType"), &params)
+	resp := h.kubeClient.WithUser(c.User).Delete(c.Param("id"), c.Param("resType"), &params)
 
 	namespace := ""
 	namespaceMap := make(map[string]struct{})
@@ -64,5 +64,5 @@ func (h *getHandler) Handle(c *api.Context) *utils.Response {
 	if err := c.ShouldBindQuery(params); err != nil {
 		return &utils.Response{Code: code.ParamsError, Msg: err.Error()}
 	}
-	return h.kubeClient.Get(c.Param("id"), c.Param("resType"), params)
+	return h.kubeClient.WithUser(c.User).Get(c.Param("id"), c.Param("resType"), params)
 }
@@ -58,5 +58,5 @@ func (h *listHandler) Handle(c *api.Context) *utils.Response {
 	if err := c.ShouldBind(params); err != nil {
 		return c.ResponseError(errors.New(code.ParamsError, err))
 	}
-	return h.kubeClient.List(c.Param("id"), c.Param("resType"), params)
+	return h.kubeClient.WithUser(c.User).List(c.Param("id"), c.Param("resType"), params)
 }
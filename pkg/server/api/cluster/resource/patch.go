@@ -59,7 +59,7 @@ func (h *patchHandler) Handle(c *api.Context) *utils.Response {
 		return c.ResponseError(err)
 	}
 
-	resp := h.kubeClient.Patch(c.Param("id"), c.Param("resType"), &ser)
+	resp := h.kubeClient.WithUser(c.User).Patch(c.Param("id"), c.Param("resType"), &ser)
 	c.CreateAudit(&types.AuditOperate{
 		Operation:            types.AuditOperationPatch,
 		OperateDetail:        fmt.Sprintf("Patch %s %s/%s", c.Param("resType"), ser.Namespace, ser.Name),
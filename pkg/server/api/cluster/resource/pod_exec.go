@@ -58,7 +58,7 @@ func (h *podExecHandler) Handle(c *api.Context) *utils.Response {
 		klog.Errorf("upgrader agent conn error: %s", err)
 		return nil
 	}
-	podexec, err := newPodExec(ws, h.kubeClient, c.Param("id"), &podExecParams{
+	podexec, err := newPodExec(ws, h.kubeClient.WithUser(c.User), c.Param("id"), &podExecParams{
 		Namespace: c.Param("namespace"),
 		Name:      c.Param("pod"),
 		Container: c.Query("container"),
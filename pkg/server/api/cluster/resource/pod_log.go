@@ -58,7 +58,7 @@ func (h *podLogHandler) Handle(c *api.Context) *utils.Response {
 		klog.Errorf("upgrader agent conn error: %s", err)
 		return nil
 	}
-	podlog, err := newPodLog(ws, h.kubeClient, c.Param("id"), &podLogParams{
+	podlog, err := newPodLog(ws, h.kubeClient.WithUser(c.User), c.Param("id"), &podLogParams{
 		Namespace: c.Param("namespace"),
 		Name:      c.Param("pod"),
 		Container: c.Query("container"),
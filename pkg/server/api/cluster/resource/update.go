@@ -61,7 +61,7 @@ func (h *updateHandler) Handle(c *api.Context) *utils.Response {
 		return c.ResponseError(err)
 	}
 
-	resp := h.kubeClient.Update(c.Param("id"), c.Param("resType"), &params)
+	resp := h.kubeClient.WithUser(c.User).Update(c.Param("id"), c.Param("resType"), &params)
 
 	c.CreateAudit(&types.AuditOperate{
 		Operation:            types.AuditOperationUpdate,
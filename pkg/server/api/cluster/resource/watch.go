@@ -58,7 +58,7 @@ func (h *watchHandler) Handle(c *api.Context) *utils.Response {
 		c.SSEvent("message", err.Error())
 		return nil
 	}
-	watchOuter, err := h.kubeClient.Watch(c.Param("id"), c.Param("resType"), &ser)
+	watchOuter, err := h.kubeClient.WithUser(c.User).Watch(c.Param("id"), c.Param("resType"), &ser)
 	if err != nil {
 		c.SSEvent("message", err.Error())
 		return nil
@@ -0,0 +1,36 @@
+package notification
+
+import (
+	"github.com/kubespace/kubespace/pkg/server/api/api"
+	"github.com/kubespace/kubespace/pkg/server/config"
+	"net/http"
+)
+
+type apiGroup struct {
+	config *config.ServerConfig
+}
+
+func ApiGroup(conf *config.ServerConfig) api.ApiGroup {
+	return &apiGroup{conf}
+}
+
+func (a *apiGroup) Apis() []*api.Api {
+	apis := []*api.Api{
+		// 通知渠道，平台级配置
+		api.NewApi(http.MethodGet, "/channel", ChannelListHandler(a.config)),
+		api.NewApi(http.MethodPost, "/channel", ChannelCreateHandler(a.config)),
+		api.NewApi(http.MethodPut, "/channel/:id", ChannelUpdateHandler(a.config)),
+		api.NewApi(http.MethodDelete, "/channel/:id", ChannelDeleteHandler(a.config)),
+		api.NewApi(http.MethodPost, "/channel/:id/test", ChannelTestHandler(a.config)),
+
+		// 通知规则，属于平台、集群、工作空间或者流水线空间
+		api.NewApi(http.MethodGet, "/rule", RuleListHandler(a.config)),
+		api.NewApi(http.MethodPost, "/rule", RuleCreateHandler(a.config)),
+		api.NewApi(http.MethodPut, "/rule/:id", RuleUpdateHandler(a.config)),
+		api.NewApi(http.MethodDelete, "/rule/:id", RuleDeleteHandler(a.config)),
+
+		// 通知发送记录
+		api.NewApi(http.MethodGet, "/delivery", DeliveryListHandler(a.config)),
+	}
+	return apis
+}
@@ -0,0 +1,259 @@
+package notification
+
+import (
+	"fmt"
+	"github.com/gin-gonic/gin/binding"
+	"github.com/kubespace/kubespace/pkg/core/code"
+	"github.com/kubespace/kubespace/pkg/core/errors"
+	"github.com/kubespace/kubespace/pkg/model"
+	"github.com/kubespace/kubespace/pkg/model/types"
+	"github.com/kubespace/kubespace/pkg/server/api/api"
+	"github.com/kubespace/kubespace/pkg/server/config"
+	notificationservice "github.com/kubespace/kubespace/pkg/service/notification"
+	"github.com/kubespace/kubespace/pkg/utils"
+	"net/url"
+)
+
+type channelBody struct {
+	Name   string                           `json:"name"`
+	Type   string                           `json:"type"`
+	Config *types.NotificationChannelConfig `json:"config"`
+	// Secret 更新时为空则保留原有的密钥
+	Secret string `json:"secret"`
+}
+
+// channelData 渠道信息，不返回密钥，只返回是否已配置
+// 机器人webhook地址中包含access token，非平台编辑者只返回地址的域名
+func channelData(channel *types.NotificationChannel, withUrl bool) map[string]interface{} {
+	config := channel.Config
+	if !withUrl && config != nil && config.Url != "" {
+		masked := *config
+		masked.Url = maskUrl(config.Url)
+		config = &masked
+	}
+	return map[string]interface{}{
+		"id":          channel.ID,
+		"name":        channel.Name,
+		"type":        channel.Type,
+		"config":      config,
+		"has_secret":  channel.Secret != "",
+		"create_user": channel.CreateUser,
+		"update_user": channel.UpdateUser,
+		"create_time": channel.CreateTime,
+		"update_time": channel.UpdateTime,
+	}
+}
+
+// maskUrl 只保留url的scheme以及host
+func maskUrl(rawUrl string) string {
+	u, err := url.Parse(rawUrl)
+	if err != nil || u.Host == "" {
+		return "******"
+	}
+	return u.Scheme + "://" + u.Host + "/******"
+}
+
+func platformEditorAuth() (bool, *api.AuthPerm, error) {
+	return true, &api.AuthPerm{
+		Scope:   types.ScopePlatform,
+		ScopeId: 0,
+		Role:    types.RoleEditor,
+	}, nil
+}
+
+type channelListHandler struct {
+	models *model.Models
+}
+
+func ChannelListHandler(conf *config.ServerConfig) api.Handler {
+	return &channelListHandler{models: conf.Models}
+}
+
+// Auth 各范围的规则都需要选择渠道，登录用户均可查看，非平台编辑者不返回完整的webhook地址
+func (h *channelListHandler) Auth(c *api.Context) (bool, *api.AuthPerm, error) {
+	return true, nil, nil
+}
+
+func (h *channelListHandler) Handle(c *api.Context) *utils.Response {
+	channels, err := h.models.NotificationManager.ListChannels()
+	if err != nil {
+		return c.ResponseError(errors.New(code.DBError, err))
+	}
+	withUrl := h.models.UserRoleManager.AuthRole(c.User, types.ScopePlatform, 0, types.RoleEditor)
+	var data []map[string]interface{}
+	for _, channel := range channels {
+		data = append(data, channelData(channel, withUrl))
+	}
+	return c.ResponseOK(data)
+}
+
+type channelCreateHandler struct {
+	models *model.Models
+}
+
+func ChannelCreateHandler(conf *config.ServerConfig) api.Handler {
+	return &channelCreateHandler{models: conf.Models}
+}
+
+func (h *channelCreateHandler) Auth(c *api.Context) (bool, *api.AuthPerm, error) {
+	return platformEditorAuth()
+}
+
+func (h *channelCreateHandler) Handle(c *api.Context) *utils.Response {
+	var body channelBody
+	if err := c.ShouldBindBodyWith(&body, binding.JSON); err != nil {
+		return c.ResponseError(errors.New(code.ParamsError, err))
+	}
+	channel := &types.NotificationChannel{
+		Name:       body.Name,
+		Type:       body.Type,
+		Config:     body.Config,
+		Secret:     body.Secret,
+		CreateUser: c.User.Name,
+		UpdateUser: c.User.Name,
+	}
+	if err := notificationservice.ValidateChannel(channel); err != nil {
+		return c.ResponseError(errors.New(code.ParamsError, err))
+	}
+	var err error
+	if err = h.models.NotificationManager.CreateChannel(channel); err != nil {
+		err = errors.New(code.DBError, "创建通知渠道失败："+err.Error())
+	}
+	resp := c.Response(err, channelData(channel, true))
+	c.CreateAudit(&types.AuditOperate{
+		Operation:            types.AuditOperationCreate,
+		OperateDetail:        fmt.Sprintf("创建通知渠道：%s", channel.Name),
+		Scope:                types.ScopePlatform,
+		ResourceId:           channel.ID,
+		ResourceType:         types.AuditResourceNotificationChannel,
+		ResourceName:         channel.Name,
+		Code:                 resp.Code,
+		Message:              resp.Msg,
+		OperateDataInterface: channelData(channel, true),
+	})
+	return resp
+}
+
+type channelUpdateHandler struct {
+	models *model.Models
+}
+
+func ChannelUpdateHandler(conf *config.ServerConfig) api.Handler {
+	return &channelUpdateHandler{models: conf.Models}
+}
+
+func (h *channelUpdateHandler) Auth(c *api.Context) (bool, *api.AuthPerm, error) {
+	return platformEditorAuth()
+}
+
+func (h *channelUpdateHandler) Handle(c *api.Context) *utils.Response {
+	var body channelBody
+	if err := c.ShouldBindBodyWith(&body, binding.JSON); err != nil {
+		return c.ResponseError(errors.New(code.ParamsError, err))
+	}
+	id, err := utils.ParseUint(c.Param("id"))
+	if err != nil {
+		return c.ResponseError(errors.New(code.ParamsError, err))
+	}
+	channel, err := h.models.NotificationManager.GetChannel(id)
+	if err != nil {
+		return c.ResponseError(errors.New(code.DataNotExists, "获取通知渠道失败："+err.Error()))
+	}
+	channel.Name = body.Name
+	channel.Type = body.Type
+	channel.Config = body.Config
+	if body.Secret != "" {
+		channel.Secret = body.Secret
+	}
+	channel.UpdateUser = c.User.Name
+	if err = notificationservice.ValidateChannel(channel); err != nil {
+		return c.ResponseError(errors.New(code.ParamsError, err))
+	}
+	if err = h.models.NotificationManager.SaveChannel(channel); err != nil {
+		err = errors.New(code.DBError, "更新通知渠道失败："+err.Error())
+	}
+	resp := c.Response(err, channelData(channel, true))
+	c.CreateAudit(&types.AuditOperate{
+		Operation:            types.AuditOperationUpdate,
+		OperateDetail:        fmt.Sprintf("更新通知渠道：%s", channel.Name),
+		Scope:                types.ScopePlatform,
+		ResourceId:           channel.ID,
+		ResourceType:         types.AuditResourceNotificationChannel,
+		ResourceName:         channel.Name,
+		Code:                 resp.Code,
+		Message:              resp.Msg,
+		OperateDataInterface: channelData(channel, true),
+	})
+	return resp
+}
+
+type channelDeleteHandler struct {
+	models *model.Models
+}
+
+func ChannelDeleteHandler(conf *config.ServerConfig) api.Handler {
+	return &channelDeleteHandler{models: conf.Models}
+}
+
+func (h *channelDeleteHandler) Auth(c *api.Context) (bool, *api.AuthPerm, error) {
+	return platformEditorAuth()
+}
+
+func (h *channelDeleteHandler) Handle(c *api.Context) *utils.Response {
+	id, err := utils.ParseUint(c.Param("id"))
+	if err != nil {
+		return c.ResponseError(errors.New(code.ParamsError, err))
+	}
+	channel, err := h.models.NotificationManager.GetChannel(id)
+	if err != nil {
+		return c.ResponseError(errors.New(code.DataNotExists, "获取通知渠道失败："+err.Error()))
+	}
+	if err = h.models.NotificationManager.DeleteChannel(channel.ID); err != nil {
+		err = errors.New(code.DeleteError, "删除通知渠道失败："+err.Error())
+	}
+	resp := c.ResponseError(err)
+	c.CreateAudit(&types.AuditOperate{
+		Operation:            types.AuditOperationDelete,
+		OperateDetail:        fmt.Sprintf("删除通知渠道：%s", channel.Name),
+		Scope:                types.ScopePlatform,
+		ResourceId:           channel.ID,
+		ResourceType:         types.AuditResourceNotificationChannel,
+		ResourceName:         channel.Name,
+		Code:                 resp.Code,
+		Message:              resp.Msg,
+		OperateDataInterface: nil,
+	})
+	return resp
+}
+
+type channelTestHandler struct {
+	models              *model.Models
+	notificationService *notificationservice.NotificationService
+}
+
+// ChannelTestHandler 向渠道发送测试消息，同步返回发送结果
+func ChannelTestHandler(conf *config.ServerConfig) api.Handler {
+	return &channelTestHandler{
+		models:              conf.Models,
+		notificationService: conf.ServiceFactory.Notification.NotificationService,
+	}
+}
+
+func (h *channelTestHandler) Auth(c *api.Context) (bool, *api.AuthPerm, error) {
+	return platformEditorAuth()
+}
+
+func (h *channelTestHandler) Handle(c *api.Context) *utils.Response {
+	id, err := utils.ParseUint(c.Param("id"))
+	if err != nil {
+		return c.ResponseError(errors.New(code.ParamsError, err))
+	}
+	channel, err := h.models.NotificationManager.GetChannel(id)
+	if err != nil {
+		return c.ResponseError(errors.New(code.DataNotExists, "获取通知渠道失败："+err.Error()))
+	}
+	if err = h.notificationService.Test(channel, c.User.Name); err != nil {
+		return c.ResponseError(errors.New(code.RequestError, "发送测试消息失败："+err.Error()))
+	}
+	return c.ResponseOK(nil)
+}
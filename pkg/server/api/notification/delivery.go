@@ -0,0 +1,46 @@
+package notification
+
+import (
+	"github.com/kubespace/kubespace/pkg/core/code"
+	"github.com/kubespace/kubespace/pkg/core/errors"
+	"github.com/kubespace/kubespace/pkg/model"
+	notificationmgr "github.com/kubespace/kubespace/pkg/model/manager/notification"
+	"github.com/kubespace/kubespace/pkg/model/types"
+	"github.com/kubespace/kubespace/pkg/server/api/api"
+	"github.com/kubespace/kubespace/pkg/server/config"
+	"github.com/kubespace/kubespace/pkg/utils"
+)
+
+type deliveryListHandler struct {
+	models *model.Models
+}
+
+// DeliveryListHandler 查询范围内通知规则的发送记录，可以按规则、事件以及发送状态过滤
+func DeliveryListHandler(conf *config.ServerConfig) api.Handler {
+	return &deliveryListHandler{models: conf.Models}
+}
+
+func (h *deliveryListHandler) Auth(c *api.Context) (bool, *api.AuthPerm, error) {
+	var cond notificationmgr.DeliveryListCondition
+	if err := c.ShouldBindQuery(&cond); err != nil {
+		return true, nil, errors.New(code.ParamsError, err)
+	}
+	return true, scopePerm(cond.Scope, cond.ScopeId, types.RoleViewer), nil
+}
+
+func (h *deliveryListHandler) Handle(c *api.Context) *utils.Response {
+	var cond notificationmgr.DeliveryListCondition
+	if err := c.ShouldBindQuery(&cond); err != nil {
+		return c.ResponseError(errors.New(code.ParamsError, err))
+	}
+	perm := scopePerm(cond.Scope, cond.ScopeId, types.RoleViewer)
+	cond.Scope, cond.ScopeId = perm.Scope, perm.ScopeId
+	deliveries, page, err := h.models.NotificationManager.ListDeliveries(&cond)
+	if err != nil {
+		return c.ResponseError(errors.New(code.DBError, err))
+	}
+	return c.ResponseOK(map[string]interface{}{
+		"data":       deliveries,
+		"pagination": page,
+	})
+}
@@ -0,0 +1,302 @@
+package notification
+
+import (
+	"fmt"
+	"github.com/gin-gonic/gin/binding"
+	"github.com/kubespace/kubespace/pkg/core/code"
+	"github.com/kubespace/kubespace/pkg/core/errors"
+	"github.com/kubespace/kubespace/pkg/model"
+	"github.com/kubespace/kubespace/pkg/model/types"
+	"github.com/kubespace/kubespace/pkg/server/api/api"
+	"github.com/kubespace/kubespace/pkg/server/config"
+	notificationservice "github.com/kubespace/kubespace/pkg/service/notification"
+	"github.com/kubespace/kubespace/pkg/utils"
+)
+
+type ruleBody struct {
+	Name            string   `json:"name"`
+	Scope           string   `json:"scope"`
+	ScopeId         uint     `json:"scope_id"`
+	Events          []string `json:"events"`
+	ChannelIds      []uint   `json:"channel_ids"`
+	TitleTemplate   string   `json:"title_template"`
+	ContentTemplate string   `json:"content_template"`
+	Enabled         *bool    `json:"enabled"`
+}
+
+type ruleScopeQuery struct {
+	Scope   string `form:"scope"`
+	ScopeId uint   `form:"scope_id"`
+}
+
+// scopePerm 通知规则以及发送记录的权限，范围为空时为平台范围
+func scopePerm(scope string, scopeId uint, role string) *api.AuthPerm {
+	if scope == "" || scope == types.ScopePlatform {
+		return &api.AuthPerm{Scope: types.ScopePlatform, ScopeId: 0, Role: role}
+	}
+	return &api.AuthPerm{Scope: scope, ScopeId: scopeId, Role: role}
+}
+
+// ruleAuth 通过url中的规则id获取规则所属范围，需要有该范围的编辑权限
+func ruleAuth(c *api.Context, models *model.Models) (bool, *api.AuthPerm, error) {
+	id, err := utils.ParseUint(c.Param("id"))
+	if err != nil {
+		return true, nil, errors.New(code.ParamsError, err)
+	}
+	rule, err := models.NotificationManager.GetRule(id)
+	if err != nil {
+		return true, nil, errors.New(code.DataNotExists, "获取通知规则失败："+err.Error())
+	}
+	return true, scopePerm(rule.Scope, rule.ScopeId, types.RoleEditor), nil
+}
+
+// scopeName 获取规则所属范围的名称，范围对象不存在时返回错误
+func scopeName(models *model.Models, scope string, scopeId uint) (string, error) {
+	switch scope {
+	case types.ScopePlatform:
+		return "", nil
+	case types.ScopeCluster:
+		clusterObj, err := models.ClusterManager.GetById(scopeId)
+		if err != nil {
+			return "", errors.New(code.DataNotExists, fmt.Sprintf("获取集群id=%d失败：%s", scopeId, err.Error()))
+		}
+		return clusterObj.Name1, nil
+	case types.ScopeProject:
+		projectObj, err := models.ProjectManager.Get(scopeId)
+		if err != nil {
+			return "", errors.New(code.DataNotExists, fmt.Sprintf("获取工作空间id=%d失败：%s", scopeId, err.Error()))
+		}
+		return projectObj.Name, nil
+	case types.ScopePipeline:
+		workspace, err := models.PipelineWorkspaceManager.Get(scopeId)
+		if err != nil {
+			return "", errors.New(code.DataNotExists, fmt.Sprintf("获取流水线空间id=%d失败：%s", scopeId, err.Error()))
+		}
+		return workspace.Name, nil
+	}
+	return "", errors.New(code.ParamsError, "不支持的通知规则范围："+scope)
+}
+
+// validateRule 校验规则订阅的事件属于规则范围，渠道存在并且模板格式正确
+func validateRule(models *model.Models, rule *types.NotificationRule) error {
+	if rule.Name == "" {
+		return errors.New(code.ParamsError, "规则名称不能为空")
+	}
+	if len(rule.Events) == 0 {
+		return errors.New(code.ParamsError, "订阅事件不能为空")
+	}
+	for _, event := range rule.Events {
+		scopes, ok := types.NotificationEventScopes[event]
+		if !ok {
+			return errors.New(code.ParamsError, "不支持的事件："+event)
+		}
+		if rule.Scope != types.ScopePlatform && !utils.Contains(scopes, rule.Scope) {
+			return errors.New(code.ParamsError, fmt.Sprintf("%s范围的规则不能订阅事件%s", rule.Scope, event))
+		}
+	}
+	if len(rule.ChannelIds) == 0 {
+		return errors.New(code.ParamsError, "通知渠道不能为空")
+	}
+	for _, channelId := range rule.ChannelIds {
+		if _, err := models.NotificationManager.GetChannel(channelId); err != nil {
+			return errors.New(code.DataNotExists, fmt.Sprintf("获取通知渠道id=%d失败：%s", channelId, err.Error()))
+		}
+	}
+	for _, tmpl := range []string{rule.TitleTemplate, rule.ContentTemplate} {
+		if err := notificationservice.ValidateTemplate(tmpl); err != nil {
+			return errors.New(code.ParamsError, err)
+		}
+	}
+	return nil
+}
+
+type ruleListHandler struct {
+	models *model.Models
+}
+
+func RuleListHandler(conf *config.ServerConfig) api.Handler {
+	return &ruleListHandler{models: conf.Models}
+}
+
+func (h *ruleListHandler) Auth(c *api.Context) (bool, *api.AuthPerm, error) {
+	var query ruleScopeQuery
+	if err := c.ShouldBindQuery(&query); err != nil {
+		return true, nil, errors.New(code.ParamsError, err)
+	}
+	return true, scopePerm(query.Scope, query.ScopeId, types.RoleViewer), nil
+}
+
+func (h *ruleListHandler) Handle(c *api.Context) *utils.Response {
+	var query ruleScopeQuery
+	if err := c.ShouldBindQuery(&query); err != nil {
+		return c.ResponseError(errors.New(code.ParamsError, err))
+	}
+	perm := scopePerm(query.Scope, query.ScopeId, types.RoleViewer)
+	rules, err := h.models.NotificationManager.ListRules(perm.Scope, perm.ScopeId)
+	if err != nil {
+		return c.ResponseError(errors.New(code.DBError, err))
+	}
+	return c.ResponseOK(rules)
+}
+
+type ruleCreateHandler struct {
+	models *model.Models
+}
+
+func RuleCreateHandler(conf *config.ServerConfig) api.Handler {
+	return &ruleCreateHandler{models: conf.Models}
+}
+
+func (h *ruleCreateHandler) Auth(c *api.Context) (bool, *api.AuthPerm, error) {
+	var body ruleBody
+	if err := c.ShouldBindBodyWith(&body, binding.JSON); err != nil {
+		return true, nil, errors.New(code.ParamsError, err)
+	}
+	return true, scopePerm(body.Scope, body.ScopeId, types.RoleEditor), nil
+}
+
+func (h *ruleCreateHandler) Handle(c *api.Context) *utils.Response {
+	var body ruleBody
+	if err := c.ShouldBindBodyWith(&body, binding.JSON); err != nil {
+		return c.ResponseError(errors.New(code.ParamsError, err))
+	}
+	perm := scopePerm(body.Scope, body.ScopeId, types.RoleEditor)
+	rule := &types.NotificationRule{
+		Name:            body.Name,
+		Scope:           perm.Scope,
+		ScopeId:         perm.ScopeId,
+		Events:          body.Events,
+		ChannelIds:      body.ChannelIds,
+		TitleTemplate:   body.TitleTemplate,
+		ContentTemplate: body.ContentTemplate,
+		Enabled:         body.Enabled == nil || *body.Enabled,
+		CreateUser:      c.User.Name,
+		UpdateUser:      c.User.Name,
+	}
+	name, err := scopeName(h.models, rule.Scope, rule.ScopeId)
+	if err != nil {
+		return c.ResponseError(err)
+	}
+	if err = validateRule(h.models, rule); err != nil {
+		return c.ResponseError(err)
+	}
+	if err = h.models.NotificationManager.CreateRule(rule); err != nil {
+		err = errors.New(code.DBError, "创建通知规则失败："+err.Error())
+	}
+	resp := c.Response(err, rule)
+	c.CreateAudit(&types.AuditOperate{
+		Operation:            types.AuditOperationCreate,
+		OperateDetail:        fmt.Sprintf("创建通知规则：%s", rule.Name),
+		Scope:                rule.Scope,
+		ScopeId:              rule.ScopeId,
+		ScopeName:            name,
+		ResourceId:           rule.ID,
+		ResourceType:         types.AuditResourceNotificationRule,
+		ResourceName:         rule.Name,
+		Code:                 resp.Code,
+		Message:              resp.Msg,
+		OperateDataInterface: body,
+	})
+	return resp
+}
+
+type ruleUpdateHandler struct {
+	models *model.Models
+}
+
+func RuleUpdateHandler(conf *config.ServerConfig) api.Handler {
+	return &ruleUpdateHandler{models: conf.Models}
+}
+
+func (h *ruleUpdateHandler) Auth(c *api.Context) (bool, *api.AuthPerm, error) {
+	return ruleAuth(c, h.models)
+}
+
+// Handle 更新规则，规则所属范围不能修改
+func (h *ruleUpdateHandler) Handle(c *api.Context) *utils.Response {
+	var body ruleBody
+	if err := c.ShouldBindBodyWith(&body, binding.JSON); err != nil {
+		return c.ResponseError(errors.New(code.ParamsError, err))
+	}
+	id, err := utils.ParseUint(c.Param("id"))
+	if err != nil {
+		return c.ResponseError(errors.New(code.ParamsError, err))
+	}
+	rule, err := h.models.NotificationManager.GetRule(id)
+	if err != nil {
+		return c.ResponseError(errors.New(code.DataNotExists, "获取通知规则失败："+err.Error()))
+	}
+	rule.Name = body.Name
+	rule.Events = body.Events
+	rule.ChannelIds = body.ChannelIds
+	rule.TitleTemplate = body.TitleTemplate
+	rule.ContentTemplate = body.ContentTemplate
+	if body.Enabled != nil {
+		rule.Enabled = *body.Enabled
+	}
+	rule.UpdateUser = c.User.Name
+	if err = validateRule(h.models, rule); err != nil {
+		return c.ResponseError(err)
+	}
+	name, _ := scopeName(h.models, rule.Scope, rule.ScopeId)
+	if err = h.models.NotificationManager.SaveRule(rule); err != nil {
+		err = errors.New(code.DBError, "更新通知规则失败："+err.Error())
+	}
+	resp := c.Response(err, rule)
+	c.CreateAudit(&types.AuditOperate{
+		Operation:            types.AuditOperationUpdate,
+		OperateDetail:        fmt.Sprintf("更新通知规则：%s", rule.Name),
+		Scope:                rule.Scope,
+		ScopeId:              rule.ScopeId,
+		ScopeName:            name,
+		ResourceId:           rule.ID,
+		ResourceType:         types.AuditResourceNotificationRule,
+		ResourceName:         rule.Name,
+		Code:                 resp.Code,
+		Message:              resp.Msg,
+		OperateDataInterface: body,
+	})
+	return resp
+}
+
+type ruleDeleteHandler struct {
+	models *model.Models
+}
+
+func RuleDeleteHandler(conf *config.ServerConfig) api.Handler {
+	return &ruleDeleteHandler{models: conf.Models}
+}
+
+func (h *ruleDeleteHandler) Auth(c *api.Context) (bool, *api.AuthPerm, error) {
+	return ruleAuth(c, h.models)
+}
+
+func (h *ruleDeleteHandler) Handle(c *api.Context) *utils.Response {
+	id, err := utils.ParseUint(c.Param("id"))
+	if err != nil {
+		return c.ResponseError(errors.New(code.ParamsError, err))
+	}
+	rule, err := h.models.NotificationManager.GetRule(id)
+	if err != nil {
+		return c.ResponseError(errors.New(code.DataNotExists, "获取通知规则失败："+err.Error()))
+	}
+	name, _ := scopeName(h.models, rule.Scope, rule.ScopeId)
+	if err = h.models.NotificationManager.DeleteRule(rule.ID); err != nil {
+		err = errors.New(code.DeleteError, "删除通知规则失败："+err.Error())
+	}
+	resp := c.ResponseError(err)
+	c.CreateAudit(&types.AuditOperate{
+		Operation:            types.AuditOperationDelete,
+		OperateDetail:        fmt.Sprintf("删除通知规则：%s", rule.Name),
+		Scope:                rule.Scope,
+		ScopeId:              rule.ScopeId,
+		ScopeName:            name,
+		ResourceId:           rule.ID,
+		ResourceType:         types.AuditResourceNotificationRule,
+		ResourceName:         rule.Name,
+		Code:                 resp.Code,
+		Message:              resp.Msg,
+		OperateDataInterface: nil,
+	})
+	return resp
+}
@@ -19,6 +19,7 @@ func (a *apiGroup) Apis() []*api.Api {
 		api.NewApi(http.MethodGet, "", ListHandler(a.config)),
 		api.NewApi(http.MethodGet, "/resources", ResourcesHandler(a.config)),
 		api.NewApi(http.MethodGet, "/:id", GetHandler(a.config)),
+		api.NewApi(http.MethodGet, "/:id/events", EventsHandler(a.config)),
 		api.NewApi(http.MethodPost, "", CreateHandler(a.config)),
 		api.NewApi(http.MethodPost, "/clone", CloneHandler(a.config)),
 		api.NewApi(http.MethodPut, "/:id", UpdateHandler(a.config)),
@@ -1,13 +1,16 @@
 package project
 
 import (
+	"fmt"
 	"github.com/kubespace/kubespace/pkg/core/code"
 	"github.com/kubespace/kubespace/pkg/core/errors"
+	kubetypes "github.com/kubespace/kubespace/pkg/kubernetes/types"
 	"github.com/kubespace/kubespace/pkg/model"
 	"github.com/kubespace/kubespace/pkg/model/types"
 	"github.com/kubespace/kubespace/pkg/server/api/api"
 	"github.com/kubespace/kubespace/pkg/server/config"
 	"github.com/kubespace/kubespace/pkg/utils"
+	"strings"
 	"time"
 )
 
@@ -40,10 +43,15 @@ func (h *createHandler) Handle(c *api.Context) *utils.Response {
 	if err := c.ShouldBind(&body); err != nil {
 		return c.ResponseError(errors.New(code.ParamsError, err))
 	}
-	_, err := h.models.ClusterManager.GetByName(body.ClusterId)
+	clusterObj, err := h.models.ClusterManager.GetByName(body.ClusterId)
 	if err != nil {
 		return c.ResponseError(errors.New(code.DataNotExists, "get cluster error: "+err.Error()))
 	}
+	if profile := clusterObj.AgentProfile; profile != nil && profile.Name == kubetypes.AgentProfileNamespace &&
+		!utils.Contains(profile.Namespaces, body.Namespace) {
+		return c.ResponseError(errors.New(code.ParamsError, fmt.Sprintf(
+			"集群agent权限限制在命名空间[%s]，不能绑定命名空间%s", strings.Join(profile.Namespaces, ", "), body.Namespace)))
+	}
 	project := &types.Project{
 		Name:        body.Name,
 		Description: body.Description,
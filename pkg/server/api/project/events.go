@@ -0,0 +1,59 @@
+package project
+
+import (
+	"github.com/kubespace/kubespace/pkg/core/code"
+	"github.com/kubespace/kubespace/pkg/core/errors"
+	"github.com/kubespace/kubespace/pkg/model"
+	clustermgr "github.com/kubespace/kubespace/pkg/model/manager/cluster"
+	"github.com/kubespace/kubespace/pkg/model/types"
+	"github.com/kubespace/kubespace/pkg/server/api/api"
+	"github.com/kubespace/kubespace/pkg/server/config"
+	"github.com/kubespace/kubespace/pkg/utils"
+)
+
+type eventsHandler struct {
+	models *model.Models
+}
+
+// EventsHandler 工作空间命名空间下归档的事件时间线
+func EventsHandler(conf *config.ServerConfig) api.Handler {
+	return &eventsHandler{models: conf.Models}
+}
+
+func (h *eventsHandler) Auth(c *api.Context) (bool, *api.AuthPerm, error) {
+	projectId, err := utils.ParseUint(c.Param("id"))
+	if err != nil {
+		return true, nil, errors.New(code.ParamsError, err)
+	}
+	return true, &api.AuthPerm{
+		Scope:   types.ScopeProject,
+		ScopeId: projectId,
+		Role:    types.RoleViewer,
+	}, nil
+}
+
+func (h *eventsHandler) Handle(c *api.Context) *utils.Response {
+	var cond clustermgr.ClusterEventListCondition
+	if err := c.ShouldBindQuery(&cond); err != nil {
+		return c.ResponseError(errors.New(code.ParamsError, err))
+	}
+	projectId, _ := utils.ParseUint(c.Param("id"))
+	project, err := h.models.ProjectManager.Get(projectId)
+	if err != nil {
+		return c.ResponseError(errors.New(code.DataNotExists, "获取工作空间失败: "+err.Error()))
+	}
+	// 只能查询工作空间所在集群以及命名空间的事件
+	cond.ClusterId, err = utils.ParseUint(project.ClusterId)
+	if err != nil {
+		return c.ResponseError(errors.New(code.ParamsError, err))
+	}
+	cond.Namespace = project.Namespace
+	events, page, err := h.models.ClusterManager.ListEvents(&cond)
+	if err != nil {
+		return c.ResponseError(errors.New(code.DBError, err))
+	}
+	return c.ResponseOK(map[string]interface{}{
+		"data":       events,
+		"pagination": page,
+	})
+}
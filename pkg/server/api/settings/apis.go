@@ -2,6 +2,7 @@ package settings
 
 import (
 	"github.com/kubespace/kubespace/pkg/server/api/api"
+	"github.com/kubespace/kubespace/pkg/server/api/settings/app_store_source"
 	"github.com/kubespace/kubespace/pkg/server/api/settings/image_registry"
 	"github.com/kubespace/kubespace/pkg/server/api/settings/secret"
 	"github.com/kubespace/kubespace/pkg/server/api/settings/settings"
@@ -30,6 +31,12 @@ func (a *apiGroup) Apis() []*api.Api {
 		api.NewApi(http.MethodPost, "/image_registry", image_registry.CreateHandler(a.config)),
 		api.NewApi(http.MethodPut, "/image_registry/:id", image_registry.UpdateHandler(a.config)),
 		api.NewApi(http.MethodDelete, "/image_registry/:id", image_registry.DeleteHandler(a.config)),
+
+		api.NewApi(http.MethodGet, "/app_store_source", app_store_source.ListHandler(a.config)),
+		api.NewApi(http.MethodPost, "/app_store_source", app_store_source.CreateHandler(a.config)),
+		api.NewApi(http.MethodPut, "/app_store_source/:id", app_store_source.UpdateHandler(a.config)),
+		api.NewApi(http.MethodDelete, "/app_store_source/:id", app_store_source.DeleteHandler(a.config)),
+		api.NewApi(http.MethodPost, "/app_store_source/:id/refresh", app_store_source.RefreshHandler(a.config)),
 	}
 	return apis
 }
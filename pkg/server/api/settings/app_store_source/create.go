@@ -0,0 +1,140 @@
+package app_store_source
+
+import (
+	"fmt"
+	"github.com/kubespace/kubespace/pkg/core/code"
+	"github.com/kubespace/kubespace/pkg/core/errors"
+	"github.com/kubespace/kubespace/pkg/model"
+	"github.com/kubespace/kubespace/pkg/model/types"
+	"github.com/kubespace/kubespace/pkg/server/api/api"
+	"github.com/kubespace/kubespace/pkg/server/config"
+	"github.com/kubespace/kubespace/pkg/third/helm"
+	"github.com/kubespace/kubespace/pkg/utils"
+	"net/url"
+	"path"
+	"strings"
+	"time"
+)
+
+type createHandler struct {
+	models *model.Models
+}
+
+func CreateHandler(conf *config.ServerConfig) api.Handler {
+	return &createHandler{models: conf.Models}
+}
+
+type appStoreSourceBody struct {
+	Name              string   `json:"name" form:"name"`
+	Type              string   `json:"type" form:"type"`
+	Url               string   `json:"url" form:"url"`
+	SecretId          uint     `json:"secret_id" form:"secret_id"`
+	Allowlist         []string `json:"allowlist" form:"allowlist"`
+	AppType           string   `json:"app_type" form:"app_type"`
+	MaxVersions       int      `json:"max_versions" form:"max_versions"`
+	Keyring           string   `json:"keyring" form:"keyring"`
+	RequireProvenance bool     `json:"require_provenance" form:"require_provenance"`
+	SyncInterval      int      `json:"sync_interval" form:"sync_interval"`
+	Enabled           bool     `json:"enabled" form:"enabled"`
+}
+
+// validate 校验同步源配置，并设置默认值
+func (b *appStoreSourceBody) validate() error {
+	b.Name = strings.TrimSpace(b.Name)
+	b.Url = strings.TrimSpace(b.Url)
+	if b.Name == "" {
+		return fmt.Errorf("名称不能为空")
+	}
+	switch b.Type {
+	case types.AppStoreSourceTypeHelm:
+		u, err := url.Parse(b.Url)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return fmt.Errorf("helm仓库地址必须为http(s)地址")
+		}
+	case types.AppStoreSourceTypeOci:
+		if !strings.HasPrefix(b.Url, helm.OciScheme) || len(b.Url) == len(helm.OciScheme) {
+			return fmt.Errorf("oci仓库地址必须以%s开头", helm.OciScheme)
+		}
+		if len(b.Allowlist) == 0 {
+			return fmt.Errorf("oci仓库必须配置同步的chart名称")
+		}
+		for _, name := range b.Allowlist {
+			if strings.ContainsAny(name, "*?[") {
+				return fmt.Errorf("oci仓库chart名称不支持通配符：%s", name)
+			}
+		}
+	default:
+		return fmt.Errorf("不支持的仓库类型：%s", b.Type)
+	}
+	for _, pattern := range b.Allowlist {
+		if _, err := path.Match(pattern, ""); err != nil {
+			return fmt.Errorf("chart名称匹配规则%s错误：%s", pattern, err.Error())
+		}
+	}
+	if b.RequireProvenance && b.Keyring == "" {
+		return fmt.Errorf("校验chart签名需要配置公钥")
+	}
+	if b.AppType == "" {
+		b.AppType = types.AppTypeOrdinaryApp
+	}
+	if b.MaxVersions <= 0 {
+		b.MaxVersions = types.AppStoreSourceDefaultMaxVersions
+	}
+	if b.SyncInterval <= 0 {
+		b.SyncInterval = types.AppStoreSourceDefaultSyncInterval
+	}
+	return nil
+}
+
+func (h *createHandler) Auth(c *api.Context) (bool, *api.AuthPerm, error) {
+	return true, &api.AuthPerm{
+		Scope:   types.ScopePlatform,
+		ScopeId: 0,
+		Role:    types.RoleEditor,
+	}, nil
+}
+
+func (h *createHandler) Handle(c *api.Context) *utils.Response {
+	var body appStoreSourceBody
+	if err := c.ShouldBind(&body); err != nil {
+		return c.ResponseError(errors.New(code.ParamsError, err))
+	}
+	if err := body.validate(); err != nil {
+		return c.ResponseError(errors.New(code.ParamsError, err))
+	}
+	source := &types.AppStoreSource{
+		Name:              body.Name,
+		Type:              body.Type,
+		Url:               body.Url,
+		SecretId:          body.SecretId,
+		Allowlist:         body.Allowlist,
+		AppType:           body.AppType,
+		MaxVersions:       body.MaxVersions,
+		Keyring:           body.Keyring,
+		RequireProvenance: body.RequireProvenance,
+		SyncInterval:      body.SyncInterval,
+		Enabled:           body.Enabled,
+		Status:            types.AppStoreSourceStatusPending,
+		CreateUser:        c.User.Name,
+		UpdateUser:        c.User.Name,
+		CreateTime:        time.Now(),
+		UpdateTime:        time.Now(),
+	}
+	_, err := h.models.AppStoreSourceManager.Create(source)
+	if err != nil {
+		err = errors.New(code.DBError, "创建应用商店源失败: "+err.Error())
+	}
+	resp := c.ResponseError(err)
+	c.CreateAudit(&types.AuditOperate{
+		Operation:            types.AuditOperationCreate,
+		OperateDetail:        fmt.Sprintf("创建应用商店源：%s", body.Name),
+		Scope:                types.ScopePlatform,
+		ResourceId:           source.ID,
+		ResourceType:         types.AuditResourceAppStoreSource,
+		ResourceName:         body.Name,
+		Code:                 resp.Code,
+		Message:              resp.Msg,
+		OperateDataInterface: body,
+	})
+	return resp
+}
@@ -0,0 +1,56 @@
+package app_store_source
+
+import (
+	"fmt"
+	"github.com/kubespace/kubespace/pkg/core/code"
+	"github.com/kubespace/kubespace/pkg/core/errors"
+	"github.com/kubespace/kubespace/pkg/model"
+	"github.com/kubespace/kubespace/pkg/model/types"
+	"github.com/kubespace/kubespace/pkg/server/api/api"
+	"github.com/kubespace/kubespace/pkg/server/config"
+	"github.com/kubespace/kubespace/pkg/utils"
+)
+
+type deleteHandler struct {
+	models *model.Models
+}
+
+func DeleteHandler(conf *config.ServerConfig) api.Handler {
+	return &deleteHandler{models: conf.Models}
+}
+
+func (h *deleteHandler) Auth(c *api.Context) (bool, *api.AuthPerm, error) {
+	return true, &api.AuthPerm{
+		Scope:   types.ScopePlatform,
+		ScopeId: 0,
+		Role:    types.RoleEditor,
+	}, nil
+}
+
+// Handle 删除同步源，已经同步到应用商店的应用保留
+func (h *deleteHandler) Handle(c *api.Context) *utils.Response {
+	id, err := utils.ParseUint(c.Param("id"))
+	if err != nil {
+		return c.ResponseError(errors.New(code.ParamsError, err))
+	}
+	obj, err := h.models.AppStoreSourceManager.Get(id)
+	if err != nil {
+		return c.ResponseError(errors.New(code.DataNotExists, "获取应用商店源失败: "+err.Error()))
+	}
+	if err = h.models.AppStoreSourceManager.Delete(obj.ID); err != nil {
+		err = errors.New(code.DBError, "删除应用商店源失败: "+err.Error())
+	}
+	resp := c.ResponseError(err)
+	c.CreateAudit(&types.AuditOperate{
+		Operation:            types.AuditOperationDelete,
+		OperateDetail:        fmt.Sprintf("删除应用商店源：%s", obj.Name),
+		Scope:                types.ScopePlatform,
+		ResourceId:           obj.ID,
+		ResourceType:         types.AuditResourceAppStoreSource,
+		ResourceName:         obj.Name,
+		Code:                 resp.Code,
+		Message:              resp.Msg,
+		OperateDataInterface: nil,
+	})
+	return resp
+}
@@ -0,0 +1,30 @@
+package app_store_source
+
+import (
+	"github.com/kubespace/kubespace/pkg/core/code"
+	"github.com/kubespace/kubespace/pkg/core/errors"
+	"github.com/kubespace/kubespace/pkg/model"
+	"github.com/kubespace/kubespace/pkg/server/api/api"
+	"github.com/kubespace/kubespace/pkg/server/config"
+	"github.com/kubespace/kubespace/pkg/utils"
+)
+
+type listHandler struct {
+	models *model.Models
+}
+
+func ListHandler(conf *config.ServerConfig) api.Handler {
+	return &listHandler{models: conf.Models}
+}
+
+func (h *listHandler) Auth(c *api.Context) (bool, *api.AuthPerm, error) {
+	return true, nil, nil
+}
+
+func (h *listHandler) Handle(c *api.Context) *utils.Response {
+	objs, err := h.models.AppStoreSourceManager.List()
+	if err != nil {
+		return c.ResponseError(errors.New(code.DBError, err))
+	}
+	return c.ResponseOK(objs)
+}
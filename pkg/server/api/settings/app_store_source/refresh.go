@@ -0,0 +1,60 @@
+package app_store_source
+
+import (
+	"fmt"
+	"github.com/kubespace/kubespace/pkg/core/code"
+	"github.com/kubespace/kubespace/pkg/core/errors"
+	"github.com/kubespace/kubespace/pkg/model"
+	"github.com/kubespace/kubespace/pkg/model/types"
+	"github.com/kubespace/kubespace/pkg/server/api/api"
+	"github.com/kubespace/kubespace/pkg/server/config"
+	"github.com/kubespace/kubespace/pkg/utils"
+)
+
+type refreshHandler struct {
+	models *model.Models
+}
+
+func RefreshHandler(conf *config.ServerConfig) api.Handler {
+	return &refreshHandler{models: conf.Models}
+}
+
+func (h *refreshHandler) Auth(c *api.Context) (bool, *api.AuthPerm, error) {
+	return true, &api.AuthPerm{
+		Scope:   types.ScopePlatform,
+		ScopeId: 0,
+		Role:    types.RoleEditor,
+	}, nil
+}
+
+// Handle 手动触发立即同步，由controller异步执行，同步结果通过同步源状态查看
+func (h *refreshHandler) Handle(c *api.Context) *utils.Response {
+	id, err := utils.ParseUint(c.Param("id"))
+	if err != nil {
+		return c.ResponseError(errors.New(code.ParamsError, err))
+	}
+	obj, err := h.models.AppStoreSourceManager.Get(id)
+	if err != nil {
+		return c.ResponseError(errors.New(code.DataNotExists, "获取应用商店源失败: "+err.Error()))
+	}
+	if !obj.Enabled {
+		return c.ResponseError(errors.New(code.ParamsError, "应用商店源未启用"))
+	}
+	source, err := h.models.AppStoreSourceManager.Refresh(obj.ID, c.User.Name)
+	if err != nil {
+		err = errors.New(code.DBError, "刷新应用商店源失败: "+err.Error())
+	}
+	resp := c.Response(err, source)
+	c.CreateAudit(&types.AuditOperate{
+		Operation:            types.AuditOperationSync,
+		OperateDetail:        fmt.Sprintf("同步应用商店源：%s", obj.Name),
+		Scope:                types.ScopePlatform,
+		ResourceId:           obj.ID,
+		ResourceType:         types.AuditResourceAppStoreSource,
+		ResourceName:         obj.Name,
+		Code:                 resp.Code,
+		Message:              resp.Msg,
+		OperateDataInterface: nil,
+	})
+	return resp
+}
@@ -0,0 +1,76 @@
+package app_store_source
+
+import (
+	"fmt"
+	"github.com/kubespace/kubespace/pkg/core/code"
+	"github.com/kubespace/kubespace/pkg/core/errors"
+	"github.com/kubespace/kubespace/pkg/model"
+	"github.com/kubespace/kubespace/pkg/model/types"
+	"github.com/kubespace/kubespace/pkg/server/api/api"
+	"github.com/kubespace/kubespace/pkg/server/config"
+	"github.com/kubespace/kubespace/pkg/utils"
+	"time"
+)
+
+type updateHandler struct {
+	models *model.Models
+}
+
+func UpdateHandler(conf *config.ServerConfig) api.Handler {
+	return &updateHandler{models: conf.Models}
+}
+
+func (h *updateHandler) Auth(c *api.Context) (bool, *api.AuthPerm, error) {
+	return true, &api.AuthPerm{
+		Scope:   types.ScopePlatform,
+		ScopeId: 0,
+		Role:    types.RoleEditor,
+	}, nil
+}
+
+func (h *updateHandler) Handle(c *api.Context) *utils.Response {
+	var body appStoreSourceBody
+	if err := c.ShouldBind(&body); err != nil {
+		return c.ResponseError(errors.New(code.ParamsError, err))
+	}
+	id, err := utils.ParseUint(c.Param("id"))
+	if err != nil {
+		return c.ResponseError(errors.New(code.ParamsError, err))
+	}
+	obj, err := h.models.AppStoreSourceManager.Get(id)
+	if err != nil {
+		return c.ResponseError(errors.New(code.DataNotExists, "获取应用商店源失败: "+err.Error()))
+	}
+	body.Name = obj.Name
+	if err = body.validate(); err != nil {
+		return c.ResponseError(errors.New(code.ParamsError, err))
+	}
+	obj.Type = body.Type
+	obj.Url = body.Url
+	obj.SecretId = body.SecretId
+	obj.Allowlist = body.Allowlist
+	obj.AppType = body.AppType
+	obj.MaxVersions = body.MaxVersions
+	obj.Keyring = body.Keyring
+	obj.RequireProvenance = body.RequireProvenance
+	obj.SyncInterval = body.SyncInterval
+	obj.Enabled = body.Enabled
+	obj.UpdateUser = c.User.Name
+	obj.UpdateTime = time.Now()
+	if err = h.models.AppStoreSourceManager.Save(obj); err != nil {
+		err = errors.New(code.DBError, "更新应用商店源失败: "+err.Error())
+	}
+	resp := c.ResponseError(err)
+	c.CreateAudit(&types.AuditOperate{
+		Operation:            types.AuditOperationUpdate,
+		OperateDetail:        fmt.Sprintf("更新应用商店源：%s", obj.Name),
+		Scope:                types.ScopePlatform,
+		ResourceId:           obj.ID,
+		ResourceType:         types.AuditResourceAppStoreSource,
+		ResourceName:         obj.Name,
+		Code:                 resp.Code,
+		Message:              resp.Msg,
+		OperateDataInterface: body,
+	})
+	return resp
+}
@@ -28,6 +28,17 @@ type createSecretBody struct {
 	Password    string `json:"password" form:"password"`
 	PrivateKey  string `json:"private_key" form:"private_key"`
 	AccessToken string `json:"access_token" form:"access_token"`
+	// Scopes 允许在应用values以及应用git源中引用该密钥的工作空间/集群
+	Scopes types.SettingsSecretScopes `json:"scopes" form:"scopes"`
+}
+
+func (b *createSecretBody) validateScopes() error {
+	for _, scope := range b.Scopes {
+		if scope.Scope != types.ScopePlatform && scope.Scope != types.ScopeProject && scope.Scope != types.ScopeCluster {
+			return fmt.Errorf("密钥引用范围%s错误，只支持platform/project/cluster", scope.Scope)
+		}
+	}
+	return nil
 }
 
 func (h *createHandler) Auth(c *api.Context) (bool, *api.AuthPerm, error) {
@@ -43,6 +54,9 @@ func (h *createHandler) Handle(c *api.Context) *utils.Response {
 	if err := c.ShouldBind(&body); err != nil {
 		return c.ResponseError(errors.New(code.ParamsError, err))
 	}
+	if err := body.validateScopes(); err != nil {
+		return c.ResponseError(errors.New(code.ParamsError, err))
+	}
 	secret := &types.SettingsSecret{
 		Name:        body.Name,
 		Description: body.Description,
@@ -51,6 +65,7 @@ func (h *createHandler) Handle(c *api.Context) *utils.Response {
 		Password:    body.Password,
 		PrivateKey:  body.PrivateKey,
 		AccessToken: body.AccessToken,
+		Scopes:      body.Scopes,
 		CreateUser:  c.User.Name,
 		UpdateUser:  c.User.Name,
 		CreateTime:  time.Time{},
@@ -33,6 +33,9 @@ func (h *updateHandler) Handle(c *api.Context) *utils.Response {
 	if err := c.ShouldBind(&body); err != nil {
 		return c.ResponseError(errors.New(code.ParamsError, err))
 	}
+	if err := body.validateScopes(); err != nil {
+		return c.ResponseError(errors.New(code.ParamsError, err))
+	}
 	secretId, err := utils.ParseUint(c.Param("id"))
 	if err != nil {
 		return c.ResponseError(errors.New(code.ParamsError, err))
@@ -47,6 +50,7 @@ func (h *updateHandler) Handle(c *api.Context) *utils.Response {
 	secret.Password = body.Password
 	secret.PrivateKey = body.PrivateKey
 	secret.AccessToken = body.AccessToken
+	secret.Scopes = body.Scopes
 	secret.UpdateUser = c.User.Name
 	secret.UpdateTime = time.Now()
 	_, err = h.models.SettingsSecretManager.Update(secret)
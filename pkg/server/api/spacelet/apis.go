@@ -19,9 +19,17 @@ func (a *apiGroup) Apis() []*api.Api {
 		api.NewApi(http.MethodGet, "", ListHandler(a.config)),
 		api.NewApi(http.MethodPut, "/:id", UpdateHandler(a.config)),
 		api.NewApi(http.MethodDelete, "/:id", DeleteHandler(a.config)),
+		// 排空spacelet节点，不再调度新任务
+		api.NewApi(http.MethodPut, "/:id/drain", DrainHandler(a.config)),
 		api.NewApi(http.MethodGet, "/install.sh", InstallHandler(a.config)),
+		// 生成spacelet引导token
+		api.NewApi(http.MethodPost, "/bootstrap_token", BootstrapTokenHandler(a.config)),
 
+		// spacelet注册前获取CA证书，校验后通过tls端口注册
+		api.NewApi(http.MethodGet, "/ca", CAHandler(a.config)),
 		api.NewApi(http.MethodPost, "/register", RegisterHandler(a.config)),
+		// spacelet定时上报心跳
+		api.NewApi(http.MethodPost, "/heartbeat", HeartbeatHandler(a.config)),
 		// spacelet执行完成任务之后回调
 		api.NewApi(http.MethodPost, "/pipeline/callback", CallbackHandler(a.config)),
 		// 发布任务执行时添加版本号
@@ -0,0 +1,34 @@
+package spacelet
+
+import (
+	"fmt"
+	"github.com/kubespace/kubespace/pkg/core/code"
+	"github.com/kubespace/kubespace/pkg/core/errors"
+	"github.com/kubespace/kubespace/pkg/model"
+	"github.com/kubespace/kubespace/pkg/model/types"
+	"github.com/kubespace/kubespace/pkg/server/api/api"
+	"github.com/kubespace/kubespace/pkg/spacelet"
+)
+
+// authSpacelet spacelet心跳以及回调需要通过kubespace双向tls端口访问，
+// 根据客户端证书CommonName获取spacelet，并校验spacelet的token
+func authSpacelet(c *api.Context, models *model.Models, token string) (*types.Spacelet, error) {
+	if c.Request.TLS == nil || len(c.Request.TLS.VerifiedChains) == 0 || len(c.Request.TLS.VerifiedChains[0]) == 0 {
+		return nil, errors.New(code.AuthError, "spacelet client certificate is required, please access kubespace by mutual tls port")
+	}
+	hostIp, port, err := spacelet.ParseCommonName(c.Request.TLS.VerifiedChains[0][0].Subject.CommonName)
+	if err != nil {
+		return nil, errors.New(code.AuthError, err)
+	}
+	spaceletObj, err := models.SpaceletManager.GetByIpPort(hostIp, port)
+	if err != nil {
+		return nil, errors.New(code.DBError, err)
+	}
+	if spaceletObj == nil {
+		return nil, errors.New(code.DataNotExists, fmt.Sprintf("spacelet %s:%d not registered", hostIp, port))
+	}
+	if !spaceletObj.TlsEnabled || !spaceletObj.ValidToken(token) {
+		return nil, errors.New(code.AuthError, "spacelet token is incorrect")
+	}
+	return spaceletObj, nil
+}
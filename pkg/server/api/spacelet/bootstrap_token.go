@@ -0,0 +1,48 @@
+package spacelet
+
+import (
+	"github.com/kubespace/kubespace/pkg/core/code"
+	"github.com/kubespace/kubespace/pkg/core/errors"
+	"github.com/kubespace/kubespace/pkg/model"
+	"github.com/kubespace/kubespace/pkg/model/types"
+	"github.com/kubespace/kubespace/pkg/server/api/api"
+	"github.com/kubespace/kubespace/pkg/server/config"
+	"github.com/kubespace/kubespace/pkg/utils"
+)
+
+// 生成spacelet引导token，安装spacelet时指定，未注册过的spacelet注册时需要提供
+type bootstrapTokenHandler struct {
+	models *model.Models
+}
+
+func BootstrapTokenHandler(conf *config.ServerConfig) api.Handler {
+	return &bootstrapTokenHandler{models: conf.Models}
+}
+
+func (h *bootstrapTokenHandler) Auth(c *api.Context) (bool, *api.AuthPerm, error) {
+	return true, &api.AuthPerm{
+		Scope:   types.ScopePlatform,
+		ScopeId: 0,
+		Role:    types.RoleAdmin,
+	}, nil
+}
+
+func (h *bootstrapTokenHandler) Handle(c *api.Context) *utils.Response {
+	token, err := h.models.SpaceletManager.CreateBootstrapToken(c.User.Name)
+	if err != nil {
+		return c.ResponseError(errors.New(code.DBError, err))
+	}
+	resp := c.ResponseOK(token)
+	c.CreateAudit(&types.AuditOperate{
+		Operation:            types.AuditOperationCreate,
+		OperateDetail:        "生成Spacelet引导token",
+		Scope:                types.ScopePlatform,
+		ResourceId:           token.ID,
+		ResourceType:         types.AuditResourcePlatformSpacelet,
+		ResourceName:         "bootstrap token",
+		Code:                 resp.Code,
+		Message:              resp.Msg,
+		OperateDataInterface: nil,
+	})
+	return resp
+}
@@ -0,0 +1,40 @@
+package spacelet
+
+import (
+	"github.com/kubespace/kubespace/pkg/core/code"
+	"github.com/kubespace/kubespace/pkg/core/errors"
+	"github.com/kubespace/kubespace/pkg/server/api/api"
+	"github.com/kubespace/kubespace/pkg/server/config"
+	spaceletservice "github.com/kubespace/kubespace/pkg/service/spacelet"
+	"github.com/kubespace/kubespace/pkg/spacelet"
+	"github.com/kubespace/kubespace/pkg/utils"
+)
+
+// spacelet注册前获取CA证书以及双向tls端口，spacelet通过安装时指定的CA公钥sha256校验后，再通过tls端口注册
+type caHandler struct {
+	pki     *spaceletservice.PKI
+	tlsPort int
+}
+
+func CAHandler(conf *config.ServerConfig) api.Handler {
+	return &caHandler{
+		pki:     conf.ServiceFactory.Pipeline.SpaceletService.PKI,
+		tlsPort: conf.SpaceletPort,
+	}
+}
+
+func (h *caHandler) Auth(c *api.Context) (bool, *api.AuthPerm, error) {
+	// CA证书不需要认证，spacelet通过公钥sha256校验
+	return false, nil, nil
+}
+
+func (h *caHandler) Handle(c *api.Context) *utils.Response {
+	caCert, err := h.pki.CACertPEM()
+	if err != nil {
+		return c.ResponseError(errors.New(code.GetError, "get spacelet ca error: "+err.Error()))
+	}
+	return c.ResponseOK(&spacelet.CAResponse{
+		CaCert:  string(caCert),
+		TlsPort: h.tlsPort,
+	})
+}
@@ -1,6 +1,7 @@
 package spacelet
 
 import (
+	"fmt"
 	"github.com/kubespace/kubespace/pkg/core/code"
 	"github.com/kubespace/kubespace/pkg/core/errors"
 	"github.com/kubespace/kubespace/pkg/model"
@@ -23,15 +24,28 @@ func CallbackHandler(conf *config.ServerConfig) api.Handler {
 	}
 }
 
+// Auth spacelet通过双向tls证书以及token认证
 func (h *callbackHandler) Auth(c *api.Context) (bool, *api.AuthPerm, error) {
 	return false, nil, nil
 }
 
 func (h *callbackHandler) Handle(c *api.Context) *utils.Response {
+	spaceletObj, err := authSpacelet(c, h.models, c.GetHeader("token"))
+	if err != nil {
+		return c.ResponseError(err)
+	}
 	var form schemas.JobCallbackParams
-	if err := c.ShouldBind(&form); err != nil {
+	if err = c.ShouldBind(&form); err != nil {
 		return c.ResponseError(errors.New(code.ParamsError, err))
 	}
-	err := h.pipelineRunService.JobCallback(form.JobId, form.Status)
+	// spacelet只能回调调度到自身的任务
+	jobRun, err := h.models.PipelineRunManager.GetJobRun(form.JobId)
+	if err != nil {
+		return c.ResponseError(errors.New(code.DataNotExists, "get job run error: "+err.Error()))
+	}
+	if jobRun.SpaceletId != spaceletObj.ID {
+		return c.ResponseError(errors.New(code.ParamsError, fmt.Sprintf("job %d is not scheduled to spacelet %s", form.JobId, spaceletObj.HostIp)))
+	}
+	err = h.pipelineRunService.JobCallback(form.JobId, form.Status)
 	return c.ResponseError(err)
 }
@@ -0,0 +1,68 @@
+package spacelet
+
+import (
+	"github.com/kubespace/kubespace/pkg/core/code"
+	"github.com/kubespace/kubespace/pkg/core/errors"
+	"github.com/kubespace/kubespace/pkg/model"
+	"github.com/kubespace/kubespace/pkg/model/manager/pipeline"
+	"github.com/kubespace/kubespace/pkg/model/types"
+	"github.com/kubespace/kubespace/pkg/server/api/api"
+	"github.com/kubespace/kubespace/pkg/server/config"
+	"github.com/kubespace/kubespace/pkg/utils"
+	"time"
+)
+
+type drainHandler struct {
+	models *model.Models
+}
+
+// DrainHandler 设置spacelet节点排空，排空后不再调度新任务，正在执行的任务执行完成后可以对节点进行维护
+func DrainHandler(conf *config.ServerConfig) api.Handler {
+	return &drainHandler{models: conf.Models}
+}
+
+type drainSpaceletBody struct {
+	Drain bool `json:"drain"`
+}
+
+func (h *drainHandler) Auth(c *api.Context) (bool, *api.AuthPerm, error) {
+	return true, &api.AuthPerm{
+		Scope:   types.ScopePlatform,
+		ScopeId: 0,
+		Role:    types.RoleEditor,
+	}, nil
+}
+
+func (h *drainHandler) Handle(c *api.Context) *utils.Response {
+	id, err := utils.ParseUint(c.Param("id"))
+	if err != nil {
+		return c.ResponseError(errors.New(code.ParamsError, err))
+	}
+	var body drainSpaceletBody
+	if err = c.ShouldBind(&body); err != nil {
+		return c.ResponseError(errors.New(code.ParamsError, err))
+	}
+	spaceletObj, err := h.models.SpaceletManager.Get(id)
+	if err != nil {
+		return c.ResponseError(errors.New(code.DataNotExists, err))
+	}
+	spaceletObj.Drain = body.Drain
+	spaceletObj.UpdateTime = time.Now()
+	if err = h.models.SpaceletManager.Save(spaceletObj); err != nil {
+		return c.ResponseError(errors.New(code.DBError, err))
+	}
+	// 返回节点上还未执行完成的任务，任务数为0时排空完成
+	withSpacelet := true
+	runningJobs, err := h.models.PipelineRunManager.ListJobRun(&pipeline.JobRunListCondition{
+		WithSpacelet: &withSpacelet,
+		StatusIn:     []string{types.PipelineStatusWait, types.PipelineStatusDoing},
+		SpaceletIds:  []uint{id},
+	})
+	if err != nil {
+		return c.ResponseError(errors.New(code.DBError, err))
+	}
+	return c.ResponseOK(map[string]interface{}{
+		"drain":        spaceletObj.Drain,
+		"running_jobs": len(runningJobs),
+	})
+}
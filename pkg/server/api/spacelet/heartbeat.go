@@ -0,0 +1,62 @@
+package spacelet
+
+import (
+	"github.com/kubespace/kubespace/pkg/core/code"
+	"github.com/kubespace/kubespace/pkg/core/errors"
+	"github.com/kubespace/kubespace/pkg/model"
+	"github.com/kubespace/kubespace/pkg/model/types"
+	"github.com/kubespace/kubespace/pkg/server/api/api"
+	"github.com/kubespace/kubespace/pkg/server/config"
+	"github.com/kubespace/kubespace/pkg/spacelet"
+	"github.com/kubespace/kubespace/pkg/utils"
+	"time"
+)
+
+type heartbeatHandler struct {
+	models *model.Models
+}
+
+func HeartbeatHandler(conf *config.ServerConfig) api.Handler {
+	return &heartbeatHandler{models: conf.Models}
+}
+
+// Auth spacelet通过双向tls证书以及注册时获取的token认证
+func (h *heartbeatHandler) Auth(c *api.Context) (bool, *api.AuthPerm, error) {
+	return false, nil, nil
+}
+
+func (h *heartbeatHandler) Handle(c *api.Context) *utils.Response {
+	var req spacelet.HeartbeatRequest
+	if err := c.ShouldBind(&req); err != nil {
+		return c.ResponseError(errors.New(code.ParamsError, err))
+	}
+	spaceletObj, err := authSpacelet(c, h.models, req.Token)
+	if err != nil {
+		return c.ResponseError(err)
+	}
+	now := time.Now()
+	update := &types.Spacelet{
+		Version: req.Version,
+		Load: &types.SpaceletLoad{
+			Load1:          req.Load1,
+			RunningJobs:    len(req.RunningJobs),
+			JobIds:         req.RunningJobs,
+			FinishedJobIds: req.FinishedJobs,
+		},
+		HeartbeatTime: &now,
+		Status:        types.SpaceletStatusOnline,
+		UpdateTime:    now,
+	}
+	// spacelet使用当前token上报心跳，说明已确认轮换后的token，到期后再次轮换
+	if req.Token == spaceletObj.Token &&
+		(spaceletObj.TokenTime == nil || now.Sub(*spaceletObj.TokenTime) > types.SpaceletTokenRotateInterval) {
+		update.PrevToken = spaceletObj.Token
+		update.Token = utils.CreateUUID()
+		update.TokenTime = &now
+		spaceletObj.Token = update.Token
+	}
+	if err = h.models.SpaceletManager.Update(spaceletObj.ID, update); err != nil {
+		return c.ResponseError(errors.New(code.DBError, err))
+	}
+	return c.ResponseOK(&spacelet.HeartbeatResponse{Drain: spaceletObj.Drain, Token: spaceletObj.Token})
+}
@@ -8,6 +8,7 @@ import (
 	"github.com/kubespace/kubespace/pkg/model"
 	"github.com/kubespace/kubespace/pkg/server/api/api"
 	"github.com/kubespace/kubespace/pkg/server/config"
+	spaceletservice "github.com/kubespace/kubespace/pkg/service/spacelet"
 	"github.com/kubespace/kubespace/pkg/utils"
 	"io"
 	"text/template"
@@ -18,10 +19,14 @@ var installAgentShellTpl string
 
 type installHandler struct {
 	models *model.Models
+	pki    *spaceletservice.PKI
 }
 
 func InstallHandler(conf *config.ServerConfig) api.Handler {
-	return &installHandler{models: conf.Models}
+	return &installHandler{
+		models: conf.Models,
+		pki:    conf.ServiceFactory.Pipeline.SpaceletService.PKI,
+	}
 }
 
 type InstallSpaceletForm struct {
@@ -31,6 +36,8 @@ type InstallSpaceletForm struct {
 	ServerHost string `form:"server_host"`
 	DataDir    string `form:"data_dir,default=/data"`
 	HostIp     string `form:"host_ip"`
+	// Token 管理员生成的引导token，spacelet首次注册时使用
+	Token string `form:"token"`
 }
 
 func (h *installHandler) Auth(c *api.Context) (bool, *api.AuthPerm, error) {
@@ -46,7 +53,21 @@ func (h *installHandler) Handle(c *api.Context) *utils.Response {
 	if serverHost == "" {
 		serverHost = utils.RequestHost(c.Request)
 	}
+	valid, err := h.models.SpaceletManager.ValidBootstrapToken(form.Token)
+	if err != nil {
+		return c.ResponseError(errors.New(code.DBError, err))
+	}
+	if !valid {
+		return c.ResponseError(errors.New(code.ParamsError, "spacelet bootstrap token is invalid or expired"))
+	}
+	// spacelet注册时校验获取的CA证书
+	caHash, err := h.pki.CAHash()
+	if err != nil {
+		return c.ResponseError(errors.New(code.GetError, "get spacelet ca error: "+err.Error()))
+	}
 	placeholders := map[string]interface{}{
+		"CAHash":     caHash,
+		"Token":      form.Token,
 		"ServerHost": serverHost,
 		"OS":         form.OS,
 		"Arch":       form.Arch,
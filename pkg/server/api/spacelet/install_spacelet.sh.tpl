@@ -10,6 +10,8 @@ readonly HTTP_SERVER=http://{{ .ServerHost }}
 readonly SPACELET_PORT={{ .Port }}
 readonly SPACELET_DATADIR={{ .DataDir }}
 readonly SPACELET_HOSTIP={{ .HostIp }}
+readonly CA_HASH={{ .CAHash }}
+readonly BOOTSTRAP_TOKEN={{ .Token }}
 
 HostIpVar=
 if [ ${SPACELET_HOSTIP} != '' ]; then
@@ -47,7 +49,7 @@ Group=root
 # Limit the number of open files to avoid exhaustion
 LimitNOFILE=4096
 
-ExecStart=/usr/local/bin/spacelet --server-url ${HTTP_SERVER} --port ${SPACELET_PORT} --data-dir ${SPACELET_DATADIR} ${HostIpVar}
+ExecStart=/usr/local/bin/spacelet --server-url ${HTTP_SERVER} --port ${SPACELET_PORT} --data-dir ${SPACELET_DATADIR} --ca-hash ${CA_HASH} --bootstrap-token ${BOOTSTRAP_TOKEN} ${HostIpVar}
 # When kill the service timeout, it will be kill -9
 # TimeoutSec = TimeoutStartSec and TimeoutStopSec
 TimeoutSec=10
@@ -8,19 +8,26 @@ import (
 	"github.com/kubespace/kubespace/pkg/model/types"
 	"github.com/kubespace/kubespace/pkg/server/api/api"
 	"github.com/kubespace/kubespace/pkg/server/config"
+	spaceletservice "github.com/kubespace/kubespace/pkg/service/spacelet"
 	"github.com/kubespace/kubespace/pkg/spacelet"
-	"github.com/kubespace/kubespace/pkg/third/httpclient"
 	"github.com/kubespace/kubespace/pkg/utils"
+	"net"
 	"net/http"
 	"time"
 )
 
 type registerHandler struct {
-	models *model.Models
+	models  *model.Models
+	pki     *spaceletservice.PKI
+	tlsPort int
 }
 
 func RegisterHandler(conf *config.ServerConfig) api.Handler {
-	return &registerHandler{models: conf.Models}
+	return &registerHandler{
+		models:  conf.Models,
+		pki:     conf.ServiceFactory.Pipeline.SpaceletService.PKI,
+		tlsPort: conf.SpaceletPort,
+	}
 }
 
 func (h *registerHandler) Auth(c *api.Context) (bool, *api.AuthPerm, error) {
@@ -28,59 +35,113 @@ func (h *registerHandler) Auth(c *api.Context) (bool, *api.AuthPerm, error) {
 }
 
 func (h *registerHandler) Handle(c *api.Context) *utils.Response {
+	// 注册时传输引导token、spacelet token以及签发的证书，只允许通过tls访问
+	if c.Request.TLS == nil {
+		return c.ResponseError(errors.New(code.AuthError, "spacelet must register through kubespace mutual tls port, please upgrade spacelet"))
+	}
 	var req spacelet.RegisterRequest
 	if err := c.BindJSON(&req); err != nil {
 		c.JSON(http.StatusBadRequest, c.ResponseError(errors.New(code.ParamsError, err)))
 		return nil
 	}
-	if req.HostIp == "" {
-		req.HostIp = c.ClientIP()
+	// 签发的证书只使用连接的ip，不使用请求中的ip
+	connIp, _, err := net.SplitHostPort(c.Request.RemoteAddr)
+	if err != nil {
+		return c.ResponseError(errors.New(code.ParamsError, err))
+	}
+	if req.HostIp != "" && req.HostIp != connIp {
+		return c.ResponseError(errors.New(code.ParamsError,
+			fmt.Sprintf("spacelet host ip %s does not match connection ip %s", req.HostIp, connIp)))
 	}
+	req.HostIp = connIp
 	if req.Port == 0 {
 		req.Port = 7521
 	}
 	return h.register(&req)
 }
 
-// register 对spacelet进行注册，调用spacelet token接口，配置认证，之后将该spacelet入库
+// register 对spacelet进行注册，根据spacelet的证书签名请求签发证书，之后将该spacelet入库。
+// 已注册的spacelet需要提供之前获取的token才能重新注册，防止其它节点冒充，未注册的spacelet需要提供有效的引导token
 func (h *registerHandler) register(req *spacelet.RegisterRequest) *utils.Response {
 	if req.Hostname == "" {
 		return &utils.Response{Code: code.ParamsError, Msg: "param hostname is empty"}
 	}
-	httpcli, err := httpclient.NewHttpClient(fmt.Sprintf("http://%s:%d", req.HostIp, req.Port))
-	if err != nil {
-		return &utils.Response{Code: code.ParamsError, Msg: err.Error()}
+	if spacelet.ReservedName(req.Hostname) {
+		return &utils.Response{Code: code.ParamsError, Msg: fmt.Sprintf("hostname %s is reserved", req.Hostname)}
+	}
+	if req.Csr == "" {
+		return &utils.Response{Code: code.ParamsError, Msg: "param csr is empty, please upgrade spacelet"}
 	}
-	var tokenResp utils.Response
 	spaceletObj, err := h.models.SpaceletManager.GetByIpPort(req.HostIp, req.Port)
 	if err != nil {
 		return &utils.Response{Code: code.DBError, Msg: err.Error()}
 	}
-	token := &spacelet.RegisterToken{Token: utils.CreateUUID()}
-	if spaceletObj != nil {
-		// 如果已注册了，token不变
-		token.Token = spaceletObj.Token
+	if spaceletObj != nil && spaceletObj.TlsEnabled {
+		if !spaceletObj.ValidToken(req.Token) {
+			return &utils.Response{
+				Code: code.AuthError,
+				Msg:  fmt.Sprintf("spacelet %s:%d already registered, token is incorrect", req.HostIp, req.Port),
+			}
+		}
+	} else {
+		// 未注册或者未签发过证书的旧版本spacelet需要提供引导token
+		valid, err := h.models.SpaceletManager.ValidBootstrapToken(req.BootstrapToken)
+		if err != nil {
+			return &utils.Response{Code: code.DBError, Msg: err.Error()}
+		}
+		if !valid {
+			return &utils.Response{Code: code.AuthError, Msg: "spacelet bootstrap token is invalid or expired"}
+		}
 	}
-	// 调用spacelet token接口，配置认证
-	if _, err = httpcli.Post("/v1/token", token, &tokenResp, httpclient.RequestOptions{}); err != nil {
-		return &utils.Response{Code: code.RequestError, Msg: err.Error()}
+	cert, err := h.pki.SignSpaceletCert([]byte(req.Csr), req.HostIp, req.Port)
+	if err != nil {
+		return &utils.Response{Code: code.ParamsError, Msg: "sign spacelet cert error: " + err.Error()}
 	}
-	if !tokenResp.IsSuccess() {
-		return &utils.Response{Code: code.RequestError, Msg: fmt.Sprintf("set spacelet token error: %s", tokenResp.Msg)}
+	caCert, err := h.pki.CACertPEM()
+	if err != nil {
+		return &utils.Response{Code: code.GetError, Msg: "get spacelet ca error: " + err.Error()}
 	}
+	now := time.Now()
 	if spaceletObj == nil {
 		// 如果不存在则入库
-		if _, err = h.models.SpaceletManager.Create(&types.Spacelet{
+		if spaceletObj, err = h.models.SpaceletManager.Create(&types.Spacelet{
 			Hostname:   req.Hostname,
 			HostIp:     req.HostIp,
 			Port:       req.Port,
-			Token:      token.Token,
+			MaxJobs:    req.MaxJobs,
+			Cpu:        req.Cpu,
+			Memory:     req.Memory,
+			Token:      utils.CreateUUID(),
+			TokenTime:  &now,
+			TlsEnabled: true,
 			Status:     types.SpaceletStatusOnline,
-			CreateTime: time.Now(),
-			UpdateTime: time.Now(),
+			CreateTime: now,
+			UpdateTime: now,
 		}); err != nil {
 			return &utils.Response{Code: code.DBError, Msg: err.Error()}
 		}
+	} else {
+		if !spaceletObj.TlsEnabled {
+			// 旧版本spacelet升级后重新生成token
+			spaceletObj.Token = utils.CreateUUID()
+			spaceletObj.PrevToken = ""
+			spaceletObj.TokenTime = &now
+		}
+		// 已注册的spacelet重启后更新上报的节点容量
+		spaceletObj.Hostname = req.Hostname
+		spaceletObj.MaxJobs = req.MaxJobs
+		spaceletObj.Cpu = req.Cpu
+		spaceletObj.Memory = req.Memory
+		spaceletObj.TlsEnabled = true
+		spaceletObj.UpdateTime = now
+		if err = h.models.SpaceletManager.Save(spaceletObj); err != nil {
+			return &utils.Response{Code: code.DBError, Msg: err.Error()}
+		}
 	}
-	return &utils.Response{Code: code.Success}
+	return &utils.Response{Code: code.Success, Data: &spacelet.RegisterResponse{
+		Token:   spaceletObj.Token,
+		Cert:    string(cert),
+		CaCert:  string(caCert),
+		TlsPort: h.tlsPort,
+	}}
 }
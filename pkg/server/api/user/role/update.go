@@ -111,6 +111,12 @@ func getScopeName(models *model.Models, scope string, scopeId uint) (string, err
 		} else {
 			scopeName = pipespace.Name
 		}
+	case types.ScopeClusterGroup:
+		if group, err := models.ClusterManager.GetGroup(scopeId); err != nil {
+			return "", errors.New(code.DataNotExists, fmt.Sprintf("获取集群组id=%d失败：%s", scopeId, err.Error()))
+		} else {
+			scopeName = group.Name
+		}
 	}
 	return scopeName, nil
 }
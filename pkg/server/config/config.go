@@ -23,6 +23,8 @@ type ServerConfig struct {
 	InformerFactory informer.Factory
 	ServiceFactory  *service.Factory
 	ReleaseVersion  string
+	// SpaceletPort spacelet访问kubespace的双向tls端口，spacelet心跳以及任务回调需要通过该端口
+	SpaceletPort int
 }
 
 func NewServerConfig(op *ServerOptions) (*ServerConfig, error) {
@@ -66,6 +68,7 @@ func NewServerConfig(op *ServerOptions) (*ServerConfig, error) {
 	models, err := model.NewModels(&model.Config{
 		DB:                db,
 		ListWatcherConfig: listWatcherConfig,
+		EncryptionKey:     op.EncryptionKey,
 	})
 	if err != nil {
 		return nil, err
@@ -84,5 +87,6 @@ func NewServerConfig(op *ServerOptions) (*ServerConfig, error) {
 		InformerFactory: informerFactory,
 		ServiceFactory:  serviceFactory,
 		ReleaseVersion:  op.ReleaseVersion,
+		SpaceletPort:    op.SpaceletPort,
 	}, nil
 }
@@ -16,4 +16,8 @@ type ServerOptions struct {
 	AgentVersion         string
 	AgentRepository      string
 	ReleaseVersion       string
+	// SpaceletPort spacelet访问kubespace的双向tls端口
+	SpaceletPort int
+	// EncryptionKey 数据库中敏感数据的加密密钥
+	EncryptionKey string
 }
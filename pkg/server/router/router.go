@@ -84,7 +84,7 @@ func (r *Router) apiWrapper(handler apictx.Handler) gin.HandlerFunc {
 		}
 		if needAuth {
 			// 获取认证用户
-			user, err := r.auth.Authenticate(context)
+			user, err := r.auth.Authenticate(context, allowBasicAuth(handler))
 			if err != nil {
 				c.JSON(http.StatusUnauthorized, context.ResponseError(errors.New(code.AuthError, err, errors.Overlap)))
 				return
@@ -109,6 +109,12 @@ func (r *Router) apiWrapper(handler apictx.Handler) gin.HandlerFunc {
 	}
 }
 
+// allowBasicAuth api是否允许通过http basic认证，只有chart仓库等需要命令行访问的api允许
+func allowBasicAuth(handler apictx.Handler) bool {
+	basicAuthHandler, ok := handler.(apictx.BasicAuthHandler)
+	return ok && basicAuthHandler.AllowBasicAuth()
+}
+
 func LocalMiddleware() gin.HandlerFunc {
 	return func(c *gin.Context) {
 		defer func() {
@@ -0,0 +1,58 @@
+package router
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/kubespace/kubespace/pkg/model"
+	"github.com/kubespace/kubespace/pkg/server/api"
+	apictx "github.com/kubespace/kubespace/pkg/server/api/api"
+	"github.com/kubespace/kubespace/pkg/server/config"
+	"github.com/kubespace/kubespace/pkg/service"
+	"github.com/kubespace/kubespace/pkg/utils"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// loginRequiredHandler 需要登录认证的api，认证通过后返回成功
+type loginRequiredHandler struct{}
+
+func (h *loginRequiredHandler) Auth(c *apictx.Context) (bool, *apictx.AuthPerm, error) {
+	return true, nil, nil
+}
+
+func (h *loginRequiredHandler) Handle(c *apictx.Context) *utils.Response {
+	return c.ResponseOK(c.User.Name)
+}
+
+func newTestConfig() *config.ServerConfig {
+	models := &model.Models{}
+	return &config.ServerConfig{
+		Models:         models,
+		ServiceFactory: service.NewServiceFactory(service.NewConfig(models)),
+	}
+}
+
+func TestBasicAuthRejectedOutsideChartRepo(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	conf := newTestConfig()
+	r := &Router{Engine: gin.New(), conf: conf, auth: apictx.NewAuth(conf)}
+	r.GET("/api/v1/cluster/list", r.apiWrapper(&loginRequiredHandler{}))
+
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/cluster/list", nil)
+	req.SetBasicAuth("admin", "password")
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	if w.Code != http.StatusUnauthorized {
+		t.Errorf("status code = %d, want %d, body: %s", w.Code, http.StatusUnauthorized, w.Body.String())
+	}
+}
+
+func TestAllowBasicAuthOnlyChartRepo(t *testing.T) {
+	for group, apis := range api.Apis(newTestConfig()) {
+		for _, a := range apis.Apis() {
+			if allowed := allowBasicAuth(a.Handler); allowed != (group == "chartrepo") {
+				t.Errorf("api %s %s/%s allow basic auth = %v", a.Method, group, a.Path, allowed)
+			}
+		}
+	}
+}
@@ -1,9 +1,11 @@
 package server
 
 import (
+	"crypto/tls"
 	"fmt"
 	"github.com/kubespace/kubespace/pkg/server/config"
 	"github.com/kubespace/kubespace/pkg/server/router"
+	"k8s.io/klog/v2"
 	"net/http"
 )
 
@@ -30,9 +32,37 @@ func (s *Server) Run() {
 	}
 	go insecureServer.ListenAndServe()
 
+	go s.runSpaceletServer()
+
 	server := &http.Server{
 		Addr:    fmt.Sprintf(":%d", s.config.Port),
 		Handler: s.router,
 	}
+	// spacelet访问kubespace时提供kubespace签发的客户端证书，提供时进行校验
+	if pool, err := s.config.ServiceFactory.Pipeline.SpaceletService.PKI.CertPool(); err != nil {
+		klog.Errorf("load spacelet ca error: %s", err.Error())
+	} else {
+		server.TLSConfig = &tls.Config{
+			ClientAuth: tls.VerifyClientCertIfGiven,
+			ClientCAs:  pool,
+		}
+	}
 	server.ListenAndServeTLS(s.config.CertFilePath, s.config.KeyFilePath)
 }
+
+// runSpaceletServer spacelet访问kubespace的双向tls端口，服务端证书由spacelet CA签发，并要求spacelet提供客户端证书
+func (s *Server) runSpaceletServer() {
+	tlsConfig, err := s.config.ServiceFactory.Pipeline.SpaceletService.PKI.ServerTLSConfig()
+	if err != nil {
+		klog.Errorf("load spacelet tls config error: %s", err.Error())
+		return
+	}
+	server := &http.Server{
+		Addr:      fmt.Sprintf(":%d", s.config.SpaceletPort),
+		Handler:   s.router,
+		TLSConfig: tlsConfig,
+	}
+	if err = server.ListenAndServeTLS("", ""); err != nil {
+		klog.Errorf("spacelet tls server error: %s", err.Error())
+	}
+}
@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"github.com/kubespace/kubespace/pkg/core/code"
 	"github.com/kubespace/kubespace/pkg/informer/listwatcher/cluster"
+	"github.com/kubespace/kubespace/pkg/kubernetes/resource"
 	kubetypes "github.com/kubespace/kubespace/pkg/kubernetes/types"
 	"github.com/kubespace/kubespace/pkg/model"
 	"github.com/kubespace/kubespace/pkg/model/types"
@@ -11,6 +12,9 @@ import (
 	"k8s.io/klog/v2"
 )
 
+// agentRequestTimeout 等待agent返回请求结果的默认超时时间，单位秒
+const agentRequestTimeout = 30
+
 func NewTraceId() string {
 	return fmt.Sprintf("kubespace:agent:response:%s", utils.CreateUUID())
 }
@@ -23,30 +27,30 @@ func NewAgentClient(models *model.Models) *AgentClient {
 	return &AgentClient{models: models}
 }
 
-func (a *AgentClient) request(clusterObj *types.Cluster, resType, action string, params interface{}) *utils.Response {
-	if handler, err := newAgentHandler(clusterObj, a.models); err != nil {
+func (a *AgentClient) request(clusterObj *types.Cluster, impersonate *kubetypes.Impersonate, resType, action string, params interface{}) *utils.Response {
+	if handler, err := newAgentHandler(clusterObj, impersonate, a.models); err != nil {
 		return &utils.Response{Code: code.RequestError, Msg: err.Error()}
 	} else {
 		return handler.Handle("", resType, action, params)
 	}
 }
 
-func (a *AgentClient) watch(clusterObj *types.Cluster, resType string, params interface{}) (Outer, error) {
-	handler, err := newAgentHandler(clusterObj, a.models)
+func (a *AgentClient) watch(clusterObj *types.Cluster, impersonate *kubetypes.Impersonate, resType string, params interface{}) (Outer, error) {
+	handler, err := newAgentHandler(clusterObj, impersonate, a.models)
 	if err != nil {
 		return nil, err
 	}
 	traceId := NewTraceId()
 	resp := handler.Handle(traceId, resType, kubetypes.WatchAction, params)
 	if !resp.IsSuccess() {
-		return nil, fmt.Errorf(resp.Msg)
+		return nil, fmt.Errorf("%s", resp.Msg)
 	}
 	o := newAgentOuter(traceId, handler)
 	return o, nil
 }
 
-func (a *AgentClient) pods(clusterObj *types.Cluster) (PodClient, error) {
-	if handler, err := newAgentHandler(clusterObj, a.models); err != nil {
+func (a *AgentClient) pods(clusterObj *types.Cluster, impersonate *kubetypes.Impersonate) (PodClient, error) {
+	if handler, err := newAgentHandler(clusterObj, impersonate, a.models); err != nil {
 		return nil, err
 	} else {
 		return &agentPod{handler}, nil
@@ -57,19 +61,26 @@ type agentHandler struct {
 	models     *model.Models
 	clusterObj *types.Cluster
 	watcher    cluster.AgentListWatcher
+	// impersonate 发送到agent的请求都以该用户身份访问集群
+	impersonate *kubetypes.Impersonate
 }
 
-func newAgentHandler(clusterObj *types.Cluster, models *model.Models) (*agentHandler, error) {
-	agentListWatcher := cluster.NewAgentListWatcher(clusterObj.Token, models.ListWatcherConfig)
+func newAgentHandler(clusterObj *types.Cluster, impersonate *kubetypes.Impersonate, models *model.Models) (*agentHandler, error) {
+	// 旧版本agent会忽略用户模拟参数，以agent自身的身份访问集群，开启用户模拟时拒绝请求
+	if impersonate != nil && !clusterObj.AgentSupportImpersonate() {
+		return nil, fmt.Errorf("集群%s的agent版本过旧，不支持用户模拟，请重新导入升级agent", clusterObj.Name1)
+	}
+	agentListWatcher := cluster.NewAgentListWatcher(clusterObj.Name, models.ListWatcherConfig)
 	if watched, err := agentListWatcher.Watched(); err != nil {
 		return nil, err
 	} else if !watched {
 		return nil, fmt.Errorf("connect kubernetes agent error")
 	}
 	return &agentHandler{
-		models:     models,
-		clusterObj: clusterObj,
-		watcher:    agentListWatcher,
+		models:      models,
+		clusterObj:  clusterObj,
+		watcher:     agentListWatcher,
+		impersonate: impersonate,
 	}, nil
 }
 
@@ -78,18 +89,38 @@ func (a *agentHandler) Handle(traceId, resType, action string, params interface{
 		traceId = NewTraceId()
 	}
 	return a.watcher.NotifyResult(&kubetypes.Request{
-		TraceId:  traceId,
-		Resource: resType,
-		Action:   action,
-		Params:   params,
-	}, 30)
+		TraceId:     traceId,
+		Resource:    resType,
+		Action:      action,
+		Params:      params,
+		Impersonate: a.impersonate,
+	}, requestTimeout(resType, action, params))
+}
+
+// requestTimeout 等待agent返回结果的超时时间，helm安装/升级时agent会等待资源就绪以及hook执行，
+// 需要在helm超时时间的基础上增加默认超时时间，避免helm仍在执行时请求已超时返回失败
+func requestTimeout(resType, action string, params interface{}) int {
+	if resType != kubetypes.HelmType || (action != kubetypes.CreateAction && action != kubetypes.UpdateAction) {
+		return agentRequestTimeout
+	}
+	helmParams, ok := params.(*resource.HelmObjectParams)
+	if !ok {
+		helmParams = &resource.HelmObjectParams{}
+		if err := utils.ConvertTypeByJson(params, helmParams); err != nil {
+			return agentRequestTimeout
+		}
+	}
+	if helmParams.DryRun {
+		return agentRequestTimeout
+	}
+	return int(helmParams.WaitTimeout().Seconds()) + agentRequestTimeout
 }
 
 func (a *agentHandler) CloseSession(traceId string) *utils.Response {
 	return a.watcher.NotifyResult(&kubetypes.Request{
 		TraceId: traceId,
 		Action:  kubetypes.CloseSession,
-	}, 30)
+	}, agentRequestTimeout)
 }
 
 func (a *agentHandler) Watch(traceId string, stopCh <-chan struct{}) <-chan []byte {
@@ -141,7 +172,7 @@ func (a *agentPod) Exec(params interface{}) (PodExec, error) {
 	traceId := NewTraceId()
 	resp := a.handler.Handle(traceId, kubetypes.PodType, kubetypes.ExecAction, params)
 	if !resp.IsSuccess() {
-		return nil, fmt.Errorf(resp.Msg)
+		return nil, fmt.Errorf("%s", resp.Msg)
 	}
 	return newAgentPodExec(traceId, a.handler), nil
 }
@@ -150,7 +181,7 @@ func (a *agentPod) Log(params interface{}) (Outer, error) {
 	traceId := NewTraceId()
 	resp := a.handler.Handle(traceId, kubetypes.PodType, kubetypes.LogAction, params)
 	if !resp.IsSuccess() {
-		return nil, fmt.Errorf(resp.Msg)
+		return nil, fmt.Errorf("%s", resp.Msg)
 	}
 	return newAgentOuter(traceId, a.handler), nil
 }
@@ -170,7 +201,7 @@ func newAgentPodExec(traceId string, handler *agentHandler) *agentPodExec {
 func (a *agentPodExec) Stdin(params interface{}) error {
 	resp := a.handler.Handle("", kubetypes.PodType, kubetypes.StdinAction, params)
 	if !resp.IsSuccess() {
-		return fmt.Errorf(resp.Msg)
+		return fmt.Errorf("%s", resp.Msg)
 	}
 	return nil
 }
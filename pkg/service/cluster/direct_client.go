@@ -13,17 +13,22 @@ import (
 
 type directClient struct{}
 
-func (d *directClient) getKubeFactory(cluster *types.Cluster) (kubernetes.KubeFactory, error) {
+func (d *directClient) getKubeFactory(cluster *types.Cluster, impersonate *kubetypes.Impersonate) (kubernetes.KubeFactory, error) {
 	kubeConfig, err := config.NewKubeConfig(&config.Options{KubeConfigString: cluster.KubeConfig})
 	if err != nil {
 		return nil, err
 	}
+	if impersonate != nil {
+		if kubeConfig, err = kubeConfig.Impersonate(impersonate.User, impersonate.Groups); err != nil {
+			return nil, err
+		}
+	}
 	kubeFactory := kubernetes.NewKubeFactory(kubeConfig)
 	return kubeFactory, nil
 }
 
-func (d *directClient) request(cluster *types.Cluster, resType, action string, params interface{}) *utils.Response {
-	kubeFactory, err := d.getKubeFactory(cluster)
+func (d *directClient) request(cluster *types.Cluster, impersonate *kubetypes.Impersonate, resType, action string, params interface{}) *utils.Response {
+	kubeFactory, err := d.getKubeFactory(cluster, impersonate)
 	if err != nil {
 		return &utils.Response{Code: code.RequestError, Msg: err.Error()}
 	}
@@ -34,8 +39,8 @@ func (d *directClient) request(cluster *types.Cluster, resType, action string, p
 	}
 }
 
-func (d *directClient) watch(cluster *types.Cluster, resType string, params interface{}) (Outer, error) {
-	kubeFactory, err := d.getKubeFactory(cluster)
+func (d *directClient) watch(cluster *types.Cluster, impersonate *kubetypes.Impersonate, resType string, params interface{}) (Outer, error) {
+	kubeFactory, err := d.getKubeFactory(cluster, impersonate)
 	if err != nil {
 		return nil, err
 	}
@@ -46,14 +51,14 @@ func (d *directClient) watch(cluster *types.Cluster, resType string, params inte
 	o := &directOuter{outer: newOuter()}
 	resp := resHandler.Watch(params, o)
 	if !resp.IsSuccess() {
-		return nil, fmt.Errorf(resp.Msg)
+		return nil, fmt.Errorf("%s", resp.Msg)
 	}
 	return o, nil
 
 }
 
-func (d *directClient) pods(cluster *types.Cluster) (PodClient, error) {
-	kubeFactory, err := d.getKubeFactory(cluster)
+func (d *directClient) pods(cluster *types.Cluster, impersonate *kubetypes.Impersonate) (PodClient, error) {
+	kubeFactory, err := d.getKubeFactory(cluster, impersonate)
 	if err != nil {
 		return nil, err
 	}
@@ -96,7 +101,7 @@ func (d *directPod) Exec(params interface{}) (PodExec, error) {
 	}
 	resp := d.PodHandler.Exec(params, exec)
 	if !resp.IsSuccess() {
-		return nil, fmt.Errorf(resp.Msg)
+		return nil, fmt.Errorf("%s", resp.Msg)
 	}
 	return exec, nil
 }
@@ -105,7 +110,7 @@ func (d *directPod) Log(params interface{}) (Outer, error) {
 	logOuter := &directOuter{outer: newOuter()}
 	resp := d.PodHandler.Log(params, logOuter)
 	if !resp.IsSuccess() {
-		return nil, fmt.Errorf(resp.Msg)
+		return nil, fmt.Errorf("%s", resp.Msg)
 	}
 	return logOuter, nil
 }
@@ -0,0 +1,233 @@
+package cluster
+
+import (
+	"fmt"
+	"github.com/kubespace/kubespace/pkg/kubernetes/resource"
+	"github.com/kubespace/kubespace/pkg/model"
+	clustermgr "github.com/kubespace/kubespace/pkg/model/manager/cluster"
+	"github.com/kubespace/kubespace/pkg/model/types"
+	"github.com/kubespace/kubespace/pkg/utils"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// fleetConcurrency 同时查询的集群数量
+	fleetConcurrency = 10
+	// fleetClusterTimeout 单个集群的查询超时时间，超时的集群记录错误，不影响其他集群的结果
+	fleetClusterTimeout = 30 * time.Second
+)
+
+// FleetQuery 跨集群资源查询条件
+type FleetQuery struct {
+	// LabelSelector/GroupId 按集群标签或者集群组选择集群，均为空时查询所有有权限的集群
+	LabelSelector string `json:"label_selector"`
+	GroupId       uint   `json:"group_id"`
+	// ResType 资源类型，如deployment、pod
+	ResType   string `json:"res_type"`
+	Namespace string `json:"namespace"`
+	// ResourceSelector 按资源标签过滤
+	ResourceSelector *metav1.LabelSelector `json:"resource_selector"`
+	// Image 只返回容器镜像包含该字符串的资源，适用于pod以及工作负载
+	Image string `json:"image"`
+	// WithObject 是否返回资源的完整数据
+	WithObject bool `json:"with_object"`
+}
+
+// FleetItem 查询到的资源
+type FleetItem struct {
+	ClusterId   uint                   `json:"cluster_id"`
+	ClusterName string                 `json:"cluster_name"`
+	Name        string                 `json:"name"`
+	Namespace   string                 `json:"namespace"`
+	Labels      map[string]string      `json:"labels"`
+	Images      []string               `json:"images"`
+	CreateTime  metav1.Time            `json:"create_time"`
+	Object      map[string]interface{} `json:"object,omitempty"`
+}
+
+// FleetClusterResult 每个集群的查询结果，查询失败时Error不为空
+type FleetClusterResult struct {
+	ClusterId   uint   `json:"cluster_id"`
+	ClusterName string `json:"cluster_name"`
+	Count       int    `json:"count"`
+	Error       string `json:"error"`
+}
+
+type FleetResult struct {
+	Items    []*FleetItem          `json:"items"`
+	Clusters []*FleetClusterResult `json:"clusters"`
+}
+
+type FleetService struct {
+	models     *model.Models
+	kubeClient *KubeClient
+}
+
+func NewFleetService(models *model.Models, kubeClient *KubeClient) *FleetService {
+	return &FleetService{
+		models:     models,
+		kubeClient: kubeClient,
+	}
+}
+
+// Clusters 获取查询条件匹配并且用户有查看权限的集群
+func (f *FleetService) Clusters(user *types.User, query *FleetQuery) ([]*types.Cluster, error) {
+	selector := query.LabelSelector
+	if query.GroupId != 0 {
+		group, err := f.models.ClusterManager.GetGroup(query.GroupId)
+		if err != nil {
+			return nil, fmt.Errorf("获取集群组id=%d失败：%s", query.GroupId, err.Error())
+		}
+		if selector != "" {
+			selector += ","
+		}
+		selector += group.Selector
+	}
+	clusters, err := f.models.ClusterManager.List(clustermgr.ListClusterCondition{LabelSelector: selector})
+	if err != nil {
+		return nil, err
+	}
+	var allowed []*types.Cluster
+	for _, c := range clusters {
+		if f.models.UserRoleManager.AuthRole(user, types.ScopeCluster, c.ID, types.RoleViewer) {
+			allowed = append(allowed, c)
+		}
+	}
+	sort.Slice(allowed, func(i, j int) bool {
+		return allowed[i].Name1 < allowed[j].Name1
+	})
+	return allowed, nil
+}
+
+// List 并发查询匹配的集群中的资源，汇总结果，单个集群失败时记录错误
+func (f *FleetService) List(user *types.User, query *FleetQuery) (*FleetResult, error) {
+	if query.ResType == "" {
+		return nil, fmt.Errorf("资源类型不能为空")
+	}
+	clusters, err := f.Clusters(user, query)
+	if err != nil {
+		return nil, err
+	}
+	kubeClient := f.kubeClient.WithUser(user)
+	results := make([]*FleetClusterResult, len(clusters))
+	items := make([][]*FleetItem, len(clusters))
+
+	sem := make(chan struct{}, fleetConcurrency)
+	var wg sync.WaitGroup
+	for i, c := range clusters {
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(i int, c *types.Cluster) {
+			defer wg.Done()
+			defer func() { <-sem }()
+			results[i] = &FleetClusterResult{ClusterId: c.ID, ClusterName: c.Name1}
+			clusterItems, err := f.listCluster(kubeClient, c, query)
+			if err != nil {
+				results[i].Error = err.Error()
+				return
+			}
+			results[i].Count = len(clusterItems)
+			items[i] = clusterItems
+		}(i, c)
+	}
+	wg.Wait()
+
+	res := &FleetResult{Items: []*FleetItem{}, Clusters: results}
+	for _, clusterItems := range items {
+		res.Items = append(res.Items, clusterItems...)
+	}
+	return res, nil
+}
+
+// listCluster 查询单个集群，超时后直接返回，查询在后台结束
+func (f *FleetService) listCluster(kubeClient *KubeClient, c *types.Cluster, query *FleetQuery) ([]*FleetItem, error) {
+	process := false
+	respCh := make(chan *utils.Response, 1)
+	go func() {
+		defer utils.HandleCrash(func(r interface{}) {
+			respCh <- &utils.Response{Msg: fmt.Sprintf("查询集群资源异常：%v", r)}
+		})
+		respCh <- kubeClient.List(c.Name, query.ResType, &resource.QueryParams{
+			Namespace:     query.Namespace,
+			LabelSelector: query.ResourceSelector,
+			Process:       &process,
+		})
+	}()
+	var resp *utils.Response
+	select {
+	case resp = <-respCh:
+	case <-time.After(fleetClusterTimeout):
+		return nil, fmt.Errorf("查询集群超时")
+	}
+	if !resp.IsSuccess() {
+		return nil, fmt.Errorf("%s", resp.Msg)
+	}
+	var objs []map[string]interface{}
+	if err := utils.ConvertTypeByJson(resp.Data, &objs); err != nil {
+		return nil, err
+	}
+	var items []*FleetItem
+	for _, obj := range objs {
+		u := &unstructured.Unstructured{Object: obj}
+		images := containerImages(u)
+		if query.Image != "" && !containsImage(images, query.Image) {
+			continue
+		}
+		item := &FleetItem{
+			ClusterId:   c.ID,
+			ClusterName: c.Name1,
+			Name:        u.GetName(),
+			Namespace:   u.GetNamespace(),
+			Labels:      u.GetLabels(),
+			Images:      images,
+			CreateTime:  u.GetCreationTimestamp(),
+		}
+		if query.WithObject {
+			item.Object = obj
+		}
+		items = append(items, item)
+	}
+	return items, nil
+}
+
+// containerPaths pod、工作负载以及cronjob中容器所在的路径
+var containerPaths = [][]string{
+	{"spec"},
+	{"spec", "template", "spec"},
+	{"spec", "jobTemplate", "spec", "template", "spec"},
+}
+
+// containerImages 获取资源中所有容器以及初始化容器的镜像
+func containerImages(u *unstructured.Unstructured) []string {
+	var images []string
+	for _, path := range containerPaths {
+		for _, field := range []string{"initContainers", "containers"} {
+			containers, found, _ := unstructured.NestedSlice(u.Object, append(append([]string{}, path...), field)...)
+			if !found {
+				continue
+			}
+			for _, container := range containers {
+				if m, ok := container.(map[string]interface{}); ok {
+					if image, ok := m["image"].(string); ok && image != "" {
+						images = append(images, image)
+					}
+				}
+			}
+		}
+	}
+	return images
+}
+
+func containsImage(images []string, image string) bool {
+	for _, i := range images {
+		if strings.Contains(i, image) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,265 @@
+package cluster
+
+import (
+	"fmt"
+	"github.com/kubespace/kubespace/pkg/kubernetes/resource"
+	kubetypes "github.com/kubespace/kubespace/pkg/kubernetes/types"
+	"github.com/kubespace/kubespace/pkg/model"
+	"github.com/kubespace/kubespace/pkg/model/types"
+	"github.com/kubespace/kubespace/pkg/utils"
+	"math"
+	"time"
+)
+
+// 集群健康检查项
+const (
+	HealthCheckConnection  = "connection"
+	HealthCheckApiserver   = "apiserver"
+	HealthCheckNodes       = "nodes"
+	HealthCheckComponents  = "components"
+	HealthCheckCoreDNS     = "coredns"
+	HealthCheckPods        = "pods"
+	HealthCheckEvents      = "events"
+	HealthCheckCertificate = "certificate"
+)
+
+// healthStatusLevel 健康状态的严重程度，集群的健康状态为所有检查项中最严重的状态
+var healthStatusLevel = map[string]int{
+	types.ClusterHealthHealthy:  0,
+	types.ClusterHealthUnknown:  1,
+	types.ClusterHealthWarning:  2,
+	types.ClusterHealthCritical: 3,
+}
+
+type HealthService struct {
+	models     *model.Models
+	kubeClient *KubeClient
+}
+
+func NewHealthService(models *model.Models, kubeClient *KubeClient) *HealthService {
+	return &HealthService{
+		models:     models,
+		kubeClient: kubeClient,
+	}
+}
+
+// Probe 对集群进行健康检查，根据集群的阈值判断各检查项的状态，并保存检查记录
+func (h *HealthService) Probe(clusterObj *types.Cluster) (*types.ClusterHealth, error) {
+	health := &types.ClusterHealth{
+		ClusterId: clusterObj.ID,
+		CheckTime: time.Now(),
+	}
+	resp := h.kubeClient.Request(clusterObj.Name, kubetypes.ClusterType, kubetypes.HealthAction, &resource.ClusterHealthParams{})
+	if resp.IsSuccess() {
+		data := &resource.ClusterHealthData{}
+		if err := utils.ConvertTypeByJson(resp.Data, data); err != nil {
+			return nil, err
+		}
+		health.Version = data.Version
+		health.Checks = EvaluateHealth(data, clusterObj.Thresholds())
+	} else {
+		health.Checks = types.ClusterHealthChecks{{
+			Name:    HealthCheckConnection,
+			Status:  types.ClusterHealthUnknown,
+			Message: "获取集群健康检查数据失败：" + resp.Msg,
+		}}
+	}
+	health.Status = worstStatus(health.Checks)
+	if err := h.models.ClusterManager.CreateHealth(health); err != nil {
+		return nil, err
+	}
+	return health, nil
+}
+
+// EvaluateHealth 根据阈值判断各检查项的健康状态
+func EvaluateHealth(data *resource.ClusterHealthData, t *types.ClusterHealthThresholds) types.ClusterHealthChecks {
+	return types.ClusterHealthChecks{
+		apiserverCheck(data, t),
+		nodesCheck(data, t),
+		componentsCheck(data),
+		coreDNSCheck(data),
+		podsCheck(data, t),
+		eventsCheck(data, t),
+		certificateCheck(data, t),
+	}
+}
+
+// thresholdStatus 数值越大越严重的检查项
+func thresholdStatus(value, warning, critical float64) string {
+	if value >= critical {
+		return types.ClusterHealthCritical
+	}
+	if value >= warning {
+		return types.ClusterHealthWarning
+	}
+	return types.ClusterHealthHealthy
+}
+
+func worstStatus(checks types.ClusterHealthChecks) string {
+	status := types.ClusterHealthHealthy
+	for _, check := range checks {
+		if healthStatusLevel[check.Status] > healthStatusLevel[status] {
+			status = check.Status
+		}
+	}
+	return status
+}
+
+func apiserverCheck(data *resource.ClusterHealthData, t *types.ClusterHealthThresholds) *types.ClusterHealthCheck {
+	check := &types.ClusterHealthCheck{
+		Name:     HealthCheckApiserver,
+		Value:    float64(data.ApiserverLatency),
+		Warning:  float64(t.ApiserverLatencyWarning),
+		Critical: float64(t.ApiserverLatencyCritical),
+	}
+	if data.ApiserverError != "" {
+		check.Status = types.ClusterHealthCritical
+		check.Message = "apiserver未就绪：" + data.ApiserverError
+		return check
+	}
+	check.Status = thresholdStatus(check.Value, check.Warning, check.Critical)
+	check.Message = fmt.Sprintf("apiserver请求耗时%dms", data.ApiserverLatency)
+	return check
+}
+
+func nodesCheck(data *resource.ClusterHealthData, t *types.ClusterHealthThresholds) *types.ClusterHealthCheck {
+	var notReady, pressure int
+	var unhealthy []*resource.NodeHealth
+	for _, node := range data.Nodes {
+		if !node.Ready {
+			notReady += 1
+		}
+		if len(node.Pressures) > 0 {
+			pressure += 1
+		}
+		if !node.Ready || len(node.Pressures) > 0 {
+			unhealthy = append(unhealthy, node)
+		}
+	}
+	check := &types.ClusterHealthCheck{
+		Name:     HealthCheckNodes,
+		Value:    float64(notReady),
+		Warning:  float64(t.NotReadyNodesWarning),
+		Critical: float64(t.NotReadyNodesCritical),
+		Message:  fmt.Sprintf("共%d个节点，%d个未就绪，%d个存在资源压力", len(data.Nodes), notReady, pressure),
+		Details:  unhealthy,
+	}
+	check.Status = thresholdStatus(check.Value, check.Warning, check.Critical)
+	if len(data.Nodes) > 0 && notReady == len(data.Nodes) {
+		check.Status = types.ClusterHealthCritical
+	}
+	if pressure > 0 && check.Status == types.ClusterHealthHealthy {
+		check.Status = types.ClusterHealthWarning
+	}
+	return check
+}
+
+func componentsCheck(data *resource.ClusterHealthData) *types.ClusterHealthCheck {
+	check := &types.ClusterHealthCheck{Name: HealthCheckComponents, Details: data.Components}
+	if len(data.Components) == 0 {
+		check.Status = types.ClusterHealthUnknown
+		check.Message = "未获取到控制面组件状态，托管集群的控制面一般不可见"
+		return check
+	}
+	var unhealthy int
+	for _, c := range data.Components {
+		if !c.Healthy {
+			unhealthy += 1
+		}
+	}
+	check.Value = float64(unhealthy)
+	check.Status = types.ClusterHealthHealthy
+	if unhealthy > 0 {
+		check.Status = types.ClusterHealthCritical
+	}
+	check.Message = fmt.Sprintf("共%d个控制面组件，%d个异常", len(data.Components), unhealthy)
+	return check
+}
+
+func coreDNSCheck(data *resource.ClusterHealthData) *types.ClusterHealthCheck {
+	check := &types.ClusterHealthCheck{Name: HealthCheckCoreDNS, Details: data.CoreDNS}
+	dns := data.CoreDNS
+	if dns == nil || !dns.Found {
+		check.Status = types.ClusterHealthWarning
+		check.Message = "kube-system中未找到k8s-app=kube-dns的CoreDNS pod"
+		return check
+	}
+	check.Value = float64(dns.Ready)
+	check.Message = fmt.Sprintf("CoreDNS就绪%d/%d，重启%d次", dns.Ready, dns.Desired, dns.Restarts)
+	switch {
+	case dns.Ready == 0:
+		check.Status = types.ClusterHealthCritical
+	case dns.Ready < dns.Desired:
+		check.Status = types.ClusterHealthWarning
+	default:
+		check.Status = types.ClusterHealthHealthy
+	}
+	return check
+}
+
+func podsCheck(data *resource.ClusterHealthData, t *types.ClusterHealthThresholds) *types.ClusterHealthCheck {
+	byNamespace := make(map[string][]*resource.FailingPod)
+	for _, pod := range data.FailingPods {
+		byNamespace[pod.Namespace] = append(byNamespace[pod.Namespace], pod)
+	}
+	check := &types.ClusterHealthCheck{
+		Name:     HealthCheckPods,
+		Value:    float64(len(data.FailingPods)),
+		Warning:  float64(t.FailingPodsWarning),
+		Critical: float64(t.FailingPodsCritical),
+		Message:  fmt.Sprintf("%d个命名空间中共%d个异常pod", len(byNamespace), len(data.FailingPods)),
+		Details:  byNamespace,
+	}
+	check.Status = thresholdStatus(check.Value, check.Warning, check.Critical)
+	return check
+}
+
+func eventsCheck(data *resource.ClusterHealthData, t *types.ClusterHealthThresholds) *types.ClusterHealthCheck {
+	window := data.EventWindow
+	if window <= 0 {
+		window = 60
+	}
+	// 换算为每小时的事件数
+	rate := math.Round(float64(data.WarningEvents) * 60 / float64(window))
+	check := &types.ClusterHealthCheck{
+		Name:     HealthCheckEvents,
+		Value:    rate,
+		Warning:  float64(t.WarningEventsWarning),
+		Critical: float64(t.WarningEventsCritical),
+		Message:  fmt.Sprintf("最近%d分钟共%d个warning事件", window, data.WarningEvents),
+		Details:  data.WarningEventReasons,
+	}
+	check.Status = thresholdStatus(check.Value, check.Warning, check.Critical)
+	return check
+}
+
+func certificateCheck(data *resource.ClusterHealthData, t *types.ClusterHealthThresholds) *types.ClusterHealthCheck {
+	check := &types.ClusterHealthCheck{
+		Name:     HealthCheckCertificate,
+		Warning:  float64(t.CertExpiryWarningDays),
+		Critical: float64(t.CertExpiryCriticalDays),
+		Details:  data.Certificate,
+	}
+	cert := data.Certificate
+	if cert == nil || cert.Error != "" || cert.NotAfter.IsZero() {
+		check.Status = types.ClusterHealthUnknown
+		check.Message = "获取apiserver证书失败"
+		if cert != nil && cert.Error != "" {
+			check.Message += "：" + cert.Error
+		}
+		return check
+	}
+	days := math.Floor(time.Until(cert.NotAfter).Hours() / 24)
+	check.Value = days
+	check.Message = fmt.Sprintf("apiserver证书%s剩余%d天过期", cert.NotAfter.Format("2006-01-02"), int(days))
+	// 剩余天数越少越严重
+	switch {
+	case days <= check.Critical:
+		check.Status = types.ClusterHealthCritical
+	case days <= check.Warning:
+		check.Status = types.ClusterHealthWarning
+	default:
+		check.Status = types.ClusterHealthHealthy
+	}
+	return check
+}
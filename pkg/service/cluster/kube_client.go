@@ -10,10 +10,11 @@ import (
 	"strconv"
 )
 
+// kubeclient 访问集群的客户端，impersonate不为空时以模拟的用户身份访问
 type kubeclient interface {
-	request(cluster *types.Cluster, resType, action string, params interface{}) *utils.Response
-	watch(cluster *types.Cluster, resType string, params interface{}) (Outer, error)
-	pods(cluster *types.Cluster) (PodClient, error)
+	request(cluster *types.Cluster, impersonate *kubetypes.Impersonate, resType, action string, params interface{}) *utils.Response
+	watch(cluster *types.Cluster, impersonate *kubetypes.Impersonate, resType string, params interface{}) (Outer, error)
+	pods(cluster *types.Cluster, impersonate *kubetypes.Impersonate) (PodClient, error)
 }
 
 type PodClient interface {
@@ -30,6 +31,8 @@ type KubeClient struct {
 	models       *model.Models
 	directClient kubeclient
 	agentClient  kubeclient
+	// user 通过WithUser设置后，集群开启用户模拟时以该用户映射的k8s身份访问集群
+	user *types.User
 }
 
 func NewKubeClient(models *model.Models) *KubeClient {
@@ -40,6 +43,13 @@ func NewKubeClient(models *model.Models) *KubeClient {
 	}
 }
 
+// WithUser 返回以该用户身份访问集群的KubeClient，集群没有开启用户模拟时仍使用导入集群的身份
+func (k *KubeClient) WithUser(user *types.User) *KubeClient {
+	c := *k
+	c.user = user
+	return &c
+}
+
 func (k *KubeClient) List(clusterId, resType string, params interface{}) *utils.Response {
 	return k.Request(clusterId, resType, kubetypes.ListAction, params)
 }
@@ -73,7 +83,7 @@ func (k *KubeClient) Watch(clusterId, resType string, params interface{}) (Outer
 	if err != nil {
 		return nil, err
 	}
-	return cli.watch(clusterObj, resType, params)
+	return cli.watch(clusterObj, k.impersonate(clusterObj), resType, params)
 }
 
 func (k *KubeClient) Pods(clusterId string) (PodClient, error) {
@@ -81,7 +91,7 @@ func (k *KubeClient) Pods(clusterId string) (PodClient, error) {
 	if err != nil {
 		return nil, err
 	}
-	return cli.pods(clusterObj)
+	return cli.pods(clusterObj, k.impersonate(clusterObj))
 }
 
 func (k *KubeClient) getClient(clusterId string) (c kubeclient, clusterObj *types.Cluster, err error) {
@@ -106,7 +116,34 @@ func (k *KubeClient) Request(clusterId, resType, action string, params interface
 	if err != nil {
 		return &utils.Response{Code: code.ParamsError, Msg: err.Error()}
 	}
-	return cli.request(clusterObj, resType, action, params)
+	return cli.request(clusterObj, k.impersonate(clusterObj), resType, action, params)
+}
+
+// impersonate 根据集群的用户模拟配置，获取用户对应的k8s用户以及用户组
+func (k *KubeClient) impersonate(clusterObj *types.Cluster) *kubetypes.Impersonate {
+	if k.user == nil || clusterObj.Impersonation == nil || !clusterObj.Impersonation.Enabled {
+		return nil
+	}
+	mapping := clusterObj.Impersonation
+	mappedGroups := append([]string{}, mapping.Groups...)
+	// 使用用户在该集群中最高的角色对应的用户组
+	for _, role := range []string{types.RoleAdmin, types.RoleEditor, types.RoleViewer} {
+		if k.models.UserRoleManager.AuthRole(k.user, types.ScopeCluster, clusterObj.ID, role) {
+			mappedGroups = append(mappedGroups, mapping.RoleGroups[role]...)
+			break
+		}
+	}
+	mappedGroups = append(mappedGroups, mapping.UserGroups[k.user.Name]...)
+	var groups []string
+	for _, group := range mappedGroups {
+		if group != "" && !utils.Contains(groups, group) {
+			groups = append(groups, group)
+		}
+	}
+	return &kubetypes.Impersonate{
+		User:   mapping.UserPrefix + k.user.Name,
+		Groups: groups,
+	}
 }
 
 type Outer interface {
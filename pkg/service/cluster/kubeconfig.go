@@ -0,0 +1,306 @@
+package cluster
+
+import (
+	"context"
+	"fmt"
+	authorizationv1 "k8s.io/api/authorization/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/clientcmd"
+	clientcmdapi "k8s.io/client-go/tools/clientcmd/api"
+	"sort"
+	"strings"
+	"time"
+)
+
+// kubeConfigCheckTimeout 校验kubeconfig时访问apiserver的超时时间
+const kubeConfigCheckTimeout = 10 * time.Second
+
+// KubeConfigContext kubeconfig中的context信息
+type KubeConfigContext struct {
+	Name      string `json:"name"`
+	Cluster   string `json:"cluster"`
+	Server    string `json:"server"`
+	User      string `json:"user"`
+	Namespace string `json:"namespace"`
+	Current   bool   `json:"current"`
+	// AuthType 认证方式，token或者cert，不支持的认证方式时Error不为空
+	AuthType string `json:"auth_type"`
+	Error    string `json:"error"`
+}
+
+// KubeConfigPermission 导入集群需要的权限
+type KubeConfigPermission struct {
+	Group    string `json:"group"`
+	Resource string `json:"resource"`
+	Verb     string `json:"verb"`
+	// Required 缺少必须的权限时不能导入集群，否则只做提示
+	Required bool `json:"required"`
+}
+
+func (p *KubeConfigPermission) String() string {
+	if p.Group == "" {
+		return p.Verb + " " + p.Resource
+	}
+	return p.Verb + " " + p.Resource + "." + p.Group
+}
+
+// kubeConfigPermissions 导入集群检查的权限，平台展示集群概览需要读取节点、命名空间以及工作负载
+var kubeConfigPermissions = []*KubeConfigPermission{
+	{Resource: "namespaces", Verb: "list", Required: true},
+	{Resource: "nodes", Verb: "list", Required: true},
+	{Resource: "pods", Verb: "list", Required: true},
+	{Resource: "pods", Verb: "watch", Required: true},
+	{Resource: "events", Verb: "list"},
+	{Resource: "events", Verb: "watch"},
+	{Resource: "services", Verb: "list"},
+	{Resource: "configmaps", Verb: "list"},
+	{Resource: "secrets", Verb: "list"},
+	{Group: "apps", Resource: "deployments", Verb: "list"},
+	{Group: "apps", Resource: "deployments", Verb: "create"},
+	{Group: "apps", Resource: "deployments", Verb: "update"},
+	{Group: "apps", Resource: "deployments", Verb: "delete"},
+	{Group: "apps", Resource: "statefulsets", Verb: "list"},
+	{Group: "apps", Resource: "daemonsets", Verb: "list"},
+	{Group: "batch", Resource: "jobs", Verb: "list"},
+	{Group: "networking.k8s.io", Resource: "ingresses", Verb: "list"},
+}
+
+// KubeConfigCheck kubeconfig连通性以及权限检查结果
+type KubeConfigCheck struct {
+	Context    string `json:"context"`
+	Server     string `json:"server"`
+	Version    string `json:"version"`
+	GitVersion string `json:"git_version"`
+	// Namespace 权限检查所在的命名空间，通过ClusterRoleBinding授予的权限在所有命名空间均可见
+	Namespace string `json:"namespace"`
+	// Missing 缺少的权限
+	Missing []*KubeConfigPermission `json:"missing"`
+	// Incomplete apiserver无法完整评估权限时（如使用webhook鉴权）为true，此时缺少的权限仅供参考
+	Incomplete      bool   `json:"incomplete"`
+	EvaluationError string `json:"evaluation_error"`
+}
+
+// MissingRequired 是否缺少必须的权限，权限评估不完整时不作为导入失败的依据
+func (c *KubeConfigCheck) MissingRequired() []string {
+	if c.Incomplete {
+		return nil
+	}
+	var missing []string
+	for _, p := range c.Missing {
+		if p.Required {
+			missing = append(missing, p.String())
+		}
+	}
+	return missing
+}
+
+func loadKubeConfig(kubeConfig string) (*clientcmdapi.Config, error) {
+	if strings.TrimSpace(kubeConfig) == "" {
+		return nil, fmt.Errorf("kubeconfig不能为空")
+	}
+	conf, err := clientcmd.Load([]byte(kubeConfig))
+	if err != nil {
+		return nil, fmt.Errorf("解析kubeconfig失败：%s", err.Error())
+	}
+	if len(conf.Contexts) == 0 {
+		return nil, fmt.Errorf("kubeconfig中没有context")
+	}
+	return conf, nil
+}
+
+// KubeConfigContexts 获取kubeconfig中所有的context，以及每个context的认证方式是否支持
+func KubeConfigContexts(kubeConfig string) ([]*KubeConfigContext, error) {
+	conf, err := loadKubeConfig(kubeConfig)
+	if err != nil {
+		return nil, err
+	}
+	var contexts []*KubeConfigContext
+	for name, ctx := range conf.Contexts {
+		c := &KubeConfigContext{
+			Name:      name,
+			Cluster:   ctx.Cluster,
+			User:      ctx.AuthInfo,
+			Namespace: ctx.Namespace,
+			Current:   name == conf.CurrentContext,
+		}
+		if cluster, ok := conf.Clusters[ctx.Cluster]; ok {
+			c.Server = cluster.Server
+		}
+		if c.AuthType, err = checkContext(conf, name); err != nil {
+			c.Error = err.Error()
+		}
+		contexts = append(contexts, c)
+	}
+	sort.Slice(contexts, func(i, j int) bool {
+		return contexts[i].Name < contexts[j].Name
+	})
+	return contexts, nil
+}
+
+// checkContext 检查context引用的集群以及用户配置，只支持内嵌的token以及客户端证书认证，
+// 平台无法访问kubeconfig所在机器上的文件以及exec插件
+func checkContext(conf *clientcmdapi.Config, contextName string) (string, error) {
+	ctx, ok := conf.Contexts[contextName]
+	if !ok {
+		return "", fmt.Errorf("kubeconfig中没有context：%s", contextName)
+	}
+	cluster, ok := conf.Clusters[ctx.Cluster]
+	if !ok {
+		return "", fmt.Errorf("context %s引用的集群%s不存在", contextName, ctx.Cluster)
+	}
+	if cluster.Server == "" {
+		return "", fmt.Errorf("集群%s没有配置apiserver地址", ctx.Cluster)
+	}
+	// 非https地址client-go不会携带凭证
+	if !strings.HasPrefix(cluster.Server, "https://") {
+		return "", fmt.Errorf("集群%s的apiserver地址需要使用https：%s", ctx.Cluster, cluster.Server)
+	}
+	if cluster.CertificateAuthority != "" {
+		return "", fmt.Errorf("集群%s的CA证书引用了文件，请使用certificate-authority-data", ctx.Cluster)
+	}
+	user, ok := conf.AuthInfos[ctx.AuthInfo]
+	if !ok {
+		return "", fmt.Errorf("context %s引用的用户%s不存在", contextName, ctx.AuthInfo)
+	}
+	switch {
+	case user.Exec != nil:
+		return "", fmt.Errorf("不支持exec插件认证，请使用token或者客户端证书")
+	case user.AuthProvider != nil:
+		return "", fmt.Errorf("不支持auth-provider认证，请使用token或者客户端证书")
+	case user.TokenFile != "", user.ClientCertificate != "", user.ClientKey != "":
+		return "", fmt.Errorf("用户%s的凭证引用了文件，请使用token或者client-certificate-data/client-key-data", ctx.AuthInfo)
+	case user.Username != "" || user.Password != "":
+		return "", fmt.Errorf("不支持用户名密码认证，请使用token或者客户端证书")
+	case user.Impersonate != "" || len(user.ImpersonateGroups) > 0:
+		return "", fmt.Errorf("kubeconfig中不能配置用户模拟，请在集群设置中配置")
+	case user.Token != "":
+		return "token", nil
+	case len(user.ClientCertificateData) > 0 && len(user.ClientKeyData) > 0:
+		return "cert", nil
+	}
+	return "", fmt.Errorf("用户%s没有配置token或者客户端证书", ctx.AuthInfo)
+}
+
+// SelectKubeConfigContext 选择kubeconfig中的context，只保留该context引用的集群以及用户，
+// context为空时使用current-context，kubeconfig中只有一个context时可以不指定
+func SelectKubeConfigContext(kubeConfig, contextName string) (string, error) {
+	conf, err := loadKubeConfig(kubeConfig)
+	if err != nil {
+		return "", err
+	}
+	if contextName == "" {
+		contextName = conf.CurrentContext
+		if contextName == "" && len(conf.Contexts) == 1 {
+			for name := range conf.Contexts {
+				contextName = name
+			}
+		}
+		if contextName == "" {
+			return "", fmt.Errorf("kubeconfig中有多个context，请选择要导入的context")
+		}
+	}
+	if _, err = checkContext(conf, contextName); err != nil {
+		return "", err
+	}
+	ctx := conf.Contexts[contextName]
+	selected := clientcmdapi.NewConfig()
+	selected.CurrentContext = contextName
+	selected.Contexts[contextName] = ctx
+	selected.Clusters[ctx.Cluster] = conf.Clusters[ctx.Cluster]
+	selected.AuthInfos[ctx.AuthInfo] = conf.AuthInfos[ctx.AuthInfo]
+	data, err := clientcmd.Write(*selected)
+	if err != nil {
+		return "", fmt.Errorf("序列化kubeconfig失败：%s", err.Error())
+	}
+	return string(data), nil
+}
+
+// KubeConfigServer 获取kubeconfig当前context的apiserver地址
+func KubeConfigServer(kubeConfig string) string {
+	conf, err := clientcmd.Load([]byte(kubeConfig))
+	if err != nil {
+		return ""
+	}
+	ctx, ok := conf.Contexts[conf.CurrentContext]
+	if !ok {
+		return ""
+	}
+	if cluster, ok := conf.Clusters[ctx.Cluster]; ok {
+		return cluster.Server
+	}
+	return ""
+}
+
+// CheckKubeConfig 使用SelectKubeConfigContext选择后的kubeconfig访问集群，获取集群版本，
+// 并通过SelfSubjectRulesReview检查导入集群需要的权限
+func CheckKubeConfig(kubeConfig string) (*KubeConfigCheck, error) {
+	conf, err := loadKubeConfig(kubeConfig)
+	if err != nil {
+		return nil, err
+	}
+	if _, err = checkContext(conf, conf.CurrentContext); err != nil {
+		return nil, err
+	}
+	ctx := conf.Contexts[conf.CurrentContext]
+	check := &KubeConfigCheck{
+		Context:   conf.CurrentContext,
+		Server:    conf.Clusters[ctx.Cluster].Server,
+		Namespace: ctx.Namespace,
+	}
+	if check.Namespace == "" {
+		check.Namespace = metav1.NamespaceDefault
+	}
+
+	restConfig, err := clientcmd.RESTConfigFromKubeConfig([]byte(kubeConfig))
+	if err != nil {
+		return nil, err
+	}
+	restConfig.Timeout = kubeConfigCheckTimeout
+	client, err := kubernetes.NewForConfig(restConfig)
+	if err != nil {
+		return nil, err
+	}
+	version, err := client.Discovery().ServerVersion()
+	if err != nil {
+		return nil, fmt.Errorf("连接集群%s失败：%s", check.Server, err.Error())
+	}
+	check.GitVersion = version.GitVersion
+	check.Version = version.Major + "." + version.Minor
+
+	c, cancel := context.WithTimeout(context.Background(), kubeConfigCheckTimeout)
+	defer cancel()
+	review, err := client.AuthorizationV1().SelfSubjectRulesReviews().Create(c, &authorizationv1.SelfSubjectRulesReview{
+		Spec: authorizationv1.SelfSubjectRulesReviewSpec{Namespace: check.Namespace},
+	}, metav1.CreateOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("检查集群权限失败：%s", err.Error())
+	}
+	check.Incomplete = review.Status.Incomplete
+	check.EvaluationError = review.Status.EvaluationError
+	for _, p := range kubeConfigPermissions {
+		if !rulesAllow(review.Status.ResourceRules, p) {
+			check.Missing = append(check.Missing, p)
+		}
+	}
+	return check, nil
+}
+
+// rulesAllow 权限规则是否允许对资源的操作
+func rulesAllow(rules []authorizationv1.ResourceRule, p *KubeConfigPermission) bool {
+	for _, rule := range rules {
+		if matchRule(rule.APIGroups, p.Group) && matchRule(rule.Resources, p.Resource) && matchRule(rule.Verbs, p.Verb) {
+			return true
+		}
+	}
+	return false
+}
+
+func matchRule(values []string, value string) bool {
+	for _, v := range values {
+		if v == "*" || v == value {
+			return true
+		}
+	}
+	return false
+}
@@ -3,6 +3,7 @@ package service
 import (
 	"github.com/kubespace/kubespace/pkg/model"
 	"github.com/kubespace/kubespace/pkg/service/cluster"
+	"github.com/kubespace/kubespace/pkg/service/notification"
 	"github.com/kubespace/kubespace/pkg/service/pipeline"
 	"github.com/kubespace/kubespace/pkg/service/pipeline/pipeline_run"
 	"github.com/kubespace/kubespace/pkg/service/project"
@@ -24,6 +25,8 @@ type Factory struct {
 	Project *ProjectFactory
 	// 流水线相关Service
 	Pipeline *PipelineFactory
+	// 通知相关Service
+	Notification *NotificationFactory
 }
 
 func NewServiceFactory(config *Config) *Factory {
@@ -33,7 +36,9 @@ func NewServiceFactory(config *Config) *Factory {
 	projectService := project.NewProjectService(config.models, kubeClient, appService)
 	return &Factory{
 		Cluster: &ClusterFactory{
-			KubeClient: kubeClient,
+			KubeClient:    kubeClient,
+			HealthService: cluster.NewHealthService(config.models, kubeClient),
+			FleetService:  cluster.NewFleetService(config.models, kubeClient),
 		},
 		Project: &ProjectFactory{
 			ProjectService:  projectService,
@@ -46,6 +51,9 @@ func NewServiceFactory(config *Config) *Factory {
 			PipelineRunService: pipeline_run.NewPipelineRunService(config.models),
 			SpaceletService:    spacelet.NewSpaceletService(config.models),
 		},
+		Notification: &NotificationFactory{
+			NotificationService: notification.NewNotificationService(config.models),
+		},
 	}
 }
 
@@ -53,6 +61,10 @@ func NewServiceFactory(config *Config) *Factory {
 type ClusterFactory struct {
 	// 集群资源操作客户端
 	KubeClient *cluster.KubeClient
+	// 集群健康检查
+	HealthService *cluster.HealthService
+	// 跨集群资源查询
+	FleetService *cluster.FleetService
 }
 
 // ProjectFactory 工作空间相关service
@@ -76,3 +88,9 @@ type PipelineFactory struct {
 	// spacelet
 	SpaceletService *spacelet.SpaceletService
 }
+
+// NotificationFactory 通知相关service
+type NotificationFactory struct {
+	// 平台事件通知
+	NotificationService *notification.NotificationService
+}
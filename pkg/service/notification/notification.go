@@ -0,0 +1,156 @@
+package notification
+
+import (
+	"fmt"
+	"github.com/kubespace/kubespace/pkg/model"
+	"github.com/kubespace/kubespace/pkg/model/types"
+	"github.com/kubespace/kubespace/pkg/utils"
+	"k8s.io/klog/v2"
+	"net/http"
+	"net/url"
+	"time"
+)
+
+// Event 平台事件，根据订阅该事件的通知规则渲染消息后发送
+type Event struct {
+	Type      string `json:"type"`
+	Scope     string `json:"scope"`
+	ScopeId   uint   `json:"scope_id"`
+	ScopeName string `json:"scope_name"`
+	// Subject 事件关联的对象，如流水线、应用、spacelet节点名称
+	Subject string `json:"subject"`
+	Message string `json:"message"`
+	// Data 事件的其他数据，可以在模板中通过{{.Data.key}}引用
+	Data map[string]interface{} `json:"data"`
+	Time time.Time              `json:"time"`
+}
+
+// Message 渲染后发送到渠道的消息
+type Message struct {
+	Title   string
+	Content string
+	Event   *Event
+}
+
+type NotificationService struct {
+	models     *model.Models
+	httpClient *http.Client
+}
+
+func NewNotificationService(models *model.Models) *NotificationService {
+	return &NotificationService{
+		models:     models,
+		httpClient: &http.Client{Timeout: 10 * time.Second},
+	}
+}
+
+// Notify 异步发送事件通知，不阻塞事件的处理流程
+func (n *NotificationService) Notify(event *Event) {
+	if event.Time.IsZero() {
+		event.Time = time.Now()
+	}
+	go func() {
+		defer utils.HandleCrash(func(r interface{}) {
+			klog.Errorf("notify event %s crashed: %v", event.Type, r)
+		})
+		if err := n.deliver(event); err != nil {
+			klog.Errorf("notify event %s scope=%s scope_id=%d error: %s", event.Type, event.Scope, event.ScopeId, err.Error())
+		}
+	}()
+}
+
+func (n *NotificationService) deliver(event *Event) error {
+	rules, err := n.models.NotificationManager.MatchRules(event.Type, event.Scope, event.ScopeId)
+	if err != nil {
+		return err
+	}
+	for _, rule := range rules {
+		msg, err := renderMessage(rule, event)
+		if err != nil {
+			// 模板错误时记录发送失败，便于在发送记录中排查
+			n.record(rule, nil, event, &Message{Event: event}, err)
+			continue
+		}
+		for _, channelId := range rule.ChannelIds {
+			channel, err := n.models.NotificationManager.GetChannel(channelId)
+			if err != nil {
+				n.record(rule, &types.NotificationChannel{ID: channelId}, event, msg, fmt.Errorf("获取通知渠道失败：%s", err.Error()))
+				continue
+			}
+			n.record(rule, channel, event, msg, n.send(channel, msg))
+		}
+	}
+	return nil
+}
+
+// record 保存发送记录，记录所属范围为规则的范围，只有规则范围内的用户可以查看
+func (n *NotificationService) record(rule *types.NotificationRule, channel *types.NotificationChannel, event *Event, msg *Message, sendErr error) {
+	delivery := &types.NotificationDelivery{
+		RuleId:  rule.ID,
+		Event:   event.Type,
+		Scope:   rule.Scope,
+		ScopeId: rule.ScopeId,
+		Title:   msg.Title,
+		Content: msg.Content,
+		Status:  types.NotificationDeliverySuccess,
+	}
+	if channel != nil {
+		delivery.ChannelId = channel.ID
+		delivery.ChannelName = channel.Name
+	}
+	if sendErr != nil {
+		delivery.Status = types.NotificationDeliveryFailed
+		delivery.Error = sendErr.Error()
+	}
+	if err := n.models.NotificationManager.CreateDelivery(delivery); err != nil {
+		klog.Errorf("create notification delivery error: %s", err.Error())
+	}
+}
+
+// Test 向渠道发送一条测试消息
+func (n *NotificationService) Test(channel *types.NotificationChannel, user string) error {
+	event := &Event{
+		Type:      "test",
+		Scope:     types.ScopePlatform,
+		ScopeName: "kubespace",
+		Subject:   channel.Name,
+		Message:   fmt.Sprintf("%s发送的测试消息", user),
+		Time:      time.Now(),
+	}
+	return n.send(channel, &Message{
+		Title:   "Kubespace通知渠道测试",
+		Content: event.Message,
+		Event:   event,
+	})
+}
+
+// ValidateChannel 校验渠道类型以及发送所需的配置
+func ValidateChannel(channel *types.NotificationChannel) error {
+	if channel.Name == "" {
+		return fmt.Errorf("渠道名称不能为空")
+	}
+	if channel.Config == nil {
+		return fmt.Errorf("渠道配置不能为空")
+	}
+	switch channel.Type {
+	case types.NotificationChannelWebhook, types.NotificationChannelDingTalk, types.NotificationChannelWeCom,
+		types.NotificationChannelFeishu, types.NotificationChannelSlack:
+		u, err := url.Parse(channel.Config.Url)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return fmt.Errorf("通知地址格式错误：%s", channel.Config.Url)
+		}
+	case types.NotificationChannelEmail:
+		if channel.Config.SmtpHost == "" {
+			return fmt.Errorf("smtp服务器不能为空")
+		}
+		if len(channel.Config.To) == 0 {
+			return fmt.Errorf("收件人不能为空")
+		}
+		if channel.Config.From == "" && channel.Config.Username == "" {
+			return fmt.Errorf("发件人不能为空")
+		}
+	default:
+		return fmt.Errorf("不支持的通知渠道类型：%s", channel.Type)
+	}
+	return nil
+}
@@ -0,0 +1,274 @@
+package notification
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"crypto/tls"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"github.com/kubespace/kubespace/pkg/model/types"
+	"io"
+	"mime"
+	"net"
+	"net/http"
+	"net/smtp"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// send 根据渠道类型发送消息
+func (n *NotificationService) send(channel *types.NotificationChannel, msg *Message) error {
+	if channel.Config == nil {
+		return fmt.Errorf("通知渠道%s未配置", channel.Name)
+	}
+	switch channel.Type {
+	case types.NotificationChannelWebhook:
+		return n.sendWebhook(channel, msg)
+	case types.NotificationChannelEmail:
+		return sendEmail(channel, msg)
+	case types.NotificationChannelDingTalk:
+		return n.sendDingTalk(channel, msg)
+	case types.NotificationChannelWeCom:
+		return n.sendWeCom(channel, msg)
+	case types.NotificationChannelFeishu:
+		return n.sendFeishu(channel, msg)
+	case types.NotificationChannelSlack:
+		return n.sendSlack(channel, msg)
+	}
+	return fmt.Errorf("不支持的通知渠道类型：%s", channel.Type)
+}
+
+// sendWebhook 以json格式发送事件以及渲染后的消息，配置了密钥时作为Authorization头
+func (n *NotificationService) sendWebhook(channel *types.NotificationChannel, msg *Message) error {
+	body := map[string]interface{}{
+		"event":      msg.Event.Type,
+		"scope":      msg.Event.Scope,
+		"scope_id":   msg.Event.ScopeId,
+		"scope_name": msg.Event.ScopeName,
+		"subject":    msg.Event.Subject,
+		"message":    msg.Event.Message,
+		"data":       msg.Event.Data,
+		"time":       msg.Event.Time,
+		"title":      msg.Title,
+		"content":    msg.Content,
+	}
+	header := http.Header{}
+	if channel.Secret != "" {
+		header.Set("Authorization", channel.Secret)
+	}
+	_, err := n.postJson(channel.Config.Url, header, body)
+	return err
+}
+
+// sendDingTalk 钉钉机器人markdown消息，配置了加签密钥时在url中添加签名
+func (n *NotificationService) sendDingTalk(channel *types.NotificationChannel, msg *Message) error {
+	webhook := channel.Config.Url
+	if channel.Secret != "" {
+		timestamp := strconv.FormatInt(time.Now().UnixMilli(), 10)
+		sign := hmacSign(channel.Secret, timestamp+"\n"+channel.Secret)
+		webhook = appendQuery(webhook, url.Values{"timestamp": {timestamp}, "sign": {sign}})
+	}
+	resp, err := n.postJson(webhook, nil, map[string]interface{}{
+		"msgtype": "markdown",
+		"markdown": map[string]string{
+			"title": msg.Title,
+			"text":  fmt.Sprintf("### %s\n\n%s", msg.Title, markdownLines(msg.Content)),
+		},
+	})
+	if err != nil {
+		return err
+	}
+	return checkErrCode(resp, "errcode", "errmsg")
+}
+
+// sendWeCom 企业微信群机器人markdown消息
+func (n *NotificationService) sendWeCom(channel *types.NotificationChannel, msg *Message) error {
+	resp, err := n.postJson(channel.Config.Url, nil, map[string]interface{}{
+		"msgtype": "markdown",
+		"markdown": map[string]string{
+			"content": fmt.Sprintf("### %s\n%s", msg.Title, msg.Content),
+		},
+	})
+	if err != nil {
+		return err
+	}
+	return checkErrCode(resp, "errcode", "errmsg")
+}
+
+// sendFeishu 飞书自定义机器人文本消息，配置了签名校验时在消息中添加签名
+func (n *NotificationService) sendFeishu(channel *types.NotificationChannel, msg *Message) error {
+	body := map[string]interface{}{
+		"msg_type": "text",
+		"content": map[string]string{
+			"text": msg.Title + "\n" + msg.Content,
+		},
+	}
+	if channel.Secret != "" {
+		timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+		body["timestamp"] = timestamp
+		body["sign"] = hmacSign(timestamp+"\n"+channel.Secret, "")
+	}
+	resp, err := n.postJson(channel.Config.Url, nil, body)
+	if err != nil {
+		return err
+	}
+	return checkErrCode(resp, "code", "msg")
+}
+
+// sendSlack slack incoming webhook消息
+func (n *NotificationService) sendSlack(channel *types.NotificationChannel, msg *Message) error {
+	_, err := n.postJson(channel.Config.Url, nil, map[string]interface{}{
+		"text": fmt.Sprintf("*%s*\n%s", msg.Title, msg.Content),
+	})
+	return err
+}
+
+func (n *NotificationService) postJson(webhook string, header http.Header, body interface{}) ([]byte, error) {
+	if webhook == "" {
+		return nil, fmt.Errorf("通知地址为空")
+	}
+	data, err := json.Marshal(body)
+	if err != nil {
+		return nil, err
+	}
+	req, err := http.NewRequest(http.MethodPost, webhook, bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	for k := range header {
+		req.Header.Set(k, header.Get(k))
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := n.httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	respBody, _ := io.ReadAll(io.LimitReader(resp.Body, 64*1024))
+	if resp.StatusCode >= http.StatusMultipleChoices {
+		return nil, fmt.Errorf("status code %d: %s", resp.StatusCode, string(respBody))
+	}
+	return respBody, nil
+}
+
+// checkErrCode 机器人接口返回200时，通过返回的错误码判断是否发送成功
+func checkErrCode(resp []byte, codeKey, msgKey string) error {
+	result := map[string]interface{}{}
+	if err := json.Unmarshal(resp, &result); err != nil {
+		return fmt.Errorf("unmarshal response error: %s", err.Error())
+	}
+	if errCode, ok := result[codeKey].(float64); ok && errCode != 0 {
+		return fmt.Errorf("%s=%v: %v", codeKey, errCode, result[msgKey])
+	}
+	return nil
+}
+
+// hmacSign 计算HmacSHA256签名并base64编码，钉钉以secret为key，飞书以timestamp+secret为key签名空字符串
+func hmacSign(key, data string) string {
+	h := hmac.New(sha256.New, []byte(key))
+	h.Write([]byte(data))
+	return base64.StdEncoding.EncodeToString(h.Sum(nil))
+}
+
+func appendQuery(webhook string, values url.Values) string {
+	if strings.Contains(webhook, "?") {
+		return webhook + "&" + values.Encode()
+	}
+	return webhook + "?" + values.Encode()
+}
+
+// markdownLines 钉钉markdown需要两个换行才会换行
+func markdownLines(content string) string {
+	return strings.ReplaceAll(content, "\n", "\n\n")
+}
+
+// sendEmail 通过smtp发送邮件，配置tls时直接建立tls连接，否则在服务器支持时使用STARTTLS
+func sendEmail(channel *types.NotificationChannel, msg *Message) error {
+	conf := channel.Config
+	if conf.SmtpHost == "" || len(conf.To) == 0 {
+		return fmt.Errorf("邮件渠道需要配置smtp服务器以及收件人")
+	}
+	port := conf.SmtpPort
+	if port == 0 {
+		port = 25
+	}
+	addr := net.JoinHostPort(conf.SmtpHost, strconv.Itoa(port))
+	dialer := &net.Dialer{Timeout: 10 * time.Second}
+	var conn net.Conn
+	var err error
+	if conf.Tls {
+		conn, err = tls.DialWithDialer(dialer, "tcp", addr, &tls.Config{ServerName: conf.SmtpHost})
+	} else {
+		conn, err = dialer.Dial("tcp", addr)
+	}
+	if err != nil {
+		return err
+	}
+	_ = conn.SetDeadline(time.Now().Add(30 * time.Second))
+	client, err := smtp.NewClient(conn, conf.SmtpHost)
+	if err != nil {
+		conn.Close()
+		return err
+	}
+	defer client.Close()
+	if !conf.Tls {
+		if ok, _ := client.Extension("STARTTLS"); ok {
+			if err = client.StartTLS(&tls.Config{ServerName: conf.SmtpHost}); err != nil {
+				return err
+			}
+		}
+	}
+	if conf.Username != "" {
+		if err = client.Auth(smtp.PlainAuth("", conf.Username, channel.Secret, conf.SmtpHost)); err != nil {
+			return err
+		}
+	}
+	from := conf.From
+	if from == "" {
+		from = conf.Username
+	}
+	if err = client.Mail(from); err != nil {
+		return err
+	}
+	for _, to := range conf.To {
+		if err = client.Rcpt(to); err != nil {
+			return err
+		}
+	}
+	w, err := client.Data()
+	if err != nil {
+		return err
+	}
+	header := []string{
+		"From: " + from,
+		"To: " + strings.Join(conf.To, ", "),
+		"Subject: " + mime.BEncoding.Encode("UTF-8", msg.Title),
+		"Date: " + time.Now().Format(time.RFC1123Z),
+		"MIME-Version: 1.0",
+		"Content-Type: text/plain; charset=UTF-8",
+		"Content-Transfer-Encoding: base64",
+	}
+	body := strings.Join(header, "\r\n") + "\r\n\r\n" + wrapBase64(base64.StdEncoding.EncodeToString([]byte(msg.Content)))
+	if _, err = w.Write([]byte(body)); err != nil {
+		return err
+	}
+	if err = w.Close(); err != nil {
+		return err
+	}
+	return client.Quit()
+}
+
+// wrapBase64 邮件正文每行不超过76个字符
+func wrapBase64(s string) string {
+	var lines []string
+	for len(s) > 76 {
+		lines = append(lines, s[:76])
+		s = s[76:]
+	}
+	lines = append(lines, s)
+	return strings.Join(lines, "\r\n")
+}
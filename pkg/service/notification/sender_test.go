@@ -0,0 +1,306 @@
+package notification
+
+import (
+	"bufio"
+	"encoding/base64"
+	"encoding/json"
+	"github.com/kubespace/kubespace/pkg/model/types"
+	"io"
+	"mime"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+// webhookRequest 本地webhook服务收到的请求
+type webhookRequest struct {
+	query  map[string]string
+	header http.Header
+	body   map[string]interface{}
+}
+
+// newWebhookServer 模拟机器人webhook服务，记录收到的请求并返回指定的响应
+func newWebhookServer(t *testing.T, response string) (*httptest.Server, chan *webhookRequest) {
+	requests := make(chan *webhookRequest, 1)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		req := &webhookRequest{query: map[string]string{}, header: r.Header}
+		for k := range r.URL.Query() {
+			req.query[k] = r.URL.Query().Get(k)
+		}
+		data, _ := io.ReadAll(r.Body)
+		if err := json.Unmarshal(data, &req.body); err != nil {
+			t.Errorf("unmarshal request body error: %s", err.Error())
+		}
+		requests <- req
+		_, _ = w.Write([]byte(response))
+	}))
+	t.Cleanup(server.Close)
+	return server, requests
+}
+
+func newTestService() *NotificationService {
+	return &NotificationService{httpClient: &http.Client{Timeout: 5 * time.Second}}
+}
+
+func testMessage() *Message {
+	return &Message{
+		Title:   "流水线「demo」构建失败",
+		Content: "对象：demo\n详情：build error",
+		Event: &Event{
+			Type:    types.NotificationEventPipelineRunFailed,
+			Scope:   types.ScopePipeline,
+			ScopeId: 1,
+			Subject: "demo",
+			Message: "build error",
+			Time:    time.Now(),
+		},
+	}
+}
+
+func TestSendWebhook(t *testing.T) {
+	server, requests := newWebhookServer(t, "ok")
+	channel := &types.NotificationChannel{
+		Name:   "webhook",
+		Type:   types.NotificationChannelWebhook,
+		Config: &types.NotificationChannelConfig{Url: server.URL + "/hook"},
+		Secret: "Bearer token",
+	}
+	if err := newTestService().send(channel, testMessage()); err != nil {
+		t.Fatal(err)
+	}
+	req := <-requests
+	if req.header.Get("Authorization") != "Bearer token" {
+		t.Errorf("authorization header = %q", req.header.Get("Authorization"))
+	}
+	if req.body["event"] != types.NotificationEventPipelineRunFailed || req.body["subject"] != "demo" {
+		t.Errorf("unexpected webhook body: %v", req.body)
+	}
+	if req.body["title"] != testMessage().Title {
+		t.Errorf("title = %v", req.body["title"])
+	}
+}
+
+func TestSendDingTalkSign(t *testing.T) {
+	server, requests := newWebhookServer(t, `{"errcode":0,"errmsg":"ok"}`)
+	channel := &types.NotificationChannel{
+		Name:   "dingtalk",
+		Type:   types.NotificationChannelDingTalk,
+		Config: &types.NotificationChannelConfig{Url: server.URL + "/robot/send?access_token=abc"},
+		Secret: "SECxxx",
+	}
+	if err := newTestService().send(channel, testMessage()); err != nil {
+		t.Fatal(err)
+	}
+	req := <-requests
+	if req.query["access_token"] != "abc" {
+		t.Errorf("access_token = %q", req.query["access_token"])
+	}
+	timestamp := req.query["timestamp"]
+	if _, err := strconv.ParseInt(timestamp, 10, 64); err != nil {
+		t.Fatalf("timestamp %q error: %v", timestamp, err)
+	}
+	if sign := hmacSign("SECxxx", timestamp+"\n"+"SECxxx"); req.query["sign"] != sign {
+		t.Errorf("sign = %q, want %q", req.query["sign"], sign)
+	}
+	markdown, _ := req.body["markdown"].(map[string]interface{})
+	if text, _ := markdown["text"].(string); !strings.Contains(text, "对象：demo\n\n详情：build error") {
+		t.Errorf("markdown text = %q", text)
+	}
+}
+
+func TestSendRobotErrCode(t *testing.T) {
+	tests := []struct {
+		channelType string
+		response    string
+		wantErr     bool
+	}{
+		{types.NotificationChannelDingTalk, `{"errcode":310000,"errmsg":"sign not match"}`, true},
+		{types.NotificationChannelWeCom, `{"errcode":0,"errmsg":"ok"}`, false},
+		{types.NotificationChannelWeCom, `{"errcode":93000,"errmsg":"invalid webhook url"}`, true},
+		{types.NotificationChannelFeishu, `{"code":0,"msg":"success"}`, false},
+		{types.NotificationChannelFeishu, `{"code":19021,"msg":"sign match fail"}`, true},
+		{types.NotificationChannelSlack, `ok`, false},
+	}
+	for _, tt := range tests {
+		server, requests := newWebhookServer(t, tt.response)
+		channel := &types.NotificationChannel{
+			Name:   tt.channelType,
+			Type:   tt.channelType,
+			Config: &types.NotificationChannelConfig{Url: server.URL},
+		}
+		err := newTestService().send(channel, testMessage())
+		<-requests
+		if (err != nil) != tt.wantErr {
+			t.Errorf("%s response %s: err = %v, wantErr %v", tt.channelType, tt.response, err, tt.wantErr)
+		}
+	}
+}
+
+func TestSendFeishuSign(t *testing.T) {
+	server, requests := newWebhookServer(t, `{"code":0}`)
+	channel := &types.NotificationChannel{
+		Name:   "feishu",
+		Type:   types.NotificationChannelFeishu,
+		Config: &types.NotificationChannelConfig{Url: server.URL},
+		Secret: "feishu-secret",
+	}
+	if err := newTestService().send(channel, testMessage()); err != nil {
+		t.Fatal(err)
+	}
+	req := <-requests
+	timestamp, _ := req.body["timestamp"].(string)
+	if sign := hmacSign(timestamp+"\n"+"feishu-secret", ""); req.body["sign"] != sign {
+		t.Errorf("sign = %v, want %s", req.body["sign"], sign)
+	}
+}
+
+func TestSendHttpError(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "forbidden", http.StatusForbidden)
+	}))
+	defer server.Close()
+	channel := &types.NotificationChannel{
+		Name:   "slack",
+		Type:   types.NotificationChannelSlack,
+		Config: &types.NotificationChannelConfig{Url: server.URL},
+	}
+	if err := newTestService().send(channel, testMessage()); err == nil || !strings.Contains(err.Error(), "403") {
+		t.Errorf("err = %v, want status code 403", err)
+	}
+}
+
+// fakeSmtpServer 只支持明文连接的本地smtp服务，记录收到的信封以及邮件内容
+type fakeSmtpServer struct {
+	listener net.Listener
+	from     string
+	to       []string
+	data     string
+	done     chan struct{}
+}
+
+func newFakeSmtpServer(t *testing.T) *fakeSmtpServer {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := &fakeSmtpServer{listener: listener, done: make(chan struct{})}
+	t.Cleanup(func() { listener.Close() })
+	go s.serve()
+	return s
+}
+
+func (s *fakeSmtpServer) serve() {
+	defer close(s.done)
+	conn, err := s.listener.Accept()
+	if err != nil {
+		return
+	}
+	defer conn.Close()
+	r := bufio.NewReader(conn)
+	reply := func(line string) { _, _ = conn.Write([]byte(line + "\r\n")) }
+	reply("220 localhost ESMTP")
+	for {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			return
+		}
+		cmd := strings.TrimSpace(line)
+		switch upper := strings.ToUpper(cmd); {
+		case strings.HasPrefix(upper, "EHLO"), strings.HasPrefix(upper, "HELO"):
+			reply("250 localhost")
+		case strings.HasPrefix(upper, "MAIL FROM:"):
+			s.from = strings.Trim(cmd[len("MAIL FROM:"):], "<>")
+			reply("250 OK")
+		case strings.HasPrefix(upper, "RCPT TO:"):
+			s.to = append(s.to, strings.Trim(cmd[len("RCPT TO:"):], "<>"))
+			reply("250 OK")
+		case upper == "DATA":
+			reply("354 End data with <CR><LF>.<CR><LF>")
+			var data []string
+			for {
+				dataLine, err := r.ReadString('\n')
+				if err != nil {
+					return
+				}
+				if dataLine == ".\r\n" {
+					break
+				}
+				data = append(data, dataLine)
+			}
+			s.data = strings.Join(data, "")
+			reply("250 OK")
+		case upper == "QUIT":
+			reply("221 Bye")
+			return
+		default:
+			reply("502 Command not implemented")
+		}
+	}
+}
+
+func TestSendEmail(t *testing.T) {
+	server := newFakeSmtpServer(t)
+	host, port, _ := net.SplitHostPort(server.listener.Addr().String())
+	smtpPort, _ := strconv.Atoi(port)
+	channel := &types.NotificationChannel{
+		Name: "email",
+		Type: types.NotificationChannelEmail,
+		Config: &types.NotificationChannelConfig{
+			SmtpHost: host,
+			SmtpPort: smtpPort,
+			From:     "kubespace@example.com",
+			To:       []string{"dev@example.com", "ops@example.com"},
+		},
+	}
+	msg := testMessage()
+	if err := newTestService().send(channel, msg); err != nil {
+		t.Fatal(err)
+	}
+	<-server.done
+	if server.from != "kubespace@example.com" {
+		t.Errorf("mail from = %q", server.from)
+	}
+	if strings.Join(server.to, ",") != "dev@example.com,ops@example.com" {
+		t.Errorf("rcpt to = %v", server.to)
+	}
+	headerBody := strings.SplitN(server.data, "\r\n\r\n", 2)
+	if len(headerBody) != 2 {
+		t.Fatalf("unexpected mail data: %q", server.data)
+	}
+	var subject string
+	for _, line := range strings.Split(headerBody[0], "\r\n") {
+		if strings.HasPrefix(line, "Subject: ") {
+			subject, _ = new(mime.WordDecoder).DecodeHeader(strings.TrimPrefix(line, "Subject: "))
+		}
+	}
+	if subject != msg.Title {
+		t.Errorf("subject = %q, want %q", subject, msg.Title)
+	}
+	for _, line := range strings.Split(strings.TrimSpace(headerBody[1]), "\r\n") {
+		if len(line) > 76 {
+			t.Errorf("body line length %d exceeds 76", len(line))
+		}
+	}
+	content, err := base64.StdEncoding.DecodeString(strings.ReplaceAll(strings.TrimSpace(headerBody[1]), "\r\n", ""))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(content) != msg.Content {
+		t.Errorf("content = %q, want %q", string(content), msg.Content)
+	}
+}
+
+func TestSendEmailWithoutRecipients(t *testing.T) {
+	channel := &types.NotificationChannel{
+		Name:   "email",
+		Type:   types.NotificationChannelEmail,
+		Config: &types.NotificationChannelConfig{SmtpHost: "127.0.0.1"},
+	}
+	if err := newTestService().send(channel, testMessage()); err == nil {
+		t.Error("expected error when no recipients configured")
+	}
+}
@@ -0,0 +1,64 @@
+package notification
+
+import (
+	"bytes"
+	"fmt"
+	"github.com/kubespace/kubespace/pkg/model/types"
+	"strings"
+	"text/template"
+)
+
+// defaultTitles 规则没有配置标题模板时各事件的默认标题
+var defaultTitles = map[string]string{
+	types.NotificationEventPipelineRunFailed:        `流水线「{{.Subject}}」构建失败`,
+	types.NotificationEventPipelineRunSucceeded:     `流水线「{{.Subject}}」构建成功`,
+	types.NotificationEventPipelineStageManual:      `流水线「{{.Subject}}」等待手动执行`,
+	types.NotificationEventAppRunningFault:          `应用「{{.Subject}}」运行异常`,
+	types.NotificationEventSpaceletOffline:          `Spacelet节点「{{.Subject}}」不在线`,
+	types.NotificationEventClusterAgentDisconnected: `集群「{{.Subject}}」agent断开连接`,
+}
+
+const defaultContent = `{{if .ScopeName}}所属：{{.ScopeName}}
+{{end}}对象：{{.Subject}}
+{{if .Message}}详情：{{.Message}}
+{{end}}时间：{{.Time.Format "2006-01-02 15:04:05"}}`
+
+// ValidateTemplate 校验模板语法
+func ValidateTemplate(text string) error {
+	if _, err := template.New("").Parse(text); err != nil {
+		return fmt.Errorf("模板格式错误：%s", err.Error())
+	}
+	return nil
+}
+
+func renderMessage(rule *types.NotificationRule, event *Event) (*Message, error) {
+	titleTmpl := rule.TitleTemplate
+	if titleTmpl == "" {
+		titleTmpl = defaultTitles[event.Type]
+	}
+	contentTmpl := rule.ContentTemplate
+	if contentTmpl == "" {
+		contentTmpl = defaultContent
+	}
+	title, err := render(titleTmpl, event)
+	if err != nil {
+		return nil, fmt.Errorf("渲染标题模板错误：%s", err.Error())
+	}
+	content, err := render(contentTmpl, event)
+	if err != nil {
+		return nil, fmt.Errorf("渲染内容模板错误：%s", err.Error())
+	}
+	return &Message{Title: strings.TrimSpace(title), Content: strings.TrimSpace(content), Event: event}, nil
+}
+
+func render(text string, event *Event) (string, error) {
+	tmpl, err := template.New("").Parse(text)
+	if err != nil {
+		return "", err
+	}
+	var buf bytes.Buffer
+	if err = tmpl.Execute(&buf, event); err != nil {
+		return "", err
+	}
+	return buf.String(), nil
+}
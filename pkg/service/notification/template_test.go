@@ -0,0 +1,94 @@
+package notification
+
+import (
+	"github.com/kubespace/kubespace/pkg/model/types"
+	"testing"
+	"time"
+)
+
+func TestRenderMessage(t *testing.T) {
+	eventTime := time.Date(2023, 5, 1, 8, 30, 0, 0, time.Local)
+	event := &Event{
+		Type:      types.NotificationEventPipelineRunFailed,
+		ScopeName: "workspace",
+		Subject:   "demo",
+		Message:   "build error",
+		Data:      map[string]interface{}{"build_number": 12},
+		Time:      eventTime,
+	}
+	tests := []struct {
+		name        string
+		rule        *types.NotificationRule
+		event       *Event
+		wantTitle   string
+		wantContent string
+		wantErr     bool
+	}{
+		{
+			name:        "default template",
+			rule:        &types.NotificationRule{},
+			event:       event,
+			wantTitle:   "流水线「demo」构建失败",
+			wantContent: "所属：workspace\n对象：demo\n详情：build error\n时间：2023-05-01 08:30:00",
+		},
+		{
+			name: "default template without scope and message",
+			rule: &types.NotificationRule{},
+			event: &Event{
+				Type:    types.NotificationEventSpaceletOffline,
+				Subject: "node-1",
+				Time:    eventTime,
+			},
+			wantTitle:   "Spacelet节点「node-1」不在线",
+			wantContent: "对象：node-1\n时间：2023-05-01 08:30:00",
+		},
+		{
+			name: "custom template with data",
+			rule: &types.NotificationRule{
+				TitleTemplate:   "[{{.Type}}] {{.Subject}}",
+				ContentTemplate: "#{{.Data.build_number}} {{.Message}}",
+			},
+			event:       event,
+			wantTitle:   "[" + types.NotificationEventPipelineRunFailed + "] demo",
+			wantContent: "#12 build error",
+		},
+		{
+			name:    "execute error",
+			rule:    &types.NotificationRule{TitleTemplate: "{{.Unknown}}"},
+			event:   event,
+			wantErr: true,
+		},
+		{
+			name:    "parse error",
+			rule:    &types.NotificationRule{ContentTemplate: "{{.Subject"},
+			event:   event,
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			msg, err := renderMessage(tt.rule, tt.event)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("err = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			if msg.Title != tt.wantTitle {
+				t.Errorf("title = %q, want %q", msg.Title, tt.wantTitle)
+			}
+			if msg.Content != tt.wantContent {
+				t.Errorf("content = %q, want %q", msg.Content, tt.wantContent)
+			}
+		})
+	}
+}
+
+func TestValidateTemplate(t *testing.T) {
+	if err := ValidateTemplate("{{.Subject}}"); err != nil {
+		t.Error(err)
+	}
+	if err := ValidateTemplate("{{.Subject"); err == nil {
+		t.Error("expected parse error")
+	}
+}
@@ -14,6 +14,8 @@ import (
 type JobRunner interface {
 	Execute(executorF plugins.ExecutorFactory, params *plugins.ExecutorParams) (interface{}, error)
 	Cancel(jobId uint) error
+	// RunningJobs 当前正在执行的任务id
+	RunningJobs() []uint
 }
 
 type jobRunner struct {
@@ -52,6 +54,16 @@ func (j *jobRunner) delJob(jobId uint) bool {
 	return true
 }
 
+func (j *jobRunner) RunningJobs() []uint {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	var jobIds []uint
+	for jobId := range j.runningJobs {
+		jobIds = append(jobIds, jobId)
+	}
+	return jobIds
+}
+
 func (j *jobRunner) Execute(executorF plugins.ExecutorFactory, params *plugins.ExecutorParams) (res interface{}, err error) {
 	// 退出时关闭日志
 	defer params.Logger.Close()
@@ -63,6 +63,12 @@ type PipelineResource struct {
 type ImageBuilds struct {
 	Dockerfile string `json:"dockerfile"`
 	Image      string `json:"image"`
+	// 构建上下文目录，相对代码根目录，默认为代码根目录
+	Context string `json:"context"`
+	// 构建参数，对应--build-arg
+	BuildArgs map[string]string `json:"build_args"`
+	// 多阶段构建的目标阶段
+	Target string `json:"target"`
 }
 
 type codeBuilderParams struct {
@@ -84,6 +90,7 @@ type codeBuilderParams struct {
 	ImageBuildRegistryId int                 `json:"image_registry_id"`
 	ImageBuildRegistry   types.ImageRegistry `json:"image_build_registry"`
 	ImageBuilds          []ImageBuilds       `json:"image_builds"`
+	ImageBuilder         *ImageBuilderConfig `json:"image_builder"`
 }
 
 // CodeBuilderPluginResult 代码构建执行结果
@@ -135,6 +142,30 @@ func newCodeBuilderExecutor(params *ExecutorParams) (*codeBuilderExecutor, error
 	return buildCodePlugin, nil
 }
 
+// resolveCodePath 获取代码目录中文件解析符号链接后的真实路径，路径不在代码目录中时返回错误，
+// 避免通过../或者仓库中的符号链接访问构建节点上的其它文件
+func resolveCodePath(codeDir, p string) (string, error) {
+	if !inDir(codeDir, filepath.Join(codeDir, p)) {
+		return "", fmt.Errorf("路径%s不在代码目录中", p)
+	}
+	realCodeDir, err := filepath.EvalSymlinks(codeDir)
+	if err != nil {
+		return "", err
+	}
+	realPath, err := filepath.EvalSymlinks(filepath.Join(codeDir, p))
+	if err != nil {
+		return "", err
+	}
+	if !inDir(realCodeDir, realPath) {
+		return "", fmt.Errorf("路径%s指向代码目录以外的文件", p)
+	}
+	return realPath, nil
+}
+
+func inDir(dir, p string) bool {
+	return p == dir || strings.HasPrefix(p, dir+string(filepath.Separator))
+}
+
 func (b *codeBuilderExecutor) Execute() (interface{}, error) {
 	steps := []stepFunc{b.clone, b.buildCode, b.buildImages}
 	for _, step := range steps {
@@ -245,82 +276,52 @@ func (b *codeBuilderExecutor) buildCode() error {
 
 // 构建镜像
 func (b *codeBuilderExecutor) buildImages() error {
+	builder, err := NewImageBuilder(b.ctx, b.Logger, b.Params.ImageBuilder)
+	if err != nil {
+		b.Log("创建镜像构建器失败：%v", err)
+		return err
+	}
 	timeStr := fmt.Sprintf("%d", time.Now().Unix())
 	for _, buildImage := range b.Params.ImageBuilds {
-		imageName := buildImage.Image
-		if imageName == "" {
+		if buildImage.Image == "" {
 			b.Log("not found build image parameter")
 			return fmt.Errorf("not found build image parameter")
 		}
-		imageName = strings.Split(imageName, ":")[0]
-		if b.Params.ImageBuildRegistry.Registry != "" {
-			imageName = b.Params.ImageBuildRegistry.Registry + "/" + imageName + ":" + timeStr
-		} else {
-			imageName = "docker.io/" + imageName + ":" + timeStr
-		}
+		imageName := imageBuildUrl(b.Params.ImageBuildRegistry.Registry, buildImage.Image, timeStr)
 		dockerfile := buildImage.Dockerfile
 		if dockerfile == "" {
 			dockerfile = "Dockerfile"
 		}
-		if err := b.buildAndPushImage(dockerfile, imageName); err != nil {
+		contextDir, err := resolveCodePath(b.codeDir, buildImage.Context)
+		if err != nil {
+			b.Log("镜像构建目录错误：%v", err)
+			return err
+		}
+		dockerfilePath, err := resolveCodePath(b.codeDir, dockerfile)
+		if err != nil {
+			b.Log("Dockerfile路径错误：%v", err)
+			return err
+		}
+		opts := &ImageBuildOptions{
+			ContextDir: contextDir,
+			Dockerfile: dockerfilePath,
+			Image:      imageName,
+			BuildArgs:  buildImage.BuildArgs,
+			Target:     buildImage.Target,
+			Registry:   &b.Params.ImageBuildRegistry,
+		}
+		if b.Params.ImageBuilder != nil {
+			opts.Platforms = b.Params.ImageBuilder.Platforms
+			if b.Params.ImageBuilder.Cache {
+				opts.CacheRef = imageCacheRef(imageName)
+			}
+		}
+		if err = builder.Build(opts); err != nil {
 			return err
 		}
+		b.images[utils.GetImageName(imageName)] = imageName
 	}
 	imgs, _ := json.Marshal(b.images)
 	b.result.ImageUrl = string(imgs)
 	return nil
 }
-
-func (b *codeBuilderExecutor) buildAndPushImage(dockerfilePath string, imageName string) error {
-	dockerfile := filepath.Join(b.codeDir, dockerfilePath)
-	//baseDockerfile := filepath.Dir(dockerfile)
-	dockerBuildCmd := fmt.Sprintf("docker build -t %s -f %s %s", imageName, dockerfile, b.codeDir)
-	cmd := exec.CommandContext(b.ctx, "bash", "-xc", dockerBuildCmd)
-	cmd.Stdout = b.Logger
-	cmd.Stderr = b.Logger
-	if err := cmd.Run(); err != nil {
-		b.Log("构建镜像%s错误：%v", imageName, err)
-		klog.Errorf("build image error: %v", err)
-		return fmt.Errorf("构建镜像%s错误：%v", imageName, err)
-	}
-	if err := b.pushImage(imageName); err != nil {
-		return err
-	}
-	b.images[utils.GetImageName(imageName)] = imageName
-	cmd = exec.CommandContext(b.ctx, "bash", "-xc", "docker rmi "+imageName)
-	cmd.Stdout = b.Logger
-	cmd.Stderr = b.Logger
-	if err := cmd.Run(); err != nil {
-		b.Log("删除本地镜像%s错误：%v", imageName, err)
-		klog.Errorf("remove image %s error: %v", imageName, err)
-		//return fmt.Errorf("删除本地构建镜像%s错误：%v", imageName, err)
-	}
-	return nil
-}
-
-func (b *codeBuilderExecutor) loginDocker(user string, password string, server string) error {
-	b.Log("docker login %s", server)
-	cmd := exec.CommandContext(b.ctx, "bash", "-c", fmt.Sprintf("docker login -u %s -p %s %s", user, password, server))
-	cmd.Stdout = b.Logger
-	cmd.Stderr = b.Logger
-	return cmd.Run()
-}
-
-func (b *codeBuilderExecutor) pushImage(imageUrl string) error {
-	if b.Params.ImageBuildRegistry.User != "" && b.Params.ImageBuildRegistry.Password != "" {
-		if err := b.loginDocker(b.Params.ImageBuildRegistry.User, b.Params.ImageBuildRegistry.Password, b.Params.ImageBuildRegistry.Registry); err != nil {
-			b.Log("docker login %s error: %v", b.Params.ImageBuildRegistry.Registry, err)
-			klog.Errorf("docker login %s error: %v", b.Params.ImageBuildRegistry.Registry, err)
-		}
-	}
-	pushCmd := fmt.Sprintf("docker push %s", imageUrl)
-	cmd := exec.CommandContext(b.ctx, "bash", "-xc", pushCmd)
-	cmd.Stdout = b.Logger
-	cmd.Stderr = b.Logger
-	if err := cmd.Run(); err != nil {
-		b.Log("docker push %s：%v", imageUrl, err)
-		klog.Errorf("push image error: %v", err)
-		return fmt.Errorf("推送镜像%s错误：%v", imageUrl, err)
-	}
-	return nil
-}
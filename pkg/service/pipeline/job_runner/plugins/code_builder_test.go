@@ -0,0 +1,57 @@
+package plugins
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestResolveCodePath(t *testing.T) {
+	root := t.TempDir()
+	codeDir := filepath.Join(root, "code")
+	if err := os.MkdirAll(filepath.Join(codeDir, "app"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	for _, f := range []string{filepath.Join(codeDir, "Dockerfile"), filepath.Join(root, "secret")} {
+		if err := os.WriteFile(f, []byte("test"), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// 仓库中指向代码目录以外的符号链接
+	if err := os.Symlink(filepath.Join(root, "secret"), filepath.Join(codeDir, "link")); err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name    string
+		path    string
+		want    string
+		wantErr bool
+	}{
+		{name: "empty context", path: "", want: codeDir},
+		{name: "current dir", path: ".", want: codeDir},
+		{name: "sub dir", path: "app", want: filepath.Join(codeDir, "app")},
+		{name: "dockerfile", path: "Dockerfile", want: filepath.Join(codeDir, "Dockerfile")},
+		{name: "clean inner parent", path: "app/../Dockerfile", want: filepath.Join(codeDir, "Dockerfile")},
+		{name: "parent dir", path: "..", wantErr: true},
+		{name: "escape with parent", path: "../secret", wantErr: true},
+		{name: "absolute path", path: "/etc/passwd", wantErr: true},
+		{name: "symlink outside", path: "link", wantErr: true},
+		{name: "not exists", path: "missing", wantErr: true},
+	}
+	realCodeDir, _ := filepath.EvalSymlinks(codeDir)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := resolveCodePath(codeDir, tt.path)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("resolveCodePath(%q) error = %v, wantErr %v", tt.path, err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			want, _ := filepath.EvalSymlinks(tt.want)
+			if got != want || !inDir(realCodeDir, got) {
+				t.Errorf("resolveCodePath(%q) = %s, want %s", tt.path, got, want)
+			}
+		})
+	}
+}
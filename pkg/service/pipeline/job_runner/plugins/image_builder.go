@@ -0,0 +1,155 @@
+package plugins
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"github.com/kubespace/kubespace/pkg/model/types"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+const (
+	// ImageBuilderDocker 通过docker build/push构建镜像，需要spacelet节点安装docker
+	ImageBuilderDocker = "docker"
+	// ImageBuilderBuildkit 通过buildctl调用buildkitd构建镜像，不依赖docker daemon
+	ImageBuilderBuildkit = "buildkit"
+	// ImageBuilderKaniko 在指定集群中以Job方式运行kaniko构建镜像
+	ImageBuilderKaniko = "kaniko"
+)
+
+// ImageBuilderConfig 镜像构建方式配置
+type ImageBuilderConfig struct {
+	// 构建方式：docker/buildkit/kaniko，默认docker
+	Type string `json:"type"`
+	// 构建的目标平台，如linux/amd64、linux/arm64，多个平台时生成多架构镜像
+	Platforms []string `json:"platforms"`
+	// 是否使用镜像仓库进行构建层缓存
+	Cache bool `json:"cache"`
+	// buildkitd服务地址，如tcp://buildkitd:1234，为空时使用buildctl默认地址
+	BuildkitAddr string `json:"buildkit_addr"`
+	// kaniko构建时Job运行的集群
+	Cluster string `json:"cluster"`
+	// kaniko构建时Job运行的命名空间
+	Namespace string `json:"namespace"`
+	// kaniko执行镜像
+	KanikoImage string `json:"kaniko_image"`
+}
+
+func (c *ImageBuilderConfig) GetType() string {
+	if c == nil || c.Type == "" {
+		return ImageBuilderDocker
+	}
+	return c.Type
+}
+
+// ImageBuildOptions 构建单个镜像的参数
+type ImageBuildOptions struct {
+	// 构建上下文目录
+	ContextDir string
+	// Dockerfile文件路径
+	Dockerfile string
+	// 构建并推送的镜像地址
+	Image string
+	// 构建的目标平台
+	Platforms []string
+	// 构建参数，对应--build-arg
+	BuildArgs map[string]string
+	// 多阶段构建的目标阶段，对应--target
+	Target string
+	// 镜像仓库层缓存地址，为空不使用缓存
+	CacheRef string
+	// 推送镜像的仓库认证信息
+	Registry *types.ImageRegistry
+}
+
+// ImageBuilder 镜像构建器，构建完成后将镜像推送到镜像仓库
+type ImageBuilder interface {
+	Build(opts *ImageBuildOptions) error
+}
+
+// NewImageBuilder 根据构建配置创建本地执行的镜像构建器，kaniko需要在集群中运行，不通过该方法创建
+func NewImageBuilder(ctx context.Context, logger Logger, config *ImageBuilderConfig) (ImageBuilder, error) {
+	switch config.GetType() {
+	case ImageBuilderDocker:
+		return &dockerImageBuilder{ctx: ctx, Logger: logger}, nil
+	case ImageBuilderBuildkit:
+		return &buildkitImageBuilder{ctx: ctx, Logger: logger, addr: config.BuildkitAddr}, nil
+	}
+	return nil, fmt.Errorf("not support image builder: %s", config.GetType())
+}
+
+// imageCacheRef 镜像仓库层缓存地址，与构建镜像同一仓库，tag为buildcache
+func imageCacheRef(image string) string {
+	registry, name, _ := parseImage(image)
+	return registry + "/" + name + ":buildcache"
+}
+
+// parseImage 解析镜像地址的仓库、名称以及tag
+func parseImage(image string) (registry, name, tag string) {
+	name = image
+	if idx := strings.LastIndex(name, ":"); idx > 0 && !strings.Contains(name[idx:], "/") {
+		tag = name[idx+1:]
+		name = name[:idx]
+	}
+	registry = "docker.io"
+	if parts := strings.SplitN(name, "/", 2); len(parts) == 2 &&
+		(strings.ContainsAny(parts[0], ".:") || parts[0] == "localhost") {
+		registry = parts[0]
+		name = parts[1]
+	}
+	return
+}
+
+// dockerConfigAuthKey docker config.json中镜像仓库认证的key，docker hub需要使用固定的地址
+func dockerConfigAuthKey(registry string) string {
+	if registry == "" || registry == "docker.io" || registry == "index.docker.io" {
+		return "https://index.docker.io/v1/"
+	}
+	return registry
+}
+
+// DockerConfigJson 生成包含镜像仓库认证信息的docker config.json内容
+func DockerConfigJson(registries ...*types.ImageRegistry) ([]byte, error) {
+	auths := make(map[string]map[string]string)
+	for _, reg := range registries {
+		if reg == nil || reg.User == "" || reg.Password == "" {
+			continue
+		}
+		auths[dockerConfigAuthKey(reg.Registry)] = map[string]string{
+			"auth": base64.StdEncoding.EncodeToString([]byte(reg.User + ":" + reg.Password)),
+		}
+	}
+	return json.Marshal(map[string]interface{}{"auths": auths})
+}
+
+// NewDockerConfigDir 将镜像仓库认证信息写入临时目录的config.json，
+// 通过DOCKER_CONFIG环境变量传递给docker/buildctl，避免密码出现在进程参数中，使用完成后需要删除该目录
+func NewDockerConfigDir(registries ...*types.ImageRegistry) (string, error) {
+	configJson, err := DockerConfigJson(registries...)
+	if err != nil {
+		return "", err
+	}
+	dir, err := os.MkdirTemp("", "kubespace-docker-config-")
+	if err != nil {
+		return "", err
+	}
+	if err = os.WriteFile(filepath.Join(dir, "config.json"), configJson, 0600); err != nil {
+		os.RemoveAll(dir)
+		return "", err
+	}
+	return dir, nil
+}
+
+// runCommand 执行命令，输出写入任务日志，命令行中不包含任何认证信息
+func runCommand(ctx context.Context, logger Logger, env []string, name string, args ...string) error {
+	logger.Log("+ %s %s", name, strings.Join(args, " "))
+	cmd := exec.CommandContext(ctx, name, args...)
+	cmd.Env = append(os.Environ(), env...)
+	cmd.Stdout = logger
+	cmd.Stderr = logger
+	return cmd.Run()
+}
@@ -0,0 +1,60 @@
+package plugins
+
+import (
+	"context"
+	"fmt"
+	"k8s.io/klog/v2"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// buildkitImageBuilder 通过buildctl调用buildkitd构建并推送镜像，不需要docker daemon
+type buildkitImageBuilder struct {
+	Logger
+	ctx context.Context
+	// buildkitd服务地址
+	addr string
+}
+
+func (b *buildkitImageBuilder) Build(opts *ImageBuildOptions) error {
+	configDir, err := NewDockerConfigDir(opts.Registry)
+	if err != nil {
+		b.Log("生成镜像仓库认证配置失败：%v", err)
+		return err
+	}
+	defer os.RemoveAll(configDir)
+	// buildctl通过DOCKER_CONFIG读取镜像仓库认证
+	env := []string{"DOCKER_CONFIG=" + configDir}
+
+	var args []string
+	if b.addr != "" {
+		args = append(args, "--addr", b.addr)
+	}
+	args = append(args, "build",
+		"--frontend", "dockerfile.v0",
+		"--local", "context="+opts.ContextDir,
+		"--local", "dockerfile="+filepath.Dir(opts.Dockerfile),
+		"--opt", "filename="+filepath.Base(opts.Dockerfile),
+		"--output", fmt.Sprintf("type=image,name=%s,push=true", opts.Image))
+	if len(opts.Platforms) > 0 {
+		args = append(args, "--opt", "platform="+strings.Join(opts.Platforms, ","))
+	}
+	for _, k := range sortedKeys(opts.BuildArgs) {
+		args = append(args, "--opt", "build-arg:"+k+"="+opts.BuildArgs[k])
+	}
+	if opts.Target != "" {
+		args = append(args, "--opt", "target="+opts.Target)
+	}
+	if opts.CacheRef != "" {
+		args = append(args,
+			"--import-cache", "type=registry,ref="+opts.CacheRef,
+			"--export-cache", "type=registry,ref="+opts.CacheRef+",mode=max")
+	}
+	if err = runCommand(b.ctx, b.Logger, env, "buildctl", args...); err != nil {
+		b.Log("构建镜像%s错误：%v", opts.Image, err)
+		klog.Errorf("buildctl build image error: %v", err)
+		return fmt.Errorf("构建镜像%s错误：%v", opts.Image, err)
+	}
+	return nil
+}
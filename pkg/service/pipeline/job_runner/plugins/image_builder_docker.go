@@ -0,0 +1,123 @@
+package plugins
+
+import (
+	"context"
+	"fmt"
+	"k8s.io/klog/v2"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// dockerImageBuilder 通过docker构建镜像
+// 未指定多平台以及缓存时使用docker build/push，否则使用docker buildx构建并直接推送
+type dockerImageBuilder struct {
+	Logger
+	ctx context.Context
+}
+
+func (d *dockerImageBuilder) Build(opts *ImageBuildOptions) error {
+	configDir, err := NewDockerConfigDir(opts.Registry)
+	if err != nil {
+		d.Log("生成镜像仓库认证配置失败：%v", err)
+		return err
+	}
+	defer os.RemoveAll(configDir)
+	if err = linkDockerCliDirs(configDir); err != nil {
+		d.Log("生成docker配置目录失败：%v", err)
+		return err
+	}
+	env := []string{"DOCKER_CONFIG=" + configDir}
+
+	if len(opts.Platforms) > 0 || opts.CacheRef != "" {
+		return d.buildx(env, opts)
+	}
+
+	args := []string{"build", "-t", opts.Image, "-f", opts.Dockerfile}
+	args = append(args, dockerBuildArgs(opts)...)
+	args = append(args, opts.ContextDir)
+	if err = runCommand(d.ctx, d.Logger, env, "docker", args...); err != nil {
+		d.Log("构建镜像%s错误：%v", opts.Image, err)
+		klog.Errorf("build image error: %v", err)
+		return fmt.Errorf("构建镜像%s错误：%v", opts.Image, err)
+	}
+	if err = runCommand(d.ctx, d.Logger, env, "docker", "push", opts.Image); err != nil {
+		d.Log("docker push %s：%v", opts.Image, err)
+		klog.Errorf("push image error: %v", err)
+		return fmt.Errorf("推送镜像%s错误：%v", opts.Image, err)
+	}
+	if err = runCommand(d.ctx, d.Logger, env, "docker", "rmi", opts.Image); err != nil {
+		d.Log("删除本地镜像%s错误：%v", opts.Image, err)
+		klog.Errorf("remove image %s error: %v", opts.Image, err)
+	}
+	return nil
+}
+
+// buildx 多平台或开启缓存时，使用buildx构建并推送到镜像仓库
+func (d *dockerImageBuilder) buildx(env []string, opts *ImageBuildOptions) error {
+	args := []string{"buildx", "build", "--push", "-t", opts.Image, "-f", opts.Dockerfile}
+	if len(opts.Platforms) > 0 {
+		args = append(args, "--platform", strings.Join(opts.Platforms, ","))
+	}
+	if opts.CacheRef != "" {
+		args = append(args,
+			"--cache-from", "type=registry,ref="+opts.CacheRef,
+			"--cache-to", "type=registry,ref="+opts.CacheRef+",mode=max")
+	}
+	args = append(args, dockerBuildArgs(opts)...)
+	args = append(args, opts.ContextDir)
+	if err := runCommand(d.ctx, d.Logger, env, "docker", args...); err != nil {
+		d.Log("构建镜像%s错误：%v", opts.Image, err)
+		klog.Errorf("buildx image error: %v", err)
+		return fmt.Errorf("构建镜像%s错误：%v", opts.Image, err)
+	}
+	return nil
+}
+
+// dockerCliDirs 临时DOCKER_CONFIG目录中需要保留的docker客户端目录，
+// cli-plugins为buildx等插件，buildx为已创建的builder实例，认证信息仍只使用临时目录中的config.json
+var dockerCliDirs = []string{"cli-plugins", "buildx"}
+
+// linkDockerCliDirs 将本机docker配置目录中的插件以及buildx builder链接到临时配置目录
+func linkDockerCliDirs(configDir string) error {
+	srcDir := os.Getenv("DOCKER_CONFIG")
+	if srcDir == "" {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return nil
+		}
+		srcDir = filepath.Join(home, ".docker")
+	}
+	for _, name := range dockerCliDirs {
+		src := filepath.Join(srcDir, name)
+		if _, err := os.Stat(src); err != nil {
+			continue
+		}
+		if err := os.Symlink(src, filepath.Join(configDir, name)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// dockerBuildArgs docker构建参数--build-arg以及--target
+func dockerBuildArgs(opts *ImageBuildOptions) []string {
+	var args []string
+	for _, k := range sortedKeys(opts.BuildArgs) {
+		args = append(args, "--build-arg", k+"="+opts.BuildArgs[k])
+	}
+	if opts.Target != "" {
+		args = append(args, "--target", opts.Target)
+	}
+	return args
+}
+
+func sortedKeys(m map[string]string) []string {
+	var keys []string
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
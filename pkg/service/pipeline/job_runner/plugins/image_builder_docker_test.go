@@ -0,0 +1,89 @@
+package plugins
+
+import (
+	"context"
+	"fmt"
+	"github.com/kubespace/kubespace/pkg/model/types"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+type testLogger struct {
+	strings.Builder
+}
+
+func (l *testLogger) Log(format string, a ...interface{}) {
+	l.WriteString(fmt.Sprintf(format, a...) + "\n")
+}
+
+func (l *testLogger) Reset(format string, a ...interface{}) {
+	l.Builder.Reset()
+	l.Log(format, a...)
+}
+
+func (l *testLogger) Close() error {
+	return nil
+}
+
+func TestDockerBuildx(t *testing.T) {
+	root := t.TempDir()
+	// 本机docker配置目录中已安装buildx插件并创建了builder
+	dockerConfig := filepath.Join(root, "docker")
+	for _, dir := range []string{"cli-plugins", "buildx/instances"} {
+		if err := os.MkdirAll(filepath.Join(dockerConfig, dir), 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := os.WriteFile(filepath.Join(dockerConfig, "config.json"), []byte(`{"auths":{"old":{}}}`), 0600); err != nil {
+		t.Fatal(err)
+	}
+	// 模拟docker命令，记录执行参数以及DOCKER_CONFIG目录内容
+	binDir := filepath.Join(root, "bin")
+	output := filepath.Join(root, "output")
+	script := `#!/bin/sh
+echo "$@" > ` + output + `
+ls "$DOCKER_CONFIG/cli-plugins" "$DOCKER_CONFIG/buildx" >/dev/null || exit 1
+cat "$DOCKER_CONFIG/config.json" >> ` + output + `
+`
+	if err := os.MkdirAll(binDir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(binDir, "docker"), []byte(script), 0755); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("PATH", binDir+string(os.PathListSeparator)+os.Getenv("PATH"))
+	t.Setenv("DOCKER_CONFIG", dockerConfig)
+
+	logger := &testLogger{}
+	builder := &dockerImageBuilder{ctx: context.Background(), Logger: logger}
+	err := builder.Build(&ImageBuildOptions{
+		ContextDir: root,
+		Dockerfile: "Dockerfile",
+		Image:      "registry.example.com/app:v1",
+		Platforms:  []string{"linux/amd64", "linux/arm64"},
+		CacheRef:   "registry.example.com/app:cache",
+		Registry:   &types.ImageRegistry{Registry: "registry.example.com", User: "user", Password: "password"},
+	})
+	if err != nil {
+		t.Fatalf("build error: %s, logs: %s", err, logger.String())
+	}
+	out, err := os.ReadFile(output)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(out), "buildx build --push") || !strings.Contains(string(out), "--platform linux/amd64,linux/arm64") {
+		t.Errorf("unexpected docker args: %s", out)
+	}
+	// 认证信息只使用临时配置中的镜像仓库
+	if !strings.Contains(string(out), "registry.example.com") || strings.Contains(string(out), `"old"`) {
+		t.Errorf("unexpected docker config: %s", out)
+	}
+	// 临时配置目录删除后，本机的插件以及builder不受影响
+	for _, dir := range []string{"cli-plugins", "buildx/instances"} {
+		if _, err = os.Stat(filepath.Join(dockerConfig, dir)); err != nil {
+			t.Errorf("docker config %s removed: %s", dir, err)
+		}
+	}
+}
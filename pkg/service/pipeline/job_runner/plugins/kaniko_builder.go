@@ -0,0 +1,440 @@
+package plugins
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	kubetypes "github.com/kubespace/kubespace/pkg/kubernetes/types"
+	"github.com/kubespace/kubespace/pkg/model"
+	"github.com/kubespace/kubespace/pkg/model/types"
+	"github.com/kubespace/kubespace/pkg/service/cluster"
+	"github.com/kubespace/kubespace/pkg/utils"
+	"io"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/klog/v2"
+	"net/url"
+	"path"
+	"sigs.k8s.io/yaml"
+	"strings"
+	"time"
+)
+
+const (
+	// DefaultKanikoImage kaniko构建默认使用的镜像
+	DefaultKanikoImage = "gcr.io/kaniko-project/executor:v1.9.2"
+	// DefaultCraneImage 多架构镜像合并manifest时使用的镜像
+	DefaultCraneImage = "gcr.io/go-containerregistry/crane:debug"
+
+	// kaniko git构建上下文克隆的目录
+	kanikoBuildContextDir = "/kaniko/buildcontext"
+	// PipelineJobIdLabel 流水线任务在集群中创建的资源标签
+	PipelineJobIdLabel = "kubespace.cn/pipeline-job-id"
+)
+
+// KanikoCodeBuilderPlugin 在指定的kubernetes集群中以Job方式运行kaniko构建镜像，不依赖docker daemon
+type KanikoCodeBuilderPlugin struct {
+	*model.Models
+	KubeClient *cluster.KubeClient
+}
+
+func (p KanikoCodeBuilderPlugin) Executor(params *ExecutorParams) (Executor, error) {
+	return newKanikoBuilderExecutor(params, p.KubeClient)
+}
+
+type kanikoBuilderExecutor struct {
+	Logger
+	kubeClient *cluster.KubeClient
+	Params     *codeBuilderParams
+	config     *ImageBuilderConfig
+	images     map[string]string
+	result     *CodeBuilderPluginResult
+	// 本次构建在集群中创建的资源名称前缀
+	name string
+	// 是否已取消
+	canceled   bool
+	cancelFunc context.CancelFunc
+	ctx        context.Context
+}
+
+func newKanikoBuilderExecutor(params *ExecutorParams, kubeClient *cluster.KubeClient) (*kanikoBuilderExecutor, error) {
+	var buildParams codeBuilderParams
+	if err := utils.ConvertTypeByJson(params.Params, &buildParams); err != nil {
+		return nil, err
+	}
+	if buildParams.ImageBuildRegistry.Registry == "" {
+		buildParams.ImageBuildRegistry.Registry = "docker.io"
+	}
+	config := buildParams.ImageBuilder
+	if config == nil || config.Cluster == "" || config.Namespace == "" {
+		return nil, fmt.Errorf("kaniko构建需要指定运行的集群以及命名空间")
+	}
+	if config.KanikoImage == "" {
+		config.KanikoImage = DefaultKanikoImage
+	}
+	ctx, cancelFunc := context.WithCancel(context.Background())
+	return &kanikoBuilderExecutor{
+		Logger:     params.Logger,
+		kubeClient: kubeClient,
+		Params:     &buildParams,
+		config:     config,
+		images:     make(map[string]string),
+		result: &CodeBuilderPluginResult{
+			ImageRegistryId: buildParams.ImageBuildRegistryId,
+			ImageRegistry:   buildParams.ImageBuildRegistry.Registry,
+		},
+		name:       fmt.Sprintf("kubespace-build-%d", params.JobId),
+		ctx:        ctx,
+		cancelFunc: cancelFunc,
+	}, nil
+}
+
+func (k *kanikoBuilderExecutor) Execute() (interface{}, error) {
+	if k.Params.CodeBuild && k.Params.CodeBuildType != CodeBuildTypeNone {
+		k.Log("kaniko构建方式不支持代码编译步骤，请使用多阶段构建的Dockerfile进行代码编译")
+		return nil, fmt.Errorf("kaniko image builder not support code build")
+	}
+	gitContext, err := k.gitContext()
+	if err != nil {
+		k.Log("%v", err)
+		return nil, err
+	}
+	if err = k.createSecret(); err != nil {
+		k.Log("创建构建认证信息失败：%v", err)
+		return nil, err
+	}
+	defer k.cleanup()
+
+	timeStr := fmt.Sprintf("%d", time.Now().Unix())
+	for i, buildImage := range k.Params.ImageBuilds {
+		if buildImage.Image == "" {
+			k.Log("not found build image parameter")
+			return nil, fmt.Errorf("not found build image parameter")
+		}
+		imageName := imageBuildUrl(k.Params.ImageBuildRegistry.Registry, buildImage.Image, timeStr)
+		if err = k.buildImage(fmt.Sprintf("%s-%d", k.name, i), gitContext, imageName, &buildImage); err != nil {
+			if k.canceled {
+				return nil, nil
+			}
+			return nil, err
+		}
+		k.images[utils.GetImageName(imageName)] = imageName
+	}
+	imgs, _ := json.Marshal(k.images)
+	k.result.ImageUrl = string(imgs)
+	return k.result, nil
+}
+
+func (k *kanikoBuilderExecutor) Cancel() error {
+	k.canceled = true
+	k.cancelFunc()
+	return nil
+}
+
+// gitContext kaniko git构建上下文，格式为git://host/path#refs/heads/branch#commit
+// kaniko仅支持通过https克隆代码，认证信息通过环境变量GIT_USERNAME、GIT_PASSWORD传入
+func (k *kanikoBuilderExecutor) gitContext() (string, error) {
+	u, err := url.Parse(k.Params.CodeUrl)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") {
+		return "", fmt.Errorf("kaniko构建仅支持http(s)代码仓库地址：%s", k.Params.CodeUrl)
+	}
+	if k.Params.CodeSecret != nil && k.Params.CodeSecret.Type == types.SettingsSecretTypeKey {
+		return "", fmt.Errorf("kaniko构建不支持密钥方式的代码仓库认证")
+	}
+	gitContext := "git://" + u.Host + u.Path
+	if k.Params.CodeBranch != "" {
+		gitContext += "#refs/heads/" + k.Params.CodeBranch
+		if k.Params.CodeCommitId != "" {
+			gitContext += "#" + k.Params.CodeCommitId
+		}
+	}
+	return gitContext, nil
+}
+
+// createSecret 创建构建使用的镜像仓库以及代码仓库认证信息
+func (k *kanikoBuilderExecutor) createSecret() error {
+	dockerConfig, err := DockerConfigJson(&k.Params.ImageBuildRegistry)
+	if err != nil {
+		return err
+	}
+	gitUser, gitPassword := "", ""
+	if secret := k.Params.CodeSecret; secret != nil {
+		switch secret.Type {
+		case types.SettingsSecretTypePassword:
+			gitUser, gitPassword = secret.User, secret.Password
+		case types.SettingsSecretTypeToken:
+			gitUser, gitPassword = "oauth2", secret.AccessToken
+		}
+	}
+	secret := &corev1.Secret{
+		TypeMeta: metav1.TypeMeta{APIVersion: "v1", Kind: "Secret"},
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      k.name,
+			Namespace: k.config.Namespace,
+			Labels:    k.labels(),
+		},
+		Type: corev1.SecretTypeOpaque,
+		StringData: map[string]string{
+			"config.json":  string(dockerConfig),
+			"git-username": gitUser,
+			"git-password": gitPassword,
+		},
+	}
+	return k.apply(secret)
+}
+
+// buildImage 构建单个镜像，多平台时每个平台分别在对应架构的节点构建，最后通过crane合并为多架构镜像
+func (k *kanikoBuilderExecutor) buildImage(name, gitContext, image string, buildImage *ImageBuilds) error {
+	platforms := k.config.Platforms
+	if len(platforms) <= 1 {
+		return k.runJob(name, k.kanikoPodSpec(gitContext, image, platforms, buildImage))
+	}
+	var manifests []string
+	for _, platform := range platforms {
+		arch := path.Base(platform)
+		archImage := image + "-" + arch
+		if err := k.runJob(name+"-"+arch, k.kanikoPodSpec(gitContext, archImage, []string{platform}, buildImage)); err != nil {
+			return err
+		}
+		manifests = append(manifests, archImage)
+	}
+	args := []string{"index", "append", "-t", image}
+	for _, m := range manifests {
+		args = append(args, "-m", m)
+	}
+	return k.runJob(name+"-manifest", &corev1.PodSpec{
+		RestartPolicy: corev1.RestartPolicyNever,
+		Containers: []corev1.Container{{
+			Name:         "crane",
+			Image:        DefaultCraneImage,
+			Command:      []string{"crane"},
+			Args:         args,
+			Env:          []corev1.EnvVar{{Name: "DOCKER_CONFIG", Value: "/kaniko/.docker"}},
+			VolumeMounts: k.dockerConfigMounts(),
+		}},
+		Volumes: k.dockerConfigVolumes(),
+	})
+}
+
+func (k *kanikoBuilderExecutor) kanikoPodSpec(gitContext, image string, platforms []string, buildImage *ImageBuilds) *corev1.PodSpec {
+	dockerfile := buildImage.Dockerfile
+	if dockerfile == "" {
+		dockerfile = "Dockerfile"
+	}
+	args := []string{
+		"--context=" + gitContext,
+		"--dockerfile=" + path.Join(kanikoBuildContextDir, dockerfile),
+		"--destination=" + image,
+	}
+	if buildImage.Context != "" {
+		args = append(args, "--context-sub-path="+buildImage.Context)
+	}
+	for _, key := range sortedKeys(buildImage.BuildArgs) {
+		args = append(args, "--build-arg="+key+"="+buildImage.BuildArgs[key])
+	}
+	if buildImage.Target != "" {
+		args = append(args, "--target="+buildImage.Target)
+	}
+	if k.config.Cache {
+		// kaniko缓存层以tag区分，缓存仓库需要单独的镜像名称
+		registry, repo, _ := parseImage(image)
+		args = append(args, "--cache=true", "--cache-repo="+registry+"/"+repo+"/cache")
+	}
+	var nodeSelector map[string]string
+	if len(platforms) == 1 {
+		args = append(args, "--custom-platform="+platforms[0])
+		nodeSelector = map[string]string{corev1.LabelArchStable: path.Base(platforms[0])}
+	}
+	secretEnv := func(name, key string) corev1.EnvVar {
+		return corev1.EnvVar{Name: name, ValueFrom: &corev1.EnvVarSource{SecretKeyRef: &corev1.SecretKeySelector{
+			LocalObjectReference: corev1.LocalObjectReference{Name: k.name},
+			Key:                  key,
+		}}}
+	}
+	return &corev1.PodSpec{
+		RestartPolicy: corev1.RestartPolicyNever,
+		NodeSelector:  nodeSelector,
+		Containers: []corev1.Container{{
+			Name:         "kaniko",
+			Image:        k.config.KanikoImage,
+			Args:         args,
+			Env:          []corev1.EnvVar{secretEnv("GIT_USERNAME", "git-username"), secretEnv("GIT_PASSWORD", "git-password")},
+			VolumeMounts: k.dockerConfigMounts(),
+		}},
+		Volumes: k.dockerConfigVolumes(),
+	}
+}
+
+func (k *kanikoBuilderExecutor) dockerConfigVolumes() []corev1.Volume {
+	return []corev1.Volume{{
+		Name: "docker-config",
+		VolumeSource: corev1.VolumeSource{Secret: &corev1.SecretVolumeSource{
+			SecretName: k.name,
+			Items:      []corev1.KeyToPath{{Key: "config.json", Path: "config.json"}},
+		}},
+	}}
+}
+
+func (k *kanikoBuilderExecutor) dockerConfigMounts() []corev1.VolumeMount {
+	return []corev1.VolumeMount{{Name: "docker-config", MountPath: "/kaniko/.docker"}}
+}
+
+func (k *kanikoBuilderExecutor) labels() map[string]string {
+	return map[string]string{PipelineJobIdLabel: fmt.Sprintf("%d", k.Params.JobId)}
+}
+
+func (k *kanikoBuilderExecutor) apply(obj interface{}) error {
+	yamlBytes, err := yaml.Marshal(obj)
+	if err != nil {
+		return err
+	}
+	resp := k.kubeClient.Apply(k.config.Cluster, map[string]interface{}{"yaml": string(yamlBytes)})
+	if !resp.IsSuccess() {
+		return fmt.Errorf("%s", resp.Msg)
+	}
+	return nil
+}
+
+// runJob 创建Job并等待执行完成，执行过程中将Pod日志写入任务日志
+func (k *kanikoBuilderExecutor) runJob(name string, podSpec *corev1.PodSpec) error {
+	backoffLimit := int32(0)
+	ttl := int32(600)
+	job := &batchv1.Job{
+		TypeMeta: metav1.TypeMeta{APIVersion: "batch/v1", Kind: "Job"},
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      name,
+			Namespace: k.config.Namespace,
+			Labels:    k.labels(),
+		},
+		Spec: batchv1.JobSpec{
+			BackoffLimit:            &backoffLimit,
+			TTLSecondsAfterFinished: &ttl,
+			Template: corev1.PodTemplateSpec{
+				ObjectMeta: metav1.ObjectMeta{Labels: k.labels()},
+				Spec:       *podSpec,
+			},
+		},
+	}
+	k.Log("创建构建任务%s/%s", k.config.Namespace, name)
+	if err := k.apply(job); err != nil {
+		k.Log("创建构建任务%s失败：%v", name, err)
+		return err
+	}
+	logged := false
+	tick := time.NewTicker(3 * time.Second)
+	defer tick.Stop()
+	for {
+		select {
+		case <-k.ctx.Done():
+			return fmt.Errorf("job canceled")
+		case <-tick.C:
+		}
+		if !logged {
+			logged = k.streamLog(name)
+		}
+		resp := k.kubeClient.Get(k.config.Cluster, kubetypes.JobType, map[string]interface{}{
+			"name":      name,
+			"namespace": k.config.Namespace,
+		})
+		if !resp.IsSuccess() {
+			klog.Errorf("get job %s error: %s", name, resp.Msg)
+			continue
+		}
+		var jobStatus batchv1.Job
+		if err := utils.ConvertTypeByJson(resp.Data, &jobStatus); err != nil {
+			klog.Errorf("convert job %s error: %s", name, err.Error())
+			continue
+		}
+		if jobStatus.Status.Succeeded > 0 {
+			return nil
+		}
+		if jobStatus.Status.Failed > 0 {
+			k.Log("构建任务%s执行失败", name)
+			return fmt.Errorf("构建任务%s执行失败", name)
+		}
+	}
+}
+
+// streamLog 查找Job的Pod，Pod启动后将日志写入任务日志，返回是否已开始输出日志
+func (k *kanikoBuilderExecutor) streamLog(jobName string) bool {
+	process := false
+	resp := k.kubeClient.List(k.config.Cluster, kubetypes.PodType, map[string]interface{}{
+		"namespace":      k.config.Namespace,
+		"label_selector": &metav1.LabelSelector{MatchLabels: map[string]string{"job-name": jobName}},
+		"process":        &process,
+	})
+	if !resp.IsSuccess() {
+		return false
+	}
+	var pods []corev1.Pod
+	if err := utils.ConvertTypeByJson(resp.Data, &pods); err != nil || len(pods) == 0 {
+		return false
+	}
+	if pods[0].Status.Phase == corev1.PodPending {
+		return false
+	}
+	if err := StreamPodLog(k.ctx, k.kubeClient, k.config.Cluster, &pods[0], k.Logger); err != nil {
+		klog.Errorf("get pod %s log error: %s", pods[0].Name, err.Error())
+		return false
+	}
+	return true
+}
+
+// StreamPodLog 将pod第一个容器的日志持续写入到writer中，直到日志结束或者ctx取消
+func StreamPodLog(ctx context.Context, kubeClient *cluster.KubeClient, clusterId string, pod *corev1.Pod, writer io.Writer) error {
+	podCli, err := kubeClient.Pods(clusterId)
+	if err != nil {
+		return err
+	}
+	logOuter, err := podCli.Log(map[string]interface{}{
+		"name":      pod.Name,
+		"namespace": pod.Namespace,
+		"container": pod.Spec.Containers[0].Name,
+	})
+	if err != nil {
+		return err
+	}
+	go func() {
+		defer logOuter.Close()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-logOuter.StopCh():
+				return
+			case out, ok := <-logOuter.OutCh():
+				if !ok {
+					return
+				}
+				if s, ok := out.(string); ok {
+					writer.Write([]byte(s))
+				}
+			}
+		}
+	}()
+	return nil
+}
+
+// cleanup 删除构建过程中创建的Job、Pod以及Secret
+func (k *kanikoBuilderExecutor) cleanup() {
+	labelSelector := &metav1.LabelSelector{MatchLabels: k.labels()}
+	for _, resType := range []string{kubetypes.JobType, kubetypes.PodType, kubetypes.SecretType} {
+		resp := k.kubeClient.Delete(k.config.Cluster, resType, map[string]interface{}{
+			"namespace":      k.config.Namespace,
+			"label_selector": labelSelector,
+		})
+		if !resp.IsSuccess() {
+			klog.Errorf("delete job=%d kaniko %s error: %s", k.Params.JobId, resType, resp.Msg)
+		}
+	}
+}
+
+// imageBuildUrl 构建镜像的完整地址，以时间戳作为tag
+func imageBuildUrl(registry, image, tag string) string {
+	image = strings.Split(image, ":")[0]
+	if registry == "" {
+		registry = "docker.io"
+	}
+	return registry + "/" + image + ":" + tag
+}
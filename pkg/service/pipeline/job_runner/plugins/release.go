@@ -10,7 +10,7 @@ import (
 	"github.com/kubespace/kubespace/pkg/third/httpclient"
 	"github.com/kubespace/kubespace/pkg/utils"
 	"k8s.io/klog/v2"
-	"os/exec"
+	"os"
 	"path/filepath"
 	"strings"
 )
@@ -61,6 +61,8 @@ type releaserExecutor struct {
 	ctx        context.Context
 	cancelFunc context.CancelFunc
 	canceled   bool
+	// docker命令执行时的环境变量，包含镜像仓库认证配置目录
+	dockerEnv []string
 }
 
 func newReleaserExecutor(pluginParams *ExecutorParams, client *httpclient.HttpClient) (*releaserExecutor, error) {
@@ -145,28 +147,22 @@ func (r *releaserExecutor) tagImage() error {
 	r.Log("images=%s", r.Params.Images)
 	images := stringToImage(r.Params.Images)
 	r.Log("images=%v", images)
-	if r.Params.ImageBuildRegistry.User != "" && r.Params.ImageBuildRegistry.Password != "" {
-		if err := r.loginDocker(r.Params.ImageBuildRegistry.User, r.Params.ImageBuildRegistry.Password, r.Params.ImageBuildRegistry.Registry); err != nil {
-			r.Log("docker login %s error: %v", r.Params.ImageBuildRegistry.Registry, err)
-			klog.Errorf("docker login %s error: %v", r.Params.ImageBuildRegistry.Registry, err)
-		}
+	// 镜像仓库认证信息写入临时docker配置，不通过docker login传递密码
+	configDir, err := NewDockerConfigDir(&r.Params.ImageBuildRegistry)
+	if err != nil {
+		r.Log("生成镜像仓库认证配置失败：%v", err)
+		return err
 	}
+	defer os.RemoveAll(configDir)
+	r.dockerEnv = []string{"DOCKER_CONFIG=" + configDir}
 	for _, image := range images {
-		if err := r.tagAndPushImage(image); err != nil {
+		if err = r.tagAndPushImage(image); err != nil {
 			return err
 		}
 	}
 	return nil
 }
 
-func (r *releaserExecutor) loginDocker(user string, password string, server string) error {
-	r.Log("docker login %s", server)
-	cmd := exec.CommandContext(r.ctx, "bash", "-c", fmt.Sprintf("docker login -u %s -p %s %s", user, password, server))
-	cmd.Stdout = r.Logger
-	cmd.Stderr = r.Logger
-	return cmd.Run()
-}
-
 func (r *releaserExecutor) getReleaseImage(img, version string) string {
 	imgSplit := strings.Split(img, ":")
 	imgName := ""
@@ -179,20 +175,13 @@ func (r *releaserExecutor) getReleaseImage(img, version string) string {
 }
 
 func (r *releaserExecutor) tagAndPushImage(image string) error {
-	dockerBuildCmd := fmt.Sprintf("docker pull %s", image)
-	cmd := exec.CommandContext(r.ctx, "bash", "-xc", dockerBuildCmd)
-	cmd.Stdout = r.Logger
-	cmd.Stderr = r.Logger
-	if err := cmd.Run(); err != nil {
+	if err := runCommand(r.ctx, r.Logger, r.dockerEnv, "docker", "pull", image); err != nil {
 		r.Log("拉取镜像%s错误：%v", image, err)
 		klog.Errorf("pull image error: %v", err)
 		return fmt.Errorf("拉取镜像%s错误：%v", image, err)
 	}
 	newImage := r.getReleaseImage(image, r.Params.Version)
-	cmd = exec.Command("bash", "-xc", "docker tag "+image+" "+newImage)
-	cmd.Stdout = r.Logger
-	cmd.Stderr = r.Logger
-	if err := cmd.Run(); err != nil {
+	if err := runCommand(r.ctx, r.Logger, r.dockerEnv, "docker", "tag", image, newImage); err != nil {
 		r.Log("镜像打标签%s错误：%v", image, err)
 		klog.Errorf("tag image error: %v", err)
 		return fmt.Errorf("镜像打标签%s错误：%v", image, err)
@@ -201,11 +190,7 @@ func (r *releaserExecutor) tagAndPushImage(image string) error {
 		return err
 	}
 	r.Images[utils.GetImageName(newImage)] = newImage
-	rmiImage := fmt.Sprintf("docker rmi %s && docker rmi %s", image, newImage)
-	cmd = exec.CommandContext(r.ctx, "bash", "-xc", rmiImage)
-	cmd.Stdout = r.Logger
-	cmd.Stderr = r.Logger
-	if err := cmd.Run(); err != nil {
+	if err := runCommand(r.ctx, r.Logger, r.dockerEnv, "docker", "rmi", image, newImage); err != nil {
 		r.Log("删除本地镜像%s错误：%v", image, err)
 		klog.Errorf("rmi image error: %v", err)
 		return fmt.Errorf("删除本地构建镜像%s错误：%v", image, err)
@@ -214,11 +199,7 @@ func (r *releaserExecutor) tagAndPushImage(image string) error {
 }
 
 func (r *releaserExecutor) pushImage(imageUrl string) error {
-	pushCmd := fmt.Sprintf("docker push %s", imageUrl)
-	cmd := exec.CommandContext(r.ctx, "bash", "-xc", pushCmd)
-	cmd.Stdout = r.Logger
-	cmd.Stderr = r.Logger
-	if err := cmd.Run(); err != nil {
+	if err := runCommand(r.ctx, r.Logger, r.dockerEnv, "docker", "push", imageUrl); err != nil {
 		r.Log("docker push %s：%v", imageUrl, err)
 		klog.Errorf("push image error: %v", err)
 		return fmt.Errorf("推送镜像%s错误：%v", imageUrl, err)
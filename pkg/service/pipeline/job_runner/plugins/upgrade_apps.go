@@ -4,10 +4,10 @@ import (
 	"fmt"
 	"github.com/kubespace/kubespace/pkg/core/code"
 	"github.com/kubespace/kubespace/pkg/core/errors"
-	kubetypes "github.com/kubespace/kubespace/pkg/kubernetes/types"
+	"github.com/kubespace/kubespace/pkg/kubernetes/resource"
 	"github.com/kubespace/kubespace/pkg/model"
 	"github.com/kubespace/kubespace/pkg/model/types"
-	"github.com/kubespace/kubespace/pkg/service/cluster"
+	"github.com/kubespace/kubespace/pkg/service/project"
 	"github.com/kubespace/kubespace/pkg/utils"
 	"k8s.io/klog/v2"
 	"sigs.k8s.io/yaml"
@@ -16,11 +16,11 @@ import (
 
 type UpgradeAppPlugin struct {
 	*model.Models
-	KubeClient *cluster.KubeClient
+	AppService *project.AppService
 }
 
 func (p UpgradeAppPlugin) Executor(params *ExecutorParams) (Executor, error) {
-	return newUpgradeApp(params, p.Models, p.KubeClient)
+	return newUpgradeApp(params, p.Models, p.AppService)
 }
 
 type upgradeAppParams struct {
@@ -28,6 +28,12 @@ type upgradeAppParams struct {
 	Apps        []uint `json:"apps"`
 	WithInstall bool   `json:"with_install"`
 	Images      string `json:"images"`
+	// ShowDiff 安装/升级前输出与当前release的资源差异
+	ShowDiff bool `json:"show_diff"`
+	// HelmOptions helm安装/升级选项，默认等待资源就绪并在升级失败时自动回滚
+	HelmOptions resource.HelmInstallOptions `json:"helm_options"`
+	// Overlays 安装/升级时按顺序合并到values的覆盖配置名称，与页面安装一致
+	Overlays []string `json:"overlays"`
 }
 
 type upgradeAppResultImage struct {
@@ -50,14 +56,14 @@ type upgradeAppResult struct {
 type upgradeApp struct {
 	Logger
 	models     *model.Models
-	kubeClient *cluster.KubeClient
+	appService *project.AppService
 	params     *upgradeAppParams
 	images     map[string]string
 	result     *upgradeAppResult
 	project    *types.Project
 }
 
-func newUpgradeApp(params *ExecutorParams, models *model.Models, kubeClient *cluster.KubeClient) (*upgradeApp, error) {
+func newUpgradeApp(params *ExecutorParams, models *model.Models, appService *project.AppService) (*upgradeApp, error) {
 	var upgradeParams upgradeAppParams
 	if err := utils.ConvertTypeByJson(params.Params, &upgradeParams); err != nil {
 		params.Logger.Log("插件参数：%v", params.Params)
@@ -65,7 +71,7 @@ func newUpgradeApp(params *ExecutorParams, models *model.Models, kubeClient *clu
 	}
 	return &upgradeApp{
 		models:     models,
-		kubeClient: kubeClient,
+		appService: appService,
 		params:     &upgradeParams,
 		result:     &upgradeAppResult{},
 		Logger:     params.Logger,
@@ -138,31 +144,35 @@ func (u *upgradeApp) upgrade(appId uint) error {
 	u.Log("更新应用「%s」values成功:", app.Name)
 	u.Log(upgradeValues)
 	if u.params.WithInstall {
-		appChart, err := u.models.AppVersionManager.GetChart(app.AppVersion.ChartPath)
-		if err != nil {
-			u.Log("not found chart path=%s", app.AppVersion.ChartPath)
-			return err
+		// 与页面安装使用相同的安装参数，合并values覆盖配置并解析密钥引用
+		installForm := &project.InstallAppForm{
+			AppId:        app.ID,
+			Values:       upgradeValues,
+			AppVersionId: app.AppVersionId,
+			Upgrade:      app.Status != types.AppStatusUninstall,
+			Overlays:     u.params.Overlays,
+			Options:      u.params.HelmOptions,
+			User:         app.UpdateUser,
+		}
+		if len(u.params.Overlays) > 0 {
+			u.Log("合并values覆盖配置：%s", strings.Join(u.params.Overlays, ", "))
+		}
+		if u.params.ShowDiff {
+			u.logDiff(app, installForm)
 		}
-		installParams := map[string]interface{}{
-			"name":        app.Name,
-			"namespace":   u.project.Namespace,
-			"chart_bytes": appChart.Content,
-			"values":      upgradeValues,
+		if u.params.HelmOptions.Wait || u.params.HelmOptions.Atomic {
+			u.Log("安装/升级后等待应用资源就绪，失败时自动回滚：%v", u.params.HelmOptions.Atomic)
 		}
-		var resp *utils.Response
-		if app.Status != types.AppStatusUninstall {
+		if installForm.Upgrade {
 			u.Log("开始对应用进行升级")
-			resp = u.kubeClient.Update(u.project.ClusterId, kubetypes.HelmType, installParams)
 		} else {
 			u.Log("开始对应用进行安装")
-			resp = u.kubeClient.Create(u.project.ClusterId, kubetypes.HelmType, installParams)
 		}
-		if resp.IsSuccess() {
-			u.Log("安装/升级成功")
-		} else {
-			u.Log("安装/升级失败：%v", resp)
-			return fmt.Errorf("升级应用失败：%s", resp.Msg)
+		if _, _, err = u.appService.InstallApp(installForm); err != nil {
+			u.Log("安装/升级失败：%s", err.Error())
+			return fmt.Errorf("升级应用失败：%s", err.Error())
 		}
+		u.Log("安装/升级成功")
 	}
 	u.result.Apps = append(u.result.Apps, &upgradeAppResultApps{
 		Id:            appId,
@@ -173,6 +183,21 @@ func (u *upgradeApp) upgrade(appId uint) error {
 	return nil
 }
 
+// logDiff 通过dry-run获取应用安装/升级的资源差异并输出到日志，获取失败不影响后续升级
+func (u *upgradeApp) logDiff(app *types.App, installForm *project.InstallAppForm) {
+	preview, err := u.appService.PreviewInstallApp(installForm)
+	if err != nil {
+		u.Log("获取应用「%s」资源差异失败：%s", app.Name, err.Error())
+		return
+	}
+	diff := resource.FormatHelmPreviewDiff(preview)
+	if diff == "" {
+		u.Log("应用「%s」资源无变化", app.Name)
+		return
+	}
+	u.Log("应用「%s」资源差异：\n%s", app.Name, diff)
+}
+
 func (u *upgradeApp) upgradeAppValues(values string) (upgradeValues string, upgradeImages []*upgradeAppResultImage, err error) {
 	var valuesDict = map[string]interface{}{}
 	if err = yaml.Unmarshal([]byte(values), &valuesDict); err != nil {
@@ -0,0 +1,149 @@
+package project
+
+import (
+	"fmt"
+	"github.com/kubespace/kubespace/pkg/core/code"
+	"github.com/kubespace/kubespace/pkg/core/errors"
+	"github.com/kubespace/kubespace/pkg/kubernetes/resource"
+	kubetypes "github.com/kubespace/kubespace/pkg/kubernetes/types"
+	"github.com/kubespace/kubespace/pkg/model/types"
+	"github.com/kubespace/kubespace/pkg/utils"
+	"k8s.io/klog/v2"
+	"strings"
+	"time"
+)
+
+// appClusterNamespace 获取应用安装的集群以及命名空间，工作空间应用为工作空间绑定的集群以及命名空间
+func (a *AppService) appClusterNamespace(app *types.App) (string, string, error) {
+	if app.Scope == types.ScopeProject {
+		projectObj, err := a.models.ProjectManager.Get(app.ScopeId)
+		if err != nil {
+			return "", "", errors.New(code.DataNotExists, "get project error: "+err.Error())
+		}
+		return projectObj.ClusterId, projectObj.Namespace, nil
+	}
+	return fmt.Sprintf("%d", app.ScopeId), app.Namespace, nil
+}
+
+// DetectAppDrift 比较应用release清单与集群中的实际资源，并保存漂移检测结果
+func (a *AppService) DetectAppDrift(appId uint) (*types.App, *resource.HelmDrift, error) {
+	app, err := a.models.AppManager.GetById(appId)
+	if err != nil {
+		return nil, nil, errors.New(code.DataNotExists, err)
+	}
+	if app.Status == types.AppStatusUninstall {
+		return app, nil, errors.New(code.StatusError, "应用未安装")
+	}
+	clusterId, namespace, err := a.appClusterNamespace(app)
+	if err != nil {
+		return app, nil, err
+	}
+	resp := a.kubeClient.Request(clusterId, kubetypes.HelmType, kubetypes.DriftAction, &resource.HelmDriftParams{
+		Name:      app.Name,
+		Namespace: namespace,
+	})
+	now := time.Now()
+	if resource.IsActionNotFound(resp, kubetypes.DriftAction) {
+		// 旧版本agent不支持漂移检测，同步状态记为未知，不作为漂移或者检测失败
+		app.SyncStatus = types.AppSyncStatusUnknown
+		app.SyncMessage = "集群agent版本过旧，不支持漂移检测，请重新导入升级agent"
+		app.SyncCheckTime = &now
+		if err = a.models.AppManager.UpdateApp(app, "sync_status", "sync_message", "sync_check_time"); err != nil {
+			return app, nil, errors.New(code.DBError, err)
+		}
+		return app, nil, nil
+	}
+	if !resp.IsSuccess() {
+		return app, nil, errors.New(resp.Code, resp.Msg)
+	}
+	drift := &resource.HelmDrift{}
+	if err = utils.ConvertTypeByJson(resp.Data, drift); err != nil {
+		return app, nil, errors.New(code.MarshalError, err)
+	}
+	app.SyncStatus = types.AppSyncStatusSynced
+	app.SyncMessage = ""
+	app.SyncCheckTime = &now
+	if drift.Drifted {
+		app.SyncStatus = types.AppSyncStatusOutOfSync
+		app.SyncMessage = driftMessage(drift)
+	}
+	if err = a.models.AppManager.UpdateApp(app, "sync_status", "sync_message", "sync_check_time"); err != nil {
+		return app, drift, errors.New(code.DBError, err)
+	}
+	return app, drift, nil
+}
+
+// CheckRuntimeStatus 获取应用当前运行状态并保存，返回保存之前的运行状态，用于判断运行状态是否发生变化
+func (a *AppService) CheckRuntimeStatus(app *types.App) (string, error) {
+	clusterId, namespace, err := a.appClusterNamespace(app)
+	if err != nil {
+		return "", err
+	}
+	resp := a.kubeClient.List(clusterId, kubetypes.HelmType, map[string]interface{}{
+		"namespace":   namespace,
+		"names":       []string{app.Name},
+		"with_status": true,
+	})
+	if !resp.IsSuccess() {
+		return "", errors.New(resp.Code, resp.Msg)
+	}
+	var statuses []*AppRuntimeStatus
+	if err = utils.ConvertTypeByJson(resp.Data, &statuses); err != nil {
+		return "", errors.New(code.MarshalError, err)
+	}
+	previous := app.RuntimeStatus
+	app.RuntimeStatus = types.AppStatusUninstall
+	for _, status := range statuses {
+		if status.Name == app.Name {
+			app.RuntimeStatus = status.RuntimeStatus
+		}
+	}
+	if previous == app.RuntimeStatus {
+		return previous, nil
+	}
+	if err = a.models.AppManager.UpdateApp(app, "runtime_status"); err != nil {
+		return previous, errors.New(code.DBError, err)
+	}
+	return previous, nil
+}
+
+// driftMessage 漂移的资源以及字段，每个资源一行
+func driftMessage(drift *resource.HelmDrift) string {
+	var lines []string
+	for _, r := range drift.Resources {
+		if r.Missing {
+			lines = append(lines, fmt.Sprintf("%s: 已被删除", r.Key()))
+		} else {
+			lines = append(lines, fmt.Sprintf("%s: %s", r.Key(), strings.Join(r.Fields, ", ")))
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+type ResyncAppForm struct {
+	AppId uint   `json:"app_id" form:"app_id"`
+	User  string `json:"user" form:"user"`
+}
+
+// ResyncApp 使用应用最近一次的安装记录重新部署，恢复集群中被修改或者删除的资源
+func (a *AppService) ResyncApp(form *ResyncAppForm) (*types.App, *types.AppRevision, error) {
+	revisions, err := a.models.AppManager.ListRevisions(form.AppId)
+	if err != nil {
+		return nil, nil, errors.New(code.DBError, err)
+	}
+	if len(revisions) == 0 {
+		return nil, nil, errors.New(code.DataNotExists, "应用没有安装记录，无法重新同步")
+	}
+	app, revision, err := a.RollbackApp(&RollbackAppForm{
+		AppId:      form.AppId,
+		RevisionId: revisions[0].ID,
+		User:       form.User,
+	})
+	if err != nil {
+		return app, revision, err
+	}
+	if _, _, err = a.DetectAppDrift(form.AppId); err != nil {
+		klog.Warningf("detect app id=%d drift after resync error: %s", form.AppId, err.Error())
+	}
+	return app, revision, nil
+}
@@ -0,0 +1,484 @@
+package project
+
+import (
+	"context"
+	"fmt"
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/object"
+	"github.com/kubespace/kubespace/pkg/core/code"
+	"github.com/kubespace/kubespace/pkg/core/errors"
+	"github.com/kubespace/kubespace/pkg/model/types"
+	utilgit "github.com/kubespace/kubespace/pkg/third/git"
+	"github.com/kubespace/kubespace/pkg/third/helm"
+	"github.com/kubespace/kubespace/pkg/utils"
+	"helm.sh/helm/v3/pkg/chart"
+	"k8s.io/klog/v2"
+	"os"
+	"path/filepath"
+	"sigs.k8s.io/yaml"
+	"strings"
+	"time"
+)
+
+// GetGitSource 获取应用的git来源，没有配置时返回nil
+func (a *AppService) GetGitSource(appId uint) (*types.AppGitSource, error) {
+	source, err := a.models.AppGitSourceManager.GetByAppId(appId)
+	if err != nil {
+		return nil, errors.New(code.DBError, err)
+	}
+	return source, nil
+}
+
+type SaveGitSourceForm struct {
+	AppId        uint     `json:"app_id" form:"app_id"`
+	CodeType     string   `json:"code_type" form:"code_type"`
+	ApiUrl       string   `json:"api_url" form:"api_url"`
+	CloneUrl     string   `json:"clone_url" form:"clone_url"`
+	SecretId     uint     `json:"secret_id" form:"secret_id"`
+	Branch       string   `json:"branch" form:"branch"`
+	Path         string   `json:"path" form:"path"`
+	ValuesFiles  []string `json:"values_files" form:"values_files"`
+	AutoSync     bool     `json:"auto_sync" form:"auto_sync"`
+	PollInterval int      `json:"poll_interval" form:"poll_interval"`
+	// Enabled 为空时默认启用
+	Enabled *bool  `json:"enabled" form:"enabled"`
+	User    string `json:"user" form:"user"`
+}
+
+// SaveGitSource 创建或者更新应用的git来源，保存后立即同步
+func (a *AppService) SaveGitSource(form *SaveGitSourceForm) (*types.AppGitSource, error) {
+	switch form.CodeType {
+	case types.WorkspaceCodeTypeHttps, types.WorkspaceCodeTypeGit, types.WorkspaceCodeTypeGitHub,
+		types.WorkspaceCodeTypeGitLab, types.WorkspaceCodeTypeGitee:
+	default:
+		return nil, errors.New(code.ParamsError, "代码仓库类型错误")
+	}
+	if form.CloneUrl == "" || form.Branch == "" {
+		return nil, errors.New(code.ParamsError, "代码仓库地址以及分支不能为空")
+	}
+	if form.SecretId != 0 {
+		app, err := a.models.AppManager.GetById(form.AppId)
+		if err != nil {
+			return nil, errors.New(code.DataNotExists, "获取应用失败："+err.Error())
+		}
+		secret, err := a.models.SettingsSecretManager.Get(form.SecretId)
+		if err != nil {
+			return nil, errors.New(code.DataNotExists, "获取代码密钥失败："+err.Error())
+		}
+		if !secret.AllowScope(app.Scope, app.ScopeId) {
+			return nil, errors.New(code.ParamsError, fmt.Sprintf("密钥%s不允许在当前应用所在的工作空间或集群中引用", secret.Name))
+		}
+	}
+	if form.PollInterval <= 0 {
+		form.PollInterval = types.AppGitSourceDefaultPollInterval
+	}
+	enabled := form.Enabled == nil || *form.Enabled
+	source, err := a.models.AppGitSourceManager.GetByAppId(form.AppId)
+	if err != nil {
+		return nil, errors.New(code.DBError, err)
+	}
+	if source == nil {
+		return a.createGitSource(form, enabled)
+	}
+	// 配置修改后重新获取最新提交生成应用版本
+	if err = a.models.AppGitSourceManager.Update(source.ID, map[string]interface{}{
+		"code_type":      form.CodeType,
+		"api_url":        form.ApiUrl,
+		"clone_url":      form.CloneUrl,
+		"secret_id":      form.SecretId,
+		"branch":         form.Branch,
+		"path":           repoRelativePath(form.Path),
+		"values_files":   types.AppGitSourceValuesFiles(form.ValuesFiles),
+		"auto_sync":      form.AutoSync,
+		"poll_interval":  form.PollInterval,
+		"enabled":        enabled,
+		"last_commit_id": "",
+	}); err != nil {
+		return nil, errors.New(code.DBError, err)
+	}
+	if source, err = a.models.AppGitSourceManager.Refresh(source.ID, form.User); err != nil {
+		return nil, errors.New(code.DBError, err)
+	}
+	return source, nil
+}
+
+func (a *AppService) createGitSource(form *SaveGitSourceForm, enabled bool) (*types.AppGitSource, error) {
+	source, err := a.models.AppGitSourceManager.Create(&types.AppGitSource{
+		AppId:        form.AppId,
+		CodeType:     form.CodeType,
+		ApiUrl:       form.ApiUrl,
+		CloneUrl:     form.CloneUrl,
+		SecretId:     form.SecretId,
+		Branch:       form.Branch,
+		Path:         repoRelativePath(form.Path),
+		ValuesFiles:  form.ValuesFiles,
+		AutoSync:     form.AutoSync,
+		PollInterval: form.PollInterval,
+		Enabled:      enabled,
+		WebhookToken: strings.ReplaceAll(utils.CreateUUID(), "-", ""),
+		Status:       types.AppGitSourceStatusPending,
+		CreateUser:   form.User,
+		UpdateUser:   form.User,
+	})
+	if err != nil {
+		return nil, errors.New(code.DBError, err)
+	}
+	return source, nil
+}
+
+func (a *AppService) DeleteGitSource(appId uint) error {
+	source, err := a.models.AppGitSourceManager.GetByAppId(appId)
+	if err != nil {
+		return errors.New(code.DBError, err)
+	}
+	if source == nil {
+		return nil
+	}
+	if err = a.models.AppGitSourceManager.Delete(source.ID); err != nil {
+		return errors.New(code.DBError, err)
+	}
+	return nil
+}
+
+// RefreshGitSource 立即获取git仓库最新提交
+func (a *AppService) RefreshGitSource(appId uint, user string) (*types.AppGitSource, error) {
+	source, err := a.models.AppGitSourceManager.GetByAppId(appId)
+	if err != nil {
+		return nil, errors.New(code.DBError, err)
+	}
+	if source == nil {
+		return nil, errors.New(code.DataNotExists, "应用没有配置git来源")
+	}
+	if source, err = a.models.AppGitSourceManager.Refresh(source.ID, user); err != nil {
+		return nil, errors.New(code.DBError, err)
+	}
+	return source, nil
+}
+
+// GitWebhook 代码仓库推送后通过webhook通知，推送的分支与git来源分支一致时立即同步
+// ref为webhook请求中推送的引用，如refs/heads/master，为空时不判断分支
+func (a *AppService) GitWebhook(token, ref string) (*types.AppGitSource, error) {
+	source, err := a.models.AppGitSourceManager.GetByWebhookToken(token)
+	if err != nil {
+		return nil, errors.New(code.DataNotExists, "webhook token错误")
+	}
+	if !source.Enabled {
+		return source, nil
+	}
+	if ref != "" && ref != plumbing.NewBranchReferenceName(source.Branch).String() {
+		return source, nil
+	}
+	if source, err = a.models.AppGitSourceManager.Refresh(source.ID, source.UpdateUser); err != nil {
+		return nil, errors.New(code.DBError, err)
+	}
+	return source, nil
+}
+
+// SyncGitSource 手动将应用安装或升级到git来源最新提交生成的应用版本
+func (a *AppService) SyncGitSource(appId uint, user string) (*types.App, *types.AppVersion, error) {
+	source, err := a.models.AppGitSourceManager.GetByAppId(appId)
+	if err != nil {
+		return nil, nil, errors.New(code.DBError, err)
+	}
+	if source == nil {
+		return nil, nil, errors.New(code.DataNotExists, "应用没有配置git来源")
+	}
+	if source.LastAppVersionId == 0 {
+		return nil, nil, errors.New(code.StatusError, "git来源还没有生成应用版本，请等待同步完成")
+	}
+	return a.installAppVersion(appId, source.LastAppVersionId, user)
+}
+
+// installAppVersion 使用应用版本的values安装应用，应用已安装时升级到该版本
+func (a *AppService) installAppVersion(appId, appVersionId uint, user string) (*types.App, *types.AppVersion, error) {
+	app, err := a.models.AppManager.GetById(appId)
+	if err != nil {
+		return nil, nil, errors.New(code.DataNotExists, err)
+	}
+	appVersion, err := a.models.AppVersionManager.GetById(appVersionId)
+	if err != nil {
+		return app, nil, errors.New(code.DataNotExists, err)
+	}
+	return a.InstallApp(&InstallAppForm{
+		AppId:        appId,
+		AppVersionId: appVersionId,
+		Values:       appVersion.Values,
+		Upgrade:      app.Status != types.AppStatusUninstall,
+		User:         user,
+	})
+}
+
+// ReconcileGitSource 获取git仓库分支的最新提交，有新提交时打包chart生成应用版本，开启自动同步时部署该版本
+func (a *AppService) ReconcileGitSource(sourceId uint) error {
+	source, err := a.models.AppGitSourceManager.Get(sourceId)
+	if err != nil {
+		return errors.New(code.DataNotExists, err)
+	}
+	if err = a.models.AppGitSourceManager.Update(source.ID, map[string]interface{}{
+		"status": types.AppGitSourceStatusSyncing,
+	}); err != nil {
+		return errors.New(code.DBError, err)
+	}
+	message, err := a.reconcileGitSource(source)
+	status := types.AppGitSourceStatusOK
+	if err != nil {
+		status = types.AppGitSourceStatusError
+		message = err.Error()
+	}
+	klog.Infof("reconcile app git source id=%d app id=%d status=%s: %s", source.ID, source.AppId, status, message)
+	if updateErr := a.models.AppGitSourceManager.Update(source.ID, map[string]interface{}{
+		"status":         status,
+		"message":        message,
+		"last_poll_time": time.Now(),
+	}); updateErr != nil {
+		return errors.New(code.DBError, updateErr)
+	}
+	return err
+}
+
+func (a *AppService) reconcileGitSource(source *types.AppGitSource) (string, error) {
+	app, err := a.models.AppManager.GetById(source.AppId)
+	if err != nil {
+		return "", fmt.Errorf("获取应用失败：%s", err.Error())
+	}
+	gitcli, err := a.gitSourceClient(app, source)
+	if err != nil {
+		return "", err
+	}
+	branches, err := gitcli.ListRepoBranches(context.Background(), source.CloneUrl)
+	if err != nil {
+		return "", err
+	}
+	var commitId string
+	for _, branch := range branches {
+		if branch.Name == source.Branch {
+			commitId = branch.CommitId
+		}
+	}
+	if commitId == "" {
+		return "", fmt.Errorf("代码仓库不存在分支%s", source.Branch)
+	}
+	if commitId == source.LastCommitId && source.LastAppVersionId != 0 {
+		return fmt.Sprintf("分支%s没有新的提交", source.Branch), nil
+	}
+	appVersion, commit, err := a.createGitAppVersion(gitcli, app, source)
+	if err != nil {
+		return "", err
+	}
+	if err = a.models.AppGitSourceManager.Update(source.ID, map[string]interface{}{
+		"last_commit_id":      commit.Hash.String(),
+		"last_commit_time":    commit.Committer.When,
+		"last_app_version_id": appVersion.ID,
+	}); err != nil {
+		return "", fmt.Errorf("更新git来源失败：%s", err.Error())
+	}
+	message := fmt.Sprintf("提交%s生成应用版本%s", shortCommitId(commit.Hash.String()), appVersion.PackageVersion)
+	if !source.AutoSync {
+		return message + "，等待手动同步", nil
+	}
+	if app.Status != types.AppStatusUninstall && app.AppVersionId == appVersion.ID {
+		return message + "，应用已是该版本", nil
+	}
+	if _, _, err = a.installAppVersion(app.ID, appVersion.ID, source.UpdateUser); err != nil {
+		return "", fmt.Errorf("%s，自动同步失败：%s", message, err.Error())
+	}
+	return message + "，已自动同步", nil
+}
+
+func (a *AppService) gitSourceClient(app *types.App, source *types.AppGitSource) (utilgit.Client, error) {
+	secret := &types.Secret{}
+	if source.SecretId != 0 {
+		settingsSecret, err := a.models.SettingsSecretManager.Get(source.SecretId)
+		if err != nil {
+			return nil, fmt.Errorf("获取代码密钥失败：%s", err.Error())
+		}
+		// 密钥的引用范围可能在保存git来源后被修改
+		if !settingsSecret.AllowScope(app.Scope, app.ScopeId) {
+			return nil, fmt.Errorf("密钥%s不允许在当前应用所在的工作空间或集群中引用", settingsSecret.Name)
+		}
+		secret = settingsSecret.GetSecret()
+	}
+	return utilgit.NewClient(source.CodeType, source.ApiUrl, secret)
+}
+
+// createGitAppVersion 克隆分支最新提交，将chart目录打包为应用版本，版本号为chart版本加上提交id
+// 相同提交的应用版本已存在时直接返回
+func (a *AppService) createGitAppVersion(
+	gitcli utilgit.Client,
+	app *types.App,
+	source *types.AppGitSource) (*types.AppVersion, *object.Commit, error) {
+	repoDir, err := os.MkdirTemp("/tmp", "")
+	if err != nil {
+		return nil, nil, errors.New(code.OsError, err)
+	}
+	defer os.RemoveAll(repoDir)
+	repo, err := gitcli.Clone(context.Background(), repoDir, false, &git.CloneOptions{
+		URL:           source.CloneUrl,
+		ReferenceName: plumbing.NewBranchReferenceName(source.Branch),
+		SingleBranch:  true,
+		Depth:         1,
+	})
+	if err != nil {
+		return nil, nil, fmt.Errorf("克隆代码仓库%s失败：%s", source.CloneUrl, err.Error())
+	}
+	head, err := repo.Head()
+	if err != nil {
+		return nil, nil, err
+	}
+	commit, err := repo.CommitObject(head.Hash())
+	if err != nil {
+		return nil, nil, err
+	}
+
+	chartDir, err := resolveRepoPath(repoDir, source.Path)
+	if err != nil {
+		return nil, nil, fmt.Errorf("读取chart目录%s失败：%s", source.Path, err.Error())
+	}
+	files, err := readChartDir(chartDir)
+	if err != nil {
+		return nil, nil, fmt.Errorf("读取chart目录%s失败：%s", source.Path, err.Error())
+	}
+	chartMeta := &chart.Metadata{}
+	chartYaml, _ := files["Chart.yaml"].(string)
+	if err = yaml.Unmarshal([]byte(chartYaml), chartMeta); err != nil || chartMeta.Version == "" {
+		return nil, nil, fmt.Errorf("chart目录%s中的Chart.yaml错误", source.Path)
+	}
+	packageVersion := fmt.Sprintf("%s-g%s", chartMeta.Version, shortCommitId(commit.Hash.String()))
+	appVersion, err := a.models.AppVersionManager.GetByPackageNameVersion(app.Scope, app.ID, app.Name, packageVersion)
+	if err != nil {
+		return nil, nil, err
+	}
+	if appVersion != nil {
+		return appVersion, commit, nil
+	}
+	values, _ := files["values.yaml"].(string)
+	if len(source.ValuesFiles) > 0 {
+		if values, err = mergeGitValuesFiles(repoDir, source.ValuesFiles); err != nil {
+			return nil, nil, err
+		}
+	}
+	appVer := chartMeta.AppVersion
+	if appVer == "" {
+		appVer = chartMeta.Version
+	}
+	chartGen := &helm.ChartGeneration{
+		NeedModifyVersion: true,
+		PackageVersion:    packageVersion,
+		AppVersion:        appVer,
+		Files:             files,
+	}
+	chartDir, chartPath, err := chartGen.GenerateChart()
+	if chartDir != "" {
+		defer os.RemoveAll(chartDir)
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+	app.UpdateUser = source.UpdateUser
+	app.UpdateTime = time.Now()
+	if _, err = a.models.AppManager.CreateApp(chartPath, app, &types.AppVersion{
+		PackageName:    app.Name,
+		PackageVersion: packageVersion,
+		AppVersion:     appVer,
+		Values:         values,
+		Description:    fmt.Sprintf("%s@%s: %s", source.Branch, shortCommitId(commit.Hash.String()), strings.SplitN(commit.Message, "\n", 2)[0]),
+		From:           types.AppVersionFromGit,
+		CreateUser:     source.UpdateUser,
+		CreateTime:     time.Now(),
+		UpdateTime:     time.Now(),
+	}); err != nil {
+		return nil, nil, errors.New(code.DBError, err)
+	}
+	appVersion, err = a.models.AppVersionManager.GetByPackageNameVersion(app.Scope, app.ID, app.Name, packageVersion)
+	if err != nil {
+		return nil, nil, err
+	}
+	if appVersion == nil {
+		return nil, nil, fmt.Errorf("应用版本%s创建失败", packageVersion)
+	}
+	return appVersion, commit, nil
+}
+
+// readChartDir 读取chart目录中的文件，子目录为嵌套的map，与ChartGeneration的文件格式一致
+// 仓库中的符号链接可能指向仓库以外的文件，chart目录中不允许包含符号链接
+func readChartDir(dir string) (map[string]interface{}, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	files := make(map[string]interface{})
+	for _, entry := range entries {
+		if entry.Name() == ".git" {
+			continue
+		}
+		if entry.Type()&os.ModeSymlink != 0 {
+			return nil, fmt.Errorf("chart目录中不允许包含符号链接：%s", entry.Name())
+		}
+		if entry.IsDir() {
+			if files[entry.Name()], err = readChartDir(filepath.Join(dir, entry.Name())); err != nil {
+				return nil, err
+			}
+			continue
+		}
+		content, err := os.ReadFile(filepath.Join(dir, entry.Name()))
+		if err != nil {
+			return nil, err
+		}
+		files[entry.Name()] = string(content)
+	}
+	return files, nil
+}
+
+// mergeGitValuesFiles 按顺序合并仓库中的values文件
+func mergeGitValuesFiles(repoDir string, valuesFiles []string) (string, error) {
+	merged := map[string]interface{}{}
+	for _, file := range valuesFiles {
+		valuesPath, err := resolveRepoPath(repoDir, file)
+		if err != nil {
+			return "", fmt.Errorf("读取values文件%s失败：%s", file, err.Error())
+		}
+		content, err := os.ReadFile(valuesPath)
+		if err != nil {
+			return "", fmt.Errorf("读取values文件%s失败：%s", file, err.Error())
+		}
+		values := map[string]interface{}{}
+		if err = yaml.Unmarshal(content, &values); err != nil {
+			return "", fmt.Errorf("values文件%s解析错误：%s", file, err.Error())
+		}
+		merged = mergeValues(merged, values)
+	}
+	mergedBytes, err := yaml.Marshal(merged)
+	if err != nil {
+		return "", err
+	}
+	return string(mergedBytes), nil
+}
+
+// repoRelativePath 将路径规范为仓库内的相对路径，不能访问仓库目录以外的文件
+func repoRelativePath(p string) string {
+	return strings.TrimPrefix(filepath.Clean("/"+p), "/")
+}
+
+// resolveRepoPath 获取仓库中文件解析符号链接后的真实路径，真实路径不在仓库目录中时返回错误
+func resolveRepoPath(repoDir, p string) (string, error) {
+	realRepoDir, err := filepath.EvalSymlinks(repoDir)
+	if err != nil {
+		return "", err
+	}
+	realPath, err := filepath.EvalSymlinks(filepath.Join(repoDir, repoRelativePath(p)))
+	if err != nil {
+		return "", err
+	}
+	if realPath != realRepoDir && !strings.HasPrefix(realPath, realRepoDir+string(filepath.Separator)) {
+		return "", fmt.Errorf("路径%s指向代码仓库以外的文件", p)
+	}
+	return realPath, nil
+}
+
+func shortCommitId(commitId string) string {
+	if len(commitId) > 7 {
+		return commitId[:7]
+	}
+	return commitId
+}
@@ -0,0 +1,261 @@
+package project
+
+import (
+	"fmt"
+	"github.com/kubespace/kubespace/pkg/core/code"
+	"github.com/kubespace/kubespace/pkg/core/errors"
+	"github.com/kubespace/kubespace/pkg/model/manager/project"
+	"github.com/kubespace/kubespace/pkg/model/types"
+	"k8s.io/klog/v2"
+	"time"
+)
+
+type CreatePromotionForm struct {
+	AppId           uint `json:"app_id" form:"app_id"`
+	AppVersionId    uint `json:"app_version_id" form:"app_version_id"`
+	TargetProjectId uint `json:"target_project_id" form:"target_project_id"`
+	// Values 为空时使用应用版本的values
+	Values string `json:"values" form:"values"`
+	// Overlays 安装时按顺序合并的目标应用values覆盖配置
+	Overlays []string `json:"overlays" form:"overlays"`
+	// Install 晋级后是否在目标工作空间安装或升级应用，否则只导入应用版本
+	Install bool `json:"install" form:"install"`
+}
+
+// CreatePromotion 将工作空间应用的版本晋级到目标工作空间
+// 用户不是目标工作空间管理员时，晋级记录为待审批状态，由目标工作空间管理员审批后执行
+func (a *AppService) CreatePromotion(form *CreatePromotionForm, user *types.User) (*types.AppPromotion, error) {
+	app, err := a.models.AppManager.GetById(form.AppId)
+	if err != nil {
+		return nil, errors.New(code.DataNotExists, err)
+	}
+	if app.Scope != types.ScopeProject {
+		return nil, errors.New(code.ParamsError, "只有工作空间应用可以晋级")
+	}
+	appVersion, err := a.models.AppVersionManager.GetById(form.AppVersionId)
+	if err != nil {
+		return nil, errors.New(code.DataNotExists, err)
+	}
+	if appVersion.Scope != types.ScopeProject || appVersion.ScopeId != app.ID {
+		return nil, errors.New(code.ParamsError, "应用版本不属于该应用")
+	}
+	if form.TargetProjectId == app.ScopeId {
+		return nil, errors.New(code.ParamsError, "目标工作空间不能与应用所在工作空间相同")
+	}
+	if _, err = a.models.ProjectManager.Get(form.TargetProjectId); err != nil {
+		return nil, errors.New(code.DataNotExists, "获取目标工作空间失败："+err.Error())
+	}
+	values := form.Values
+	if values == "" {
+		values = appVersion.Values
+	}
+	promotion := &types.AppPromotion{
+		SourceProjectId:    app.ScopeId,
+		SourceAppId:        app.ID,
+		SourceAppVersionId: appVersion.ID,
+		TargetProjectId:    form.TargetProjectId,
+		AppName:            app.Name,
+		PackageName:        appVersion.PackageName,
+		PackageVersion:     appVersion.PackageVersion,
+		Values:             values,
+		Overlays:           form.Overlays,
+		Install:            form.Install,
+		Status:             types.AppPromotionStatusPending,
+		CreateUser:         user.Name,
+	}
+	needApproval := !a.models.UserRoleManager.AuthRole(user, types.ScopeProject, form.TargetProjectId, types.RoleAdmin)
+	if !needApproval {
+		now := time.Now()
+		promotion.Status = types.AppPromotionStatusPromoting
+		promotion.ApproveUser = user.Name
+		promotion.ApproveTime = &now
+	}
+	if promotion, err = a.models.AppPromotionManager.Create(promotion); err != nil {
+		return nil, errors.New(code.DBError, err)
+	}
+	if needApproval {
+		return promotion, nil
+	}
+	return promotion, a.executePromotion(promotion, user.Name)
+}
+
+// ApprovePromotion 审批通过待审批的晋级，并执行晋级
+func (a *AppService) ApprovePromotion(promotionId uint, user string) (*types.AppPromotion, error) {
+	now := time.Now()
+	ok, err := a.models.AppPromotionManager.UpdatePending(promotionId, map[string]interface{}{
+		"status":       types.AppPromotionStatusPromoting,
+		"approve_user": user,
+		"approve_time": &now,
+	})
+	if err != nil {
+		return nil, errors.New(code.DBError, err)
+	}
+	if !ok {
+		return nil, errors.New(code.StatusError, "该晋级不是待审批状态")
+	}
+	promotion, err := a.models.AppPromotionManager.Get(promotionId)
+	if err != nil {
+		return nil, errors.New(code.DataNotExists, err)
+	}
+	return promotion, a.executePromotion(promotion, user)
+}
+
+// RejectPromotion 审批拒绝待审批的晋级
+func (a *AppService) RejectPromotion(promotionId uint, user, message string) (*types.AppPromotion, error) {
+	now := time.Now()
+	ok, err := a.models.AppPromotionManager.UpdatePending(promotionId, map[string]interface{}{
+		"status":       types.AppPromotionStatusRejected,
+		"message":      message,
+		"approve_user": user,
+		"approve_time": &now,
+	})
+	if err != nil {
+		return nil, errors.New(code.DBError, err)
+	}
+	if !ok {
+		return nil, errors.New(code.StatusError, "该晋级不是待审批状态")
+	}
+	promotion, err := a.models.AppPromotionManager.Get(promotionId)
+	if err != nil {
+		return nil, errors.New(code.DataNotExists, err)
+	}
+	return promotion, nil
+}
+
+// executePromotion 将应用版本导入到目标工作空间的同名应用，目标工作空间已存在相同版本时直接使用
+// 需要安装时，目标应用未安装则安装，否则升级到晋级的版本
+func (a *AppService) executePromotion(promotion *types.AppPromotion, user string) error {
+	err := a.promote(promotion, user)
+	promotion.Status = types.AppPromotionStatusSucceeded
+	promotion.Message = ""
+	if err != nil {
+		promotion.Status = types.AppPromotionStatusFailed
+		promotion.Message = err.Error()
+	}
+	if updateErr := a.models.AppPromotionManager.Update(promotion.ID, map[string]interface{}{
+		"status":                promotion.Status,
+		"message":               promotion.Message,
+		"target_app_id":         promotion.TargetAppId,
+		"target_app_version_id": promotion.TargetAppVersionId,
+	}); updateErr != nil {
+		klog.Errorf("update app promotion id=%d status error: %s", promotion.ID, updateErr.Error())
+	}
+	return err
+}
+
+func (a *AppService) promote(promotion *types.AppPromotion, user string) error {
+	sourceApp, err := a.models.AppManager.GetById(promotion.SourceAppId)
+	if err != nil {
+		return errors.New(code.DataNotExists, "获取晋级应用失败："+err.Error())
+	}
+	sourceVersion, err := a.models.AppVersionManager.GetById(promotion.SourceAppVersionId)
+	if err != nil {
+		return errors.New(code.DataNotExists, "获取晋级应用版本失败："+err.Error())
+	}
+	targetApp, err := a.models.AppManager.GetByName(types.ScopeProject, promotion.TargetProjectId, promotion.AppName)
+	if err != nil {
+		return errors.New(code.DBError, err)
+	}
+	var targetVersion *types.AppVersion
+	if targetApp != nil {
+		targetVersion, err = a.models.AppVersionManager.GetByPackageNameVersion(
+			types.ScopeProject, targetApp.ID, promotion.PackageName, promotion.PackageVersion)
+		if err != nil {
+			return errors.New(code.DBError, err)
+		}
+	}
+	if targetVersion == nil {
+		if err = a.ImportProjectApp(sourceApp, sourceVersion, promotion.TargetProjectId, promotion.AppName, user); err != nil {
+			return err
+		}
+		if targetApp, err = a.models.AppManager.GetByName(types.ScopeProject, promotion.TargetProjectId, promotion.AppName); err != nil {
+			return errors.New(code.DBError, err)
+		}
+		if targetVersion, err = a.models.AppVersionManager.GetByPackageNameVersion(
+			types.ScopeProject, targetApp.ID, promotion.PackageName, promotion.PackageVersion); err != nil {
+			return errors.New(code.DBError, err)
+		}
+	}
+	promotion.TargetAppId = targetApp.ID
+	promotion.TargetAppVersionId = targetVersion.ID
+	if !promotion.Install {
+		return nil
+	}
+	_, _, err = a.InstallApp(&InstallAppForm{
+		AppId:        targetApp.ID,
+		AppVersionId: targetVersion.ID,
+		Values:       promotion.Values,
+		Overlays:     promotion.Overlays,
+		Upgrade:      targetApp.Status != types.AppStatusUninstall,
+		User:         user,
+	})
+	return err
+}
+
+// ListPromotions 获取应用作为晋级来源或者目标的晋级记录
+func (a *AppService) ListPromotions(appId uint) ([]*types.AppPromotion, error) {
+	promotions, err := a.models.AppPromotionManager.List(&project.ListAppPromotionCondition{AppId: appId})
+	if err != nil {
+		return nil, errors.New(code.DBError, err)
+	}
+	return promotions, nil
+}
+
+// AppEnvironment 应用在某个工作空间中的运行情况
+type AppEnvironment struct {
+	ProjectId      uint      `json:"project_id"`
+	ProjectName    string    `json:"project_name"`
+	ClusterId      string    `json:"cluster_id"`
+	Namespace      string    `json:"namespace"`
+	AppId          uint      `json:"app_id"`
+	Status         string    `json:"status"`
+	AppVersionId   uint      `json:"app_version_id"`
+	PackageName    string    `json:"package_name"`
+	PackageVersion string    `json:"package_version"`
+	UpdateUser     string    `json:"update_user"`
+	UpdateTime     time.Time `json:"update_time"`
+}
+
+// AppEnvironments 获取所有工作空间中同名应用的运行版本，只返回用户有查看权限的工作空间
+func (a *AppService) AppEnvironments(appId uint, user *types.User) ([]*AppEnvironment, error) {
+	app, err := a.models.AppManager.GetById(appId)
+	if err != nil {
+		return nil, errors.New(code.DataNotExists, err)
+	}
+	apps, err := a.models.AppManager.ListByName(types.ScopeProject, app.Name)
+	if err != nil {
+		return nil, errors.New(code.DBError, err)
+	}
+	var envs []*AppEnvironment
+	for _, envApp := range apps {
+		if !a.models.UserRoleManager.AuthRole(user, types.ScopeProject, envApp.ScopeId, types.RoleViewer) {
+			continue
+		}
+		projectObj, err := a.models.ProjectManager.Get(envApp.ScopeId)
+		if err != nil {
+			klog.Warningf("get project id=%d of app id=%d error: %s", envApp.ScopeId, envApp.ID, err.Error())
+			continue
+		}
+		env := &AppEnvironment{
+			ProjectId:    projectObj.ID,
+			ProjectName:  projectObj.Name,
+			ClusterId:    projectObj.ClusterId,
+			Namespace:    projectObj.Namespace,
+			AppId:        envApp.ID,
+			Status:       envApp.Status,
+			AppVersionId: envApp.AppVersionId,
+			UpdateUser:   envApp.UpdateUser,
+			UpdateTime:   envApp.UpdateTime,
+		}
+		if envApp.AppVersionId != 0 {
+			appVersion, err := a.models.AppVersionManager.GetById(envApp.AppVersionId)
+			if err != nil {
+				return nil, errors.New(code.DBError, fmt.Sprintf("获取应用%s版本失败：%s", envApp.Name, err.Error()))
+			}
+			env.PackageName = appVersion.PackageName
+			env.PackageVersion = appVersion.PackageVersion
+		}
+		envs = append(envs, env)
+	}
+	return envs, nil
+}
@@ -0,0 +1,112 @@
+package project
+
+import (
+	"fmt"
+	"github.com/kubespace/kubespace/pkg/core/code"
+	"github.com/kubespace/kubespace/pkg/core/errors"
+	"github.com/kubespace/kubespace/pkg/model/types"
+	"github.com/kubespace/kubespace/pkg/utils"
+)
+
+// ListAppRevisions 获取应用安装升级历史记录，并填充每条记录对应的应用版本包信息
+func (a *AppService) ListAppRevisions(appId uint) ([]*types.AppRevision, error) {
+	app, err := a.models.AppManager.GetById(appId)
+	if err != nil {
+		return nil, errors.New(code.DataNotExists, err)
+	}
+	revisions, err := a.models.AppManager.ListRevisions(appId)
+	if err != nil {
+		return nil, errors.New(code.DBError, err)
+	}
+	appVersions, err := a.models.AppVersionManager.List(app.Scope, app.ID)
+	if err != nil {
+		return nil, errors.New(code.DBError, err)
+	}
+	versionMap := make(map[uint]*types.AppVersion)
+	for _, v := range appVersions {
+		versionMap[v.ID] = v
+	}
+	for _, revision := range revisions {
+		if v, ok := versionMap[revision.AppVersionId]; ok {
+			revision.PackageName = v.PackageName
+			revision.PackageVersion = v.PackageVersion
+		}
+	}
+	return revisions, nil
+}
+
+// GetAppRevision 获取应用历史记录，历史记录必须属于该应用
+func (a *AppService) GetAppRevision(appId, revisionId uint) (*types.AppRevision, error) {
+	revision, err := a.models.AppManager.GetRevision(revisionId)
+	if err != nil {
+		return nil, errors.New(code.DataNotExists, fmt.Sprintf("获取应用历史记录失败：%s", err.Error()))
+	}
+	if revision.AppId != appId {
+		return nil, errors.New(code.ParamsError, "当前应用不存在该历史记录")
+	}
+	if appVersion, err := a.models.AppVersionManager.GetById(revision.AppVersionId); err == nil {
+		revision.PackageName = appVersion.PackageName
+		revision.PackageVersion = appVersion.PackageVersion
+	}
+	return revision, nil
+}
+
+// AppRevisionDiff 应用两个历史记录之间的差异
+type AppRevisionDiff struct {
+	From *types.AppRevision `json:"from"`
+	To   *types.AppRevision `json:"to"`
+	// VersionChanged 两次记录的应用版本是否不同
+	VersionChanged bool `json:"version_changed"`
+	// ValuesDiff values的unified diff内容，相同时为空
+	ValuesDiff string `json:"values_diff"`
+}
+
+// DiffAppRevisions 比较应用两个历史记录的应用版本以及values差异
+func (a *AppService) DiffAppRevisions(appId, fromRevisionId, toRevisionId uint) (*AppRevisionDiff, error) {
+	from, err := a.GetAppRevision(appId, fromRevisionId)
+	if err != nil {
+		return nil, err
+	}
+	to, err := a.GetAppRevision(appId, toRevisionId)
+	if err != nil {
+		return nil, err
+	}
+	return &AppRevisionDiff{
+		From:           from,
+		To:             to,
+		VersionChanged: from.AppVersionId != to.AppVersionId,
+		ValuesDiff: utils.UnifiedDiff(
+			fmt.Sprintf("revision-%d", from.BuildRevision),
+			fmt.Sprintf("revision-%d", to.BuildRevision),
+			from.Values, to.Values),
+	}, nil
+}
+
+type RollbackAppForm struct {
+	AppId      uint   `json:"app_id" form:"app_id"`
+	RevisionId uint   `json:"revision_id" form:"revision_id"`
+	User       string `json:"user" form:"user"`
+}
+
+// RollbackApp 将应用回滚到指定的历史记录，使用历史记录对应的应用版本chart以及values重新安装，并生成新的历史记录
+func (a *AppService) RollbackApp(form *RollbackAppForm) (*types.App, *types.AppRevision, error) {
+	app, err := a.models.AppManager.GetById(form.AppId)
+	if err != nil {
+		return nil, nil, errors.New(code.DataNotExists, "get app error: "+err.Error())
+	}
+	revision, err := a.GetAppRevision(form.AppId, form.RevisionId)
+	if err != nil {
+		return app, nil, err
+	}
+	if revision.PackageName == "" {
+		return app, revision, errors.New(code.DataNotExists, "该历史记录对应的应用版本已被删除，无法回滚")
+	}
+	app, _, err = a.InstallApp(&InstallAppForm{
+		AppId:        form.AppId,
+		Values:       revision.Values,
+		AppVersionId: revision.AppVersionId,
+		Upgrade:      app.Status != types.AppStatusUninstall,
+		User:         form.User,
+	})
+	return app, revision, err
+}
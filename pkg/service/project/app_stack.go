@@ -0,0 +1,368 @@
+package project
+
+import (
+	"fmt"
+	"github.com/kubespace/kubespace/pkg/core/code"
+	"github.com/kubespace/kubespace/pkg/core/errors"
+	"github.com/kubespace/kubespace/pkg/model/types"
+	"k8s.io/klog/v2"
+	"strings"
+	"time"
+)
+
+// appStackReadyCheckInterval 等待应用运行正常时查询应用状态的间隔
+var appStackReadyCheckInterval = 5 * time.Second
+
+func (a *AppService) ListStacks(projectId uint) ([]*types.AppStack, error) {
+	stacks, err := a.models.AppStackManager.List(projectId)
+	if err != nil {
+		return nil, errors.New(code.DBError, err)
+	}
+	return stacks, nil
+}
+
+// AppStackAppInfo 应用栈中应用的当前状态
+type AppStackAppInfo struct {
+	*types.AppStackApp
+	Name           string `json:"name"`
+	Status         string `json:"status"`
+	PackageVersion string `json:"package_version"`
+	// Order 应用在应用栈中的部署顺序，从1开始
+	Order int `json:"order"`
+}
+
+type AppStackDetail struct {
+	*types.AppStack
+	AppInfos []*AppStackAppInfo `json:"app_infos"`
+}
+
+// GetStack 获取应用栈以及按部署顺序排列的应用状态
+func (a *AppService) GetStack(stackId uint) (*AppStackDetail, error) {
+	stack, err := a.models.AppStackManager.Get(stackId)
+	if err != nil {
+		return nil, errors.New(code.DataNotExists, err)
+	}
+	ordered, err := appStackOrder(stack.Apps)
+	if err != nil {
+		return nil, errors.New(code.ParamsError, err)
+	}
+	detail := &AppStackDetail{AppStack: stack}
+	for i, stackApp := range ordered {
+		info := &AppStackAppInfo{AppStackApp: stackApp, Order: i + 1}
+		app, err := a.models.AppManager.GetById(stackApp.AppId)
+		if err != nil {
+			return nil, errors.New(code.DataNotExists, fmt.Sprintf("获取应用id=%d失败：%s", stackApp.AppId, err.Error()))
+		}
+		info.Name = app.Name
+		info.Status = app.Status
+		if app.AppVersionId != 0 {
+			if appVersion, err := a.models.AppVersionManager.GetById(app.AppVersionId); err == nil {
+				info.PackageVersion = appVersion.PackageVersion
+			}
+		}
+		detail.AppInfos = append(detail.AppInfos, info)
+	}
+	return detail, nil
+}
+
+type SaveStackForm struct {
+	ProjectId   uint               `json:"project_id" form:"project_id"`
+	Name        string             `json:"name" form:"name"`
+	Description string             `json:"description" form:"description"`
+	Apps        types.AppStackApps `json:"apps" form:"apps"`
+	User        string             `json:"user" form:"user"`
+}
+
+// CreateStack 创建应用栈，应用栈中的应用必须属于同一个工作空间，且依赖关系不能有环
+func (a *AppService) CreateStack(form *SaveStackForm) (*types.AppStack, error) {
+	if err := a.validateStack(form); err != nil {
+		return nil, err
+	}
+	exists, err := a.models.AppStackManager.GetByName(form.ProjectId, form.Name)
+	if err != nil {
+		return nil, errors.New(code.DBError, err)
+	}
+	if exists != nil {
+		return nil, errors.New(code.ParamsError, fmt.Sprintf("应用栈%s已存在", form.Name))
+	}
+	stack, err := a.models.AppStackManager.Create(&types.AppStack{
+		ProjectId:   form.ProjectId,
+		Name:        form.Name,
+		Description: form.Description,
+		Apps:        form.Apps,
+		CreateUser:  form.User,
+		UpdateUser:  form.User,
+	})
+	if err != nil {
+		return nil, errors.New(code.DBError, err)
+	}
+	return stack, nil
+}
+
+// UpdateStack 更新应用栈的描述、应用以及依赖关系，应用栈正在执行操作时不能更新
+func (a *AppService) UpdateStack(stackId uint, form *SaveStackForm) (*types.AppStack, error) {
+	stack, err := a.models.AppStackManager.Get(stackId)
+	if err != nil {
+		return nil, errors.New(code.DataNotExists, err)
+	}
+	if stack.Operating() {
+		return nil, errors.New(code.StatusError, "应用栈正在执行操作，请稍后再试")
+	}
+	form.ProjectId = stack.ProjectId
+	form.Name = stack.Name
+	if err = a.validateStack(form); err != nil {
+		return nil, err
+	}
+	if err = a.models.AppStackManager.Update(stackId, map[string]interface{}{
+		"description": form.Description,
+		"apps":        form.Apps,
+		"update_user": form.User,
+		"update_time": time.Now(),
+	}); err != nil {
+		return nil, errors.New(code.DBError, err)
+	}
+	if stack, err = a.models.AppStackManager.Get(stackId); err != nil {
+		return nil, errors.New(code.DBError, err)
+	}
+	return stack, nil
+}
+
+func (a *AppService) validateStack(form *SaveStackForm) error {
+	if form.Name == "" {
+		return errors.New(code.ParamsError, "应用栈名称不能为空")
+	}
+	if len(form.Apps) == 0 {
+		return errors.New(code.ParamsError, "应用栈中至少需要一个应用")
+	}
+	for _, stackApp := range form.Apps {
+		if stackApp == nil {
+			return errors.New(code.ParamsError, "应用栈中的应用不能为空")
+		}
+		app, err := a.models.AppManager.GetById(stackApp.AppId)
+		if err != nil {
+			return errors.New(code.DataNotExists, fmt.Sprintf("获取应用id=%d失败：%s", stackApp.AppId, err.Error()))
+		}
+		if app.Scope != types.ScopeProject || app.ScopeId != form.ProjectId {
+			return errors.New(code.ParamsError, fmt.Sprintf("应用%s不属于该工作空间", app.Name))
+		}
+		if stackApp.AppVersionId != 0 {
+			appVersion, err := a.models.AppVersionManager.GetById(stackApp.AppVersionId)
+			if err != nil {
+				return errors.New(code.DataNotExists, fmt.Sprintf("获取应用%s版本失败：%s", app.Name, err.Error()))
+			}
+			if appVersion.Scope != types.ScopeProject || appVersion.ScopeId != app.ID {
+				return errors.New(code.ParamsError, fmt.Sprintf("应用版本%s不属于应用%s", appVersion.PackageVersion, app.Name))
+			}
+		}
+		if stackApp.ReadyTimeout < 0 {
+			return errors.New(code.ParamsError, fmt.Sprintf("应用%s等待就绪超时时间不能小于0", app.Name))
+		}
+	}
+	if _, err := appStackOrder(form.Apps); err != nil {
+		return errors.New(code.ParamsError, err)
+	}
+	return nil
+}
+
+// DeleteStack 删除应用栈，不会删除以及销毁应用栈中的应用
+func (a *AppService) DeleteStack(stackId uint) error {
+	stack, err := a.models.AppStackManager.Get(stackId)
+	if err != nil {
+		return errors.New(code.DataNotExists, err)
+	}
+	if stack.Operating() {
+		return errors.New(code.StatusError, "应用栈正在执行操作，请稍后再试")
+	}
+	if err = a.models.AppStackManager.Delete(stackId); err != nil {
+		return errors.New(code.DBError, err)
+	}
+	return nil
+}
+
+// StartStackOperation 开始安装、升级或者销毁应用栈，由controller按依赖顺序执行
+func (a *AppService) StartStackOperation(stackId uint, status, user string) (*types.AppStack, error) {
+	switch status {
+	case types.AppStackStatusInstalling, types.AppStackStatusUpgrading, types.AppStackStatusDestroying:
+	default:
+		return nil, errors.New(code.ParamsError, "不支持的应用栈操作："+status)
+	}
+	stack, err := a.models.AppStackManager.Get(stackId)
+	if err != nil {
+		return nil, errors.New(code.DataNotExists, err)
+	}
+	if _, err = appStackOrder(stack.Apps); err != nil {
+		return nil, errors.New(code.ParamsError, err)
+	}
+	ok, err := a.models.AppStackManager.StartOperation(stackId, status, user)
+	if err != nil {
+		return nil, errors.New(code.DBError, err)
+	}
+	if !ok {
+		return nil, errors.New(code.StatusError, "应用栈正在执行操作，请稍后再试")
+	}
+	if stack, err = a.models.AppStackManager.Get(stackId); err != nil {
+		return nil, errors.New(code.DBError, err)
+	}
+	return stack, nil
+}
+
+// ExecuteStack 执行应用栈未完成的操作，安装以及升级按依赖顺序进行，每个应用运行正常后再部署依赖它的应用，
+// 销毁按依赖的逆序进行
+func (a *AppService) ExecuteStack(stackId uint) error {
+	stack, err := a.models.AppStackManager.Get(stackId)
+	if err != nil {
+		return errors.New(code.DataNotExists, err)
+	}
+	if !stack.Operating() {
+		return nil
+	}
+	status, message := types.AppStackStatusReady, ""
+	if stack.Status == types.AppStackStatusDestroying {
+		status = types.AppStackStatusDestroyed
+	}
+	if err = a.executeStack(stack); err != nil {
+		status, message = types.AppStackStatusFailed, err.Error()
+	}
+	klog.Infof("execute app stack id=%d name=%s %s finished, status=%s %s", stack.ID, stack.Name, stack.Status, status, message)
+	if updateErr := a.models.AppStackManager.Update(stack.ID, map[string]interface{}{
+		"status":  status,
+		"message": message,
+	}); updateErr != nil {
+		return errors.New(code.DBError, updateErr)
+	}
+	return err
+}
+
+func (a *AppService) executeStack(stack *types.AppStack) error {
+	ordered, err := appStackOrder(stack.Apps)
+	if err != nil {
+		return err
+	}
+	// 刷新应用的运行状态，避免使用数据库中过期的状态判断应用是否已安装
+	if _, err = a.GetAppStatus(types.ScopeProject, stack.ProjectId, ""); err != nil {
+		return err
+	}
+	if stack.Status == types.AppStackStatusDestroying {
+		for i := len(ordered) - 1; i >= 0; i-- {
+			app, err := a.models.AppManager.GetById(ordered[i].AppId)
+			if err != nil {
+				return fmt.Errorf("获取应用id=%d失败：%s", ordered[i].AppId, err.Error())
+			}
+			if app.Status == types.AppStatusUninstall {
+				continue
+			}
+			a.updateStackMessage(stack, fmt.Sprintf("正在销毁应用%s", app.Name))
+			if _, err = a.DestroyApp(app.ID, stack.UpdateUser); err != nil {
+				return fmt.Errorf("销毁应用%s失败：%s", app.Name, err.Error())
+			}
+		}
+		return nil
+	}
+	for _, stackApp := range ordered {
+		app, err := a.models.AppManager.GetById(stackApp.AppId)
+		if err != nil {
+			return fmt.Errorf("获取应用id=%d失败：%s", stackApp.AppId, err.Error())
+		}
+		appVersionId := stackApp.AppVersionId
+		if appVersionId == 0 {
+			appVersionId = app.AppVersionId
+		}
+		// 安装时已安装的应用保持不变，升级时只升级版本与应用栈中不一致的应用，未安装的应用都进行安装
+		deploy := app.Status == types.AppStatusUninstall ||
+			(stack.Status == types.AppStackStatusUpgrading && app.AppVersionId != appVersionId)
+		if deploy {
+			a.updateStackMessage(stack, fmt.Sprintf("正在部署应用%s", app.Name))
+			if _, _, err = a.installAppVersion(app.ID, appVersionId, stack.UpdateUser); err != nil {
+				return fmt.Errorf("部署应用%s失败：%s", app.Name, err.Error())
+			}
+		}
+		a.updateStackMessage(stack, fmt.Sprintf("等待应用%s运行正常", app.Name))
+		if err = a.waitAppReady(app, stackApp.ReadyTimeout); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (a *AppService) updateStackMessage(stack *types.AppStack, message string) {
+	if err := a.models.AppStackManager.Update(stack.ID, map[string]interface{}{"message": message}); err != nil {
+		klog.Warningf("update app stack id=%d message error: %s", stack.ID, err.Error())
+	}
+}
+
+// waitAppReady 等待应用运行正常，timeout为0时使用默认超时时间
+func (a *AppService) waitAppReady(app *types.App, timeout int) error {
+	if timeout <= 0 {
+		timeout = types.AppStackDefaultReadyTimeout
+	}
+	deadline := time.Now().Add(time.Duration(timeout) * time.Second)
+	status := ""
+	for {
+		statuses, err := a.GetAppStatus(types.ScopeProject, app.ScopeId, app.Name)
+		if err != nil {
+			return fmt.Errorf("获取应用%s状态失败：%s", app.Name, err.Error())
+		}
+		if appStatus, ok := statuses[app.Name]; ok {
+			status = appStatus.RuntimeStatus
+		}
+		// 检测到资源漂移的应用也是运行正常的
+		if status == types.AppStatusRunning || status == types.AppStatusOutOfSync {
+			return nil
+		}
+		if time.Now().After(deadline) {
+			return fmt.Errorf("等待应用%s运行正常超时，当前状态：%s", app.Name, status)
+		}
+		time.Sleep(appStackReadyCheckInterval)
+	}
+}
+
+// appStackOrder 按依赖关系对应用栈中的应用进行拓扑排序，没有依赖关系的应用保持声明的顺序
+func appStackOrder(apps types.AppStackApps) ([]*types.AppStackApp, error) {
+	index := make(map[uint]*types.AppStackApp)
+	for _, app := range apps {
+		if _, ok := index[app.AppId]; ok {
+			return nil, fmt.Errorf("应用id=%d在应用栈中重复", app.AppId)
+		}
+		index[app.AppId] = app
+	}
+	inDegree := make(map[uint]int)
+	dependents := make(map[uint][]uint)
+	for _, app := range apps {
+		for _, dep := range app.DependsOn {
+			if dep == app.AppId {
+				return nil, fmt.Errorf("应用id=%d不能依赖自身", app.AppId)
+			}
+			if _, ok := index[dep]; !ok {
+				return nil, fmt.Errorf("应用id=%d依赖的应用id=%d不在应用栈中", app.AppId, dep)
+			}
+			inDegree[app.AppId]++
+			dependents[dep] = append(dependents[dep], app.AppId)
+		}
+	}
+	var ordered []*types.AppStackApp
+	done := make(map[uint]bool)
+	for len(ordered) < len(apps) {
+		progressed := false
+		for _, app := range apps {
+			if done[app.AppId] || inDegree[app.AppId] > 0 {
+				continue
+			}
+			done[app.AppId] = true
+			ordered = append(ordered, app)
+			for _, dependent := range dependents[app.AppId] {
+				inDegree[dependent]--
+			}
+			progressed = true
+		}
+		if !progressed {
+			var cycle []string
+			for _, app := range apps {
+				if !done[app.AppId] {
+					cycle = append(cycle, fmt.Sprintf("%d", app.AppId))
+				}
+			}
+			return nil, fmt.Errorf("应用栈依赖关系存在循环：%s", strings.Join(cycle, ","))
+		}
+	}
+	return ordered, nil
+}
@@ -131,6 +131,8 @@ func (s *AppStoreService) ListStoreApp(withVersions bool) *utils.Response {
 					"package_name":    v.PackageName,
 					"package_version": v.PackageVersion,
 					"app_version":     v.AppVersion,
+					"from":            v.From,
+					"verified":        v.Verified,
 				})
 			}
 			a["versions"] = appVersions
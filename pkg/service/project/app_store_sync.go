@@ -0,0 +1,211 @@
+package project
+
+import (
+	"bytes"
+	"fmt"
+	"github.com/kubespace/kubespace/pkg/core/code"
+	"github.com/kubespace/kubespace/pkg/core/errors"
+	"github.com/kubespace/kubespace/pkg/model/types"
+	"github.com/kubespace/kubespace/pkg/third/helm"
+	"helm.sh/helm/v3/pkg/chart/loader"
+	"k8s.io/klog/v2"
+	"sort"
+	"strings"
+	"time"
+)
+
+// appStoreSyncResult 应用商店源一次同步的结果统计
+type appStoreSyncResult struct {
+	imported int
+	skipped  int
+	errs     []string
+}
+
+func (r *appStoreSyncResult) message() string {
+	msg := fmt.Sprintf("新增%d个版本，已存在%d个版本", r.imported, r.skipped)
+	if len(r.errs) > 0 {
+		msg += fmt.Sprintf("，%d个版本同步失败：\n%s", len(r.errs), strings.Join(r.errs, "\n"))
+	}
+	return msg
+}
+
+// SyncSource 从应用商店源同步白名单中chart的最新版本到应用商店，并记录同步状态
+func (s *AppStoreService) SyncSource(sourceId uint) error {
+	source, err := s.models.AppStoreSourceManager.Get(sourceId)
+	if err != nil {
+		return errors.New(code.DataNotExists, err)
+	}
+	if err = s.models.AppStoreSourceManager.Update(source.ID, map[string]interface{}{
+		"status": types.AppStoreSourceStatusSyncing,
+	}); err != nil {
+		return errors.New(code.DBError, err)
+	}
+	klog.Infof("start sync app store source id=%d name=%s url=%s", source.ID, source.Name, source.Url)
+	result, err := s.syncSource(source)
+	status := types.AppStoreSourceStatusOK
+	var message string
+	if err != nil {
+		status = types.AppStoreSourceStatusError
+		message = err.Error()
+	} else {
+		message = result.message()
+		if len(result.errs) > 0 {
+			status = types.AppStoreSourceStatusError
+		}
+	}
+	klog.Infof("sync app store source id=%d name=%s status=%s: %s", source.ID, source.Name, status, message)
+	if updateErr := s.models.AppStoreSourceManager.Update(source.ID, map[string]interface{}{
+		"status":         status,
+		"message":        message,
+		"last_sync_time": time.Now(),
+	}); updateErr != nil {
+		return errors.New(code.DBError, updateErr)
+	}
+	return err
+}
+
+func (s *AppStoreService) syncSource(source *types.AppStoreSource) (*appStoreSyncResult, error) {
+	opts := &helm.RepoOptions{Url: source.Url, Charts: source.Allowlist}
+	if source.SecretId != 0 {
+		secret, err := s.models.SettingsSecretManager.Get(source.SecretId)
+		if err != nil {
+			return nil, fmt.Errorf("获取仓库密钥失败：%s", err.Error())
+		}
+		opts.Username = secret.User
+		opts.Password = secret.Password
+		if secret.Type == types.SettingsSecretTypeToken {
+			opts.Password = secret.AccessToken
+		}
+	}
+	repo, err := helm.NewChartRepository(opts)
+	if err != nil {
+		return nil, fmt.Errorf("连接chart仓库失败：%s", err.Error())
+	}
+	defer repo.Close()
+	charts, err := repo.Versions()
+	if err != nil {
+		return nil, fmt.Errorf("获取chart列表失败：%s", err.Error())
+	}
+	maxVersions := source.MaxVersions
+	if maxVersions <= 0 {
+		maxVersions = types.AppStoreSourceDefaultMaxVersions
+	}
+	var names []string
+	for name := range charts {
+		if source.Allowed(name) {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+
+	result := &appStoreSyncResult{}
+	for _, name := range names {
+		versions := charts[name]
+		if len(versions) > maxVersions {
+			versions = versions[:maxVersions]
+		}
+		storeApp, err := s.models.AppStoreManager.GetByName(name)
+		if err != nil {
+			return result, err
+		}
+		// 从旧到新导入，保证应用商店中最新版本为仓库中的最新版本
+		for i := len(versions) - 1; i >= 0; i-- {
+			version := versions[i]
+			if storeApp != nil {
+				exists, err := s.models.AppVersionManager.GetByPackageNameVersion(types.ScopeAppStore, storeApp.ID, name, version.Version)
+				if err != nil {
+					return result, err
+				}
+				if exists != nil {
+					result.skipped++
+					continue
+				}
+			}
+			imported, err := s.importRemoteChart(source, repo, storeApp, version)
+			if err != nil {
+				klog.Errorf("sync app store source id=%d chart %s-%s error: %s", source.ID, name, version.Version, err.Error())
+				result.errs = append(result.errs, fmt.Sprintf("%s-%s: %s", name, version.Version, err.Error()))
+				continue
+			}
+			storeApp = imported
+			result.imported++
+		}
+	}
+	return result, nil
+}
+
+// importRemoteChart 下载并校验chart，导入为应用商店应用的新版本，应用不存在时创建应用
+func (s *AppStoreService) importRemoteChart(
+	source *types.AppStoreSource,
+	repo helm.ChartRepository,
+	storeApp *types.AppStore,
+	version *helm.RemoteChartVersion) (*types.AppStore, error) {
+	remoteChart, err := repo.Download(version)
+	if err != nil {
+		return nil, err
+	}
+	verified := false
+	if source.Keyring != "" && len(remoteChart.Prov) > 0 {
+		if err = helm.VerifyProvenance(remoteChart, source.Keyring); err != nil {
+			return nil, err
+		}
+		verified = true
+	}
+	if source.RequireProvenance && !verified {
+		return nil, fmt.Errorf("chart没有签名或者未配置校验公钥")
+	}
+	charts, err := loader.LoadArchive(bytes.NewReader(remoteChart.Data))
+	if err != nil {
+		return nil, fmt.Errorf("加载chart失败：%s", err.Error())
+	}
+	if charts.Name() != version.Name || charts.Metadata.Version != version.Version {
+		return nil, fmt.Errorf("chart包名称版本%s-%s与仓库不一致", charts.Name(), charts.Metadata.Version)
+	}
+	values := ""
+	for _, rawFile := range charts.Raw {
+		if rawFile.Name == "values.yaml" {
+			values = string(rawFile.Data)
+			break
+		}
+	}
+	if storeApp == nil {
+		appType := source.AppType
+		if appType == "" {
+			appType = types.AppTypeOrdinaryApp
+		}
+		storeApp = &types.AppStore{
+			Name:        charts.Name(),
+			Description: charts.Metadata.Description,
+			Type:        appType,
+			CreateUser:  source.CreateUser,
+			UpdateUser:  source.CreateUser,
+			CreateTime:  time.Now(),
+			UpdateTime:  time.Now(),
+		}
+		if charts.Metadata.Icon != "" {
+			if icon, err := helm.FetchIcon(charts.Metadata.Icon); err == nil {
+				storeApp.Icon = icon
+			} else {
+				klog.Warningf("fetch chart %s icon error: %s", charts.Name(), err.Error())
+			}
+		}
+	}
+	appVersion := &types.AppVersion{
+		PackageName:    charts.Name(),
+		PackageVersion: charts.Metadata.Version,
+		AppVersion:     charts.AppVersion(),
+		From:           types.AppVersionFromSync,
+		Values:         values,
+		Description:    fmt.Sprintf("从%s同步", source.Name),
+		SourceId:       source.ID,
+		Digest:         version.Digest,
+		Verified:       verified,
+		CreateUser:     source.CreateUser,
+		CreateTime:     time.Now(),
+		UpdateTime:     time.Now(),
+	}
+	if _, err = s.models.AppStoreManager.CreateStoreApp(remoteChart.Data, storeApp, appVersion); err != nil {
+		return nil, err
+	}
+	return storeApp, nil
+}
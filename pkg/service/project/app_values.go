@@ -0,0 +1,249 @@
+package project
+
+import (
+	"bytes"
+	"fmt"
+	"github.com/kubespace/kubespace/pkg/core/code"
+	"github.com/kubespace/kubespace/pkg/core/errors"
+	"github.com/kubespace/kubespace/pkg/kubernetes/resource"
+	kubetypes "github.com/kubespace/kubespace/pkg/kubernetes/types"
+	"github.com/kubespace/kubespace/pkg/model/types"
+	"github.com/kubespace/kubespace/pkg/utils"
+	"helm.sh/helm/v3/pkg/chart/loader"
+	"helm.sh/helm/v3/pkg/chartutil"
+	corev1 "k8s.io/api/core/v1"
+	"sigs.k8s.io/yaml"
+	"strings"
+	"time"
+)
+
+const (
+	// ValuesSettingsSecretPrefix 引用平台密钥的values，格式为secret://<密钥名称>/<user|password|private_key|access_token>
+	ValuesSettingsSecretPrefix = "secret://"
+	// ValuesK8sSecretPrefix 引用应用所在namespace中k8s secret的values，格式为k8s-secret://<secret名称>/<key>
+	ValuesK8sSecretPrefix = "k8s-secret://"
+	// ValuesSecretMask 预览安装/升级时密钥引用渲染为该掩码，预览结果以及日志中不包含密钥明文
+	ValuesSecretMask = "******"
+)
+
+// ListValuesOverlays 获取应用的values覆盖配置
+func (a *AppService) ListValuesOverlays(appId uint) ([]*types.AppValuesOverlay, error) {
+	overlays, err := a.models.AppManager.ListValuesOverlays(appId)
+	if err != nil {
+		return nil, errors.New(code.DBError, err)
+	}
+	return overlays, nil
+}
+
+type SaveValuesOverlayForm struct {
+	AppId       uint   `json:"app_id" form:"app_id"`
+	Name        string `json:"name" form:"name"`
+	Description string `json:"description" form:"description"`
+	Values      string `json:"values" form:"values"`
+	User        string `json:"user" form:"user"`
+}
+
+// SaveValuesOverlay 保存应用values覆盖配置，覆盖配置中的密钥需要使用引用，不能保存明文
+func (a *AppService) SaveValuesOverlay(form *SaveValuesOverlayForm) (*types.AppValuesOverlay, error) {
+	if form.Name == "" || strings.ContainsAny(form.Name, "/\\ ") {
+		return nil, errors.New(code.ParamsError, "覆盖配置名称不能为空且不能包含空格或者路径分隔符")
+	}
+	values := map[string]interface{}{}
+	if err := yaml.Unmarshal([]byte(form.Values), &values); err != nil {
+		return nil, errors.New(code.ParamsError, "values格式错误："+err.Error())
+	}
+	overlay, err := a.models.AppManager.SaveValuesOverlay(&types.AppValuesOverlay{
+		AppId:       form.AppId,
+		Name:        form.Name,
+		Description: form.Description,
+		Values:      form.Values,
+		CreateUser:  form.User,
+		UpdateUser:  form.User,
+		UpdateTime:  time.Now(),
+	})
+	if err != nil {
+		return nil, errors.New(code.DBError, err)
+	}
+	return overlay, nil
+}
+
+func (a *AppService) DeleteValuesOverlay(appId uint, name string) error {
+	if err := a.models.AppManager.DeleteValuesOverlay(appId, name); err != nil {
+		return errors.New(code.DBError, err)
+	}
+	return nil
+}
+
+// mergeValuesOverlays 将应用的values覆盖配置按顺序合并到values，没有覆盖配置时返回原values
+func (a *AppService) mergeValuesOverlays(appId uint, values string, overlayNames []string) (string, error) {
+	if len(overlayNames) == 0 {
+		return values, nil
+	}
+	merged := map[string]interface{}{}
+	if err := yaml.Unmarshal([]byte(values), &merged); err != nil {
+		return "", errors.New(code.ParamsError, "values参数解析错误："+err.Error())
+	}
+	for _, name := range overlayNames {
+		overlay, err := a.models.AppManager.GetValuesOverlay(appId, name)
+		if err != nil {
+			return "", errors.New(code.DataNotExists, fmt.Sprintf("获取values覆盖配置%s失败：%s", name, err.Error()))
+		}
+		overlayValues := map[string]interface{}{}
+		if err = yaml.Unmarshal([]byte(overlay.Values), &overlayValues); err != nil {
+			return "", errors.New(code.ParamsError, fmt.Sprintf("values覆盖配置%s解析错误：%s", name, err.Error()))
+		}
+		merged = mergeValues(merged, overlayValues)
+	}
+	mergedBytes, err := yaml.Marshal(merged)
+	if err != nil {
+		return "", errors.New(code.MarshalError, err)
+	}
+	return string(mergedBytes), nil
+}
+
+// mergeValues 递归合并values，与helm多个-f参数的合并规则一致，map递归合并，其他类型直接覆盖
+func mergeValues(base, overlay map[string]interface{}) map[string]interface{} {
+	for k, v := range overlay {
+		if overlayMap, ok := v.(map[string]interface{}); ok {
+			if baseMap, ok := base[k].(map[string]interface{}); ok {
+				base[k] = mergeValues(baseMap, overlayMap)
+				continue
+			}
+		}
+		base[k] = v
+	}
+	return base
+}
+
+// resolveSecretValues 解析values中的密钥引用，解析后的values只用于安装，不做保存
+// 平台密钥只能解析允许应用所在工作空间/集群引用的密钥
+func (a *AppService) resolveSecretValues(app *types.App, clusterId, namespace, values string) (string, error) {
+	k8sSecrets := make(map[string]*corev1.Secret)
+	return replaceSecretRefs(values, func(ref string) (string, error) {
+		if strings.HasPrefix(ref, ValuesSettingsSecretPrefix) {
+			return a.resolveSettingsSecret(app, ref)
+		}
+		return a.resolveK8sSecret(clusterId, namespace, ref, k8sSecrets)
+	})
+}
+
+// maskSecretValues 将values中的密钥引用替换为掩码，用于预览安装/升级
+func maskSecretValues(values string) (string, error) {
+	return replaceSecretRefs(values, func(string) (string, error) {
+		return ValuesSecretMask, nil
+	})
+}
+
+// replaceSecretRefs 将values中的平台密钥以及k8s secret引用替换为replace返回的值，没有引用时原样返回
+func replaceSecretRefs(values string, replace func(ref string) (string, error)) (string, error) {
+	if !strings.Contains(values, ValuesSettingsSecretPrefix) && !strings.Contains(values, ValuesK8sSecretPrefix) {
+		return values, nil
+	}
+	valuesMap := map[string]interface{}{}
+	if err := yaml.Unmarshal([]byte(values), &valuesMap); err != nil {
+		return "", errors.New(code.ParamsError, "values参数解析错误："+err.Error())
+	}
+	var resolve func(v interface{}) (interface{}, error)
+	resolve = func(v interface{}) (interface{}, error) {
+		switch val := v.(type) {
+		case map[string]interface{}:
+			for k, sub := range val {
+				resolved, err := resolve(sub)
+				if err != nil {
+					return nil, err
+				}
+				val[k] = resolved
+			}
+		case []interface{}:
+			for i, sub := range val {
+				resolved, err := resolve(sub)
+				if err != nil {
+					return nil, err
+				}
+				val[i] = resolved
+			}
+		case string:
+			if strings.HasPrefix(val, ValuesSettingsSecretPrefix) || strings.HasPrefix(val, ValuesK8sSecretPrefix) {
+				return replace(val)
+			}
+		}
+		return v, nil
+	}
+	if _, err := resolve(valuesMap); err != nil {
+		return "", err
+	}
+	resolvedBytes, err := yaml.Marshal(valuesMap)
+	if err != nil {
+		return "", errors.New(code.MarshalError, err)
+	}
+	return string(resolvedBytes), nil
+}
+
+func (a *AppService) resolveSettingsSecret(app *types.App, ref string) (string, error) {
+	parts := strings.SplitN(strings.TrimPrefix(ref, ValuesSettingsSecretPrefix), "/", 2)
+	if len(parts) != 2 {
+		return "", errors.New(code.ParamsError, "密钥引用格式错误："+ref)
+	}
+	secret, err := a.models.SettingsSecretManager.GetByName(parts[0])
+	if err != nil {
+		return "", errors.New(code.DataNotExists, fmt.Sprintf("获取密钥%s失败：%s", parts[0], err.Error()))
+	}
+	if !secret.AllowScope(app.Scope, app.ScopeId) {
+		return "", errors.New(code.ParamsError, fmt.Sprintf("密钥%s不允许在当前应用所在的工作空间或集群中引用", parts[0]))
+	}
+	switch parts[1] {
+	case "user":
+		return secret.User, nil
+	case "password":
+		return secret.Password, nil
+	case "private_key":
+		return secret.PrivateKey, nil
+	case "access_token":
+		return secret.AccessToken, nil
+	}
+	return "", errors.New(code.ParamsError, fmt.Sprintf("密钥引用%s字段错误，只支持user/password/private_key/access_token", ref))
+}
+
+func (a *AppService) resolveK8sSecret(clusterId, namespace, ref string, cache map[string]*corev1.Secret) (string, error) {
+	parts := strings.SplitN(strings.TrimPrefix(ref, ValuesK8sSecretPrefix), "/", 2)
+	if len(parts) != 2 {
+		return "", errors.New(code.ParamsError, "secret引用格式错误："+ref)
+	}
+	secret, ok := cache[parts[0]]
+	if !ok {
+		resp := a.kubeClient.Get(clusterId, kubetypes.SecretType, &resource.QueryParams{Name: parts[0], Namespace: namespace})
+		if !resp.IsSuccess() {
+			return "", errors.New(resp.Code, fmt.Sprintf("获取secret %s/%s失败：%s", namespace, parts[0], resp.Msg))
+		}
+		secret = &corev1.Secret{}
+		if err := utils.ConvertTypeByJson(resp.Data, secret); err != nil {
+			return "", errors.New(code.MarshalError, err)
+		}
+		cache[parts[0]] = secret
+	}
+	data, ok := secret.Data[parts[1]]
+	if !ok {
+		return "", errors.New(code.DataNotExists, fmt.Sprintf("secret %s/%s中不存在%s", namespace, parts[0], parts[1]))
+	}
+	return string(data), nil
+}
+
+// validateValuesSchema 通过chart中的values.schema.json校验合并chart默认值后的values，chart没有schema时不校验
+func validateValuesSchema(chartBytes []byte, values string) error {
+	chart, err := loader.LoadArchive(bytes.NewReader(chartBytes))
+	if err != nil {
+		return errors.New(code.ParamsError, "加载chart失败："+err.Error())
+	}
+	valuesMap := map[string]interface{}{}
+	if err = yaml.Unmarshal([]byte(values), &valuesMap); err != nil {
+		return errors.New(code.ParamsError, "values参数解析错误："+err.Error())
+	}
+	coalesced, err := chartutil.CoalesceValues(chart, valuesMap)
+	if err != nil {
+		return errors.New(code.ParamsError, err)
+	}
+	if err = chartutil.ValidateAgainstSchema(chart, coalesced); err != nil {
+		return errors.New(code.ParamsError, "values校验失败："+err.Error())
+	}
+	return nil
+}
@@ -0,0 +1,34 @@
+package project
+
+import (
+	"strings"
+	"testing"
+)
+
+// TestMaskSecretValues 预览时values中的密钥引用替换为掩码，其它值原样保留
+func TestMaskSecretValues(t *testing.T) {
+	values := `image: nginx
+db:
+  password: secret://mysql/password
+  hosts:
+  - k8s-secret://db/host
+  - db.local
+`
+	masked, err := maskSecretValues(values)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, ref := range []string{"secret://", "k8s-secret://"} {
+		if strings.Contains(masked, ref) {
+			t.Errorf("masked values contains reference %s: %s", ref, masked)
+		}
+	}
+	for _, want := range []string{"password: '" + ValuesSecretMask + "'", "- '" + ValuesSecretMask + "'", "- db.local", "image: nginx"} {
+		if !strings.Contains(masked, want) {
+			t.Errorf("masked values = %s, want contains %s", masked, want)
+		}
+	}
+	if got, _ := maskSecretValues("image: nginx\n"); got != "image: nginx\n" {
+		t.Errorf("values without reference = %q, want unchanged", got)
+	}
+}
@@ -6,6 +6,7 @@ import (
 	"fmt"
 	"github.com/kubespace/kubespace/pkg/core/code"
 	"github.com/kubespace/kubespace/pkg/core/errors"
+	"github.com/kubespace/kubespace/pkg/kubernetes/resource"
 	kubetypes "github.com/kubespace/kubespace/pkg/kubernetes/types"
 	"github.com/kubespace/kubespace/pkg/model/manager/project"
 	"github.com/kubespace/kubespace/pkg/model/types"
@@ -136,49 +137,117 @@ type InstallAppForm struct {
 	Values       string `json:"values" form:"values"`
 	AppVersionId uint   `json:"app_version_id" form:"app_version_id"`
 	Upgrade      bool   `json:"upgrade" form:"upgrade"`
-	User         string `json:"user" form:"user"`
+	// DryRun 只预览安装/升级的资源清单以及差异
+	DryRun bool `json:"dry_run" form:"dry_run"`
+	// Overlays 按顺序合并到values的覆盖配置名称
+	Overlays []string `json:"overlays" form:"overlays"`
+	// Options helm安装/升级选项，如等待资源就绪、失败自动回滚等
+	Options resource.HelmInstallOptions `json:"options" form:"options"`
+	User    string                      `json:"user" form:"user"`
 }
 
-func (a *AppService) InstallApp(installForm *InstallAppForm) (*types.App, *types.AppVersion, error) {
+// appInstall 应用安装所在集群、helm安装参数以及合并覆盖配置后的values
+type appInstall struct {
+	app       *types.App
+	version   *types.AppVersion
+	clusterId string
+	params    *resource.HelmObjectParams
+	// values 合并覆盖配置后、解析密钥引用前的values，记录到应用历史记录中
+	values string
+}
+
+// installParams 获取应用安装所在集群以及helm安装参数，合并values覆盖配置、解析密钥引用并通过chart的values.schema.json校验。
+// 预览时仍解析密钥引用进行校验，但渲染使用的values中密钥引用替换为掩码，避免预览结果中出现密钥明文
+func (a *AppService) installParams(installForm *InstallAppForm, preview bool) (*appInstall, error) {
+	install := &appInstall{}
 	versionApp, err := a.models.AppVersionManager.GetById(installForm.AppVersionId)
 	if err != nil {
-		return nil, nil, errors.New(code.DataNotExists, "get app version error: "+err.Error())
+		return install, errors.New(code.DataNotExists, "get app version error: "+err.Error())
 	}
 	if versionApp.ScopeId != installForm.AppId {
-		return nil, nil, errors.New(code.ParamsError, "当前应用不存在该版本，请重新选择")
+		return install, errors.New(code.ParamsError, "当前应用不存在该版本，请重新选择")
 	}
 	app, err := a.models.AppManager.GetById(installForm.AppId)
 	if err != nil {
-		return nil, nil, errors.New(code.DataNotExists, "get app error: "+err.Error())
+		return install, errors.New(code.DataNotExists, "get app error: "+err.Error())
 	}
-	var clusterId string
+	install.app = app
+	install.version = versionApp
 	var namespace string
 	if app.Scope == types.ScopeProject {
 		projectObj, err := a.models.ProjectManager.Get(app.ScopeId)
 		if err != nil {
-			return app, versionApp, errors.New(code.DataNotExists, "get project error: "+err.Error())
+			return install, errors.New(code.DataNotExists, "get project error: "+err.Error())
 		}
-		clusterId = projectObj.ClusterId
+		install.clusterId = projectObj.ClusterId
 		namespace = projectObj.Namespace
 	} else {
-		clusterId = fmt.Sprintf("%d", app.ScopeId)
+		install.clusterId = fmt.Sprintf("%d", app.ScopeId)
 		namespace = app.Namespace
 	}
 	appChart, err := a.models.AppVersionManager.GetChart(versionApp.ChartPath)
 	if err != nil {
-		return app, versionApp, errors.New(code.DataNotExists, "not found chart path "+versionApp.ChartPath)
+		return install, errors.New(code.DataNotExists, "not found chart path "+versionApp.ChartPath)
+	}
+	if install.values, err = a.mergeValuesOverlays(app.ID, installForm.Values, installForm.Overlays); err != nil {
+		return install, err
+	}
+	values, err := a.resolveSecretValues(app, install.clusterId, namespace, install.values)
+	if err != nil {
+		return install, err
+	}
+	if err = validateValuesSchema(appChart.Content, values); err != nil {
+		return install, err
+	}
+	if preview {
+		if values, err = maskSecretValues(install.values); err != nil {
+			return install, err
+		}
+	}
+	install.params = &resource.HelmObjectParams{
+		Name:               app.Name,
+		Namespace:          namespace,
+		ChartBytes:         appChart.Content,
+		Values:             values,
+		HelmInstallOptions: installForm.Options,
+	}
+	return install, nil
+}
+
+// PreviewInstallApp 通过dry-run渲染应用资源清单，返回与当前release以及集群中资源的差异，不实际安装/升级
+func (a *AppService) PreviewInstallApp(installForm *InstallAppForm) (*resource.HelmPreview, error) {
+	install, err := a.installParams(installForm, true)
+	if err != nil {
+		return nil, err
 	}
-	installParams := map[string]interface{}{
-		"name":        app.Name,
-		"namespace":   namespace,
-		"chart_bytes": appChart.Content,
-		"values":      installForm.Values,
+	install.params.DryRun = true
+	var resp *utils.Response
+	if installForm.Upgrade {
+		resp = a.kubeClient.Update(install.clusterId, kubetypes.HelmType, install.params)
+	} else {
+		resp = a.kubeClient.Create(install.clusterId, kubetypes.HelmType, install.params)
+	}
+	if !resp.IsSuccess() {
+		return nil, errors.New(resp.Code, resp.Msg)
+	}
+	var preview resource.HelmPreview
+	if err = utils.ConvertTypeByJson(resp.Data, &preview); err != nil {
+		return nil, errors.New(code.MarshalError, err)
+	}
+	return &preview, nil
+}
+
+func (a *AppService) InstallApp(installForm *InstallAppForm) (*types.App, *types.AppVersion, error) {
+	install, err := a.installParams(installForm, false)
+	app, versionApp := install.app, install.version
+	if err != nil {
+		return app, versionApp, err
 	}
 	var resp *utils.Response
 	if installForm.Upgrade {
-		resp = a.kubeClient.Update(clusterId, kubetypes.HelmType, installParams)
+		resp = a.kubeClient.Update(install.clusterId, kubetypes.HelmType, install.params)
 	} else {
-		resp = a.kubeClient.Create(clusterId, kubetypes.HelmType, installParams)
+		resp = a.kubeClient.Create(install.clusterId, kubetypes.HelmType, install.params)
 	}
 	if !resp.IsSuccess() {
 		return app, versionApp, errors.New(resp.Code, resp.Msg)
@@ -186,16 +255,23 @@ func (a *AppService) InstallApp(installForm *InstallAppForm) (*types.App, *types
 	app.AppVersionId = installForm.AppVersionId
 	app.UpdateUser = installForm.User
 	app.Status = types.AppStatusNotReady
-	if err = a.models.AppManager.UpdateApp(app, "status", "app_version_id", "update_user", "update_time"); err != nil {
+	// 重新安装后需要重新进行漂移检测
+	app.SyncStatus = ""
+	app.SyncMessage = ""
+	app.SyncCheckTime = nil
+	if err = a.models.AppManager.UpdateApp(app, "status", "app_version_id", "sync_status", "sync_message", "sync_check_time", "update_user", "update_time"); err != nil {
 		return app, versionApp, errors.New(code.DBError, err)
 	}
 	versionApp.Values = installForm.Values
 	if err = a.models.AppVersionManager.UpdateAppVersion(versionApp, "values"); err != nil {
 		return app, versionApp, errors.New(code.DBError, err)
 	}
+	// 历史记录保存合并覆盖配置后的values，回滚时不依赖覆盖配置的当前内容
+	versionApp.Values = install.values
 	if _, err = a.models.AppManager.CreateRevision(versionApp, app); err != nil {
 		klog.Errorf("create project app id=%s, name=%s revision error: %s", app.ID, app.Name, err)
 	}
+	versionApp.Values = installForm.Values
 	return app, versionApp, nil
 }
 
@@ -281,6 +357,12 @@ func (a *AppService) updateAppStatus(scope string, scopeId uint, apps []*types.A
 			return nil, nil
 		}
 	}
+	// 运行正常但检测到资源漂移的应用展示为OutOfSync
+	for _, app := range apps {
+		if status, ok := nameStatusMap[app.Name]; ok && status.RuntimeStatus == types.AppStatusRunning && app.SyncStatus == types.AppSyncStatusOutOfSync {
+			status.RuntimeStatus = types.AppStatusOutOfSync
+		}
+	}
 	return nameStatusMap, nil
 }
 
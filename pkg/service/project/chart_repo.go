@@ -0,0 +1,146 @@
+package project
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"github.com/kubespace/kubespace/pkg/core/code"
+	"github.com/kubespace/kubespace/pkg/core/errors"
+	"github.com/kubespace/kubespace/pkg/model/types"
+	"github.com/kubespace/kubespace/pkg/third/helm"
+	"helm.sh/helm/v3/pkg/chart"
+	"helm.sh/helm/v3/pkg/repo"
+	"k8s.io/klog/v2"
+	"sigs.k8s.io/yaml"
+	"strings"
+	"time"
+)
+
+// ChartRepoIndex 生成chart仓库的index.yaml内容
+// scope为工作空间时包括工作空间下所有应用的版本，为应用商店时包括所有应用商店应用的版本
+func (b *AppBaseService) ChartRepoIndex(scope string, scopeId uint) ([]byte, error) {
+	var appVersions []*types.AppVersion
+	var err error
+	switch scope {
+	case types.ScopeProject:
+		appVersions, err = b.models.AppVersionManager.ListByProject(scopeId)
+	case types.ScopeAppStore:
+		appVersions, err = b.models.AppVersionManager.ListByScope(types.ScopeAppStore)
+	default:
+		return nil, errors.New(code.ParamsError, "chart仓库不支持该范围："+scope)
+	}
+	if err != nil {
+		return nil, errors.New(code.DBError, err)
+	}
+	index := repo.NewIndexFile()
+	exists := make(map[string]bool)
+	// 应用版本按id倒序，工作空间下不同应用有相同的chart名称以及版本时，使用最新创建的版本
+	for _, v := range appVersions {
+		key := v.PackageName + "-" + v.PackageVersion
+		if exists[key] {
+			continue
+		}
+		if v.Digest == "" {
+			if err = b.fillAppVersionDigest(v); err != nil {
+				klog.Warningf("compute app version id=%d digest error: %s", v.ID, err.Error())
+				continue
+			}
+		}
+		exists[key] = true
+		index.Entries[v.PackageName] = append(index.Entries[v.PackageName], &repo.ChartVersion{
+			Metadata: &chart.Metadata{
+				APIVersion:  chart.APIVersionV2,
+				Name:        v.PackageName,
+				Version:     v.PackageVersion,
+				AppVersion:  v.AppVersion,
+				Description: v.Description,
+			},
+			URLs:    []string{fmt.Sprintf("charts/%d/%s-%s.tgz", v.ID, v.PackageName, v.PackageVersion)},
+			Created: v.CreateTime,
+			Digest:  v.Digest,
+		})
+	}
+	index.SortEntries()
+	index.Generated = time.Now()
+	data, err := yaml.Marshal(index)
+	if err != nil {
+		return nil, errors.New(code.GetError, err)
+	}
+	return data, nil
+}
+
+// fillAppVersionDigest 计算应用版本chart包的sha256摘要并保存，兼容没有记录摘要的历史版本
+func (b *AppBaseService) fillAppVersionDigest(appVersion *types.AppVersion) error {
+	appChart, err := b.models.AppVersionManager.GetChart(appVersion.ChartPath)
+	if err != nil {
+		return err
+	}
+	sum := sha256.Sum256(appChart.Content)
+	appVersion.Digest = hex.EncodeToString(sum[:])
+	return b.models.AppVersionManager.UpdateAppVersion(appVersion, "digest")
+}
+
+// GetChartRepoChart 获取chart仓库中应用版本的chart包，应用版本必须属于该chart仓库
+func (b *AppBaseService) GetChartRepoChart(scope string, scopeId, appVersionId uint) (*types.AppVersion, []byte, error) {
+	appVersion, err := b.models.AppVersionManager.GetById(appVersionId)
+	if err != nil {
+		return nil, nil, errors.New(code.DataNotExists, err)
+	}
+	if appVersion.Scope != scope {
+		return nil, nil, errors.New(code.DataNotExists, "chart仓库中不存在该应用版本")
+	}
+	if scope == types.ScopeProject {
+		app, err := b.models.AppManager.GetById(appVersion.ScopeId)
+		if err != nil {
+			return nil, nil, errors.New(code.DataNotExists, err)
+		}
+		if app.Scope != types.ScopeProject || app.ScopeId != scopeId {
+			return nil, nil, errors.New(code.DataNotExists, "chart仓库中不存在该应用版本")
+		}
+	}
+	appChart, err := b.models.AppVersionManager.GetChart(appVersion.ChartPath)
+	if err != nil {
+		return nil, nil, errors.New(code.DataNotExists, err)
+	}
+	return appVersion, appChart.Content, nil
+}
+
+type PushAppVersionForm struct {
+	AppVersionId uint `json:"app_version_id" form:"app_version_id"`
+	RegistryId   uint `json:"registry_id" form:"registry_id"`
+	// Repository 镜像仓库中chart的推送路径，如library/charts
+	Repository string `json:"repository" form:"repository"`
+}
+
+// PushAppVersionToOci 使用镜像仓库配置的认证信息将应用版本chart推送到oci仓库，返回推送后的chart引用
+func (b *AppBaseService) PushAppVersionToOci(form *PushAppVersionForm) (string, error) {
+	appVersion, err := b.models.AppVersionManager.GetById(form.AppVersionId)
+	if err != nil {
+		return "", errors.New(code.DataNotExists, err)
+	}
+	imageRegistry, err := b.models.ImageRegistryManager.Get(form.RegistryId)
+	if err != nil {
+		return "", errors.New(code.DataNotExists, fmt.Sprintf("获取镜像仓库失败：%s", err.Error()))
+	}
+	appChart, err := b.models.AppVersionManager.GetChart(appVersion.ChartPath)
+	if err != nil {
+		return "", errors.New(code.DataNotExists, err)
+	}
+	host := strings.TrimSuffix(strings.TrimPrefix(strings.TrimPrefix(imageRegistry.Registry, "https://"), "http://"), "/")
+	ref := host
+	if repository := strings.Trim(form.Repository, "/"); repository != "" {
+		ref += "/" + repository
+	}
+	// oci tag不支持+，helm推送时使用_替换
+	ref = fmt.Sprintf("%s/%s:%s", ref, appVersion.PackageName, strings.ReplaceAll(appVersion.PackageVersion, "+", "_"))
+
+	client, cleanup, err := helm.NewRegistryClient(host, imageRegistry.User, imageRegistry.Password)
+	if err != nil {
+		return ref, errors.New(code.RequestError, err)
+	}
+	defer cleanup()
+	if _, err = client.Push(appChart.Content, ref); err != nil {
+		return ref, errors.New(code.RequestError, fmt.Sprintf("推送chart到%s失败：%s", ref, err.Error()))
+	}
+	return helm.OciScheme + ref, nil
+}
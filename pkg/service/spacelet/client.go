@@ -1,6 +1,7 @@
 package spacelet
 
 import (
+	"crypto/tls"
 	"errors"
 	"fmt"
 	"github.com/kubespace/kubespace/pkg/model/types"
@@ -20,8 +21,12 @@ type Client interface {
 	Exec(params *spacelet.ExecRequest) (*spacelet.ExecResponse, error)
 }
 
-func NewClient(spacelet *types.Spacelet) (Client, error) {
-	httpcli, err := httpclient.NewHttpClient(fmt.Sprintf("http://%s:%d", spacelet.HostIp, spacelet.Port))
+// NewClient 创建spacelet客户端，通过双向tls访问spacelet
+func NewClient(spacelet *types.Spacelet, tlsConfig *tls.Config) (Client, error) {
+	if tlsConfig == nil {
+		return nil, fmt.Errorf("spacelet client tls config is empty")
+	}
+	httpcli, err := httpclient.NewHttpClientWithTLS(fmt.Sprintf("https://%s:%d", spacelet.HostIp, spacelet.Port), tlsConfig)
 	if err != nil {
 		return nil, err
 	}
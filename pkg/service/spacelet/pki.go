@@ -0,0 +1,194 @@
+package spacelet
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"github.com/kubespace/kubespace/pkg/model"
+	"github.com/kubespace/kubespace/pkg/model/types"
+	"github.com/kubespace/kubespace/pkg/spacelet"
+	"github.com/kubespace/kubespace/pkg/utils"
+	"net"
+	"sync"
+	"time"
+)
+
+const (
+	// caValidity kubespace签发spacelet证书的CA有效期
+	caValidity = time.Hour * 24 * 365 * 10
+	// clientCertValidity kubespace客户端以及服务端证书有效期，每个进程启动后生成，过期前重新生成
+	clientCertValidity = time.Hour * 24 * 30
+)
+
+// PKI kubespace作为CA，给spacelet签发证书，kubespace与spacelet之间通过双向tls认证
+type PKI struct {
+	models *model.Models
+	mu     sync.Mutex
+	caPEM  []byte
+	ca     *x509.Certificate
+	caKey  interface{}
+	// kubespace访问spacelet的客户端证书
+	clientCert *tls.Certificate
+	// spacelet访问kubespace时kubespace的服务端证书
+	serverCert *tls.Certificate
+}
+
+func NewPKI(models *model.Models) *PKI {
+	return &PKI{models: models}
+}
+
+// loadCA 调用时需加锁，从数据库加载CA，不存在时生成
+func (p *PKI) loadCA() error {
+	if p.ca != nil {
+		return nil
+	}
+	caObj, err := p.models.CertificateAuthorityManager.Get(types.CertificateAuthoritySpacelet)
+	if err != nil {
+		return err
+	}
+	if caObj == nil {
+		certPEM, keyPEM, err := utils.GenerateCertPEM(&utils.CertOptions{
+			CommonName:  "kubespace-spacelet-ca",
+			ValidFor:    caValidity,
+			IsCA:        true,
+			EcdsaCurve:  "P256",
+			ExtKeyUsage: []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
+		})
+		if err != nil {
+			return err
+		}
+		if caObj, err = p.models.CertificateAuthorityManager.GetOrCreate(&types.CertificateAuthority{
+			Name: types.CertificateAuthoritySpacelet,
+			Cert: string(certPEM),
+			Key:  string(keyPEM),
+		}); err != nil {
+			return err
+		}
+	}
+	ca, caKey, err := utils.ParseCertKeyPEM([]byte(caObj.Cert), []byte(caObj.Key))
+	if err != nil {
+		return fmt.Errorf("parse spacelet ca error: %s", err.Error())
+	}
+	p.caPEM, p.ca, p.caKey = []byte(caObj.Cert), ca, caKey
+	return nil
+}
+
+// CACertPEM CA证书，spacelet注册时下发，用于认证kubespace
+func (p *PKI) CACertPEM() ([]byte, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if err := p.loadCA(); err != nil {
+		return nil, err
+	}
+	return p.caPEM, nil
+}
+
+// CAHash CA证书公钥的sha256，安装spacelet时指定，spacelet注册时校验获取的CA证书
+func (p *PKI) CAHash() (string, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if err := p.loadCA(); err != nil {
+		return "", err
+	}
+	return spacelet.CAHash(p.ca), nil
+}
+
+// CertPool 包含CA证书的证书池，用于认证spacelet
+func (p *PKI) CertPool() (*x509.CertPool, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if err := p.loadCA(); err != nil {
+		return nil, err
+	}
+	pool := x509.NewCertPool()
+	pool.AddCert(p.ca)
+	return pool, nil
+}
+
+// SignSpaceletCert 根据spacelet证书签名请求签发证书，证书同时用于spacelet服务端以及访问kubespace的客户端，
+// 证书只包含spacelet的ip，不包含spacelet上报的主机名等域名，避免冒充kubespace服务端
+func (p *PKI) SignSpaceletCert(csrPEM []byte, hostIp string, port int) ([]byte, error) {
+	if net.ParseIP(hostIp) == nil {
+		return nil, fmt.Errorf("spacelet host ip %s is invalid", hostIp)
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if err := p.loadCA(); err != nil {
+		return nil, err
+	}
+	return utils.SignCSR(csrPEM, p.ca, p.caKey, spacelet.CommonName(hostIp, port), []string{hostIp},
+		types.SpaceletCertValidity, []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth})
+}
+
+// issueCert 调用时需加锁，使用CA签发kubespace自身的证书，过期前重新签发
+func (p *PKI) issueCert(cached *tls.Certificate, hosts []string, extKeyUsage x509.ExtKeyUsage) (*tls.Certificate, error) {
+	if cached != nil && time.Until(cached.Leaf.NotAfter) >= clientCertValidity/2 {
+		return cached, nil
+	}
+	certPEM, keyPEM, err := utils.GenerateCertPEM(&utils.CertOptions{
+		Hosts:       hosts,
+		CommonName:  spacelet.ServerClientCommonName,
+		ValidFor:    clientCertValidity,
+		EcdsaCurve:  "P256",
+		ExtKeyUsage: []x509.ExtKeyUsage{extKeyUsage},
+		Parent:      p.ca,
+		ParentKey:   p.caKey,
+	})
+	if err != nil {
+		return nil, err
+	}
+	cert, err := tls.X509KeyPair(certPEM, keyPEM)
+	if err != nil {
+		return nil, err
+	}
+	if cert.Leaf, err = x509.ParseCertificate(cert.Certificate[0]); err != nil {
+		return nil, err
+	}
+	return &cert, nil
+}
+
+// ClientTLSConfig kubespace访问spacelet的双向tls配置
+func (p *PKI) ClientTLSConfig(serverName string) (*tls.Config, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if err := p.loadCA(); err != nil {
+		return nil, err
+	}
+	clientCert, err := p.issueCert(p.clientCert, nil, x509.ExtKeyUsageClientAuth)
+	if err != nil {
+		return nil, err
+	}
+	p.clientCert = clientCert
+	pool := x509.NewCertPool()
+	pool.AddCert(p.ca)
+	return &tls.Config{
+		Certificates: []tls.Certificate{*p.clientCert},
+		RootCAs:      pool,
+		ServerName:   serverName,
+		MinVersion:   tls.VersionTLS12,
+	}, nil
+}
+
+// ServerTLSConfig spacelet访问kubespace的双向tls配置，服务端证书由CA签发，spacelet通过CA认证kubespace。
+// 未注册的spacelet没有客户端证书，需要通过该端口使用引导token注册，心跳以及回调接口要求CA签发的客户端证书
+func (p *PKI) ServerTLSConfig() (*tls.Config, error) {
+	pool, err := p.CertPool()
+	if err != nil {
+		return nil, err
+	}
+	return &tls.Config{
+		ClientAuth: tls.VerifyClientCertIfGiven,
+		ClientCAs:  pool,
+		MinVersion: tls.VersionTLS12,
+		GetCertificate: func(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+			p.mu.Lock()
+			defer p.mu.Unlock()
+			serverCert, err := p.issueCert(p.serverCert, []string{spacelet.ServerClientCommonName}, x509.ExtKeyUsageServerAuth)
+			if err != nil {
+				return nil, err
+			}
+			p.serverCert = serverCert
+			return serverCert, nil
+		},
+	}, nil
+}
@@ -0,0 +1,66 @@
+package spacelet
+
+import (
+	"crypto/x509"
+	"encoding/pem"
+	"github.com/kubespace/kubespace/pkg/spacelet"
+	"github.com/kubespace/kubespace/pkg/utils"
+	"testing"
+	"time"
+)
+
+// testPKI 使用内存中生成的CA，不依赖数据库
+func testPKI(t *testing.T) *PKI {
+	certPEM, keyPEM, err := utils.GenerateCertPEM(&utils.CertOptions{
+		CommonName: "kubespace-spacelet-ca",
+		ValidFor:   time.Hour,
+		IsCA:       true,
+		EcdsaCurve: "P256",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ca, caKey, err := utils.ParseCertKeyPEM(certPEM, keyPEM)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &PKI{caPEM: certPEM, ca: ca, caKey: caKey}
+}
+
+func TestSignSpaceletCert(t *testing.T) {
+	p := testPKI(t)
+	// spacelet可以在证书签名请求中指定任意名称，签发的证书只包含连接的ip
+	csrPEM, _, err := utils.GenerateCSR(spacelet.ServerClientCommonName, "P256")
+	if err != nil {
+		t.Fatal(err)
+	}
+	certPEM, err := p.SignSpaceletCert(csrPEM, "10.0.0.8", 7520)
+	if err != nil {
+		t.Fatalf("SignSpaceletCert() error = %v", err)
+	}
+	block, _ := pem.Decode(certPEM)
+	cert, err := x509.ParseCertificate(block.Bytes)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cert.Subject.CommonName != spacelet.CommonName("10.0.0.8", 7520) {
+		t.Errorf("common name = %s, want %s", cert.Subject.CommonName, spacelet.CommonName("10.0.0.8", 7520))
+	}
+	if len(cert.DNSNames) != 0 {
+		t.Errorf("dns names = %v, want empty", cert.DNSNames)
+	}
+	if len(cert.IPAddresses) != 1 || cert.IPAddresses[0].String() != "10.0.0.8" {
+		t.Errorf("ip addresses = %v, want [10.0.0.8]", cert.IPAddresses)
+	}
+	pool := x509.NewCertPool()
+	pool.AddCert(p.ca)
+	if _, err = cert.Verify(x509.VerifyOptions{Roots: pool, DNSName: spacelet.ServerClientCommonName}); err == nil {
+		t.Errorf("spacelet cert verified as %s", spacelet.ServerClientCommonName)
+	}
+
+	for _, hostIp := range []string{"", spacelet.ServerClientCommonName, "10.0.0.8,kubespace-server"} {
+		if _, err = p.SignSpaceletCert(csrPEM, hostIp, 7520); err == nil {
+			t.Errorf("SignSpaceletCert(%q) error = nil, want error", hostIp)
+		}
+	}
+}
@@ -1,6 +1,7 @@
 package spacelet
 
 import (
+	"fmt"
 	"github.com/kubespace/kubespace/pkg/core/code"
 	"github.com/kubespace/kubespace/pkg/model"
 	"github.com/kubespace/kubespace/pkg/model/types"
@@ -9,10 +10,23 @@ import (
 
 type SpaceletService struct {
 	models *model.Models
+	PKI    *PKI
 }
 
 func NewSpaceletService(models *model.Models) *SpaceletService {
-	return &SpaceletService{models: models}
+	return &SpaceletService{models: models, PKI: NewPKI(models)}
+}
+
+// Client 创建spacelet客户端，通过双向tls访问，旧版本spacelet未签发证书，需要升级后重新注册
+func (s *SpaceletService) Client(spaceletObj *types.Spacelet) (Client, error) {
+	if !spaceletObj.TlsEnabled {
+		return nil, fmt.Errorf("spacelet %s:%d未签发证书，请升级spacelet", spaceletObj.HostIp, spaceletObj.Port)
+	}
+	tlsConfig, err := s.PKI.ClientTLSConfig(spaceletObj.HostIp)
+	if err != nil {
+		return nil, err
+	}
+	return NewClient(spaceletObj, tlsConfig)
 }
 
 func (s *SpaceletService) Delete(id uint) *utils.Response {
@@ -4,10 +4,14 @@ import (
 	"fmt"
 	"github.com/kubespace/kubespace/pkg/third/httpclient"
 	"github.com/kubespace/kubespace/pkg/utils"
+	"k8s.io/apimachinery/pkg/api/resource"
 	"k8s.io/klog/v2"
 	"net"
 	"net/url"
+	"os"
 	"os/exec"
+	"runtime"
+	"strconv"
 	"strings"
 )
 
@@ -16,12 +20,30 @@ type Options struct {
 	Port      int
 	DataDir   string
 	ServerUrl string
+	// ServerTLSUrl spacelet通过双向tls访问kubespace的地址，为空时使用ServerUrl的主机以及注册时返回的端口
+	ServerTLSUrl string
+	// CAHash kubespace CA证书公钥的sha256，首次注册时校验获取的CA证书
+	CAHash string
+	// BootstrapToken 管理员生成的引导token，首次注册时需要提供
+	BootstrapToken string
+	// 最大并发任务数，为0时不限制
+	MaxJobs int
+	// cpu容量，如4、3500m，为空时为主机cpu核数
+	Cpu string
+	// 内存容量，如16Gi，为空时为主机内存大小
+	Memory string
+	// 允许通过exec接口执行的命令，为空时禁用exec接口
+	ExecAllowlist []string
 }
 
 type Config struct {
-	// kubespace服务地址
-	ServerUrl string
-	Client    *httpclient.HttpClient
+	// kubespace服务地址，用于注册
+	ServerUrl      string
+	ServerTLSUrl   string
+	CAHash         string
+	BootstrapToken string
+	// 注册之后为双向tls客户端
+	Client *httpclient.HttpClient
 	// spacelet所在服务器的主机ip
 	HostIp string
 	// spacelet服务启动端口
@@ -30,6 +52,12 @@ type Config struct {
 	DataDir string
 	// 注册之后获取的token，用来进行认证
 	Token string
+	// 允许通过exec接口执行的命令，为空时禁用exec接口
+	ExecAllowlist []string
+	// spacelet节点容量，注册时上报给kubespace，用于任务调度
+	MaxJobs int
+	Cpu     int64
+	Memory  int64
 }
 
 func getServerIp(serverUrl string) (net.IP, error) {
@@ -94,12 +122,68 @@ func NewConfig(options *Options) (*Config, error) {
 	if hostIp == "" {
 		hostIp = getSpaceletHostIp(options)
 	}
-	return &Config{
+	config := &Config{
 		ServerUrl: options.ServerUrl,
 		Client:    httpcli,
 		HostIp:    hostIp,
 		Port:      options.Port,
 		DataDir:   options.DataDir,
 		Token:     "",
-	}, nil
+		MaxJobs:   options.MaxJobs,
+
+		ServerTLSUrl:   options.ServerTLSUrl,
+		CAHash:         options.CAHash,
+		BootstrapToken: options.BootstrapToken,
+		ExecAllowlist:  options.ExecAllowlist,
+	}
+	if err = config.setCapacity(options); err != nil {
+		return nil, err
+	}
+	return config, nil
+}
+
+// setCapacity 设置spacelet节点容量，未指定时使用主机cpu核数以及内存大小，最大任务数未指定时不限制
+func (c *Config) setCapacity(options *Options) error {
+	if c.MaxJobs < 0 {
+		c.MaxJobs = 0
+	}
+	if options.Cpu != "" {
+		cpu, err := resource.ParseQuantity(options.Cpu)
+		if err != nil {
+			return fmt.Errorf("parse cpu capacity %s error: %s", options.Cpu, err.Error())
+		}
+		c.Cpu = cpu.MilliValue()
+	} else {
+		c.Cpu = int64(runtime.NumCPU()) * 1000
+	}
+	if options.Memory != "" {
+		memory, err := resource.ParseQuantity(options.Memory)
+		if err != nil {
+			return fmt.Errorf("parse memory capacity %s error: %s", options.Memory, err.Error())
+		}
+		c.Memory = memory.Value()
+	} else {
+		c.Memory = getHostMemory()
+	}
+	return nil
+}
+
+// getHostMemory 从/proc/meminfo获取主机内存大小，获取失败返回0，即不限制
+func getHostMemory() int64 {
+	data, err := os.ReadFile("/proc/meminfo")
+	if err != nil {
+		klog.Warningf("read meminfo error: %s", err.Error())
+		return 0
+	}
+	for _, line := range strings.Split(string(data), "\n") {
+		fields := strings.Fields(line)
+		if len(fields) >= 2 && fields[0] == "MemTotal:" {
+			kb, err := strconv.ParseInt(fields[1], 10, 64)
+			if err != nil {
+				return 0
+			}
+			return kb * 1024
+		}
+	}
+	return 0
 }
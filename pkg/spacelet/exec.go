@@ -7,6 +7,7 @@ import (
 	"github.com/kubespace/kubespace/pkg/utils"
 	"net/http"
 	"os/exec"
+	"strings"
 )
 
 type ExecResponse struct {
@@ -17,30 +18,38 @@ type ExecResponse struct {
 }
 
 type ExecRequest struct {
-	Command    string `json:"command"`
+	Command string `json:"command"`
+	// 已废弃，命令不再通过shell执行
 	Executable string `json:"executable"`
 }
 
+// Exec 执行白名单中的命令，未配置白名单时禁用
+// 命令按空格拆分后直接执行，不经过shell解析，防止通过管道、分号等执行白名单之外的命令
 func (s *Server) Exec(c *gin.Context) {
 	req := ExecRequest{}
 	if err := c.BindJSON(&req); err != nil {
 		c.JSON(http.StatusOK, &utils.Response{Code: code.ParamsError, Msg: err.Error()})
 		return
 	}
-	if req.Command == "" {
+	args := strings.Fields(req.Command)
+	if len(args) == 0 {
 		c.JSON(http.StatusOK, &utils.Response{Code: code.ParamsError, Msg: "command is required"})
 		return
 	}
-	executable := req.Executable
-	if executable == "" {
-		executable = "/bin/sh"
+	if len(s.config.ExecAllowlist) == 0 {
+		c.JSON(http.StatusForbidden, &utils.Response{Code: code.AuthError, Msg: "exec is disabled"})
+		return
+	}
+	if !utils.Contains(s.config.ExecAllowlist, args[0]) {
+		c.JSON(http.StatusForbidden, &utils.Response{Code: code.AuthError, Msg: "command " + args[0] + " is not allowed"})
+		return
 	}
 
 	stdout := bytes.NewBuffer(nil)
 	stderr := bytes.NewBuffer(nil)
 	stdin := bytes.NewBuffer(nil)
 
-	cmd := exec.Command(executable, "-c", req.Command)
+	cmd := exec.Command(args[0], args[1:]...)
 	cmd.Stdin = stdin
 	cmd.Stdout = stdout
 	cmd.Stderr = stderr
@@ -0,0 +1,94 @@
+package spacelet
+
+import (
+	"fmt"
+	"github.com/kubespace/kubespace/pkg/model/types"
+	"github.com/kubespace/kubespace/pkg/third/httpclient"
+	"github.com/kubespace/kubespace/pkg/utils"
+	"k8s.io/klog/v2"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Version spacelet版本，编译时通过-ldflags设置
+var Version = "latest"
+
+// HeartbeatRequest spacelet定时上报心跳，kubespace通过心跳判断spacelet节点是否在线
+type HeartbeatRequest struct {
+	HostIp  string `json:"hostip"`
+	Port    int    `json:"port"`
+	Token   string `json:"token"`
+	Version string `json:"version"`
+	// 最近1分钟系统平均负载
+	Load1 float64 `json:"load1"`
+	// 正在执行的任务id，kubespace根据该字段判断分配到该节点的任务是否丢失
+	RunningJobs []uint `json:"running_jobs"`
+	// 最近执行完成且还未被kubespace清理的任务id，kubespace获取任务结果前不会将其判定为丢失
+	FinishedJobs []uint `json:"finished_jobs"`
+}
+
+// HeartbeatResponse 心跳返回spacelet节点当前的调度状态以及轮换后的token
+type HeartbeatResponse struct {
+	Drain bool   `json:"drain"`
+	Token string `json:"token"`
+}
+
+func (s *Server) heartbeat() {
+	defer utils.HandleCrash()
+	drain := false
+	tick := time.NewTicker(types.SpaceletHeartbeatInterval)
+	defer tick.Stop()
+	for ; true; <-tick.C {
+		resp, err := s.sendHeartbeat()
+		if err != nil {
+			klog.Errorf("send spacelet heartbeat error: %s", err.Error())
+			continue
+		}
+		if err = s.rotateToken(resp.Token); err != nil {
+			klog.Errorf("rotate spacelet token error: %s", err.Error())
+		}
+		if resp.Drain != drain {
+			drain = resp.Drain
+			klog.Infof("spacelet drain changed to %v", drain)
+		}
+	}
+}
+
+func (s *Server) sendHeartbeat() (*HeartbeatResponse, error) {
+	var resp utils.Response
+	if _, err := s.config.Client.Post("/api/v1/spacelet/heartbeat", &HeartbeatRequest{
+		HostIp:       s.config.HostIp,
+		Port:         s.config.Port,
+		Token:        s.token(),
+		Version:      Version,
+		Load1:        getLoad1(),
+		RunningJobs:  s.jobExecutor.RunningJobs(),
+		FinishedJobs: s.jobExecutor.FinishedJobs(),
+	}, &resp, httpclient.RequestOptions{}); err != nil {
+		return nil, err
+	}
+	if !resp.IsSuccess() {
+		return nil, fmt.Errorf("%s", resp.Msg)
+	}
+	var heartbeatResp HeartbeatResponse
+	if err := utils.ConvertTypeByJson(resp.Data, &heartbeatResp); err != nil {
+		return nil, err
+	}
+	return &heartbeatResp, nil
+}
+
+// getLoad1 从/proc/loadavg获取最近1分钟系统平均负载，获取失败返回0
+func getLoad1() float64 {
+	data, err := os.ReadFile("/proc/loadavg")
+	if err != nil {
+		return 0
+	}
+	fields := strings.Fields(string(data))
+	if len(fields) == 0 {
+		return 0
+	}
+	load1, _ := strconv.ParseFloat(fields[0], 64)
+	return load1
+}
@@ -0,0 +1,167 @@
+package spacelet
+
+import (
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"os"
+	"path"
+	"strconv"
+	"strings"
+)
+
+// ServerClientCommonName kubespace访问spacelet的客户端证书CommonName，spacelet只允许该证书访问，
+// 同时为spacelet访问kubespace时服务端证书的域名
+const ServerClientCommonName = "kubespace-server"
+
+const commonNamePrefix = "spacelet:"
+
+// ReservedName kubespace服务端证书使用的名称以及spacelet证书CommonName前缀，spacelet不能使用
+func ReservedName(name string) bool {
+	name = strings.ToLower(strings.TrimSpace(name))
+	return name == ServerClientCommonName || strings.HasPrefix(name, commonNamePrefix)
+}
+
+// CommonName kubespace签发给spacelet的证书CommonName
+func CommonName(hostIp string, port int) string {
+	return fmt.Sprintf("%s%s:%d", commonNamePrefix, hostIp, port)
+}
+
+// ParseCommonName 从spacelet证书CommonName中解析spacelet的ip以及端口
+func ParseCommonName(cn string) (hostIp string, port int, err error) {
+	if !strings.HasPrefix(cn, commonNamePrefix) {
+		return "", 0, fmt.Errorf("certificate %s is not issued for spacelet", cn)
+	}
+	hostPort := strings.TrimPrefix(cn, commonNamePrefix)
+	idx := strings.LastIndex(hostPort, ":")
+	if idx <= 0 {
+		return "", 0, fmt.Errorf("certificate common name %s is invalid", cn)
+	}
+	if port, err = strconv.Atoi(hostPort[idx+1:]); err != nil {
+		return "", 0, fmt.Errorf("certificate common name %s is invalid", cn)
+	}
+	return hostPort[:idx], port, nil
+}
+
+// CAHash CA证书公钥的sha256，格式为sha256:<hex>
+func CAHash(ca *x509.Certificate) string {
+	sum := sha256.Sum256(ca.RawSubjectPublicKeyInfo)
+	return "sha256:" + hex.EncodeToString(sum[:])
+}
+
+// verifyCAHash 校验注册时获取的CA证书是否与安装时指定的公钥sha256一致
+func verifyCAHash(caCertPEM, caHash string) error {
+	block, _ := pem.Decode([]byte(caCertPEM))
+	if block == nil {
+		return fmt.Errorf("parse kubespace ca cert error")
+	}
+	ca, err := x509.ParseCertificate(block.Bytes)
+	if err != nil {
+		return fmt.Errorf("parse kubespace ca cert error: %s", err.Error())
+	}
+	if !strings.EqualFold(CAHash(ca), caHash) {
+		return fmt.Errorf("kubespace ca hash %s does not match %s", CAHash(ca), caHash)
+	}
+	return nil
+}
+
+// identity spacelet注册后获取的token以及证书，保存在数据目录，重启后使用token重新注册认证
+type identity struct {
+	Token     string `json:"token"`
+	PrevToken string `json:"prev_token"`
+	Key       string `json:"key"`
+	Cert      string `json:"cert"`
+	CaCert    string `json:"ca_cert"`
+	// ServerUrl spacelet通过双向tls访问kubespace的地址
+	ServerUrl string `json:"server_url"`
+}
+
+func identityFile(dataDir string) string {
+	return path.Join(dataDir, "spacelet", "identity.json")
+}
+
+// loadIdentity 加载保存的身份信息，不存在时返回空
+func loadIdentity(dataDir string) (*identity, error) {
+	data, err := os.ReadFile(identityFile(dataDir))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return &identity{}, nil
+		}
+		return nil, err
+	}
+	var id identity
+	if err = json.Unmarshal(data, &id); err != nil {
+		return nil, err
+	}
+	return &id, nil
+}
+
+func (i *identity) save(dataDir string) error {
+	file := identityFile(dataDir)
+	if err := os.MkdirAll(path.Dir(file), 0700); err != nil {
+		return err
+	}
+	data, err := json.Marshal(i)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(file, data, 0600)
+}
+
+// serverTLSConfig spacelet服务端tls配置，只允许持有kubespace签发的客户端证书访问
+func (i *identity) serverTLSConfig() (*tls.Config, error) {
+	cert, err := tls.X509KeyPair([]byte(i.Cert), []byte(i.Key))
+	if err != nil {
+		return nil, err
+	}
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM([]byte(i.CaCert)) {
+		return nil, fmt.Errorf("parse kubespace ca cert error")
+	}
+	return &tls.Config{
+		Certificates: []tls.Certificate{cert},
+		ClientCAs:    pool,
+		ClientAuth:   tls.RequireAndVerifyClientCert,
+		MinVersion:   tls.VersionTLS12,
+		// 其它spacelet的证书同样由kubespace签发，只允许kubespace客户端证书访问
+		VerifyPeerCertificate: func(rawCerts [][]byte, verifiedChains [][]*x509.Certificate) error {
+			for _, chain := range verifiedChains {
+				if len(chain) > 0 && chain[0].Subject.CommonName == ServerClientCommonName {
+					return nil
+				}
+			}
+			return fmt.Errorf("client certificate is not issued for kubespace server")
+		},
+	}, nil
+}
+
+// clientTLSConfig spacelet访问kubespace的双向tls配置，提供客户端证书，并通过CA认证kubespace
+func (i *identity) clientTLSConfig() (*tls.Config, error) {
+	cert, err := tls.X509KeyPair([]byte(i.Cert), []byte(i.Key))
+	if err != nil {
+		return nil, err
+	}
+	config, err := caTLSConfig(i.CaCert)
+	if err != nil {
+		return nil, err
+	}
+	config.Certificates = []tls.Certificate{cert}
+	return config, nil
+}
+
+// caTLSConfig 通过kubespace CA认证kubespace服务端的tls配置，注册时还没有客户端证书
+func caTLSConfig(caCert string) (*tls.Config, error) {
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM([]byte(caCert)) {
+		return nil, fmt.Errorf("parse kubespace ca cert error")
+	}
+	return &tls.Config{
+		RootCAs:    pool,
+		ServerName: ServerClientCommonName,
+		MinVersion: tls.VersionTLS12,
+	}, nil
+}
@@ -20,6 +20,16 @@ func NewJobExecutor(dataDir string, client *httpclient.HttpClient) *JobExecutor
 	}
 }
 
+// RunningJobs 当前正在执行的任务id
+func (j *JobExecutor) RunningJobs() []uint {
+	return j.jobRun.RunningJobs()
+}
+
+// FinishedJobs 最近执行完成且还未被清理的任务id
+func (j *JobExecutor) FinishedJobs() []uint {
+	return j.jobRun.FinishedJobs()
+}
+
 type JobRunParams struct {
 	JobId  uint                   `json:"job_id" form:"job_id"`
 	Plugin string                 `json:"plugin" form:"plugin"`
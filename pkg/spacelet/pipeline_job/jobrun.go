@@ -16,10 +16,15 @@ import (
 	"os"
 	"path"
 	"strconv"
+	"sync"
+	"time"
 )
 
 const PipelineCallbackUri = "/api/v1/spacelet/pipeline/callback"
 
+// finishedJobRetention 执行完成的任务在该时间内或者被清理前，心跳时作为已完成任务上报
+const finishedJobRetention = time.Minute * 10
+
 // SpaceletJobRun Spacelet流水线任务插件执行处理
 type SpaceletJobRun struct {
 	jobRunner job_runner.JobRunner
@@ -28,6 +33,9 @@ type SpaceletJobRun struct {
 	dataDir string
 	// 对server进行调用
 	client *httpclient.HttpClient
+	// 已执行完成但还未被kubespace清理的任务以及完成时间，避免kubespace获取结果前将任务判定为丢失
+	mu       sync.Mutex
+	finished map[uint]time.Time
 }
 
 func NewSpaceletJobRun(dataDir string, client *httpclient.HttpClient) *SpaceletJobRun {
@@ -36,6 +44,7 @@ func NewSpaceletJobRun(dataDir string, client *httpclient.HttpClient) *SpaceletJ
 		plugins:   make(map[string]plugins.ExecutorFactory),
 		dataDir:   dataDir,
 		client:    client,
+		finished:  make(map[uint]time.Time),
 	}
 	p.plugins[types.BuiltinPluginBuildCodeToImage] = plugins.CodeBuilderPlugin{}
 	p.plugins[types.BuiltinPluginExecuteShell] = plugins.ExecShellPlugin{}
@@ -80,6 +89,26 @@ func (b *SpaceletJobRun) Cancel(jobId uint) error {
 	return b.jobRunner.Cancel(jobId)
 }
 
+// RunningJobs 当前spacelet节点正在执行的任务id，心跳时上报
+func (b *SpaceletJobRun) RunningJobs() []uint {
+	return b.jobRunner.RunningJobs()
+}
+
+// FinishedJobs 最近执行完成且还未被清理的任务id，心跳时上报
+func (b *SpaceletJobRun) FinishedJobs() []uint {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	var jobIds []uint
+	for jobId, finishTime := range b.finished {
+		if time.Since(finishTime) > finishedJobRetention {
+			delete(b.finished, jobId)
+			continue
+		}
+		jobIds = append(jobIds, jobId)
+	}
+	return jobIds
+}
+
 // Execute 执行任务插件，任务开启一个协程后台执行，该方法立即返回，后续的任务执行状态通过回调接口上报
 func (b *SpaceletJobRun) Execute(jobId uint, pluginKey string, params map[string]interface{}) (resp *utils.Response) {
 	if pluginKey == "" {
@@ -147,6 +176,9 @@ func (b *SpaceletJobRun) execute(executorF plugins.ExecutorFactory, params *plug
 	if err = jobStatus.Set(&StatusResult{Status: status, Result: resp}); err != nil {
 		klog.Errorf("set job=%d status result error: %s", params.JobId, err.Error())
 	}
+	b.mu.Lock()
+	b.finished[params.JobId] = time.Now()
+	b.mu.Unlock()
 	callbackParams := &schemas.JobCallbackParams{JobId: params.JobId, Status: status}
 	// 任务执行完成之后回调，回调失败不影响，pipeline controller有轮询机制定期查询任务状态
 	if _, err = b.client.Post(PipelineCallbackUri, callbackParams, nil, httpclient.RequestOptions{}); err != nil {
@@ -190,6 +222,9 @@ func (b *SpaceletJobRun) GetStatusLog(jobId uint, withLog bool) (*StatusLog, err
 
 // Cleanup 清理任务目录
 func (b *SpaceletJobRun) Cleanup(jobId uint) error {
+	b.mu.Lock()
+	delete(b.finished, jobId)
+	b.mu.Unlock()
 	rootDir, err := b.GetRootDir(jobId)
 	if err != nil {
 		return err
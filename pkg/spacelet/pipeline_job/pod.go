@@ -0,0 +1,134 @@
+package pipeline_job
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/kubespace/kubespace/pkg/core/code"
+	corerrors "github.com/kubespace/kubespace/pkg/core/errors"
+	"github.com/kubespace/kubespace/pkg/model/types"
+	"github.com/kubespace/kubespace/pkg/service/pipeline/job_runner/plugins"
+	"github.com/kubespace/kubespace/pkg/third/httpclient"
+	"github.com/kubespace/kubespace/pkg/utils"
+	"k8s.io/klog/v2"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+const (
+	// PodTerminationMessagePath 在pod中执行任务时，任务状态以及结果写入容器终止信息，由pipeline controller读取
+	PodTerminationMessagePath = "/dev/termination-log"
+	// PodTerminationMessageMaxSize kubelet会截断超过4KB的容器终止信息
+	PodTerminationMessageMaxSize = 4096
+	// PodWorkspacePrefix 共享存储中流水线构建目录的前缀，同一次构建的任务使用同一个目录
+	PodWorkspacePrefix = "pipeline-run-"
+	// podWorkspaceRetention 共享存储中超过该时间没有更新的构建目录在任务启动时清理
+	podWorkspaceRetention = 7 * 24 * time.Hour
+)
+
+// PodWorkspace 流水线构建在共享存储中的目录名称
+func PodWorkspace(pipelineRunId uint) string {
+	return fmt.Sprintf("%s%d", PodWorkspacePrefix, pipelineRunId)
+}
+
+// RunPodJob 在kubernetes pod中执行单个流水线任务，任务执行完成后退出
+// 任务参数从paramsFile中读取，日志输出到标准输出，任务状态以及结果写入容器终止信息。
+// dataDir为共享存储中流水线构建的目录，任务目录执行完成后保留，同一次构建之后的任务可以读取
+func RunPodJob(paramsFile, dataDir string, client *httpclient.HttpClient) error {
+	paramsBytes, err := os.ReadFile(paramsFile)
+	if err != nil {
+		return err
+	}
+	var params JobRunParams
+	if err = json.Unmarshal(paramsBytes, &params); err != nil {
+		return err
+	}
+	jobRun := NewSpaceletJobRun(dataDir, client)
+	executorF, ok := jobRun.plugins[params.Plugin]
+	if !ok {
+		return fmt.Errorf("not found plugin executor: %s", params.Plugin)
+	}
+	rootDir, err := jobRun.GetRootDir(params.JobId)
+	if err != nil {
+		return err
+	}
+	pruneWorkspaces(dataDir)
+	logger := &StdoutLogger{File: os.Stdout}
+	hostname, _ := os.Hostname()
+	logger.Log("current pod: %s", hostname)
+
+	jobParams := plugins.NewExecutorParams(params.JobId, params.Plugin, rootDir, params.Params, logger)
+	result, err := jobRun.jobRunner.Execute(executorF, jobParams)
+	statusResult := &StatusResult{Status: types.PipelineStatusOK, Result: utils.NewResponseOk(result)}
+	if err != nil {
+		klog.Errorf("execute job=%d error: %s", params.JobId, err.Error())
+		statusResult = &StatusResult{
+			Status: types.PipelineStatusError,
+			Result: utils.NewResponseWithError(corerrors.New(code.PluginError, err)),
+		}
+	}
+	if size := statusResultSize(statusResult); size > PodTerminationMessageMaxSize {
+		// 超过限制的结果会被截断，直接返回失败
+		err = fmt.Errorf("任务执行结果大小%d字节超过容器终止信息%d字节的限制", size, PodTerminationMessageMaxSize)
+		logger.Log(err.Error())
+		statusResult = &StatusResult{
+			Status: types.PipelineStatusError,
+			Result: utils.NewResponseWithError(corerrors.New(code.PluginError, err)),
+		}
+	}
+	if setErr := NewJobStatus(PodTerminationMessagePath).Set(statusResult); setErr != nil {
+		klog.Errorf("set job=%d status result error: %s", params.JobId, setErr.Error())
+		return setErr
+	}
+	return err
+}
+
+func statusResultSize(statusResult *StatusResult) int {
+	statusBytes, err := json.Marshal(statusResult)
+	if err != nil {
+		return 0
+	}
+	return len(statusBytes)
+}
+
+// pruneWorkspaces 清理共享存储中超过保留时间没有更新的其它流水线构建目录
+func pruneWorkspaces(dataDir string) {
+	parent, current := filepath.Dir(dataDir), filepath.Base(dataDir)
+	entries, err := os.ReadDir(parent)
+	if err != nil {
+		klog.Warningf("read workspace dir %s error: %s", parent, err.Error())
+		return
+	}
+	for _, entry := range entries {
+		if !entry.IsDir() || entry.Name() == current || !strings.HasPrefix(entry.Name(), PodWorkspacePrefix) {
+			continue
+		}
+		info, err := entry.Info()
+		if err != nil || time.Since(info.ModTime()) < podWorkspaceRetention {
+			continue
+		}
+		klog.Infof("remove expired pipeline workspace %s", entry.Name())
+		if err = os.RemoveAll(filepath.Join(parent, entry.Name())); err != nil {
+			klog.Warningf("remove pipeline workspace %s error: %s", entry.Name(), err.Error())
+		}
+	}
+}
+
+// StdoutLogger 在pod中执行任务时日志输出到标准输出，由pipeline controller读取pod日志
+type StdoutLogger struct {
+	*os.File
+}
+
+func (l *StdoutLogger) Log(format string, a ...interface{}) {
+	fmt.Fprintf(l.File, format+"\n", a...)
+}
+
+// Reset 标准输出不能重写，直接追加
+func (l *StdoutLogger) Reset(format string, a ...interface{}) {
+	l.Log(format, a...)
+}
+
+func (l *StdoutLogger) Close() error {
+	return nil
+}
@@ -8,100 +8,257 @@ import (
 	"github.com/kubespace/kubespace/pkg/third/httpclient"
 	"github.com/kubespace/kubespace/pkg/utils"
 	"k8s.io/klog/v2"
+	"net"
 	"net/http"
+	"net/url"
 	"os"
 	"runtime"
+	"strconv"
+	"sync"
 )
 
 type Server struct {
-	config *Config
-	engine *gin.Engine
+	config      *Config
+	engine      *gin.Engine
+	jobExecutor *pipeline_job.JobExecutor
+	// 注册后获取的token以及证书
+	mu       sync.RWMutex
+	identity *identity
 }
 
 func NewServer(config *Config) (*Server, error) {
 	engine := gin.Default()
 	s := &Server{config: config, engine: engine}
-	// 配置token接口
-	engine.POST("/v1/token", s.Token)
+	return s, nil
+}
 
-	// 统一token认证
-	authGroup := engine.Group("/v1")
+// routes 注册成功之后配置接口，所有接口通过双向tls以及token认证
+func (s *Server) routes() {
+	authGroup := s.engine.Group("/v1")
 	authGroup.Use(s.AuthMiddleware())
 
 	authGroup.POST("/exec", s.Exec)
 
-	jobExecutor := pipeline_job.NewJobExecutor(config.DataDir, config.Client)
+	jobExecutor := pipeline_job.NewJobExecutor(s.config.DataDir, s.config.Client)
+	s.jobExecutor = jobExecutor
 	authGroup.POST("/pipeline_job/execute", jobExecutor.Execute)
 	authGroup.GET("/pipeline_job/status", jobExecutor.Status)
 	authGroup.PUT("/pipeline_job/cleanup", jobExecutor.Cleanup)
 	authGroup.PUT("/pipeline_job/cancel", jobExecutor.Cancel)
-
-	return s, nil
 }
 
 func (s *Server) Run() {
+	// 注册spacelet节点，获取证书以及token
+	if err := s.Register(); err != nil {
+		// 注册失败程序退出
+		klog.Fatalf("register spacelet error: %s", err.Error())
+	}
+	s.routes()
+	tlsConfig, err := s.identity.serverTLSConfig()
+	if err != nil {
+		klog.Fatalf("spacelet tls config error: %s", err.Error())
+	}
 	server := &http.Server{
-		Addr:    fmt.Sprintf(":%d", s.config.Port),
-		Handler: s.engine,
+		Addr:      fmt.Sprintf(":%d", s.config.Port),
+		Handler:   s.engine,
+		TLSConfig: tlsConfig,
 	}
 	go func() {
-		if err := server.ListenAndServe(); err != nil {
+		if err := server.ListenAndServeTLS("", ""); err != nil {
 			panic(err)
 		}
 	}()
-	// 注册spacelet节点
-	if err := s.Register(); err != nil {
-		// 注册失败程序退出
-		klog.Fatalf("register spacelet error: %s", err.Error())
-	}
+	// 注册成功后定时上报心跳
+	go s.heartbeat()
 }
 
 type RegisterRequest struct {
 	Hostname string `json:"hostname"`
 	HostIp   string `json:"hostip"`
 	Port     int    `json:"port"`
+	// 节点容量
+	MaxJobs int   `json:"max_jobs"`
+	Cpu     int64 `json:"cpu"`
+	Memory  int64 `json:"memory"`
+	// 已注册过的spacelet重新注册时需要提供之前获取的token
+	Token string `json:"token"`
+	// 证书签名请求，私钥保留在spacelet本地
+	Csr string `json:"csr"`
+	// BootstrapToken 管理员生成的引导token，未注册过的spacelet注册时需要提供
+	BootstrapToken string `json:"bootstrap_token"`
+}
+
+// CAResponse spacelet注册前获取的CA证书以及双向tls端口
+type CAResponse struct {
+	CaCert  string `json:"ca_cert"`
+	TlsPort int    `json:"tls_port"`
 }
 
-// Register 启动spacelet后进行注册
+// RegisterResponse 注册成功后返回token以及kubespace签发的证书
+type RegisterResponse struct {
+	Token  string `json:"token"`
+	Cert   string `json:"cert"`
+	CaCert string `json:"ca_cert"`
+	// TlsPort kubespace双向tls端口，spacelet心跳以及任务回调通过该端口访问
+	TlsPort int `json:"tls_port"`
+}
+
+// Register 启动spacelet后进行注册，每次注册重新生成私钥并签发证书。
+// 注册通过kubespace双向tls端口进行，首次注册时获取CA证书并通过安装时指定的CA公钥sha256校验
 func (s *Server) Register() error {
 	hostname, _ := os.Hostname()
+	id, err := loadIdentity(s.config.DataDir)
+	if err != nil {
+		return fmt.Errorf("load spacelet identity error: %s", err.Error())
+	}
+	caCert, serverUrl := id.CaCert, id.ServerUrl
+	if caCert == "" || serverUrl == "" {
+		if caCert, serverUrl, err = s.fetchCA(); err != nil {
+			return err
+		}
+	}
+	tlsConfig, err := caTLSConfig(caCert)
+	if err != nil {
+		return err
+	}
+	client, err := httpclient.NewHttpClientWithTLS(serverUrl, tlsConfig)
+	if err != nil {
+		return err
+	}
+	csrPEM, keyPEM, err := utils.GenerateCSR(CommonName(s.config.HostIp, s.config.Port), "P256")
+	if err != nil {
+		return err
+	}
 	var resp utils.Response
 	// 调用spacelet注册接口
-	if _, err := s.config.Client.Post("/api/v1/spacelet/register", &RegisterRequest{
-		Hostname: hostname,
-		HostIp:   s.config.HostIp,
-		Port:     s.config.Port,
+	if _, err = client.Post("/api/v1/spacelet/register", &RegisterRequest{
+		Hostname:       hostname,
+		HostIp:         s.config.HostIp,
+		Port:           s.config.Port,
+		MaxJobs:        s.config.MaxJobs,
+		Cpu:            s.config.Cpu,
+		Memory:         s.config.Memory,
+		Token:          id.Token,
+		Csr:            string(csrPEM),
+		BootstrapToken: s.config.BootstrapToken,
 	}, &resp, httpclient.RequestOptions{}); err != nil {
 		return err
 	}
 	if !resp.IsSuccess() {
 		return fmt.Errorf("%s", resp.Msg)
 	}
-	return nil
+	var registerResp RegisterResponse
+	if err = utils.ConvertTypeByJson(resp.Data, &registerResp); err != nil {
+		return err
+	}
+	if s.config.CAHash != "" {
+		if err = verifyCAHash(registerResp.CaCert, s.config.CAHash); err != nil {
+			return err
+		}
+	}
+	id = &identity{
+		Token:     registerResp.Token,
+		Key:       string(keyPEM),
+		Cert:      registerResp.Cert,
+		CaCert:    registerResp.CaCert,
+		ServerUrl: serverUrl,
+	}
+	return s.setIdentity(id)
 }
 
-type RegisterToken struct {
-	Token string `json:"token"`
+// fetchCA 获取kubespace CA证书以及双向tls地址，CA证书通过安装时指定的公钥sha256校验，校验通过后才能注册
+func (s *Server) fetchCA() (caCert string, serverUrl string, err error) {
+	if s.config.CAHash == "" {
+		return "", "", fmt.Errorf("kubespace ca hash is required to register spacelet, please specify --ca-hash")
+	}
+	var resp utils.Response
+	if _, err = s.config.Client.Get("/api/v1/spacelet/ca", nil, &resp, httpclient.RequestOptions{}); err != nil {
+		return "", "", err
+	}
+	if !resp.IsSuccess() {
+		return "", "", fmt.Errorf("%s", resp.Msg)
+	}
+	var caResp CAResponse
+	if err = utils.ConvertTypeByJson(resp.Data, &caResp); err != nil {
+		return "", "", err
+	}
+	if err = verifyCAHash(caResp.CaCert, s.config.CAHash); err != nil {
+		return "", "", err
+	}
+	if serverUrl, err = s.serverTLSUrl(caResp.TlsPort); err != nil {
+		return "", "", err
+	}
+	return caResp.CaCert, serverUrl, nil
 }
 
-// Token 注册时kubespace server会调用该接口配置token
-func (s *Server) Token(c *gin.Context) {
-	if s.config.Token != "" {
-		c.JSON(http.StatusBadRequest, &utils.Response{Code: code.ParamsError, Msg: "spacelet already has token"})
-		return
+// serverTLSUrl spacelet通过双向tls访问kubespace的地址，未指定时使用注册地址的主机以及kubespace返回的tls端口
+func (s *Server) serverTLSUrl(tlsPort int) (string, error) {
+	if s.config.ServerTLSUrl != "" {
+		return s.config.ServerTLSUrl, nil
 	}
-	var token RegisterToken
-	if err := c.BindJSON(&token); err != nil {
-		c.JSON(http.StatusBadRequest, &utils.Response{Code: code.ParamsError, Msg: err.Error()})
-		return
+	if tlsPort == 0 {
+		return "", fmt.Errorf("kubespace server does not support spacelet mutual tls, please upgrade kubespace")
 	}
-	if token.Token == "" {
-		c.JSON(http.StatusBadRequest, &utils.Response{Code: code.ParamsError, Msg: "token is empty"})
-		return
+	u, err := url.Parse(s.config.ServerUrl)
+	if err != nil {
+		return "", err
 	}
-	// 配置token，后续认证
-	s.config.Token = token.Token
-	c.JSON(http.StatusOK, &utils.Response{Code: code.Success})
+	return fmt.Sprintf("https://%s", net.JoinHostPort(u.Hostname(), strconv.Itoa(tlsPort))), nil
+}
+
+// setIdentity 保存token以及证书，并更新访问kubespace的客户端
+func (s *Server) setIdentity(id *identity) error {
+	if err := id.save(s.config.DataDir); err != nil {
+		return fmt.Errorf("save spacelet identity error: %s", err.Error())
+	}
+	clientTLSConfig, err := id.clientTLSConfig()
+	if err != nil {
+		return err
+	}
+	client, err := httpclient.NewHttpClientWithTLS(id.ServerUrl, clientTLSConfig)
+	if err != nil {
+		return err
+	}
+	client.SetHeader("token", id.Token)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.identity = id
+	if s.jobExecutor == nil {
+		s.config.Client = client
+	} else {
+		// 任务执行器已使用当前客户端，只更新token
+		s.config.Client.SetHeader("token", id.Token)
+	}
+	s.config.Token = id.Token
+	return nil
+}
+
+// rotateToken kubespace轮换token后更新，轮换前的token在kubespace确认前仍然有效
+func (s *Server) rotateToken(token string) error {
+	s.mu.RLock()
+	id := *s.identity
+	s.mu.RUnlock()
+	if token == "" || token == id.Token {
+		return nil
+	}
+	id.PrevToken, id.Token = id.Token, token
+	klog.Infof("spacelet token rotated")
+	return s.setIdentity(&id)
+}
+
+func (s *Server) token() string {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.identity.Token
+}
+
+func (s *Server) validToken(token string) bool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if s.identity == nil || token == "" {
+		return false
+	}
+	return token == s.identity.Token || token == s.identity.PrevToken
 }
 
 // AuthMiddleware 调用spacelet接口需要通过token认证
@@ -118,16 +275,8 @@ func (s *Server) AuthMiddleware() gin.HandlerFunc {
 				c.JSON(200, resp)
 			}
 		}()
-		// 未配置token，返回报错
-		if s.config.Token == "" {
-			c.JSON(http.StatusUnauthorized, &utils.Response{Code: code.AuthError, Msg: "not register with token"})
-			c.Abort()
-			return
-		}
-		// 从header获取token
-		token := c.Request.Header.Get("token")
-		// 判断token跟配置是否相同
-		if token != s.config.Token {
+		// 从header获取token，判断跟当前或者轮换前的token是否相同
+		if !s.validToken(c.Request.Header.Get("token")) {
 			c.JSON(http.StatusUnauthorized, &utils.Response{Code: code.AuthError, Msg: "token is incorrect"})
 			c.Abort()
 			return
@@ -0,0 +1,382 @@
+package helm
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"golang.org/x/crypto/openpgp/armor" //nolint
+	"helm.sh/helm/v3/pkg/provenance"
+	"helm.sh/helm/v3/pkg/registry"
+	"helm.sh/helm/v3/pkg/repo"
+	"io"
+	"net"
+	"net/http"
+	"net/netip"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"sigs.k8s.io/yaml"
+	"strings"
+	"syscall"
+	"time"
+)
+
+const (
+	// OciScheme oci仓库地址前缀
+	OciScheme = "oci://"
+
+	repoHttpTimeout = 60 * time.Second
+	// chart包下载最大限制
+	maxChartSize = 20 << 20
+
+	iconHttpTimeout = 10 * time.Second
+	// chart图标下载最大限制
+	maxIconSize = 1 << 20
+)
+
+// RepoOptions 远程chart仓库配置
+type RepoOptions struct {
+	// 仓库地址，http(s)开头为helm仓库，oci://开头为oci仓库
+	Url      string
+	Username string
+	Password string
+	// oci仓库不支持列出所有chart，需要指定同步的chart名称
+	Charts []string
+}
+
+// RemoteChartVersion 远程仓库中的chart版本
+type RemoteChartVersion struct {
+	Name        string
+	Version     string
+	AppVersion  string
+	Description string
+	Icon        string
+	// chart包sha256摘要，helm仓库从index.yaml中获取，oci仓库在下载后获取
+	Digest string
+	// chart包下载地址，helm仓库为完整的http地址，oci仓库为oci引用
+	Url string
+}
+
+// RemoteChart 下载的chart包以及签名文件
+type RemoteChart struct {
+	// chart包文件名称，校验签名时需要与签名文件中的名称一致
+	Filename string
+	Data     []byte
+	// 签名文件内容，仓库中没有签名时为空
+	Prov []byte
+}
+
+// ChartRepository 远程chart仓库，包括helm仓库以及oci仓库
+type ChartRepository interface {
+	// Versions 获取仓库中所有chart的版本，每个chart的版本按从新到旧排序
+	Versions() (map[string][]*RemoteChartVersion, error)
+	// Download 下载chart包，同时尝试下载签名文件
+	Download(version *RemoteChartVersion) (*RemoteChart, error)
+	// Close 清理仓库客户端的临时文件
+	Close()
+}
+
+// NewChartRepository 根据仓库地址前缀返回helm仓库或者oci仓库客户端
+func NewChartRepository(opts *RepoOptions) (ChartRepository, error) {
+	if strings.HasPrefix(opts.Url, OciScheme) {
+		return newOciRepository(opts)
+	}
+	return newHttpRepository(opts)
+}
+
+// httpRepository 通过index.yaml获取chart列表的helm仓库
+type httpRepository struct {
+	opts   *RepoOptions
+	client *http.Client
+}
+
+func newHttpRepository(opts *RepoOptions) (*httpRepository, error) {
+	u, err := url.Parse(opts.Url)
+	if err != nil {
+		return nil, err
+	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return nil, fmt.Errorf("unsupported chart repository url: %s", opts.Url)
+	}
+	return &httpRepository{opts: opts, client: &http.Client{Timeout: repoHttpTimeout}}, nil
+}
+
+func (r *httpRepository) Versions() (map[string][]*RemoteChartVersion, error) {
+	indexUrl := strings.TrimSuffix(r.opts.Url, "/") + "/index.yaml"
+	data, err := r.get(indexUrl)
+	if err != nil {
+		return nil, err
+	}
+	index := &repo.IndexFile{}
+	if err = yaml.Unmarshal(data, index); err != nil {
+		return nil, fmt.Errorf("parse index.yaml error: %s", err.Error())
+	}
+	if index.APIVersion == "" {
+		return nil, fmt.Errorf("invalid index.yaml: no api version")
+	}
+	index.SortEntries()
+	charts := make(map[string][]*RemoteChartVersion)
+	for name, versions := range index.Entries {
+		for _, v := range versions {
+			if v == nil || v.Metadata == nil || len(v.URLs) == 0 {
+				continue
+			}
+			chartUrl, err := repo.ResolveReferenceURL(r.opts.Url, v.URLs[0])
+			if err != nil {
+				continue
+			}
+			charts[name] = append(charts[name], &RemoteChartVersion{
+				Name:        name,
+				Version:     v.Version,
+				AppVersion:  v.AppVersion,
+				Description: v.Description,
+				Icon:        v.Icon,
+				Digest:      v.Digest,
+				Url:         chartUrl,
+			})
+		}
+	}
+	return charts, nil
+}
+
+func (r *httpRepository) Download(version *RemoteChartVersion) (*RemoteChart, error) {
+	data, err := r.get(version.Url)
+	if err != nil {
+		return nil, err
+	}
+	if version.Digest != "" {
+		sum := sha256.Sum256(data)
+		if hex.EncodeToString(sum[:]) != strings.TrimPrefix(version.Digest, "sha256:") {
+			return nil, fmt.Errorf("chart %s-%s digest mismatch", version.Name, version.Version)
+		}
+	}
+	filename := path.Base(strings.SplitN(version.Url, "?", 2)[0])
+	chart := &RemoteChart{Filename: filename, Data: data}
+	// 签名文件不存在时忽略
+	if prov, err := r.get(version.Url + ".prov"); err == nil {
+		chart.Prov = prov
+	}
+	return chart, nil
+}
+
+func (r *httpRepository) Close() {}
+
+func (r *httpRepository) get(u string) ([]byte, error) {
+	req, err := http.NewRequest(http.MethodGet, u, nil)
+	if err != nil {
+		return nil, err
+	}
+	// 只向仓库所在的主机发送认证信息
+	if r.opts.Username != "" || r.opts.Password != "" {
+		if repoUrl, _ := url.Parse(r.opts.Url); repoUrl != nil && req.URL.Host == repoUrl.Host {
+			req.SetBasicAuth(r.opts.Username, r.opts.Password)
+		}
+	}
+	resp, err := r.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("get %s error: %s", u, resp.Status)
+	}
+	data, err := io.ReadAll(io.LimitReader(resp.Body, maxChartSize+1))
+	if err != nil {
+		return nil, err
+	}
+	if len(data) > maxChartSize {
+		return nil, fmt.Errorf("get %s error: content too large", u)
+	}
+	return data, nil
+}
+
+// iconClient 下载chart图标的http客户端，图标地址由chart作者填写，只允许访问公网地址，
+// 在建立连接时校验解析后的ip，避免通过域名解析或者重定向访问内网服务
+var iconClient = &http.Client{
+	Timeout: iconHttpTimeout,
+	Transport: &http.Transport{
+		DialContext: (&net.Dialer{
+			Timeout: iconHttpTimeout,
+			Control: func(network, address string, _ syscall.RawConn) error {
+				host, _, err := net.SplitHostPort(address)
+				if err != nil {
+					return err
+				}
+				if ip, err := netip.ParseAddr(host); err != nil || !publicAddr(ip) {
+					return fmt.Errorf("icon address %s is not allowed", host)
+				}
+				return nil
+			},
+		}).DialContext,
+		TLSHandshakeTimeout: iconHttpTimeout,
+	},
+	CheckRedirect: func(req *http.Request, via []*http.Request) error {
+		if len(via) >= 3 {
+			return fmt.Errorf("too many redirects")
+		}
+		if req.URL.Scheme != "http" && req.URL.Scheme != "https" {
+			return fmt.Errorf("unsupported icon url: %s", req.URL.String())
+		}
+		return nil
+	},
+}
+
+// sharedAddressSpace 运营商级NAT地址段100.64.0.0/10
+var sharedAddressSpace = netip.MustParsePrefix("100.64.0.0/10")
+
+// publicAddr ip是否为公网地址，排除回环、私有、链路本地、组播以及未指定地址
+func publicAddr(ip netip.Addr) bool {
+	ip = ip.Unmap()
+	return ip.IsGlobalUnicast() && !ip.IsPrivate() && !sharedAddressSpace.Contains(ip)
+}
+
+// FetchIcon 下载chart图标，图标地址一般为公开地址，不携带仓库认证信息，只允许访问公网的http/https地址
+func FetchIcon(iconUrl string) ([]byte, error) {
+	u, err := url.Parse(iconUrl)
+	if err != nil {
+		return nil, err
+	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return nil, fmt.Errorf("unsupported icon url: %s", iconUrl)
+	}
+	resp, err := iconClient.Get(u.String())
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("get %s error: %s", iconUrl, resp.Status)
+	}
+	data, err := io.ReadAll(io.LimitReader(resp.Body, maxIconSize+1))
+	if err != nil {
+		return nil, err
+	}
+	if len(data) > maxIconSize {
+		return nil, fmt.Errorf("get %s error: content too large", iconUrl)
+	}
+	return data, nil
+}
+
+// ociRepository oci镜像仓库中的chart
+type ociRepository struct {
+	opts   *RepoOptions
+	client *registry.Client
+	// oci仓库地址，不包括oci://前缀
+	ref     string
+	cleanup func()
+}
+
+func newOciRepository(opts *RepoOptions) (*ociRepository, error) {
+	ref := strings.TrimSuffix(strings.TrimPrefix(opts.Url, OciScheme), "/")
+	client, cleanup, err := NewRegistryClient(strings.SplitN(ref, "/", 2)[0], opts.Username, opts.Password)
+	if err != nil {
+		return nil, err
+	}
+	return &ociRepository{opts: opts, client: client, ref: ref, cleanup: cleanup}, nil
+}
+
+func (r *ociRepository) Versions() (map[string][]*RemoteChartVersion, error) {
+	charts := make(map[string][]*RemoteChartVersion)
+	for _, name := range r.opts.Charts {
+		// oci仓库chart名称不能为通配符
+		if strings.ContainsAny(name, "*?[") {
+			continue
+		}
+		chartRef := r.ref + "/" + name
+		tags, err := r.client.Tags(chartRef)
+		if err != nil {
+			return nil, fmt.Errorf("list chart %s tags error: %s", chartRef, err.Error())
+		}
+		for _, tag := range tags {
+			charts[name] = append(charts[name], &RemoteChartVersion{
+				Name:    name,
+				Version: tag,
+				Url:     fmt.Sprintf("%s:%s", chartRef, strings.ReplaceAll(tag, "+", "_")),
+			})
+		}
+	}
+	return charts, nil
+}
+
+func (r *ociRepository) Download(version *RemoteChartVersion) (*RemoteChart, error) {
+	result, err := r.client.Pull(version.Url, registry.PullOptWithProv(true), registry.PullOptIgnoreMissingProv(true))
+	if err != nil {
+		return nil, err
+	}
+	chart := &RemoteChart{
+		Filename: fmt.Sprintf("%s-%s.tgz", version.Name, version.Version),
+		Data:     result.Chart.Data,
+	}
+	if result.Prov != nil {
+		chart.Prov = result.Prov.Data
+	}
+	if result.Chart.Meta != nil {
+		version.AppVersion = result.Chart.Meta.AppVersion
+		version.Description = result.Chart.Meta.Description
+		version.Icon = result.Chart.Meta.Icon
+	}
+	version.Digest = result.Chart.Digest
+	return chart, nil
+}
+
+func (r *ociRepository) Close() {
+	r.cleanup()
+}
+
+// NewRegistryClient 创建oci仓库客户端，认证信息保存在临时文件中，使用完成后需要调用cleanup清理
+func NewRegistryClient(host, username, password string) (*registry.Client, func(), error) {
+	credentialsDir, err := os.MkdirTemp("", "kubespace-registry-")
+	if err != nil {
+		return nil, nil, err
+	}
+	cleanup := func() { os.RemoveAll(credentialsDir) }
+	client, err := registry.NewClient(registry.ClientOptCredentialsFile(filepath.Join(credentialsDir, "config.json")))
+	if err != nil {
+		cleanup()
+		return nil, nil, err
+	}
+	if username != "" || password != "" {
+		if err = client.Login(host, registry.LoginOptBasicAuth(username, password)); err != nil {
+			cleanup()
+			return nil, nil, fmt.Errorf("login registry %s error: %s", host, err.Error())
+		}
+	}
+	return client, cleanup, nil
+}
+
+// VerifyProvenance 通过公钥校验chart包签名，keyring可以是二进制或者armor格式的gpg公钥
+func VerifyProvenance(chart *RemoteChart, keyring string) error {
+	if len(chart.Prov) == 0 {
+		return fmt.Errorf("chart %s provenance not found", chart.Filename)
+	}
+	dir, err := os.MkdirTemp("", "kubespace-provenance-")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(dir)
+
+	keyringBytes := []byte(keyring)
+	if block, err := armor.Decode(bytes.NewReader(keyringBytes)); err == nil {
+		if keyringBytes, err = io.ReadAll(block.Body); err != nil {
+			return fmt.Errorf("decode keyring error: %s", err.Error())
+		}
+	}
+	keyringPath := filepath.Join(dir, "pubring.gpg")
+	chartPath := filepath.Join(dir, chart.Filename)
+	provPath := chartPath + ".prov"
+	for p, data := range map[string][]byte{keyringPath: keyringBytes, chartPath: chart.Data, provPath: chart.Prov} {
+		if err = os.WriteFile(p, data, 0600); err != nil {
+			return err
+		}
+	}
+	signatory, err := provenance.NewFromKeyring(keyringPath, "")
+	if err != nil {
+		return fmt.Errorf("load keyring error: %s", err.Error())
+	}
+	if _, err = signatory.Verify(chartPath, provPath); err != nil {
+		return fmt.Errorf("verify chart %s provenance error: %s", chart.Filename, err.Error())
+	}
+	return nil
+}
@@ -0,0 +1,187 @@
+package helm
+
+import (
+	"bytes"
+	"golang.org/x/crypto/openpgp"       //nolint
+	"golang.org/x/crypto/openpgp/armor" //nolint
+	"helm.sh/helm/v3/pkg/chart/loader"
+	"helm.sh/helm/v3/pkg/chartutil"
+	"helm.sh/helm/v3/pkg/provenance"
+	"helm.sh/helm/v3/pkg/repo"
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// newChartRepoFixture 在本地目录中生成demo chart的多个版本以及index.yaml，并通过http提供服务
+func newChartRepoFixture(t *testing.T, signer *openpgp.Entity) *httptest.Server {
+	dir := t.TempDir()
+	chartDir, err := chartutil.Create("demo", t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	chart, err := loader.LoadDir(chartDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, version := range []string{"0.1.0", "0.2.0"} {
+		chart.Metadata.Version = version
+		chartPath, err := chartutil.Save(chart, dir)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if signer != nil && version == "0.2.0" {
+			sig, err := (&provenance.Signatory{Entity: signer}).ClearSign(chartPath)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err = os.WriteFile(chartPath+".prov", []byte(sig), 0644); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+	server := httptest.NewServer(nil)
+	index, err := repo.IndexDirectory(dir, server.URL+"/charts")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = index.WriteFile(filepath.Join(dir, "index.yaml"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	fileServer := http.StripPrefix("/charts", http.FileServer(http.Dir(dir)))
+	server.Config.Handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if user, password, ok := r.BasicAuth(); !ok || user != "admin" || password != "secret" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		fileServer.ServeHTTP(w, r)
+	})
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestHttpRepositorySync(t *testing.T) {
+	server := newChartRepoFixture(t, nil)
+
+	unauthorized, _ := NewChartRepository(&RepoOptions{Url: server.URL + "/charts"})
+	if _, err := unauthorized.Versions(); err == nil {
+		t.Fatal("expected unauthorized error")
+	}
+
+	chartRepo, err := NewChartRepository(&RepoOptions{Url: server.URL + "/charts", Username: "admin", Password: "secret"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer chartRepo.Close()
+	charts, err := chartRepo.Versions()
+	if err != nil {
+		t.Fatal(err)
+	}
+	versions := charts["demo"]
+	if len(versions) != 2 || versions[0].Version != "0.2.0" || versions[1].Version != "0.1.0" {
+		t.Fatalf("unexpected versions: %+v", versions)
+	}
+	remoteChart, err := chartRepo.Download(versions[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if remoteChart.Filename != "demo-0.2.0.tgz" || len(remoteChart.Prov) != 0 {
+		t.Fatalf("unexpected chart %s prov=%d", remoteChart.Filename, len(remoteChart.Prov))
+	}
+
+	// 摘要与index.yaml不一致时下载失败
+	versions[1].Digest = "0000"
+	if _, err = chartRepo.Download(versions[1]); err == nil {
+		t.Fatal("expected digest mismatch error")
+	}
+}
+
+func TestVerifyProvenance(t *testing.T) {
+	signer, err := openpgp.NewEntity("kubespace", "", "test@kubespace.cn", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var keyring bytes.Buffer
+	w, err := armor.Encode(&keyring, openpgp.PublicKeyType, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = signer.Serialize(w); err != nil {
+		t.Fatal(err)
+	}
+	w.Close()
+
+	server := newChartRepoFixture(t, signer)
+	chartRepo, err := NewChartRepository(&RepoOptions{Url: server.URL + "/charts", Username: "admin", Password: "secret"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	charts, err := chartRepo.Versions()
+	if err != nil {
+		t.Fatal(err)
+	}
+	signed, err := chartRepo.Download(charts["demo"][0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = VerifyProvenance(signed, keyring.String()); err != nil {
+		t.Fatalf("verify signed chart error: %s", err)
+	}
+
+	unsigned, err := chartRepo.Download(charts["demo"][1])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = VerifyProvenance(unsigned, keyring.String()); err == nil {
+		t.Fatal("expected unsigned chart verify error")
+	}
+	// 签名文件与chart包不匹配
+	unsigned.Prov = signed.Prov
+	unsigned.Filename = signed.Filename
+	if err = VerifyProvenance(unsigned, keyring.String()); err == nil {
+		t.Fatal("expected tampered chart verify error")
+	}
+}
+
+func TestPublicAddr(t *testing.T) {
+	tests := []struct {
+		addr string
+		want bool
+	}{
+		{addr: "8.8.8.8", want: true},
+		{addr: "2001:4860:4860::8888", want: true},
+		{addr: "127.0.0.1"},
+		{addr: "10.0.0.1"},
+		{addr: "172.16.0.1"},
+		{addr: "192.168.1.1"},
+		{addr: "100.64.0.1"},
+		{addr: "169.254.169.254"},
+		{addr: "0.0.0.0"},
+		{addr: "224.0.0.1"},
+		{addr: "::1"},
+		{addr: "fd00::1"},
+		{addr: "fe80::1"},
+		{addr: "::ffff:127.0.0.1"},
+	}
+	for _, tt := range tests {
+		if got := publicAddr(netip.MustParseAddr(tt.addr)); got != tt.want {
+			t.Errorf("publicAddr(%s) = %v, want %v", tt.addr, got, tt.want)
+		}
+	}
+}
+
+func TestFetchIcon(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("icon"))
+	}))
+	defer server.Close()
+	// 不允许访问本机以及内网地址，非http协议地址
+	for _, u := range []string{server.URL + "/icon.png", "file:///etc/passwd", "ftp://example.com/icon.png"} {
+		if _, err := FetchIcon(u); err == nil {
+			t.Errorf("fetch icon %s should fail", u)
+		}
+	}
+}
@@ -15,16 +15,25 @@ import (
 	"net/url"
 	"os"
 	"path"
+	"sync"
 )
 
 type HttpClient struct {
 	client  *http.Client
 	baseUrl *url.URL
+	// 每次请求默认带上的header，如认证token
+	mu      sync.RWMutex
+	headers http.Header
 }
 
 func NewHttpClient(baseUrl string) (*HttpClient, error) {
+	return NewHttpClientWithTLS(baseUrl, &tls.Config{InsecureSkipVerify: true})
+}
+
+// NewHttpClientWithTLS 使用指定的tls配置创建客户端，如双向tls认证
+func NewHttpClientWithTLS(baseUrl string, tlsConfig *tls.Config) (*HttpClient, error) {
 	tr := &http.Transport{
-		TLSClientConfig: &tls.Config{InsecureSkipVerify: true},
+		TLSClientConfig: tlsConfig,
 	}
 	u, err := url.Parse(baseUrl)
 	if err != nil {
@@ -37,6 +46,16 @@ func NewHttpClient(baseUrl string) (*HttpClient, error) {
 	}, nil
 }
 
+// SetHeader 设置每次请求默认带上的header，请求参数中的header会覆盖默认header
+func (c *HttpClient) SetHeader(name, value string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.headers == nil {
+		c.headers = make(http.Header)
+	}
+	c.headers.Set(name, value)
+}
+
 type RequestOptions struct {
 	Header  http.Header
 	Context context.Context
@@ -134,6 +153,11 @@ func (c *HttpClient) NewRequest(method, reqPath string, params interface{}, opti
 		klog.Errorf("get http request error: error=%v, url=%s, method=%s", err, u.String(), method)
 		return nil, err
 	}
+	c.mu.RLock()
+	for k, v := range c.headers {
+		headers[k] = v
+	}
+	c.mu.RUnlock()
 	// 覆盖Header
 	for k, v := range options.Header {
 		headers[k] = v
@@ -0,0 +1,69 @@
+package utils
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// aesEncryptedPrefix 加密后数据的前缀，用于区分未加密的历史数据
+const aesEncryptedPrefix = "aes:"
+
+func newGCM(key string) (cipher.AEAD, error) {
+	if key == "" {
+		return nil, fmt.Errorf("encryption key is empty")
+	}
+	sum := sha256.Sum256([]byte(key))
+	block, err := aes.NewCipher(sum[:])
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// AESEncrypt 使用AES-GCM加密，密钥为key的sha256，返回带前缀的base64字符串
+func AESEncrypt(key, plaintext string) (string, error) {
+	gcm, err := newGCM(key)
+	if err != nil {
+		return "", err
+	}
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err = io.ReadFull(rand.Reader, nonce); err != nil {
+		return "", err
+	}
+	sealed := gcm.Seal(nonce, nonce, []byte(plaintext), nil)
+	return aesEncryptedPrefix + base64.StdEncoding.EncodeToString(sealed), nil
+}
+
+// AESDecrypt 解密AESEncrypt加密的数据
+func AESDecrypt(key, ciphertext string) (string, error) {
+	if !AESEncrypted(ciphertext) {
+		return "", fmt.Errorf("data is not encrypted")
+	}
+	gcm, err := newGCM(key)
+	if err != nil {
+		return "", err
+	}
+	sealed, err := base64.StdEncoding.DecodeString(strings.TrimPrefix(ciphertext, aesEncryptedPrefix))
+	if err != nil {
+		return "", err
+	}
+	if len(sealed) < gcm.NonceSize() {
+		return "", fmt.Errorf("encrypted data is too short")
+	}
+	plaintext, err := gcm.Open(nil, sealed[:gcm.NonceSize()], sealed[gcm.NonceSize():], nil)
+	if err != nil {
+		return "", fmt.Errorf("decrypt data error, encryption key may be incorrect: %s", err.Error())
+	}
+	return string(plaintext), nil
+}
+
+// AESEncrypted 数据是否为AESEncrypt加密后的数据
+func AESEncrypted(data string) bool {
+	return strings.HasPrefix(data, aesEncryptedPrefix)
+}
@@ -0,0 +1,100 @@
+package utils
+
+import (
+	"fmt"
+	"github.com/sergi/go-diff/diffmatchpatch"
+	"strings"
+)
+
+// diffContextLines unified diff每个变更块前后保留的上下文行数
+const diffContextLines = 3
+
+type diffLine struct {
+	op   diffmatchpatch.Operation
+	text string
+}
+
+// UnifiedDiff 按行比较两段文本，返回unified diff格式的差异内容，内容相同时返回空字符串
+func UnifiedDiff(fromName, toName, from, to string) string {
+	if from == to {
+		return ""
+	}
+	dmp := diffmatchpatch.New()
+	fromChars, toChars, lineArray := dmp.DiffLinesToChars(from, to)
+	diffs := dmp.DiffCharsToLines(dmp.DiffMain(fromChars, toChars, false), lineArray)
+
+	var lines []diffLine
+	for _, d := range diffs {
+		text := strings.TrimSuffix(d.Text, "\n")
+		for _, line := range strings.Split(text, "\n") {
+			lines = append(lines, diffLine{op: d.Type, text: line})
+		}
+	}
+
+	var buf strings.Builder
+	buf.WriteString(fmt.Sprintf("--- %s\n+++ %s\n", fromName, toName))
+	// fromLine、toLine为当前行在原文本以及新文本中的行号，从1开始
+	fromLine, toLine := 1, 1
+	for i := 0; i < len(lines); {
+		if lines[i].op == diffmatchpatch.DiffEqual {
+			fromLine++
+			toLine++
+			i++
+			continue
+		}
+		// 找到变更块的起止位置，两个变更之间相等行不超过2倍上下文时合并为一个块
+		start := i - diffContextLines
+		if start < 0 {
+			start = 0
+		}
+		end := i
+		for j := i; j < len(lines); j++ {
+			if lines[j].op != diffmatchpatch.DiffEqual {
+				end = j
+			} else if j-end > 2*diffContextLines {
+				break
+			}
+		}
+		end += diffContextLines
+		if end >= len(lines) {
+			end = len(lines) - 1
+		}
+		hunkFromStart, hunkToStart := fromLine-(i-start), toLine-(i-start)
+		var fromCount, toCount int
+		var hunk strings.Builder
+		for _, line := range lines[start : end+1] {
+			switch line.op {
+			case diffmatchpatch.DiffEqual:
+				fromCount++
+				toCount++
+				hunk.WriteString(" " + line.text + "\n")
+			case diffmatchpatch.DiffDelete:
+				fromCount++
+				hunk.WriteString("-" + line.text + "\n")
+			case diffmatchpatch.DiffInsert:
+				toCount++
+				hunk.WriteString("+" + line.text + "\n")
+			}
+		}
+		// 块中没有行时，起始行号为变更位置的前一行，如空文本为0
+		if fromCount == 0 {
+			hunkFromStart--
+		}
+		if toCount == 0 {
+			hunkToStart--
+		}
+		buf.WriteString(fmt.Sprintf("@@ -%d,%d +%d,%d @@\n", hunkFromStart, fromCount, hunkToStart, toCount))
+		buf.WriteString(hunk.String())
+		// 跳过当前块中已处理的行
+		for _, line := range lines[i : end+1] {
+			if line.op != diffmatchpatch.DiffInsert {
+				fromLine++
+			}
+			if line.op != diffmatchpatch.DiffDelete {
+				toLine++
+			}
+		}
+		i = end + 1
+	}
+	return buf.String()
+}
@@ -0,0 +1,94 @@
+package utils
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+// numberedLines 生成line1到lineN的文本，每行以换行结尾
+func numberedLines(n int, replace map[int]string) string {
+	var lines []string
+	for i := 1; i <= n; i++ {
+		line := fmt.Sprintf("line%d", i)
+		if r, ok := replace[i]; ok {
+			line = r
+		}
+		lines = append(lines, line)
+	}
+	return strings.Join(lines, "\n") + "\n"
+}
+
+func TestUnifiedDiff(t *testing.T) {
+	tests := []struct {
+		name string
+		from string
+		to   string
+		want string
+	}{
+		{
+			name: "both empty",
+			from: "",
+			to:   "",
+			want: "",
+		},
+		{
+			name: "identical",
+			from: "a\nb\nc\n",
+			to:   "a\nb\nc\n",
+			want: "",
+		},
+		{
+			name: "add to empty",
+			from: "",
+			to:   "a\nb\n",
+			want: "--- old\n+++ new\n@@ -0,0 +1,2 @@\n+a\n+b\n",
+		},
+		{
+			name: "delete all",
+			from: "a\nb\n",
+			to:   "",
+			want: "--- old\n+++ new\n@@ -1,2 +0,0 @@\n-a\n-b\n",
+		},
+		{
+			name: "pure add",
+			from: "a\nc\n",
+			to:   "a\nb\nc\n",
+			want: "--- old\n+++ new\n@@ -1,2 +1,3 @@\n a\n+b\n c\n",
+		},
+		{
+			name: "pure delete",
+			from: "a\nb\nc\n",
+			to:   "a\nc\n",
+			want: "--- old\n+++ new\n@@ -1,3 +1,2 @@\n a\n-b\n c\n",
+		},
+		{
+			name: "modify",
+			from: "a\nb\nc\n",
+			to:   "a\nB\nc\n",
+			want: "--- old\n+++ new\n@@ -1,3 +1,3 @@\n a\n-b\n+B\n c\n",
+		},
+		{
+			name: "multi hunk",
+			from: numberedLines(20, nil),
+			to:   numberedLines(20, map[int]string{2: "changed2", 18: "changed18"}),
+			want: "--- old\n+++ new\n" +
+				"@@ -1,5 +1,5 @@\n line1\n-line2\n+changed2\n line3\n line4\n line5\n" +
+				"@@ -15,6 +15,6 @@\n line15\n line16\n line17\n-line18\n+changed18\n line19\n line20\n",
+		},
+		{
+			name: "near changes merged into one hunk",
+			from: numberedLines(10, nil),
+			to:   numberedLines(10, map[int]string{2: "changed2", 8: "changed8"}),
+			want: "--- old\n+++ new\n" +
+				"@@ -1,10 +1,10 @@\n line1\n-line2\n+changed2\n line3\n line4\n line5\n line6\n line7\n-line8\n+changed8\n line9\n line10\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := UnifiedDiff("old", "new", tt.from, tt.to); got != tt.want {
+				t.Errorf("UnifiedDiff() =\n%s\nwant:\n%s", got, tt.want)
+			}
+		})
+	}
+}
@@ -11,7 +11,6 @@ import (
 	"encoding/pem"
 	"fmt"
 	"k8s.io/klog/v2"
-	"log"
 	"math/big"
 	"net"
 	"os"
@@ -42,41 +41,46 @@ func publicKey(priv interface{}) interface{} {
 	}
 }
 
-func GenerateCert(host string, validFor time.Duration, isCA bool, ecdsaCurve string) error {
-	//flag.Parse()
-
-	if len(host) == 0 {
-		return fmt.Errorf("missing required host parameter")
-	}
+// CertOptions 生成证书的参数
+type CertOptions struct {
+	// 证书中的主机名以及ip
+	Hosts      []string
+	CommonName string
+	ValidFor   time.Duration
+	IsCA       bool
+	// 为空时使用rsa 2048生成私钥
+	EcdsaCurve  string
+	ExtKeyUsage []x509.ExtKeyUsage
+	// 签发证书的CA，为空时生成自签名证书
+	Parent    *x509.Certificate
+	ParentKey interface{}
+}
 
-	var priv interface{}
-	var err error
+func generatePrivateKey(ecdsaCurve string) (interface{}, error) {
 	switch ecdsaCurve {
 	case "":
-		priv, err = rsa.GenerateKey(rand.Reader, 2048)
+		return rsa.GenerateKey(rand.Reader, 2048)
 	case "P224":
-		priv, err = ecdsa.GenerateKey(elliptic.P224(), rand.Reader)
+		return ecdsa.GenerateKey(elliptic.P224(), rand.Reader)
 	case "P256":
-		priv, err = ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+		return ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
 	case "P384":
-		priv, err = ecdsa.GenerateKey(elliptic.P384(), rand.Reader)
+		return ecdsa.GenerateKey(elliptic.P384(), rand.Reader)
 	case "P521":
-		priv, err = ecdsa.GenerateKey(elliptic.P521(), rand.Reader)
+		return ecdsa.GenerateKey(elliptic.P521(), rand.Reader)
 	default:
-		return fmt.Errorf("unrecognized elliptic curve: %q", ecdsaCurve)
-	}
-	if err != nil {
-		klog.Errorf("Failed to generate private key: %v", err)
-		return err
+		return nil, fmt.Errorf("unrecognized elliptic curve: %q", ecdsaCurve)
 	}
+}
 
+func certTemplate(commonName string, hosts []string, validFor time.Duration, isCA bool, extKeyUsage []x509.ExtKeyUsage, rsaKey bool) (*x509.Certificate, error) {
 	// ECDSA, ED25519 and RSA subject keys should have the DigitalSignature
 	// KeyUsage bits set in the x509.Certificate template
 	keyUsage := x509.KeyUsageDigitalSignature
 	// Only RSA subject keys should have the KeyEncipherment KeyUsage bits set. In
 	// the context of TLS this KeyUsage is particular to RSA key exchange and
 	// authentication.
-	if _, isRSA := priv.(*rsa.PrivateKey); isRSA {
+	if rsaKey {
 		keyUsage |= x509.KeyUsageKeyEncipherment
 	}
 
@@ -87,24 +91,30 @@ func GenerateCert(host string, validFor time.Duration, isCA bool, ecdsaCurve str
 	serialNumberLimit := new(big.Int).Lsh(big.NewInt(1), 128)
 	serialNumber, err := rand.Int(rand.Reader, serialNumberLimit)
 	if err != nil {
-		log.Fatalf("Failed to generate serial number: %v", err)
+		return nil, fmt.Errorf("failed to generate serial number: %v", err)
+	}
+	if len(extKeyUsage) == 0 && !isCA {
+		extKeyUsage = []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth}
 	}
 
-	template := x509.Certificate{
+	template := &x509.Certificate{
 		SerialNumber: serialNumber,
 		Subject: pkix.Name{
 			Organization: []string{"OpenSpace Lab"},
+			CommonName:   commonName,
 		},
 		NotBefore: notBefore,
 		NotAfter:  notAfter,
 
 		KeyUsage:              keyUsage,
-		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+		ExtKeyUsage:           extKeyUsage,
 		BasicConstraintsValid: true,
 	}
 
-	hosts := strings.Split(host, ",")
 	for _, h := range hosts {
+		if h == "" {
+			continue
+		}
 		if ip := net.ParseIP(h); ip != nil {
 			template.IPAddresses = append(template.IPAddresses, ip)
 		} else {
@@ -116,44 +126,136 @@ func GenerateCert(host string, validFor time.Duration, isCA bool, ecdsaCurve str
 		template.IsCA = true
 		template.KeyUsage |= x509.KeyUsageCertSign
 	}
+	return template, nil
+}
 
-	derBytes, err := x509.CreateCertificate(rand.Reader, &template, &template, publicKey(priv), priv)
+func encodeKeyPEM(priv interface{}) ([]byte, error) {
+	privBytes, err := x509.MarshalPKCS8PrivateKey(priv)
+	if err != nil {
+		return nil, fmt.Errorf("unable to marshal private key: %v", err)
+	}
+	return pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: privBytes}), nil
+}
+
+// GenerateCertPEM 生成证书以及私钥，返回pem编码内容
+func GenerateCertPEM(opts *CertOptions) (certPEM []byte, keyPEM []byte, err error) {
+	priv, err := generatePrivateKey(opts.EcdsaCurve)
+	if err != nil {
+		klog.Errorf("Failed to generate private key: %v", err)
+		return nil, nil, err
+	}
+	_, isRSA := priv.(*rsa.PrivateKey)
+	template, err := certTemplate(opts.CommonName, opts.Hosts, opts.ValidFor, opts.IsCA, opts.ExtKeyUsage, isRSA)
+	if err != nil {
+		return nil, nil, err
+	}
+	parent, parentKey := template, priv
+	if opts.Parent != nil {
+		parent, parentKey = opts.Parent, opts.ParentKey
+	}
+	derBytes, err := x509.CreateCertificate(rand.Reader, template, parent, publicKey(priv), parentKey)
 	if err != nil {
 		klog.Errorf("Failed to create certificate: %v", err)
-		return err
+		return nil, nil, err
 	}
+	keyPEM, err = encodeKeyPEM(priv)
+	if err != nil {
+		return nil, nil, err
+	}
+	return pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: derBytes}), keyPEM, nil
+}
 
-	certOut, err := os.Create("cert.pem")
+// GenerateCSR 生成私钥以及证书签名请求，私钥保留在本地，签名请求发送给CA签发证书
+func GenerateCSR(commonName string, ecdsaCurve string) (csrPEM []byte, keyPEM []byte, err error) {
+	priv, err := generatePrivateKey(ecdsaCurve)
 	if err != nil {
-		klog.Errorf("Failed to open cert.pem for writing: %v", err)
-		return err
+		return nil, nil, err
 	}
-	if err := pem.Encode(certOut, &pem.Block{Type: "CERTIFICATE", Bytes: derBytes}); err != nil {
-		klog.Errorf("Failed to write data to cert.pem: %v", err)
-		return err
+	csrBytes, err := x509.CreateCertificateRequest(rand.Reader, &x509.CertificateRequest{
+		Subject: pkix.Name{CommonName: commonName},
+	}, priv)
+	if err != nil {
+		return nil, nil, err
 	}
-	if err := certOut.Close(); err != nil {
-		klog.Errorf("Error closing cert.pem: %v", err)
-		return err
+	keyPEM, err = encodeKeyPEM(priv)
+	if err != nil {
+		return nil, nil, err
 	}
-	klog.Info("wrote cert.pem\n")
+	return pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE REQUEST", Bytes: csrBytes}), keyPEM, nil
+}
 
-	keyOut, err := os.OpenFile("key.pem", os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0600)
+// SignCSR 使用CA对证书签名请求签发证书，证书中的CommonName以及主机由签发方指定，不使用请求中的内容
+func SignCSR(csrPEM []byte, caCert *x509.Certificate, caKey interface{}, commonName string, hosts []string,
+	validFor time.Duration, extKeyUsage []x509.ExtKeyUsage) ([]byte, error) {
+	block, _ := pem.Decode(csrPEM)
+	if block == nil || block.Type != "CERTIFICATE REQUEST" {
+		return nil, fmt.Errorf("failed to decode certificate request")
+	}
+	csr, err := x509.ParseCertificateRequest(block.Bytes)
 	if err != nil {
-		klog.Errorf("Failed to open key.pem for writing: %v", err)
-		return err
+		return nil, err
 	}
-	privBytes, err := x509.MarshalPKCS8PrivateKey(priv)
+	if err = csr.CheckSignature(); err != nil {
+		return nil, fmt.Errorf("certificate request signature error: %v", err)
+	}
+	_, isRSA := csr.PublicKey.(*rsa.PublicKey)
+	template, err := certTemplate(commonName, hosts, validFor, false, extKeyUsage, isRSA)
+	if err != nil {
+		return nil, err
+	}
+	derBytes, err := x509.CreateCertificate(rand.Reader, template, caCert, csr.PublicKey, caKey)
+	if err != nil {
+		return nil, err
+	}
+	return pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: derBytes}), nil
+}
+
+// ParseCertKeyPEM 解析pem编码的证书以及私钥
+func ParseCertKeyPEM(certPEM, keyPEM []byte) (*x509.Certificate, interface{}, error) {
+	certBlock, _ := pem.Decode(certPEM)
+	if certBlock == nil {
+		return nil, nil, fmt.Errorf("failed to decode certificate")
+	}
+	cert, err := x509.ParseCertificate(certBlock.Bytes)
+	if err != nil {
+		return nil, nil, err
+	}
+	keyBlock, _ := pem.Decode(keyPEM)
+	if keyBlock == nil {
+		return nil, nil, fmt.Errorf("failed to decode private key")
+	}
+	key, err := x509.ParsePKCS8PrivateKey(keyBlock.Bytes)
+	if err != nil {
+		return nil, nil, err
+	}
+	return cert, key, nil
+}
+
+func GenerateCert(host string, validFor time.Duration, isCA bool, ecdsaCurve string) error {
+	//flag.Parse()
+
+	if len(host) == 0 {
+		return fmt.Errorf("missing required host parameter")
+	}
+
+	certPEM, keyPEM, err := GenerateCertPEM(&CertOptions{
+		Hosts:      strings.Split(host, ","),
+		ValidFor:   validFor,
+		IsCA:       isCA,
+		EcdsaCurve: ecdsaCurve,
+	})
 	if err != nil {
-		klog.Errorf("Unable to marshal private key: %v", err)
 		return err
 	}
-	if err := pem.Encode(keyOut, &pem.Block{Type: "PRIVATE KEY", Bytes: privBytes}); err != nil {
-		klog.Errorf("Failed to write data to key.pem: %v", err)
+
+	if err = os.WriteFile("cert.pem", certPEM, 0644); err != nil {
+		klog.Errorf("Failed to write data to cert.pem: %v", err)
 		return err
 	}
-	if err := keyOut.Close(); err != nil {
-		klog.Errorf("Error closing key.pem: %v", err)
+	klog.Info("wrote cert.pem\n")
+
+	if err = os.WriteFile("key.pem", keyPEM, 0600); err != nil {
+		klog.Errorf("Failed to write data to key.pem: %v", err)
 		return err
 	}
 	klog.Info("wrote key.pem\n")